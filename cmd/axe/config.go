@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit axe configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check .axerc and the resolved preview configuration without running anything",
+	Long: `Loads .axerc from the current directory, merges it with project auto-detection
+and the global default simulator the same way axe preview does, and reports
+every problem found: unknown keys, PROJECT and WORKSPACE both set, paths that
+do not exist, a missing SCHEME, a DEVICE, RUNTIME or DEVICE_TYPE that does
+not resolve, an unknown FAMILY or APPEARANCE, and a malformed TOOLCHAIN or
+SIMCTL_TIMEOUT. Run axe doctor to check the installed tools.
+
+Exits non-zero if any problem is found.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runConfigValidate(newConfigValidator())
+	},
+}
+
+// knownRCKeys lists the keys read from .axerc.
+var knownRCKeys = []string{"APP_NAME", "APPEARANCE", "CONFIGURATION", "DEVICE", "DEVICE_TYPE", "FAMILY", "PROJECT", "RUNTIME", "SCHEME", "SIMCTL_TIMEOUT", "TOOLCHAIN", "WORKSPACE"}
+
+// configValidator checks the merged configuration. Its function fields are
+// the lookups axe preview performs, replaced by fakes in tests.
+type configValidator struct {
+	readRC            func() map[string]string
+	detectProject     func() (project, workspace string)
+	stat              func(name string) (os.FileInfo, error)
+	findSimulator     func(udid string) error
+	resolveRuntime    func(name string) error
+	resolveDeviceType func(name, runtime string) error
+	defaultSimulator  func() (string, error)
+}
+
+func newConfigValidator() *configValidator {
+	simctl := &platform.RealSimctlRunner{}
+	return &configValidator{
+		readRC:        platform.ReadRC,
+		detectProject: platform.DetectXcodeProject,
+		stat:          os.Stat,
+		findSimulator: func(udid string) error {
+			_, _, err := platform.FindSimulator(simctl, udid)
+			return err
+		},
+		resolveRuntime: func(name string) error {
+			_, err := platform.ResolveRuntime(simctl, name)
+			return err
+		},
+		resolveDeviceType: func(name, runtime string) error {
+			// An unresolvable runtime is reported on its own; check the
+			// device type against every runtime then.
+			runtimeID, _ := platform.ResolveRuntime(simctl, runtime)
+			_, err := platform.ResolveDeviceType(simctl, name, runtimeID)
+			return err
+		},
+		defaultSimulator: func() (string, error) {
+			store, err := platform.NewConfigStore()
+			if err != nil {
+				return "", err
+			}
+			return store.GetDefault()
+		},
+	}
+}
+
+// resolvedConfig is the configuration axe preview would use.
+type resolvedConfig struct {
+	Project       string
+	Workspace     string
+	Scheme        string
+	Configuration string
+	Device        string
+	Runtime       string
+	Family        string
+	DeviceType    string
+	Appearance    string
+	Toolchain     string
+	SimctlTimeout string
+}
+
+// validate resolves the configuration and returns every problem found
+// rather than stopping at the first one.
+func (v *configValidator) validate() (resolvedConfig, []error) {
+	var problems []error
+	rc := v.readRC()
+
+	var unknown []string
+	for k := range rc {
+		if !slices.Contains(knownRCKeys, k) {
+			unknown = append(unknown, k)
+		}
+	}
+	slices.Sort(unknown)
+	for _, k := range unknown {
+		problems = append(problems, fmt.Errorf(".axerc: unknown key %s (known keys: %s)", k, strings.Join(knownRCKeys, ", ")))
+	}
+
+	if rc["PROJECT"] != "" && rc["WORKSPACE"] != "" {
+		problems = append(problems, errors.New(".axerc: PROJECT and WORKSPACE are mutually exclusive; remove one of them"))
+	}
+	if err := v.checkPath("PROJECT", rc["PROJECT"], ".xcodeproj"); err != nil {
+		problems = append(problems, err)
+	}
+	if err := v.checkPath("WORKSPACE", rc["WORKSPACE"], ".xcworkspace"); err != nil {
+		problems = append(problems, err)
+	}
+
+	// Same priority as resolveProjectConfig: auto-detection, then .axerc.
+	cfg := resolvedConfig{
+		Scheme:        rc["SCHEME"],
+		Configuration: rc["CONFIGURATION"],
+		Device:        rc["DEVICE"],
+		Runtime:       rc["RUNTIME"],
+		Family:        rc["FAMILY"],
+		DeviceType:    rc["DEVICE_TYPE"],
+		Appearance:    rc["APPEARANCE"],
+		Toolchain:     rc["TOOLCHAIN"],
+		SimctlTimeout: rc["SIMCTL_TIMEOUT"],
+	}
+	cfg.Project, cfg.Workspace = v.detectProject()
+	if cfg.Project == "" && cfg.Workspace == "" {
+		if rc["WORKSPACE"] != "" {
+			cfg.Workspace = rc["WORKSPACE"]
+		} else {
+			cfg.Project = rc["PROJECT"]
+		}
+	}
+	if cfg.Project == "" && cfg.Workspace == "" {
+		problems = append(problems, fmt.Errorf("%w: no project found. Set PROJECT or WORKSPACE in .axerc, or place a single .xcodeproj or .xcworkspace in the current directory", errConfigMissing))
+	}
+	if cfg.Scheme == "" {
+		problems = append(problems, fmt.Errorf("%w: SCHEME is not set in .axerc", errConfigMissing))
+	}
+
+	if err := build.ValidateToolchain(cfg.Toolchain); err != nil {
+		problems = append(problems, fmt.Errorf(".axerc TOOLCHAIN: %w", err))
+	}
+	if cfg.Runtime != "" {
+		if err := v.resolveRuntime(cfg.Runtime); err != nil {
+			problems = append(problems, fmt.Errorf(".axerc RUNTIME: %w", err))
+		}
+	}
+	if family, err := platform.ParseDeviceFamily(cfg.Family); err != nil {
+		problems = append(problems, fmt.Errorf(".axerc FAMILY: %w", err))
+	} else {
+		cfg.Family = family
+	}
+	if cfg.DeviceType != "" {
+		if err := v.resolveDeviceType(cfg.DeviceType, cfg.Runtime); err != nil {
+			problems = append(problems, fmt.Errorf(".axerc DEVICE_TYPE: %w", err))
+		}
+	}
+	if err := (platform.AccessibilityOverrides{Appearance: cfg.Appearance}).Validate(); err != nil {
+		problems = append(problems, fmt.Errorf(".axerc APPEARANCE: %w", err))
+	}
+	if cfg.SimctlTimeout != "" {
+		if _, err := platform.ParseSimctlTimeout(cfg.SimctlTimeout); err != nil {
+			problems = append(problems, fmt.Errorf(".axerc SIMCTL_TIMEOUT: %w", err))
+		}
+	}
+	if cfg.Device != "" {
+		if err := v.findSimulator(cfg.Device); err != nil {
+			problems = append(problems, fmt.Errorf(".axerc DEVICE: %w", err))
+		}
+	} else if udid, err := v.defaultSimulator(); err != nil {
+		problems = append(problems, fmt.Errorf("reading default simulator: %w", err))
+	} else if udid != "" {
+		if err := v.findSimulator(udid); err != nil {
+			problems = append(problems, fmt.Errorf("default simulator (axe preview simulator default): %w", err))
+		} else {
+			cfg.Device = udid
+		}
+	}
+
+	return cfg, problems
+}
+
+// checkPath verifies that a path from .axerc key exists and has the
+// expected extension. An empty value is not checked.
+func (v *configValidator) checkPath(key, path, ext string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := v.stat(path); err != nil {
+		return fmt.Errorf(".axerc %s: %s does not exist", key, path)
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(path, "/"), ext) {
+		return fmt.Errorf(".axerc %s: %s is not a %s", key, path, ext)
+	}
+	return nil
+}
+
+func runConfigValidate(v *configValidator) error {
+	cfg, problems := v.validate()
+	if len(problems) > 0 {
+		return errors.Join(problems...)
+	}
+
+	fmt.Println("Configuration is valid.")
+	if cfg.Workspace != "" {
+		fmt.Printf("  workspace:     %s\n", cfg.Workspace)
+	} else {
+		fmt.Printf("  project:       %s\n", cfg.Project)
+	}
+	fmt.Printf("  scheme:        %s\n", cfg.Scheme)
+	if cfg.Configuration != "" {
+		fmt.Printf("  configuration: %s\n", cfg.Configuration)
+	}
+	if cfg.Device != "" {
+		fmt.Printf("  device:        %s\n", cfg.Device)
+	}
+	if cfg.Runtime != "" {
+		fmt.Printf("  runtime:       %s\n", cfg.Runtime)
+	}
+	if cfg.Family != "" {
+		fmt.Printf("  family:        %s\n", cfg.Family)
+	}
+	if cfg.DeviceType != "" {
+		fmt.Printf("  device type:   %s\n", cfg.DeviceType)
+	}
+	if cfg.Appearance != "" {
+		fmt.Printf("  appearance:    %s\n", cfg.Appearance)
+	}
+	if cfg.Toolchain != "" {
+		fmt.Printf("  toolchain:     %s\n", cfg.Toolchain)
+	}
+	if cfg.SimctlTimeout != "" {
+		fmt.Printf("  simctl timeout: %s\n", cfg.SimctlTimeout)
+	}
+	return nil
+}
+
+const configKeysHelp = `Keys:
+  default-simulator                  UDID of the default simulator (axe preview simulator default)
+  simulator-strategy                 how axe picks a simulator to create when none is available:
+                                     latest-iphone (default) or model:<device model>,
+                                     e.g. "model:iPhone SE (3rd generation)"
+  last-used-simulator.<project path> UDID of the simulator the project or workspace at the
+                                     absolute path last ran on`
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a value from the global config",
+	Long: `Prints the value of key from the global config (~/Library/Developer/axe/config.json).
+Exits non-zero if the key is not set.
+
+` + configKeysHelp,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		e, err := newConfigEditor()
+		if err != nil {
+			return err
+		}
+		return e.get(args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a value in the global config",
+	Long: `Sets key to value in the global config (~/Library/Developer/axe/config.json).
+Simulator values must be UDIDs of simulators in the axe device set, and a
+simulator-strategy value must name a known strategy. An empty value removes
+the key.
+
+` + configKeysHelp,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		e, err := newConfigEditor()
+		if err != nil {
+			return err
+		}
+		return e.set(args[0], args[1])
+	},
+}
+
+var configListJSON bool
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the values in the global config",
+	Long: `Lists every key set in the global config (~/Library/Developer/axe/config.json)
+as key=value lines, or as a JSON object with --json.
+
+` + configKeysHelp,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		e, err := newConfigEditor()
+		if err != nil {
+			return err
+		}
+		return e.list(configListJSON)
+	},
+}
+
+// configEditor reads and writes the global config store for config get,
+// set, and list. simulatorExists checks a UDID against the axe device set
+// and is replaced by a fake in tests.
+type configEditor struct {
+	store           *platform.ConfigStore
+	simulatorExists func(udid string) error
+	out             io.Writer
+}
+
+func newConfigEditor() (*configEditor, error) {
+	store, err := platform.NewConfigStore()
+	if err != nil {
+		return nil, err
+	}
+	simctl := &platform.RealSimctlRunner{}
+	return &configEditor{
+		store: store,
+		simulatorExists: func(udid string) error {
+			managed, err := platform.ListManaged(simctl, store)
+			if err != nil {
+				return err
+			}
+			if !slices.ContainsFunc(managed, func(s platform.ManagedSimulator) bool { return s.UDID == udid }) {
+				return fmt.Errorf("%w: simulator %s not found. Run 'axe preview simulator list' to see managed simulators", platform.ErrNoSimulator, udid)
+			}
+			return nil
+		},
+		out: os.Stdout,
+	}, nil
+}
+
+// configKeyError marks an unknown or malformed key as a usage error.
+func configKeyError(err error) error {
+	if errors.Is(err, platform.ErrInvalidConfigKey) {
+		return &usageError{err: err}
+	}
+	return err
+}
+
+func (e *configEditor) get(key string) error {
+	value, err := e.store.Get(key)
+	if err != nil {
+		return configKeyError(err)
+	}
+	if value == "" {
+		return fmt.Errorf("%s is not set", key)
+	}
+	_, err = fmt.Fprintln(e.out, value)
+	return err
+}
+
+func (e *configEditor) set(key, value string) error {
+	// Check the key before touching the device set.
+	if _, err := e.store.Get(key); err != nil {
+		return configKeyError(err)
+	}
+	switch {
+	case value == "":
+	case key == platform.ConfigKeySimulatorStrategy:
+		if _, err := platform.ParseSelectionStrategy(value); err != nil {
+			return &usageError{err: fmt.Errorf("%s: %w", key, err)}
+		}
+	default:
+		if err := e.simulatorExists(value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err := e.store.Set(key, value); err != nil {
+		return err
+	}
+	if value == "" {
+		_, err := fmt.Fprintf(e.out, "Removed %s.\n", key)
+		return err
+	}
+	_, err := fmt.Fprintf(e.out, "Set %s to %s.\n", key, value)
+	return err
+}
+
+func (e *configEditor) list(asJSON bool) error {
+	entries, err := e.store.Entries()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		m := make(map[string]string, len(entries))
+		for _, en := range entries {
+			m[en.Key] = en.Value
+		}
+		enc := json.NewEncoder(e.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	}
+	for _, en := range entries {
+		if _, err := fmt.Fprintf(e.out, "%s=%s\n", en.Key, en.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	configListCmd.Flags().BoolVar(&configListJSON, "json", false, "output as JSON")
+	configCmd.AddCommand(configValidateCmd, configGetCmd, configSetCmd, configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/k-kohey/axe/internal/platform"
+)
+
+// newFakeConfigValidator returns a validator over rc where only the given
+// paths exist, nothing is auto-detected, and only the given simulators and
+// runtimes resolve.
+func newFakeConfigValidator(rc map[string]string, paths, simulators, runtimes []string) *configValidator {
+	return &configValidator{
+		readRC:        func() map[string]string { return rc },
+		detectProject: func() (string, string) { return "", "" },
+		stat: func(name string) (os.FileInfo, error) {
+			if slices.Contains(paths, name) {
+				return nil, nil
+			}
+			return nil, os.ErrNotExist
+		},
+		findSimulator: func(udid string) error {
+			if slices.Contains(simulators, udid) {
+				return nil
+			}
+			return fmt.Errorf("%w: simulator %s not found", platform.ErrNoSimulator, udid)
+		},
+		resolveRuntime: func(name string) error {
+			if slices.Contains(runtimes, name) {
+				return nil
+			}
+			return fmt.Errorf("%w: runtime %q is not installed", platform.ErrNoSimulator, name)
+		},
+		resolveDeviceType: func(name, _ string) error {
+			if name == "iPad Air 13-inch (M2)" {
+				return nil
+			}
+			return fmt.Errorf("%w: device type %q is not available", platform.ErrNoSimulator, name)
+		},
+		defaultSimulator: func() (string, error) { return "", nil },
+	}
+}
+
+func TestConfigValidator_Valid(t *testing.T) {
+	v := newFakeConfigValidator(map[string]string{
+		"PROJECT":     "App.xcodeproj",
+		"SCHEME":      "App",
+		"DEVICE":      "AAA",
+		"RUNTIME":     "iOS 18.2",
+		"FAMILY":      "ipad",
+		"DEVICE_TYPE": "iPad Air 13-inch (M2)",
+		"APPEARANCE":  "dark",
+
+		"SIMCTL_TIMEOUT": "2m",
+	}, []string{"App.xcodeproj"}, []string{"AAA"}, []string{"iOS 18.2"})
+
+	cfg, problems := v.validate()
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if cfg.Project != "App.xcodeproj" || cfg.Scheme != "App" || cfg.Device != "AAA" || cfg.Family != "iPad" || cfg.Appearance != "dark" || cfg.SimctlTimeout != "2m" {
+		t.Errorf("unexpected resolved config: %+v", cfg)
+	}
+}
+
+func TestConfigValidator_ReportsAllProblems(t *testing.T) {
+	v := newFakeConfigValidator(map[string]string{
+		"PROJECT":     "Missing.xcodeproj",
+		"WORKSPACE":   "App.xcodeproj", // exists, but is not a workspace
+		"DEVCE":       "typo",
+		"DEVICE":      "GONE",
+		"RUNTIME":     "iOS 9.0",
+		"FAMILY":      "watch",
+		"DEVICE_TYPE": "iPhone 99",
+		"APPEARANCE":  "dim",
+
+		"SIMCTL_TIMEOUT": "30",
+	}, []string{"App.xcodeproj"}, nil, []string{"iOS 18.2"})
+
+	_, problems := v.validate()
+	want := []string{
+		"unknown key DEVCE",
+		"PROJECT and WORKSPACE are mutually exclusive",
+		"Missing.xcodeproj does not exist",
+		"App.xcodeproj is not a .xcworkspace",
+		"SCHEME is not set",
+		`runtime "iOS 9.0" is not installed`,
+		`FAMILY: unknown device family "watch"`,
+		`DEVICE_TYPE: no available simulator: device type "iPhone 99" is not available`,
+		`APPEARANCE: invalid appearance "dim"`,
+		`SIMCTL_TIMEOUT: invalid timeout "30"`,
+		"DEVICE: no available simulator: simulator GONE not found",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(problems), len(want), errors.Join(problems...))
+	}
+	for i, w := range want {
+		if !strings.Contains(problems[i].Error(), w) {
+			t.Errorf("problem %d = %q, want it to contain %q", i, problems[i], w)
+		}
+	}
+
+	// All problems are reported together and the command fails.
+	err := runConfigValidate(v)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error does not mention %q:\n%v", w, err)
+		}
+	}
+	if got := exitCode(err); got != exitConfigMissing {
+		t.Errorf("exitCode = %d, want %d", got, exitConfigMissing)
+	}
+}
+
+func TestConfigValidator_StaleDefaultSimulator(t *testing.T) {
+	v := newFakeConfigValidator(map[string]string{"SCHEME": "App"}, nil, nil, nil)
+	v.detectProject = func() (string, string) { return "", "App.xcworkspace" }
+	v.defaultSimulator = func() (string, error) { return "DELETED", nil }
+
+	cfg, problems := v.validate()
+	if cfg.Workspace != "App.xcworkspace" {
+		t.Errorf("Workspace = %q, want auto-detected App.xcworkspace", cfg.Workspace)
+	}
+	if len(problems) != 1 || !errors.Is(problems[0], platform.ErrNoSimulator) {
+		t.Fatalf("expected a single ErrNoSimulator problem, got %v", problems)
+	}
+}
+
+// newTestConfigEditor returns an editor over a temp config store in which
+// only the given simulators exist.
+func newTestConfigEditor(t *testing.T, simulators ...string) (*configEditor, *strings.Builder) {
+	t.Helper()
+	out := &strings.Builder{}
+	return &configEditor{
+		store: platform.NewConfigStoreWithPath(filepath.Join(t.TempDir(), "config.json")),
+		simulatorExists: func(udid string) error {
+			if slices.Contains(simulators, udid) {
+				return nil
+			}
+			return fmt.Errorf("%w: simulator %s not found", platform.ErrNoSimulator, udid)
+		},
+		out: out,
+	}, out
+}
+
+func TestConfigEditor_SetGetRoundTrip(t *testing.T) {
+	e, out := newTestConfigEditor(t, "AAA", "BBB")
+
+	if err := e.set("default-simulator", "AAA"); err != nil {
+		t.Fatalf("set default-simulator: %v", err)
+	}
+	if err := e.set("last-used-simulator./work/App.xcodeproj", "BBB"); err != nil {
+		t.Fatalf("set last-used-simulator: %v", err)
+	}
+	out.Reset()
+
+	if err := e.get("default-simulator"); err != nil {
+		t.Fatalf("get default-simulator: %v", err)
+	}
+	if err := e.get("last-used-simulator./work/App.xcodeproj"); err != nil {
+		t.Fatalf("get last-used-simulator: %v", err)
+	}
+	if got, want := out.String(), "AAA\nBBB\n"; got != want {
+		t.Errorf("get output = %q, want %q", got, want)
+	}
+
+	// An empty value removes the key.
+	if err := e.set("default-simulator", ""); err != nil {
+		t.Fatalf("clearing default-simulator: %v", err)
+	}
+	if err := e.get("default-simulator"); err == nil || !strings.Contains(err.Error(), "not set") {
+		t.Errorf("get after clearing = %v, want a not set error", err)
+	}
+}
+
+func TestConfigEditor_SetRejectsMissingSimulator(t *testing.T) {
+	e, _ := newTestConfigEditor(t, "AAA")
+
+	err := e.set("default-simulator", "ZZZ")
+	if !errors.Is(err, platform.ErrNoSimulator) {
+		t.Fatalf("err = %v, want ErrNoSimulator", err)
+	}
+	if v, _ := e.store.GetDefault(); v != "" {
+		t.Errorf("default simulator = %q after a rejected set, want empty", v)
+	}
+}
+
+func TestConfigEditor_SetSimulatorStrategy(t *testing.T) {
+	e, out := newTestConfigEditor(t)
+
+	if err := e.set("simulator-strategy", "model:iPhone SE (3rd generation)"); err != nil {
+		t.Fatalf("set simulator-strategy: %v", err)
+	}
+	out.Reset()
+	if err := e.get("simulator-strategy"); err != nil {
+		t.Fatalf("get simulator-strategy: %v", err)
+	}
+	if got, want := out.String(), "model:iPhone SE (3rd generation)\n"; got != want {
+		t.Errorf("get output = %q, want %q", got, want)
+	}
+
+	err := e.set("simulator-strategy", "cheapest")
+	var ue *usageError
+	if !errors.As(err, &ue) {
+		t.Errorf("set unknown strategy: err = %v, want a usage error", err)
+	}
+	if v, _ := e.store.Get("simulator-strategy"); v != "model:iPhone SE (3rd generation)" {
+		t.Errorf("strategy = %q after a rejected set, want it unchanged", v)
+	}
+}
+
+func TestConfigEditor_InvalidKeys(t *testing.T) {
+	e, _ := newTestConfigEditor(t, "AAA")
+
+	for _, key := range []string{"default-device", "last-used-simulator.relative/App.xcodeproj"} {
+		err := e.set(key, "AAA")
+		var ue *usageError
+		if !errors.As(err, &ue) {
+			t.Errorf("set %s: err = %v, want a usage error", key, err)
+		}
+		if err := e.get(key); !errors.As(err, &ue) {
+			t.Errorf("get %s: err = %v, want a usage error", key, err)
+		}
+	}
+}
+
+func TestConfigEditor_List(t *testing.T) {
+	e, out := newTestConfigEditor(t, "AAA", "BBB")
+	if err := e.set("last-used-simulator./work/App.xcodeproj", "BBB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.set("default-simulator", "AAA"); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := e.list(false); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got, want := out.String(), "default-simulator=AAA\nlast-used-simulator./work/App.xcodeproj=BBB\n"; got != want {
+		t.Errorf("list = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := e.list(true); err != nil {
+		t.Fatalf("list --json: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("list --json output is not JSON: %v\n%s", err, out)
+	}
+	want := map[string]string{"default-simulator": "AAA", "last-used-simulator./work/App.xcodeproj": "BBB"}
+	if !maps.Equal(got, want) {
+		t.Errorf("list --json = %v, want %v", got, want)
+	}
+}
+
+func TestConfigEditor_ListEmpty(t *testing.T) {
+	e, out := newTestConfigEditor(t)
+
+	if err := e.list(true); err != nil {
+		t.Fatalf("list --json: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "{}" {
+		t.Errorf("list --json on an empty config = %q, want {}", got)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the tools axe needs are installed",
+	Long: `Checks the environment axe preview runs in: that xcode-select points to a
+full Xcode rather than only the Command Line Tools, and that idb_companion
+is in PATH. Every check runs, and each problem is reported with how to fix it.
+
+Exits non-zero if any check fails.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runDoctor(defaultDoctorChecks(cmd.Context()), os.Stdout)
+	},
+}
+
+// doctorCheck is one check of axe doctor. run is replaced by a fake in
+// tests.
+type doctorCheck struct {
+	name string
+	run  func() error
+}
+
+func defaultDoctorChecks(ctx context.Context) []doctorCheck {
+	return []doctorCheck{
+		{name: "Xcode", run: func() error { return platform.CheckXcode(ctx) }},
+		{name: "idb_companion", run: platform.CheckIDBCompanion},
+	}
+}
+
+// runDoctor runs every check, prints one line per check to out, and
+// returns the failures joined.
+func runDoctor(checks []doctorCheck, out io.Writer) error {
+	var problems []error
+	for _, c := range checks {
+		if err := c.run(); err != nil {
+			problems = append(problems, err)
+			if _, err := fmt.Fprintf(out, "  %-14s failed\n", c.name); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "  %-14s ok\n", c.name); err != nil {
+			return err
+		}
+	}
+	return errors.Join(problems...)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
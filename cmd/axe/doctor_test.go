@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/k-kohey/axe/internal/platform"
+)
+
+func TestRunDoctor_CommandLineToolsOnly(t *testing.T) {
+	checks := []doctorCheck{
+		{name: "Xcode", run: func() error {
+			return platform.CheckXcodeSelectOutput("/Library/Developer/CommandLineTools\n")
+		}},
+		{name: "idb_companion", run: func() error { return nil }},
+	}
+
+	var out strings.Builder
+	err := runDoctor(checks, &out)
+	if !errors.Is(err, platform.ErrFullXcodeNotSelected) {
+		t.Fatalf("err = %v, want ErrFullXcodeNotSelected", err)
+	}
+	if !strings.Contains(err.Error(), "sudo xcode-select -s") {
+		t.Errorf("error %q does not say how to fix it", err)
+	}
+	if got, want := out.String(), "  Xcode          failed\n  idb_companion  ok\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRunDoctor_AllChecksRun(t *testing.T) {
+	checks := []doctorCheck{
+		{name: "Xcode", run: func() error {
+			return platform.CheckXcodeSelectOutput("/Applications/Xcode.app/Contents/Developer\n")
+		}},
+		{name: "idb_companion", run: func() error { return platform.ErrIDBCompanionNotFound }},
+	}
+
+	var out strings.Builder
+	err := runDoctor(checks, &out)
+	if errors.Is(err, platform.ErrFullXcodeNotSelected) {
+		t.Errorf("full Xcode reported as a problem: %v", err)
+	}
+	if got := exitCode(err); got != exitIDBMissing {
+		t.Errorf("exitCode = %d, want %d", got, exitIDBMissing)
+	}
+	if got, want := out.String(), "  Xcode          ok\n  idb_companion  failed\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview"
+	"github.com/k-kohey/axe/internal/preview/build"
+)
+
+// Exit codes returned by the axe CLI. These values are part of the public
+// contract for scripts and must not be renumbered.
+const (
+	exitOK            = 0
+	exitInternal      = 1 // unclassified failure
+	exitUsage         = 2 // invalid flags or arguments
+	exitConfigMissing = 3 // project/workspace/scheme unresolved, or Xcode / device set unusable
+	exitIDBMissing    = 4 // idb_companion not found in PATH
+	exitNoSimulator   = 5 // no usable simulator found
+	exitBuildFailed   = 6 // xcodebuild failed
+	exitBootFailed    = 7 // simulator failed to boot
+	exitTimeout       = 8 // an operation exceeded its deadline
+	exitBuildWarnings = 9 // build succeeded with warnings (--fail-on-warning)
+)
+
+// errConfigMissing is returned when the project configuration cannot be
+// resolved from flags, auto-detection, or .axerc.
+var errConfigMissing = errors.New("project configuration missing")
+
+// usageError marks an error caused by invalid command-line input.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// exitCode maps an error returned by a command to the CLI exit code.
+// The first matching category wins, so more specific failures are checked
+// before the generic timeout.
+func exitCode(err error) int {
+	var ue *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, errConfigMissing), errors.Is(err, platform.ErrFullXcodeNotSelected),
+		errors.Is(err, platform.ErrUnsupportedDeviceSetFS):
+		return exitConfigMissing
+	case errors.Is(err, platform.ErrIDBCompanionNotFound):
+		return exitIDBMissing
+	case errors.Is(err, platform.ErrNoSimulator):
+		return exitNoSimulator
+	case errors.Is(err, build.ErrBuildFailed):
+		return exitBuildFailed
+	case errors.Is(err, build.ErrBuildWarnings):
+		return exitBuildWarnings
+	case errors.Is(err, preview.ErrBootFailed):
+		return exitBootFailed
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	default:
+		return exitInternal
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview"
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/spf13/cobra"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"generic", errors.New("boom"), exitInternal},
+		{"usage", &usageError{err: errors.New("bad flag")}, exitUsage},
+		{"config missing", fmt.Errorf("%w: --scheme is required", errConfigMissing), exitConfigMissing},
+		{"xcode not selected", fmt.Errorf("preamble: %w", platform.ErrFullXcodeNotSelected), exitConfigMissing},
+		{"device set fs", fmt.Errorf("device set: %w", platform.ErrUnsupportedDeviceSetFS), exitConfigMissing},
+		{"idb missing", fmt.Errorf("preamble: %w", platform.ErrIDBCompanionNotFound), exitIDBMissing},
+		{"no simulator", fmt.Errorf("resolve: %w", platform.ErrNoSimulator), exitNoSimulator},
+		{"build failed", fmt.Errorf("build: %w", build.ErrBuildFailed), exitBuildFailed},
+		{"build warnings", fmt.Errorf("%w: 2 warning(s)", build.ErrBuildWarnings), exitBuildWarnings},
+		{"boot failed", fmt.Errorf("booting simulator: %w", preview.ErrBootFailed), exitBootFailed},
+		{"timeout", fmt.Errorf("simctl: %w", context.DeadlineExceeded), exitTimeout},
+		// A boot that timed out is reported as a boot failure, not a generic timeout.
+		{"boot timeout", fmt.Errorf("%w: %w", preview.ErrBootFailed, context.DeadlineExceeded), exitBootFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExitCode_FailOnWarning verifies that --fail-on-warning turns a
+// warning in the xcodebuild output into a non-zero exit code.
+func TestExitCode_FailOnWarning(t *testing.T) {
+	out := "/tmp/App/View.swift:7:13: warning: 'foregroundColor' is deprecated\n** BUILD SUCCEEDED **\n"
+	err := build.CheckWarnings(io.Discard, build.Warnings(out))
+	if got := exitCode(err); got != exitBuildWarnings {
+		t.Errorf("exitCode = %d, want %d (err: %v)", got, exitBuildWarnings, err)
+	}
+}
+
+func TestValidateThunkFlags_UsageError(t *testing.T) {
+	err := validateThunkFlags(-1, 0)
+	if got := exitCode(err); got != exitUsage {
+		t.Errorf("exitCode = %d, want %d (err: %v)", got, exitUsage, err)
+	}
+}
+
+func TestUsageArgs(t *testing.T) {
+	root := &cobra.Command{Use: "axe", SilenceUsage: true, SilenceErrors: true}
+	root.AddCommand(&cobra.Command{Use: "get", Args: cobra.ExactArgs(1), RunE: func(*cobra.Command, []string) error { return nil }})
+	usageArgs(root)
+
+	for _, args := range [][]string{{"get"}, {"get", "a", "b"}} {
+		root.SetArgs(args)
+		if got := exitCode(root.Execute()); got != exitUsage {
+			t.Errorf("exit code for %q = %d, want %d", args, got, exitUsage)
+		}
+	}
+	root.SetArgs([]string{"get", "a"})
+	if err := root.Execute(); err != nil {
+		t.Errorf("valid arguments: %v", err)
+	}
+}
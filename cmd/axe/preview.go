@@ -3,11 +3,19 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/k-kohey/axe/internal/platform"
 	"github.com/k-kohey/axe/internal/preview"
+	"github.com/k-kohey/axe/internal/preview/analysis"
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/k-kohey/axe/internal/preview/codegen"
+	"github.com/k-kohey/axe/internal/preview/protocol"
 	"github.com/spf13/cobra"
 )
 
@@ -18,13 +26,54 @@ var (
 	previewScheme        string
 	previewConfiguration string
 	previewDevice        string
+	previewRuntime       string
+	previewFamily        string
+	previewDeviceType    string
+	previewToolchain     string
+	previewApp           string
+
+	previewDynamicType      string
+	previewBoldText         bool
+	previewIncreaseContrast bool
+	previewAppearance       string
+	previewLocale           string
+	previewRegion           string
+	previewLanguage         string
+	previewLayout           string
+	previewSizeClass        string
+
+	previewMockSources  []string
+	previewThunkImports []string
+	previewSeedDir      string
+	previewKeepThunk    bool
+	previewBootTimeout  time.Duration
+
+	// previewInitArgs is bound by both preview and preview watch.
+	previewInitArgs string
 )
 
+// initArgsUsage is the help of --init-args, shared by preview and preview watch.
+const initArgsUsage = "Swift expression constructing the view to preview instead of its #Preview body, e.g. 'MyView(title: \"Hi\")'; needed for views without a #Preview"
+
 // Oneshot-specific flags.
 var (
-	previewSelector   string
-	previewReuseBuild bool
-	previewFullThunk  bool
+	previewSelector       string
+	previewReuseBuild     bool
+	previewNoReuse        bool
+	previewFullThunk      bool
+	previewPID            int
+	previewGitRef         string
+	previewRecord         string
+	previewRecordDuration time.Duration
+	previewFailOnWarning  bool
+	previewFresh          bool
+)
+
+// Status bar flags shared by oneshot and report (screenshot modes).
+var (
+	previewCleanStatusBar   bool
+	previewStatusBarTime    string
+	previewStatusBarBattery int
 )
 
 var previewCmd = &cobra.Command{
@@ -41,7 +90,16 @@ var previewCmd = &cobra.Command{
 	  3. PROJECT / WORKSPACE in .axerc
 
 	By default the command runs in oneshot mode: build, launch, capture a screenshot
-	to stdout (PNG), then clean up and exit. Exit 0 on success, exit 1 on failure.
+	to stdout (PNG), then clean up and exit. With --bench it instead runs that pipeline
+	--bench-iterations times (one cold run, then warm runs that reuse the build) and
+	prints how long each phase took.
+
+	Exit codes:
+	  0 success            5 no simulator available
+	  1 internal error     6 build failed
+	  2 usage error        7 simulator boot failed
+	  3 config missing     8 timeout
+	  4 idb_companion missing  9 build warnings (--fail-on-warning)
 
 	Subcommands:
 	  build     — build the project (xcodebuild phase only)
@@ -53,7 +111,25 @@ var previewCmd = &cobra.Command{
 	Requires idb_companion (install via: brew install facebook/fb/idb-companion).`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runOneshotLogic(args[0])
+		if previewPID != 0 && previewBench {
+			return &usageError{err: fmt.Errorf("--pid cannot be combined with --bench")}
+		}
+		if previewGitRef != "" && (previewPID != 0 || previewBench) {
+			return &usageError{err: fmt.Errorf("--git-ref cannot be combined with --pid or --bench")}
+		}
+		if previewRecord != "" && previewBench {
+			return &usageError{err: fmt.Errorf("--record cannot be combined with --bench")}
+		}
+		if previewFailOnWarning && (previewPID != 0 || previewBench || previewReuseBuild) {
+			return &usageError{err: fmt.Errorf("--fail-on-warning needs a build and cannot be combined with --pid, --bench, or --reuse-build")}
+		}
+		if previewFresh && (previewPID != 0 || previewBench) {
+			return &usageError{err: fmt.Errorf("--fresh cannot be combined with --pid or --bench")}
+		}
+		if previewBench {
+			return runBenchLogic(args[0], cmd.Flags().Changed("device"))
+		}
+		return runOneshotLogic(args[0], cmd.Flags().Changed("device"))
 	},
 }
 
@@ -69,37 +145,163 @@ func resolveSourceFile(sourceArg string) (string, error) {
 	return sourceFile, nil
 }
 
-// previewPreamble resolves project config and checks for idb_companion.
-// Common setup shared by oneshot, watch, and serve modes.
+// previewPreamble resolves project config and checks for a full Xcode,
+// idb_companion, and enough free disk space, then resolves the build
+// configuration. Common setup shared by oneshot, watch, and serve modes.
 func previewPreamble() (preview.ProjectConfig, error) {
 	pc, err := resolveProjectConfig()
 	if err != nil {
 		return pc, err
 	}
+	if err := platform.CheckXcode(context.Background()); err != nil {
+		return pc, err
+	}
 	if err := platform.CheckIDBCompanion(); err != nil {
 		return pc, err
 	}
-	return pc, nil
+	if err := checkDiskSpace(); err != nil {
+		return pc, err
+	}
+	return resolveConfiguration(pc), nil
 }
 
-// runOneshotLogic executes a single preview capture (PNG to stdout).
-func runOneshotLogic(sourceArg string) error {
-	pc, err := previewPreamble()
+// resolveConfiguration fills in the scheme's default build configuration
+// when neither --configuration nor .axerc CONFIGURATION sets one, so that
+// build directories are keyed on the configuration actually built.
+func resolveConfiguration(pc preview.ProjectConfig) preview.ProjectConfig {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return build.ResolveConfiguration(ctx, pc, build.NewRunner())
+}
+
+// checkDiskSpace checks the free space of the volumes holding the axe
+// device set and the build cache before anything is built or booted.
+func checkDiskSpace() error {
+	deviceSet, err := platform.AxeDeviceSetPath()
 	if err != nil {
 		return err
 	}
-	sourceFile, err := resolveSourceFile(sourceArg)
+	return platform.CheckDiskSpace(deviceSet, build.CacheRoot())
+}
+
+// accessibilityOverrides builds the simulator overrides from the common
+// --dynamic-type, --bold-text, --increase-contrast, --appearance, --locale,
+// --region, and --language flags.
+func accessibilityOverrides() (platform.AccessibilityOverrides, error) {
+	a := platform.AccessibilityOverrides{
+		DynamicType:      previewDynamicType,
+		BoldText:         previewBoldText,
+		IncreaseContrast: previewIncreaseContrast,
+		Appearance:       previewAppearance,
+		Locale:           previewLocale,
+		Region:           previewRegion,
+		Language:         previewLanguage,
+	}
+	if err := a.Validate(); err != nil {
+		return a, &usageError{err: err}
+	}
+	return a, nil
+}
+
+// layoutOverride builds the iPad layout from the --layout and --size-class
+// flags.
+func layoutOverride() (codegen.Layout, error) {
+	l := codegen.Layout{Name: previewLayout, SizeClass: previewSizeClass}
+	if err := l.Validate(); err != nil {
+		return l, &usageError{err: err}
+	}
+	return l, nil
+}
+
+// statusBarOverride builds the status bar override from the --clean-status-bar
+// flags. Returns nil when --clean-status-bar is not set.
+func statusBarOverride() (*platform.StatusBarOverride, error) {
+	if !previewCleanStatusBar {
+		return nil, nil
+	}
+	o := platform.StatusBarOverride{
+		Time:         previewStatusBarTime,
+		BatteryLevel: previewStatusBarBattery,
+	}
+	if err := o.Validate(); err != nil {
+		return nil, &usageError{err: err}
+	}
+	return &o, nil
+}
+
+// mockSources resolves the --mock flag values to absolute .swift paths.
+func mockSources() ([]string, error) {
+	var paths []string
+	for _, m := range previewMockSources {
+		if filepath.Ext(m) != ".swift" {
+			return nil, &usageError{err: fmt.Errorf("--mock: %s is not a .swift file", m)}
+		}
+		p, err := filepath.Abs(m)
+		if err != nil {
+			return nil, fmt.Errorf("resolving mock source path: %w", err)
+		}
+		if _, err := os.Stat(p); err != nil {
+			return nil, &usageError{err: fmt.Errorf("--mock: source file not found: %s", p)}
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// swiftModuleName matches a Swift module name, optionally with submodules
+// (e.g. "DSKit" or "Darwin.C").
+var swiftModuleName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// thunkImports validates the --thunk-import flag values.
+func thunkImports() ([]string, error) {
+	for _, m := range previewThunkImports {
+		if !swiftModuleName.MatchString(m) {
+			return nil, &usageError{err: fmt.Errorf("--thunk-import: %q is not a module name", m)}
+		}
+	}
+	return previewThunkImports, nil
+}
+
+// initArgs validates the --init-args flag, which replaces the preview
+// that selector would pick.
+func initArgs(selector string) (string, error) {
+	if previewInitArgs == "" {
+		return "", nil
+	}
+	if selector != "" {
+		return "", &usageError{err: fmt.Errorf("--init-args cannot be combined with --preview")}
+	}
+	if err := codegen.ValidateInitArgs(previewInitArgs); err != nil {
+		return "", &usageError{err: err}
+	}
+	return previewInitArgs, nil
+}
+
+// seedDir resolves the --seed flag to an absolute directory path.
+// Returns "" when the flag is not set.
+func seedDir() (string, error) {
+	if previewSeedDir == "" {
+		return "", nil
+	}
+	p, err := filepath.Abs(previewSeedDir)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("resolving seed directory: %w", err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", &usageError{err: fmt.Errorf("--seed: directory not found: %s", p)}
 	}
+	if !info.IsDir() {
+		return "", &usageError{err: fmt.Errorf("--seed: %s is not a directory", p)}
+	}
+	return p, nil
+}
 
-	opts := preview.RunOptions{
-		SourceFile:      sourceFile,
-		PC:              pc,
-		PreviewSelector: previewSelector,
-		PreferredDevice: previewDevice,
-		ReuseBuild:      previewReuseBuild,
-		FullThunk:       previewFullThunk,
+// runOneshotLogic executes a single preview capture (PNG to stdout).
+func runOneshotLogic(sourceArg string, deviceFlagSet bool) error {
+	opts, err := oneshotOptions(sourceArg, deviceFlagSet)
+	if err != nil {
+		return err
 	}
 	opts.OnReady = func(ctx context.Context, device, deviceSetPath string) error {
 		data, err := platform.Screenshot(ctx, device, deviceSetPath)
@@ -110,25 +312,224 @@ func runOneshotLogic(sourceArg string) error {
 		return err
 	}
 
+	if previewPID != 0 {
+		if previewPID < 0 {
+			return &usageError{err: fmt.Errorf("--pid must be a positive process ID, got %d", previewPID)}
+		}
+		return preview.RunAttach(opts, previewPID)
+	}
+	if previewGitRef != "" {
+		return preview.RunGitRef(opts, previewGitRef)
+	}
 	return preview.Run(opts)
 }
 
+// recordPath validates --record and --record-duration and returns the
+// absolute path to record to ("" when not recording). Oneshot mode has no
+// session to record until, so it sets requireDuration.
+func recordPath(path string, duration time.Duration, requireDuration bool) (string, error) {
+	if duration < 0 {
+		return "", &usageError{err: fmt.Errorf("--record-duration must not be negative, got %s", duration)}
+	}
+	if path == "" {
+		if duration > 0 {
+			return "", &usageError{err: fmt.Errorf("--record-duration requires --record")}
+		}
+		return "", nil
+	}
+	if requireDuration && duration == 0 {
+		return "", &usageError{err: fmt.Errorf("--record requires --record-duration in oneshot mode (or record a session with axe preview watch --record)")}
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(filepath.Dir(abs)); err != nil || !info.IsDir() {
+		return "", &usageError{err: fmt.Errorf("--record: directory not found: %s", filepath.Dir(abs))}
+	}
+	return abs, nil
+}
+
+// oneshotOptions builds the oneshot RunOptions from the common and
+// oneshot-specific flags. deviceFlagSet reports whether --device was given.
+func oneshotOptions(sourceArg string, deviceFlagSet bool) (preview.RunOptions, error) {
+	if err := validatePreviewSelector(previewSelector, sourceArg); err != nil {
+		return preview.RunOptions{}, err
+	}
+	a11y, err := accessibilityOverrides()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	layout, err := layoutOverride()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	statusBar, err := statusBarOverride()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	mocks, err := mockSources()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	seed, err := seedDir()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	imports, err := thunkImports()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	initExpr, err := initArgs(previewSelector)
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	mode, err := buildMode(previewReuseBuild, previewNoReuse)
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	record, err := recordPath(previewRecord, previewRecordDuration, true)
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	pc, err := previewPreamble()
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	sourceFile, err := resolveSourceFile(sourceArg)
+	if err != nil {
+		return preview.RunOptions{}, err
+	}
+	pc.AppSource = sourceFile
+
+	opts := preview.RunOptions{
+		SourceFile:      sourceFile,
+		PC:              pc,
+		PreviewSelector: previewSelector,
+		PreferredDevice: previewDevice,
+		Runtime:         previewRuntime,
+		Family:          previewFamily,
+		DeviceType:      previewDeviceType,
+		BuildMode:       mode,
+		FullThunk:       previewFullThunk,
+		Accessibility:   a11y,
+		StatusBar:       statusBar,
+		MockSources:     mocks,
+		ThunkImports:    imports,
+		SeedDir:         seed,
+		KeepThunk:       previewKeepThunk,
+		Layout:          layout,
+		InitArgs:        initExpr,
+		BootTimeout:     previewBootTimeout,
+		Fresh:           previewFresh,
+		Record:          record,
+		RecordDuration:  previewRecordDuration,
+		FailOnWarning:   previewFailOnWarning,
+	}
+	if err := applySidecar(&opts, deviceFlagSet); err != nil {
+		return preview.RunOptions{}, err
+	}
+	return opts, nil
+}
+
+// applySidecar merges the .axpreview sidecar of opts.SourceFile, if any,
+// into opts. Flags take precedence over the sidecar; deviceFlagSet reports
+// whether --device was given.
+func applySidecar(opts *preview.RunOptions, deviceFlagSet bool) error {
+	sc, err := preview.LoadSidecar(opts.SourceFile)
+	if err != nil {
+		return &usageError{err: err}
+	}
+	if sc != nil {
+		slog.Debug("Applying preview sidecar", "path", sc.Path)
+	}
+	sc.Apply(opts, deviceFlagSet)
+	return nil
+}
+
 // validateThunkFlags checks that incremental thunk flags have valid values.
 func validateThunkFlags(maxThunkFiles, preThunkDepth int) error {
 	if maxThunkFiles < 0 {
-		return fmt.Errorf("--max-thunk-files must be >= 0 (0 = unlimited), got %d", maxThunkFiles)
+		return &usageError{err: fmt.Errorf("--max-thunk-files must be >= 0 (0 = unlimited), got %d", maxThunkFiles)}
 	}
 	if preThunkDepth < 0 {
-		return fmt.Errorf("--pre-thunk-depth must be >= 0, got %d", preThunkDepth)
+		return &usageError{err: fmt.Errorf("--pre-thunk-depth must be >= 0, got %d", preThunkDepth)}
 	}
 	return nil
 }
 
+// buildMode returns the build mode selected by --reuse-build and --no-reuse.
+func buildMode(reuse, noReuse bool) (build.Mode, error) {
+	switch {
+	case reuse && noReuse:
+		return 0, &usageError{err: fmt.Errorf("--reuse-build and --no-reuse are mutually exclusive")}
+	case reuse:
+		return build.Reuse, nil
+	case noReuse:
+		return build.Clean, nil
+	}
+	return build.Incremental, nil
+}
+
+// validatePreviewSelector rejects a --preview /regex/ that does not compile
+// and a file:line selector naming a file other than sourceArg.
+func validatePreviewSelector(selector, sourceArg string) error {
+	if err := analysis.ValidatePreviewSelector(selector); err != nil {
+		return &usageError{err: fmt.Errorf("--preview: %w", err)}
+	}
+	if file, _, ok := analysis.ParsePreviewLine(selector); ok && file != "" && !samePath(file, sourceArg) {
+		return &usageError{err: fmt.Errorf("--preview %s: line selectors must refer to the previewed file %s", selector, sourceArg)}
+	}
+	return nil
+}
+
+// samePath reports whether a and b name the same path once made absolute.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
 // runWatchLogic starts preview in watch mode with hot-reload.
-func runWatchLogic(sourceArg, selector string, reuseBuild, strict, noHeadless bool, maxThunkFiles, preThunkDepth int) error {
+func runWatchLogic(sourceArg, selector string, reuseBuild, noReuse, strict, noHeadless, logs bool, maxThunkFiles, preThunkDepth int, record string, recordDuration time.Duration, deviceFlagSet bool) error {
 	if err := validateThunkFlags(maxThunkFiles, preThunkDepth); err != nil {
 		return err
 	}
+	if err := validatePreviewSelector(selector, sourceArg); err != nil {
+		return err
+	}
+	a11y, err := accessibilityOverrides()
+	if err != nil {
+		return err
+	}
+	layout, err := layoutOverride()
+	if err != nil {
+		return err
+	}
+	mocks, err := mockSources()
+	if err != nil {
+		return err
+	}
+	seed, err := seedDir()
+	if err != nil {
+		return err
+	}
+	imports, err := thunkImports()
+	if err != nil {
+		return err
+	}
+	initExpr, err := initArgs(selector)
+	if err != nil {
+		return err
+	}
+	mode, err := buildMode(reuseBuild, noReuse)
+	if err != nil {
+		return err
+	}
+	record, err = recordPath(record, recordDuration, false)
+	if err != nil {
+		return err
+	}
 
 	pc, err := previewPreamble()
 	if err != nil {
@@ -138,31 +539,125 @@ func runWatchLogic(sourceArg, selector string, reuseBuild, strict, noHeadless bo
 	if err != nil {
 		return err
 	}
+	pc.AppSource = sourceFile
 
-	return preview.Run(preview.RunOptions{
+	opts := preview.RunOptions{
 		SourceFile:      sourceFile,
 		PC:              pc,
 		Watch:           true,
 		PreviewSelector: selector,
 		PreferredDevice: previewDevice,
-		ReuseBuild:      reuseBuild,
+		Runtime:         previewRuntime,
+		Family:          previewFamily,
+		DeviceType:      previewDeviceType,
+		BuildMode:       mode,
 		Strict:          strict,
 		NoHeadless:      noHeadless,
 		MaxThunkFiles:   maxThunkFiles,
 		PreThunkDepth:   preThunkDepth,
-	})
+		Accessibility:   a11y,
+		MockSources:     mocks,
+		ThunkImports:    imports,
+		SeedDir:         seed,
+		KeepThunk:       previewKeepThunk,
+		Layout:          layout,
+		InitArgs:        initExpr,
+		BootTimeout:     previewBootTimeout,
+		Fresh:           previewFresh,
+		Logs:            logs,
+		Record:          record,
+		RecordDuration:  recordDuration,
+	}
+	if err := applySidecar(&opts, deviceFlagSet); err != nil {
+		return err
+	}
+	return preview.Run(opts)
 }
 
 // runServeLogic starts preview in multi-stream serve mode.
-func runServeLogic(strict bool, maxThunkFiles, preThunkDepth int) error {
+func runServeLogic(strict, noReuse, once, previewAll, rejectDuplicates, logs bool, frameEncoding string, frameDiff bool, pngCompression string, maxThunkFiles, preThunkDepth, warmPool int, idleTimeout time.Duration, idleExit bool, reconcileInterval time.Duration, eventsFIFO string) error {
 	if err := validateThunkFlags(maxThunkFiles, preThunkDepth); err != nil {
 		return err
 	}
+	if once && previewAll {
+		return &usageError{err: fmt.Errorf("--once cannot be combined with --preview-all")}
+	}
+	if idleTimeout < 0 {
+		return &usageError{err: fmt.Errorf("--idle-timeout must not be negative, got %s", idleTimeout)}
+	}
+	if idleExit && idleTimeout == 0 {
+		return &usageError{err: fmt.Errorf("--idle-exit requires --idle-timeout")}
+	}
+	if reconcileInterval < 0 {
+		return &usageError{err: fmt.Errorf("--reconcile-interval must not be negative, got %s", reconcileInterval)}
+	}
+	if warmPool < 0 {
+		return &usageError{err: fmt.Errorf("--warm-pool must not be negative, got %d", warmPool)}
+	}
+	encoding, err := protocol.ParseFrameEncoding(frameEncoding)
+	if err != nil {
+		return &usageError{err: fmt.Errorf("--frame-encoding: %w", err)}
+	}
+	pngLevel, err := protocol.ParsePNGCompression(pngCompression)
+	if err != nil {
+		return &usageError{err: fmt.Errorf("--png-compression: %w", err)}
+	}
+	a11y, err := accessibilityOverrides()
+	if err != nil {
+		return err
+	}
+	layout, err := layoutOverride()
+	if err != nil {
+		return err
+	}
+	statusBar, err := statusBarOverride()
+	if err != nil {
+		return err
+	}
+	mocks, err := mockSources()
+	if err != nil {
+		return err
+	}
+	seed, err := seedDir()
+	if err != nil {
+		return err
+	}
+	imports, err := thunkImports()
+	if err != nil {
+		return err
+	}
 	pc, err := previewPreamble()
 	if err != nil {
 		return err
 	}
-	return preview.RunServe(pc, strict, maxThunkFiles, preThunkDepth)
+	return preview.RunServe(preview.ServeOptions{
+		PC:             pc,
+		Strict:         strict,
+		CleanBuild:     noReuse,
+		MaxThunkFiles:  maxThunkFiles,
+		PreThunkDepth:  preThunkDepth,
+		Accessibility:  a11y,
+		StatusBar:      statusBar,
+		BootTimeout:    previewBootTimeout,
+		MockSources:    mocks,
+		ThunkImports:   imports,
+		SeedDir:        seed,
+		KeepThunk:      previewKeepThunk,
+		Layout:         layout,
+		Once:           once,
+		PreviewAll:     previewAll,
+		FrameEncoding:  encoding,
+		FrameDiff:      frameDiff,
+		PNGCompression: pngLevel,
+		Logs:           logs,
+		IdleTimeout:    idleTimeout,
+		IdleExit:       idleExit,
+		WarmPool:       warmPool,
+
+		RejectDuplicateStreams: rejectDuplicates,
+		ReconcileInterval:      reconcileInterval,
+		EventsFIFO:             eventsFIFO,
+	})
 }
 
 // resolveProjectConfig resolves project settings using the following priority:
@@ -179,6 +674,7 @@ func resolveProjectConfig() (preview.ProjectConfig, error) {
 	workspace := previewWorkspace
 	scheme := previewScheme
 	configuration := previewConfiguration
+	toolchain := previewToolchain
 	device := previewDevice
 
 	// Priority 2: auto-detect from current directory when flags are not set.
@@ -213,11 +709,30 @@ func resolveProjectConfig() (preview.ProjectConfig, error) {
 	if configuration == "" && rc["CONFIGURATION"] != "" {
 		configuration = rc["CONFIGURATION"]
 	}
+	if toolchain == "" && rc["TOOLCHAIN"] != "" {
+		toolchain = rc["TOOLCHAIN"]
+	}
 	if device == "" && rc["DEVICE"] != "" {
 		device = rc["DEVICE"]
 		// Write back so that subcommand logic can reference previewDevice.
 		previewDevice = device
 	}
+	if previewRuntime == "" && rc["RUNTIME"] != "" {
+		// Write back so that subcommand logic can reference previewRuntime.
+		previewRuntime = rc["RUNTIME"]
+	}
+	if previewFamily == "" && rc["FAMILY"] != "" {
+		// Write back so that subcommand logic can reference previewFamily.
+		previewFamily = rc["FAMILY"]
+	}
+	if previewDeviceType == "" && rc["DEVICE_TYPE"] != "" {
+		// Write back so that subcommand logic can reference previewDeviceType.
+		previewDeviceType = rc["DEVICE_TYPE"]
+	}
+	if previewAppearance == "" && rc["APPEARANCE"] != "" {
+		// Write back so that accessibilityOverrides can reference previewAppearance.
+		previewAppearance = rc["APPEARANCE"]
+	}
 	// Write back scheme so that subcommand logic can reference previewScheme.
 	if previewScheme == "" && scheme != "" {
 		previewScheme = scheme
@@ -227,13 +742,23 @@ func resolveProjectConfig() (preview.ProjectConfig, error) {
 		return preview.ProjectConfig{}, fmt.Errorf("--project and --workspace are mutually exclusive")
 	}
 	if project == "" && workspace == "" {
-		return preview.ProjectConfig{}, fmt.Errorf("either --project or --workspace is required. Place a single .xcodeproj or .xcworkspace in the current directory, or set PROJECT/WORKSPACE in .axerc")
+		return preview.ProjectConfig{}, fmt.Errorf("%w: either --project or --workspace is required. Place a single .xcodeproj or .xcworkspace in the current directory, or set PROJECT/WORKSPACE in .axerc", errConfigMissing)
 	}
 	if scheme == "" {
-		return preview.ProjectConfig{}, fmt.Errorf("--scheme is required. Use the flag or set SCHEME in .axerc")
+		return preview.ProjectConfig{}, fmt.Errorf("%w: --scheme is required. Use the flag or set SCHEME in .axerc", errConfigMissing)
+	}
+
+	if err := build.ValidateToolchain(toolchain); err != nil {
+		return preview.ProjectConfig{}, &usageError{err: err}
+	}
+	if _, err := platform.ParseDeviceFamily(previewFamily); err != nil {
+		return preview.ProjectConfig{}, &usageError{err: err}
 	}
 
-	return preview.NewProjectConfig(project, workspace, scheme, configuration)
+	pc, err := preview.NewProjectConfig(project, workspace, scheme, configuration)
+	pc.Toolchain = toolchain
+	pc.App = previewApp
+	return pc, err
 }
 
 func init() {
@@ -242,12 +767,42 @@ func init() {
 	previewCmd.PersistentFlags().StringVar(&previewWorkspace, "workspace", "", "path to .xcworkspace")
 	previewCmd.PersistentFlags().StringVar(&previewScheme, "scheme", "", "Xcode scheme to build")
 	previewCmd.PersistentFlags().StringVar(&previewConfiguration, "configuration", "", "build configuration (e.g. Debug, Release)")
+	previewCmd.PersistentFlags().StringVar(&previewToolchain, "toolchain", "", "Swift toolchain identifier used for xcodebuild and the thunk compile, e.g. org.swift.600202409101a (overrides .axerc TOOLCHAIN)")
+	previewCmd.PersistentFlags().StringVar(&previewApp, "app-product", "", "PRODUCT_NAME of the app to launch when the scheme builds several (defaults to the app whose target contains the source file)")
 	previewCmd.PersistentFlags().StringVar(&previewDevice, "device", "", "simulator UDID to use for preview (overrides .axerc DEVICE and global default)")
+	previewCmd.PersistentFlags().StringVar(&previewRuntime, "runtime", "", "only reuse or create simulators on this runtime, e.g. \"iOS 18.2\" (overrides .axerc RUNTIME)")
+	previewCmd.PersistentFlags().StringVar(&previewFamily, "family", "", "only reuse or create simulators of this device family: iPhone or iPad (overrides .axerc FAMILY)")
+	previewCmd.PersistentFlags().StringVar(&previewDeviceType, "device-type", "", "only reuse or create simulators of this device type, e.g. \"iPhone 15\" (overrides .axerc DEVICE_TYPE)")
+	previewCmd.PersistentFlags().StringVar(&previewDynamicType, "dynamic-type", "", "render with the given Dynamic Type size (e.g. large, accessibility-extra-large)")
+	previewCmd.PersistentFlags().BoolVar(&previewBoldText, "bold-text", false, "render with the Bold Text accessibility setting enabled")
+	previewCmd.PersistentFlags().BoolVar(&previewIncreaseContrast, "increase-contrast", false, "render with the Increase Contrast accessibility setting enabled")
+	previewCmd.PersistentFlags().StringVar(&previewAppearance, "appearance", "", "render in the given appearance: light or dark (overrides .axerc APPEARANCE)")
+	previewCmd.PersistentFlags().StringVar(&previewLocale, "locale", "", "render with the given language and locale (e.g. ja_JP, en_GB, zh-Hant-TW)")
+	previewCmd.PersistentFlags().StringVar(&previewRegion, "region", "", "render with the given region (e.g. JP, 419), overriding the region of --locale")
+	previewCmd.PersistentFlags().StringVar(&previewLanguage, "language", "", "render with the given UI language (e.g. ja, en-GB, zh-Hant), overriding the language of --locale")
+	previewCmd.PersistentFlags().StringVar(&previewLayout, "layout", "", "render in an iPad multitasking layout: "+strings.Join(codegen.LayoutNames(), ", "))
+	previewCmd.PersistentFlags().StringVar(&previewSizeClass, "size-class", "", "horizontal size class injected into the preview: compact or regular (defaults to the --layout's size class)")
+	previewCmd.PersistentFlags().DurationVar(&previewBootTimeout, "boot-timeout", platform.DefaultBootTimeout, "how long to wait for the simulator to finish booting")
+	previewCmd.PersistentFlags().StringArrayVar(&previewMockSources, "mock", nil, "preview-only .swift file compiled into the thunk (repeatable), e.g. to supply mock data")
+	previewCmd.PersistentFlags().StringArrayVar(&previewThunkImports, "thunk-import", nil, "module imported by the generated thunk in addition to the source file's imports (repeatable)")
+	previewCmd.PersistentFlags().StringVar(&previewSeedDir, "seed", "", "directory copied into the app's data container before every launch (e.g. Documents/, Library/Application Support/)")
+	previewCmd.PersistentFlags().BoolVar(&previewKeepThunk, "keep-thunk", false, "keep the generated thunk sources in the session's thunk dir and log their paths with the swiftc command")
 
 	// Oneshot-specific flags.
-	previewCmd.Flags().StringVar(&previewSelector, "preview", "", "select preview by title or index (e.g. --preview \"Dark Mode\" or --preview 1)")
+	previewCmd.Flags().StringVar(&previewSelector, "preview", "", "select preview by title, index, /regex/, or file:line (e.g. --preview \"Dark Mode\", --preview 1, --preview '/^Dark/', or --preview HogeView.swift:42)")
 	previewCmd.Flags().BoolVar(&previewReuseBuild, "reuse-build", false, "skip xcodebuild and reuse artifacts from a previous build")
+	previewCmd.Flags().BoolVar(&previewNoReuse, "no-reuse", false, "run a clean build, ignoring artifacts from a previous build")
+	previewCmd.Flags().StringVar(&previewInitArgs, "init-args", "", initArgsUsage)
 	previewCmd.Flags().BoolVar(&previewFullThunk, "full-thunk", false, "use full thunk compilation in oneshot mode (per-file dynamic replacement)")
+	previewCmd.Flags().IntVar(&previewPID, "pid", 0, "inject into this running app process instead of launching the app; the process must have been launched by axe preview, is not reinstalled, and --seed and --clean-status-bar do not apply")
+	previewCmd.Flags().StringVar(&previewGitRef, "git-ref", "", "preview the source file as it exists at this git branch, tag, or commit, using a temporary worktree; the working tree is left untouched")
+	previewCmd.Flags().StringVar(&previewRecord, "record", "", "record the simulator screen to this mp4 file for --record-duration before the screenshot is taken")
+	previewCmd.Flags().DurationVar(&previewRecordDuration, "record-duration", 0, "how long --record records, e.g. 10s")
+	previewCmd.Flags().BoolVar(&previewFailOnWarning, "fail-on-warning", false, "exit with code 9 after capturing if the build produced compiler warnings (for CI gating); implies a clean build")
+	previewCmd.Flags().BoolVar(&previewFresh, "fresh", false, "erase the simulator before booting it (shutting it down first if booted) so that no app data or defaults carry over; axe-managed simulators only")
+	previewCmd.Flags().BoolVar(&previewCleanStatusBar, "clean-status-bar", false, "override the status bar (time, full battery and signal) for clean screenshots")
+	previewCmd.Flags().StringVar(&previewStatusBarTime, "status-bar-time", platform.DefaultStatusBarOverride().Time, "time shown with --clean-status-bar")
+	previewCmd.Flags().IntVar(&previewStatusBarBattery, "status-bar-battery", platform.DefaultStatusBarOverride().BatteryLevel, "battery level (0-100) shown with --clean-status-bar")
 
 	rootCmd.AddCommand(previewCmd)
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview"
+)
+
+var (
+	previewBench           bool
+	previewBenchIterations int
+	previewBenchJSON       bool
+)
+
+// runBenchLogic runs the oneshot pipeline --bench-iterations times and
+// prints the per-phase timings of the cold run against the warm runs.
+func runBenchLogic(sourceArg string, deviceFlagSet bool) error {
+	if previewBenchIterations < 2 {
+		return &usageError{err: fmt.Errorf("--bench-iterations must be >= 2 (one cold and at least one warm run), got %d", previewBenchIterations)}
+	}
+	opts, err := oneshotOptions(sourceArg, deviceFlagSet)
+	if err != nil {
+		return err
+	}
+	// Capture the frame like a oneshot run does, but discard it.
+	opts.OnReady = func(ctx context.Context, device, deviceSetPath string) error {
+		_, err := platform.Screenshot(ctx, device, deviceSetPath)
+		return err
+	}
+
+	res, err := preview.RunBench(opts, previewBenchIterations)
+	if err != nil {
+		return err
+	}
+	if previewBenchJSON {
+		return writeBenchJSON(os.Stdout, res)
+	}
+	return writeBenchTable(os.Stdout, res)
+}
+
+// benchPhaseJSON is the JSON form of one phase of a bench result.
+type benchPhaseJSON struct {
+	Phase   string    `json:"phase"`
+	ColdMs  float64   `json:"coldMs"`
+	WarmMs  float64   `json:"warmMs"`
+	DeltaMs float64   `json:"deltaMs"`
+	Samples []float64 `json:"samplesMs,omitempty"`
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func writeBenchJSON(w io.Writer, res *preview.BenchResult) error {
+	out := struct {
+		Iterations int              `json:"iterations"`
+		Phases     []benchPhaseJSON `json:"phases"`
+	}{Iterations: res.Iterations}
+	for _, p := range res.Summary() {
+		pj := benchPhaseJSON{Phase: p.Phase, ColdMs: ms(p.Cold), WarmMs: ms(p.Warm), DeltaMs: ms(p.Delta)}
+		for _, d := range res.Samples[p.Phase] {
+			pj.Samples = append(pj.Samples, ms(d))
+		}
+		out.Phases = append(out.Phases, pj)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeBenchTable(w io.Writer, res *preview.BenchResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "PHASE\tCOLD\tWARM (mean of %d)\tDELTA\n", res.Iterations-1)
+	for _, p := range res.Summary() {
+		_, _ = fmt.Fprintf(tw, "%s\t%.2fs\t%.2fs\t%+.2fs\n", p.Phase, p.Cold.Seconds(), p.Warm.Seconds(), p.Delta.Seconds())
+	}
+	return tw.Flush()
+}
+
+func init() {
+	previewCmd.Flags().BoolVar(&previewBench, "bench", false, "run the preview pipeline repeatedly and report per-phase cold vs warm timings instead of writing a PNG")
+	previewCmd.Flags().IntVar(&previewBenchIterations, "bench-iterations", 3, "number of --bench runs: one cold run followed by warm runs that reuse its build")
+	previewCmd.Flags().BoolVar(&previewBenchJSON, "bench-json", false, "print --bench results as JSON")
+}
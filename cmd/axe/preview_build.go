@@ -1,7 +1,9 @@
 package main
 
 import (
+	"github.com/k-kohey/axe/internal/platform"
 	"github.com/k-kohey/axe/internal/preview"
+	"github.com/k-kohey/axe/internal/preview/build"
 	"github.com/spf13/cobra"
 )
 
@@ -27,7 +29,10 @@ func runBuildLogic() error {
 	if err != nil {
 		return err
 	}
-	return preview.RunBuild(pc)
+	if err := platform.CheckDiskSpace(build.CacheRoot()); err != nil {
+		return err
+	}
+	return preview.RunBuild(resolveConfiguration(pc))
 }
 
 func init() {
@@ -15,6 +15,7 @@ var (
 	reportFormat      string
 	reportConcurrency int
 	reportReuseBuild  bool
+	reportNoReuse     bool
 )
 
 var previewReportCmd = &cobra.Command{
@@ -28,6 +29,9 @@ var previewReportCmd = &cobra.Command{
 	  - axe_swiftui_preview_report.md (or .html)
 	  - axe_swiftui_preview_report_assets/*.png
 
+	.axpreview sidecars are not read: all files share one session, so pass their
+	settings as flags instead.
+
 	Examples:
 	  axe preview report Sources/FooView.swift --output ./screenshots/
 	  axe preview report Sources/FooView.swift --output ./out.png
@@ -45,9 +49,45 @@ var previewReportCmd = &cobra.Command{
 			return fmt.Errorf("--output is required")
 		}
 
+		if err := platform.CheckXcode(cmd.Context()); err != nil {
+			return err
+		}
 		if err := platform.CheckIDBCompanion(); err != nil {
 			return err
 		}
+		if err := checkDiskSpace(); err != nil {
+			return err
+		}
+		pc = resolveConfiguration(pc)
+
+		a11y, err := accessibilityOverrides()
+		if err != nil {
+			return err
+		}
+		layout, err := layoutOverride()
+		if err != nil {
+			return err
+		}
+		statusBar, err := statusBarOverride()
+		if err != nil {
+			return err
+		}
+		mocks, err := mockSources()
+		if err != nil {
+			return err
+		}
+		seed, err := seedDir()
+		if err != nil {
+			return err
+		}
+		imports, err := thunkImports()
+		if err != nil {
+			return err
+		}
+		mode, err := buildMode(reportReuseBuild, reportNoReuse)
+		if err != nil {
+			return err
+		}
 
 		return report.RunReport(report.ReportOptions{
 			Files:       args,
@@ -56,8 +96,20 @@ var previewReportCmd = &cobra.Command{
 			Format:      reportFormat,
 			PC:          pc,
 			Device:      previewDevice,
+			Runtime:     previewRuntime,
+			Family:      previewFamily,
+			DeviceType:  previewDeviceType,
 			Concurrency: reportConcurrency,
-			ReuseBuild:  reportReuseBuild,
+			BuildMode:   mode,
+
+			Accessibility: a11y,
+			StatusBar:     statusBar,
+			MockSources:   mocks,
+			ThunkImports:  imports,
+			SeedDir:       seed,
+			KeepThunk:     previewKeepThunk,
+			Layout:        layout,
+			BootTimeout:   previewBootTimeout,
 		})
 	},
 }
@@ -70,5 +122,10 @@ func init() {
 		"max parallel simulators (0 = auto)")
 	previewReportCmd.Flags().BoolVar(&reportReuseBuild, "reuse-build", false,
 		"skip xcodebuild and reuse artifacts from a previous build")
+	previewReportCmd.Flags().BoolVar(&reportNoReuse, "no-reuse", false,
+		"run a clean build, ignoring artifacts from a previous build")
+	previewReportCmd.Flags().BoolVar(&previewCleanStatusBar, "clean-status-bar", false, "override the status bar (time, full battery and signal) for clean screenshots")
+	previewReportCmd.Flags().StringVar(&previewStatusBarTime, "status-bar-time", platform.DefaultStatusBarOverride().Time, "time shown with --clean-status-bar")
+	previewReportCmd.Flags().IntVar(&previewStatusBarBattery, "status-bar-battery", platform.DefaultStatusBarOverride().BatteryLevel, "battery level (0-100) shown with --clean-status-bar")
 	previewCmd.AddCommand(previewReportCmd)
 }
@@ -1,13 +1,29 @@
 package main
 
 import (
+	"time"
+
+	"github.com/k-kohey/axe/internal/platform"
 	"github.com/spf13/cobra"
 )
 
 var (
-	serveStrict        bool
-	serveMaxThunkFiles int
-	servePreThunkDepth int
+	serveStrict         bool
+	serveNoReuse        bool
+	serveMaxThunkFiles  int
+	servePreThunkDepth  int
+	serveOnce           bool
+	servePreviewAll     bool
+	serveRejectDups     bool
+	serveFrameEncoding  string
+	serveFrameDiff      bool
+	servePNGCompression string
+	serveLogs           bool
+	serveIdleTimeout    time.Duration
+	serveIdleExit       bool
+	serveReconcile      time.Duration
+	serveEventsFIFO     string
+	serveWarmPool       int
 )
 
 var previewServeCmd = &cobra.Command{
@@ -19,16 +35,89 @@ var previewServeCmd = &cobra.Command{
 
 	This mode is used by the VS Code / Cursor extension for real-time preview.
 
+	With --once, serve handles a single AddStream, exits zero after the stream
+	emits its first Frame, and exits non-zero if the stream stops first. This
+	gives CI a one-shot preview with machine-readable events.
+
+	With --preview-all, each AddStream renders every #Preview in its file:
+	it expands into one stream per preview ("<streamId>#<index>"), each on its
+	own simulator, and frames carry the preview title as their label.
+	RemoveStream with the original streamId stops all of them.
+
+	An AddStream for a streamId that is already running updates that stream:
+	a new file or preview is switched in place (hot-reload, rebuilding only if
+	needed), while a new device, runtime, or project restarts it. Pass
+	--reject-duplicate-streams to ignore such AddStreams instead.
+
+	--frame-encoding selects the Frame payload: base64 (default) puts the JPEG
+	in data, dataurl puts a data: URL in data, and file writes the JPEG under
+	the stream's staging directory and reports it in path.
+
+	Frames with the "png" codec (AddStream.codec) are lossless.
+	--png-compression trades encoding CPU for frame size: 0 stores the
+	pixels uncompressed, 1-3 or fast encode fastest, 7-9 or best give the
+	smallest frames (e.g. for remote clients), and the default, 6, balances
+	the two.
+
+	With --frame-diff, each JPEG or PNG Frame carries in dirty the bounding box of
+	the pixels that changed since the stream's previous frame, so a client
+	can redraw only that region. Comparing frames costs CPU, so it is off
+	by default.
+
+	With --logs, each stream forwards its app's log (NSLog, os_log, and Logger
+	entries at info level and above) as LogStream events from launch until the
+	stream stops.
+
+	serve keeps --warm-pool simulators (default 1) booted ahead of time for
+	the device type and runtime of the last AddStream, so that the next
+	stream of that kind skips the cold boot. A claimed simulator is replaced
+	in the background, and the spares are shut down on exit or idle
+	shutdown. Pass --warm-pool 0 to boot simulators on demand only; --once
+	never warms.
+
+	With --idle-timeout, once no stream has been active for that long, serve
+	shuts down its pooled simulators and stops watching files; the next
+	AddStream starts them again. Add --idle-exit to exit instead.
+
+	With --events-fifo, events are written to the named pipe at that path
+	instead of stdout, so that a client can keep the protocol apart from the
+	process output. serve creates the pipe if it does not exist (and removes
+	it on exit) and waits for a reader to open it before sending Hello.
+
+	With --reconcile-interval, serve re-lists its simulators that often to
+	notice ones deleted or created outside axe (e.g. in Xcode). A stream whose
+	simulator was deleted stops with reason "device_removed".
+
+	serve reuses the app built by a previous run when it exists. Pass
+	--no-reuse to force a clean build instead, e.g. when the previous build
+	is suspected to be stale.
+
 	Requires idb_companion (install via: brew install facebook/fb/idb-companion).`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runServeLogic(serveStrict, serveMaxThunkFiles, servePreThunkDepth)
+		return runServeLogic(serveStrict, serveNoReuse, serveOnce, servePreviewAll, serveRejectDups, serveLogs, serveFrameEncoding, serveFrameDiff, servePNGCompression, serveMaxThunkFiles, servePreThunkDepth, serveWarmPool, serveIdleTimeout, serveIdleExit, serveReconcile, serveEventsFIFO)
 	},
 }
 
 func init() {
 	previewServeCmd.Flags().BoolVar(&serveStrict, "strict", false, "require full thunk compilation (no degraded fallback)")
+	previewServeCmd.Flags().BoolVar(&serveNoReuse, "no-reuse", false, "run a clean build for the first stream instead of reusing a previous build")
 	previewServeCmd.Flags().IntVar(&serveMaxThunkFiles, "max-thunk-files", 32, "maximum number of tracked files for incremental thunk generation")
 	previewServeCmd.Flags().IntVar(&servePreThunkDepth, "pre-thunk-depth", 0, "dependency depth for initial thunk generation (0=target only, 1=direct deps)")
+	previewServeCmd.Flags().BoolVar(&serveOnce, "once", false, "handle a single AddStream and exit after its first frame")
+	previewServeCmd.Flags().BoolVar(&servePreviewAll, "preview-all", false, "render every #Preview in each added file as its own stream")
+	previewServeCmd.Flags().BoolVar(&serveRejectDups, "reject-duplicate-streams", false, "ignore AddStream for an active streamId instead of updating the stream")
+	previewServeCmd.Flags().StringVar(&serveFrameEncoding, "frame-encoding", "base64", "Frame payload encoding: base64, dataurl, or file (h264 streams fall back to jpeg)")
+	previewServeCmd.Flags().BoolVar(&serveFrameDiff, "frame-diff", false, "add the region that changed since the previous frame to JPEG and PNG frames (costs CPU)")
+	previewServeCmd.Flags().StringVar(&servePNGCompression, "png-compression", "6", "compression level of PNG frames: 0-9, fast, or best")
+	previewServeCmd.Flags().BoolVar(&serveLogs, "logs", false, "send the app's log as LogStream events")
+	previewServeCmd.Flags().BoolVar(&previewCleanStatusBar, "clean-status-bar", false, "override the status bar (time, full battery and signal) of every stream's simulator for reproducible frames")
+	previewServeCmd.Flags().StringVar(&previewStatusBarTime, "status-bar-time", platform.DefaultStatusBarOverride().Time, "time shown with --clean-status-bar")
+	previewServeCmd.Flags().IntVar(&previewStatusBarBattery, "status-bar-battery", platform.DefaultStatusBarOverride().BatteryLevel, "battery level (0-100) shown with --clean-status-bar")
+	previewServeCmd.Flags().IntVar(&serveWarmPool, "warm-pool", 1, "number of simulators kept booted ahead of AddStream (0 = boot on demand)")
+	previewServeCmd.Flags().DurationVar(&serveIdleTimeout, "idle-timeout", 0, "shut down idle simulators and the file watcher after this long without streams (e.g. 10m; 0 = never)")
+	previewServeCmd.Flags().BoolVar(&serveIdleExit, "idle-exit", false, "exit after the --idle-timeout shutdown")
+	previewServeCmd.Flags().StringVar(&serveEventsFIFO, "events-fifo", "", "write events to the named pipe at this path instead of stdout (created if absent)")
+	previewServeCmd.Flags().DurationVar(&serveReconcile, "reconcile-interval", 0, "re-list simulators this often and stop streams whose simulator was deleted (e.g. 1m; 0 = never)")
 	previewCmd.AddCommand(previewServeCmd)
 }
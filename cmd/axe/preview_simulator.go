@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview"
 	"github.com/spf13/cobra"
 )
 
@@ -181,26 +191,173 @@ func runSimulatorAdd(cmd *cobra.Command, args []string) error {
 
 // --- remove ---
 
+var simulatorRemoveAll bool
+
 var simulatorRemoveCmd = &cobra.Command{
-	Use:   "remove <udid>",
-	Short: "Remove a managed simulator",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runSimulatorRemove,
+	Use:     "remove <udid>",
+	Aliases: []string{"delete"},
+	Short:   "Remove a managed simulator",
+	Long: `Delete a simulator from axe's device set and report the disk space freed.
+
+Only simulators in axe's own set can be removed; devices of the standard Xcode
+set are refused. With --all, every Shutdown simulator in axe's set is removed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSimulatorRemove,
 }
 
 func runSimulatorRemove(cmd *cobra.Command, args []string) error {
+	if simulatorRemoveAll == (len(args) == 1) {
+		return &usageError{err: errors.New("specify either a simulator UDID or --all")}
+	}
 	store, err := platform.NewConfigStore()
 	if err != nil {
 		return err
 	}
 
 	simctl := &platform.RealSimctlRunner{}
-	udid := args[0]
-	if err := platform.Remove(simctl, udid, store); err != nil {
+	if !simulatorRemoveAll {
+		removed, err := platform.Remove(simctl, args[0], store)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed simulator: %s (%s freed)\n", removed.UDID, platform.FormatBytes(removed.Freed))
+		return nil
+	}
+
+	removed, err := platform.RemoveAll(simctl, store)
+	var freed uint64
+	for _, r := range removed {
+		fmt.Printf("Removed simulator: %s (%s)\n", r.UDID, r.Name)
+		freed += r.Freed
+	}
+	fmt.Printf("Removed %d simulator(s), %s freed.\n", len(removed), platform.FormatBytes(freed))
+	return err
+}
+
+// --- clean ---
+
+var (
+	simulatorCleanOlderThan string
+	simulatorCleanDryRun    bool
+	simulatorCleanJSON      bool
+)
+
+var simulatorCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Reclaim disk space from simulators that have not been used recently",
+	Long: `Shut down every booted simulator in axe's device set, then delete those not
+used within --older-than. The default simulator is kept.
+
+A simulator counts as used when axe picks it for a preview or when simctl last
+updated it, whichever is later.`,
+	Args: cobra.NoArgs,
+	RunE: runSimulatorClean,
+}
+
+func runSimulatorClean(cmd *cobra.Command, args []string) error {
+	olderThan, err := parseAge(simulatorCleanOlderThan)
+	if err != nil {
+		return &usageError{err: fmt.Errorf("--older-than: %w", err)}
+	}
+	store, err := platform.NewConfigStore()
+	if err != nil {
+		return err
+	}
+
+	simctl := &platform.RealSimctlRunner{}
+	result, err := platform.Clean(simctl, store, platform.CleanOptions{
+		OlderThan: olderThan,
+		DryRun:    simulatorCleanDryRun,
+	})
+
+	if simulatorCleanJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(result); encErr != nil {
+			return encErr
+		}
+		return err
+	}
+
+	shutDown, remove := "Shut down", "Removed"
+	if simulatorCleanDryRun {
+		shutDown, remove = "Would shut down", "Would remove"
+	}
+	for _, udid := range result.ShutDown {
+		fmt.Printf("%s simulator: %s\n", shutDown, udid)
+	}
+	var freed uint64
+	for _, r := range result.Removed {
+		fmt.Printf("%s simulator: %s (%s, last used %s)\n", remove, r.UDID, r.Name, r.LastUsed.Local().Format(time.DateOnly))
+		freed += r.Freed
+	}
+	if simulatorCleanDryRun {
+		fmt.Printf("Would remove %d simulator(s), freeing %s.\n", len(result.Removed), platform.FormatBytes(freed))
+	} else {
+		fmt.Printf("Removed %d simulator(s), %s freed.\n", len(result.Removed), platform.FormatBytes(freed))
+	}
+	return err
+}
+
+// parseAge parses a duration that may also be given in days, e.g. "7d" or
+// "36h".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q (use e.g. 7d or 36h)", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 7d or 36h)", s)
+	}
+	return d, nil
+}
+
+// --- recreate ---
+
+var simulatorRecreateJSON bool
+
+var simulatorRecreateCmd = &cobra.Command{
+	Use:   "recreate <udid|name>",
+	Short: "Replace a corrupted simulator with a fresh one",
+	Long: `Delete a managed simulator that no longer boots or erases and create a new
+one with the same name, device type, and runtime.
+
+The default simulator and per-project last-used simulators that referred to
+the old device are moved to the new UDID, and the old device's preview
+session directories are removed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSimulatorRecreate,
+}
+
+func runSimulatorRecreate(cmd *cobra.Command, args []string) error {
+	store, err := platform.NewConfigStore()
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Removed simulator: %s\n", udid)
+	simctl := &platform.RealSimctlRunner{}
+	old, sim, err := platform.Recreate(simctl, args[0], store)
+	if err != nil {
+		return err
+	}
+	if err := preview.RemoveDeviceSessions(old.UDID); err != nil {
+		slog.Warn("Failed to remove preview sessions of the old simulator", "udid", old.UDID, "err", err)
+	}
+
+	if simulatorRecreateJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sim)
+	}
+
+	fmt.Printf("Recreated simulator: %s (%s -> %s)\n", sim.Name, old.UDID, sim.UDID)
+	if sim.IsDefault {
+		fmt.Println("Default simulator updated.")
+	}
 	return nil
 }
 
@@ -300,6 +457,144 @@ func runSimulatorDefault(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// --- config ---
+
+var (
+	simulatorConfigClear bool
+	simulatorConfigJSON  bool
+)
+
+var simulatorConfigCmd = &cobra.Command{
+	Use:   "config <udid>",
+	Short: "Get or set the override profile of a simulator",
+	Long: `Save render overrides for a managed simulator. Whenever a preview, watch,
+serve stream, bench, or report runs on that simulator, whether axe picked it or
+it was named with --device, the profile is applied as if its flags had been
+passed; flags given on the command line (or in AddStream) still win.
+
+Without override flags, shows the current profile. Accepts --dynamic-type,
+--bold-text, --increase-contrast, --appearance, --locale, --region, and
+--language.
+Use --clear to remove the profile.
+
+Example:
+  axe preview simulator config <udid> --appearance dark --locale ja_JP`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSimulatorConfig,
+}
+
+func runSimulatorConfig(cmd *cobra.Command, args []string) error {
+	store, err := platform.NewConfigStore()
+	if err != nil {
+		return err
+	}
+	udid := args[0]
+
+	profile, err := accessibilityOverrides()
+	if err != nil {
+		return err
+	}
+	if simulatorConfigClear && !profile.IsZero() {
+		return &usageError{err: fmt.Errorf("--clear cannot be combined with override flags")}
+	}
+
+	if simulatorConfigClear || !profile.IsZero() {
+		// Verify the UDID exists in the axe device set.
+		managed, err := platform.ListManaged(&platform.RealSimctlRunner{}, store)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(managed, func(s platform.ManagedSimulator) bool { return s.UDID == udid }) {
+			return fmt.Errorf("simulator %s not found. Run 'axe preview simulator list' to see managed simulators", udid)
+		}
+		if err := store.SetDeviceProfile(udid, profile); err != nil {
+			return err
+		}
+		if simulatorConfigClear {
+			fmt.Printf("Profile cleared for %s.\n", udid)
+		} else {
+			fmt.Printf("Profile saved for %s: %s\n", udid, strings.Join(profile.Labels(), ", "))
+		}
+		return nil
+	}
+
+	// Show the current profile.
+	profile, err = store.GetDeviceProfile(udid)
+	if err != nil {
+		return err
+	}
+	if simulatorConfigJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(profile)
+	}
+	if profile.IsZero() {
+		fmt.Printf("No profile set for %s.\n", udid)
+		return nil
+	}
+	fmt.Println(strings.Join(profile.Labels(), ", "))
+	return nil
+}
+
+// --- logs ---
+
+var (
+	simulatorLogsUDID   string
+	simulatorLogsGrep   string
+	simulatorLogsLines  int
+	simulatorLogsFollow bool
+)
+
+var simulatorLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show CoreSimulator logs to diagnose simulator problems",
+	Long: `Print the end of CoreSimulator's service log and, with --udid, the logs
+CoreSimulator keeps for that simulator (from ~/Library/Logs/CoreSimulator),
+e.g. when a simulator won't boot or renders incorrectly.
+
+For a complete diagnostic archive, run 'xcrun simctl diagnose'.
+
+Examples:
+  axe preview simulator logs --udid <udid>
+  axe preview simulator logs --udid <udid> --grep 'error|fail' --follow`,
+	Args: cobra.NoArgs,
+	RunE: runSimulatorLogs,
+}
+
+func runSimulatorLogs(cmd *cobra.Command, args []string) error {
+	if simulatorLogsLines < 0 {
+		return &usageError{err: fmt.Errorf("--lines must be >= 0, got %d", simulatorLogsLines)}
+	}
+	var grep *regexp.Regexp
+	if simulatorLogsGrep != "" {
+		re, err := regexp.Compile(simulatorLogsGrep)
+		if err != nil {
+			return &usageError{err: fmt.Errorf("--grep: %w", err)}
+		}
+		grep = re
+	}
+	logsDir, err := platform.CoreSimulatorLogsDir()
+	if err != nil {
+		return err
+	}
+	deviceSet, err := platform.AxeDeviceSetPath()
+	if err != nil {
+		return err
+	}
+	paths, err := platform.SimulatorLogPaths(logsDir, deviceSet, simulatorLogsUDID)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return platform.TailLogs(ctx, os.Stdout, paths, platform.TailOptions{
+		Lines:  simulatorLogsLines,
+		Grep:   grep,
+		Follow: simulatorLogsFollow,
+	})
+}
+
 func init() {
 	simulatorListCmd.Flags().BoolVar(&simulatorListAvailable, "available", false, "list available device types instead of managed simulators")
 	simulatorListCmd.Flags().BoolVar(&simulatorListJSON, "json", false, "output as JSON")
@@ -311,9 +606,25 @@ func init() {
 	_ = simulatorAddCmd.MarkFlagRequired("device-type")
 	_ = simulatorAddCmd.MarkFlagRequired("runtime")
 
+	simulatorRemoveCmd.Flags().BoolVar(&simulatorRemoveAll, "all", false, "remove every Shutdown simulator in axe's device set")
+
+	simulatorCleanCmd.Flags().StringVar(&simulatorCleanOlderThan, "older-than", "7d", "remove simulators not used for this long, e.g. 7d or 36h")
+	simulatorCleanCmd.Flags().BoolVar(&simulatorCleanDryRun, "dry-run", false, "list what would be shut down and removed without doing it")
+	simulatorCleanCmd.Flags().BoolVar(&simulatorCleanJSON, "json", false, "output as JSON")
+
+	simulatorRecreateCmd.Flags().BoolVar(&simulatorRecreateJSON, "json", false, "output the new simulator as JSON")
+
 	simulatorDefaultCmd.Flags().BoolVar(&simulatorDefaultClear, "clear", false, "clear the default simulator")
 	simulatorDefaultCmd.Flags().BoolVar(&simulatorDefaultJSON, "json", false, "output as JSON")
 
-	simulatorCmd.AddCommand(simulatorListCmd, simulatorAddCmd, simulatorRemoveCmd, simulatorDefaultCmd)
+	simulatorConfigCmd.Flags().BoolVar(&simulatorConfigClear, "clear", false, "remove the simulator's profile")
+	simulatorConfigCmd.Flags().BoolVar(&simulatorConfigJSON, "json", false, "output as JSON")
+
+	simulatorLogsCmd.Flags().StringVar(&simulatorLogsUDID, "udid", "", "also show the logs of this simulator in axe's device set")
+	simulatorLogsCmd.Flags().StringVar(&simulatorLogsGrep, "grep", "", "only show lines matching this regular expression")
+	simulatorLogsCmd.Flags().IntVarP(&simulatorLogsLines, "lines", "n", 200, "number of lines to show from the end of each log")
+	simulatorLogsCmd.Flags().BoolVarP(&simulatorLogsFollow, "follow", "f", false, "keep printing new lines until Ctrl+C")
+
+	simulatorCmd.AddCommand(simulatorListCmd, simulatorAddCmd, simulatorRemoveCmd, simulatorCleanCmd, simulatorRecreateCmd, simulatorDefaultCmd, simulatorConfigCmd, simulatorLogsCmd)
 	previewCmd.AddCommand(simulatorCmd)
 }
@@ -1,16 +1,22 @@
 package main
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
 var (
-	watchSelector      string
-	watchReuseBuild    bool
-	watchStrict        bool
-	watchHeadless      bool
-	watchMaxThunkFiles int
-	watchPreThunkDepth int
+	watchSelector       string
+	watchReuseBuild     bool
+	watchNoReuse        bool
+	watchStrict         bool
+	watchHeadless       bool
+	watchMaxThunkFiles  int
+	watchPreThunkDepth  int
+	watchLogs           bool
+	watchRecord         string
+	watchRecordDuration time.Duration
 )
 
 var previewWatchCmd = &cobra.Command{
@@ -22,19 +28,31 @@ var previewWatchCmd = &cobra.Command{
 	without a full rebuild. Structural changes (stored properties, type signatures) trigger
 	an automatic full rebuild.
 
+	With --logs, the app's log (NSLog, os_log, and Logger entries at info level
+	and above) is printed to stderr while the preview runs.
+
+	With --record, the simulator screen is recorded to an mp4 file from launch
+	until exit (Ctrl+C finalizes the file), or for --record-duration if given.
+
 	Requires idb_companion (install via: brew install facebook/fb/idb-companion).`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runWatchLogic(args[0], watchSelector, watchReuseBuild, watchStrict, !watchHeadless, watchMaxThunkFiles, watchPreThunkDepth)
+		return runWatchLogic(args[0], watchSelector, watchReuseBuild, watchNoReuse, watchStrict, !watchHeadless, watchLogs, watchMaxThunkFiles, watchPreThunkDepth, watchRecord, watchRecordDuration, cmd.Flags().Changed("device"))
 	},
 }
 
 func init() {
-	previewWatchCmd.Flags().StringVar(&watchSelector, "preview", "", "select preview by title or index (e.g. --preview \"Dark Mode\" or --preview 1)")
+	previewWatchCmd.Flags().StringVar(&watchSelector, "preview", "", "select preview by title, index, /regex/, or file:line (e.g. --preview \"Dark Mode\", --preview 1, --preview '/^Dark/', or --preview HogeView.swift:42)")
+	previewWatchCmd.Flags().StringVar(&previewInitArgs, "init-args", "", initArgsUsage)
 	previewWatchCmd.Flags().BoolVar(&watchReuseBuild, "reuse-build", false, "skip xcodebuild and reuse artifacts from a previous build")
+	previewWatchCmd.Flags().BoolVar(&watchNoReuse, "no-reuse", false, "run a clean build, ignoring artifacts from a previous build")
 	previewWatchCmd.Flags().BoolVar(&watchStrict, "strict", false, "require full thunk compilation (no degraded fallback)")
 	previewWatchCmd.Flags().BoolVar(&watchHeadless, "headless", false, "run simulator headlessly without a display window")
 	previewWatchCmd.Flags().IntVar(&watchMaxThunkFiles, "max-thunk-files", 32, "maximum number of tracked files for incremental thunk generation")
 	previewWatchCmd.Flags().IntVar(&watchPreThunkDepth, "pre-thunk-depth", 0, "dependency depth for initial thunk generation (0=target only, 1=direct deps)")
+	previewWatchCmd.Flags().BoolVar(&watchLogs, "logs", false, "print the app's log to stderr")
+	previewWatchCmd.Flags().BoolVar(&previewFresh, "fresh", false, "erase the simulator before booting it (shutting it down first if booted) so that no app data or defaults carry over; axe-managed simulators only")
+	previewWatchCmd.Flags().StringVar(&watchRecord, "record", "", "record the simulator screen to this mp4 file until exit")
+	previewWatchCmd.Flags().DurationVar(&watchRecordDuration, "record-duration", 0, "stop --record after this long while the preview keeps running (0 = until exit)")
 	previewCmd.AddCommand(previewWatchCmd)
 }
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 
+	"github.com/k-kohey/axe/internal/platform"
 	"github.com/k-kohey/axe/internal/preview/analysis"
+	"github.com/k-kohey/axe/internal/procgroup"
+	"github.com/k-kohey/axe/internal/termcolor"
 	"github.com/spf13/cobra"
 )
 
@@ -12,16 +16,27 @@ var version = "dev"
 
 var appName string
 var verbose bool
+var colorMode string
 
 var rootCmd = &cobra.Command{
 	Use:   "axe",
 	Short: "Alternative Xcode Environment — command-line development tools for iOS simulators",
 	Long:  "axe (Alternative Xcode Environment) provides command-line development tools for iOS simulators.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		m, err := termcolor.ParseMode(colorMode)
+		if err != nil {
+			return &usageError{err: fmt.Errorf("--color: %w", err)}
+		}
+		termcolor.SetMode(m)
+		applySimctlTimeout()
+		return nil
+	},
 }
 
 func main() {
+	usageArgs(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -30,8 +45,29 @@ func init() {
 	analysis.Version = version
 
 	cobra.OnInitialize(initConfig)
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
 	rootCmd.PersistentFlags().StringVar(&appName, "app", "", "target app process name (overrides .axerc)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", string(termcolor.Auto), "color human-facing output: auto (only on a terminal, honoring NO_COLOR), always, or never")
+}
+
+// usageArgs makes the positional argument checks of cmd and its
+// subcommands, such as cobra.ExactArgs, fail with a usageError, as flag
+// errors do. Call it once every command is registered.
+func usageArgs(cmd *cobra.Command) {
+	if check := cmd.Args; check != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			if err := check(c, args); err != nil {
+				return &usageError{err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		usageArgs(sub)
+	}
 }
 
 func initConfig() {
@@ -42,4 +78,25 @@ func initConfig() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: level,
 	})))
+
+	// Trace external commands to stderr (never stdout, which serve uses for events).
+	if verbose || os.Getenv(procgroup.TraceEnv) == "1" {
+		procgroup.EnableTrace(os.Stderr)
+	}
+}
+
+// applySimctlTimeout applies .axerc SIMCTL_TIMEOUT to every simctl call. An
+// invalid value only warns, so that axe config validate can still report
+// it along with the other problems.
+func applySimctlTimeout() {
+	v := platform.ReadRC()["SIMCTL_TIMEOUT"]
+	if v == "" {
+		return
+	}
+	d, err := platform.ParseSimctlTimeout(v)
+	if err != nil {
+		slog.Warn("Ignoring .axerc SIMCTL_TIMEOUT", "err", err)
+		return
+	}
+	platform.SetSimctlTimeout(d)
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/termcolor"
 	"github.com/k-kohey/axe/internal/view"
 	"github.com/spf13/cobra"
 )
@@ -27,7 +28,7 @@ With a 0x address argument, shows detailed info for that specific view.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		device := platform.ResolveSimulator(viewSimulator)
 		if viewInteractive {
-			return view.RunInteractive(appName, device)
+			return view.RunInteractive(appName, device, termcolor.Enabled(os.Stdout))
 		}
 		if len(args) == 1 {
 			return runDetail(args[0], device)
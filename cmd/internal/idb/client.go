@@ -11,6 +11,7 @@ import (
 
 // Client wraps the idb_companion gRPC connection.
 type Client struct {
+	addr   string
 	conn   *grpc.ClientConn
 	client pb.CompanionServiceClient
 }
@@ -30,6 +31,7 @@ func NewClient(addr string) (*Client, error) {
 		return nil, fmt.Errorf("connecting to idb_companion at %s: %w", addr, err)
 	}
 	return &Client{
+		addr:   addr,
 		conn:   conn,
 		client: pb.NewCompanionServiceClient(conn),
 	}, nil
@@ -41,8 +43,12 @@ func (c *Client) Close() error {
 }
 
 // ScreenSize returns the device screen dimensions in points.
+// Like Screenshot and VideoStream, it retries while idb_companion is
+// transiently unreachable (see withConnRetry).
 func (c *Client) ScreenSize(ctx context.Context) (width, height int, err error) {
-	resp, err := c.client.Describe(ctx, &pb.TargetDescriptionRequest{})
+	resp, err := withConnRetry(ctx, c.addr, func() (*pb.TargetDescriptionResponse, error) {
+		return c.client.Describe(ctx, &pb.TargetDescriptionRequest{})
+	})
 	if err != nil {
 		return 0, 0, fmt.Errorf("describe: %w", err)
 	}
@@ -54,35 +60,54 @@ func (c *Client) ScreenSize(ctx context.Context) (width, height int, err error)
 	return int(sd.GetWidthPoints()), int(sd.GetHeightPoints()), nil
 }
 
-// VideoStream starts streaming video frames at the given FPS using RBGA (raw pixel) format.
-// Returns a channel that receives raw RGBA pixel data per frame.
+// VideoFormat selects the pixel format idb_companion streams video in.
+type VideoFormat int
+
+const (
+	// VideoFormatRBGA streams raw pixels, one independent frame per message.
+	VideoFormatRBGA VideoFormat = iota
+	// VideoFormatH264 streams H.264 in Annex B form. Frames depend on earlier
+	// ones, so none may be dropped.
+	VideoFormatH264
+)
+
+// VideoStream starts streaming video frames at the given FPS in format.
+// Returns a channel that receives one payload per message: raw RGBA pixel
+// data for VideoFormatRBGA, H.264 NAL units for VideoFormatH264.
 // The channel is closed when the stream ends or the context is cancelled.
 // The caller must cancel ctx to stop the stream; the goroutine will send
 // a Stop message to idb_companion before closing.
 //
-// RBGA format is used instead of H264 because idb_companion's H264 encoder
-// produces severe ghosting artifacts during rapid screen changes.
-func (c *Client) VideoStream(ctx context.Context, fps int) (<-chan []byte, error) {
-	stream, err := c.client.VideoStream(ctx)
+// RBGA is the default because idb_companion's H264 encoder produces severe
+// ghosting artifacts during rapid screen changes; H264 is opt-in for
+// consumers that prefer bandwidth over fidelity.
+func (c *Client) VideoStream(ctx context.Context, fps int, format VideoFormat) (<-chan []byte, error) {
+	stream, err := withConnRetry(ctx, c.addr, func() (grpc.BidiStreamingClient[pb.VideoStreamRequest, pb.VideoStreamResponse], error) {
+		return c.client.VideoStream(ctx)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("starting video stream: %w", err)
 	}
 
-	// Use RBGA (raw pixels) with ScaleFactor 0.5 to halve the resolution.
-	// Each frame is ~3.5 MB at half resolution, which is manageable over local gRPC.
+	// Use ScaleFactor 0.5 to halve the resolution. Each RBGA frame is
+	// ~3.5 MB at half resolution, which is manageable over local gRPC.
 	// Do NOT call CloseSend — idb_companion requires the request stream
 	// to stay open until a Stop message is sent.
+	pbFormat := pb.VideoStreamRequest_RBGA
+	if format == VideoFormatH264 {
+		pbFormat = pb.VideoStreamRequest_H264
+	}
 	err = stream.Send(&pb.VideoStreamRequest{
 		Control: &pb.VideoStreamRequest_Start_{
 			Start: &pb.VideoStreamRequest_Start{
 				Fps:         uint64(fps),
-				Format:      pb.VideoStreamRequest_RBGA,
+				Format:      pbFormat,
 				ScaleFactor: 0.5,
 			},
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("sending video stream start: %w", err)
+		return nil, fmt.Errorf("sending video stream start: %w", classifyConnError(c.addr, err))
 	}
 
 	frameCh := make(chan []byte, 4)
@@ -121,7 +146,7 @@ func (c *Client) VideoStream(ctx context.Context, fps int) (<-chan []byte, error
 func (c *Client) Tap(ctx context.Context, x, y float64) error {
 	stream, err := c.client.Hid(ctx)
 	if err != nil {
-		return fmt.Errorf("opening HID stream: %w", err)
+		return fmt.Errorf("opening HID stream: %w", classifyConnError(c.addr, err))
 	}
 
 	point := &pb.Point{X: x, Y: y}
@@ -166,7 +191,7 @@ func (c *Client) Tap(ctx context.Context, x, y float64) error {
 func (c *Client) Swipe(ctx context.Context, startX, startY, endX, endY float64, durationSec float64) error {
 	stream, err := c.client.Hid(ctx)
 	if err != nil {
-		return fmt.Errorf("opening HID stream: %w", err)
+		return fmt.Errorf("opening HID stream: %w", classifyConnError(c.addr, err))
 	}
 
 	if err := stream.Send(&pb.HIDEvent{
@@ -190,7 +215,7 @@ func (c *Client) Swipe(ctx context.Context, startX, startY, endX, endY float64,
 func (c *Client) Text(ctx context.Context, text string) error {
 	stream, err := c.client.Hid(ctx)
 	if err != nil {
-		return fmt.Errorf("opening HID stream: %w", err)
+		return fmt.Errorf("opening HID stream: %w", classifyConnError(c.addr, err))
 	}
 
 	for _, ch := range text {
@@ -237,7 +262,7 @@ func (c *Client) Text(ctx context.Context, text string) error {
 func (c *Client) OpenHIDStream(ctx context.Context) (pb.CompanionService_HidClient, error) {
 	stream, err := c.client.Hid(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("opening HID stream: %w", err)
+		return nil, fmt.Errorf("opening HID stream: %w", classifyConnError(c.addr, err))
 	}
 	return stream, nil
 }
@@ -304,7 +329,9 @@ func (c *Client) TouchUp(stream pb.CompanionService_HidClient, x, y float64) err
 
 // Screenshot takes a single screenshot and returns the image data.
 func (c *Client) Screenshot(ctx context.Context) ([]byte, error) {
-	resp, err := c.client.Screenshot(ctx, &pb.ScreenshotRequest{})
+	resp, err := withConnRetry(ctx, c.addr, func() (*pb.ScreenshotResponse, error) {
+		return c.client.Screenshot(ctx, &pb.ScreenshotRequest{})
+	})
 	if err != nil {
 		return nil, fmt.Errorf("screenshot: %w", err)
 	}
@@ -320,7 +347,7 @@ type HIDStream = pb.CompanionService_HidClient
 // IDBClient defines the interface for idb operations, enabling mock injection in tests.
 type IDBClient interface {
 	ScreenSize(ctx context.Context) (width, height int, err error)
-	VideoStream(ctx context.Context, fps int) (<-chan []byte, error)
+	VideoStream(ctx context.Context, fps int, format VideoFormat) (<-chan []byte, error)
 	Tap(ctx context.Context, x, y float64) error
 	Swipe(ctx context.Context, startX, startY, endX, endY float64, durationSec float64) error
 	Text(ctx context.Context, text string) error
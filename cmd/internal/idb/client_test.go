@@ -18,6 +18,7 @@ type mockCompanionServer struct {
 	screenshotResp  *pb.ScreenshotResponse
 	hidEvents       []*pb.HIDEvent
 	videoStartCalls int
+	videoFormat     pb.VideoStreamRequest_Format
 }
 
 func (s *mockCompanionServer) Describe(_ context.Context, _ *pb.TargetDescriptionRequest) (*pb.TargetDescriptionResponse, error) {
@@ -41,11 +42,12 @@ func (s *mockCompanionServer) Hid(stream grpc.ClientStreamingServer[pb.HIDEvent,
 
 func (s *mockCompanionServer) VideoStream(stream grpc.BidiStreamingServer[pb.VideoStreamRequest, pb.VideoStreamResponse]) error {
 	// Read start request.
-	_, err := stream.Recv()
+	req, err := stream.Recv()
 	if err != nil {
 		return err
 	}
 	s.videoStartCalls++
+	s.videoFormat = req.GetStart().GetFormat()
 
 	// Send one frame then close.
 	_ = stream.Send(&pb.VideoStreamResponse{
@@ -202,7 +204,7 @@ func TestClient_VideoStream(t *testing.T) {
 
 	ctx := t.Context()
 
-	frames, err := client.VideoStream(ctx, 15)
+	frames, err := client.VideoStream(ctx, 15, VideoFormatRBGA)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -215,6 +217,30 @@ func TestClient_VideoStream(t *testing.T) {
 	if srv.videoStartCalls != 1 {
 		t.Errorf("expected 1 video start call, got %d", srv.videoStartCalls)
 	}
+	if srv.videoFormat != pb.VideoStreamRequest_RBGA {
+		t.Errorf("format = %v, want RBGA", srv.videoFormat)
+	}
+}
+
+func TestClient_VideoStream_H264(t *testing.T) {
+	srv := &mockCompanionServer{}
+	addr := startMockServer(t, srv)
+
+	client, err := NewClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	frames, err := client.VideoStream(t.Context(), 15, VideoFormatH264)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-frames
+
+	if srv.videoFormat != pb.VideoStreamRequest_H264 {
+		t.Errorf("format = %v, want H264", srv.videoFormat)
+	}
 }
 
 func TestClient_Screenshot(t *testing.T) {
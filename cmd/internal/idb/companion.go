@@ -2,9 +2,13 @@ package idb
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"os"
 	"os/exec"
 	"strconv"
@@ -24,6 +28,7 @@ type Commander interface {
 // CmdRunner abstracts *exec.Cmd methods used by Companion.
 type CmdRunner interface {
 	StdoutPipe() (*os.File, error)
+	StderrPipe() (*os.File, error)
 	Start() error
 	Process() *os.Process
 	Wait() error
@@ -31,25 +36,68 @@ type CmdRunner interface {
 
 // Companion manages an idb_companion process.
 type Companion struct {
-	cmd     CmdRunner
-	port    string
-	process *os.Process
-	done    chan struct{} // closed when the process exits
-	exitErr error         // set before done is closed; read only after <-done
+	host     string
+	udid     string              // target of a companion from Start or StartForDevice
+	physical bool                // serves a physical device (StartForDevice)
+	done     chan struct{}       // closed when the process exits
+	exitErr  error               // set before done is closed; read only after <-done
+	grace    time.Duration       // Stop's wait between SIGTERM and SIGKILL
+	opts     Options             // options the companion was started with
+	events   chan CompanionEvent // see Events; closed after done
+
+	// mu guards the fields replaced when the watchdog restarts the process.
+	mu        sync.Mutex
+	cmd       CmdRunner
+	port      string // preferred gRPC port; see Port
+	swiftPort string // grpc_swift_port, if reported
+	process   *os.Process
+	stopping  bool      // set by Stop; the watchdog must not restart
+	exited    bool      // the monitor saw the last process exit
+	watchdog  *watchdog // nil unless EnableAutoRestart was called
+
+	// discovered is set for a companion found by DiscoverRunning, which
+	// this process did not start; release ends watching it.
+	discovered  bool
+	release     chan struct{}
+	releaseOnce sync.Once
 }
 
 // startMonitor launches a goroutine that waits for the process to exit
 // and signals via the done channel. Must be called exactly once after
 // the process is started.
 func (c *Companion) startMonitor() {
-	go func() {
-		c.exitErr = c.cmd.Wait()
-		close(c.done)
-	}()
+	go c.monitor(c.cmd)
+}
+
+// monitor waits for cmd to exit. A crash is handed to the watchdog, if
+// enabled; when it restarts the process, the new process gets its own
+// monitor and done stays open.
+func (c *Companion) monitor(cmd CmdRunner) {
+	err := cmd.Wait()
+	emit(c.events, CompanionEvent{Kind: CompanionExited, Err: err})
+	c.mu.Lock()
+	w, stopping := c.watchdog, c.stopping
+	if w == nil {
+		c.exited = true
+	}
+	c.mu.Unlock()
+	if w != nil {
+		if err != nil && !stopping && w.restart(c, err) {
+			return
+		}
+		close(w.restarts)
+	}
+	c.removeState()
+	c.exitErr = err
+	close(c.done)
+	if c.events != nil {
+		close(c.events)
+	}
 }
 
 // Done returns a channel that is closed when the companion process exits
-// (either normally or due to a crash).
+// (either normally or due to a crash). With auto-restart enabled, it is
+// closed only once the process exits and is not restarted.
 func (c *Companion) Done() <-chan struct{} {
 	return c.done
 }
@@ -61,37 +109,18 @@ func (c *Companion) Err() error {
 	return c.exitErr
 }
 
-var (
-	devNullOnce sync.Once
-	devNull     *os.File
-)
-
-func getDevNull() *os.File {
-	devNullOnce.Do(func() {
-		var err error
-		devNull, err = os.Open(os.DevNull)
-		if err != nil {
-			devNull = nil
-		}
-	})
-	return devNull
-}
-
 type defaultCommander struct{}
 
 func (defaultCommander) Command(name string, args ...string) CmdRunner {
 	cmd := exec.Command(name, args...)
 	procgroup.Setup(cmd)
-	// Suppress idb_companion's stderr noise (objc warnings, startup logs).
-	if f := getDevNull(); f != nil {
-		cmd.Stderr = f
-	}
 	return &execCmdRunner{cmd: cmd}
 }
 
 type execCmdRunner struct {
 	cmd    *exec.Cmd
 	stdout *os.File
+	stderr *os.File
 }
 
 func (r *execCmdRunner) StdoutPipe() (*os.File, error) {
@@ -104,25 +133,165 @@ func (r *execCmdRunner) StdoutPipe() (*os.File, error) {
 	return pr, nil
 }
 
+func (r *execCmdRunner) StderrPipe() (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	r.cmd.Stderr = pw
+	r.stderr = pr
+	return pr, nil
+}
+
 func (r *execCmdRunner) Start() error {
 	err := r.cmd.Start()
-	// Close the write end of the pipe after starting so reads see EOF when process exits.
+	// Close the write ends of the pipes after starting so reads see EOF when process exits.
 	if r.stdout != nil {
 		if pw, ok := r.cmd.Stdout.(*os.File); ok {
 			_ = pw.Close()
 		}
 	}
+	if r.stderr != nil {
+		if pw, ok := r.cmd.Stderr.(*os.File); ok {
+			_ = pw.Close()
+		}
+	}
 	return err
 }
 
 func (r *execCmdRunner) Process() *os.Process { return r.cmd.Process }
 func (r *execCmdRunner) Wait() error          { return r.cmd.Wait() }
 
+// HostEnv names the environment variable that overrides the host used to
+// reach idb_companion (e.g. when axe and the simulator run on different
+// machines or containers). Defaults to localhost. When set, the companions
+// axe starts also bind their gRPC server to it.
+const HostEnv = "IDB_HOST"
+
+// companionHost returns the host Address() dials.
+func companionHost() string {
+	if h := os.Getenv(HostEnv); h != "" {
+		return h
+	}
+	return "localhost"
+}
+
+// grpcArgs returns the gRPC server arguments of an idb_companion: an
+// ephemeral port, bound to HostEnv when it is set so that the companion
+// listens on the address Address() dials.
+func grpcArgs() []string {
+	args := []string{"--grpc-port", "0"}
+	if h := os.Getenv(HostEnv); h != "" {
+		args = append(args, "--grpc-host", h)
+	}
+	return args
+}
+
 // DefaultCommander returns the standard Commander using exec.Command.
 func DefaultCommander() Commander {
 	return defaultCommander{}
 }
 
+// Default waits of Options.
+const (
+	DefaultStartTimeout    = 10 * time.Second
+	DefaultBootTimeout     = 60 * time.Second
+	DefaultStopGracePeriod = 5 * time.Second
+)
+
+// Options configures the start and boot functions. They bound only the wait
+// for idb_companion to become ready, not the lifetime of the process.
+type Options struct {
+	// StartTimeout bounds the wait for idb_companion to report its gRPC port
+	// and accept connections on it (0 = DefaultStartTimeout).
+	StartTimeout time.Duration
+	// BootTimeout bounds the wait for the simulator to report Booted
+	// (0 = DefaultBootTimeout).
+	BootTimeout time.Duration
+	// MaxStartAttempts is how many times idb_companion is run when it exits
+	// before reporting its port or the Booted state, as it occasionally does
+	// on its first run on CI (0 = 1, no retry).
+	MaxStartAttempts int
+	// StopGracePeriod is how long Stop waits after SIGTERM before sending
+	// SIGKILL (0 = DefaultStopGracePeriod).
+	StopGracePeriod time.Duration
+	// SkipReadyProbe returns a started companion as soon as it reports its
+	// port, without dialing the port to check that it accepts connections.
+	SkipReadyProbe bool
+	// StateDir is where a started companion records its pid and port so
+	// that a later axe process can reuse it with DiscoverRunning; preview
+	// passes the session directory of the project and device. Empty
+	// records nothing.
+	StateDir string
+}
+
+// Errors returned by Stop when the companion did not exit on SIGTERM.
+var (
+	// ErrNotRunning reports that the process had already exited.
+	ErrNotRunning = errors.New("idb_companion was not running")
+	// ErrStopTimeout reports that the process ignored SIGTERM for the grace
+	// period and was killed.
+	ErrStopTimeout = errors.New("idb_companion was killed after not exiting on SIGTERM")
+)
+
+// errExitedEarly reports that idb_companion closed stdout before printing
+// the line we were waiting for, typically because it exited.
+var errExitedEarly = errors.New("exited before it was ready")
+
+// Delay before retrying an idb_companion that exited early, doubled on each
+// further attempt up to maxStartRetryDelay.
+var (
+	startRetryDelay    = 500 * time.Millisecond
+	maxStartRetryDelay = 4 * time.Second
+)
+
+// withStartAttempts calls start up to opts.MaxStartAttempts times, retrying
+// with backoff while it fails with errExitedEarly. Each call starts a fresh
+// idb_companion process; start returns it with what was awaited on stdout.
+func withStartAttempts[T any](ctx context.Context, opts Options, start func() (CmdRunner, T, error)) (CmdRunner, T, error) {
+	attempts := max(opts.MaxStartAttempts, 1)
+	delay := startRetryDelay
+	for attempt := 1; ; attempt++ {
+		cmd, v, err := start()
+		if err == nil || attempt >= attempts || !errors.Is(err, errExitedEarly) {
+			return cmd, v, err
+		}
+		slog.Warn("idb_companion exited early, retrying",
+			"attempt", attempt,
+			"maxAttempts", attempts,
+			"err", err,
+		)
+		select {
+		case <-ctx.Done():
+			var zero T
+			return nil, zero, fmt.Errorf("waiting to retry idb_companion: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxStartRetryDelay)
+	}
+}
+
+func (o Options) startTimeout() time.Duration {
+	if o.StartTimeout > 0 {
+		return o.StartTimeout
+	}
+	return DefaultStartTimeout
+}
+
+func (o Options) bootTimeout() time.Duration {
+	if o.BootTimeout > 0 {
+		return o.BootTimeout
+	}
+	return DefaultBootTimeout
+}
+
+func (o Options) stopGracePeriod() time.Duration {
+	if o.StopGracePeriod > 0 {
+		return o.StopGracePeriod
+	}
+	return DefaultStopGracePeriod
+}
+
 // Start launches idb_companion for the given device UDID and returns a Companion.
 // It reads the assigned gRPC port from companion stdout.
 // If deviceSetPath is non-empty, --device-set-path is added.
@@ -132,112 +301,341 @@ func Start(udid, deviceSetPath string) (*Companion, error) {
 
 // StartWith launches idb_companion using the given Commander.
 func StartWith(cmdr Commander, udid, deviceSetPath string) (*Companion, error) {
-	args := []string{"--udid", udid, "--grpc-port", "0"}
+	return StartWithContext(context.Background(), cmdr, udid, deviceSetPath, Options{})
+}
+
+// StartWithContext is StartWith that gives up when ctx is done or
+// opts.StartTimeout passes: the started process is killed and an error
+// (wrapping ctx.Err() on cancellation) is returned.
+func StartWithContext(ctx context.Context, cmdr Commander, udid, deviceSetPath string, opts Options) (*Companion, error) {
+	return startCompanion(ctx, cmdr, udid, companionArgs(udid, deviceSetPath), opts)
+}
+
+// StartForDevice launches idb_companion for the connected physical iOS
+// device udid. Physical devices are not in a simulator device set, so no
+// --device-set-path is passed, and --only device keeps idb_companion from
+// looking the UDID up among simulators.
+func StartForDevice(cmdr Commander, udid string) (*Companion, error) {
+	c, err := startCompanion(context.Background(), cmdr, udid, deviceArgs(udid), Options{})
+	if err != nil {
+		return nil, err
+	}
+	c.physical = true
+	return c, nil
+}
+
+// startCompanion runs idb_companion for udid with args and returns it once
+// it reports its port, recorded for DiscoverRunning.
+func startCompanion(ctx context.Context, cmdr Commander, udid string, args []string, opts Options) (*Companion, error) {
+	events := newEvents()
+	emit(events, CompanionEvent{Kind: CompanionStarting})
+	cmd, ports, err := withStartAttempts(ctx, opts, func() (CmdRunner, companionPorts, error) {
+		return startForPort(ctx, cmdr, args, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := &Companion{
+		cmd:       cmd,
+		host:      companionHost(),
+		udid:      udid,
+		port:      ports.port(),
+		swiftPort: ports.GRPCSwiftPort,
+		process:   cmd.Process(),
+		done:      make(chan struct{}),
+		grace:     opts.stopGracePeriod(),
+		opts:      opts,
+		events:    events,
+	}
+	emit(events, CompanionEvent{Kind: CompanionPortResolved, Port: c.port})
+	c.saveState()
+	c.startMonitor()
+	return c, nil
+}
+
+// companionArgs returns the arguments of an idb_companion serving the
+// simulator udid.
+func companionArgs(udid, deviceSetPath string) []string {
+	args := append([]string{"--udid", udid}, grpcArgs()...)
 	if deviceSetPath != "" {
 		args = append(args, "--device-set-path", deviceSetPath)
 	}
+	return args
+}
+
+// deviceArgs returns the arguments of an idb_companion serving the physical
+// device udid.
+func deviceArgs(udid string) []string {
+	return append(append([]string{"--udid", udid}, grpcArgs()...), "--only", "device")
+}
+
+// startForPort runs idb_companion once and waits for its port.
+func startForPort(ctx context.Context, cmdr Commander, args []string, opts Options) (CmdRunner, companionPorts, error) {
 	cmd := cmdr.Command("idb_companion", args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+		return nil, companionPorts{}, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		_ = stdout.Close()
+		return nil, companionPorts{}, fmt.Errorf("creating stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("starting idb_companion: %w", err)
+		_ = stdout.Close()
+		_ = stderrPipe.Close()
+		return nil, companionPorts{}, fmt.Errorf("starting idb_companion: %w", err)
 	}
+	// Drained for the lifetime of the process, so it never blocks on a
+	// full stderr pipe; the tail explains a failed start.
+	stderr := captureStderr(stderrPipe)
 
-	// Read first line of stdout to get the assigned port.
+	// Read stdout line by line until the assigned port appears.
 	// idb_companion outputs JSON: {"grpc_swift_port":N,"grpc_port":N}
-	scanner := bufio.NewScanner(stdout)
-	portCh := make(chan string, 1)
+	// The scanner only yields complete lines, so JSON written in fragments
+	// is parsed once its newline arrives.
+	s := scanStdout(stdout, "idb_companion", parseCompanionPort)
+
+	timeout := opts.startTimeout()
+	deadline := time.Now().Add(timeout)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ports, ok := <-s.found:
+		if !ok {
+			s.abort(cmd)
+			return nil, companionPorts{}, stderr.annotate(fmt.Errorf("idb_companion did not output a port: %w", errExitedEarly))
+		}
+		if !opts.SkipReadyProbe {
+			if err := waitConnectable(ctx, net.JoinHostPort(companionHost(), ports.port()), deadline); err != nil {
+				s.abort(cmd)
+				if ctx.Err() != nil {
+					return nil, companionPorts{}, err
+				}
+				return nil, companionPorts{}, stderr.annotate(err)
+			}
+		}
+		return cmd, ports, nil
+	case <-timer.C:
+		s.abort(cmd)
+		return nil, companionPorts{}, stderr.annotate(fmt.Errorf("companion did not report its port within %s", timeout))
+	case <-ctx.Done():
+		s.abort(cmd)
+		return nil, companionPorts{}, fmt.Errorf("waiting for idb_companion port: %w", ctx.Err())
+	}
+}
+
+// stdoutScan reads idb_companion stdout in a goroutine until match accepts
+// a line.
+type stdoutScan[T comparable] struct {
+	stdout *os.File
+	found  chan T        // receives the match; closed without one at EOF
+	done   chan struct{} // closed when the goroutine has returned
+}
+
+// scanStdout starts scanning stdout. match accepts a line by returning a
+// non-zero value. After a match, the rest of stdout is drained so
+// idb_companion never blocks on a full pipe.
+func scanStdout[T comparable](stdout *os.File, name string, match func(line string) T) *stdoutScan[T] {
+	s := &stdoutScan[T]{stdout: stdout, found: make(chan T, 1), done: make(chan struct{})}
 	go func() {
+		defer close(s.done)
+		var zero T
+		scanner := newLineScanner(stdout)
 		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if port := parseCompanionPort(line); port != "" {
-				portCh <- port
+			if v := match(strings.TrimSpace(scanner.Text())); v != zero {
+				s.found <- v
+				drain(stdout)
 				return
 			}
 		}
-		close(portCh)
+		if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+			slog.Warn("Reading "+name+" output failed", "err", err)
+		}
+		close(s.found)
 	}()
+	return s
+}
 
-	select {
-	case port, ok := <-portCh:
-		if !ok || port == "" {
-			if proc := cmd.Process(); proc != nil {
-				_ = procgroup.KillProcess(proc)
-			}
-			return nil, fmt.Errorf("idb_companion did not output a port")
-		}
-		c := &Companion{
-			cmd:     cmd,
-			port:    port,
-			process: cmd.Process(),
-			done:    make(chan struct{}),
-		}
-		c.startMonitor()
-		return c, nil
-	case <-time.After(10 * time.Second):
-		if proc := cmd.Process(); proc != nil {
-			_ = procgroup.KillProcess(proc)
-		}
-		return nil, fmt.Errorf("timed out waiting for idb_companion port")
+// abort kills the process, closes stdout so a blocked read returns, and
+// waits for the scanning goroutine to exit. The process is reaped in the
+// background.
+func (s *stdoutScan[T]) abort(cmd CmdRunner) {
+	if proc := cmd.Process(); proc != nil {
+		_ = procgroup.KillProcess(proc)
+		go func() { _ = cmd.Wait() }()
+	}
+	_ = s.stdout.Close()
+	<-s.done
+}
+
+// maxCompanionLine bounds a single line of idb_companion stdout. It is far
+// above bufio.Scanner's 64 KiB default so that verbose log lines do not end
+// the scan before the line we are waiting for.
+const maxCompanionLine = 4 << 20
+
+// newLineScanner returns a Scanner over idb_companion stdout that buffers
+// until each newline and accepts lines up to maxCompanionLine bytes.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCompanionLine)
+	return scanner
+}
+
+// drain discards the rest of r, so idb_companion never blocks on a full
+// stdout pipe after we stop looking at its output.
+func drain(r io.Reader) {
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// companionPorts are the gRPC ports idb_companion reports. Newer builds
+// serve the Swift gRPC implementation on a port of its own.
+type companionPorts struct {
+	GRPCPort      string
+	GRPCSwiftPort string
+}
+
+// port returns the port to connect to: grpc_swift_port when reported,
+// otherwise grpc_port.
+func (p companionPorts) port() string {
+	if p.GRPCSwiftPort != "" {
+		return p.GRPCSwiftPort
 	}
+	return p.GRPCPort
 }
 
-// parseCompanionPort extracts the gRPC port from an idb_companion stdout line.
-// The line is typically JSON like {"grpc_swift_port":N,"grpc_port":N}.
-func parseCompanionPort(line string) string {
+// parseCompanionPort extracts the gRPC ports from an idb_companion stdout
+// line. The line is typically JSON like {"grpc_swift_port":N,"grpc_port":N};
+// other lines yield the zero value.
+func parseCompanionPort(line string) companionPorts {
 	var info struct {
-		GRPCPort int `json:"grpc_port"`
+		GRPCPort      int `json:"grpc_port"`
+		GRPCSwiftPort int `json:"grpc_swift_port"`
+	}
+	var p companionPorts
+	if err := json.Unmarshal([]byte(line), &info); err != nil {
+		return p
+	}
+	if info.GRPCPort > 0 {
+		p.GRPCPort = strconv.Itoa(info.GRPCPort)
 	}
-	if err := json.Unmarshal([]byte(line), &info); err == nil && info.GRPCPort > 0 {
-		return strconv.Itoa(info.GRPCPort)
+	if info.GRPCSwiftPort > 0 {
+		p.GRPCSwiftPort = strconv.Itoa(info.GRPCSwiftPort)
 	}
-	return ""
+	return p
 }
 
-// Port returns the gRPC port string reported by idb_companion.
+// Port returns the gRPC port string reported by idb_companion, preferring
+// grpc_swift_port over grpc_port when both are reported.
 func (c *Companion) Port() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.port
 }
 
-// Address returns the gRPC address (localhost:port) for connecting.
+// SwiftPort returns the grpc_swift_port reported by idb_companion, or ""
+// if it reported only grpc_port.
+func (c *Companion) SwiftPort() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.swiftPort
+}
+
+// Address returns the gRPC address (host:port) for connecting.
+// The host is localhost unless overridden via IDB_HOST.
 func (c *Companion) Address() string {
-	return "localhost:" + c.port
+	return net.JoinHostPort(c.host, c.Port())
 }
 
-// Stop gracefully stops the idb_companion process.
-// Sends SIGTERM first, then SIGKILL after timeout.
-func (c *Companion) Stop() error {
+// PID returns the process ID of the idb_companion process, or 0 if it has
+// not been started. After an automatic restart it is the new process's.
+func (c *Companion) PID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.process == nil {
+		return 0
+	}
+	return c.process.Pid
+}
+
+// Kill sends SIGKILL to the idb_companion process group right away and
+// waits for the process to exit. Unlike Stop it does not give the companion
+// a chance to clean up (a booted simulator is left running), so it is meant
+// for a companion that ignores SIGTERM. Like Stop, it leaves a companion
+// from DiscoverRunning alone.
+func (c *Companion) Kill() error {
+	if c.discovered {
+		return nil
+	}
+	c.mu.Lock()
+	c.stopping = true
+	proc := c.process
+	c.mu.Unlock()
+	if proc == nil {
+		return nil
+	}
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	if err := procgroup.KillProcess(proc); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("killing idb_companion: %w", err)
+	}
+	<-c.done
+	return nil
+}
+
+// Stop gracefully stops the idb_companion process: it sends SIGTERM and,
+// if the process has not exited within Options.StopGracePeriod, SIGKILL.
+// It returns nil when the process exited on SIGTERM, ErrStopTimeout when it
+// had to be killed, and ErrNotRunning when it had already exited. A
+// Companion without a process has nothing to stop and returns nil.
+// A companion from DiscoverRunning is left running; Stop only stops
+// watching it.
+func (c *Companion) Stop() error {
+	if c.discovered {
+		c.releaseOnce.Do(func() { close(c.release) })
+		<-c.done
+		return nil
+	}
+
+	c.mu.Lock()
+	c.stopping = true
+	proc := c.process
+	c.mu.Unlock()
+	if proc == nil {
 		return nil
 	}
 
 	// Already exited (crash or previous stop).
 	select {
 	case <-c.done:
-		return nil
+		return ErrNotRunning
 	default:
 	}
 
 	// Send SIGTERM to the entire process group.
-	if err := procgroup.SignalProcess(c.process, syscall.SIGTERM); err != nil {
+	if err := procgroup.SignalProcess(proc, syscall.SIGTERM); err != nil {
 		slog.Debug("SIGTERM failed, trying SIGKILL", "err", err)
-		_ = procgroup.KillProcess(c.process)
+		_ = procgroup.KillProcess(proc)
 		<-c.done // wait for the monitor goroutine to finish
-		return nil
+		return fmt.Errorf("%w: SIGTERM failed: %v", ErrStopTimeout, err)
 	}
 
-	// Wait up to 5 seconds for graceful exit.
+	grace := c.grace
+	if grace <= 0 {
+		grace = DefaultStopGracePeriod
+	}
 	select {
 	case <-c.done:
 		return nil
-	case <-time.After(5 * time.Second):
-		slog.Debug("idb_companion did not exit after SIGTERM, sending SIGKILL")
-		_ = procgroup.KillProcess(c.process)
+	case <-time.After(grace):
+		slog.Debug("idb_companion did not exit after SIGTERM, sending SIGKILL", "grace", grace)
+		_ = procgroup.KillProcess(proc)
 		<-c.done // wait for the monitor goroutine to finish
-		return nil
+		return fmt.Errorf("%w within %s", ErrStopTimeout, grace)
 	}
 }
 
@@ -250,7 +648,13 @@ func Boot(udid, deviceSetPath string) (*Companion, error) {
 
 // BootWith boots a simulator using the given Commander.
 func BootWith(cmdr Commander, udid, deviceSetPath string) (*Companion, error) {
-	return bootSimulator(cmdr, udid, deviceSetPath, false)
+	return bootSimulator(context.Background(), cmdr, udid, deviceSetPath, false, Options{})
+}
+
+// BootWithContext is BootWith that gives up when ctx is done or
+// opts.BootTimeout passes, like BootHeadlessWithContext.
+func BootWithContext(ctx context.Context, cmdr Commander, udid, deviceSetPath string, opts Options) (*Companion, error) {
+	return bootSimulator(ctx, cmdr, udid, deviceSetPath, false, opts)
 }
 
 // BootHeadless boots a simulator headlessly via idb_companion.
@@ -262,11 +666,18 @@ func BootHeadless(udid, deviceSetPath string) (*Companion, error) {
 
 // BootHeadlessWith boots a simulator headlessly using the given Commander.
 func BootHeadlessWith(cmdr Commander, udid, deviceSetPath string) (*Companion, error) {
-	return bootSimulator(cmdr, udid, deviceSetPath, true)
+	return BootHeadlessWithContext(context.Background(), cmdr, udid, deviceSetPath, Options{})
+}
+
+// BootHeadlessWithContext is BootHeadlessWith that gives up when ctx is done
+// or opts.BootTimeout passes: the started process is killed and an error
+// (wrapping ctx.Err() on cancellation) is returned.
+func BootHeadlessWithContext(ctx context.Context, cmdr Commander, udid, deviceSetPath string, opts Options) (*Companion, error) {
+	return bootSimulator(ctx, cmdr, udid, deviceSetPath, true, opts)
 }
 
 // bootSimulator is the shared implementation for Boot and BootHeadless.
-func bootSimulator(cmdr Commander, udid, deviceSetPath string, headless bool) (*Companion, error) {
+func bootSimulator(ctx context.Context, cmdr Commander, udid, deviceSetPath string, headless bool, opts Options) (*Companion, error) {
 	args := []string{"--boot", udid}
 	if headless {
 		args = append(args, "--headless", "1")
@@ -274,55 +685,74 @@ func bootSimulator(cmdr Commander, udid, deviceSetPath string, headless bool) (*
 	if deviceSetPath != "" {
 		args = append(args, "--device-set-path", deviceSetPath)
 	}
+	events := newEvents()
+	emit(events, CompanionEvent{Kind: CompanionStarting})
+	cmd, _, err := withStartAttempts(ctx, opts, func() (CmdRunner, string, error) {
+		return bootOnce(ctx, cmdr, args, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := &Companion{
+		cmd:     cmd,
+		process: cmd.Process(),
+		done:    make(chan struct{}),
+		grace:   opts.stopGracePeriod(),
+		events:  events,
+	}
+	emit(events, CompanionEvent{Kind: CompanionBooted})
+	c.startMonitor()
+	return c, nil
+}
+
+// bootOnce runs idb_companion --boot once and waits for the Booted state.
+func bootOnce(ctx context.Context, cmdr Commander, args []string, opts Options) (CmdRunner, string, error) {
 	cmd := cmdr.Command("idb_companion", args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+		return nil, "", fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		_ = stdout.Close()
+		return nil, "", fmt.Errorf("creating stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("starting idb_companion boot: %w", err)
+		_ = stdout.Close()
+		_ = stderrPipe.Close()
+		return nil, "", fmt.Errorf("starting idb_companion boot: %w", err)
 	}
+	// Drained for the lifetime of the process, so it never blocks on a
+	// full stderr pipe; the tail explains a failed start.
+	stderr := captureStderr(stderrPipe)
 
 	// Wait for JSON output confirming boot (e.g. {"state":"Booted",...}).
-	scanner := bufio.NewScanner(stdout)
-	bootCh := make(chan struct{}, 1)
-	go func() {
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
-			}
-			var info map[string]any
-			if err := json.Unmarshal([]byte(line), &info); err == nil {
-				if state, ok := info["state"].(string); ok && state == "Booted" {
-					bootCh <- struct{}{}
-					return
-				}
+	s := scanStdout(stdout, "idb_companion boot", func(line string) string {
+		var info map[string]any
+		if err := json.Unmarshal([]byte(line), &info); err == nil {
+			if state, ok := info["state"].(string); ok && state == "Booted" {
+				return state
 			}
 		}
-		close(bootCh)
-	}()
+		return ""
+	})
 
+	timeout := opts.bootTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 	select {
-	case _, ok := <-bootCh:
+	case state, ok := <-s.found:
 		if !ok {
-			if proc := cmd.Process(); proc != nil {
-				_ = procgroup.KillProcess(proc)
-			}
-			return nil, fmt.Errorf("idb_companion boot did not report Booted state")
-		}
-		c := &Companion{
-			cmd:     cmd,
-			process: cmd.Process(),
-			done:    make(chan struct{}),
-		}
-		c.startMonitor()
-		return c, nil
-	case <-time.After(120 * time.Second):
-		if proc := cmd.Process(); proc != nil {
-			_ = procgroup.KillProcess(proc)
+			s.abort(cmd)
+			return nil, "", stderr.annotate(fmt.Errorf("idb_companion boot did not report Booted state: %w", errExitedEarly))
 		}
-		return nil, fmt.Errorf("timed out waiting for simulator boot (120s)")
+		return cmd, state, nil
+	case <-timer.C:
+		s.abort(cmd)
+		return nil, "", stderr.annotate(fmt.Errorf("companion did not boot within %s", timeout))
+	case <-ctx.Done():
+		s.abort(cmd)
+		return nil, "", fmt.Errorf("waiting for simulator boot: %w", ctx.Err())
 	}
 }
@@ -1,12 +1,17 @@
 package idb
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/k-kohey/axe/internal/procgroup"
 )
 
 // fakeCmd implements CmdRunner for testing.
@@ -17,6 +22,7 @@ type fakeCmd struct {
 	stdoutPW    *os.File
 	onPipeReady func()
 	waitCh      chan error // if set, Wait blocks until a value is sent
+	stderr      string     // written to the stderr pipe, which is then closed
 }
 
 func (f *fakeCmd) StdoutPipe() (*os.File, error) {
@@ -32,6 +38,16 @@ func (f *fakeCmd) StdoutPipe() (*os.File, error) {
 	return pr, nil
 }
 
+func (f *fakeCmd) StderrPipe() (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	_, _ = pw.WriteString(f.stderr)
+	_ = pw.Close()
+	return pr, nil
+}
+
 func (f *fakeCmd) Start() error {
 	f.started = true
 	return nil
@@ -90,6 +106,7 @@ func writeToPipe(cmdr *fakeCommander, lines ...string) {
 }
 
 func TestStartWith_Success(t *testing.T) {
+	t.Setenv(HostEnv, "")
 	cmdr := newFakeCommander()
 
 	go writeToPipe(cmdr, `{"grpc_swift_port":10882,"grpc_port":10882}`+"\n")
@@ -118,6 +135,39 @@ func TestStartWith_Success(t *testing.T) {
 	if !strings.Contains(args, "--grpc-port 0") {
 		t.Errorf("expected --grpc-port 0 in args: %s", args)
 	}
+	if strings.Contains(args, "--grpc-host") {
+		t.Errorf("unexpected --grpc-host without %s: %s", HostEnv, args)
+	}
+}
+
+func TestStartWith_CustomHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "192.168.1.20", want: "192.168.1.20:10882"},
+		{host: "mac-runner.local", want: "mac-runner.local:10882"},
+		{host: "fd00::1", want: "[fd00::1]:10882"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			t.Setenv(HostEnv, tt.host)
+			cmdr := newFakeCommander()
+
+			go writeToPipe(cmdr, `{"grpc_swift_port":10882,"grpc_port":10882}`+"\n")
+
+			companion, err := StartWith(cmdr, "UDID-123", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if companion.Address() != tt.want {
+				t.Errorf("expected address %s, got %s", tt.want, companion.Address())
+			}
+			if args := strings.Join(cmdr.lastArgs, " "); !strings.Contains(args, "--grpc-host "+tt.host) {
+				t.Errorf("expected --grpc-host %s in args: %s", tt.host, args)
+			}
+		})
+	}
 }
 
 func TestStartWith_EmptyPort(t *testing.T) {
@@ -136,28 +186,59 @@ func TestStartWith_EmptyPort(t *testing.T) {
 
 func TestParseCompanionPort(t *testing.T) {
 	tests := []struct {
-		name string
-		line string
-		want string
+		name     string
+		line     string
+		want     companionPorts
+		wantPort string
 	}{
-		{"valid JSON", `{"grpc_swift_port":10882,"grpc_port":10882}`, "10882"},
-		{"only grpc_port", `{"grpc_port":9999}`, "9999"},
-		{"port zero", `{"grpc_port":0}`, ""},
-		{"empty JSON", `{}`, ""},
-		{"not JSON", `IDB Companion Built at Aug 12 2022`, ""},
-		{"empty string", ``, ""},
-		{"log line", `Providing targets across Simulator and Device sets.`, ""},
+		{"valid JSON", `{"grpc_swift_port":10882,"grpc_port":10882}`, companionPorts{"10882", "10882"}, "10882"},
+		{"only grpc_port", `{"grpc_port":9999}`, companionPorts{GRPCPort: "9999"}, "9999"},
+		{"only grpc_swift_port", `{"grpc_swift_port":9998}`, companionPorts{GRPCSwiftPort: "9998"}, "9998"},
+		{"ports differ", `{"grpc_swift_port":10883,"grpc_port":10882}`, companionPorts{"10882", "10883"}, "10883"},
+		{"ports differ, swift first", `{"grpc_port":10882,"grpc_swift_port":10883}`, companionPorts{"10882", "10883"}, "10883"},
+		{"swift port zero", `{"grpc_swift_port":0,"grpc_port":10882}`, companionPorts{GRPCPort: "10882"}, "10882"},
+		{"port zero", `{"grpc_port":0}`, companionPorts{}, ""},
+		{"empty JSON", `{}`, companionPorts{}, ""},
+		{"not JSON", `IDB Companion Built at Aug 12 2022`, companionPorts{}, ""},
+		{"empty string", ``, companionPorts{}, ""},
+		{"log line", `Providing targets across Simulator and Device sets.`, companionPorts{}, ""},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			got := parseCompanionPort(tc.line)
 			if got != tc.want {
-				t.Errorf("parseCompanionPort(%q) = %q, want %q", tc.line, got, tc.want)
+				t.Errorf("parseCompanionPort(%q) = %+v, want %+v", tc.line, got, tc.want)
+			}
+			if got.port() != tc.wantPort {
+				t.Errorf("port() = %q, want %q", got.port(), tc.wantPort)
 			}
 		})
 	}
 }
 
+func TestStartWith_SwiftPort(t *testing.T) {
+	cmdr := newFakeCommander()
+	go writeToPipe(cmdr, `{"grpc_swift_port":10883,"grpc_port":10882}`+"\n")
+
+	c, err := StartWith(cmdr, "SWIFT-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port() != "10883" || c.SwiftPort() != "10883" {
+		t.Errorf("Port/SwiftPort = %s/%s, want the Swift port for both", c.Port(), c.SwiftPort())
+	}
+
+	cmdr = newFakeCommander()
+	go writeToPipe(cmdr, `{"grpc_port":10882}`+"\n")
+	c, err = StartWith(cmdr, "SWIFT-2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port() != "10882" || c.SwiftPort() != "" {
+		t.Errorf("Port/SwiftPort = %s/%s, want grpc_port and no Swift port", c.Port(), c.SwiftPort())
+	}
+}
+
 func TestStartWith_LogLinesBeforePort(t *testing.T) {
 	cmdr := newFakeCommander()
 
@@ -177,6 +258,71 @@ func TestStartWith_LogLinesBeforePort(t *testing.T) {
 	}
 }
 
+func TestStartWith_PortSplitAcrossWrites(t *testing.T) {
+	cmdr := newFakeCommander()
+
+	// The port JSON arrives in two writes; only the second carries the newline.
+	go func() {
+		<-cmdr.pipeReady
+		pw := cmdr.lastCmd.stdoutPW
+		_, _ = pw.WriteString(`{"grpc_swift_port":12345,"grp`)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = pw.WriteString(`c_port":12345}` + "\n")
+		_ = pw.Close()
+	}()
+
+	companion, err := StartWith(cmdr, "UDID-SPLIT", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if companion.Port() != "12345" {
+		t.Errorf("expected port 12345, got %s", companion.Port())
+	}
+}
+
+func TestStartWith_LongLogLineBeforePort(t *testing.T) {
+	cmdr := newFakeCommander()
+
+	// A log line longer than bufio.Scanner's default 64 KiB limit must not
+	// stop the scan before the port line.
+	go writeToPipe(cmdr,
+		strings.Repeat("x", 200*1024)+"\n",
+		`{"grpc_swift_port":12345,"grpc_port":12345}`+"\n",
+	)
+
+	companion, err := StartWith(cmdr, "UDID-LONG", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if companion.Port() != "12345" {
+		t.Errorf("expected port 12345, got %s", companion.Port())
+	}
+}
+
+func TestStartWith_DrainsOutputAfterPort(t *testing.T) {
+	cmdr := newFakeCommander()
+
+	// Output written after the port line must still be consumed, or the
+	// companion would block once the pipe buffer fills.
+	written := make(chan struct{})
+	go func() {
+		writeToPipe(cmdr,
+			`{"grpc_swift_port":12345,"grpc_port":12345}`+"\n",
+			strings.Repeat("log line\n", 64*1024),
+		)
+		close(written)
+	}()
+
+	if _, err := StartWith(cmdr, "UDID-DRAIN", ""); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer blocked: companion output is not drained after the port line")
+	}
+}
+
 func TestStartWith_NoPortJSON(t *testing.T) {
 	cmdr := newFakeCommander()
 
@@ -393,3 +539,373 @@ func TestStartWith_DoneClosesOnExit(t *testing.T) {
 		t.Fatal("Done() did not close after process exited")
 	}
 }
+
+func TestStartWithContext_Cancel(t *testing.T) {
+	cmdr := newFakeCommander()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// idb_companion logs but never prints the port line or exits.
+	go func() {
+		<-cmdr.pipeReady
+		_, _ = cmdr.lastCmd.stdoutPW.WriteString("starting up\n")
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := StartWithContext(ctx, cmdr, "UDID-123", "", Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if !strings.Contains(err.Error(), "idb_companion port") {
+		t.Errorf("error does not say what was being waited for: %v", err)
+	}
+	// The read end was closed, so nothing keeps the pipe open.
+	if _, err := cmdr.lastCmd.stdoutPR.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("reading stdout after cancel: err = %v, want os.ErrClosed", err)
+	}
+	_ = cmdr.lastCmd.stdoutPW.Close()
+}
+
+func TestBootHeadlessWithContext_Deadline(t *testing.T) {
+	cmdr := newFakeCommander()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := BootHeadlessWithContext(ctx, cmdr, "UDID-123", "", Options{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if !strings.Contains(err.Error(), "waiting for simulator boot") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_ = cmdr.lastCmd.stdoutPW.Close()
+}
+
+func TestBootHeadlessWithContext_BootTimeout(t *testing.T) {
+	cmdr, waitCh := newBlockingFakeCommander()
+
+	// idb_companion logs but never reports the Booted state.
+	go func() {
+		<-cmdr.pipeReady
+		_, _ = cmdr.lastCmd.stdoutPW.WriteString(`{"state":"Booting"}` + "\n")
+	}()
+
+	start := time.Now()
+	_, err := BootHeadlessWithContext(context.Background(), cmdr, "UDID-123", "", Options{BootTimeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if want := "companion did not boot within 50ms"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s, want the boot timeout to end the wait", elapsed)
+	}
+	waitCh <- nil
+	_ = cmdr.lastCmd.stdoutPW.Close()
+}
+
+func TestBootWithContext_BootTimeout(t *testing.T) {
+	cmdr, waitCh := newBlockingFakeCommander()
+
+	go func() {
+		<-cmdr.pipeReady
+		_, _ = cmdr.lastCmd.stdoutPW.WriteString(`{"state":"Booting"}` + "\n")
+	}()
+
+	_, err := BootWithContext(context.Background(), cmdr, "UDID-123", "", Options{BootTimeout: 50 * time.Millisecond})
+	if want := "companion did not boot within 50ms"; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+	if strings.Contains(strings.Join(cmdr.lastArgs, " "), "--headless") {
+		t.Errorf("non-headless boot should not include --headless in args: %v", cmdr.lastArgs)
+	}
+	waitCh <- nil
+	_ = cmdr.lastCmd.stdoutPW.Close()
+}
+
+func TestOptions_Defaults(t *testing.T) {
+	var o Options
+	if o.bootTimeout() != DefaultBootTimeout || o.startTimeout() != DefaultStartTimeout {
+		t.Errorf("zero Options = %s/%s, want the defaults", o.startTimeout(), o.bootTimeout())
+	}
+	o = Options{StartTimeout: time.Second, BootTimeout: 2 * time.Second}
+	if o.startTimeout() != time.Second || o.bootTimeout() != 2*time.Second {
+		t.Errorf("Options = %s/%s, want the configured timeouts", o.startTimeout(), o.bootTimeout())
+	}
+}
+
+// newScriptedFakeCommander creates a fakeCommander whose n-th command prints
+// outputs[n] and closes stdout. Commands past the end print nothing.
+func newScriptedFakeCommander(outputs ...string) (*fakeCommander, *[]*fakeCmd) {
+	cmdr := &fakeCommander{}
+	var cmds []*fakeCmd
+	cmdr.commandFn = func(name string, args ...string) CmdRunner {
+		cmdr.mu.Lock()
+		defer cmdr.mu.Unlock()
+		var out string
+		if len(cmds) < len(outputs) {
+			out = outputs[len(cmds)]
+		}
+		cmd := &fakeCmd{}
+		cmd.onPipeReady = func() {
+			go func() {
+				_, _ = cmd.stdoutPW.WriteString(out)
+				_ = cmd.stdoutPW.Close()
+			}()
+		}
+		cmds = append(cmds, cmd)
+		cmdr.lastCmd = cmd
+		cmdr.lastArgs = append([]string{name}, args...)
+		return cmd
+	}
+	return cmdr, &cmds
+}
+
+func withFastStartRetry(t *testing.T) {
+	t.Helper()
+	delay := startRetryDelay
+	startRetryDelay = time.Millisecond
+	t.Cleanup(func() { startRetryDelay = delay })
+}
+
+func TestStartWithContext_RetriesEarlyExit(t *testing.T) {
+	withFastStartRetry(t)
+	cmdr, cmds := newScriptedFakeCommander(
+		"objc[1]: Class FBSimulator is implemented in both...\n",
+		`{"grpc_swift_port":10882,"grpc_port":10882}`+"\n",
+	)
+
+	companion, err := StartWithContext(context.Background(), cmdr, "UDID-123", "", Options{MaxStartAttempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if companion.Port() != "10882" {
+		t.Errorf("port = %s, want 10882 from the second attempt", companion.Port())
+	}
+	if len(*cmds) != 2 {
+		t.Errorf("ran idb_companion %d times, want 2", len(*cmds))
+	}
+	if first, second := (*cmds)[0], (*cmds)[1]; first.stdoutPR == second.stdoutPR || !second.started {
+		t.Error("expected the second attempt to start a fresh command with its own pipe")
+	}
+}
+
+func TestStartWithContext_GivesUpAfterMaxAttempts(t *testing.T) {
+	withFastStartRetry(t)
+	cmdr, cmds := newScriptedFakeCommander()
+
+	_, err := StartWithContext(context.Background(), cmdr, "UDID-123", "", Options{MaxStartAttempts: 2})
+	if err == nil || !strings.Contains(err.Error(), "did not output a port") {
+		t.Fatalf("err = %v, want the early-exit error of the last attempt", err)
+	}
+	if len(*cmds) != 2 {
+		t.Errorf("ran idb_companion %d times, want 2", len(*cmds))
+	}
+}
+
+func TestBootHeadlessWithContext_RetriesEarlyExit(t *testing.T) {
+	withFastStartRetry(t)
+	cmdr, cmds := newScriptedFakeCommander("", `{"state":"Booted","udid":"TEST"}`+"\n")
+
+	if _, err := BootHeadlessWithContext(context.Background(), cmdr, "TEST", "", Options{MaxStartAttempts: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*cmds) != 2 {
+		t.Errorf("ran idb_companion %d times, want 2", len(*cmds))
+	}
+}
+
+func TestStartWith_NoRetryByDefault(t *testing.T) {
+	withFastStartRetry(t)
+	cmdr, cmds := newScriptedFakeCommander()
+
+	if _, err := StartWith(cmdr, "UDID-123", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(*cmds) != 1 {
+		t.Errorf("ran idb_companion %d times, want 1 without MaxStartAttempts", len(*cmds))
+	}
+}
+
+func TestStartForDevice_Args(t *testing.T) {
+	t.Setenv(HostEnv, "")
+	cmdr := newFakeCommander()
+
+	go writeToPipe(cmdr, `{"grpc_swift_port":10882,"grpc_port":10882}`+"\n")
+
+	companion, err := StartForDevice(cmdr, "00008110-001A2B3C4D5E6F70")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if companion.Address() != "localhost:10882" {
+		t.Errorf("expected address localhost:10882, got %s", companion.Address())
+	}
+
+	args := strings.Join(cmdr.lastArgs, " ")
+	for _, want := range []string{"--udid 00008110-001A2B3C4D5E6F70", "--grpc-port 0", "--only device"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %s in args: %s", want, args)
+		}
+	}
+	if strings.Contains(args, "--device-set-path") {
+		t.Errorf("unexpected --device-set-path for a physical device: %s", args)
+	}
+}
+
+func TestStartWith_NoDeviceTargetFilter(t *testing.T) {
+	cmdr := newFakeCommander()
+
+	go writeToPipe(cmdr, `{"grpc_swift_port":10882,"grpc_port":10882}`+"\n")
+
+	if _, err := StartWith(cmdr, "UDID-123", "/tmp/axe-devices"); err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(cmdr.lastArgs, " "); strings.Contains(args, "--only") {
+		t.Errorf("unexpected --only for a simulator: %s", args)
+	}
+}
+
+func TestStartWith_StderrInError(t *testing.T) {
+	cmdr := newFakeCommander()
+	cmdr.commandFn = func(name string, args ...string) CmdRunner {
+		cmd := &fakeCmd{
+			stderr: "objc[42]: Class FBSimulatorControl is implemented twice\n" +
+				"Error Domain=com.facebook.FBControlCore Code=0 \"No target with udid UDID-404\"\n",
+		}
+		cmd.onPipeReady = func() { _ = cmd.stdoutPW.Close() }
+		cmdr.lastCmd = cmd
+		return cmd
+	}
+
+	_, err := StartWith(cmdr, "UDID-404", "")
+	if err == nil {
+		t.Fatal("expected error when no port JSON is output")
+	}
+	for _, want := range []string{"did not output a port", `No target with udid UDID-404`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q: %v", want, err)
+		}
+	}
+}
+
+func TestStderrTail_KeepsLastBytes(t *testing.T) {
+	tail := &stderrTail{done: make(chan struct{})}
+	close(tail.done)
+	_, _ = tail.Write([]byte(strings.Repeat("a", maxStderrTail)))
+	_, _ = tail.Write([]byte("last line"))
+
+	got := tail.String()
+	if len(got) != maxStderrTail || !strings.HasSuffix(got, "last line") {
+		t.Errorf("tail has %d bytes ending %q, want the last %d bytes", len(got), got[len(got)-9:], maxStderrTail)
+	}
+	if err := tail.annotate(errors.New("boom")); !strings.HasPrefix(err.Error(), "boom\nidb_companion stderr:\n") {
+		t.Errorf("annotate = %q", err)
+	}
+
+	empty := &stderrTail{done: make(chan struct{})}
+	close(empty.done)
+	base := errors.New("boom")
+	if err := empty.annotate(base); err != base {
+		t.Errorf("annotate without stderr = %v, want the error unchanged", err)
+	}
+}
+
+// scriptCommander runs a shell script in place of idb_companion, for tests
+// that need a real process to signal.
+type scriptCommander struct{ script string }
+
+func (s scriptCommander) Command(string, ...string) CmdRunner {
+	return DefaultCommander().Command("sh", "-c", s.script)
+}
+
+func TestCompanion_KillIgnoredSIGTERM(t *testing.T) {
+	// Ignores SIGTERM like a wedged companion; Stop would wait out its grace
+	// period before escalating.
+	cmdr := scriptCommander{script: `trap '' TERM; echo '{"grpc_port":12345}'; exec sleep 30`}
+	c, err := StartWith(cmdr, "KILL-1", "")
+	if err != nil {
+		t.Skip("cannot run sh:", err)
+	}
+	if c.PID() == 0 {
+		t.Fatal("PID = 0 for a started companion")
+	}
+
+	if err := procgroup.SignalProcess(c.process, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Done():
+		t.Fatal("the script did not ignore SIGTERM")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	killed := make(chan error, 1)
+	go func() { killed <- c.Kill() }()
+	select {
+	case err := <-killed:
+		if err != nil {
+			t.Fatalf("Kill = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Kill did not return promptly")
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done not closed after Kill returned")
+	}
+	if err := c.Kill(); err != nil {
+		t.Errorf("Kill after exit = %v", err)
+	}
+}
+
+func TestCompanion_PIDWithoutProcess(t *testing.T) {
+	c := &Companion{done: make(chan struct{})}
+	if c.PID() != 0 {
+		t.Errorf("PID = %d, want 0", c.PID())
+	}
+	if err := c.Kill(); err != nil {
+		t.Errorf("Kill = %v, want nil without a process", err)
+	}
+}
+
+func TestCompanion_StopOutcomes(t *testing.T) {
+	const port = `echo '{"grpc_port":12345}'`
+	start := func(t *testing.T, script string) *Companion {
+		t.Helper()
+		opts := Options{StopGracePeriod: 100 * time.Millisecond}
+		c, err := StartWithContext(context.Background(), scriptCommander{script: script}, "STOP-1", "", opts)
+		if err != nil {
+			t.Skip("cannot run sh:", err)
+		}
+		return c
+	}
+
+	t.Run("exits on SIGTERM", func(t *testing.T) {
+		c := start(t, port+"; exec sleep 30")
+		if err := c.Stop(); err != nil {
+			t.Errorf("Stop = %v, want nil", err)
+		}
+	})
+
+	t.Run("killed after grace period", func(t *testing.T) {
+		c := start(t, `trap '' TERM; `+port+"; exec sleep 30")
+		begin := time.Now()
+		err := c.Stop()
+		if !errors.Is(err, ErrStopTimeout) {
+			t.Errorf("Stop = %v, want ErrStopTimeout", err)
+		}
+		if elapsed := time.Since(begin); elapsed > 2*time.Second {
+			t.Errorf("Stop took %s, want about the 100ms grace period", elapsed)
+		}
+	})
+
+	t.Run("already exited", func(t *testing.T) {
+		c := start(t, port)
+		<-c.Done()
+		if err := c.Stop(); !errors.Is(err, ErrNotRunning) {
+			t.Errorf("Stop = %v, want ErrNotRunning", err)
+		}
+	})
+}
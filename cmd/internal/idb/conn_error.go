@@ -0,0 +1,122 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCompanionUnreachable is matched (via errors.Is) by errors caused by
+// failing to reach idb_companion's gRPC server, as opposed to errors
+// idb_companion itself returned.
+var ErrCompanionUnreachable = errors.New("idb_companion unreachable")
+
+// UnreachableError describes a failed connection to idb_companion: the
+// address tried, the kind of failure, and what the user can do about it.
+type UnreachableError struct {
+	Addr   string // host:port that was dialed
+	Reason string // e.g. "connection refused"
+	Hint   string // actionable guidance for the user
+	Err    error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("cannot reach idb_companion at %s (%s): %s: %v", e.Addr, e.Reason, e.Hint, e.Err)
+}
+
+func (e *UnreachableError) Unwrap() error { return e.Err }
+
+func (e *UnreachableError) Is(target error) bool { return target == ErrCompanionUnreachable }
+
+// classifyConnError maps err from a call to idb_companion at addr to an
+// *UnreachableError when it is a connection failure. Other errors,
+// including cancellation, are returned unchanged.
+func classifyConnError(addr string, err error) error {
+	if err == nil || errors.Is(err, ErrCompanionUnreachable) ||
+		errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+		return err
+	}
+	// gRPC flattens the dial error into the status message, so the
+	// syscall errors can only be recognized by their text.
+	msg := strings.ToLower(err.Error())
+	has := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(msg, s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, port, _ := net.SplitHostPort(addr)
+	e := &UnreachableError{Addr: addr, Err: err}
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED) || has("connection refused"):
+		e.Reason = "connection refused"
+		e.Hint = "nothing is listening on the port yet; idb_companion may still be starting or may have exited"
+	case has("tls:", "handshake", "server preface", "connection reset by peer", "http2: frame too large"):
+		e.Reason = "handshake failed"
+		e.Hint = fmt.Sprintf("the port may be in use by another process that does not speak idb's gRPC; check it with `lsof -nP -i :%s`", port)
+	case errors.Is(err, os.ErrDeadlineExceeded) || has("i/o timeout", "operation timed out"):
+		e.Reason = "connection timed out"
+		e.Hint = "a firewall may be blocking connections to localhost; allow incoming connections for idb_companion"
+	case errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) ||
+		has("operation not permitted", "permission denied", "no route to host", "network is unreachable"):
+		e.Reason = "connection blocked"
+		e.Hint = "a firewall may be blocking connections to localhost; allow incoming connections for idb_companion"
+	case status.Code(err) == codes.Unavailable:
+		e.Reason = "unavailable"
+		e.Hint = "idb_companion is not accepting connections; make sure it is still running"
+	default:
+		return err
+	}
+	return e
+}
+
+// transient reports whether the failure may clear up by itself because
+// idb_companion is still starting or restarting, rather than pointing at
+// something the user has to fix.
+func (e *UnreachableError) transient() bool {
+	return e.Reason == "connection refused" || e.Reason == "unavailable"
+}
+
+// Retries of calls that fail to reach idb_companion transiently: up to
+// connAttempts calls, waiting connRetryDelay after the first failure and
+// doubling it on each further one up to maxConnRetryDelay.
+var (
+	connAttempts      = 4
+	connRetryDelay    = 100 * time.Millisecond
+	maxConnRetryDelay = time.Second
+)
+
+// withConnRetry calls fn, a call to idb_companion at addr, until it
+// succeeds or fails with anything but a transient connection failure,
+// backing off between attempts. The last error is returned classified by
+// classifyConnError.
+func withConnRetry[T any](ctx context.Context, addr string, fn func() (T, error)) (T, error) {
+	delay := connRetryDelay
+	for attempt := 1; ; attempt++ {
+		v, err := fn()
+		err = classifyConnError(addr, err)
+		var ue *UnreachableError
+		if err == nil || attempt >= connAttempts || !errors.As(err, &ue) || !ue.transient() {
+			return v, err
+		}
+		slog.Debug("idb_companion unreachable, retrying", "addr", addr, "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return v, err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxConnRetryDelay)
+	}
+}
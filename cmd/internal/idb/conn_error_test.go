@@ -0,0 +1,192 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyConnError(t *testing.T) {
+	const addr = "localhost:10882"
+	unavailable := func(dial string) error {
+		return status.Error(codes.Unavailable, `connection error: desc = "transport: Error while dialing: `+dial+`"`)
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantReason string
+		wantHint   string
+	}{
+		{
+			name:       "connection refused",
+			err:        unavailable("dial tcp [::1]:10882: connect: connection refused"),
+			wantReason: "connection refused",
+			wantHint:   "still be starting",
+		},
+		{
+			name:       "raw refused syscall error",
+			err:        &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			wantReason: "connection refused",
+			wantHint:   "still be starting",
+		},
+		{
+			name:       "tls handshake",
+			err:        unavailable("tls: first record does not look like a TLS handshake"),
+			wantReason: "handshake failed",
+			wantHint:   "lsof -nP -i :10882",
+		},
+		{
+			name:       "http server on the port",
+			err:        status.Error(codes.Unavailable, `connection error: desc = "error reading server preface: http2: frame too large"`),
+			wantReason: "handshake failed",
+			wantHint:   "in use by another process",
+		},
+		{
+			name:       "timeout",
+			err:        unavailable("dial tcp 127.0.0.1:10882: i/o timeout"),
+			wantReason: "connection timed out",
+			wantHint:   "firewall",
+		},
+		{
+			name:       "blocked",
+			err:        unavailable("dial tcp 127.0.0.1:10882: connect: operation not permitted"),
+			wantReason: "connection blocked",
+			wantHint:   "firewall",
+		},
+		{
+			name:       "other unavailable",
+			err:        status.Error(codes.Unavailable, "transport is closing"),
+			wantReason: "unavailable",
+			wantHint:   "still running",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyConnError(addr, fmt.Errorf("starting video stream: %w", tt.err))
+			var ue *UnreachableError
+			if !errors.As(err, &ue) {
+				t.Fatalf("classifyConnError() = %v, want *UnreachableError", err)
+			}
+			if ue.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", ue.Reason, tt.wantReason)
+			}
+			if !strings.Contains(ue.Hint, tt.wantHint) {
+				t.Errorf("Hint = %q, want to contain %q", ue.Hint, tt.wantHint)
+			}
+			if !strings.Contains(err.Error(), addr) {
+				t.Errorf("error %q does not name the address %s", err, addr)
+			}
+			if !errors.Is(err, ErrCompanionUnreachable) || !errors.Is(err, tt.err) {
+				t.Errorf("error %v should match ErrCompanionUnreachable and wrap the cause", err)
+			}
+		})
+	}
+}
+
+func TestClassifyConnError_PassesThroughOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		nil,
+		context.Canceled,
+		status.Error(codes.Canceled, "context canceled"),
+		status.Error(codes.Internal, "simulator crashed"),
+		errors.New("no screen dimensions in target description"),
+	} {
+		if got := classifyConnError("localhost:10882", err); got != err {
+			t.Errorf("classifyConnError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
+
+// setConnRetryDelay shortens the delays of withConnRetry for the rest of
+// the test. Tests using it must not be parallel.
+func setConnRetryDelay(t *testing.T, d time.Duration) {
+	t.Helper()
+	prev, prevMax := connRetryDelay, maxConnRetryDelay
+	connRetryDelay, maxConnRetryDelay = d, d
+	t.Cleanup(func() { connRetryDelay, maxConnRetryDelay = prev, prevMax })
+}
+
+func TestWithConnRetry(t *testing.T) {
+	setConnRetryDelay(t, time.Millisecond)
+	const addr = "localhost:10882"
+	refused := status.Error(codes.Unavailable, "connection error: desc = \"transport: Error while dialing: dial tcp [::1]:10882: connect: connection refused\"")
+	handshake := status.Error(codes.Unavailable, "connection error: desc = \"transport: authentication handshake failed: tls: first record does not look like a TLS handshake\"")
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive calls; nil once exhausted
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "succeeds once the companion is up", errs: []error{refused, refused}, wantCalls: 3},
+		{name: "handshake failures are not retried", errs: []error{handshake}, wantCalls: 1, wantErr: true},
+		{name: "other errors are not retried", errs: []error{status.Error(codes.Internal, "simulator crashed")}, wantCalls: 1, wantErr: true},
+		{name: "gives up after connAttempts", errs: []error{refused, refused, refused, refused, refused}, wantCalls: connAttempts, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, err := withConnRetry(context.Background(), addr, func() (int, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return 0, tt.errs[calls-1]
+				}
+				return 42, nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != 42 {
+				t.Errorf("result = %d, want 42", got)
+			}
+			if tt.wantErr && calls > 1 && !errors.Is(err, ErrCompanionUnreachable) {
+				t.Errorf("err = %v, want ErrCompanionUnreachable after retries", err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	_, err := withConnRetry(ctx, addr, func() (int, error) { calls++; return 0, refused })
+	if calls != 1 || !errors.Is(err, ErrCompanionUnreachable) {
+		t.Errorf("with a canceled context: calls = %d, err = %v, want 1 call and ErrCompanionUnreachable", calls, err)
+	}
+}
+
+func TestClient_ScreenSize_Unreachable(t *testing.T) {
+	setConnRetryDelay(t, time.Millisecond)
+	// Reserve a port, then close it so that nothing listens there.
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	client, err := NewClient(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	_, _, err = client.ScreenSize(context.Background())
+	if !errors.Is(err, ErrCompanionUnreachable) {
+		t.Fatalf("ScreenSize error = %v, want ErrCompanionUnreachable", err)
+	}
+	if !strings.Contains(err.Error(), addr) {
+		t.Errorf("error %q does not name the address %s", err, addr)
+	}
+}
@@ -0,0 +1,167 @@
+package idb
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// companionState is what a started companion records so another axe
+// process can reuse it (see DiscoverRunning).
+type companionState struct {
+	UDID      string `json:"udid"`
+	PID       int    `json:"pid"`
+	Port      string `json:"port"`
+	SwiftPort string `json:"swift_port,omitempty"`
+}
+
+// discoverDialTimeout bounds the reachability check of a recorded port.
+const discoverDialTimeout = 500 * time.Millisecond
+
+// discoverPollInterval is how often a discovered companion's process is
+// checked for exit.
+var discoverPollInterval = time.Second
+
+// stateFileName is the file in Options.StateDir where a started companion
+// records itself.
+const stateFileName = "idb_companion.json"
+
+func statePath(dir string) string {
+	return filepath.Join(dir, stateFileName)
+}
+
+// state returns what c records about its current process.
+func (c *Companion) state() companionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := companionState{UDID: c.udid, Port: c.port, SwiftPort: c.swiftPort}
+	if c.process != nil {
+		s.PID = c.process.Pid
+	}
+	return s
+}
+
+// saveState records c's process in opts.StateDir for DiscoverRunning.
+// Failures only cost the reuse, so they are logged and ignored.
+func (c *Companion) saveState() {
+	if c.opts.StateDir == "" || c.udid == "" || c.discovered {
+		return
+	}
+	data, err := json.Marshal(c.state())
+	if err != nil {
+		return
+	}
+	path := statePath(c.opts.StateDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		slog.Debug("Cannot record idb_companion state", "err", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		slog.Debug("Cannot record idb_companion state", "err", err)
+	}
+}
+
+// removeState deletes c's state file, unless another companion has
+// recorded itself in the same directory since.
+func (c *Companion) removeState() {
+	if c.opts.StateDir == "" || c.udid == "" || c.discovered {
+		return
+	}
+	path := statePath(c.opts.StateDir)
+	s, ok := readState(path)
+	if !ok || s != c.state() {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+func readState(path string) (companionState, bool) {
+	var s companionState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, false
+	}
+	if err := json.Unmarshal(data, &s); err != nil || s.Port == "" {
+		return s, false
+	}
+	return s, true
+}
+
+// processAlive reports whether pid is a running process. pid 0 means the
+// process is unknown and is treated as alive.
+func processAlive(pid int) bool {
+	if pid == 0 {
+		return true
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// DiscoverRunning returns the companion another axe process started for
+// udid with Options.StateDir set to stateDir, if its process is still alive
+// and its port accepts connections. The returned companion does not own the
+// process: Stop leaves it running, and Done is closed when the process
+// exits (or after Stop).
+func DiscoverRunning(stateDir, udid string) (*Companion, bool) {
+	if stateDir == "" {
+		return nil, false
+	}
+	path := statePath(stateDir)
+	s, ok := readState(path)
+	if !ok || s.UDID != udid {
+		return nil, false
+	}
+	if !processAlive(s.PID) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	host := companionHost()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, s.Port), discoverDialTimeout)
+	if err != nil {
+		slog.Debug("Recorded idb_companion is not reachable", "udid", udid, "port", s.Port, "err", err)
+		return nil, false
+	}
+	_ = conn.Close()
+
+	c := &Companion{
+		host:       host,
+		udid:       udid,
+		port:       s.Port,
+		swiftPort:  s.SwiftPort,
+		done:       make(chan struct{}),
+		discovered: true,
+		release:    make(chan struct{}),
+		events:     newEvents(),
+	}
+	if s.PID != 0 {
+		c.process, _ = os.FindProcess(s.PID) // never fails on Unix
+	}
+	go c.watchDiscovered(s.PID)
+	return c, true
+}
+
+// watchDiscovered closes done once pid exits or Stop releases c.
+func (c *Companion) watchDiscovered(pid int) {
+	defer close(c.events)
+	defer close(c.done)
+	ticker := time.NewTicker(discoverPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.release:
+			return
+		case <-ticker.C:
+			if !processAlive(pid) {
+				emit(c.events, CompanionEvent{Kind: CompanionExited})
+				c.mu.Lock()
+				c.exited = true
+				c.mu.Unlock()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package idb
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Fake companions report ports nobody listens on; tests of the
+	// readiness probe restore the real dialer.
+	dialCompanion = func(context.Context, string) (net.Conn, error) {
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+	os.Exit(m.Run())
+}
+
+// listen returns the port of a TCP listener standing in for a companion.
+func listen(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return port
+}
+
+func TestDiscoverRunning_ReusesStartedCompanion(t *testing.T) {
+	t.Setenv(HostEnv, "")
+	port := listen(t)
+	dir := t.TempDir()
+
+	// A previous axe process started a companion for the UDID and recorded it.
+	first := &Companion{udid: "REUSE-1", port: port, opts: Options{StateDir: dir}}
+	first.saveState()
+
+	if _, ok := DiscoverRunning(dir, "REUSE-2"); ok {
+		t.Error("discovered the companion of another UDID")
+	}
+	c, ok := DiscoverRunning(dir, "REUSE-1")
+	if !ok {
+		t.Fatal("expected the recorded companion to be discovered")
+	}
+	if c.Port() != port || c.Address() != "localhost:"+port {
+		t.Errorf("Port/Address = %s/%s, want the recorded port", c.Port(), c.Address())
+	}
+	if _, err := c.EnableAutoRestart(newFakeCommander(), "REUSE-1", "", DefaultRestartPolicy); err == nil {
+		t.Error("expected auto-restart to be refused for a discovered companion")
+	}
+
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done did not close after Stop")
+	}
+	if _, err := os.Stat(statePath(dir)); err != nil {
+		t.Errorf("Stop of a discovered companion removed the owner's state: %v", err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Errorf("second Stop = %v", err)
+	}
+}
+
+func TestDiscoverRunning_NotFound(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := DiscoverRunning(dir, "NOTFOUND-1"); ok {
+		t.Error("discovered a companion without a state file")
+	}
+
+	// The recorded port no longer accepts connections.
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	_ = ln.Close()
+	(&Companion{udid: "NOTFOUND-1", port: port, opts: Options{StateDir: dir}}).saveState()
+	if _, ok := DiscoverRunning(dir, "NOTFOUND-1"); ok {
+		t.Error("discovered a companion whose port is closed")
+	}
+
+	if err := os.WriteFile(statePath(dir), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := DiscoverRunning(dir, "NOTFOUND-1"); ok {
+		t.Error("discovered a companion from a corrupt state file")
+	}
+}
+
+func TestDiscoverRunning_DeadProcess(t *testing.T) {
+	port := listen(t)
+	dir := t.TempDir()
+
+	// A reachable port recorded by a process that has exited is stale.
+	cmd := DefaultCommander().Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Skip("cannot run true:", err)
+	}
+	pid := cmd.Process().Pid
+	_ = cmd.Wait()
+	c := &Companion{udid: "DEAD-1", port: port, process: &os.Process{Pid: pid}, opts: Options{StateDir: dir}}
+	c.saveState()
+
+	if _, ok := DiscoverRunning(dir, "DEAD-1"); ok {
+		t.Error("discovered a companion whose process has exited")
+	}
+	if _, err := os.Stat(statePath(dir)); !os.IsNotExist(err) {
+		t.Errorf("stale state file kept: %v", err)
+	}
+}
+
+func TestStartWith_RecordsState(t *testing.T) {
+	t.Setenv(HostEnv, "")
+	cmdr, exits := newRestartingFakeCommander(1)
+	dir := t.TempDir()
+
+	c, err := StartWithContext(context.Background(), cmdr, "RECORD-1", "", Options{StateDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := readState(statePath(dir))
+	if !ok || s.Port != "10000" || s.UDID != "RECORD-1" {
+		t.Errorf("state = %+v, %v; want the started port", s, ok)
+	}
+
+	exits[0] <- nil
+	<-c.Done()
+	if _, err := os.Stat(statePath(dir)); !os.IsNotExist(err) {
+		t.Errorf("state file kept after the companion exited: %v", err)
+	}
+}
+
+func TestRemoveState_KeepsNewerCompanion(t *testing.T) {
+	opts := Options{StateDir: t.TempDir()}
+	old := &Companion{udid: "NEWER-1", port: "1", opts: opts}
+	old.saveState()
+	(&Companion{udid: "NEWER-1", port: "2", opts: opts}).saveState()
+
+	old.removeState()
+	if s, ok := readState(statePath(opts.StateDir)); !ok || s.Port != "2" {
+		t.Errorf("state = %+v, %v; want the newer companion kept", s, ok)
+	}
+}
+
+func TestStartWith_NoStateDir(t *testing.T) {
+	t.Setenv(HostEnv, "")
+	cmdr, exits := newRestartingFakeCommander(1)
+
+	c, err := StartWith(cmdr, "NOSTATE-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := DiscoverRunning("", "NOSTATE-1"); ok {
+		t.Error("discovered a companion started without a state directory")
+	}
+	exits[0] <- nil
+	<-c.Done()
+}
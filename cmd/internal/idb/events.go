@@ -0,0 +1,76 @@
+package idb
+
+import (
+	"log/slog"
+	"time"
+)
+
+// CompanionEventKind is a lifecycle transition of a Companion.
+type CompanionEventKind int
+
+const (
+	// CompanionStarting is sent when an idb_companion process is launched,
+	// including by the watchdog after a crash.
+	CompanionStarting CompanionEventKind = iota
+	// CompanionPortResolved is sent when the process has reported its gRPC
+	// port.
+	CompanionPortResolved
+	// CompanionBooted is sent when a Boot companion's simulator has booted.
+	CompanionBooted
+	// CompanionExited is sent when the process exits; Err is its exit error.
+	// The watchdog may start another process afterwards.
+	CompanionExited
+)
+
+func (k CompanionEventKind) String() string {
+	switch k {
+	case CompanionStarting:
+		return "starting"
+	case CompanionPortResolved:
+		return "port_resolved"
+	case CompanionBooted:
+		return "booted"
+	case CompanionExited:
+		return "exited"
+	default:
+		return "unknown"
+	}
+}
+
+// CompanionEvent describes one lifecycle transition.
+type CompanionEvent struct {
+	Kind CompanionEventKind
+	Time time.Time
+	Port string // for CompanionPortResolved
+	Err  error  // for CompanionExited
+}
+
+// maxPendingEvents is how many events Events buffers for a slow reader
+// before further ones are dropped.
+const maxPendingEvents = 32
+
+func newEvents() chan CompanionEvent {
+	return make(chan CompanionEvent, maxPendingEvents)
+}
+
+// emit sends ev on events without blocking; the process-wait goroutine must
+// never wait for a reader.
+func emit(events chan CompanionEvent, ev CompanionEvent) {
+	if events == nil {
+		return
+	}
+	ev.Time = time.Now()
+	select {
+	case events <- ev:
+	default:
+		slog.Debug("Dropped idb_companion event, nobody is reading", "event", ev.Kind)
+	}
+}
+
+// Events returns the companion's lifecycle events, from CompanionStarting
+// on (a companion from DiscoverRunning only reports CompanionExited). The
+// channel is closed once Done is. Reading is optional: events beyond a
+// small buffer are dropped rather than delaying the companion.
+func (c *Companion) Events() <-chan CompanionEvent {
+	return c.events
+}
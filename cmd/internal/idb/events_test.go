@@ -0,0 +1,126 @@
+package idb
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// drainEvents reads events until the channel is closed.
+func drainEvents(t *testing.T, events <-chan CompanionEvent) []CompanionEvent {
+	t.Helper()
+	var got []CompanionEvent
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("events not closed; got %v", got)
+			return nil
+		}
+	}
+}
+
+func eventKinds(events []CompanionEvent) []CompanionEventKind {
+	kinds := make([]CompanionEventKind, len(events))
+	for i, ev := range events {
+		kinds[i] = ev.Kind
+	}
+	return kinds
+}
+
+func TestCompanion_Events_Start(t *testing.T) {
+	cmdr, exits := newRestartingFakeCommander(1)
+	c, err := StartWith(cmdr, "EVENTS-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exits[0] <- nil
+
+	got := drainEvents(t, c.Events())
+	want := []CompanionEventKind{CompanionStarting, CompanionPortResolved, CompanionExited}
+	if !slices.Equal(eventKinds(got), want) {
+		t.Fatalf("events = %v, want %v", eventKinds(got), want)
+	}
+	if got[1].Port != "10000" {
+		t.Errorf("PortResolved port = %q, want 10000", got[1].Port)
+	}
+	if got[2].Err != nil {
+		t.Errorf("Exited err = %v, want nil", got[2].Err)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Time.Before(got[i-1].Time) {
+			t.Errorf("event %d is timestamped before the previous one", i)
+		}
+	}
+}
+
+func TestCompanion_Events_Boot(t *testing.T) {
+	cmdr := newFakeCommander()
+	go writeToPipe(cmdr, `{"state":"Booted","udid":"EVENTS-2"}`+"\n")
+	c, err := BootWith(cmdr, "EVENTS-2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fake process exits right away.
+	got := eventKinds(drainEvents(t, c.Events()))
+	want := []CompanionEventKind{CompanionStarting, CompanionBooted, CompanionExited}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestCompanion_Events_Restart(t *testing.T) {
+	cmdr, exits := newRestartingFakeCommander(2)
+	c, err := StartWith(cmdr, "EVENTS-3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarts, err := c.EnableAutoRestart(cmdr, "EVENTS-3", "", DefaultRestartPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crash := errors.New("crash")
+	exits[0] <- crash
+	receiveRestart(t, restarts)
+	exits[1] <- nil
+
+	got := drainEvents(t, c.Events())
+	want := []CompanionEventKind{
+		CompanionStarting, CompanionPortResolved,
+		CompanionExited, CompanionStarting, CompanionPortResolved,
+		CompanionExited,
+	}
+	if !slices.Equal(eventKinds(got), want) {
+		t.Fatalf("events = %v, want %v", eventKinds(got), want)
+	}
+	if !errors.Is(got[2].Err, crash) || got[4].Port != "10001" {
+		t.Errorf("crash event = %+v, restart port = %q", got[2], got[4].Port)
+	}
+}
+
+func TestEmit_DoesNotBlock(t *testing.T) {
+	events := make(chan CompanionEvent, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 3 {
+			emit(events, CompanionEvent{Kind: CompanionStarting})
+		}
+		emit(nil, CompanionEvent{Kind: CompanionExited})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("emit blocked on a full channel")
+	}
+	if len(events) != 1 {
+		t.Errorf("buffered %d events, want 1", len(events))
+	}
+}
@@ -0,0 +1,89 @@
+package idb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+// Target is a simulator or device that idb_companion can attach to.
+type Target struct {
+	UDID  string `json:"udid"`
+	Name  string `json:"name"`
+	State string `json:"state"` // e.g. "Booted", "Shutdown"
+	Type  string `json:"type"`  // "simulator" or "device"
+}
+
+// ListTargets runs `idb_companion --list 1` and returns the targets it
+// reports. Unlike simctl, this is what idb itself can attach to, physical
+// devices included.
+func ListTargets(ctx context.Context, cmdr Commander) ([]Target, error) {
+	cmd := cmdr.Command("idb_companion", "--list", "1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		_ = stdout.Close()
+		return nil, fmt.Errorf("creating stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		_ = stdout.Close()
+		_ = stderrPipe.Close()
+		return nil, fmt.Errorf("starting idb_companion: %w", err)
+	}
+	stderr := captureStderr(stderrPipe)
+
+	// Kill the process if ctx ends first; closing stdout then unblocks
+	// parseTargets.
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if proc := cmd.Process(); proc != nil {
+				_ = procgroup.KillProcess(proc)
+			}
+			_ = stdout.Close()
+		case <-exited:
+		}
+	}()
+
+	targets, parseErr := parseTargets(stdout)
+	_ = stdout.Close()
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("listing idb targets: %w", ctx.Err())
+	}
+	if waitErr != nil {
+		return nil, stderr.annotate(fmt.Errorf("idb_companion --list failed: %w", waitErr))
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("reading idb_companion --list output: %w", parseErr)
+	}
+	return targets, nil
+}
+
+// parseTargets reads the JSON objects `idb_companion --list 1` prints, one
+// per line. Lines that are not a target (log output) are skipped.
+func parseTargets(r io.Reader) ([]Target, error) {
+	targets := []Target{}
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var t Target
+		if err := json.Unmarshal([]byte(line), &t); err != nil || t.UDID == "" {
+			continue
+		}
+		targets = append(targets, t)
+	}
+	return targets, scanner.Err()
+}
@@ -0,0 +1,89 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	iPhone15 := Target{UDID: "6C1F7B0E-2A43-4C6E-9F0B-1D2E3F4A5B6C", Name: "iPhone 15", State: "Booted", Type: "simulator"}
+	tests := []struct {
+		fixture string
+		want    []Target
+	}{
+		{"list_simulators.txt", []Target{
+			iPhone15,
+			{UDID: "A0B1C2D3-E4F5-4061-8293-A4B5C6D7E8F9", Name: "iPhone SE (3rd generation)", State: "Shutdown", Type: "simulator"},
+			{UDID: "0F1E2D3C-4B5A-4697-8877-665544332211", Name: "iPad Pro (11-inch) (4th generation)", State: "Shutdown", Type: "simulator"},
+		}},
+		{"list_with_device.txt", []Target{
+			{UDID: iPhone15.UDID, Name: "iPhone 15", State: "Shutdown", Type: "simulator"},
+			{UDID: "00008110-001A2B3C4D5E801E", Name: "Test iPhone", State: "Booted", Type: "device"},
+		}},
+		// Log lines and JSON that is not a target are skipped.
+		{"list_with_logs.txt", []Target{iPhone15}},
+		{"list_empty.txt", []Target{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tc.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = f.Close() }()
+			got, err := parseTargets(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseTargets = %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListTargets(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "list_with_device.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmdr := newFakeCommander()
+	go writeToPipe(cmdr, string(fixture))
+
+	targets, err := ListTargets(context.Background(), cmdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[1].Type != "device" {
+		t.Errorf("targets = %+v", targets)
+	}
+	if got := strings.Join(cmdr.lastArgs, " "); got != "idb_companion --list 1" {
+		t.Errorf("args = %s", got)
+	}
+}
+
+func TestListTargets_Failure(t *testing.T) {
+	cmdr := &fakeCommander{}
+	cmdr.commandFn = func(name string, args ...string) CmdRunner {
+		exit := make(chan error, 1)
+		exit <- errors.New("exit status 1")
+		cmd := &fakeCmd{waitCh: exit, stderr: "Failed to list targets"}
+		cmd.onPipeReady = func() { _ = cmd.stdoutPW.Close() }
+		return cmd
+	}
+
+	_, err := ListTargets(context.Background(), cmdr)
+	if err == nil {
+		t.Fatal("expected an error when idb_companion fails")
+	}
+	for _, want := range []string{"idb_companion --list failed", "Failed to list targets"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q: %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CompanionPool shares one idb_companion per UDID among its users, so a
+// stream removed and added again for the same device reuses the running
+// companion instead of starting another.
+type CompanionPool struct {
+	cmdr    Commander
+	opts    Options
+	idleTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	closed  bool
+}
+
+// poolEntry is the companion of one UDID. ready is closed once the current
+// start attempt has finished; c and err are valid after that.
+type poolEntry struct {
+	refs  int
+	ready chan struct{}
+	c     *Companion
+	err   error
+	idle  *time.Timer // pending stop after the last Release, if idleTTL > 0
+
+	started  time.Time // when c started
+	restarts int       // exited companions replaced by Acquire
+	lastErr  error     // last start or exit error
+}
+
+// NewCompanionPool returns a pool that starts companions with cmdr and
+// opts. A companion is stopped idleTTL after its last Release, or right
+// away if idleTTL is 0.
+func NewCompanionPool(cmdr Commander, opts Options, idleTTL time.Duration) *CompanionPool {
+	return &CompanionPool{
+		cmdr:    cmdr,
+		opts:    opts,
+		idleTTL: idleTTL,
+		entries: make(map[string]*poolEntry),
+	}
+}
+
+// Acquire returns the companion for udid, starting it if none is running,
+// and takes a reference that must be returned with Release. A companion
+// that has exited is replaced. Concurrent callers for the same UDID share
+// one start; if it fails, all of them get its error. A start first looks
+// for a companion another axe process recorded in stateDir (see
+// DiscoverRunning), and a started companion records itself there.
+func (p *CompanionPool) Acquire(ctx context.Context, udid, deviceSetPath, stateDir string) (*Companion, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errors.New("companion pool is closed")
+	}
+	e, ok := p.entries[udid]
+	if !ok {
+		e = &poolEntry{}
+		p.entries[udid] = e
+	}
+	e.refs++
+	if e.idle != nil {
+		e.idle.Stop()
+		e.idle = nil
+	}
+	start := e.ready == nil || e.exited()
+	if start {
+		if e.c != nil {
+			e.restarts++
+			if err := e.c.Err(); err != nil { // exited, so Err does not block
+				e.lastErr = err
+			}
+		}
+		e.ready = make(chan struct{})
+		e.c, e.err = nil, nil
+	}
+	ready := e.ready
+	p.mu.Unlock()
+
+	if start {
+		c, err := p.start(ctx, udid, deviceSetPath, stateDir)
+		p.mu.Lock()
+		e.c, e.err = c, err
+		if err != nil {
+			e.lastErr = err
+		} else {
+			e.started = time.Now()
+		}
+		close(ready)
+		p.mu.Unlock()
+	}
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		p.Release(udid)
+		return nil, ctx.Err()
+	}
+	p.mu.Lock()
+	c, err := e.c, e.err
+	p.mu.Unlock()
+	if err != nil {
+		p.Release(udid)
+		return nil, err
+	}
+	return c, nil
+}
+
+// start returns the companion recorded in stateDir, if it is still running,
+// or starts a new one that records itself there.
+func (p *CompanionPool) start(ctx context.Context, udid, deviceSetPath, stateDir string) (*Companion, error) {
+	if c, ok := DiscoverRunning(stateDir, udid); ok {
+		slog.Info("Reusing running idb_companion", "udid", udid, "port", c.Port())
+		return c, nil
+	}
+	opts := p.opts
+	opts.StateDir = stateDir
+	return StartWithContext(ctx, p.cmdr, udid, deviceSetPath, opts)
+}
+
+// exited reports whether the entry's start has finished without leaving a
+// running companion. Called with the pool's mu held.
+func (e *poolEntry) exited() bool {
+	select {
+	case <-e.ready:
+	default:
+		return false // still starting
+	}
+	if e.c == nil {
+		return true
+	}
+	select {
+	case <-e.c.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns a reference taken by Acquire. When the last one is
+// returned, the companion is stopped after the pool's idle TTL unless it
+// is acquired again first. Releasing a UDID without references does nothing.
+func (p *CompanionPool) Release(udid string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[udid]
+	if !ok || e.refs == 0 {
+		return
+	}
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+	if p.idleTTL <= 0 || p.closed {
+		p.evictLocked(udid, e)
+		return
+	}
+	e.idle = time.AfterFunc(p.idleTTL, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.entries[udid] == e && e.refs == 0 {
+			p.evictLocked(udid, e)
+		}
+	})
+}
+
+// evictLocked removes e and stops its companion in the background once its
+// start has finished. Called with mu held.
+func (p *CompanionPool) evictLocked(udid string, e *poolEntry) {
+	delete(p.entries, udid)
+	ready := e.ready
+	go func() {
+		<-ready
+		p.mu.Lock()
+		c := e.c
+		p.mu.Unlock()
+		if c == nil {
+			return
+		}
+		if err := c.Stop(); err != nil && !errors.Is(err, ErrNotRunning) {
+			slog.Debug("Failed to stop pooled idb companion", "udid", udid, "err", err)
+		}
+	}()
+}
+
+// StopIdle stops the companions that have no references left without
+// waiting for the idle TTL, e.g. before their simulators are shut down.
+func (p *CompanionPool) StopIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for udid, e := range p.entries {
+		if e.refs > 0 {
+			continue
+		}
+		if e.idle != nil {
+			e.idle.Stop()
+		}
+		p.evictLocked(udid, e)
+	}
+}
+
+// CompanionStatus describes the companion of one UDID in a CompanionPool.
+type CompanionStatus struct {
+	UDID      string
+	Address   string        // gRPC address; empty while starting or after a failed start
+	Refs      int           // references taken by Acquire and not yet released
+	Starting  bool          // a start is in progress
+	Uptime    time.Duration // since the running companion started; 0 if none runs
+	Restarts  int           // exited companions replaced by a later Acquire
+	LastError string        // last start or exit error; empty if none
+}
+
+// Status returns the state of every companion in the pool, including ones
+// kept for the idle TTL, sorted by UDID. It is safe to call concurrently
+// with Acquire and Release.
+func (p *CompanionPool) Status() []CompanionStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]CompanionStatus, 0, len(p.entries))
+	for udid, e := range p.entries {
+		st := CompanionStatus{UDID: udid, Refs: e.refs, Restarts: e.restarts}
+		if e.lastErr != nil {
+			st.LastError = e.lastErr.Error()
+		}
+		select {
+		case <-e.ready:
+			if e.c == nil {
+				break
+			}
+			select {
+			case <-e.c.Done():
+				if err := e.c.Err(); err != nil {
+					st.LastError = err.Error()
+				}
+			default:
+				st.Address = e.c.Address()
+				st.Uptime = time.Since(e.started)
+			}
+		default:
+			st.Starting = e.ready != nil
+		}
+		statuses = append(statuses, st)
+	}
+	slices.SortFunc(statuses, func(a, b CompanionStatus) int { return strings.Compare(a.UDID, b.UDID) })
+	return statuses
+}
+
+// Close stops every companion in the pool, whether or not it is still
+// referenced, and makes further Acquire calls fail. It waits for the
+// companions to exit.
+func (p *CompanionPool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	entries := p.entries
+	p.entries = make(map[string]*poolEntry)
+	for _, e := range entries {
+		if e.idle != nil {
+			e.idle.Stop()
+		}
+	}
+	p.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for udid, e := range entries {
+		wg.Go(func() {
+			<-e.ready
+			p.mu.Lock()
+			c := e.c
+			p.mu.Unlock()
+			if c == nil {
+				return
+			}
+			if err := c.Stop(); err != nil && !errors.Is(err, ErrNotRunning) {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("stopping companion for %s: %w", udid, err))
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,265 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingCommander counts the idb_companion processes started through it.
+type countingCommander struct {
+	Commander
+	starts atomic.Int32
+}
+
+func (c *countingCommander) Command(name string, args ...string) CmdRunner {
+	c.starts.Add(1)
+	return c.Commander.Command(name, args...)
+}
+
+func newPoolCommander(n int) (*countingCommander, []chan error) {
+	cmdr, exits := newRestartingFakeCommander(n)
+	return &countingCommander{Commander: cmdr}, exits
+}
+
+func TestCompanionPool_ConcurrentAcquireSharesCompanion(t *testing.T) {
+	cmdr, _ := newPoolCommander(2)
+	pool := NewCompanionPool(cmdr, Options{}, 0)
+
+	const n = 16
+	got := make([]*Companion, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			c, err := pool.Acquire(context.Background(), "POOL-SHARE", "", "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			got[i] = c
+		})
+	}
+	wg.Wait()
+	if s := cmdr.starts.Load(); s != 1 {
+		t.Fatalf("started %d companions, want 1", s)
+	}
+	for _, c := range got {
+		if c != got[0] {
+			t.Fatal("concurrent Acquire returned different companions")
+		}
+	}
+
+	for range n - 1 {
+		pool.Release("POOL-SHARE")
+	}
+	c, err := pool.Acquire(context.Background(), "POOL-SHARE", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != got[0] || cmdr.starts.Load() != 1 {
+		t.Error("companion was replaced while still referenced")
+	}
+
+	// Dropping the last two references stops it; the next Acquire starts anew.
+	pool.Release("POOL-SHARE")
+	pool.Release("POOL-SHARE")
+	if _, err := pool.Acquire(context.Background(), "POOL-SHARE", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := cmdr.starts.Load(); s != 2 {
+		t.Errorf("started %d companions, want a new one after the last Release", s)
+	}
+}
+
+func TestCompanionPool_IdleTTL(t *testing.T) {
+	cmdr, _ := newPoolCommander(2)
+	pool := NewCompanionPool(cmdr, Options{}, 100*time.Millisecond)
+
+	first, err := pool.Acquire(context.Background(), "POOL-TTL", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Release("POOL-TTL")
+	again, err := pool.Acquire(context.Background(), "POOL-TTL", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Error("companion not kept warm within the idle TTL")
+	}
+
+	pool.Release("POOL-TTL")
+	time.Sleep(300 * time.Millisecond)
+	if _, err := pool.Acquire(context.Background(), "POOL-TTL", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := cmdr.starts.Load(); s != 2 {
+		t.Errorf("started %d companions, want a new one after the idle TTL", s)
+	}
+}
+
+func TestCompanionPool_ReplacesExitedCompanion(t *testing.T) {
+	cmdr, exits := newPoolCommander(2)
+	pool := NewCompanionPool(cmdr, Options{}, 0)
+
+	first, err := pool.Acquire(context.Background(), "POOL-EXIT", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exits[0] <- errors.New("crash")
+	<-first.Done()
+
+	second, err := pool.Acquire(context.Background(), "POOL-EXIT", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || second.Port() != "10001" {
+		t.Errorf("Acquire returned port %s, want a new companion", second.Port())
+	}
+}
+
+func TestCompanionPool_StartFailureIsShared(t *testing.T) {
+	cmdr := &countingCommander{Commander: &fakeCommander{commandFn: func(string, ...string) CmdRunner {
+		cmd := &fakeCmd{stderr: "boom"}
+		cmd.onPipeReady = func() {
+			go func() {
+				time.Sleep(50 * time.Millisecond) // let the other callers queue up
+				_ = cmd.stdoutPW.Close()
+			}()
+		}
+		return cmd
+	}}}
+	pool := NewCompanionPool(cmdr, Options{}, time.Minute)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, err := pool.Acquire(context.Background(), "POOL-FAIL", "", ""); err == nil || !strings.Contains(err.Error(), "boom") {
+				t.Errorf("Acquire = %v, want the start error", err)
+			}
+		})
+	}
+	wg.Wait()
+	if s := cmdr.starts.Load(); s != 1 {
+		t.Errorf("started %d companions, want the callers to share one start", s)
+	}
+
+	// A failed start is retried by the next Acquire.
+	_, _ = pool.Acquire(context.Background(), "POOL-FAIL", "", "")
+	if s := cmdr.starts.Load(); s != 2 {
+		t.Errorf("started %d companions, want a retry", s)
+	}
+}
+
+func TestCompanionPool_AcquireCancelled(t *testing.T) {
+	cmdr := &countingCommander{Commander: &fakeCommander{commandFn: func(string, ...string) CmdRunner {
+		return &fakeCmd{onPipeReady: func() {}} // never reports a port
+	}}}
+	pool := NewCompanionPool(cmdr, Options{}, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx, "POOL-CANCEL", "", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire = %v, want the context's error", err)
+	}
+	pool.mu.Lock()
+	_, ok := pool.entries["POOL-CANCEL"]
+	pool.mu.Unlock()
+	if ok {
+		t.Error("cancelled Acquire left a reference behind")
+	}
+}
+
+func TestCompanionPool_Close(t *testing.T) {
+	cmdr, _ := newPoolCommander(2)
+	pool := NewCompanionPool(cmdr, Options{}, time.Minute)
+
+	for _, udid := range []string{"POOL-CLOSE-1", "POOL-CLOSE-2"} {
+		if _, err := pool.Acquire(context.Background(), udid, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Acquire(context.Background(), "POOL-CLOSE-1", "", ""); err == nil {
+		t.Error("Acquire succeeded on a closed pool")
+	}
+	pool.Release("POOL-CLOSE-1") // no-op after Close
+}
+
+func TestCompanionPool_Status(t *testing.T) {
+	cmdr, exits := newPoolCommander(3)
+	pool := NewCompanionPool(cmdr, Options{}, time.Minute)
+	defer func() { _ = pool.Close() }()
+
+	for _, udid := range []string{"POOL-STATUS-B", "POOL-STATUS-A", "POOL-STATUS-A"} {
+		if _, err := pool.Acquire(context.Background(), udid, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.Release("POOL-STATUS-A")
+
+	got := pool.Status()
+	if len(got) != 2 || got[0].UDID != "POOL-STATUS-A" || got[1].UDID != "POOL-STATUS-B" {
+		t.Fatalf("Status = %+v, want A and B sorted by UDID", got)
+	}
+	for i, wantRefs := range []int{1, 1} {
+		st := got[i]
+		if st.Refs != wantRefs || st.Address == "" || st.Starting || st.Restarts != 0 || st.LastError != "" {
+			t.Errorf("Status[%d] = %+v, want a running companion with %d reference", i, st, wantRefs)
+		}
+	}
+
+	// B crashes: its error shows up, and replacing it counts a restart.
+	b, err := pool.Acquire(context.Background(), "POOL-STATUS-B", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exits[0] <- errors.New("crash") // B started first
+	<-b.Done()
+	if st := pool.Status()[1]; st.Address != "" || !strings.Contains(st.LastError, "crash") {
+		t.Errorf("Status after crash = %+v, want no address and the exit error", st)
+	}
+	if _, err := pool.Acquire(context.Background(), "POOL-STATUS-B", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if st := pool.Status()[1]; st.Refs != 3 || st.Restarts != 1 || st.Address == "" || !strings.Contains(st.LastError, "crash") {
+		t.Errorf("Status after restart = %+v, want 3 references, 1 restart and the last error", st)
+	}
+
+	// Released to zero, A stays for the idle TTL until StopIdle.
+	pool.Release("POOL-STATUS-A")
+	if st := pool.Status()[0]; st.UDID != "POOL-STATUS-A" || st.Refs != 0 || st.Address == "" {
+		t.Errorf("Status of idle A = %+v, want a running companion without references", st)
+	}
+	pool.StopIdle()
+	if got := pool.Status(); len(got) != 1 || got[0].UDID != "POOL-STATUS-B" {
+		t.Errorf("Status after StopIdle = %+v, want only B", got)
+	}
+}
+
+func TestCompanionPool_ReusesRecordedCompanion(t *testing.T) {
+	t.Setenv(HostEnv, "")
+	port := listen(t)
+	dir := t.TempDir()
+	(&Companion{udid: "POOL-DISCOVER", port: port, opts: Options{StateDir: dir}}).saveState()
+
+	cmdr := &countingCommander{Commander: newFakeCommander()}
+	pool := NewCompanionPool(cmdr, Options{}, 0)
+	c, err := pool.Acquire(context.Background(), "POOL-DISCOVER", "", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port() != port {
+		t.Errorf("Port = %s, want the recorded %s", c.Port(), port)
+	}
+	if s := cmdr.starts.Load(); s != 0 {
+		t.Errorf("started %d companions, want the recorded one reused", s)
+	}
+	pool.Release("POOL-DISCOVER")
+}
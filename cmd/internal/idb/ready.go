@@ -0,0 +1,49 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Timing of the readiness probe: each dial is bounded by readyDialTimeout
+// and failed dials are retried every readyRetryInterval.
+var (
+	readyDialTimeout   = 200 * time.Millisecond
+	readyRetryInterval = 50 * time.Millisecond
+)
+
+// dialCompanion opens a TCP connection to addr; tests replace it.
+var dialCompanion = func(ctx context.Context, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: readyDialTimeout}
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// ErrNotConnectable reports that idb_companion's port did not accept a
+// connection within Options.StartTimeout.
+var ErrNotConnectable = errors.New("port is not accepting connections")
+
+// waitConnectable dials addr until a connection succeeds. idb_companion
+// prints its port before the gRPC server necessarily accepts connections,
+// and a client connecting in that window fails its first calls.
+func waitConnectable(ctx context.Context, addr string, deadline time.Time) error {
+	dialCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	for {
+		conn, err := dialCompanion(dialCtx, addr)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		select {
+		case <-dialCtx.Done():
+			if ctx.Err() != nil {
+				return fmt.Errorf("waiting for idb_companion to accept connections: %w", ctx.Err())
+			}
+			return fmt.Errorf("idb_companion %w at %s: %v", ErrNotConnectable, addr, err)
+		case <-time.After(readyRetryInterval):
+		}
+	}
+}
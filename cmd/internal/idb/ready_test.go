@@ -0,0 +1,108 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// realDialCompanion is the production dialer, captured before TestMain
+// replaces it.
+var realDialCompanion = dialCompanion
+
+func withRealDial(t *testing.T) {
+	t.Helper()
+	stub := dialCompanion
+	dialCompanion = realDialCompanion
+	t.Cleanup(func() { dialCompanion = stub })
+}
+
+// closedPort returns a port that refuses connections.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	_ = ln.Close()
+	return port
+}
+
+func TestWaitConnectable(t *testing.T) {
+	withRealDial(t)
+
+	t.Run("listening", func(t *testing.T) {
+		addr := net.JoinHostPort("localhost", listen(t))
+		if err := waitConnectable(context.Background(), addr, time.Now().Add(time.Second)); err != nil {
+			t.Errorf("waitConnectable = %v", err)
+		}
+	})
+
+	t.Run("listens after a while", func(t *testing.T) {
+		port := closedPort(t)
+		listening := make(chan net.Listener, 1)
+		go func() {
+			time.Sleep(150 * time.Millisecond)
+			ln, _ := net.Listen("tcp", net.JoinHostPort("localhost", port))
+			listening <- ln // nil if the port was taken meanwhile
+		}()
+		err := waitConnectable(context.Background(), net.JoinHostPort("localhost", port), time.Now().Add(2*time.Second))
+		if ln := <-listening; ln != nil {
+			_ = ln.Close()
+		}
+		if err != nil {
+			t.Errorf("waitConnectable = %v, want success once the port listens", err)
+		}
+	})
+
+	t.Run("never listens", func(t *testing.T) {
+		addr := net.JoinHostPort("localhost", closedPort(t))
+		err := waitConnectable(context.Background(), addr, time.Now().Add(200*time.Millisecond))
+		if !errors.Is(err, ErrNotConnectable) || !strings.Contains(err.Error(), addr) {
+			t.Errorf("waitConnectable = %v, want ErrNotConnectable naming %s", err, addr)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		addr := net.JoinHostPort("localhost", closedPort(t))
+		err := waitConnectable(ctx, addr, time.Now().Add(time.Second))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("waitConnectable = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestStartWithContext_ReadyProbe(t *testing.T) {
+	stub := dialCompanion
+	t.Cleanup(func() { dialCompanion = stub })
+	var dials atomic.Int32
+	dialCompanion = func(context.Context, string) (net.Conn, error) {
+		dials.Add(1)
+		return nil, errors.New("connection refused")
+	}
+
+	cmdr, _ := newRestartingFakeCommander(2)
+	_, err := StartWithContext(context.Background(), cmdr, "READY-1", "", Options{StartTimeout: 200 * time.Millisecond})
+	if !errors.Is(err, ErrNotConnectable) {
+		t.Errorf("StartWithContext = %v, want ErrNotConnectable", err)
+	}
+	if dials.Load() < 2 {
+		t.Errorf("dialed %d times, want retries until the timeout", dials.Load())
+	}
+
+	dials.Store(0)
+	c, err := StartWithContext(context.Background(), cmdr, "READY-2", "", Options{SkipReadyProbe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dials.Load() != 0 || c.Port() != "10001" {
+		t.Errorf("dials = %d, port = %s; want no probe with SkipReadyProbe", dials.Load(), c.Port())
+	}
+}
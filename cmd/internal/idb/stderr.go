@@ -0,0 +1,66 @@
+package idb
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStderrTail is how much of idb_companion's stderr is kept for errors.
+const maxStderrTail = 4 << 10
+
+// stderrSettle is how long a failed start waits for the rest of stderr (the
+// process was just killed or exited) before reporting what it has.
+var stderrSettle = time.Second
+
+// stderrTail drains idb_companion's stderr for the lifetime of the process,
+// keeping only its last maxStderrTail bytes.
+type stderrTail struct {
+	mu   sync.Mutex
+	buf  []byte
+	done chan struct{} // closed at EOF
+}
+
+// captureStderr starts draining r. r is closed at EOF.
+func captureStderr(r *os.File) *stderrTail {
+	t := &stderrTail{done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		defer func() { _ = r.Close() }()
+		_, _ = io.Copy(t, r)
+	}()
+	return t
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - maxStderrTail; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *stderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.TrimSpace(string(t.buf))
+}
+
+// annotate appends the captured stderr to err, once stderr has ended or
+// stderrSettle has passed.
+func (t *stderrTail) annotate(err error) error {
+	select {
+	case <-t.done:
+	case <-time.After(stderrSettle):
+	}
+	tail := t.String()
+	if tail == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nidb_companion stderr:\n%s", err, tail)
+}
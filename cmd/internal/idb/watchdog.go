@@ -0,0 +1,135 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+// RestartPolicy bounds automatic restarts so a companion that crashes on
+// every start is not relaunched forever: at most MaxRestarts within any
+// Window.
+type RestartPolicy struct {
+	MaxRestarts int
+	Window      time.Duration
+}
+
+// DefaultRestartPolicy allows three restarts a minute.
+var DefaultRestartPolicy = RestartPolicy{MaxRestarts: 3, Window: time.Minute}
+
+// Restart is sent when the watchdog has relaunched a crashed companion.
+type Restart struct {
+	Address string // gRPC address of the new process
+	Err     error  // exit error of the process that crashed
+}
+
+// watchdog holds what is needed to relaunch a companion.
+type watchdog struct {
+	cmdr     Commander
+	args     []string
+	opts     Options // as the companion was started with
+	policy   RestartPolicy
+	history  []time.Time // restart times within the policy window
+	restarts chan Restart
+}
+
+// EnableAutoRestart makes the companion relaunch idb_companion for udid when
+// the process exits with an error, as long as policy allows. Port and
+// Address report the new process after a restart, and Done stays open until
+// a crash is not restarted (or Stop is called). Each restart is sent on the
+// returned channel, which is closed once the companion is done; restarts
+// nobody receives in time are dropped.
+//
+// Only companions from Start or StartForDevice can be restarted (for the
+// latter, deviceSetPath is ignored); those from Boot own the simulator's
+// boot and those from DiscoverRunning belong to another process, so neither
+// is.
+func (c *Companion) EnableAutoRestart(cmdr Commander, udid, deviceSetPath string, policy RestartPolicy) (<-chan Restart, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.port == "" || c.discovered {
+		return nil, errors.New("auto-restart needs a companion started with Start")
+	}
+	if c.watchdog != nil {
+		return nil, errors.New("auto-restart is already enabled")
+	}
+	if c.exited {
+		return nil, errors.New("idb_companion has already exited")
+	}
+	if policy.MaxRestarts <= 0 || policy.Window <= 0 {
+		policy = DefaultRestartPolicy
+	}
+	args := companionArgs(udid, deviceSetPath)
+	if c.physical {
+		args = deviceArgs(udid)
+	}
+	c.watchdog = &watchdog{
+		cmdr:     cmdr,
+		args:     args,
+		opts:     c.opts,
+		policy:   policy,
+		restarts: make(chan Restart, policy.MaxRestarts),
+	}
+	return c.watchdog.restarts, nil
+}
+
+// allow reports whether another restart fits the policy at now, recording
+// it if so.
+func (w *watchdog) allow(now time.Time) bool {
+	kept := w.history[:0]
+	for _, t := range w.history {
+		if now.Sub(t) < w.policy.Window {
+			kept = append(kept, t)
+		}
+	}
+	w.history = kept
+	if len(w.history) >= w.policy.MaxRestarts {
+		return false
+	}
+	w.history = append(w.history, now)
+	return true
+}
+
+// restart relaunches c after its process exited with crashErr. It reports
+// whether a new process now runs under c; called only from c's monitor.
+func (w *watchdog) restart(c *Companion, crashErr error) bool {
+	if !w.allow(time.Now()) {
+		slog.Warn("idb_companion crashed too often, not restarting",
+			"maxRestarts", w.policy.MaxRestarts, "window", w.policy.Window, "err", crashErr)
+		return false
+	}
+	slog.Warn("idb_companion crashed, restarting", "err", crashErr)
+	emit(c.events, CompanionEvent{Kind: CompanionStarting})
+	ctx := context.Background()
+	cmd, ports, err := withStartAttempts(ctx, w.opts, func() (CmdRunner, companionPorts, error) {
+		return startForPort(ctx, w.cmdr, w.args, w.opts)
+	})
+	if err != nil {
+		slog.Warn("Restarting idb_companion failed", "err", err)
+		return false
+	}
+
+	c.mu.Lock()
+	if c.stopping {
+		c.mu.Unlock()
+		if proc := cmd.Process(); proc != nil {
+			_ = procgroup.KillProcess(proc)
+		}
+		_ = cmd.Wait()
+		return false
+	}
+	c.cmd, c.port, c.swiftPort, c.process = cmd, ports.port(), ports.GRPCSwiftPort, cmd.Process()
+	c.mu.Unlock()
+	emit(c.events, CompanionEvent{Kind: CompanionPortResolved, Port: ports.port()})
+	c.saveState()
+
+	select {
+	case w.restarts <- Restart{Address: c.Address(), Err: crashErr}:
+	default:
+	}
+	go c.monitor(cmd)
+	return true
+}
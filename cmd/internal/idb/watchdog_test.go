@@ -0,0 +1,225 @@
+package idb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRestartingFakeCommander creates a fakeCommander whose n-th command
+// reports port 10000+n and runs until an exit error is sent on exits[n].
+func newRestartingFakeCommander(n int) (*fakeCommander, []chan error) {
+	exits := make([]chan error, n)
+	for i := range exits {
+		exits[i] = make(chan error, 1)
+	}
+	cmdr := &fakeCommander{}
+	started := 0
+	cmdr.commandFn = func(name string, args ...string) CmdRunner {
+		cmdr.mu.Lock()
+		defer cmdr.mu.Unlock()
+		port := 10000 + started
+		cmd := &fakeCmd{waitCh: exits[started]}
+		cmd.onPipeReady = func() {
+			go func() {
+				_, _ = fmt.Fprintf(cmd.stdoutPW, `{"grpc_port":%d}`+"\n", port)
+				_ = cmd.stdoutPW.Close()
+			}()
+		}
+		started++
+		cmdr.lastCmd = cmd
+		cmdr.lastArgs = append([]string{name}, args...)
+		return cmd
+	}
+	return cmdr, exits
+}
+
+func receiveRestart(t *testing.T, restarts <-chan Restart) Restart {
+	t.Helper()
+	select {
+	case r := <-restarts:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("no restart notification")
+		return Restart{}
+	}
+}
+
+func TestEnableAutoRestart_RestartsAfterCrash(t *testing.T) {
+	t.Setenv(HostEnv, "")
+	cmdr, exits := newRestartingFakeCommander(2)
+	c, err := StartWith(cmdr, "UDID-1", "/tmp/set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarts, err := c.EnableAutoRestart(cmdr, "UDID-1", "/tmp/set", RestartPolicy{MaxRestarts: 3, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crash := errors.New("signal: segmentation fault")
+	exits[0] <- crash
+	r := receiveRestart(t, restarts)
+	if r.Address != "localhost:10001" || !errors.Is(r.Err, crash) {
+		t.Errorf("restart = %+v, want the new address and the crash", r)
+	}
+	if c.Port() != "10001" || c.Address() != "localhost:10001" {
+		t.Errorf("Port/Address = %s/%s, want the restarted process", c.Port(), c.Address())
+	}
+	select {
+	case <-c.Done():
+		t.Fatal("Done closed although the companion was restarted")
+	default:
+	}
+
+	// A clean exit is not a crash: no restart, and the companion is done.
+	exits[1] <- nil
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done did not close after a clean exit")
+	}
+	if c.Err() != nil {
+		t.Errorf("Err = %v, want nil", c.Err())
+	}
+	if _, ok := <-restarts; ok {
+		t.Error("restarts channel not closed after the companion was done")
+	}
+}
+
+func TestEnableAutoRestart_KeepsOptions(t *testing.T) {
+	stub := dialCompanion
+	t.Cleanup(func() { dialCompanion = stub })
+	var dials atomic.Int32
+	dialCompanion = func(context.Context, string) (net.Conn, error) {
+		dials.Add(1)
+		return nil, errors.New("connection refused")
+	}
+
+	cmdr, exits := newRestartingFakeCommander(2)
+	c, err := StartWithContext(context.Background(), cmdr, "UDID-1", "", Options{SkipReadyProbe: true, StartTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = c.Stop() }()
+	restarts, err := c.EnableAutoRestart(cmdr, "UDID-1", "", RestartPolicy{MaxRestarts: 1, Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the default options the restart would wait for the port to
+	// accept connections, which it never does here.
+	exits[0] <- errors.New("crash")
+	receiveRestart(t, restarts)
+	if n := dials.Load(); n != 0 {
+		t.Errorf("restart dialed the port %d times, want no probe with SkipReadyProbe", n)
+	}
+}
+
+func TestEnableAutoRestart_GivesUpPerPolicy(t *testing.T) {
+	cmdr, exits := newRestartingFakeCommander(2)
+	c, err := StartWith(cmdr, "UDID-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarts, err := c.EnableAutoRestart(cmdr, "UDID-1", "", RestartPolicy{MaxRestarts: 1, Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exits[0] <- errors.New("crash 1")
+	receiveRestart(t, restarts)
+
+	second := errors.New("crash 2")
+	exits[1] <- second
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done did not close once the restart budget was used up")
+	}
+	if !errors.Is(c.Err(), second) {
+		t.Errorf("Err = %v, want the crash that was not restarted", c.Err())
+	}
+}
+
+func TestEnableAutoRestart_NoRestartAfterStop(t *testing.T) {
+	cmdr, exits := newRestartingFakeCommander(1)
+	c, err := StartWith(cmdr, "UDID-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.EnableAutoRestart(cmdr, "UDID-1", "", DefaultRestartPolicy); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Stop() // the fake has no process to signal; marks the companion stopping
+	exits[0] <- errors.New("signal: terminated")
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done did not close after Stop")
+	}
+}
+
+func TestEnableAutoRestart_Rejected(t *testing.T) {
+	boot := &Companion{done: make(chan struct{})}
+	if _, err := boot.EnableAutoRestart(newFakeCommander(), "U", "", DefaultRestartPolicy); err == nil {
+		t.Error("expected an error for a companion without a port")
+	}
+
+	cmdr, exits := newRestartingFakeCommander(1)
+	c, err := StartWith(cmdr, "UDID-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.EnableAutoRestart(cmdr, "UDID-1", "", DefaultRestartPolicy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.EnableAutoRestart(cmdr, "UDID-1", "", DefaultRestartPolicy); err == nil {
+		t.Error("expected an error when enabling twice")
+	}
+	exits[0] <- nil
+	<-c.Done()
+}
+
+func TestWatchdog_Allow(t *testing.T) {
+	w := &watchdog{policy: RestartPolicy{MaxRestarts: 2, Window: time.Minute}}
+	now := time.Now()
+	if !w.allow(now) || !w.allow(now.Add(time.Second)) {
+		t.Fatal("expected the first two restarts to be allowed")
+	}
+	if w.allow(now.Add(2 * time.Second)) {
+		t.Error("expected a third restart within the window to be refused")
+	}
+	if !w.allow(now.Add(time.Minute + time.Second)) {
+		t.Error("expected a restart once the first one left the window")
+	}
+}
+
+func TestEnableAutoRestart_PhysicalDeviceArgs(t *testing.T) {
+	cmdr, exits := newRestartingFakeCommander(2)
+	c, err := StartForDevice(cmdr, "DEVICE-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarts, err := c.EnableAutoRestart(cmdr, "DEVICE-1", "/tmp/set", DefaultRestartPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exits[0] <- errors.New("crash")
+	receiveRestart(t, restarts)
+	cmdr.mu.Lock()
+	args := strings.Join(cmdr.lastArgs, " ")
+	cmdr.mu.Unlock()
+	if !strings.Contains(args, "--only device") || strings.Contains(args, "--device-set-path") {
+		t.Errorf("restart args = %s, want the physical device's", args)
+	}
+	exits[1] <- nil
+	<-c.Done()
+}
@@ -0,0 +1,258 @@
+package platform
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+// dynamicTypeSizes lists the content size categories accepted by
+// "simctl ui <device> content_size", from smallest to largest.
+var dynamicTypeSizes = []string{
+	"extra-small",
+	"small",
+	"medium",
+	"large",
+	"extra-large",
+	"extra-extra-large",
+	"extra-extra-extra-large",
+	"accessibility-medium",
+	"accessibility-large",
+	"accessibility-extra-large",
+	"accessibility-extra-extra-large",
+	"accessibility-extra-extra-extra-large",
+}
+
+// appearances lists the values accepted by "simctl ui <device> appearance".
+var appearances = []string{"light", "dark"}
+
+// localeRe matches locale identifiers such as "ja", "en_GB", "zh-Hant-TW"
+// or "es_419": a language, an optional script and an optional region.
+var localeRe = regexp.MustCompile(`^([a-z]{2,3})(?:[-_]([A-Z][a-z]{3}))?(?:[-_]([A-Z]{2}|[0-9]{3}))?$`)
+
+// regionRe matches a region code such as "JP" or "419".
+var regionRe = regexp.MustCompile(`^([A-Z]{2}|[0-9]{3})$`)
+
+// AccessibilityOverrides describes simulator accessibility, appearance and
+// language settings applied before the preview app renders.
+// The zero value leaves the device unchanged. It is also the per-device
+// profile persisted in the config (see ConfigStore.SetDeviceProfile).
+type AccessibilityOverrides struct {
+	DynamicType      string `json:"dynamicType,omitempty"` // content size category (e.g. "accessibility-large"); empty = unchanged
+	BoldText         bool   `json:"boldText,omitempty"`
+	IncreaseContrast bool   `json:"increaseContrast,omitempty"`
+
+	Appearance string `json:"appearance,omitempty"` // "light" or "dark"; empty = unchanged
+	Locale     string `json:"locale,omitempty"`     // locale identifier (e.g. "ja_JP"); empty = unchanged
+	Region     string `json:"region,omitempty"`     // region code (e.g. "JP") overriding the region part of Locale
+	Language   string `json:"language,omitempty"`   // UI language (e.g. "ja", "zh-Hant") overriding the language of Locale
+}
+
+// IsZero reports whether no override is requested.
+func (a AccessibilityOverrides) IsZero() bool {
+	return a == AccessibilityOverrides{}
+}
+
+// Or returns a with each unset field taken from fallback, e.g. the
+// overrides given for a run on top of the device's persisted profile.
+// A switch enabled in fallback stays enabled, since false means unset.
+func (a AccessibilityOverrides) Or(fallback AccessibilityOverrides) AccessibilityOverrides {
+	if a.DynamicType == "" {
+		a.DynamicType = fallback.DynamicType
+	}
+	a.BoldText = a.BoldText || fallback.BoldText
+	a.IncreaseContrast = a.IncreaseContrast || fallback.IncreaseContrast
+	if a.Appearance == "" {
+		a.Appearance = fallback.Appearance
+	}
+	// Locale, region and language go together: a locale given for the run
+	// replaces the fallback's region and language too, while a region or
+	// language alone applies to the fallback's locale.
+	if a.Locale == "" {
+		a.Locale = fallback.Locale
+		if a.Region == "" {
+			a.Region = fallback.Region
+		}
+		if a.Language == "" {
+			a.Language = fallback.Language
+		}
+	}
+	return a
+}
+
+// Validate checks that each set field holds a value simctl accepts:
+// a known content size category and appearance, and well-formed locale,
+// region and language identifiers.
+func (a AccessibilityOverrides) Validate() error {
+	var errs []error
+	if a.DynamicType != "" && !slices.Contains(dynamicTypeSizes, a.DynamicType) {
+		errs = append(errs, fmt.Errorf("invalid dynamic type size %q (valid: %s)", a.DynamicType, strings.Join(dynamicTypeSizes, ", ")))
+	}
+	if a.Appearance != "" && !slices.Contains(appearances, a.Appearance) {
+		errs = append(errs, fmt.Errorf("invalid appearance %q (valid: %s)", a.Appearance, strings.Join(appearances, ", ")))
+	}
+	if a.Locale != "" && !localeRe.MatchString(a.Locale) {
+		errs = append(errs, fmt.Errorf("invalid locale %q (expected e.g. ja, en_GB, zh-Hant-TW)", a.Locale))
+	}
+	if a.Region != "" && !regionRe.MatchString(a.Region) {
+		errs = append(errs, fmt.Errorf("invalid region %q (expected e.g. JP, 419)", a.Region))
+	}
+	if a.Language != "" && !localeRe.MatchString(a.Language) {
+		errs = append(errs, fmt.Errorf("invalid language %q (expected e.g. ja, en-GB, zh-Hant)", a.Language))
+	}
+	return errors.Join(errs...)
+}
+
+// languageAndLocale derives the AppleLanguages entry and AppleLocale value
+// from Locale, Region and Language. Either is empty when the overrides leave
+// it unchanged. Without a Locale, the locale's language is taken from
+// Language, or, when only a region is set, from deviceLocale (the device's
+// own AppleLocale), defaulting to "en" when that is unknown.
+func (a AccessibilityOverrides) languageAndLocale(deviceLocale string) (language, locale string) {
+	lang, script, region := "en", "", ""
+	if m := localeRe.FindStringSubmatch(cmp.Or(a.Locale, a.Language, deviceLocale)); m != nil {
+		lang, script, region = m[1], m[2], m[3]
+	}
+	if a.Region != "" {
+		region = a.Region
+	}
+	language = lang
+	if script != "" {
+		language += "-" + script
+	}
+	locale = strings.ReplaceAll(language, "-", "_")
+	if region != "" {
+		language += "-" + region
+		locale += "_" + region
+	}
+	if a.Language != "" {
+		language = a.Language
+	} else if a.Locale == "" {
+		language = ""
+	}
+	if a.Locale == "" && a.Region == "" {
+		locale = ""
+	}
+	return language, locale
+}
+
+// Labels returns a short description of each active override, suitable for
+// status reporting (e.g. "dynamic_type=accessibility-large", "bold_text").
+func (a AccessibilityOverrides) Labels() []string {
+	var labels []string
+	if a.DynamicType != "" {
+		labels = append(labels, "dynamic_type="+a.DynamicType)
+	}
+	if a.BoldText {
+		labels = append(labels, "bold_text")
+	}
+	if a.IncreaseContrast {
+		labels = append(labels, "increase_contrast")
+	}
+	if a.Appearance != "" {
+		labels = append(labels, "appearance="+a.Appearance)
+	}
+	if a.Locale != "" {
+		labels = append(labels, "locale="+a.Locale)
+	}
+	if a.Region != "" {
+		labels = append(labels, "region="+a.Region)
+	}
+	if a.Language != "" {
+		labels = append(labels, "language="+a.Language)
+	}
+	return labels
+}
+
+// accessibilityArgs builds the xcrun arguments for each override.
+// Bold text has no "simctl ui" switch, so it is written to the Accessibility
+// preferences domain inside the simulator; apps pick it up on next launch.
+// Language and region are likewise written to the global domain.
+// deviceLocale is the device's AppleLocale, used when only a region is set.
+func accessibilityArgs(udid, deviceSetPath string, a AccessibilityOverrides, deviceLocale string) [][]string {
+	base := []string{"simctl"}
+	if deviceSetPath != "" {
+		base = append(base, "--set", deviceSetPath)
+	}
+	withBase := func(args ...string) []string {
+		return append(slices.Clone(base), args...)
+	}
+
+	var cmds [][]string
+	if a.DynamicType != "" {
+		cmds = append(cmds, withBase("ui", udid, "content_size", a.DynamicType))
+	}
+	if a.BoldText {
+		cmds = append(cmds, withBase("spawn", udid, "defaults", "write", "com.apple.Accessibility", "EnhancedTextLegibilityEnabled", "-bool", "YES"))
+	}
+	if a.IncreaseContrast {
+		cmds = append(cmds, withBase("ui", udid, "increase_contrast", "enabled"))
+	}
+	if a.Appearance != "" {
+		cmds = append(cmds, withBase("ui", udid, "appearance", a.Appearance))
+	}
+	language, locale := a.languageAndLocale(deviceLocale)
+	return append(cmds, localeArgs(udid, deviceSetPath, locale, language)...)
+}
+
+// localeArgs builds the xcrun arguments that write AppleLocale and
+// AppleLanguages to the simulator's global domain. An empty locale or
+// language is left unchanged. Either separator is accepted; the locale is
+// written with underscores ("zh_Hant_TW") and the language with hyphens
+// ("zh-Hant-TW"), as iOS stores them.
+func localeArgs(udid, deviceSetPath, locale, language string) [][]string {
+	base := []string{"simctl"}
+	if deviceSetPath != "" {
+		base = append(base, "--set", deviceSetPath)
+	}
+	var cmds [][]string
+	if language != "" {
+		cmds = append(cmds, append(slices.Clone(base), "spawn", udid, "defaults", "write", "-g", "AppleLanguages", "-array", strings.ReplaceAll(language, "_", "-")))
+	}
+	if locale != "" {
+		cmds = append(cmds, append(slices.Clone(base), "spawn", udid, "defaults", "write", "-g", "AppleLocale", "-string", strings.ReplaceAll(locale, "-", "_")))
+	}
+	return cmds
+}
+
+// ApplyLocale sets the locale (e.g. "ja_JP") and UI language (e.g. "ja")
+// of a booted simulator; an empty value is left unchanged. Running apps
+// keep the language they launched with, so the app must be relaunched to
+// render in the new one.
+func ApplyLocale(ctx context.Context, udid, deviceSetPath, locale, language string) error {
+	if err := (AccessibilityOverrides{Locale: locale, Language: language}).Validate(); err != nil {
+		return err
+	}
+	return runXcrun(ctx, localeArgs(udid, deviceSetPath, locale, language))
+}
+
+// runXcrun runs each argument list with xcrun, stopping at the first
+// failure.
+func runXcrun(ctx context.Context, cmds [][]string) error {
+	for _, args := range cmds {
+		if _, err := xcrun(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xcrun runs xcrun with args and returns its standard output. The error
+// includes the command and its standard error. Replaced in tests.
+var xcrun = func(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := procgroup.Command(ctx, "xcrun", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("xcrun %s: %w\n%s", strings.Join(args, " "), err, stderr.Bytes())
+	}
+	return out, nil
+}
@@ -0,0 +1,272 @@
+package platform
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAccessibilityArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		setPath      string
+		a            AccessibilityOverrides
+		deviceLocale string
+		want         [][]string
+	}{
+		{
+			name: "none",
+			a:    AccessibilityOverrides{},
+			want: nil,
+		},
+		{
+			name:    "dynamic type",
+			setPath: "/tmp/set",
+			a:       AccessibilityOverrides{DynamicType: "accessibility-large"},
+			want: [][]string{
+				{"simctl", "--set", "/tmp/set", "ui", "UDID", "content_size", "accessibility-large"},
+			},
+		},
+		{
+			name:    "bold text",
+			setPath: "/tmp/set",
+			a:       AccessibilityOverrides{BoldText: true},
+			want: [][]string{
+				{"simctl", "--set", "/tmp/set", "spawn", "UDID", "defaults", "write", "com.apple.Accessibility", "EnhancedTextLegibilityEnabled", "-bool", "YES"},
+			},
+		},
+		{
+			name:    "increase contrast",
+			setPath: "/tmp/set",
+			a:       AccessibilityOverrides{IncreaseContrast: true},
+			want: [][]string{
+				{"simctl", "--set", "/tmp/set", "ui", "UDID", "increase_contrast", "enabled"},
+			},
+		},
+		{
+			name: "all without device set",
+			a:    AccessibilityOverrides{DynamicType: "small", BoldText: true, IncreaseContrast: true},
+			want: [][]string{
+				{"simctl", "ui", "UDID", "content_size", "small"},
+				{"simctl", "spawn", "UDID", "defaults", "write", "com.apple.Accessibility", "EnhancedTextLegibilityEnabled", "-bool", "YES"},
+				{"simctl", "ui", "UDID", "increase_contrast", "enabled"},
+			},
+		},
+		{
+			name: "dark appearance",
+			a:    AccessibilityOverrides{Appearance: "dark"},
+			want: [][]string{
+				{"simctl", "ui", "UDID", "appearance", "dark"},
+			},
+		},
+		{
+			name: "locale",
+			a:    AccessibilityOverrides{Locale: "ja_JP"},
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "ja-JP"},
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "ja_JP"},
+			},
+		},
+		{
+			name: "locale with script and region override",
+			a:    AccessibilityOverrides{Locale: "zh-Hant-TW", Region: "HK"},
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "zh-Hant-HK"},
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "zh_Hant_HK"},
+			},
+		},
+		{
+			name: "region only",
+			a:    AccessibilityOverrides{Region: "419"},
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "en_419"},
+			},
+		},
+		{
+			name:         "region only keeps the device's language",
+			a:            AccessibilityOverrides{Region: "JP"},
+			deviceLocale: "fr_FR",
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "fr_JP"},
+			},
+		},
+		{
+			name: "language only",
+			a:    AccessibilityOverrides{Language: "ja"},
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "ja"},
+			},
+		},
+		{
+			name: "language overrides the locale's",
+			a:    AccessibilityOverrides{Locale: "en_JP", Language: "ja"},
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "ja"},
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "en_JP"},
+			},
+		},
+		{
+			name: "region with language",
+			a:    AccessibilityOverrides{Region: "JP", Language: "ja"},
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "ja"},
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "ja_JP"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := accessibilityArgs("UDID", tt.setPath, tt.a, tt.deviceLocale)
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+				t.Errorf("accessibilityArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocaleArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		setPath  string
+		locale   string
+		language string
+		want     [][]string
+	}{
+		{
+			name: "none",
+			want: nil,
+		},
+		{
+			name:     "both in a device set",
+			setPath:  "/tmp/set",
+			locale:   "ja_JP",
+			language: "ja",
+			want: [][]string{
+				{"simctl", "--set", "/tmp/set", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "ja"},
+				{"simctl", "--set", "/tmp/set", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "ja_JP"},
+			},
+		},
+		{
+			name:   "locale only",
+			locale: "en_GB",
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "en_GB"},
+			},
+		},
+		{
+			name:     "separators are normalized",
+			locale:   "zh-Hant-TW",
+			language: "zh_Hant",
+			want: [][]string{
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "zh-Hant"},
+				{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "zh_Hant_TW"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := localeArgs("UDID", tt.setPath, tt.locale, tt.language)
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+				t.Errorf("localeArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLocale_Invalid(t *testing.T) {
+	if err := ApplyLocale(t.Context(), "UDID", "", "ja_JP", "Japanese"); err == nil {
+		t.Error("expected error for an invalid language")
+	}
+}
+
+func TestAccessibilityOverrides_Validate(t *testing.T) {
+	if err := (AccessibilityOverrides{}).Validate(); err != nil {
+		t.Errorf("zero value should be valid, got %v", err)
+	}
+	if err := (AccessibilityOverrides{DynamicType: "accessibility-extra-large"}).Validate(); err != nil {
+		t.Errorf("expected valid size, got %v", err)
+	}
+	if err := (AccessibilityOverrides{DynamicType: "huge"}).Validate(); err == nil {
+		t.Error("expected error for unknown size")
+	}
+
+	valid := []AccessibilityOverrides{
+		{Appearance: "dark"},
+		{Locale: "ja"},
+		{Locale: "en_GB"},
+		{Locale: "zh-Hant-TW"},
+		{Locale: "es_419", Region: "MX"},
+		{Language: "zh-Hant"},
+		{Locale: "en_JP", Language: "ja"},
+	}
+	for _, a := range valid {
+		if err := a.Validate(); err != nil {
+			t.Errorf("%+v should be valid, got %v", a, err)
+		}
+	}
+	invalid := []AccessibilityOverrides{
+		{Appearance: "sepia"},
+		{Locale: "Japanese"},
+		{Locale: "ja_jp"},
+		{Locale: "ja JP"},
+		{Region: "jp"},
+		{Language: "Japanese"},
+	}
+	for _, a := range invalid {
+		if err := a.Validate(); err == nil {
+			t.Errorf("%+v should be invalid", a)
+		}
+	}
+}
+
+func TestAccessibilityOverrides_Labels(t *testing.T) {
+	a := AccessibilityOverrides{DynamicType: "large", BoldText: true, IncreaseContrast: true, Appearance: "dark", Locale: "ja_JP", Region: "US", Language: "en"}
+	want := []string{"dynamic_type=large", "bold_text", "increase_contrast", "appearance=dark", "locale=ja_JP", "region=US", "language=en"}
+	if got := a.Labels(); !slices.Equal(got, want) {
+		t.Errorf("Labels() = %v, want %v", got, want)
+	}
+	if got := (AccessibilityOverrides{}).Labels(); len(got) != 0 {
+		t.Errorf("Labels() on zero value = %v, want empty", got)
+	}
+}
+
+func TestAccessibilityOverrides_Or(t *testing.T) {
+	profile := AccessibilityOverrides{Appearance: "dark", Locale: "ja_JP", Region: "JP", Language: "ja", BoldText: true}
+	tests := []struct {
+		name string
+		a    AccessibilityOverrides
+		want AccessibilityOverrides
+	}{
+		{
+			name: "no flags",
+			a:    AccessibilityOverrides{},
+			want: profile,
+		},
+		{
+			name: "flags win",
+			a:    AccessibilityOverrides{Appearance: "light", DynamicType: "large"},
+			want: AccessibilityOverrides{Appearance: "light", DynamicType: "large", Locale: "ja_JP", Region: "JP", Language: "ja", BoldText: true},
+		},
+		{
+			name: "locale replaces the profile's region and language",
+			a:    AccessibilityOverrides{Locale: "en_GB"},
+			want: AccessibilityOverrides{Appearance: "dark", Locale: "en_GB", BoldText: true},
+		},
+		{
+			name: "region applies to the profile's locale",
+			a:    AccessibilityOverrides{Region: "US"},
+			want: AccessibilityOverrides{Appearance: "dark", Locale: "ja_JP", Region: "US", Language: "ja", BoldText: true},
+		},
+		{
+			name: "language applies to the profile's locale",
+			a:    AccessibilityOverrides{Language: "en"},
+			want: AccessibilityOverrides{Appearance: "dark", Locale: "ja_JP", Region: "JP", Language: "en", BoldText: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Or(profile); got != tt.want {
+				t.Errorf("Or() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package platform
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -230,6 +231,61 @@ func FindProcess(simctl SimctlRunner, pl ProcessLister, name string, device stri
 	return matched[0].PID, nil
 }
 
+// simAppPathRe captures the device set and device UDID from the path of an
+// app running on a simulator: <device set>/<UDID>/data/Containers/...
+// Unlike coreSimRe it matches devices in any set, including axe's own.
+var simAppPathRe = regexp.MustCompile(`(/.*?)/([0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12})/data/`)
+
+// ValidateSimulatorPID checks that pid is an app process running on a
+// booted simulator. It returns the process together with the device set
+// path its simulator belongs to.
+func ValidateSimulatorPID(ctx context.Context, simctl SimctlRunner, pl ProcessLister, pid int) (SimProcess, string, error) {
+	psOut, err := pl.ListProcesses()
+	if err != nil {
+		return SimProcess{}, "", err
+	}
+
+	var args string
+	for line := range strings.SplitSeq(strings.TrimSpace(psOut), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != strconv.Itoa(pid) {
+			continue
+		}
+		args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+		break
+	}
+	if args == "" {
+		return SimProcess{}, "", fmt.Errorf("process %d not found", pid)
+	}
+
+	pathMatch := simAppPathRe.FindStringSubmatch(args)
+	appMatch := appNameRe.FindStringSubmatch(args)
+	if pathMatch == nil || appMatch == nil || strings.Contains(args, "launchd_sim") {
+		return SimProcess{}, "", fmt.Errorf("process %d is not an app running on a simulator", pid)
+	}
+	setPath, udid := pathMatch[1], pathMatch[2]
+
+	devices, err := simctl.ListDevices(ctx, setPath)
+	if err != nil {
+		return SimProcess{}, "", err
+	}
+	for _, d := range devices {
+		if d.UDID != udid {
+			continue
+		}
+		if d.State != "Booted" {
+			return SimProcess{}, "", fmt.Errorf("process %d runs on simulator %s (%s), which is %s, not Booted", pid, d.Name, udid, d.State)
+		}
+		return SimProcess{
+			PID:        pid,
+			App:        appMatch[1],
+			DeviceUDID: udid,
+			DeviceName: d.Name,
+		}, setPath, nil
+	}
+	return SimProcess{}, "", fmt.Errorf("process %d runs on simulator %s, which is not in device set %s", pid, udid, setPath)
+}
+
 // matchProcesses filters processes by app name and optionally by device.
 // The device value is matched against both DeviceUDID and DeviceName,
 // since simctl accepts either form. If device is empty or "booted",
@@ -1,16 +1,36 @@
 package platform
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 // axeConfig represents the persistent config stored at ~/Library/Developer/axe/config.json.
 type axeConfig struct {
 	DefaultSimulator string `json:"defaultSimulator,omitempty"`
+
+	// LastUsedSimulators maps an absolute project or workspace path to the
+	// UDID of the axe-managed simulator it last ran on.
+	LastUsedSimulators map[string]string `json:"lastUsedSimulators,omitempty"`
+
+	// SimulatorStrategy names the SelectionStrategy used to pick a
+	// simulator to create (see ParseSelectionStrategy).
+	SimulatorStrategy string `json:"simulatorStrategy,omitempty"`
+
+	// DeviceProfiles maps a simulator UDID to the overrides applied
+	// whenever it runs a preview (axe preview simulator config).
+	DeviceProfiles map[string]AccessibilityOverrides `json:"deviceProfiles,omitempty"`
+
+	// SimulatorsUsedAt maps the UDID of an axe-managed simulator to when
+	// ResolveAxeSimulator last picked it (axe preview simulator clean).
+	SimulatorsUsedAt map[string]time.Time `json:"simulatorsUsedAt,omitempty"`
 }
 
 // ConfigStore reads and writes the axe global config file.
@@ -53,6 +73,8 @@ func (s *ConfigStore) Load() (axeConfig, error) {
 
 // Save writes the config to disk atomically, creating parent directories if needed.
 // It writes to a temporary file first, then renames to avoid partial writes.
+// Save does not lock; read-modify-write changes go through update so that
+// concurrent axe processes do not drop each other's changes.
 func (s *ConfigStore) Save(cfg axeConfig) error {
 	dir := filepath.Dir(s.path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -93,6 +115,25 @@ func (s *ConfigStore) Save(cfg axeConfig) error {
 	return nil
 }
 
+// update applies fn to the config under the config lock and saves the
+// result if fn reports a change.
+func (s *ConfigStore) update(fn func(cfg *axeConfig) bool) error {
+	lock, err := acquireFileLock(context.Background(), s.path+".lock", "config "+s.path, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	cfg, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if !fn(&cfg) {
+		return nil
+	}
+	return s.Save(cfg)
+}
+
 // GetDefault returns the default simulator UDID, or "" if not set.
 func (s *ConfigStore) GetDefault() (string, error) {
 	cfg, err := s.Load()
@@ -104,20 +145,252 @@ func (s *ConfigStore) GetDefault() (string, error) {
 
 // SetDefault sets the default simulator UDID.
 func (s *ConfigStore) SetDefault(udid string) error {
+	return s.update(func(cfg *axeConfig) bool {
+		cfg.DefaultSimulator = udid
+		return true
+	})
+}
+
+// ClearDefault removes the default simulator setting.
+func (s *ConfigStore) ClearDefault() error {
+	return s.update(func(cfg *axeConfig) bool {
+		cfg.DefaultSimulator = ""
+		return true
+	})
+}
+
+// GetLastUsed returns the simulator UDID last used for project, or "" if none.
+func (s *ConfigStore) GetLastUsed(project string) (string, error) {
 	cfg, err := s.Load()
 	if err != nil {
-		return err
+		return "", err
 	}
-	cfg.DefaultSimulator = udid
-	return s.Save(cfg)
+	return cfg.LastUsedSimulators[project], nil
 }
 
-// ClearDefault removes the default simulator setting.
-func (s *ConfigStore) ClearDefault() error {
+// SetLastUsed records udid as the simulator last used for project.
+func (s *ConfigStore) SetLastUsed(project, udid string) error {
+	// Checked without the lock first: this runs on every preview launch
+	// and rarely changes anything.
+	if cfg, err := s.Load(); err == nil && cfg.LastUsedSimulators[project] == udid {
+		return nil
+	}
+	return s.update(func(cfg *axeConfig) bool {
+		if cfg.LastUsedSimulators[project] == udid {
+			return false
+		}
+		if cfg.LastUsedSimulators == nil {
+			cfg.LastUsedSimulators = make(map[string]string)
+		}
+		cfg.LastUsedSimulators[project] = udid
+		return true
+	})
+}
+
+// ClearLastUsed forgets the simulator last used for project.
+func (s *ConfigStore) ClearLastUsed(project string) error {
+	return s.update(func(cfg *axeConfig) bool {
+		delete(cfg.LastUsedSimulators, project)
+		return true
+	})
+}
+
+// GetDeviceProfile returns the overrides persisted for the simulator udid,
+// or the zero value if it has none.
+func (s *ConfigStore) GetDeviceProfile(udid string) (AccessibilityOverrides, error) {
 	cfg, err := s.Load()
+	if err != nil {
+		return AccessibilityOverrides{}, err
+	}
+	return cfg.DeviceProfiles[udid], nil
+}
+
+// SetDeviceProfile persists profile as the overrides of the simulator udid.
+// A zero profile removes it.
+func (s *ConfigStore) SetDeviceProfile(udid string, profile AccessibilityOverrides) error {
+	return s.update(func(cfg *axeConfig) bool {
+		if profile.IsZero() {
+			delete(cfg.DeviceProfiles, udid)
+			return true
+		}
+		if cfg.DeviceProfiles == nil {
+			cfg.DeviceProfiles = make(map[string]AccessibilityOverrides)
+		}
+		cfg.DeviceProfiles[udid] = profile
+		return true
+	})
+}
+
+// usageResolution is how stale a SimulatorsUsedAt entry may get before
+// TouchSimulator rewrites it, so that every preview launch does not write
+// the config.
+const usageResolution = time.Hour
+
+// SimulatorsUsedAt returns when each simulator was last picked, by UDID.
+func (s *ConfigStore) SimulatorsUsedAt() (map[string]time.Time, error) {
+	cfg, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.SimulatorsUsedAt, nil
+}
+
+// TouchSimulator records that the simulator udid was used at now.
+func (s *ConfigStore) TouchSimulator(udid string, now time.Time) error {
+	fresh := func(cfg *axeConfig) bool {
+		return now.Sub(cfg.SimulatorsUsedAt[udid]) < usageResolution
+	}
+	// Checked without the lock first, as in SetLastUsed.
+	if cfg, err := s.Load(); err == nil && fresh(&cfg) {
+		return nil
+	}
+	return s.update(func(cfg *axeConfig) bool {
+		if fresh(cfg) {
+			return false
+		}
+		if cfg.SimulatorsUsedAt == nil {
+			cfg.SimulatorsUsedAt = make(map[string]time.Time)
+		}
+		cfg.SimulatorsUsedAt[udid] = now.UTC()
+		return true
+	})
+}
+
+// ForgetSimulator removes the usage record of the simulator udid.
+func (s *ConfigStore) ForgetSimulator(udid string) error {
+	return s.update(func(cfg *axeConfig) bool {
+		if _, ok := cfg.SimulatorsUsedAt[udid]; !ok {
+			return false
+		}
+		delete(cfg.SimulatorsUsedAt, udid)
+		return true
+	})
+}
+
+// ReplaceSimulator points every reference to oldUDID (the default, any
+// per-project last-used entry, its device profile and usage record) at
+// newUDID.
+func (s *ConfigStore) ReplaceSimulator(oldUDID, newUDID string) error {
+	return s.update(func(cfg *axeConfig) bool {
+		changed := false
+		if cfg.DefaultSimulator == oldUDID {
+			cfg.DefaultSimulator = newUDID
+			changed = true
+		}
+		if profile, ok := cfg.DeviceProfiles[oldUDID]; ok {
+			delete(cfg.DeviceProfiles, oldUDID)
+			cfg.DeviceProfiles[newUDID] = profile
+			changed = true
+		}
+		if usedAt, ok := cfg.SimulatorsUsedAt[oldUDID]; ok {
+			delete(cfg.SimulatorsUsedAt, oldUDID)
+			cfg.SimulatorsUsedAt[newUDID] = usedAt
+			changed = true
+		}
+		for project, udid := range cfg.LastUsedSimulators {
+			if udid == oldUDID {
+				cfg.LastUsedSimulators[project] = newUDID
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// Config keys accepted by Get and Set.
+const (
+	// ConfigKeyDefaultSimulator is the UDID of the default simulator.
+	ConfigKeyDefaultSimulator = "default-simulator"
+	// ConfigKeyLastUsedPrefix prefixes an absolute project or workspace
+	// path to form the key of the simulator it last ran on.
+	ConfigKeyLastUsedPrefix = "last-used-simulator."
+	// ConfigKeySimulatorStrategy selects how axe picks a simulator to create.
+	ConfigKeySimulatorStrategy = "simulator-strategy"
+)
+
+// ErrInvalidConfigKey is returned by Get and Set for a key that is not
+// stored in the config or is malformed.
+var ErrInvalidConfigKey = errors.New("invalid config key")
+
+// ConfigEntry is one key and value of the config.
+type ConfigEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Entries returns every key that has a value, sorted by key.
+func (s *ConfigStore) Entries() ([]ConfigEntry, error) {
+	cfg, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	var entries []ConfigEntry
+	if cfg.DefaultSimulator != "" {
+		entries = append(entries, ConfigEntry{ConfigKeyDefaultSimulator, cfg.DefaultSimulator})
+	}
+	if cfg.SimulatorStrategy != "" {
+		entries = append(entries, ConfigEntry{ConfigKeySimulatorStrategy, cfg.SimulatorStrategy})
+	}
+	for project, udid := range cfg.LastUsedSimulators {
+		entries = append(entries, ConfigEntry{ConfigKeyLastUsedPrefix + project, udid})
+	}
+	slices.SortFunc(entries, func(a, b ConfigEntry) int { return strings.Compare(a.Key, b.Key) })
+	return entries, nil
+}
+
+// Get returns the value of key, or "" if it is not set.
+func (s *ConfigStore) Get(key string) (string, error) {
+	switch key {
+	case ConfigKeyDefaultSimulator:
+		return s.GetDefault()
+	case ConfigKeySimulatorStrategy:
+		cfg, err := s.Load()
+		if err != nil {
+			return "", err
+		}
+		return cfg.SimulatorStrategy, nil
+	}
+	project, err := lastUsedProject(key)
+	if err != nil {
+		return "", err
+	}
+	return s.GetLastUsed(project)
+}
+
+// Set stores value under key. An empty value removes the key. Values are
+// not validated here; callers check that a simulator exists or that a
+// strategy parses.
+func (s *ConfigStore) Set(key, value string) error {
+	switch key {
+	case ConfigKeyDefaultSimulator:
+		if value == "" {
+			return s.ClearDefault()
+		}
+		return s.SetDefault(value)
+	case ConfigKeySimulatorStrategy:
+		return s.update(func(cfg *axeConfig) bool {
+			cfg.SimulatorStrategy = value
+			return true
+		})
+	}
+	project, err := lastUsedProject(key)
 	if err != nil {
 		return err
 	}
-	cfg.DefaultSimulator = ""
-	return s.Save(cfg)
+	if value == "" {
+		return s.ClearLastUsed(project)
+	}
+	return s.SetLastUsed(project, value)
+}
+
+// lastUsedProject returns the project path of a last-used-simulator key.
+func lastUsedProject(key string) (string, error) {
+	project, ok := strings.CutPrefix(key, ConfigKeyLastUsedPrefix)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown key %q (known keys: %s, %s, %s<project path>)", ErrInvalidConfigKey, key, ConfigKeyDefaultSimulator, ConfigKeySimulatorStrategy, ConfigKeyLastUsedPrefix)
+	}
+	if !filepath.IsAbs(project) {
+		return "", fmt.Errorf("%w: %s: project path must be absolute", ErrInvalidConfigKey, key)
+	}
+	return project, nil
 }
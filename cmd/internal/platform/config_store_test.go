@@ -1,9 +1,13 @@
 package platform
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfigStore_LoadEmpty(t *testing.T) {
@@ -74,6 +78,171 @@ func TestConfigStore_ClearDefault(t *testing.T) {
 	}
 }
 
+func TestConfigStore_LastUsed(t *testing.T) {
+	dir := t.TempDir()
+	store := NewConfigStoreWithPath(filepath.Join(dir, "config.json"))
+
+	if err := store.SetLastUsed("/src/App.xcodeproj", "UDID-APP"); err != nil {
+		t.Fatalf("SetLastUsed: %v", err)
+	}
+	if err := store.SetLastUsed("/src/Other.xcworkspace", "UDID-OTHER"); err != nil {
+		t.Fatalf("SetLastUsed: %v", err)
+	}
+	if err := store.ClearLastUsed("/src/Other.xcworkspace"); err != nil {
+		t.Fatalf("ClearLastUsed: %v", err)
+	}
+
+	got, err := store.GetLastUsed("/src/App.xcodeproj")
+	if err != nil {
+		t.Fatalf("GetLastUsed: %v", err)
+	}
+	if got != "UDID-APP" {
+		t.Errorf("GetLastUsed(App) = %q, want %q", got, "UDID-APP")
+	}
+	got, err = store.GetLastUsed("/src/Other.xcworkspace")
+	if err != nil {
+		t.Fatalf("GetLastUsed: %v", err)
+	}
+	if got != "" {
+		t.Errorf("GetLastUsed(Other) after clear = %q, want empty", got)
+	}
+}
+
+func TestConfigStore_DeviceProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	store := NewConfigStoreWithPath(path)
+	profile := AccessibilityOverrides{Appearance: "dark", Locale: "ja_JP"}
+
+	if err := store.SetDeviceProfile("UDID-A", profile); err != nil {
+		t.Fatalf("SetDeviceProfile: %v", err)
+	}
+	// Persisted on disk: a new store sees it.
+	got, err := NewConfigStoreWithPath(path).GetDeviceProfile("UDID-A")
+	if err != nil {
+		t.Fatalf("GetDeviceProfile: %v", err)
+	}
+	if got != profile {
+		t.Errorf("GetDeviceProfile(A) = %+v, want %+v", got, profile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"appearance": "dark"`) {
+		t.Errorf("config does not store the profile by field name:\n%s", data)
+	}
+
+	// Recreating the simulator carries the profile over.
+	if err := store.ReplaceSimulator("UDID-A", "UDID-B"); err != nil {
+		t.Fatalf("ReplaceSimulator: %v", err)
+	}
+	if got, _ := store.GetDeviceProfile("UDID-A"); !got.IsZero() {
+		t.Errorf("GetDeviceProfile(A) after replace = %+v, want zero", got)
+	}
+	if got, _ := store.GetDeviceProfile("UDID-B"); got != profile {
+		t.Errorf("GetDeviceProfile(B) after replace = %+v, want %+v", got, profile)
+	}
+
+	// A zero profile removes it.
+	if err := store.SetDeviceProfile("UDID-B", AccessibilityOverrides{}); err != nil {
+		t.Fatalf("SetDeviceProfile(zero): %v", err)
+	}
+	cfg, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.DeviceProfiles) != 0 {
+		t.Errorf("DeviceProfiles = %v, want empty", cfg.DeviceProfiles)
+	}
+}
+
+func TestConfigStore_SimulatorUsage(t *testing.T) {
+	store := NewConfigStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := store.TouchSimulator("UDID-A", t0); err != nil {
+		t.Fatalf("TouchSimulator: %v", err)
+	}
+	// A touch within usageResolution keeps the earlier time.
+	if err := store.TouchSimulator("UDID-A", t0.Add(time.Minute)); err != nil {
+		t.Fatalf("TouchSimulator: %v", err)
+	}
+	usedAt, err := store.SimulatorsUsedAt()
+	if err != nil {
+		t.Fatalf("SimulatorsUsedAt: %v", err)
+	}
+	if !usedAt["UDID-A"].Equal(t0) {
+		t.Errorf("used at %v, want %v", usedAt["UDID-A"], t0)
+	}
+	later := t0.Add(2 * usageResolution)
+	_ = store.TouchSimulator("UDID-A", later)
+	if usedAt, _ := store.SimulatorsUsedAt(); !usedAt["UDID-A"].Equal(later) {
+		t.Errorf("used at %v after a later touch, want %v", usedAt["UDID-A"], later)
+	}
+
+	// Recreating moves the record; removing forgets it.
+	if err := store.ReplaceSimulator("UDID-A", "UDID-B"); err != nil {
+		t.Fatalf("ReplaceSimulator: %v", err)
+	}
+	if usedAt, _ := store.SimulatorsUsedAt(); !usedAt["UDID-B"].Equal(later) || !usedAt["UDID-A"].IsZero() {
+		t.Errorf("after replace: %v", usedAt)
+	}
+	if err := store.ForgetSimulator("UDID-B"); err != nil {
+		t.Fatalf("ForgetSimulator: %v", err)
+	}
+	if usedAt, _ := store.SimulatorsUsedAt(); len(usedAt) != 0 {
+		t.Errorf("after forget: %v", usedAt)
+	}
+}
+
+func TestConfigStore_GetSetEntries(t *testing.T) {
+	store := NewConfigStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+
+	if err := store.Set("last-used-simulator./src/App.xcodeproj", "UDID-APP"); err != nil {
+		t.Fatalf("Set last-used: %v", err)
+	}
+	if err := store.Set("default-simulator", "UDID-DEFAULT"); err != nil {
+		t.Fatalf("Set default: %v", err)
+	}
+	if err := store.Set("simulator-strategy", "model:iPhone 16"); err != nil {
+		t.Fatalf("Set strategy: %v", err)
+	}
+
+	got, err := store.Get("last-used-simulator./src/App.xcodeproj")
+	if err != nil || got != "UDID-APP" {
+		t.Errorf("Get last-used = (%q, %v), want UDID-APP", got, err)
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	want := []ConfigEntry{
+		{"default-simulator", "UDID-DEFAULT"},
+		{"last-used-simulator./src/App.xcodeproj", "UDID-APP"},
+		{"simulator-strategy", "model:iPhone 16"},
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("Entries = %v, want %v", entries, want)
+	}
+
+	if err := store.Set("default-simulator", ""); err != nil {
+		t.Fatalf("clearing default: %v", err)
+	}
+	if got, _ := store.Get("default-simulator"); got != "" {
+		t.Errorf("default after clear = %q, want empty", got)
+	}
+
+	for _, key := range []string{"default", "last-used-simulator.App.xcodeproj"} {
+		if _, err := store.Get(key); !errors.Is(err, ErrInvalidConfigKey) {
+			t.Errorf("Get(%q) err = %v, want ErrInvalidConfigKey", key, err)
+		}
+		if err := store.Set(key, "X"); !errors.Is(err, ErrInvalidConfigKey) {
+			t.Errorf("Set(%q) err = %v, want ErrInvalidConfigKey", key, err)
+		}
+	}
+}
+
 func TestConfigStore_LoadCorruptedJSON(t *testing.T) {
 	dir := t.TempDir()
 	p := filepath.Join(dir, "config.json")
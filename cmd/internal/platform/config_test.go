@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"howett.net/plist"
@@ -14,10 +15,11 @@ import (
 // configFakeSimctlRunner is a SimctlRunner fake for config tests.
 type configFakeSimctlRunner struct {
 	allDevicesJSON []byte
+	devices        map[string][]simDevice // set path -> devices
 }
 
-func (f *configFakeSimctlRunner) ListDevices(_ context.Context, _ string) ([]simDevice, error) {
-	return nil, nil
+func (f *configFakeSimctlRunner) ListDevices(_ context.Context, setPath string) ([]simDevice, error) {
+	return f.devices[setPath], nil
 }
 func (f *configFakeSimctlRunner) Clone(_ context.Context, _, _, _ string) (string, error) {
 	return "", nil
@@ -27,6 +29,7 @@ func (f *configFakeSimctlRunner) Create(_ context.Context, _, _, _, _ string) (s
 }
 func (f *configFakeSimctlRunner) Shutdown(_ context.Context, _, _ string) error { return nil }
 func (f *configFakeSimctlRunner) Delete(_ context.Context, _, _ string) error   { return nil }
+func (f *configFakeSimctlRunner) Erase(_ context.Context, _, _ string) error    { return nil }
 func (f *configFakeSimctlRunner) Boot(_ context.Context, _ string) error        { return nil }
 func (f *configFakeSimctlRunner) ListAllDevices(_ context.Context, _ bool) ([]byte, error) {
 	if f.allDevicesJSON != nil {
@@ -598,3 +601,59 @@ func TestBuildDeviceMap_WithFake(t *testing.T) {
 func TestRealProcessLister_ImplementsInterface(t *testing.T) {
 	var _ ProcessLister = &RealProcessLister{}
 }
+
+func TestValidateSimulatorPID(t *testing.T) {
+	const axeSet = "/Users/user/Library/Developer/axe/Simulator Devices"
+	const stdSet = "/Users/user/Library/Developer/CoreSimulator/Devices"
+	simctl := &configFakeSimctlRunner{devices: map[string][]simDevice{
+		axeSet: {{Name: "axe iPhone 16 Pro (1)", UDID: "AAAAAAAA-0000-0000-0000-000000000001", State: "Booted"}},
+		stdSet: {{Name: "iPhone 16", UDID: "BBBBBBBB-0000-0000-0000-000000000002", State: "Shutdown"}},
+	}}
+	pl := &fakeProcessLister{
+		output: `  PID ARGS
+  101 /sbin/launchd
+  202 ` + axeSet + `/AAAAAAAA-0000-0000-0000-000000000001/data/Containers/Bundle/Application/0F1E2D3C-4B5A-6978-8796-A5B4C3D2E1F0/HogeApp.app/HogeApp
+  303 ` + stdSet + `/BBBBBBBB-0000-0000-0000-000000000002/data/Containers/Bundle/Application/ABC123/HogeApp.app/HogeApp
+  404 ` + axeSet + `/AAAAAAAA-0000-0000-0000-000000000001/data/../launchd_sim`,
+	}
+
+	t.Run("app on booted axe device", func(t *testing.T) {
+		proc, setPath, err := ValidateSimulatorPID(context.Background(), simctl, pl, 202)
+		if err != nil {
+			t.Fatalf("ValidateSimulatorPID: %v", err)
+		}
+		if setPath != axeSet {
+			t.Errorf("setPath = %q, want %q", setPath, axeSet)
+		}
+		want := SimProcess{PID: 202, App: "HogeApp", DeviceUDID: "AAAAAAAA-0000-0000-0000-000000000001", DeviceName: "axe iPhone 16 Pro (1)"}
+		if proc != want {
+			t.Errorf("proc = %+v, want %+v", proc, want)
+		}
+	})
+
+	for _, tc := range []struct {
+		name    string
+		pid     int
+		wantErr string
+	}{
+		{"unknown pid", 999, "not found"},
+		{"pid prefix is not a match", 20, "not found"},
+		{"not a simulator app", 101, "not an app running on a simulator"},
+		{"launchd_sim", 404, "not an app running on a simulator"},
+		{"device not booted", 303, "not Booted"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := ValidateSimulatorPID(context.Background(), simctl, pl, tc.pid)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("device missing from its set", func(t *testing.T) {
+		_, _, err := ValidateSimulatorPID(context.Background(), &configFakeSimctlRunner{}, pl, 202)
+		if err == nil || !strings.Contains(err.Error(), "not in device set") {
+			t.Errorf("err = %v, want not in device set", err)
+		}
+	})
+}
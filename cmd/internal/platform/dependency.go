@@ -1,10 +1,22 @@
 package platform
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strings"
+
+	"github.com/k-kohey/axe/internal/procgroup"
 )
 
+// ErrIDBCompanionNotFound is returned when idb_companion is not in PATH.
+var ErrIDBCompanionNotFound = errors.New("idb_companion not found in PATH")
+
+// ErrFullXcodeNotSelected is returned when xcode-select does not point into a
+// full Xcode, typically because only the Command Line Tools are installed.
+var ErrFullXcodeNotSelected = errors.New("full Xcode is not selected")
+
 // LookPather abstracts exec.LookPath for testing.
 type LookPather interface {
 	LookPath(file string) (string, error)
@@ -30,7 +42,34 @@ func CheckIDBCompanion() error {
 func CheckIDBCompanionWith(lp LookPather) error {
 	_, err := lp.LookPath("idb_companion")
 	if err != nil {
-		return fmt.Errorf("idb_companion not found in PATH. Install via: brew install facebook/fb/idb-companion")
+		return fmt.Errorf("%w. Install via: brew install facebook/fb/idb-companion", ErrIDBCompanionNotFound)
 	}
 	return nil
 }
+
+// CheckXcode validates that the active developer directory (xcode-select -p)
+// belongs to a full Xcode. simctl and xcodebuild misbehave when only the
+// Command Line Tools are selected.
+func CheckXcode(ctx context.Context) error {
+	out, err := procgroup.Command(ctx, "xcode-select", "-p").Output()
+	if err != nil {
+		return fmt.Errorf("%w: xcode-select -p failed: %v. Install Xcode, then run: sudo xcode-select -s /Applications/Xcode.app", ErrFullXcodeNotSelected, err)
+	}
+	return CheckXcodeSelectOutput(string(out))
+}
+
+// CheckXcodeSelectOutput validates the output of xcode-select -p, e.g.
+// "/Applications/Xcode.app/Contents/Developer".
+func CheckXcodeSelectOutput(out string) error {
+	dir := strings.TrimSpace(out)
+	for elem := range strings.SplitSeq(dir, "/") {
+		if strings.HasSuffix(elem, ".app") {
+			return nil
+		}
+	}
+	if dir == "" {
+		dir = "nothing"
+	}
+	return fmt.Errorf("%w: xcode-select points to %s, which is not inside an Xcode.app (Command Line Tools only?). "+
+		"Install Xcode, then run: sudo xcode-select -s /Applications/Xcode.app", ErrFullXcodeNotSelected, dir)
+}
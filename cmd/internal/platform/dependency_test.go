@@ -32,6 +32,9 @@ func TestCheckIDBCompanion_NotFound(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
+	if !errors.Is(err, ErrIDBCompanionNotFound) {
+		t.Errorf("expected ErrIDBCompanionNotFound, got %v", err)
+	}
 	if !strings.Contains(err.Error(), "idb_companion not found") {
 		t.Errorf("unexpected error message: %v", err)
 	}
@@ -39,3 +42,33 @@ func TestCheckIDBCompanion_NotFound(t *testing.T) {
 		t.Errorf("error should mention brew install: %v", err)
 	}
 }
+
+func TestCheckXcodeSelectOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		wantErr bool
+	}{
+		{"full Xcode", "/Applications/Xcode.app/Contents/Developer\n", false},
+		{"beta Xcode", "/Applications/Xcode-beta.app/Contents/Developer\n", false},
+		{"command line tools only", "/Library/Developer/CommandLineTools\n", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckXcodeSelectOutput(tt.out)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrFullXcodeNotSelected) {
+				t.Fatalf("expected ErrFullXcodeNotSelected, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "sudo xcode-select -s") {
+				t.Errorf("error should explain how to select Xcode: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// deviceSetting is one simulator setting an AccessibilityOverrides field
+// changes. read holds the simctl arguments after the UDID that print its
+// current value; restore returns the arguments after the UDID that write a
+// value printed by read back, or nil when it cannot be written back. found
+// is false when the value is not set on the device at all.
+type deviceSetting struct {
+	name    string
+	sub     string // simctl subcommand preceding the UDID ("ui" or "spawn")
+	read    []string
+	restore func(value string, found bool) []string
+}
+
+var (
+	contentSizeSetting = deviceSetting{
+		name: "content_size",
+		sub:  "ui",
+		read: []string{"content_size"},
+		restore: func(v string, _ bool) []string {
+			if !slices.Contains(dynamicTypeSizes, v) {
+				return nil
+			}
+			return []string{"content_size", v}
+		},
+	}
+	boldTextSetting = deviceSetting{
+		name: "bold_text",
+		sub:  "spawn",
+		read: []string{"defaults", "read", "com.apple.Accessibility", "EnhancedTextLegibilityEnabled"},
+		restore: func(v string, found bool) []string {
+			if !found {
+				return []string{"defaults", "delete", "com.apple.Accessibility", "EnhancedTextLegibilityEnabled"}
+			}
+			b := "NO"
+			if v == "1" {
+				b = "YES"
+			}
+			return []string{"defaults", "write", "com.apple.Accessibility", "EnhancedTextLegibilityEnabled", "-bool", b}
+		},
+	}
+	increaseContrastSetting = deviceSetting{
+		name: "increase_contrast",
+		sub:  "ui",
+		read: []string{"increase_contrast"},
+		restore: func(v string, _ bool) []string {
+			if v != "enabled" && v != "disabled" {
+				return nil
+			}
+			return []string{"increase_contrast", v}
+		},
+	}
+	appearanceSetting = deviceSetting{
+		name: "appearance",
+		sub:  "ui",
+		read: []string{"appearance"},
+		restore: func(v string, _ bool) []string {
+			if !slices.Contains(appearances, v) {
+				return nil
+			}
+			return []string{"appearance", v}
+		},
+	}
+	languagesSetting = deviceSetting{
+		name: "AppleLanguages",
+		sub:  "spawn",
+		read: []string{"defaults", "read", "-g", "AppleLanguages"},
+		restore: func(v string, found bool) []string {
+			if !found {
+				return []string{"defaults", "delete", "-g", "AppleLanguages"}
+			}
+			return append([]string{"defaults", "write", "-g", "AppleLanguages", "-array"}, parseDefaultsArray(v)...)
+		},
+	}
+	localeSetting = deviceSetting{
+		name: "AppleLocale",
+		sub:  "spawn",
+		read: []string{"defaults", "read", "-g", "AppleLocale"},
+		restore: func(v string, found bool) []string {
+			if !found {
+				return []string{"defaults", "delete", "-g", "AppleLocale"}
+			}
+			return []string{"defaults", "write", "-g", "AppleLocale", "-string", v}
+		},
+	}
+)
+
+// settings returns the device settings that applying a changes.
+func (a AccessibilityOverrides) settings() []deviceSetting {
+	var s []deviceSetting
+	if a.DynamicType != "" {
+		s = append(s, contentSizeSetting)
+	}
+	if a.BoldText {
+		s = append(s, boldTextSetting)
+	}
+	if a.IncreaseContrast {
+		s = append(s, increaseContrastSetting)
+	}
+	if a.Appearance != "" {
+		s = append(s, appearanceSetting)
+	}
+	if a.Locale != "" || a.Language != "" {
+		s = append(s, languagesSetting)
+	}
+	if a.Locale != "" || a.Region != "" {
+		s = append(s, localeSetting)
+	}
+	return s
+}
+
+// parseDefaultsArray parses an array as printed by "defaults read", e.g.
+// `("en-US", ja)`, into its elements.
+func parseDefaultsArray(out string) []string {
+	out = strings.TrimSpace(out)
+	out = strings.TrimSuffix(strings.TrimPrefix(out, "("), ")")
+	var items []string
+	for item := range strings.SplitSeq(out, ",") {
+		if item = strings.Trim(strings.TrimSpace(item), `"`); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// savedSetting is the value a device had before a setting was overridden.
+type savedSetting struct {
+	setting deviceSetting
+	value   string
+	found   bool
+}
+
+// DeviceOverrides applies AccessibilityOverrides to one simulator and
+// remembers the value each setting had before it was first overridden.
+// A setting that a later Apply no longer overrides, and every setting on
+// Restore, is written back, so that a reused device is left as it was.
+// It is safe for concurrent use.
+type DeviceOverrides struct {
+	udid          string
+	deviceSetPath string
+
+	mu    sync.Mutex
+	saved map[string]savedSetting // by deviceSetting.name
+}
+
+// NewDeviceOverrides returns a DeviceOverrides for the simulator udid in
+// deviceSetPath (empty for the default device set).
+func NewDeviceOverrides(udid, deviceSetPath string) *DeviceOverrides {
+	return &DeviceOverrides{udid: udid, deviceSetPath: deviceSetPath, saved: map[string]savedSetting{}}
+}
+
+// Apply applies a to the booted simulator. The current value of each
+// setting a changes is read first, unless an earlier Apply already saved
+// it; settings overridden earlier but not by a are restored. A setting
+// whose value cannot be read is still applied, and is then left as a sets
+// it.
+func (d *DeviceOverrides) Apply(ctx context.Context, a AccessibilityOverrides) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	wanted := a.settings()
+	for _, name := range slices.Sorted(maps.Keys(d.saved)) {
+		if slices.ContainsFunc(wanted, func(s deviceSetting) bool { return s.name == name }) {
+			continue
+		}
+		if err := d.restoreLocked(ctx, d.saved[name]); err != nil {
+			errs = append(errs, err)
+		}
+		delete(d.saved, name)
+	}
+	for _, s := range wanted {
+		if _, ok := d.saved[s.name]; ok {
+			continue
+		}
+		sv, err := d.read(ctx, s)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d.saved[s.name] = sv
+	}
+
+	var deviceLocale string
+	if sv, ok := d.saved[localeSetting.name]; ok && sv.found {
+		deviceLocale = sv.value
+	}
+	if err := runXcrun(ctx, accessibilityArgs(d.udid, d.deviceSetPath, a, deviceLocale)); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Restore writes back every saved setting, in name order.
+func (d *DeviceOverrides) Restore(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(d.saved)) {
+		if err := d.restoreLocked(ctx, d.saved[name]); err != nil {
+			errs = append(errs, err)
+		}
+		delete(d.saved, name)
+	}
+	return errors.Join(errs...)
+}
+
+// read reads the current value of s. A "defaults read" of a key that is
+// not set fails; that is recorded as not found rather than returned.
+func (d *DeviceOverrides) read(ctx context.Context, s deviceSetting) (savedSetting, error) {
+	out, err := xcrun(ctx, d.args(s.sub, s.read)...)
+	if err != nil {
+		if s.sub == "spawn" && strings.Contains(err.Error(), "does not exist") {
+			return savedSetting{setting: s}, nil
+		}
+		return savedSetting{}, fmt.Errorf("reading %s: %w", s.name, err)
+	}
+	return savedSetting{setting: s, value: strings.TrimSpace(string(out)), found: true}, nil
+}
+
+func (d *DeviceOverrides) restoreLocked(ctx context.Context, sv savedSetting) error {
+	tail := sv.setting.restore(sv.value, sv.found)
+	if tail == nil {
+		return nil
+	}
+	_, err := xcrun(ctx, d.args(sv.setting.sub, tail)...)
+	return err
+}
+
+// args builds "simctl [--set <path>] <sub> <udid> <tail...>".
+func (d *DeviceOverrides) args(sub string, tail []string) []string {
+	args := []string{"simctl"}
+	if d.deviceSetPath != "" {
+		args = append(args, "--set", d.deviceSetPath)
+	}
+	args = append(args, sub, d.udid)
+	return append(args, tail...)
+}
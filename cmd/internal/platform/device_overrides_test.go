@@ -0,0 +1,71 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeviceOverrides(t *testing.T) {
+	var calls [][]string
+	orig := xcrun
+	xcrun = func(_ context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		switch strings.Join(args, " ") {
+		case "simctl ui UDID content_size":
+			return []byte("large\n"), nil
+		case "simctl spawn UDID defaults read -g AppleLocale":
+			return []byte("fr_FR\n"), nil
+		case "simctl spawn UDID defaults read -g AppleLanguages":
+			return nil, errors.New("The domain/default pair of (kCFPreferencesAnyApplication, AppleLanguages) does not exist")
+		}
+		return nil, nil
+	}
+	t.Cleanup(func() { xcrun = orig })
+
+	d := NewDeviceOverrides("UDID", "")
+	step := func(name string, run func() error, want [][]string) {
+		t.Helper()
+		calls = nil
+		if err := run(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !slices.EqualFunc(calls, want, slices.Equal[[]string]) {
+			t.Errorf("%s: calls = %v, want %v", name, calls, want)
+		}
+	}
+
+	step("first apply", func() error {
+		return d.Apply(t.Context(), AccessibilityOverrides{DynamicType: "small", Region: "JP"})
+	}, [][]string{
+		{"simctl", "ui", "UDID", "content_size"},
+		{"simctl", "spawn", "UDID", "defaults", "read", "-g", "AppleLocale"},
+		{"simctl", "ui", "UDID", "content_size", "small"},
+		{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "fr_JP"},
+	})
+	// The dynamic type is no longer overridden and is put back; the
+	// region applies to the device's original locale, which is not read
+	// again.
+	step("second apply", func() error {
+		return d.Apply(t.Context(), AccessibilityOverrides{Region: "GB", Language: "ja"})
+	}, [][]string{
+		{"simctl", "ui", "UDID", "content_size", "large"},
+		{"simctl", "spawn", "UDID", "defaults", "read", "-g", "AppleLanguages"},
+		{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLanguages", "-array", "ja"},
+		{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "ja_GB"},
+	})
+	step("restore", func() error { return d.Restore(t.Context()) }, [][]string{
+		{"simctl", "spawn", "UDID", "defaults", "delete", "-g", "AppleLanguages"},
+		{"simctl", "spawn", "UDID", "defaults", "write", "-g", "AppleLocale", "-string", "fr_FR"},
+	})
+	step("restore again", func() error { return d.Restore(t.Context()) }, nil)
+}
+
+func TestParseDefaultsArray(t *testing.T) {
+	got := parseDefaultsArray("(\n    \"en-US\",\n    ja\n)\n")
+	if want := []string{"en-US", "ja"}; !slices.Equal(got, want) {
+		t.Errorf("parseDefaultsArray() = %v, want %v", got, want)
+	}
+}
@@ -1,12 +1,14 @@
 package platform
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
@@ -15,8 +17,6 @@ import (
 
 const gcMaxAge = 14 * 24 * time.Hour // 2 weeks
 
-const simctlTimeout = 30 * time.Second
-
 // deviceKey groups pool entries by device type and runtime.
 type deviceKey struct {
 	DeviceType string
@@ -43,6 +43,7 @@ type DevicePool struct {
 	available     map[deviceKey][]poolEntry // Shutdown devices ready for reuse
 	inUse         map[string]poolEntry      // UDID → in-use entry
 	lockFiles     map[string]*os.File       // UDID → held flock file handle
+	known         map[string]bool           // UDIDs seen by the last Reconcile; nil before the first
 	simctl        SimctlRunner
 }
 
@@ -80,7 +81,7 @@ func (p *DevicePool) Acquire(ctx context.Context, deviceType, runtime string) (s
 	p.mu.Unlock()
 
 	// List existing devices (outside of lock to avoid holding mutex during I/O).
-	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout)
+	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout())
 	defer listCancel()
 	devices, err := p.simctl.ListDevices(listCtx, p.deviceSetPath)
 	if err != nil {
@@ -123,9 +124,11 @@ func (p *DevicePool) Acquire(ctx context.Context, deviceType, runtime string) (s
 	// Priority 3: clone an existing device.
 	if cloneSource != "" {
 		name := p.nextDeviceName(devices, deviceType)
-		cloneCtx, cloneCancel := context.WithTimeout(ctx, simctlTimeout)
-		udid, err := p.simctl.Clone(cloneCtx, cloneSource, name, p.deviceSetPath)
-		cloneCancel()
+		udid, err := p.withSetLock(ctx, func() (string, error) {
+			cloneCtx, cloneCancel := context.WithTimeout(ctx, simctlCreateTimeout())
+			defer cloneCancel()
+			return p.simctl.Clone(cloneCtx, cloneSource, name, p.deviceSetPath)
+		})
 		if err != nil {
 			slog.Warn("Clone failed, falling back to create", "source", cloneSource, "err", err)
 		} else {
@@ -153,9 +156,11 @@ func (p *DevicePool) Acquire(ctx context.Context, deviceType, runtime string) (s
 	const maxCreateRetries = 3
 	for range maxCreateRetries {
 		name := p.nextDeviceName(devices, deviceType)
-		createCtx, createCancel := context.WithTimeout(ctx, simctlTimeout)
-		udid, err := p.simctl.Create(createCtx, name, deviceType, runtime, p.deviceSetPath)
-		createCancel()
+		udid, err := p.withSetLock(ctx, func() (string, error) {
+			createCtx, createCancel := context.WithTimeout(ctx, simctlCreateTimeout())
+			defer createCancel()
+			return p.simctl.Create(createCtx, name, deviceType, runtime, p.deviceSetPath)
+		})
 		if err != nil {
 			return "", fmt.Errorf("creating device: %w", err)
 		}
@@ -179,6 +184,162 @@ func (p *DevicePool) Acquire(ctx context.Context, deviceType, runtime string) (s
 	return "", fmt.Errorf("failed to create device after %d attempts (concurrent claim conflicts)", maxCreateRetries)
 }
 
+// AcquireUDID obtains the specific simulator udid from the pool's device
+// set, e.g. when a user picks a device instead of a device type. It fails
+// if the device is not in the set or is already in use by this pool or
+// another axe process.
+func (p *DevicePool) AcquireUDID(ctx context.Context, udid string) error {
+	p.mu.Lock()
+	if _, inUse := p.inUse[udid]; inUse {
+		p.mu.Unlock()
+		return fmt.Errorf("device %s is already in use", udid)
+	}
+	for key, entries := range p.available {
+		i := slices.IndexFunc(entries, func(e poolEntry) bool { return e.UDID == udid })
+		if i < 0 {
+			continue
+		}
+		entry := entries[i]
+		p.available[key] = slices.Delete(entries, i, i+1)
+		p.inUse[udid] = entry
+		p.mu.Unlock()
+		p.acquireLockFile(udid)
+		p.writeMetaFile(udid)
+		slog.Info("Reusing pooled device by UDID", "udid", udid)
+		return nil
+	}
+	p.mu.Unlock()
+
+	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout())
+	defer listCancel()
+	devices, err := p.simctl.ListDevices(listCtx, p.deviceSetPath)
+	if err != nil {
+		return fmt.Errorf("listing devices: %w", err)
+	}
+	i := slices.IndexFunc(devices, func(d simDevice) bool { return d.UDID == udid })
+	if i < 0 {
+		return fmt.Errorf("device %s not found in %s", udid, p.deviceSetPath)
+	}
+	d := devices[i]
+
+	p.mu.Lock()
+	if _, inUse := p.inUse[udid]; inUse {
+		p.mu.Unlock()
+		return fmt.Errorf("device %s is already in use", udid)
+	}
+	p.inUse[udid] = poolEntry{UDID: udid, DeviceType: d.DeviceTypeIdentifier, Runtime: d.RuntimeID}
+	p.mu.Unlock()
+
+	if !p.acquireLockFile(udid) {
+		p.mu.Lock()
+		delete(p.inUse, udid)
+		p.mu.Unlock()
+		return fmt.Errorf("device %s is in use by another axe process", udid)
+	}
+	p.writeMetaFile(udid)
+	slog.Info("Acquired device by UDID", "udid", udid, "deviceType", d.DeviceTypeIdentifier, "runtime", d.RuntimeID)
+	return nil
+}
+
+// PoolDevice describes a simulator in the pool's device set.
+type PoolDevice struct {
+	UDID       string
+	Name       string
+	State      string // simctl state, e.g. "Booted" or "Shutdown"
+	DeviceType string
+	Runtime    string
+	InUse      bool // acquired from this pool and not yet released
+}
+
+// Devices lists the simulators in the pool's device set with their live
+// state, sorted by name.
+func (p *DevicePool) Devices(ctx context.Context) ([]PoolDevice, error) {
+	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout())
+	defer listCancel()
+	devices, err := p.simctl.ListDevices(listCtx, p.deviceSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make([]PoolDevice, 0, len(devices))
+	for _, d := range devices {
+		_, inUse := p.inUse[d.UDID]
+		result = append(result, PoolDevice{
+			UDID:       d.UDID,
+			Name:       d.Name,
+			State:      d.State,
+			DeviceType: d.DeviceTypeIdentifier,
+			Runtime:    d.RuntimeID,
+			InUse:      inUse,
+		})
+	}
+	slices.SortFunc(result, func(a, b PoolDevice) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.UDID, b.UDID))
+	})
+	return result, nil
+}
+
+// DeviceSetChanges lists the simulators that left or joined the pool's
+// device set since the previous Reconcile. Acquired devices missing from
+// the set are reported as Vanished until they are released.
+type DeviceSetChanges struct {
+	Vanished []string // UDIDs no longer in the set, e.g. deleted in Xcode
+	Appeared []string // UDIDs new to the set
+}
+
+// Reconcile lists the device set again to catch changes made outside axe
+// in a long-running session. Pooled devices that no longer exist are
+// dropped so that Acquire does not hand them out. In-use devices stay
+// acquired: callers stop whatever runs on the Vanished ones, whose Release
+// then fails to shut them down and frees their lock. The first call only
+// records the set, so it reports nothing as Appeared.
+func (p *DevicePool) Reconcile(ctx context.Context) (DeviceSetChanges, error) {
+	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout())
+	defer listCancel()
+	devices, err := p.simctl.ListDevices(listCtx, p.deviceSetPath)
+	if err != nil {
+		return DeviceSetChanges{}, fmt.Errorf("listing devices: %w", err)
+	}
+	current := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		current[d.UDID] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var changes DeviceSetChanges
+	vanished := func(udid string) {
+		if !current[udid] && !slices.Contains(changes.Vanished, udid) {
+			changes.Vanished = append(changes.Vanished, udid)
+		}
+	}
+	for udid := range p.known {
+		vanished(udid)
+	}
+	for udid := range p.inUse {
+		vanished(udid)
+	}
+	for key, entries := range p.available {
+		p.available[key] = slices.DeleteFunc(entries, func(e poolEntry) bool {
+			vanished(e.UDID)
+			return !current[e.UDID]
+		})
+	}
+	if p.known != nil {
+		for _, d := range devices {
+			if !p.known[d.UDID] {
+				changes.Appeared = append(changes.Appeared, d.UDID)
+			}
+		}
+	}
+	p.known = current
+	slices.Sort(changes.Vanished)
+	slices.Sort(changes.Appeared)
+	return changes, nil
+}
+
 // Release shuts down a device and returns it to the pool for reuse.
 func (p *DevicePool) Release(ctx context.Context, udid string) error {
 	p.mu.Lock()
@@ -190,7 +351,7 @@ func (p *DevicePool) Release(ctx context.Context, udid string) error {
 	delete(p.inUse, udid)
 	p.mu.Unlock()
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, simctlTimeout)
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, simctlTimeout())
 	defer shutdownCancel()
 	if err := p.simctl.Shutdown(shutdownCtx, udid, p.deviceSetPath); err != nil {
 		// Shutdown failed — device state is unknown, don't return to pool.
@@ -228,7 +389,7 @@ func (p *DevicePool) ShutdownAll(ctx context.Context) {
 	p.mu.Unlock()
 
 	for _, entry := range all {
-		sdCtx, sdCancel := context.WithTimeout(ctx, simctlTimeout)
+		sdCtx, sdCancel := context.WithTimeout(ctx, simctlTimeout())
 		if err := p.simctl.Shutdown(sdCtx, entry.UDID, p.deviceSetPath); err != nil {
 			slog.Debug("Failed to shutdown device during ShutdownAll", "udid", entry.UDID, "err", err)
 		}
@@ -242,7 +403,7 @@ func (p *DevicePool) ShutdownAll(ctx context.Context) {
 // (lock file is acquirable) and shuts them down.
 // Called at process startup before accepting streams.
 func (p *DevicePool) CleanupOrphans(ctx context.Context) error {
-	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout)
+	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout())
 	defer listCancel()
 	devices, err := p.simctl.ListDevices(listCtx, p.deviceSetPath)
 	if err != nil {
@@ -255,7 +416,7 @@ func (p *DevicePool) CleanupOrphans(ctx context.Context) error {
 		}
 		if p.isOrphaned(d.UDID) {
 			slog.Info("Cleaning up orphaned device", "udid", d.UDID, "name", d.Name)
-			sdCtx, sdCancel := context.WithTimeout(ctx, simctlTimeout)
+			sdCtx, sdCancel := context.WithTimeout(ctx, simctlTimeout())
 			if err := p.simctl.Shutdown(sdCtx, d.UDID, p.deviceSetPath); err != nil {
 				slog.Warn("Failed to shutdown orphaned device", "udid", d.UDID, "err", err)
 			}
@@ -268,7 +429,7 @@ func (p *DevicePool) CleanupOrphans(ctx context.Context) error {
 // GarbageCollect deletes devices that haven't been used in gcMaxAge.
 // Called at process exit to prevent disk bloat.
 func (p *DevicePool) GarbageCollect(ctx context.Context) {
-	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout)
+	listCtx, listCancel := context.WithTimeout(ctx, simctlTimeout())
 	defer listCancel()
 	devices, err := p.simctl.ListDevices(listCtx, p.deviceSetPath)
 	if err != nil {
@@ -296,8 +457,11 @@ func (p *DevicePool) GarbageCollect(ctx context.Context) {
 
 		if now.Sub(meta.LastUsed) > gcMaxAge {
 			slog.Info("Garbage collecting expired device", "udid", d.UDID, "name", d.Name, "lastUsed", meta.LastUsed)
-			delCtx, delCancel := context.WithTimeout(ctx, simctlTimeout)
-			if err := p.simctl.Delete(delCtx, d.UDID, p.deviceSetPath); err != nil {
+			delCtx, delCancel := context.WithTimeout(ctx, simctlTimeout())
+			_, err := p.withSetLock(ctx, func() (string, error) {
+				return "", p.simctl.Delete(delCtx, d.UDID, p.deviceSetPath)
+			})
+			if err != nil {
 				slog.Warn("Failed to delete expired device", "udid", d.UDID, "err", err)
 			} else {
 				// Remove from available map so stale UDIDs are not reused.
@@ -324,6 +488,17 @@ func (p *DevicePool) GarbageCollect(ctx context.Context) {
 	}
 }
 
+// withSetLock runs a simctl create, clone, or delete under the device set
+// lock so that it does not interleave with those of other axe processes.
+func (p *DevicePool) withSetLock(ctx context.Context, fn func() (string, error)) (string, error) {
+	lock, err := lockDeviceSet(ctx, p.deviceSetPath)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+	return fn()
+}
+
 // acquireLockFile creates and holds an exclusive flock for the device.
 // The OS automatically releases the flock on process exit (including SIGKILL),
 // allowing CleanupOrphans to detect zombie devices.
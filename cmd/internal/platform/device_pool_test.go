@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"syscall"
 	"testing"
@@ -105,6 +106,8 @@ func (f *fakeSimctlRunner) Shutdown(_ context.Context, udid, _ string) error {
 
 func (f *fakeSimctlRunner) Boot(_ context.Context, _ string) error { return nil }
 
+func (f *fakeSimctlRunner) Erase(_ context.Context, _, _ string) error { return nil }
+
 func (f *fakeSimctlRunner) Delete(_ context.Context, udid, _ string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -833,3 +836,177 @@ func TestDevicePool_Acquire_SkipsShutdownDeviceWithDifferentType(t *testing.T) {
 		t.Errorf("expected 1 Create call, got %d", runner.createCalls)
 	}
 }
+
+func TestDevicePool_AcquireUDID(t *testing.T) {
+	runner := newFakeSimctlRunner()
+	runner.addDevice("PICK-1", "axe iPad Air (1)", otherDeviceType, otherRuntime, "Shutdown")
+	pool := newTestPool(t, runner)
+
+	if err := pool.AcquireUDID(context.Background(), "PICK-1"); err != nil {
+		t.Fatalf("AcquireUDID: %v", err)
+	}
+	if err := pool.AcquireUDID(context.Background(), "PICK-1"); err == nil {
+		t.Error("second AcquireUDID of an in-use device should fail")
+	}
+	if err := pool.AcquireUDID(context.Background(), "MISSING-1"); err == nil {
+		t.Error("AcquireUDID of a device outside the set should fail")
+	}
+
+	// Released devices return to the pool under their own type and runtime,
+	// so an Acquire by type reuses the picked device.
+	if err := pool.Release(context.Background(), "PICK-1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	udid, err := pool.Acquire(context.Background(), otherDeviceType, otherRuntime)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if udid != "PICK-1" {
+		t.Errorf("Acquire = %q, want the released PICK-1", udid)
+	}
+}
+
+func TestDevicePool_AcquireUDID_FromPool(t *testing.T) {
+	runner := newFakeSimctlRunner()
+	pool := newTestPool(t, runner)
+
+	udid, err := pool.Acquire(context.Background(), testDeviceType, testRuntime)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := pool.Release(context.Background(), udid); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := pool.AcquireUDID(context.Background(), udid); err != nil {
+		t.Fatalf("AcquireUDID of a pooled device: %v", err)
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if n := len(pool.available[deviceKey{DeviceType: testDeviceType, Runtime: testRuntime}]); n != 0 {
+		t.Errorf("%d devices left in the pool, want 0", n)
+	}
+}
+
+func TestDevicePool_AcquireUDID_LockedDevice(t *testing.T) {
+	runner := newFakeSimctlRunner()
+	setPath := t.TempDir()
+	pool := NewDevicePool(runner, setPath)
+	runner.addDevice("LOCKED-1", "axe iPhone 16 Pro (1)", testDeviceType, testRuntime, "Booted")
+
+	// Hold a lock on this device (simulates another process using it).
+	lockFile, err := os.OpenFile(filepath.Join(setPath, "LOCKED-1.lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		t.Fatalf("creating lock file: %v", err)
+	}
+	defer func() { _ = lockFile.Close() }()
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Fatalf("acquiring lock: %v", err)
+	}
+	defer func() { _ = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN) }()
+
+	if err := pool.AcquireUDID(context.Background(), "LOCKED-1"); err == nil {
+		t.Fatal("AcquireUDID of a device locked by another process should fail")
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if _, inUse := pool.inUse["LOCKED-1"]; inUse {
+		t.Error("failed AcquireUDID left the device marked in use")
+	}
+}
+
+func TestDevicePool_Devices(t *testing.T) {
+	runner := newFakeSimctlRunner()
+	runner.addDevice("B-1", "axe iPhone 16 Pro (2)", testDeviceType, testRuntime, "Shutdown")
+	runner.addDevice("A-1", "axe iPhone 16 Pro (1)", testDeviceType, testRuntime, "Shutdown")
+	pool := newTestPool(t, runner)
+
+	if err := pool.AcquireUDID(context.Background(), "B-1"); err != nil {
+		t.Fatalf("AcquireUDID: %v", err)
+	}
+	runner.mu.Lock()
+	d := runner.devices["B-1"]
+	d.State = "Booted"
+	runner.devices["B-1"] = d
+	runner.mu.Unlock()
+
+	devices, err := pool.Devices(context.Background())
+	if err != nil {
+		t.Fatalf("Devices: %v", err)
+	}
+	want := []PoolDevice{
+		{UDID: "A-1", Name: "axe iPhone 16 Pro (1)", State: "Shutdown", DeviceType: testDeviceType, Runtime: testRuntime},
+		{UDID: "B-1", Name: "axe iPhone 16 Pro (2)", State: "Booted", DeviceType: testDeviceType, Runtime: testRuntime, InUse: true},
+	}
+	if !slices.Equal(devices, want) {
+		t.Errorf("Devices = %+v, want %+v", devices, want)
+	}
+}
+
+func TestDevicePool_Reconcile(t *testing.T) {
+	runner := newFakeSimctlRunner()
+	pool := newTestPool(t, runner)
+	ctx := context.Background()
+
+	runner.addDevice("POOLED", "iPhone 16 Pro", testDeviceType, testRuntime, "Shutdown")
+	pooled, err := pool.Acquire(ctx, testDeviceType, testRuntime)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := pool.Release(ctx, pooled); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	// Added after the Acquire above, which would otherwise pick either.
+	runner.addDevice("IDLE", "iPhone 16 Pro 2", testDeviceType, testRuntime, "Shutdown")
+	inUse, err := pool.Acquire(ctx, otherDeviceType, otherRuntime)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	// The first call records the set.
+	changes, err := pool.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(changes.Vanished) != 0 || len(changes.Appeared) != 0 {
+		t.Errorf("first Reconcile = %+v, want no changes", changes)
+	}
+
+	// Devices deleted and created outside axe.
+	for _, udid := range []string{pooled, inUse, "IDLE"} {
+		if err := runner.Delete(ctx, udid, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runner.addDevice("ADDED", "iPhone 16 Pro 3", testDeviceType, testRuntime, "Shutdown")
+
+	changes, err = pool.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	wantVanished := []string{pooled, inUse, "IDLE"}
+	slices.Sort(wantVanished)
+	if !slices.Equal(changes.Vanished, wantVanished) {
+		t.Errorf("Vanished = %v, want %v", changes.Vanished, wantVanished)
+	}
+	if !slices.Equal(changes.Appeared, []string{"ADDED"}) {
+		t.Errorf("Appeared = %v, want [ADDED]", changes.Appeared)
+	}
+
+	// The vanished pooled device is not handed out again.
+	udid, err := pool.Acquire(ctx, testDeviceType, testRuntime)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if udid != "ADDED" {
+		t.Errorf("Acquire = %s, want the appeared device ADDED", udid)
+	}
+
+	// Changes are reported once.
+	changes, err = pool.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(changes.Appeared) != 0 || !slices.Equal(changes.Vanished, []string{inUse}) {
+		t.Errorf("third Reconcile = %+v, want only the still-acquired %s", changes, inUse)
+	}
+}
@@ -0,0 +1,105 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedDeviceSetFS is returned when the axe device set is on a
+// volume CoreSimulator does not handle reliably.
+var ErrUnsupportedDeviceSetFS = errors.New("unsupported filesystem for the axe device set")
+
+// networkFSTypes lists statfs filesystem type names of network volumes.
+var networkFSTypes = []string{"afpfs", "cifs", "nfs", "smbfs", "webdav"}
+
+// FSInfo describes the filesystem holding a path, as reported by statfs(2).
+type FSInfo struct {
+	Type          string // filesystem type name, e.g. "apfs" or "smbfs"
+	Local         bool   // MNT_LOCAL is set
+	CaseSensitive bool
+}
+
+// CheckDeviceSetFS reports whether fs, the filesystem holding the device set
+// at path, is supported. CoreSimulator expects a local, case-insensitive
+// volume; elsewhere it fails later with confusing simctl errors. overridden
+// tells whether AXE_DEVICE_SET chose path, which the advice depends on.
+func CheckDeviceSetFS(path string, fs FSInfo, overridden bool) error {
+	advice := "Set AXE_DEVICE_SET to a directory on a local, case-insensitive volume"
+	if overridden {
+		advice = "Point AXE_DEVICE_SET to a directory on a local, case-insensitive volume, or unset it to use the default"
+	}
+	if !fs.Local || slices.Contains(networkFSTypes, fs.Type) {
+		return fmt.Errorf("%w: %s is on a network volume (%s). %s", ErrUnsupportedDeviceSetFS, path, fs.Type, advice)
+	}
+	if fs.CaseSensitive {
+		return fmt.Errorf("%w: %s is on a case-sensitive volume (%s). %s", ErrUnsupportedDeviceSetFS, path, fs.Type, advice)
+	}
+	return nil
+}
+
+// statDeviceSetFS is deviceSetFS, replaced in tests.
+var statDeviceSetFS = deviceSetFS
+
+// checkedDeviceSets holds the device set paths whose filesystem this process
+// has already checked, so that the statfs and case probe run once per path
+// rather than on every resolve.
+var checkedDeviceSets sync.Map
+
+// PrepareAxeDeviceSet resolves the axe device set, creates it if needed, and
+// checks that it is on a supported filesystem. An unsupported filesystem is
+// an error when AXE_DEVICE_SET chose it, and only a warning for the default
+// location, which the user did not pick and may not be able to move.
+func PrepareAxeDeviceSet() (string, error) {
+	deviceSetPath, err := AxeDeviceSetPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(deviceSetPath, 0o755); err != nil {
+		return "", fmt.Errorf("creating axe device set directory: %w", err)
+	}
+	if _, done := checkedDeviceSets.Load(deviceSetPath); done {
+		return deviceSetPath, nil
+	}
+	fs, err := statDeviceSetFS(deviceSetPath)
+	if err != nil {
+		slog.Warn("Cannot determine the device set filesystem", "path", deviceSetPath, "err", err)
+		return deviceSetPath, nil
+	}
+	overridden := os.Getenv("AXE_DEVICE_SET") != ""
+	if err := CheckDeviceSetFS(deviceSetPath, fs, overridden); err != nil {
+		if overridden {
+			return "", err
+		}
+		slog.Warn("The axe device set may not work on this volume", "err", err)
+	}
+	checkedDeviceSets.Store(deviceSetPath, struct{}{})
+	return deviceSetPath, nil
+}
+
+// isCaseSensitiveDir reports whether dir is on a case-sensitive volume by
+// creating a probe file and looking it up with a different case.
+func isCaseSensitiveDir(dir string) (bool, error) {
+	f, err := os.CreateTemp(dir, ".axe-case-probe-")
+	if err != nil {
+		return false, err
+	}
+	name := f.Name()
+	_ = f.Close()
+	defer func() { _ = os.Remove(name) }()
+
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(name))))
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, os.ErrNotExist):
+		return true, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,35 @@
+package platform
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// mntLocal is MNT_LOCAL from <sys/mount.h>, which package syscall does not
+// export for darwin.
+const mntLocal = 0x1000
+
+// deviceSetFS describes the filesystem holding path.
+func deviceSetFS(path string) (FSInfo, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return FSInfo{}, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	caseSensitive, err := isCaseSensitiveDir(path)
+	if err != nil {
+		return FSInfo{}, fmt.Errorf("probing case sensitivity of %s: %w", path, err)
+	}
+
+	name := make([]byte, 0, len(st.Fstypename))
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return FSInfo{
+		Type:          string(name),
+		Local:         st.Flags&mntLocal != 0,
+		CaseSensitive: caseSensitive,
+	}, nil
+}
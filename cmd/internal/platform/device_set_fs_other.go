@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package platform
+
+// deviceSetFS reports every path as supported. CoreSimulator only exists on
+// macOS, so there is nothing to check elsewhere.
+func deviceSetFS(string) (FSInfo, error) {
+	return FSInfo{Type: "unknown", Local: true}, nil
+}
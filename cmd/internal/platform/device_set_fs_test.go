@@ -0,0 +1,124 @@
+package platform
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDeviceSetFS(t *testing.T) {
+	tests := []struct {
+		name    string
+		fs      FSInfo
+		wantErr string
+	}{
+		{name: "local APFS", fs: FSInfo{Type: "apfs", Local: true}},
+		{name: "local HFS+", fs: FSInfo{Type: "hfs", Local: true}},
+		{name: "SMB share", fs: FSInfo{Type: "smbfs"}, wantErr: "network volume"},
+		{name: "NFS reported as local", fs: FSInfo{Type: "nfs", Local: true}, wantErr: "network volume"},
+		{name: "case-sensitive APFS", fs: FSInfo{Type: "apfs", Local: true, CaseSensitive: true}, wantErr: "case-sensitive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDeviceSetFS("/Volumes/Shared/axe", tt.fs, true)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupportedDeviceSetFS) {
+				t.Fatalf("expected ErrUnsupportedDeviceSetFS, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "AXE_DEVICE_SET") {
+				t.Errorf("error should mention %q and AXE_DEVICE_SET: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckDeviceSetFS_DefaultPathAdvice(t *testing.T) {
+	err := CheckDeviceSetFS("/Users/me/Library/Developer/axe/Simulator Devices", FSInfo{Type: "apfs", Local: true, CaseSensitive: true}, false)
+	if !errors.Is(err, ErrUnsupportedDeviceSetFS) {
+		t.Fatalf("expected ErrUnsupportedDeviceSetFS, got %v", err)
+	}
+	if strings.Contains(err.Error(), "unset") {
+		t.Errorf("advice for the default path should not suggest unsetting AXE_DEVICE_SET: %v", err)
+	}
+}
+
+// stubDeviceSetFS makes PrepareAxeDeviceSet see fs for every path and
+// returns a counter of the lookups. Tests using it must not be parallel.
+func stubDeviceSetFS(t *testing.T, fs FSInfo) *int {
+	t.Helper()
+	calls := 0
+	orig := statDeviceSetFS
+	statDeviceSetFS = func(string) (FSInfo, error) {
+		calls++
+		return fs, nil
+	}
+	t.Cleanup(func() {
+		statDeviceSetFS = orig
+		checkedDeviceSets.Clear()
+	})
+	return &calls
+}
+
+func TestPrepareAxeDeviceSet_Env(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AXE_DEVICE_SET", filepath.Join(dir, "devices"))
+	calls := stubDeviceSetFS(t, FSInfo{Type: "apfs", Local: true})
+
+	for range 2 {
+		path, err := PrepareAxeDeviceSet()
+		if err != nil {
+			t.Fatalf("PrepareAxeDeviceSet: %v", err)
+		}
+		if path != filepath.Join(dir, "devices") {
+			t.Errorf("path = %q, want %q", path, filepath.Join(dir, "devices"))
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "devices")); err != nil || !info.IsDir() {
+		t.Errorf("device set directory not created: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("filesystem checked %d times for the same device set, want 1", *calls)
+	}
+}
+
+func TestPrepareAxeDeviceSet_UnsupportedFS(t *testing.T) {
+	caseSensitive := FSInfo{Type: "apfs", Local: true, CaseSensitive: true}
+
+	t.Run("AXE_DEVICE_SET fails", func(t *testing.T) {
+		t.Setenv("AXE_DEVICE_SET", filepath.Join(t.TempDir(), "devices"))
+		stubDeviceSetFS(t, caseSensitive)
+		if _, err := PrepareAxeDeviceSet(); !errors.Is(err, ErrUnsupportedDeviceSetFS) {
+			t.Errorf("err = %v, want ErrUnsupportedDeviceSetFS", err)
+		}
+	})
+
+	t.Run("default path warns", func(t *testing.T) {
+		t.Setenv("AXE_DEVICE_SET", "")
+		t.Setenv("HOME", t.TempDir())
+		stubDeviceSetFS(t, caseSensitive)
+		if _, err := PrepareAxeDeviceSet(); err != nil {
+			t.Errorf("PrepareAxeDeviceSet on the default path = %v, want only a warning", err)
+		}
+	})
+}
+
+func TestIsCaseSensitiveDir_LeavesNoProbe(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := isCaseSensitiveDir(dir); err != nil {
+		t.Fatalf("isCaseSensitiveDir: %v", err)
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 0 {
+		t.Errorf("probe file left behind: %v", ents)
+	}
+}
@@ -0,0 +1,127 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLowDiskSpace is returned when a volume axe writes to has less free
+// space than the configured minimum.
+var ErrLowDiskSpace = errors.New("not enough free disk space")
+
+// DefaultMinFreeSpace is the free space required when AXE_MIN_FREE_SPACE
+// is not set. A preview build plus a booted simulator can take several
+// gigabytes.
+const DefaultMinFreeSpace uint64 = 2 << 30
+
+// CheckFreeSpace reports whether free bytes available on the volume holding
+// path meet min. A min of 0 disables the check.
+func CheckFreeSpace(path string, free, minFree uint64) error {
+	if minFree == 0 || free >= minFree {
+		return nil
+	}
+	return fmt.Errorf("%w: only %s free on the volume holding %s, need at least %s. Free up space, or lower the limit with AXE_MIN_FREE_SPACE (e.g. AXE_MIN_FREE_SPACE=1GB, 0 disables the check)",
+		ErrLowDiskSpace, FormatBytes(free), path, FormatBytes(minFree))
+}
+
+// CheckDiskSpace fails fast when a volume holding one of paths (the axe
+// device set, the build cache) is nearly full, before a build or boot runs
+// out of space halfway and leaves a broken device set behind. The minimum
+// comes from AXE_MIN_FREE_SPACE, defaulting to DefaultMinFreeSpace.
+// Volumes whose free space cannot be determined are skipped.
+func CheckDiskSpace(paths ...string) error {
+	minFree, err := MinFreeSpace()
+	if err != nil {
+		return err
+	}
+	if minFree == 0 {
+		return nil
+	}
+	for _, path := range paths {
+		// The directory may not exist before the first run; measure the
+		// volume of its nearest existing ancestor.
+		dir := existingAncestor(path)
+		free, err := freeSpace(dir)
+		if err != nil {
+			slog.Debug("Cannot determine free disk space", "path", dir, "err", err)
+			continue
+		}
+		if err := CheckFreeSpace(path, free, minFree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinFreeSpace returns the free space CheckDiskSpace requires, from
+// AXE_MIN_FREE_SPACE (e.g. "2GB", "500MB", or a byte count) or
+// DefaultMinFreeSpace when unset.
+func MinFreeSpace() (uint64, error) {
+	v := os.Getenv("AXE_MIN_FREE_SPACE")
+	if v == "" {
+		return DefaultMinFreeSpace, nil
+	}
+	n, err := parseByteSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("AXE_MIN_FREE_SPACE: %w", err)
+	}
+	return n, nil
+}
+
+// byteUnits maps size suffixes to their multiplier. Units are binary
+// (1GB = 1024MB), like df -h.
+var byteUnits = []struct {
+	suffix string
+	n      uint64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseByteSize parses a size such as "2GB", "1.5G", "500MB", or "1048576".
+func parseByteSize(s string) (uint64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	mult := uint64(1)
+	for _, u := range byteUnits {
+		if n, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, mult = strings.TrimSpace(n), u.n
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 2GB, 500MB, or 0 to disable)", s)
+	}
+	return uint64(f * float64(mult)), nil
+}
+
+// FormatBytes renders n in the largest binary unit that keeps it >= 1,
+// e.g. "1.5 GB".
+func FormatBytes(n uint64) string {
+	for _, u := range byteUnits[:4] {
+		if n >= u.n {
+			return strconv.FormatFloat(float64(n)/float64(u.n), 'f', 1, 64) + " " + u.suffix
+		}
+	}
+	return strconv.FormatUint(n, 10) + " B"
+}
+
+// existingAncestor returns path or its nearest ancestor that exists.
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !linux
+
+package platform
+
+import "errors"
+
+// freeSpace is not implemented on this platform; CheckDiskSpace skips the
+// check.
+func freeSpace(string) (uint64, error) {
+	return 0, errors.New("free space is not available on this platform")
+}
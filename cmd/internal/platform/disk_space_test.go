@@ -0,0 +1,73 @@
+package platform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckFreeSpace(t *testing.T) {
+	tests := []struct {
+		name    string
+		free    uint64
+		min     uint64
+		wantErr bool
+	}{
+		{name: "above threshold", free: 50 << 30, min: DefaultMinFreeSpace},
+		{name: "exactly at threshold", free: DefaultMinFreeSpace, min: DefaultMinFreeSpace},
+		{name: "below threshold", free: 700 << 20, min: DefaultMinFreeSpace, wantErr: true},
+		{name: "check disabled", free: 0, min: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFreeSpace("/Users/me/Library/Developer/axe", tt.free, tt.min)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLowDiskSpace) {
+				t.Fatalf("expected ErrLowDiskSpace, got %v", err)
+			}
+			for _, want := range []string{"700.0 MB", "2.0 GB", "/Users/me/Library/Developer/axe", "AXE_MIN_FREE_SPACE"} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error should mention %q: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMinFreeSpace(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    uint64
+		wantErr bool
+	}{
+		{env: "", want: DefaultMinFreeSpace},
+		{env: "0", want: 0},
+		{env: "500MB", want: 500 << 20},
+		{env: "1.5g", want: 3 << 29},
+		{env: "1048576", want: 1 << 20},
+		{env: "lots", wantErr: true},
+		{env: "-1GB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Setenv("AXE_MIN_FREE_SPACE", tt.env)
+		got, err := MinFreeSpace()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("AXE_MIN_FREE_SPACE=%q: got (%d, %v), want %d (error %v)", tt.env, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckDiskSpace_MissingDirectory(t *testing.T) {
+	// An impossible minimum fails on any real volume, including that of a
+	// directory that does not exist yet.
+	t.Setenv("AXE_MIN_FREE_SPACE", "1000000TB")
+	err := CheckDiskSpace(t.TempDir() + "/not/created/yet")
+	if !errors.Is(err, ErrLowDiskSpace) {
+		t.Errorf("expected ErrLowDiskSpace, got %v", err)
+	}
+}
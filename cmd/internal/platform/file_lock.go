@@ -0,0 +1,77 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// ErrLockTimeout is returned when another axe process holds a device set
+// or config lock for longer than lockTimeout.
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// lockTimeout bounds the wait for a device set or config lock. It is longer
+// than a simctl create, the slowest operation performed under the lock.
+const lockTimeout = 60 * time.Second
+
+// lockPollInterval is how often a contended lock is retried.
+const lockPollInterval = 100 * time.Millisecond
+
+// fileLock is an exclusive advisory flock(2) on a lock file. It serializes
+// read-modify-write operations across axe processes (and across goroutines,
+// since each fileLock opens its own file description). The OS releases the
+// lock when the process exits, so a crashed process never leaves it held.
+type fileLock struct {
+	file *os.File
+}
+
+// acquireFileLock takes the exclusive lock on path, polling until it is
+// obtained, timeout elapses, or ctx is done. what names the guarded
+// resource in the contention error.
+func acquireFileLock(ctx context.Context, path, what string, timeout time.Duration) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return &fileLock{file: f}, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			_ = f.Close()
+			return nil, fmt.Errorf("flock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("%w: another axe process has been modifying the %s for over %s (lock file: %s)", ErrLockTimeout, what, timeout, path)
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, fmt.Errorf("waiting for the %s lock: %w", what, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Unlock releases the lock. The lock file is left in place for reuse.
+func (l *fileLock) Unlock() {
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	_ = l.file.Close()
+}
+
+// lockDeviceSet takes the lock serializing creation and deletion of
+// simulators in the device set at deviceSetPath.
+func lockDeviceSet(ctx context.Context, deviceSetPath string) (*fileLock, error) {
+	return acquireFileLock(ctx, filepath.Join(deviceSetPath, ".axe-device-set.lock"), "device set "+deviceSetPath, lockTimeout)
+}
@@ -0,0 +1,143 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentFakeSimctlRunner is a goroutine-safe SimctlRunner fake that
+// records how many creates run at the same time.
+type concurrentFakeSimctlRunner struct {
+	managerFakeSimctlRunner
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	created     int
+}
+
+func (f *concurrentFakeSimctlRunner) ListDevices(_ context.Context, _ string) ([]simDevice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.devices), nil
+}
+
+func (f *concurrentFakeSimctlRunner) Create(_ context.Context, name, deviceType, runtime, _ string) (string, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.mu.Unlock()
+
+	// Simulate simctl create taking a while, so that unserialized callers
+	// overlap.
+	time.Sleep(100 * time.Millisecond)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	f.created++
+	udid := fmt.Sprintf("UDID-%d", f.created)
+	f.devices = append(f.devices, simDevice{Name: name, UDID: udid, State: "Shutdown", DeviceTypeIdentifier: deviceType, RuntimeID: runtime})
+	return udid, nil
+}
+
+func TestAdd_ConcurrentCreatesAreSerialized(t *testing.T) {
+	t.Setenv("AXE_DEVICE_SET", t.TempDir())
+	store := NewConfigStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+	fake := &concurrentFakeSimctlRunner{}
+	fake.deviceTypesJSON = []byte(`{"devicetypes":[{"identifier":"com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro","name":"iPhone 16 Pro"}]}`)
+
+	const n = 2
+	var wg sync.WaitGroup
+	results := make([]ManagedSimulator, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Go(func() {
+			results[i], errs[i] = Add(fake, "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro", "com.apple.CoreSimulator.SimRuntime.iOS-18-2", false, store)
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Add #%d: %v", i, err)
+		}
+	}
+	if fake.maxInFlight != 1 {
+		t.Errorf("max concurrent creates = %d, want 1", fake.maxInFlight)
+	}
+	names := []string{results[0].Name, results[1].Name}
+	slices.Sort(names)
+	if want := []string{"axe iPhone 16 Pro (1)", "axe iPhone 16 Pro (2)"}; !slices.Equal(names, want) {
+		t.Errorf("created names = %v, want %v", names, want)
+	}
+}
+
+func TestAcquireFileLock_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set", ".axe-device-set.lock")
+	held, err := acquireFileLock(context.Background(), path, "device set", time.Second)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	_, err = acquireFileLock(context.Background(), path, "device set", 300*time.Millisecond)
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("contended acquire error = %v, want ErrLockTimeout", err)
+	}
+
+	held.Unlock()
+	l, err := acquireFileLock(context.Background(), path, "device set", time.Second)
+	if err != nil {
+		t.Fatalf("acquire after unlock: %v", err)
+	}
+	l.Unlock()
+}
+
+func TestAcquireFileLock_ContextCanceled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set", ".axe-device-set.lock")
+	held, err := acquireFileLock(context.Background(), path, "device set", time.Second)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	defer held.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = acquireFileLock(ctx, path, "device set", time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("contended acquire error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("acquire took %s after its context expired", elapsed)
+	}
+}
+
+func TestConfigStore_ConcurrentUpdatesKeepAllChanges(t *testing.T) {
+	store := NewConfigStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			if err := store.SetLastUsed(fmt.Sprintf("/p/%d", i), fmt.Sprintf("UDID-%d", i)); err != nil {
+				t.Errorf("SetLastUsed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	cfg, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.LastUsedSimulators) != n {
+		t.Errorf("LastUsedSimulators has %d entries, want %d: %v", len(cfg.LastUsedSimulators), n, cfg.LastUsedSimulators)
+	}
+}
@@ -0,0 +1,121 @@
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+// recordingStopTimeout bounds how long Stop waits for simctl to write the
+// end of the movie file after being interrupted.
+const recordingStopTimeout = 10 * time.Second
+
+// Recording is a screen recording of a simulator in progress, started by
+// StartRecording.
+type Recording struct {
+	// Path is the movie file being written.
+	Path string
+
+	cmd         *exec.Cmd
+	stderr      bytes.Buffer
+	stopTimeout time.Duration
+	done        chan struct{} // closed when the recording process exits
+	waitErr     error         // set before done is closed
+
+	stopOnce sync.Once
+	err      error // result of Stop
+}
+
+// recordVideoArgs builds the xcrun arguments for "simctl io recordVideo".
+// --force overwrites an existing file so that re-recording to the same
+// path works.
+func recordVideoArgs(udid, deviceSetPath, path string) []string {
+	args := []string{"simctl"}
+	if deviceSetPath != "" {
+		args = append(args, "--set", deviceSetPath)
+	}
+	return append(args, "io", udid, "recordVideo", "--codec=h264", "--force", path)
+}
+
+// StartRecording starts recording the screen of the given simulator device
+// to path as an H.264 mp4, using "xcrun simctl io recordVideo".
+//
+// The recording is deliberately not bound to a context: simctl writes the
+// end of the file only when it is interrupted, and killing it on context
+// cancellation would leave an unplayable file. Callers must call Stop, also
+// when shutting down on Ctrl+C. The process runs in its own process group,
+// so a Ctrl+C in the terminal does not reach it before Stop does.
+func StartRecording(udid, deviceSetPath, path string) (*Recording, error) {
+	cmd := exec.Command("xcrun", recordVideoArgs(udid, deviceSetPath, path)...)
+	procgroup.Setup(cmd)
+	return startRecording(cmd, path)
+}
+
+// startRecording starts cmd as the recording process for path.
+func startRecording(cmd *exec.Cmd, path string) (*Recording, error) {
+	r := &Recording{
+		Path:        path,
+		cmd:         cmd,
+		stopTimeout: recordingStopTimeout,
+		done:        make(chan struct{}),
+	}
+	cmd.Stderr = &r.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("simctl recordVideo: %w", err)
+	}
+	go func() {
+		r.waitErr = cmd.Wait()
+		close(r.done)
+	}()
+	return r, nil
+}
+
+// Done returns a channel that is closed when the recording process exits,
+// either after Stop or on its own (e.g. because the simulator shut down).
+func (r *Recording) Done() <-chan struct{} {
+	return r.done
+}
+
+// Stop interrupts the recording and waits until the movie file has been
+// finalized. If simctl does not exit in time it is killed and an error is
+// returned, since the file is then likely unplayable. Stop reports an
+// error as well when the recording process failed or wrote no file. It is
+// safe to call more than once and returns the same result each time.
+func (r *Recording) Stop() error {
+	r.stopOnce.Do(func() {
+		select {
+		case <-r.done:
+			// Exited on its own; only its result remains to be checked.
+		default:
+			if err := procgroup.SignalProcess(r.cmd.Process, syscall.SIGINT); err != nil {
+				r.err = fmt.Errorf("stopping recording: %w", err)
+				_ = procgroup.KillProcess(r.cmd.Process)
+				<-r.done
+				return
+			}
+			select {
+			case <-r.done:
+			case <-time.After(r.stopTimeout):
+				_ = procgroup.KillProcess(r.cmd.Process)
+				<-r.done
+				r.err = fmt.Errorf("simctl recordVideo did not finish writing %s within %s", r.Path, r.stopTimeout)
+				return
+			}
+		}
+		if r.waitErr != nil {
+			r.err = fmt.Errorf("simctl recordVideo: %w\n%s", r.waitErr, strings.TrimSpace(r.stderr.String()))
+			return
+		}
+		if _, err := os.Stat(r.Path); err != nil {
+			r.err = fmt.Errorf("no recording was written to %s: %w\n%s", r.Path, err, strings.TrimSpace(r.stderr.String()))
+		}
+	})
+	return r.err
+}
@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+func TestRecordVideoArgs(t *testing.T) {
+	got := recordVideoArgs("UDID-1", "/sets/axe", "/tmp/out.mp4")
+	want := []string{"simctl", "--set", "/sets/axe", "io", "UDID-1", "recordVideo", "--codec=h264", "--force", "/tmp/out.mp4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("recordVideoArgs:\n got %q\nwant %q", got, want)
+	}
+
+	got = recordVideoArgs("UDID-1", "", "/tmp/out.mp4")
+	want = []string{"simctl", "io", "UDID-1", "recordVideo", "--codec=h264", "--force", "/tmp/out.mp4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("recordVideoArgs without device set:\n got %q\nwant %q", got, want)
+	}
+}
+
+// startFakeRecording starts a shell script in place of simctl recordVideo.
+// The script gets the output path as $1.
+func startFakeRecording(t *testing.T, script, path string) *Recording {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script, "sh", path)
+	procgroup.Setup(cmd)
+	r, err := startRecording(cmd, path)
+	if err != nil {
+		t.Fatalf("startRecording: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = procgroup.KillProcess(cmd.Process)
+		<-r.Done()
+	})
+	// Give the shell time to install its trap.
+	time.Sleep(100 * time.Millisecond)
+	return r
+}
+
+// TestRecording_StopFinalizes verifies that Stop interrupts the recorder
+// and waits for it to write the file, like simctl does on SIGINT.
+func TestRecording_StopFinalizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.mp4")
+	r := startFakeRecording(t, `trap 'printf movie > "$1"; exit 0' INT; while :; do sleep 0.05; done`, path)
+
+	if err := r.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "movie" {
+		t.Errorf("recording not finalized: data=%q err=%v", data, err)
+	}
+	// Stop is idempotent.
+	if err := r.Stop(); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
+
+// TestRecording_StopKillsUnresponsiveRecorder verifies that Stop gives up
+// on a recorder that ignores the interrupt and reports the file as broken.
+func TestRecording_StopKillsUnresponsiveRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.mp4")
+	r := startFakeRecording(t, `trap '' INT; sleep 30`, path)
+	r.stopTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+	err := r.Stop()
+	if err == nil || !strings.Contains(err.Error(), "did not finish writing") {
+		t.Errorf("err = %v, want timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stop took %s, want about the stop timeout", elapsed)
+	}
+}
+
+// TestRecording_NoFileWritten verifies that Stop fails when the recorder
+// exits cleanly without producing the movie file.
+func TestRecording_NoFileWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.mp4")
+	r := startFakeRecording(t, `echo "Invalid device" >&2; trap 'exit 0' INT; while :; do sleep 0.05; done`, path)
+
+	err := r.Stop()
+	if err == nil || !strings.Contains(err.Error(), "no recording was written") || !strings.Contains(err.Error(), "Invalid device") {
+		t.Errorf("err = %v, want missing-file error with the recorder's stderr", err)
+	}
+}
+
+// TestRecording_ExitedOnItsOwn verifies that a recorder that failed before
+// Stop is reported through Done and Stop.
+func TestRecording_ExitedOnItsOwn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.mp4")
+	r := startFakeRecording(t, `echo "device shut down" >&2; exit 1`, path)
+
+	select {
+	case <-r.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after the recorder exited")
+	}
+	if err := r.Stop(); err == nil || !strings.Contains(err.Error(), "device shut down") {
+		t.Errorf("err = %v, want the recorder's failure", err)
+	}
+}
@@ -0,0 +1,242 @@
+package platform
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// SelectionStrategy decides which simulator axe creates when it has to
+// pick one on its own: when no Shutdown device in the axe set can be used
+// (ResolveAxeSimulator) and for the devices of report mode
+// (FindDefaultDeviceSpec).
+//
+// candidates are the available devices of the standard Xcode set, with
+// RuntimeID set. available are the device types simctl can create, with
+// their runtimes. Both are already restricted to --runtime when one is
+// given. Select returns a device whose Name and DeviceTypeIdentifier
+// describe the simulator to create, and the runtime identifier to create
+// it with. The error wraps ErrNoSimulator when nothing matches.
+type SelectionStrategy interface {
+	Select(candidates []simDevice, available []AvailableDeviceType) (simDevice, string, error)
+}
+
+// Strategy names accepted by ParseSelectionStrategy.
+const (
+	// StrategyLatestIPhone selects the newest iPhone on the newest iOS runtime.
+	StrategyLatestIPhone = "latest-iphone"
+	// StrategyModelPrefix prefixes a device model name, e.g.
+	// "model:iPhone SE (3rd generation)".
+	StrategyModelPrefix = "model:"
+)
+
+// ParseSelectionStrategy returns the strategy named by value, the
+// simulator-strategy config key. An empty value is the default,
+// LatestIPhone.
+func ParseSelectionStrategy(value string) (SelectionStrategy, error) {
+	if value == "" || value == StrategyLatestIPhone {
+		return LatestIPhone{}, nil
+	}
+	if model, ok := strings.CutPrefix(value, StrategyModelPrefix); ok {
+		if model = strings.TrimSpace(model); model != "" {
+			return DeviceModel{Name: model}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown simulator strategy %q (use %s or %s<device model>)", value, StrategyLatestIPhone, StrategyModelPrefix)
+}
+
+// Device families accepted by ParseDeviceFamily (--family, .axerc FAMILY).
+const (
+	FamilyIPhone = "iPhone"
+	FamilyIPad   = "iPad"
+)
+
+// ParseDeviceFamily returns the family named by value, case-insensitively.
+// An empty value is returned as is and means no family constraint.
+func ParseDeviceFamily(value string) (string, error) {
+	for _, f := range []string{"", FamilyIPhone, FamilyIPad} {
+		if strings.EqualFold(value, f) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown device family %q (use %s or %s)", value, FamilyIPhone, FamilyIPad)
+}
+
+// inFamily reports whether a device or device type name belongs to family.
+func inFamily(name, family string) bool {
+	return strings.Contains(name, family)
+}
+
+// LatestDevice prefers, among the devices of Family, the highest iOS
+// version and, on the same version, the lexicographically largest name.
+type LatestDevice struct {
+	Family string // FamilyIPhone or FamilyIPad; "" is FamilyIPhone
+}
+
+func (s LatestDevice) Select(candidates []simDevice, _ []AvailableDeviceType) (simDevice, string, error) {
+	family := cmp.Or(s.Family, FamilyIPhone)
+	var best simDevice
+	var bestVersion [2]int
+	for _, d := range candidates {
+		major, minor := parseIOSVersion(d.RuntimeID)
+		if major < 0 || !inFamily(d.Name, family) {
+			continue
+		}
+		v := [2]int{major, minor}
+		if best.UDID == "" || newerVersion(v, bestVersion) || (v == bestVersion && d.Name > best.Name) {
+			best = d
+			bestVersion = v
+		}
+	}
+	if best.UDID == "" {
+		return simDevice{}, "", fmt.Errorf("%w: no %s simulator found", ErrNoSimulator, family)
+	}
+	return best, best.RuntimeID, nil
+}
+
+// LatestIPhone is LatestDevice for iPhones. It is the default strategy.
+type LatestIPhone struct{}
+
+func (LatestIPhone) Select(candidates []simDevice, available []AvailableDeviceType) (simDevice, string, error) {
+	return LatestDevice{Family: FamilyIPhone}.Select(candidates, available)
+}
+
+// DeviceModel selects a specific device model by name (case-insensitive),
+// on the newest iOS runtime that has it. A model that has no simulator in
+// the standard set yet is created from its device type.
+type DeviceModel struct {
+	Name string // e.g. "iPhone SE (3rd generation)"
+}
+
+func (s DeviceModel) Select(candidates []simDevice, available []AvailableDeviceType) (simDevice, string, error) {
+	var best simDevice
+	var bestVersion [2]int
+	for _, d := range candidates {
+		major, minor := parseIOSVersion(d.RuntimeID)
+		if major < 0 || !strings.EqualFold(d.Name, s.Name) {
+			continue
+		}
+		if v := [2]int{major, minor}; best.UDID == "" || newerVersion(v, bestVersion) {
+			best = d
+			bestVersion = v
+		}
+	}
+	if best.UDID != "" {
+		return best, best.RuntimeID, nil
+	}
+
+	for _, dt := range available {
+		if !strings.EqualFold(dt.Name, s.Name) {
+			continue
+		}
+		var runtime string
+		for _, rt := range dt.Runtimes {
+			major, minor := parseIOSVersion(rt.Identifier)
+			if major < 0 {
+				continue
+			}
+			if v := [2]int{major, minor}; runtime == "" || newerVersion(v, bestVersion) {
+				runtime = rt.Identifier
+				bestVersion = v
+			}
+		}
+		if runtime != "" {
+			return simDevice{Name: dt.Name, DeviceTypeIdentifier: dt.Identifier, RuntimeID: runtime}, runtime, nil
+		}
+	}
+	return simDevice{}, "", fmt.Errorf("%w: no %s simulator found", ErrNoSimulator, s.Name)
+}
+
+// strategyForFamily returns the strategy to use when family (already
+// parsed) is requested. The configured strategy is kept if it picks a model
+// of that family; otherwise the family wins, as it comes from the command
+// line or .axerc.
+func strategyForFamily(configured SelectionStrategy, family string) SelectionStrategy {
+	if family == "" {
+		return configured
+	}
+	if m, ok := configured.(DeviceModel); ok && inFamily(m.Name, family) {
+		return configured
+	}
+	return LatestDevice{Family: family}
+}
+
+// newerVersion reports whether iOS version a is newer than b.
+func newerVersion(a, b [2]int) bool {
+	return a[0] > b[0] || (a[0] == b[0] && a[1] > b[1])
+}
+
+// configuredStrategy returns the strategy of the simulator-strategy config
+// key. An unreadable config falls back to the default; a value that does
+// not parse is an error so that a typo is not silently ignored.
+func configuredStrategy() (SelectionStrategy, error) {
+	store, err := NewConfigStore()
+	if err != nil {
+		return LatestIPhone{}, nil
+	}
+	value, err := store.Get(ConfigKeySimulatorStrategy)
+	if err != nil {
+		slog.Debug("Failed to read simulator strategy, using the default", "err", err)
+		return LatestIPhone{}, nil
+	}
+	strategy, err := ParseSelectionStrategy(value)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", ConfigKeySimulatorStrategy, err)
+	}
+	return strategy, nil
+}
+
+// selectDevice lists the standard Xcode set and the creatable device types
+// and lets strategy choose among them, without booting anything.
+// A non-empty runtimeID restricts the choice to that runtime.
+func selectDevice(simctl SimctlRunner, strategy SelectionStrategy, runtimeID string) (simDevice, string, error) {
+	ctx, cancel := simctlContext()
+	defer cancel()
+
+	out, err := simctl.ListAllDevices(ctx, true)
+	if err != nil {
+		return simDevice{}, "", fmt.Errorf("listing available devices: %w", err)
+	}
+	// Device types only matter to strategies that create a model nobody
+	// has a simulator of yet, so a failure here is not fatal.
+	available, err := ListAvailable(simctl)
+	if err != nil {
+		slog.Debug("Failed to list available device types", "err", err)
+	}
+	return selectFromJSON(out, available, strategy, runtimeID)
+}
+
+// selectFromJSON runs strategy over simctl "list devices --json" output and
+// available, restricted to runtimeID when it is non-empty.
+func selectFromJSON(devicesJSON []byte, available []AvailableDeviceType, strategy SelectionStrategy, runtimeID string) (simDevice, string, error) {
+	candidates, err := parseDevicesJSON(devicesJSON)
+	if err != nil {
+		return simDevice{}, "", err
+	}
+	if runtimeID != "" {
+		candidates = filterDevicesByRuntime(candidates, runtimeID)
+		available = filterAvailableByRuntime(available, runtimeID)
+	}
+	dev, runtime, err := strategy.Select(candidates, available)
+	if err != nil && runtimeID != "" {
+		return simDevice{}, "", fmt.Errorf("%w for runtime %s", err, humanReadableRuntime(runtimeID))
+	}
+	return dev, runtime, err
+}
+
+// filterAvailableByRuntime returns the device types that support
+// runtimeID, with only that runtime.
+func filterAvailableByRuntime(available []AvailableDeviceType, runtimeID string) []AvailableDeviceType {
+	var filtered []AvailableDeviceType
+	for _, dt := range available {
+		for _, rt := range dt.Runtimes {
+			if rt.Identifier == runtimeID {
+				dt.Runtimes = []AvailableRuntime{rt}
+				filtered = append(filtered, dt)
+				break
+			}
+		}
+	}
+	return filtered
+}
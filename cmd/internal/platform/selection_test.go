@@ -0,0 +1,115 @@
+package platform
+
+import (
+	"errors"
+	"testing"
+)
+
+// strategyCandidates is one candidate set shared by the strategy tests:
+// two iOS runtimes, a non-iOS runtime, and a model that only exists as a
+// creatable device type.
+var strategyCandidates = []simDevice{
+	{Name: "iPhone 15", UDID: "AAA", DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-15", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-17-5"},
+	{Name: "iPhone SE (3rd generation)", UDID: "BBB", DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-SE-3rd-generation", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-17-5"},
+	{Name: "iPhone 16", UDID: "CCC", DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-16", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+	{Name: "iPhone 16 Pro", UDID: "DDD", DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+	{Name: "iPad Air", UDID: "EEE", DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.iPad-Air", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+	{Name: "Apple TV", UDID: "FFF", DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.Apple-TV", RuntimeID: "com.apple.CoreSimulator.SimRuntime.tvOS-18-0"},
+}
+
+var strategyAvailable = []AvailableDeviceType{
+	{
+		Identifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-16e",
+		Name:       "iPhone 16e",
+		Runtimes: []AvailableRuntime{
+			{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-18-2", Name: "iOS 18.2"},
+			{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-18-4", Name: "iOS 18.4"},
+		},
+	},
+}
+
+func TestSelectionStrategies_SameCandidates(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    SelectionStrategy
+		wantType    string
+		wantRuntime string
+	}{
+		{"latest iPhone", LatestIPhone{}, "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro", "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+		{"latest iPad", LatestDevice{Family: FamilyIPad}, "com.apple.CoreSimulator.SimDeviceType.iPad-Air", "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+		{"device model", DeviceModel{Name: "iphone se (3rd generation)"}, "com.apple.CoreSimulator.SimDeviceType.iPhone-SE-3rd-generation", "com.apple.CoreSimulator.SimRuntime.iOS-17-5"},
+		{"device model from device types", DeviceModel{Name: "iPhone 16e"}, "com.apple.CoreSimulator.SimDeviceType.iPhone-16e", "com.apple.CoreSimulator.SimRuntime.iOS-18-4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, runtime, err := tt.strategy.Select(strategyCandidates, strategyAvailable)
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if dev.DeviceTypeIdentifier != tt.wantType || runtime != tt.wantRuntime {
+				t.Errorf("Select = (%s, %s), want (%s, %s)", dev.DeviceTypeIdentifier, runtime, tt.wantType, tt.wantRuntime)
+			}
+		})
+	}
+}
+
+func TestSelectionStrategies_NoMatch(t *testing.T) {
+	ipads := []simDevice{strategyCandidates[4]}
+	for _, s := range []SelectionStrategy{LatestIPhone{}, DeviceModel{Name: "iPhone 99"}} {
+		if _, _, err := s.Select(ipads, strategyAvailable); !errors.Is(err, ErrNoSimulator) {
+			t.Errorf("%T.Select: err = %v, want ErrNoSimulator", s, err)
+		}
+	}
+}
+
+func TestParseSelectionStrategy(t *testing.T) {
+	tests := []struct {
+		value string
+		want  SelectionStrategy
+	}{
+		{"", LatestIPhone{}},
+		{"latest-iphone", LatestIPhone{}},
+		{"model:iPhone 16 Pro", DeviceModel{Name: "iPhone 16 Pro"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSelectionStrategy(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSelectionStrategy(%q) = (%#v, %v), want %#v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"newest", "model:", "model:  "} {
+		if _, err := ParseSelectionStrategy(value); err == nil {
+			t.Errorf("ParseSelectionStrategy(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestParseDeviceFamily(t *testing.T) {
+	for value, want := range map[string]string{"": "", "iphone": FamilyIPhone, "iPad": FamilyIPad, "IPAD": FamilyIPad} {
+		if got, err := ParseDeviceFamily(value); err != nil || got != want {
+			t.Errorf("ParseDeviceFamily(%q) = (%q, %v), want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseDeviceFamily("watch"); err == nil {
+		t.Error("ParseDeviceFamily(watch): expected an error")
+	}
+}
+
+func TestStrategyForFamily(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured SelectionStrategy
+		family     string
+		want       SelectionStrategy
+	}{
+		{"no family", DeviceModel{Name: "iPhone SE (3rd generation)"}, "", DeviceModel{Name: "iPhone SE (3rd generation)"}},
+		{"family replaces the default", LatestIPhone{}, FamilyIPad, LatestDevice{Family: FamilyIPad}},
+		{"model of the family kept", DeviceModel{Name: "iPad mini (A17 Pro)"}, FamilyIPad, DeviceModel{Name: "iPad mini (A17 Pro)"}},
+		{"model of another family replaced", DeviceModel{Name: "iPhone 16"}, FamilyIPad, LatestDevice{Family: FamilyIPad}},
+	}
+	for _, tt := range tests {
+		if got := strategyForFamily(tt.configured, tt.family); got != tt.want {
+			t.Errorf("%s: strategyForFamily = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package platform
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// availableDevicesTTL is how long a "simctl list devices available" result
+// is reused. Long enough to cover the repeated resolutions of one command
+// or of serve's concurrent streams, short enough that simulators created
+// elsewhere (e.g. in Xcode) show up quickly.
+const availableDevicesTTL = 5 * time.Second
+
+// listCache reuses the output of a simctl listing for ttl. The listing has
+// no inputs, so a single entry is kept. It is safe for concurrent use;
+// concurrent callers on a stale entry wait for one listing instead of each
+// running simctl.
+type listCache struct {
+	ttl time.Duration
+	now func() time.Time // replaced in tests
+
+	mu  sync.Mutex
+	out []byte
+	at  time.Time // when out was listed; zero when there is no entry
+}
+
+// availableDevices caches RealSimctlRunner.ListAllDevices(ctx, true), which
+// ResolveAxeSimulator runs to pick the device it auto-creates. Creating,
+// cloning or deleting a simulator invalidates it.
+var availableDevices = &listCache{ttl: availableDevicesTTL, now: time.Now}
+
+// get returns the cached output if it is younger than c.ttl, and otherwise
+// calls list and caches its output. Errors are not cached.
+func (c *listCache) get(ctx context.Context, list func(context.Context) ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.at.IsZero() && c.now().Sub(c.at) < c.ttl {
+		return c.out, nil
+	}
+	out, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.out, c.at = out, c.now()
+	return out, nil
+}
+
+// invalidate drops the cached output so that the next get lists again.
+func (c *listCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out, c.at = nil, time.Time{}
+}
+
+// RefreshAvailableDevices makes the next simulator resolution list the
+// available simulators again instead of reusing a listing from the last
+// few seconds. axe refreshes by itself when it creates or deletes a
+// simulator; call this after changes made by other tools.
+func RefreshAvailableDevices() {
+	availableDevices.invalidate()
+}
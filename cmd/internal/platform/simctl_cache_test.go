@@ -0,0 +1,87 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &listCache{ttl: 5 * time.Second, now: func() time.Time { return now }}
+	var calls atomic.Int32
+	list := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		return []byte("devices"), nil
+	}
+	get := func() {
+		t.Helper()
+		out, err := c.get(t.Context(), list)
+		if err != nil || string(out) != "devices" {
+			t.Fatalf("get = (%q, %v), want devices", out, err)
+		}
+	}
+
+	get()
+	now = now.Add(4 * time.Second)
+	get()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("listed %d times within the TTL, want 1", got)
+	}
+
+	now = now.Add(time.Second)
+	get()
+	if got := calls.Load(); got != 2 {
+		t.Errorf("listed %d times after the TTL, want 2", got)
+	}
+
+	c.invalidate()
+	get()
+	if got := calls.Load(); got != 3 {
+		t.Errorf("listed %d times after invalidate, want 3", got)
+	}
+}
+
+func TestListCache_ErrorsAreNotCached(t *testing.T) {
+	t.Parallel()
+
+	c := &listCache{ttl: time.Hour, now: time.Now}
+	errList := errors.New("simctl failed")
+	if _, err := c.get(t.Context(), func(context.Context) ([]byte, error) { return nil, errList }); !errors.Is(err, errList) {
+		t.Fatalf("err = %v, want %v", err, errList)
+	}
+	out, err := c.get(t.Context(), func(context.Context) ([]byte, error) { return []byte("devices"), nil })
+	if err != nil || string(out) != "devices" {
+		t.Errorf("get after a failure = (%q, %v), want a fresh listing", out, err)
+	}
+}
+
+func TestListCache_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	c := &listCache{ttl: time.Hour, now: time.Now}
+	var calls atomic.Int32
+	list := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return []byte("devices"), nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, err := c.get(t.Context(), list); err != nil {
+				t.Errorf("get: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("concurrent gets listed %d times, want 1", got)
+	}
+}
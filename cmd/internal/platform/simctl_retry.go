@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+// transientSimctlErrors are fragments of simctl output for failures that
+// CoreSimulator reports while it is busy and that usually succeed on retry.
+var transientSimctlErrors = []string{
+	// CoreSimulatorService refuses requests while it is (re)starting or
+	// handling another request for the same device.
+	"The request was denied by service delegate",
+	"CoreSimulatorService connection became invalid",
+	// The device is between states, e.g. still shutting down from a
+	// previous session. The settled states ("current state: Booted" for
+	// boot, "current state: Shutdown" for shutdown) are handled as success
+	// by the callers instead.
+	"in current state: Shutting Down",
+	"in current state: Booting",
+	"in current state: Creating",
+}
+
+// isTransientSimctlError reports whether simctl output describes a
+// transient CoreSimulator failure worth retrying. Anything else, such as
+// an invalid device type or runtime, is a real error and fails fast.
+func isTransientSimctlError(out string) bool {
+	for _, s := range transientSimctlErrors {
+		if strings.Contains(out, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// simctlRetryDelays are the waits before each retry of a transient simctl
+// failure, so a command runs at most len(simctlRetryDelays)+1 times.
+var simctlRetryDelays = []time.Duration{500 * time.Millisecond, 1 * time.Second, 2 * time.Second}
+
+// simctlWait waits for d or until ctx is done. Replaced in tests.
+var simctlWait = func(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// runSimctl runs "xcrun simctl args..." and returns its combined output,
+// retrying transient CoreSimulator failures with backoff.
+func runSimctl(ctx context.Context, args ...string) ([]byte, error) {
+	return retrySimctl(ctx, args, func() ([]byte, error) {
+		return procgroup.Command(ctx, "xcrun", append([]string{"simctl"}, args...)...).CombinedOutput()
+	})
+}
+
+// retrySimctl calls run until it succeeds, fails with a non-transient
+// error, runs out of retries, or ctx is done. It returns the output and
+// error of the last call.
+func retrySimctl(ctx context.Context, args []string, run func() ([]byte, error)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		out, err := run()
+		if err == nil || attempt == len(simctlRetryDelays) || !isTransientSimctlError(string(out)) {
+			return out, err
+		}
+		slog.Warn("Transient simctl failure, retrying",
+			"args", args,
+			"attempt", attempt+1,
+			"delay", simctlRetryDelays[attempt],
+			"output", strings.TrimSpace(string(out)),
+		)
+		if werr := simctlWait(ctx, simctlRetryDelays[attempt]); werr != nil {
+			return out, err
+		}
+	}
+}
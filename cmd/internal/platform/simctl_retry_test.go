@@ -0,0 +1,151 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsTransientSimctlError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		out  string
+		want bool
+	}{
+		{
+			"service delegate denied",
+			"An error was encountered processing the command (domain=com.apple.CoreSimulator.SimError, code=405):\nUnable to boot device because the request was denied by service delegate (SimServiceDelegate).\nThe request was denied by service delegate (SimServiceDelegate).",
+			true,
+		},
+		{
+			"connection invalidated",
+			"CoreSimulatorService connection became invalid.  Simulator services will no longer be available.",
+			true,
+		},
+		{
+			"boot while shutting down",
+			"An error was encountered processing the command (domain=com.apple.CoreSimulator.SimError, code=405):\nUnable to boot device in current state: Shutting Down",
+			true,
+		},
+		{
+			"shutdown while booting",
+			"An error was encountered processing the command (domain=com.apple.CoreSimulator.SimError, code=405):\nUnable to shutdown device in current state: Booting",
+			true,
+		},
+		{
+			"already booted is not retried",
+			"An error was encountered processing the command (domain=com.apple.CoreSimulator.SimError, code=405):\nUnable to boot device in current state: Booted",
+			false,
+		},
+		{
+			"invalid device type",
+			"Invalid device type: com.apple.CoreSimulator.SimDeviceType.iPhone-99",
+			false,
+		},
+		{
+			"invalid runtime",
+			"Invalid runtime: com.apple.CoreSimulator.SimRuntime.iOS-9-0",
+			false,
+		},
+		{
+			"invalid device",
+			"Invalid device: 00000000-0000-0000-0000-000000000000",
+			false,
+		},
+		{"empty output", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isTransientSimctlError(tt.out); got != tt.want {
+				t.Errorf("isTransientSimctlError(%q) = %v, want %v", tt.out, got, tt.want)
+			}
+		})
+	}
+}
+
+func withSimctlRetryNoDelay(t *testing.T) {
+	t.Helper()
+	origWait := simctlWait
+	simctlWait = func(ctx context.Context, _ time.Duration) error {
+		return ctx.Err()
+	}
+	t.Cleanup(func() {
+		simctlWait = origWait
+	})
+}
+
+func TestRetrySimctl_RetriesTransientFailures(t *testing.T) {
+	withSimctlRetryNoDelay(t)
+
+	calls := 0
+	out, err := retrySimctl(context.Background(), []string{"boot", "UDID"}, func() ([]byte, error) {
+		calls++
+		if calls < 3 {
+			return []byte("The request was denied by service delegate (SimServiceDelegate)."), errors.New("exit status 149")
+		}
+		return []byte("ok"), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != "ok" || calls != 3 {
+		t.Errorf("out = %q after %d calls, want \"ok\" after 3", out, calls)
+	}
+}
+
+func TestRetrySimctl_FailsFastOnRealErrors(t *testing.T) {
+	withSimctlRetryNoDelay(t)
+
+	calls := 0
+	_, err := retrySimctl(context.Background(), []string{"create"}, func() ([]byte, error) {
+		calls++
+		return []byte("Invalid device type: foo"), errors.New("exit status 148")
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetrySimctl_GivesUpAfterMaxRetries(t *testing.T) {
+	withSimctlRetryNoDelay(t)
+
+	calls := 0
+	out, err := retrySimctl(context.Background(), []string{"boot", "UDID"}, func() ([]byte, error) {
+		calls++
+		return []byte("Unable to boot device in current state: Shutting Down"), errors.New("exit status 149")
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if want := len(simctlRetryDelays) + 1; calls != want {
+		t.Errorf("calls = %d, want %d", calls, want)
+	}
+	if string(out) != "Unable to boot device in current state: Shutting Down" {
+		t.Errorf("out = %q, want the last attempt's output", out)
+	}
+}
+
+func TestRetrySimctl_StopsWhenContextDone(t *testing.T) {
+	withSimctlRetryNoDelay(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := retrySimctl(ctx, []string{"boot", "UDID"}, func() ([]byte, error) {
+		calls++
+		cancel()
+		return []byte("The request was denied by service delegate"), errors.New("exit status 149")
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
@@ -15,6 +15,9 @@ type SimctlRunner interface {
 	Create(ctx context.Context, name, deviceType, runtime, setPath string) (string, error)
 	Shutdown(ctx context.Context, udid, setPath string) error
 	Delete(ctx context.Context, udid, setPath string) error
+	// Erase resets a shut-down simulator to its factory state, removing
+	// installed apps and their data.
+	Erase(ctx context.Context, udid, setPath string) error
 	// Boot boots a simulator in the default (standard) device set.
 	// Returns nil if the device is already booted.
 	Boot(ctx context.Context, udid string) error
@@ -40,7 +43,8 @@ func (r *RealSimctlRunner) ListDevices(ctx context.Context, setPath string) ([]s
 }
 
 func (r *RealSimctlRunner) Clone(ctx context.Context, sourceUDID, name, setPath string) (string, error) {
-	out, err := procgroup.Command(ctx, "xcrun", "simctl", "--set", setPath, "clone", sourceUDID, name).CombinedOutput()
+	defer availableDevices.invalidate()
+	out, err := runSimctl(ctx, "--set", setPath, "clone", sourceUDID, name)
 	if err != nil {
 		return "", fmt.Errorf("simctl clone: %w\n%s", err, out)
 	}
@@ -48,9 +52,8 @@ func (r *RealSimctlRunner) Clone(ctx context.Context, sourceUDID, name, setPath
 }
 
 func (r *RealSimctlRunner) Create(ctx context.Context, name, deviceType, runtime, setPath string) (string, error) {
-	out, err := procgroup.Command(ctx, "xcrun", "simctl", "--set", setPath,
-		"create", name, deviceType, runtime,
-	).CombinedOutput()
+	defer availableDevices.invalidate()
+	out, err := runSimctl(ctx, "--set", setPath, "create", name, deviceType, runtime)
 	if err != nil {
 		return "", fmt.Errorf("simctl create: %w\n%s", err, out)
 	}
@@ -58,7 +61,7 @@ func (r *RealSimctlRunner) Create(ctx context.Context, name, deviceType, runtime
 }
 
 func (r *RealSimctlRunner) Shutdown(ctx context.Context, udid, setPath string) error {
-	out, err := procgroup.Command(ctx, "xcrun", "simctl", "--set", setPath, "shutdown", udid).CombinedOutput()
+	out, err := runSimctl(ctx, "--set", setPath, "shutdown", udid)
 	if err != nil {
 		// "Unable to shutdown device in current state: Shutdown" means the device
 		// is already shut down — treat as success.
@@ -71,15 +74,24 @@ func (r *RealSimctlRunner) Shutdown(ctx context.Context, udid, setPath string) e
 }
 
 func (r *RealSimctlRunner) Delete(ctx context.Context, udid, setPath string) error {
-	out, err := procgroup.Command(ctx, "xcrun", "simctl", "--set", setPath, "delete", udid).CombinedOutput()
+	defer availableDevices.invalidate()
+	out, err := runSimctl(ctx, "--set", setPath, "delete", udid)
 	if err != nil {
 		return fmt.Errorf("simctl delete: %w\n%s", err, out)
 	}
 	return nil
 }
 
+func (r *RealSimctlRunner) Erase(ctx context.Context, udid, setPath string) error {
+	out, err := runSimctl(ctx, "--set", setPath, "erase", udid)
+	if err != nil {
+		return fmt.Errorf("simctl erase: %w\n%s", err, out)
+	}
+	return nil
+}
+
 func (r *RealSimctlRunner) Boot(ctx context.Context, udid string) error {
-	out, err := procgroup.Command(ctx, "xcrun", "simctl", "boot", udid).CombinedOutput()
+	out, err := runSimctl(ctx, "boot", udid)
 	if err != nil {
 		// "Unable to boot device in current state: Booted" means it is
 		// already running — treat as success.
@@ -91,12 +103,18 @@ func (r *RealSimctlRunner) Boot(ctx context.Context, udid string) error {
 	return nil
 }
 
+// ListAllDevices lists every device, or with onlyAvailable the available
+// ones, which are cached for a few seconds (see availableDevices).
 func (r *RealSimctlRunner) ListAllDevices(ctx context.Context, onlyAvailable bool) ([]byte, error) {
-	args := []string{"simctl", "list", "devices"}
 	if onlyAvailable {
-		args = append(args, "available")
+		return availableDevices.get(ctx, func(ctx context.Context) ([]byte, error) {
+			return listAllDevices(ctx, "simctl", "list", "devices", "available", "--json")
+		})
 	}
-	args = append(args, "--json")
+	return listAllDevices(ctx, "simctl", "list", "devices", "--json")
+}
+
+func listAllDevices(ctx context.Context, args ...string) ([]byte, error) {
 	out, err := procgroup.Command(ctx, "xcrun", args...).Output()
 	if err != nil {
 		return nil, fmt.Errorf("simctl list devices: %w", err)
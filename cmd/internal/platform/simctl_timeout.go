@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Default timeouts of simctl invocations. Creating or cloning a simulator
+// copies its data and takes far longer than other commands on a busy
+// machine.
+const (
+	defaultSimctlTimeout       = 30 * time.Second
+	defaultSimctlCreateTimeout = 2 * time.Minute
+)
+
+// simctlTimeoutOverride, when positive, replaces both defaults. Stored as
+// a time.Duration.
+var simctlTimeoutOverride atomic.Int64
+
+// SetSimctlTimeout makes every simctl invocation time out after d, e.g.
+// from .axerc SIMCTL_TIMEOUT. Zero restores the defaults: 2 minutes for
+// creating and cloning simulators, 30 seconds for anything else.
+func SetSimctlTimeout(d time.Duration) {
+	simctlTimeoutOverride.Store(int64(d))
+}
+
+// ParseSimctlTimeout parses a SIMCTL_TIMEOUT value, a positive Go
+// duration such as "45s" or "2m".
+func ParseSimctlTimeout(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q (use a duration such as 45s or 2m)", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// simctlTimeout returns the timeout of a simctl invocation other than
+// create and clone.
+func simctlTimeout() time.Duration {
+	if d := time.Duration(simctlTimeoutOverride.Load()); d > 0 {
+		return d
+	}
+	return defaultSimctlTimeout
+}
+
+// simctlCreateTimeout returns the timeout of simctl create and clone.
+func simctlCreateTimeout() time.Duration {
+	if d := time.Duration(simctlTimeoutOverride.Load()); d > 0 {
+		return d
+	}
+	return defaultSimctlCreateTimeout
+}
+
+// simctlContext returns a context with simctlTimeout for simctl calls.
+func simctlContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), simctlTimeout())
+}
+
+// simctlCreateContext returns a context with simctlCreateTimeout for
+// simctl create and clone.
+func simctlCreateContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), simctlCreateTimeout())
+}
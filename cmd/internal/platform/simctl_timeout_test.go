@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// slowCreateSimctlRunner is a fakeSimctlRunner whose Create hangs until
+// its context is done, like simctl create on a busy machine.
+type slowCreateSimctlRunner struct {
+	*fakeSimctlRunner
+}
+
+func (r slowCreateSimctlRunner) Create(ctx context.Context, _, _, _, _ string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+// setSimctlTimeout overrides the simctl timeout for the rest of the test.
+// Tests using it must not be parallel.
+func setSimctlTimeout(t *testing.T, d time.Duration) {
+	t.Helper()
+	SetSimctlTimeout(d)
+	t.Cleanup(func() { SetSimctlTimeout(0) })
+}
+
+func TestSimctlTimeout_Defaults(t *testing.T) {
+	if got := simctlTimeout(); got != 30*time.Second {
+		t.Errorf("simctlTimeout() = %s, want 30s", got)
+	}
+	if got := simctlCreateTimeout(); got != 2*time.Minute {
+		t.Errorf("simctlCreateTimeout() = %s, want 2m", got)
+	}
+
+	setSimctlTimeout(t, 45*time.Second)
+	if got := simctlTimeout(); got != 45*time.Second {
+		t.Errorf("simctlTimeout() = %s after SetSimctlTimeout(45s)", got)
+	}
+	if got := simctlCreateTimeout(); got != 45*time.Second {
+		t.Errorf("simctlCreateTimeout() = %s after SetSimctlTimeout(45s)", got)
+	}
+}
+
+func TestSimctlTimeout_DeadlineSurfaces(t *testing.T) {
+	setSimctlTimeout(t, 10*time.Millisecond)
+	pool := NewDevicePool(slowCreateSimctlRunner{newFakeSimctlRunner()}, t.TempDir())
+
+	start := time.Now()
+	_, err := pool.Acquire(context.Background(), testDeviceType, testRuntime)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Acquire took %s with a 10ms simctl timeout", elapsed)
+	}
+}
+
+func TestParseSimctlTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"45s", 45 * time.Second, false},
+		{"2m", 2 * time.Minute, false},
+		{"30", 0, true},
+		{"0s", 0, true},
+		{"-1m", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSimctlTimeout(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSimctlTimeout(%q) = (%s, %v), want (%s, error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
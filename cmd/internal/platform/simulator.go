@@ -1,23 +1,24 @@
 package platform
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// simctlContext returns a context with a 30-second timeout for simctl calls.
-func simctlContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 30*time.Second)
-}
+// ErrNoSimulator is returned when no usable simulator can be found or created.
+var ErrNoSimulator = errors.New("no available simulator")
 
 type simDevice struct {
 	Name                 string `json:"name"`
@@ -36,8 +37,12 @@ func ResolveSimulator(flagValue string) string {
 	return "booted"
 }
 
-// AxeDeviceSetPath returns the path to axe's dedicated simulator device set.
+// AxeDeviceSetPath returns the path to axe's dedicated simulator device set:
+// $AXE_DEVICE_SET if set, otherwise ~/Library/Developer/axe/Simulator Devices.
 func AxeDeviceSetPath() (string, error) {
+	if p := os.Getenv("AXE_DEVICE_SET"); p != "" {
+		return filepath.Abs(p)
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("resolving home directory: %w", err)
@@ -45,15 +50,72 @@ func AxeDeviceSetPath() (string, error) {
 	return filepath.Join(home, "Library", "Developer", "axe", "Simulator Devices"), nil
 }
 
+// SimulatorSource tells how ResolveAxeSimulator chose a simulator.
+type SimulatorSource int
+
+const (
+	SourceSpecified SimulatorSource = iota // the preferred UDID (--device)
+	SourceDefault                          // the configured default simulator
+	SourceLastUsed                         // the simulator the project last ran on
+	SourceReused                           // another Shutdown device in the axe set
+	SourceCreated                          // created in the axe set by this call
+)
+
+func (s SimulatorSource) String() string {
+	switch s {
+	case SourceSpecified:
+		return "specified"
+	case SourceDefault:
+		return "default"
+	case SourceLastUsed:
+		return "last-used"
+	case SourceReused:
+		return "reused"
+	case SourceCreated:
+		return "created"
+	}
+	return fmt.Sprintf("SimulatorSource(%d)", int(s))
+}
+
+// ResolvedSimulator is the simulator picked by ResolveAxeSimulator.
+type ResolvedSimulator struct {
+	UDID          string
+	DeviceSetPath string // "" for a device in the standard Xcode set
+	IsExternal    bool   // the device belongs to the standard Xcode set
+	Source        SimulatorSource
+}
+
+// Created reports whether the simulator was created by this resolution,
+// so that the caller may delete it again when done with it.
+func (r ResolvedSimulator) Created() bool {
+	return r.Source == SourceCreated
+}
+
 // ResolveAxeSimulator finds or creates a simulator in the axe device set.
-// It returns the UDID, device set path, and whether the device is external
-// (belongs to the standard Xcode simulator set rather than the axe set).
+// It returns the UDID, device set path, whether the device is external
+// (belongs to the standard Xcode simulator set rather than the axe set),
+// and which of the priorities below picked it.
 //
 // Resolution priority:
 //  1. preferredUDID (from --device flag) — search axe set first, then standard set
 //  2. config.json defaultSimulator — Shutdown only; skip if Booted or absent
-//  3. First Shutdown device in the axe set
-//  4. Auto-create from the latest available iPhone
+//  3. The simulator last used for project (see RecordLastUsedSimulator) — Shutdown only;
+//     forgotten once the device no longer exists
+//  4. First Shutdown device in the axe set
+//  5. Auto-create a device chosen by the configured SelectionStrategy
+//     (default: the latest available iPhone)
+//
+// project is the absolute project or workspace path; empty skips priority 3.
+// When runtimeName (e.g. "iOS 18.2", from --runtime or .axerc RUNTIME) is set,
+// priorities 2-5 only consider devices on that runtime. Likewise, family
+// ("iPhone" or "iPad", from --family or .axerc FAMILY) restricts priorities
+// 2-5 to that device family, auto-creating the latest device of the family
+// unless the configured strategy already picks a model of it. deviceType
+// (e.g. "iPhone 15", from --device-type or .axerc DEVICE_TYPE) restricts
+// priorities 2-5 to that device type and overrides the strategy; it must be
+// creatable on runtimeName, if set, and belong to family, if set. An
+// explicit preferredUDID is used as-is regardless of its runtime, family
+// and device type.
 //
 // When a device is found in the standard set (isExternal=true), deviceSetPath is
 // returned as "" so that downstream simctl commands target the default set.
@@ -61,11 +123,12 @@ func AxeDeviceSetPath() (string, error) {
 // using them in other workflows (e.g. Xcode).
 //
 // NOTE: Race condition with concurrent processes
-// This function is not protected by a lock. When multiple axe preview processes
-// start simultaneously, the following races may occur:
+// Only the auto-create step holds the device set lock (see lockDeviceSet);
+// selection is not protected. When multiple axe preview processes start
+// simultaneously, the following races may occur:
 //   - Two processes both see a Shutdown device and select it before either boots it.
 //     The later boot will fail or the app will be overwritten on the same simulator.
-//   - Two processes both reach priority 4, finding no Shutdown devices, and each
+//   - Two processes both reach priority 5, finding no Shutdown devices, and each
 //     creates a new simulator. This results in duplicate devices but both work correctly.
 //
 // A proper fix would require either:
@@ -75,86 +138,160 @@ func AxeDeviceSetPath() (string, error) {
 //
 // Both add complexity and startup latency; the current behavior is acceptable for typical
 // usage since duplicate creation is harmless and same-device collision is unlikely in practice.
-func ResolveAxeSimulator(simctl SimctlRunner, preferredUDID string) (udid, deviceSetPath string, isExternal bool, err error) {
-	deviceSetPath, err = AxeDeviceSetPath()
+func ResolveAxeSimulator(simctl SimctlRunner, preferredUDID, runtimeName, family, deviceType, project string) (ResolvedSimulator, error) {
+	family, err := ParseDeviceFamily(family)
 	if err != nil {
-		return "", "", false, err
+		return ResolvedSimulator{}, err
 	}
-	if err := os.MkdirAll(deviceSetPath, 0o755); err != nil {
-		return "", "", false, fmt.Errorf("creating axe device set directory: %w", err)
+	deviceSetPath, err := PrepareAxeDeviceSet()
+	if err != nil {
+		return ResolvedSimulator{}, err
 	}
 
 	listCtx, listCancel := simctlContext()
 	defer listCancel()
 	devices, err := simctl.ListDevices(listCtx, deviceSetPath)
+	listed := err == nil
 	if err != nil {
 		slog.Debug("Failed to list devices in axe set, will clone", "err", err)
 	}
 
 	// Priority 1: explicit preferred UDID.
 	if preferredUDID != "" {
-		for _, d := range devices {
-			if d.UDID == preferredUDID {
-				slog.Info("Using specified simulator", "name", d.Name, "udid", d.UDID)
-				return d.UDID, deviceSetPath, false, nil
-			}
+		udid, setPath, isExternal, err := findSimulator(simctl, devices, deviceSetPath, preferredUDID)
+		if err != nil {
+			return ResolvedSimulator{}, err
 		}
-
-		// Fallback: search the standard Xcode simulator set.
-		stdCtx, stdCancel := simctlContext()
-		defer stdCancel()
-		stdJSON, listErr := simctl.ListAllDevices(stdCtx, true)
-		if listErr != nil {
-			slog.Warn("Failed to list standard Xcode simulator set", "err", listErr)
-		} else {
-			stdDevices, parseErr := parseDevicesJSON(stdJSON)
-			if parseErr != nil {
-				slog.Warn("Failed to parse standard Xcode simulator set", "err", parseErr)
-			} else {
-				for _, d := range stdDevices {
-					if d.UDID == preferredUDID {
-						slog.Info("Using simulator from standard Xcode set", "name", d.Name, "udid", d.UDID)
-						return d.UDID, "", true, nil
-					}
-				}
-			}
+		if !isExternal {
+			recordSimulatorUse(udid)
 		}
-
-		return "", "", false, fmt.Errorf("simulator %s not found in axe device set or standard Xcode simulator set. Run 'axe preview simulator list' or 'xcrun simctl list devices' to see available devices", preferredUDID)
+		return ResolvedSimulator{UDID: udid, DeviceSetPath: setPath, IsExternal: isExternal, Source: SourceSpecified}, nil
 	}
 
-	// Priority 2-3: pick a Shutdown simulator (config default preferred, then any).
-	var defaultUDID string
+	// Priority 2-4: pick a Shutdown simulator (config default preferred,
+	// then the project's last-used device, then any).
+	var defaultUDID, lastUsedUDID string
 	store, storeErr := NewConfigStore()
 	if storeErr == nil {
 		defaultUDID, _ = store.GetDefault()
+		// Only trust the device list for invalidation when simctl answered.
+		if listed {
+			lastUsedUDID = lastUsedSimulator(store, project, devices)
+		}
 	}
 
-	if selected, ok := selectAvailableSimulator(devices, defaultUDID); ok {
-		slog.Info("Using simulator", "udid", selected)
-		return selected, deviceSetPath, false, nil
+	runtimeID, err := ResolveRuntime(simctl, runtimeName)
+	if err != nil {
+		return ResolvedSimulator{}, err
+	}
+	if runtimeID != "" {
+		devices = filterDevicesByRuntime(devices, runtimeID)
+	}
+	if family != "" {
+		devices = filterDevicesByFamily(devices, family)
+	}
+	dt, err := resolveDeviceTypeInFamily(simctl, deviceType, runtimeID, family)
+	if err != nil {
+		return ResolvedSimulator{}, err
+	}
+	if dt.Identifier != "" {
+		devices = filterDevicesByDeviceType(devices, dt.Identifier)
 	}
 
-	// Priority 4: auto-create from the latest iPhone.
-	source, runtime, err := findLatestIPhone(simctl)
+	if selected, source, ok := selectAvailableSimulator(devices, defaultUDID, lastUsedUDID); ok {
+		slog.Info("Using simulator", "udid", selected, "source", source)
+		recordSimulatorUse(selected)
+		return ResolvedSimulator{UDID: selected, DeviceSetPath: deviceSetPath, Source: source}, nil
+	}
+
+	// Priority 5: auto-create the device the selection strategy picks.
+	strategy, err := configuredStrategy()
+	if err != nil {
+		return ResolvedSimulator{}, err
+	}
+	strategy = strategyForFamily(strategy, family)
+	if dt.Identifier != "" {
+		strategy = DeviceModel{Name: dt.Name}
+	}
+	source, runtime, err := selectDevice(simctl, strategy, runtimeID)
 	if err != nil {
-		return "", "", false, fmt.Errorf("finding latest iPhone: %w", err)
+		return ResolvedSimulator{}, fmt.Errorf("selecting a simulator to create: %w", err)
 	}
 
 	slog.Info("Creating simulator in axe device set", "source", source.Name, "deviceType", source.DeviceTypeIdentifier, "runtime", runtime)
-	createCtx, createCancel := simctlContext()
+	lock, err := lockDeviceSet(context.Background(), deviceSetPath)
+	if err != nil {
+		return ResolvedSimulator{}, err
+	}
+	defer lock.Unlock()
+	createCtx, createCancel := simctlCreateContext()
 	defer createCancel()
 	createdUDID, err := simctl.Create(createCtx, "axe "+source.Name+" (1)", source.DeviceTypeIdentifier, runtime, deviceSetPath)
 	if err != nil {
-		return "", "", false, fmt.Errorf("creating simulator: %w", err)
+		return ResolvedSimulator{}, fmt.Errorf("creating simulator: %w", err)
+	}
+	recordSimulatorUse(createdUDID)
+	return ResolvedSimulator{UDID: createdUDID, DeviceSetPath: deviceSetPath, Source: SourceCreated}, nil
+}
+
+// FindSimulator looks up the simulator udid in the axe device set, then in
+// the standard Xcode set, without creating or booting anything. For a device
+// in the standard set, isExternal is true and deviceSetPath is "". The error
+// wraps ErrNoSimulator when neither set contains udid.
+func FindSimulator(simctl SimctlRunner, udid string) (deviceSetPath string, isExternal bool, err error) {
+	deviceSetPath, err = AxeDeviceSetPath()
+	if err != nil {
+		return "", false, err
+	}
+	ctx, cancel := simctlContext()
+	defer cancel()
+	devices, err := simctl.ListDevices(ctx, deviceSetPath)
+	if err != nil {
+		slog.Debug("Failed to list devices in axe set", "err", err)
+	}
+	_, deviceSetPath, isExternal, err = findSimulator(simctl, devices, deviceSetPath, udid)
+	return deviceSetPath, isExternal, err
+}
+
+// findSimulator searches axeDevices (the contents of the axe set at
+// deviceSetPath) for udid, falling back to the standard Xcode set.
+func findSimulator(simctl SimctlRunner, axeDevices []simDevice, deviceSetPath, udid string) (string, string, bool, error) {
+	for _, d := range axeDevices {
+		if d.UDID == udid {
+			slog.Info("Using specified simulator", "name", d.Name, "udid", d.UDID)
+			return d.UDID, deviceSetPath, false, nil
+		}
+	}
+
+	// Fallback: search the standard Xcode simulator set.
+	stdCtx, stdCancel := simctlContext()
+	defer stdCancel()
+	stdJSON, listErr := simctl.ListAllDevices(stdCtx, true)
+	if listErr != nil {
+		slog.Warn("Failed to list standard Xcode simulator set", "err", listErr)
+	} else {
+		stdDevices, parseErr := parseDevicesJSON(stdJSON)
+		if parseErr != nil {
+			slog.Warn("Failed to parse standard Xcode simulator set", "err", parseErr)
+		} else {
+			for _, d := range stdDevices {
+				if d.UDID == udid {
+					slog.Info("Using simulator from standard Xcode set", "name", d.Name, "udid", d.UDID)
+					return d.UDID, "", true, nil
+				}
+			}
+		}
 	}
-	return createdUDID, deviceSetPath, false, nil
+
+	return "", "", false, fmt.Errorf("%w: simulator %s not found in axe device set or standard Xcode simulator set. Run 'axe preview simulator list' or 'xcrun simctl list devices' to see available devices", ErrNoSimulator, udid)
 }
 
 // selectAvailableSimulator picks a Shutdown simulator from devices.
-// defaultUDID is tried first; if it is Booted or absent, other Shutdown devices
-// are checked. Returns ("", false) if no Shutdown device is available.
-func selectAvailableSimulator(devices []simDevice, defaultUDID string) (string, bool) {
+// defaultUDID is tried first, then lastUsedUDID; if neither is Shutdown,
+// other Shutdown devices are checked. The returned source tells which of
+// these picked the device. Returns ("", 0, false) if no Shutdown device is
+// available.
+func selectAvailableSimulator(devices []simDevice, defaultUDID, lastUsedUDID string) (string, SimulatorSource, bool) {
 	// Prefer the configured default if it is Shutdown.
 	if defaultUDID != "" {
 		found := false
@@ -162,7 +299,7 @@ func selectAvailableSimulator(devices []simDevice, defaultUDID string) (string,
 			if d.UDID == defaultUDID {
 				found = true
 				if d.State == "Shutdown" {
-					return d.UDID, true
+					return d.UDID, SourceDefault, true
 				}
 				slog.Warn("Default simulator is in use, selecting another", "udid", defaultUDID, "state", d.State)
 				break
@@ -173,77 +310,320 @@ func selectAvailableSimulator(devices []simDevice, defaultUDID string) (string,
 		}
 	}
 
+	// Then the device this project ran on last time.
+	if lastUsedUDID != "" && lastUsedUDID != defaultUDID {
+		for _, d := range devices {
+			if d.UDID == lastUsedUDID {
+				if d.State == "Shutdown" {
+					return d.UDID, SourceLastUsed, true
+				}
+				slog.Debug("Last-used simulator is in use, selecting another", "udid", lastUsedUDID, "state", d.State)
+				break
+			}
+		}
+	}
+
 	// Fall back to the first Shutdown device.
 	for _, d := range devices {
 		if d.State == "Shutdown" {
-			return d.UDID, true
+			return d.UDID, SourceReused, true
 		}
 	}
-	return "", false
+	return "", 0, false
 }
 
-// FindDefaultDeviceSpec returns the device type and runtime identifiers
-// for the latest available iPhone. Used by DevicePool.Acquire in report mode.
-func FindDefaultDeviceSpec(simctl SimctlRunner) (deviceType, runtime string, err error) {
-	dev, rt, err := findLatestIPhone(simctl)
+// lastUsedSimulator returns the UDID recorded for project, forgetting the
+// record when the device no longer exists in devices (the axe device set).
+func lastUsedSimulator(store *ConfigStore, project string, devices []simDevice) string {
+	if project == "" {
+		return ""
+	}
+	udid, err := store.GetLastUsed(project)
+	if err != nil || udid == "" {
+		return ""
+	}
+	for _, d := range devices {
+		if d.UDID == udid {
+			return udid
+		}
+	}
+	slog.Info("Last-used simulator no longer exists, forgetting it", "udid", udid, "project", project)
+	if err := store.ClearLastUsed(project); err != nil {
+		slog.Debug("Failed to clear last-used simulator", "project", project, "err", err)
+	}
+	return ""
+}
+
+// RecordLastUsedSimulator remembers udid as the simulator project last ran on
+// so that ResolveAxeSimulator prefers it next time. Failures are only logged:
+// the record is a convenience, not something a run should fail over.
+func RecordLastUsedSimulator(project, udid string) {
+	if project == "" || udid == "" {
+		return
+	}
+	store, err := NewConfigStore()
+	if err == nil {
+		err = store.SetLastUsed(project, udid)
+	}
 	if err != nil {
-		return "", "", err
+		slog.Debug("Failed to record last-used simulator", "project", project, "udid", udid, "err", err)
 	}
-	return dev.DeviceTypeIdentifier, rt, nil
 }
 
-// findLatestIPhone selects the latest available iPhone from the default device set
-// without booting it. The selection prefers the highest iOS version and, among
-// devices on the same version, the lexicographically largest name.
-// Returns the device and its runtime key (e.g. "com.apple.CoreSimulator.SimRuntime.iOS-18-2").
-func findLatestIPhone(simctl SimctlRunner) (simDevice, string, error) {
+// recordSimulatorUse timestamps udid, a device of the axe set, so that
+// Clean keeps it. Failures are only logged, as in RecordLastUsedSimulator.
+func recordSimulatorUse(udid string) {
+	store, err := NewConfigStore()
+	if err == nil {
+		err = store.TouchSimulator(udid, time.Now())
+	}
+	if err != nil {
+		slog.Debug("Failed to record simulator use", "udid", udid, "err", err)
+	}
+}
+
+// LoadDeviceProfile returns the overrides persisted for the simulator udid
+// (see ConfigStore.SetDeviceProfile), or the zero value if it has none.
+// Failures are only logged, like RecordLastUsedSimulator's.
+func LoadDeviceProfile(udid string) AccessibilityOverrides {
+	store, err := NewConfigStore()
+	if err != nil {
+		slog.Debug("Failed to load device profile", "udid", udid, "err", err)
+		return AccessibilityOverrides{}
+	}
+	profile, err := store.GetDeviceProfile(udid)
+	if err != nil {
+		slog.Debug("Failed to load device profile", "udid", udid, "err", err)
+	}
+	return profile
+}
+
+// ResolveRuntime maps a runtime name such as "iOS 18.2" to the identifier of
+// an installed runtime (e.g. "com.apple.CoreSimulator.SimRuntime.iOS-18-2").
+// Names match case-insensitively; a full identifier is accepted as well.
+// An empty name resolves to "" (no constraint). If the runtime is not
+// installed, the error lists the runtimes that are.
+func ResolveRuntime(simctl SimctlRunner, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
 	ctx, cancel := simctlContext()
 	defer cancel()
 
-	out, err := simctl.ListAllDevices(ctx, true)
+	out, err := simctl.ListRuntimes(ctx)
+	if err != nil {
+		return "", fmt.Errorf("listing runtimes: %w", err)
+	}
+	return matchRuntime(out, name)
+}
+
+// matchRuntime looks up name in simctl "list runtimes --json" output.
+func matchRuntime(runtimesJSON []byte, name string) (string, error) {
+	runtimes, err := parseRuntimes(runtimesJSON)
 	if err != nil {
-		return simDevice{}, "", fmt.Errorf("listing available devices: %w", err)
+		return "", err
+	}
+	names := make([]string, 0, len(runtimes))
+	for _, rt := range runtimes {
+		if strings.EqualFold(rt.Name, name) || rt.Identifier == name {
+			return rt.Identifier, nil
+		}
+		names = append(names, rt.Name)
 	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("%w: runtime %q is not installed and no simulator runtimes are available. Install one from Xcode > Settings > Platforms", ErrNoSimulator, name)
+	}
+	return "", fmt.Errorf("%w: runtime %q is not installed (available: %s)", ErrNoSimulator, name, strings.Join(names, ", "))
+}
 
-	return selectLatestIPhone(out)
+// ResolveDeviceType looks up name (e.g. "iPhone 15") among the device types
+// simctl can create, restricted to runtimeID when it is non-empty. Names
+// match case-insensitively; a full identifier is accepted as well. An empty
+// name resolves to a zero AvailableDeviceType (no constraint). If nothing
+// matches, the error lists close matches.
+func ResolveDeviceType(simctl SimctlRunner, name, runtimeID string) (AvailableDeviceType, error) {
+	if name == "" {
+		return AvailableDeviceType{}, nil
+	}
+	available, err := ListAvailable(simctl)
+	if err != nil {
+		return AvailableDeviceType{}, fmt.Errorf("listing device types: %w", err)
+	}
+	return matchDeviceType(available, name, runtimeID)
 }
 
-// selectLatestIPhone parses simctl JSON output and selects the best iPhone device.
-// Exported for testing.
-func selectLatestIPhone(jsonData []byte) (simDevice, string, error) {
-	var result struct {
-		Devices map[string][]simDevice `json:"devices"`
+// resolveDeviceTypeInFamily is ResolveDeviceType that also rejects a device
+// type outside family, when one is requested.
+func resolveDeviceTypeInFamily(simctl SimctlRunner, name, runtimeID, family string) (AvailableDeviceType, error) {
+	dt, err := ResolveDeviceType(simctl, name, runtimeID)
+	if err != nil {
+		return AvailableDeviceType{}, err
 	}
-	if err := json.Unmarshal(jsonData, &result); err != nil {
-		return simDevice{}, "", fmt.Errorf("parsing simctl output: %w", err)
+	if dt.Identifier != "" && family != "" && !inFamily(dt.Name, family) {
+		return AvailableDeviceType{}, fmt.Errorf("device type %q is not an %s", dt.Name, family)
 	}
+	return dt, nil
+}
 
-	var best simDevice
-	var bestRuntime string
-	var bestVersion [2]int
-	for runtime, devices := range result.Devices {
-		major, minor := parseIOSVersion(runtime)
-		if major < 0 {
+// matchDeviceType looks up name in available. With a non-empty runtimeID,
+// the returned device type lists only that runtime.
+func matchDeviceType(available []AvailableDeviceType, name, runtimeID string) (AvailableDeviceType, error) {
+	idx := slices.IndexFunc(available, func(dt AvailableDeviceType) bool {
+		return strings.EqualFold(dt.Name, name) || dt.Identifier == name
+	})
+	if runtimeID == "" {
+		if idx < 0 {
+			return AvailableDeviceType{}, fmt.Errorf("%w: device type %q is not available%s", ErrNoSimulator, name, closeMatchesHint(name, available))
+		}
+		return available[idx], nil
+	}
+
+	onRuntime := filterAvailableByRuntime(available, runtimeID)
+	if idx >= 0 {
+		if filtered := filterAvailableByRuntime(available[idx:idx+1], runtimeID); len(filtered) == 1 {
+			return filtered[0], nil
+		}
+		runtimes := make([]string, len(available[idx].Runtimes))
+		for i, rt := range available[idx].Runtimes {
+			runtimes[i] = rt.Name
+		}
+		return AvailableDeviceType{}, fmt.Errorf("%w: device type %q is not available on %s (it is on %s)%s",
+			ErrNoSimulator, available[idx].Name, humanReadableRuntime(runtimeID), strings.Join(runtimes, ", "), closeMatchesHint(name, onRuntime))
+	}
+	return AvailableDeviceType{}, fmt.Errorf("%w: device type %q is not available on %s%s",
+		ErrNoSimulator, name, humanReadableRuntime(runtimeID), closeMatchesHint(name, onRuntime))
+}
+
+// maxCloseMatches caps the device types suggested by closeMatchesHint.
+const maxCloseMatches = 5
+
+// closeMatchesHint returns a "; close matches: ..." suffix naming the
+// device types in available whose names are close to name, or "" if there
+// are none. A name is close if either contains the other or they are a few
+// edits apart, ignoring case.
+func closeMatchesHint(name string, available []AvailableDeviceType) string {
+	want := strings.ToLower(name)
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	for _, dt := range available {
+		got := strings.ToLower(dt.Name)
+		if got == want {
 			continue
 		}
-		for _, d := range devices {
-			if !strings.Contains(d.Name, "iPhone") {
-				continue
-			}
-			v := [2]int{major, minor}
-			if v[0] > bestVersion[0] || (v[0] == bestVersion[0] && v[1] > bestVersion[1]) ||
-				(v == bestVersion && d.Name > best.Name) {
-				best = d
-				bestRuntime = runtime
-				bestVersion = v
+		dist := editDistance(want, got)
+		if strings.Contains(got, want) || strings.Contains(want, got) || dist <= max(2, len(want)/4) {
+			matches = append(matches, match{dt.Name, dist})
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return cmp.Or(cmp.Compare(a.dist, b.dist), cmp.Compare(a.name, b.name))
+	})
+	names := make([]string, 0, maxCloseMatches)
+	for _, m := range matches[:min(len(matches), maxCloseMatches)] {
+		names = append(names, m.name)
+	}
+	return "; close matches: " + strings.Join(names, ", ")
+}
+
+// editDistance returns the Levenshtein distance between a and b, by byte.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
 			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// filterDevicesByRuntime returns the devices whose runtime is runtimeID.
+func filterDevicesByRuntime(devices []simDevice, runtimeID string) []simDevice {
+	var filtered []simDevice
+	for _, d := range devices {
+		if d.RuntimeID == runtimeID {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// filterDevicesByFamily returns the devices of family.
+func filterDevicesByFamily(devices []simDevice, family string) []simDevice {
+	var filtered []simDevice
+	for _, d := range devices {
+		if inFamily(d.Name, family) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// filterDevicesByDeviceType returns the devices of the device type
+// identifier.
+func filterDevicesByDeviceType(devices []simDevice, identifier string) []simDevice {
+	var filtered []simDevice
+	for _, d := range devices {
+		if d.DeviceTypeIdentifier == identifier {
+			filtered = append(filtered, d)
 		}
 	}
+	return filtered
+}
 
-	if best.UDID == "" {
-		return simDevice{}, "", fmt.Errorf("no available iPhone simulator found")
+// FindDefaultDeviceSpec returns the device type and runtime identifiers
+// chosen by the configured SelectionStrategy (default: the latest available
+// iPhone), restricted to family and typeName as in ResolveAxeSimulator.
+// Used by DevicePool.Acquire in report mode.
+// When runtimeName is set, only devices on that runtime are considered.
+func FindDefaultDeviceSpec(simctl SimctlRunner, runtimeName, family, typeName string) (deviceType, runtime string, err error) {
+	family, err = ParseDeviceFamily(family)
+	if err != nil {
+		return "", "", err
+	}
+	runtimeID, err := ResolveRuntime(simctl, runtimeName)
+	if err != nil {
+		return "", "", err
 	}
-	return best, bestRuntime, nil
+	dt, err := resolveDeviceTypeInFamily(simctl, typeName, runtimeID, family)
+	if err != nil {
+		return "", "", err
+	}
+	strategy, err := configuredStrategy()
+	if err != nil {
+		return "", "", err
+	}
+	strategy = strategyForFamily(strategy, family)
+	if dt.Identifier != "" {
+		strategy = DeviceModel{Name: dt.Name}
+	}
+	dev, rt, err := selectDevice(simctl, strategy, runtimeID)
+	if err != nil {
+		return "", "", err
+	}
+	return dev.DeviceTypeIdentifier, rt, nil
+}
+
+// selectLatestDevice parses simctl JSON output and selects the best device
+// of family ("" for iPhone) with the LatestDevice strategy.
+// A non-empty runtimeID restricts the selection to that runtime.
+func selectLatestDevice(jsonData []byte, family, runtimeID string) (simDevice, string, error) {
+	return selectFromJSON(jsonData, nil, LatestDevice{Family: family}, runtimeID)
 }
 
 // parseDevicesJSON parses simctl "list devices --json" output into a flat slice of simDevice.
@@ -284,3 +664,84 @@ func parseIOSVersion(runtime string) (major, minor int) {
 	minor, _ = strconv.Atoi(m[2])
 	return major, minor
 }
+
+// DefaultBootTimeout bounds WaitForBooted when no timeout is configured.
+const DefaultBootTimeout = 60 * time.Second
+
+// bootPollInterval is how often WaitForBooted re-queries simctl.
+// Overridden in tests.
+var bootPollInterval = 500 * time.Millisecond
+
+// WaitForBooted polls simctl until the device reports the "Booted" state.
+// `simctl boot` can return while a reused device is still "Booting", and
+// launching or injecting into it at that point fails.
+// deviceSetPath selects the device set; empty means the standard Xcode set.
+// A non-positive timeout uses DefaultBootTimeout.
+func WaitForBooted(ctx context.Context, simctl SimctlRunner, udid, deviceSetPath string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultBootTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(bootPollInterval)
+	defer ticker.Stop()
+
+	var state string
+	for {
+		s, err := deviceState(ctx, simctl, udid, deviceSetPath)
+		switch {
+		case err != nil:
+			// simctl occasionally fails while CoreSimulator is busy booting;
+			// keep polling until the deadline.
+			slog.Debug("Polling simulator state failed", "udid", udid, "err", err)
+		case s == "":
+			return fmt.Errorf("simulator %s not found", udid)
+		case s == "Booted":
+			return nil
+		default:
+			if s != state {
+				slog.Debug("Waiting for simulator to boot", "udid", udid, "state", s)
+			}
+			state = s
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out after %s waiting for simulator %s to boot (last state %q)", timeout, udid, state)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// deviceState returns the simctl state of udid, or "" if the device is not
+// in the set.
+func deviceState(ctx context.Context, simctl SimctlRunner, udid, deviceSetPath string) (string, error) {
+	var devices []simDevice
+	if deviceSetPath != "" {
+		ds, err := simctl.ListDevices(ctx, deviceSetPath)
+		if err != nil {
+			return "", err
+		}
+		devices = ds
+	} else {
+		out, err := simctl.ListAllDevices(ctx, false)
+		if err != nil {
+			return "", err
+		}
+		ds, err := parseDevicesJSON(out)
+		if err != nil {
+			return "", err
+		}
+		devices = ds
+	}
+	for _, d := range devices {
+		if d.UDID == udid {
+			return d.State, nil
+		}
+	}
+	return "", nil
+}
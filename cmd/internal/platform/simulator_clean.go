@@ -0,0 +1,120 @@
+package platform
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CleanOptions configures Clean.
+type CleanOptions struct {
+	// OlderThan removes simulators last used longer ago than this.
+	OlderThan time.Duration
+	// DryRun reports what would be shut down and removed without doing it.
+	DryRun bool
+	// Now is the reference time; zero means time.Now().
+	Now time.Time
+}
+
+// CleanResult is what Clean shut down and removed, or would have with
+// DryRun.
+type CleanResult struct {
+	ShutDown []string           `json:"shutDown"` // UDIDs of booted simulators
+	Removed  []RemovedSimulator `json:"removed"`
+}
+
+// Clean reclaims disk space from the axe device set. It shuts down every
+// booted simulator in the set, then removes those last used before
+// opts.OlderThan. The default simulator is never removed.
+//
+// A simulator's last use is the later of when ResolveAxeSimulator last
+// picked it and when simctl last wrote its device.plist, which also covers
+// devices created by report mode or before usage was recorded. Simulators
+// that fail to shut down or delete are reported in the error; the others
+// are cleaned anyway.
+func Clean(simctl SimctlRunner, store *ConfigStore, opts CleanOptions) (CleanResult, error) {
+	now := cmp.Or(opts.Now, time.Now())
+	deviceSetPath, err := AxeDeviceSetPath()
+	if err != nil {
+		return CleanResult{}, err
+	}
+	lock, err := lockDeviceSet(context.Background(), deviceSetPath)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	defer lock.Unlock()
+
+	listCtx, listCancel := simctlContext()
+	defer listCancel()
+	devices, err := simctl.ListDevices(listCtx, deviceSetPath)
+	if err != nil {
+		return CleanResult{}, fmt.Errorf("listing devices: %w", err)
+	}
+	usedAt, err := store.SimulatorsUsedAt()
+	if err != nil {
+		slog.Debug("Failed to read simulator usage, using device files only", "err", err)
+	}
+	defaultUDID, _ := store.GetDefault()
+
+	var result CleanResult
+	var errs []error
+	for _, d := range devices {
+		// Measured before shutting down, which touches device.plist, so that
+		// a dry run predicts the real one.
+		last := lastUse(d, usedAt, deviceSetPath)
+		if d.State == "Booted" {
+			if !opts.DryRun {
+				if err := shutdownDevice(simctl, d.UDID, deviceSetPath); err != nil {
+					errs = append(errs, fmt.Errorf("shutting down %s (%s): %w", d.UDID, d.Name, err))
+					continue
+				}
+			}
+			d.State = "Shutdown"
+			result.ShutDown = append(result.ShutDown, d.UDID)
+		}
+
+		if d.UDID == defaultUDID || last.IsZero() || now.Sub(last) < opts.OlderThan {
+			continue
+		}
+		if opts.DryRun {
+			result.Removed = append(result.Removed, RemovedSimulator{
+				UDID:     d.UDID,
+				Name:     d.Name,
+				Freed:    dirSize(filepath.Join(deviceSetPath, d.UDID)),
+				LastUsed: last,
+			})
+			continue
+		}
+		removed, err := removeDevice(simctl, d, deviceSetPath, store)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed.LastUsed = last
+		result.Removed = append(result.Removed, removed)
+	}
+	return result, errors.Join(errs...)
+}
+
+// shutdownDevice shuts down udid in the set at deviceSetPath.
+func shutdownDevice(simctl SimctlRunner, udid, deviceSetPath string) error {
+	ctx, cancel := simctlContext()
+	defer cancel()
+	return simctl.Shutdown(ctx, udid, deviceSetPath)
+}
+
+// lastUse returns when d was last used: the later of its usedAt record and
+// the modification time of its device.plist. Zero if neither is known, in
+// which case Clean keeps the device.
+func lastUse(d simDevice, usedAt map[string]time.Time, deviceSetPath string) time.Time {
+	last := usedAt[d.UDID]
+	if info, err := os.Stat(filepath.Join(deviceSetPath, d.UDID, "device.plist")); err == nil && info.ModTime().After(last) {
+		last = info.ModTime()
+	}
+	return last
+}
@@ -0,0 +1,89 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeDevicePlist creates <set>/<udid>/device.plist, last modified at mtime.
+func writeDevicePlist(t *testing.T, setPath, udid string, mtime time.Time) {
+	t.Helper()
+	dir := filepath.Join(setPath, udid)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plist := filepath.Join(dir, "device.plist")
+	if err := os.WriteFile(plist, make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(plist, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-30 * 24 * time.Hour)
+	const week = 7 * 24 * time.Hour
+
+	newRunner := func() *managerFakeSimctlRunner {
+		return &managerFakeSimctlRunner{devices: []simDevice{
+			{Name: "axe iPhone 16 (1)", UDID: "STALE", State: "Shutdown"},
+			{Name: "axe iPhone 16 (2)", UDID: "RECENT", State: "Shutdown"},  // resolved yesterday
+			{Name: "axe iPhone 16 (3)", UDID: "BOOTED", State: "Booted"},    // stale, but running
+			{Name: "axe iPhone 16 (4)", UDID: "DEFAULT", State: "Shutdown"}, // stale, but the default
+			{Name: "axe iPhone 16 (5)", UDID: "UNKNOWN", State: "Shutdown"}, // no record at all
+		}}
+	}
+	setUp := func(t *testing.T) *ConfigStore {
+		setPath := t.TempDir()
+		t.Setenv("AXE_DEVICE_SET", setPath)
+		for _, udid := range []string{"STALE", "RECENT", "BOOTED", "DEFAULT"} {
+			writeDevicePlist(t, setPath, udid, old)
+		}
+		store := NewConfigStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+		_ = store.SetDefault("DEFAULT")
+		_ = store.TouchSimulator("RECENT", now.Add(-24*time.Hour))
+		return store
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		store := setUp(t)
+		runner := newRunner()
+		got, err := Clean(runner, store, CleanOptions{OlderThan: week, DryRun: true, Now: now})
+		if err != nil {
+			t.Fatalf("Clean: %v", err)
+		}
+		if len(runner.calls) != 0 {
+			t.Errorf("dry run called simctl: %v", runner.calls)
+		}
+		if !slices.Equal(got.ShutDown, []string{"BOOTED"}) {
+			t.Errorf("ShutDown = %v, want [BOOTED]", got.ShutDown)
+		}
+		if len(got.Removed) != 2 || got.Removed[0].UDID != "STALE" || got.Removed[1].UDID != "BOOTED" {
+			t.Fatalf("Removed = %+v, want STALE and BOOTED", got.Removed)
+		}
+		if got.Removed[0].Freed != 100 || !got.Removed[0].LastUsed.Equal(old) {
+			t.Errorf("Removed[0] = %+v, want 100 bytes last used %v", got.Removed[0], old)
+		}
+	})
+
+	t.Run("removes", func(t *testing.T) {
+		store := setUp(t)
+		runner := newRunner()
+		got, err := Clean(runner, store, CleanOptions{OlderThan: week, Now: now})
+		if err != nil {
+			t.Fatalf("Clean: %v", err)
+		}
+		want := []string{"delete STALE", "shutdown BOOTED", "delete BOOTED"}
+		if !slices.Equal(runner.calls, want) {
+			t.Errorf("calls = %v, want %v", runner.calls, want)
+		}
+		if len(got.Removed) != 2 {
+			t.Errorf("Removed = %+v, want 2", got.Removed)
+		}
+	})
+}
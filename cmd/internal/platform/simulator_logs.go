@@ -0,0 +1,208 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxTailBytes bounds how much of the end of each log file is read for
+// the initial tail, so that a multi-gigabyte log does not stall the output.
+const maxTailBytes = 1 << 20
+
+// logPollInterval is how often followed log files are checked for growth.
+const logPollInterval = 500 * time.Millisecond
+
+// CoreSimulatorLogsDir returns the directory CoreSimulator writes its
+// service and per-device logs to (~/Library/Logs/CoreSimulator).
+func CoreSimulatorLogsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolving home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "Logs", "CoreSimulator"), nil
+}
+
+// SimulatorLogPaths returns the log files relevant to a simulator:
+// CoreSimulator's service log, followed by the files CoreSimulator keeps
+// for the device udid (e.g. system.log) under logsDir/<udid>. With an empty
+// udid only the service log is returned. A udid must name a device in the
+// device set at deviceSetPath. Files that do not exist are skipped; an
+// error is returned when none exists.
+func SimulatorLogPaths(logsDir, deviceSetPath, udid string) ([]string, error) {
+	var paths []string
+	if service := filepath.Join(logsDir, "CoreSimulator.log"); fileExists(service) {
+		paths = append(paths, service)
+	}
+	if udid != "" {
+		if !fileExists(filepath.Join(deviceSetPath, udid, "device.plist")) {
+			return nil, fmt.Errorf("simulator %s not found in device set %s", udid, deviceSetPath)
+		}
+		deviceLogs, err := filepath.Glob(filepath.Join(logsDir, udid, "*.log"))
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(deviceLogs)
+		paths = append(paths, deviceLogs...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no CoreSimulator logs found in %s", logsDir)
+	}
+	return paths, nil
+}
+
+// fileExists reports whether path exists and is a regular file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// TailOptions controls TailLogs.
+type TailOptions struct {
+	Lines  int            // lines printed per file initially (0 = none)
+	Grep   *regexp.Regexp // only print matching lines; nil prints all
+	Follow bool           // keep printing lines appended until ctx is done
+}
+
+// TailLogs prints the last opts.Lines matching lines of each file to w,
+// under a "==> path <==" header when there are several files, and with
+// opts.Follow keeps printing lines as they are appended. A file that is
+// truncated or rotated while followed is read again from the start.
+func TailLogs(ctx context.Context, w io.Writer, paths []string, opts TailOptions) error {
+	multi := len(paths) > 1
+	offsets := make([]int64, len(paths))
+	for i, p := range paths {
+		lines, size, err := lastLines(p, opts.Lines, opts.Grep)
+		if err != nil {
+			return err
+		}
+		offsets[i] = size
+		if multi {
+			_, _ = fmt.Fprintf(w, "==> %s <==\n", p)
+		}
+		for _, l := range lines {
+			_, _ = fmt.Fprintln(w, l)
+		}
+	}
+	if !opts.Follow {
+		return nil
+	}
+
+	partial := make([][]byte, len(paths))
+	last := len(paths) - 1 // file of the last printed header
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logPollInterval):
+		}
+		for i, p := range paths {
+			data, next, truncated, err := readFrom(p, offsets[i])
+			if err != nil {
+				continue // rotated away; picked up again once recreated
+			}
+			if truncated {
+				partial[i] = nil
+			}
+			offsets[i] = next
+			data = append(partial[i], data...)
+			end := bytes.LastIndexByte(data, '\n')
+			if end < 0 {
+				partial[i] = data
+				continue
+			}
+			partial[i] = slices.Clone(data[end+1:])
+			for l := range strings.SplitSeq(string(data[:end]), "\n") {
+				if opts.Grep != nil && !opts.Grep.MatchString(l) {
+					continue
+				}
+				if multi && last != i {
+					_, _ = fmt.Fprintf(w, "\n==> %s <==\n", p)
+					last = i
+				}
+				_, _ = fmt.Fprintln(w, l)
+			}
+		}
+	}
+}
+
+// lastLines returns the last n lines of the file at path matching grep,
+// reading at most maxTailBytes from its end, together with the file size.
+func lastLines(path string, n int, grep *regexp.Regexp) ([]string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	if n <= 0 {
+		return nil, size, nil
+	}
+	start := max(0, size-maxTailBytes)
+	buf := make([]byte, size-start)
+	if _, err := f.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	text := strings.TrimSuffix(string(buf), "\n")
+	if start > 0 {
+		// Drop the line cut in half by the start offset.
+		if _, rest, ok := strings.Cut(text, "\n"); ok {
+			text = rest
+		}
+	}
+	var lines []string
+	if text != "" {
+		for l := range strings.SplitSeq(text, "\n") {
+			if grep == nil || grep.MatchString(l) {
+				lines = append(lines, l)
+			}
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, size, nil
+}
+
+// readFrom returns the bytes appended to the file at path since offset and
+// the new end offset. When the file shrank (truncated or replaced), it is
+// read from the start and truncated is true. At most maxTailBytes are
+// returned; older appended bytes are skipped, also reported as truncated.
+func readFrom(path string, offset int64) (data []byte, next int64, truncated bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, offset, false, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, false, err
+	}
+	size := info.Size()
+	if size < offset {
+		offset, truncated = 0, true
+	}
+	if size-offset > maxTailBytes {
+		offset, truncated = size-maxTailBytes, true
+	}
+	if size == offset {
+		return nil, size, truncated, nil
+	}
+	buf := make([]byte, size-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, offset, truncated, err
+	}
+	return buf, size, truncated, nil
+}
@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLogUDID = "0A1B2C3D-0000-4000-8000-000000000001"
+
+// setupSimulatorLogs creates a device set holding testLogUDID and a
+// CoreSimulator logs dir with the given files (relative path -> content).
+func setupSimulatorLogs(t *testing.T, files map[string]string) (logsDir, deviceSet string) {
+	t.Helper()
+	root := t.TempDir()
+	logsDir = filepath.Join(root, "Logs", "CoreSimulator")
+	deviceSet = filepath.Join(root, "Simulator Devices")
+	writeTestFile(t, filepath.Join(deviceSet, testLogUDID, "device.plist"), "")
+	if err := os.MkdirAll(logsDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(logsDir, name), content)
+	}
+	return logsDir, deviceSet
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimulatorLogPaths(t *testing.T) {
+	logsDir, deviceSet := setupSimulatorLogs(t, map[string]string{
+		"CoreSimulator.log":                          "service\n",
+		testLogUDID + "/system.log":                  "device\n",
+		testLogUDID + "/launchd_bootstrap.log":       "boot\n",
+		testLogUDID + "/notes.txt":                   "not a log\n",
+		"FFFFFFFF-0000-4000-8000-000000000002/x.log": "other device\n",
+	})
+
+	t.Run("device", func(t *testing.T) {
+		got, err := SimulatorLogPaths(logsDir, deviceSet, testLogUDID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			filepath.Join(logsDir, "CoreSimulator.log"),
+			filepath.Join(logsDir, testLogUDID, "launchd_bootstrap.log"),
+			filepath.Join(logsDir, testLogUDID, "system.log"),
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("paths = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("no udid", func(t *testing.T) {
+		got, err := SimulatorLogPaths(logsDir, deviceSet, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{filepath.Join(logsDir, "CoreSimulator.log")}; !slices.Equal(got, want) {
+			t.Errorf("paths = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("device outside the set", func(t *testing.T) {
+		_, err := SimulatorLogPaths(logsDir, deviceSet, "FFFFFFFF-0000-4000-8000-000000000002")
+		if err == nil || !strings.Contains(err.Error(), "not found in device set") {
+			t.Errorf("error = %v, want not found in device set", err)
+		}
+	})
+}
+
+func TestSimulatorLogPaths_NoLogs(t *testing.T) {
+	logsDir, deviceSet := setupSimulatorLogs(t, nil)
+	if _, err := SimulatorLogPaths(logsDir, deviceSet, testLogUDID); err == nil {
+		t.Error("expected an error when no log exists")
+	}
+}
+
+func TestTailLogs(t *testing.T) {
+	logsDir, _ := setupSimulatorLogs(t, map[string]string{
+		"a.log": "boot ok\nerror: GPU hang\nidle\nerror: disk full\n",
+		"b.log": "error: only one\n",
+	})
+	paths := []string{filepath.Join(logsDir, "a.log"), filepath.Join(logsDir, "b.log")}
+
+	var out bytes.Buffer
+	err := TailLogs(context.Background(), &out, paths, TailOptions{Lines: 1, Grep: regexp.MustCompile("^error")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "==> " + paths[0] + " <==\nerror: disk full\n==> " + paths[1] + " <==\nerror: only one\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestTailLogs_Follow(t *testing.T) {
+	logsDir, _ := setupSimulatorLogs(t, map[string]string{"system.log": "old\n"})
+	path := filepath.Join(logsDir, "system.log")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*logPollInterval)
+	defer cancel()
+	go func() {
+		time.Sleep(logPollInterval / 2)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return
+		}
+		_, _ = f.WriteString("skip me\nnew line\npart")
+		_ = f.Close()
+	}()
+
+	var out bytes.Buffer
+	if err := TailLogs(ctx, &out, []string{path}, TailOptions{Lines: 0, Grep: regexp.MustCompile("new|part"), Follow: true}); err != nil {
+		t.Fatal(err)
+	}
+	// The unterminated "part" is held back until its line ends.
+	if want := "new line\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLastLines_BoundsRead(t *testing.T) {
+	logsDir, _ := setupSimulatorLogs(t, map[string]string{
+		"big.log": strings.Repeat("x", maxTailBytes) + "\nlast\n",
+	})
+	lines, _, err := lastLines(filepath.Join(logsDir, "big.log"), 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first line straddles the read window and is dropped.
+	if !slices.Equal(lines, []string{"last"}) {
+		t.Errorf("lines = %v, want [last]", lines)
+	}
+}
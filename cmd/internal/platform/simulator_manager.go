@@ -1,12 +1,18 @@
 package platform
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ManagedSimulator represents a simulator in the axe device set.
@@ -152,6 +158,18 @@ func parseAvailable(runtimesJSON, deviceTypesJSON []byte) ([]AvailableDeviceType
 	return result, nil
 }
 
+// parseRuntimes returns the installed runtimes from simctl
+// "list runtimes --json" output, in the order simctl reports them.
+func parseRuntimes(runtimesJSON []byte) ([]AvailableRuntime, error) {
+	var result struct {
+		Runtimes []AvailableRuntime `json:"runtimes"`
+	}
+	if err := json.Unmarshal(runtimesJSON, &result); err != nil {
+		return nil, fmt.Errorf("parsing runtimes JSON: %w", err)
+	}
+	return result.Runtimes, nil
+}
+
 // Add creates a new simulator in the axe device set.
 // It generates a sequential name like "axe iPhone 16 Pro (1)".
 func Add(simctl SimctlRunner, deviceType, runtime string, setDefault bool, store *ConfigStore) (ManagedSimulator, error) {
@@ -163,12 +181,20 @@ func Add(simctl SimctlRunner, deviceType, runtime string, setDefault bool, store
 	// Look up the human-readable name for this device type.
 	baseName := deviceTypeBaseName(simctl, deviceType)
 
+	// Hold the set lock from picking the name through creation so that
+	// concurrent adds do not pick the same sequence number.
+	lock, err := lockDeviceSet(context.Background(), deviceSetPath)
+	if err != nil {
+		return ManagedSimulator{}, err
+	}
+	defer lock.Unlock()
+
 	// Determine the next sequence number.
 	existing, _ := ListManaged(simctl, store)
 	seq := nextSequenceNumber(existing, baseName)
 	name := fmt.Sprintf("axe %s (%d)", baseName, seq)
 
-	createCtx, createCancel := simctlContext()
+	createCtx, createCancel := simctlCreateContext()
 	defer createCancel()
 	udid, err := simctl.Create(createCtx, name, deviceType, runtime, deviceSetPath)
 	if err != nil {
@@ -195,20 +221,37 @@ func Add(simctl SimctlRunner, deviceType, runtime string, setDefault bool, store
 	}, nil
 }
 
+// RemovedSimulator is a simulator deleted by Remove, RemoveAll or Clean.
+type RemovedSimulator struct {
+	UDID  string `json:"udid"`
+	Name  string `json:"name"`
+	Freed uint64 `json:"freedBytes"` // size of its data directory; 0 if unknown
+
+	// LastUsed is when the simulator was last used, for Clean; zero if
+	// unknown.
+	LastUsed time.Time `json:"lastUsed,omitzero"`
+}
+
 // Remove deletes a simulator from the axe device set.
-// Returns an error if the simulator is currently booted.
-func Remove(simctl SimctlRunner, udid string, store *ConfigStore) error {
+// Returns an error if the simulator is currently booted, or if it is not in
+// the axe set: devices of the standard Xcode set are never deleted.
+func Remove(simctl SimctlRunner, udid string, store *ConfigStore) (RemovedSimulator, error) {
 	deviceSetPath, err := AxeDeviceSetPath()
 	if err != nil {
-		return err
+		return RemovedSimulator{}, err
+	}
+	lock, err := lockDeviceSet(context.Background(), deviceSetPath)
+	if err != nil {
+		return RemovedSimulator{}, err
 	}
+	defer lock.Unlock()
 
 	// Check if the device exists and its state.
 	listCtx, listCancel := simctlContext()
 	defer listCancel()
 	devices, err := simctl.ListDevices(listCtx, deviceSetPath)
 	if err != nil {
-		return fmt.Errorf("listing devices: %w", err)
+		return RemovedSimulator{}, fmt.Errorf("listing devices: %w", err)
 	}
 
 	var found *simDevice
@@ -219,26 +262,239 @@ func Remove(simctl SimctlRunner, udid string, store *ConfigStore) error {
 		}
 	}
 	if found == nil {
-		return fmt.Errorf("simulator %s not found in axe device set", udid)
+		if inStandardSet(simctl, udid) {
+			return RemovedSimulator{}, fmt.Errorf("simulator %s is in the standard Xcode device set, not axe's; axe only removes the simulators it manages", udid)
+		}
+		return RemovedSimulator{}, fmt.Errorf("simulator %s not found in axe device set", udid)
+	}
+	return removeDevice(simctl, *found, deviceSetPath, store)
+}
+
+// RemoveAll deletes every Shutdown simulator in the axe device set. Booted
+// ones are kept and reported in the error; the others are removed anyway.
+func RemoveAll(simctl SimctlRunner, store *ConfigStore) ([]RemovedSimulator, error) {
+	deviceSetPath, err := AxeDeviceSetPath()
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockDeviceSet(context.Background(), deviceSetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	listCtx, listCancel := simctlContext()
+	defer listCancel()
+	devices, err := simctl.ListDevices(listCtx, deviceSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("listing devices: %w", err)
 	}
-	if found.State == "Booted" {
-		return fmt.Errorf("simulator %s (%s) is currently booted; shut it down first", udid, found.Name)
+
+	var removed []RemovedSimulator
+	var errs []error
+	for _, d := range devices {
+		r, err := removeDevice(simctl, d, deviceSetPath, store)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, r)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// removeDevice deletes d from the axe set at deviceSetPath and forgets the
+// default and profile that referred to it. Called with the set locked.
+func removeDevice(simctl SimctlRunner, d simDevice, deviceSetPath string, store *ConfigStore) (RemovedSimulator, error) {
+	if d.State == "Booted" {
+		return RemovedSimulator{}, fmt.Errorf("simulator %s (%s) is currently booted; shut it down first", d.UDID, d.Name)
 	}
 
+	// Measure before deleting; simctl removes the directory.
+	freed := dirSize(filepath.Join(deviceSetPath, d.UDID))
 	deleteCtx, deleteCancel := simctlContext()
 	defer deleteCancel()
-	if err := simctl.Delete(deleteCtx, udid, deviceSetPath); err != nil {
-		return err
+	if err := simctl.Delete(deleteCtx, d.UDID, deviceSetPath); err != nil {
+		return RemovedSimulator{}, err
 	}
 
 	// Clear default if this was the default.
-	if defaultUDID, _ := store.GetDefault(); defaultUDID == udid {
+	if defaultUDID, _ := store.GetDefault(); defaultUDID == d.UDID {
 		if err := store.ClearDefault(); err != nil {
 			slog.Warn("Failed to clear default after removing simulator", "err", err)
 		}
 	}
+	if profile, _ := store.GetDeviceProfile(d.UDID); !profile.IsZero() {
+		if err := store.SetDeviceProfile(d.UDID, AccessibilityOverrides{}); err != nil {
+			slog.Warn("Failed to remove the device profile of the removed simulator", "err", err)
+		}
+	}
+	if err := store.ForgetSimulator(d.UDID); err != nil {
+		slog.Debug("Failed to forget the usage of the removed simulator", "err", err)
+	}
 
-	return nil
+	return RemovedSimulator{UDID: d.UDID, Name: d.Name, Freed: freed}, nil
+}
+
+// inStandardSet reports whether udid is a device of the standard Xcode set.
+// A failure to list the set counts as not found.
+func inStandardSet(simctl SimctlRunner, udid string) bool {
+	ctx, cancel := simctlContext()
+	defer cancel()
+	out, err := simctl.ListAllDevices(ctx, false)
+	if err != nil {
+		return false
+	}
+	devices, err := parseDevicesJSON(out)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(devices, func(d simDevice) bool { return d.UDID == udid })
+}
+
+// dirSize returns the total size of the regular files under dir, skipping
+// anything it cannot read.
+func dirSize(dir string) uint64 {
+	var total uint64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += uint64(info.Size())
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// EraseDevice resets the simulator udid in the set at setPath to its factory
+// state, so that no app data or defaults carry over from earlier sessions.
+// simctl only erases a shut-down device, so a booted one is shut down first.
+func EraseDevice(simctl SimctlRunner, setPath, udid string) error {
+	lock, err := lockDeviceSet(context.Background(), setPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	listCtx, listCancel := simctlContext()
+	defer listCancel()
+	devices, err := simctl.ListDevices(listCtx, setPath)
+	if err != nil {
+		return fmt.Errorf("listing devices: %w", err)
+	}
+	i := slices.IndexFunc(devices, func(d simDevice) bool { return d.UDID == udid })
+	if i < 0 {
+		return fmt.Errorf("%w: simulator %s not found in %s", ErrNoSimulator, udid, setPath)
+	}
+	if devices[i].State != "Shutdown" {
+		if err := shutdownDevice(simctl, udid, setPath); err != nil {
+			return fmt.Errorf("shutting down %s before erasing: %w", udid, err)
+		}
+	}
+
+	eraseCtx, eraseCancel := simctlContext()
+	defer eraseCancel()
+	return simctl.Erase(eraseCtx, udid, setPath)
+}
+
+// Recreate replaces a managed simulator, identified by UDID or name, with a
+// fresh one of the same name, device type, and runtime. It is meant for
+// devices that no longer boot or erase: the old device is shut down (errors
+// ignored) and deleted before the new one is created, and the default and
+// last-used references are moved to the new UDID.
+// It returns the old device and its replacement.
+func Recreate(simctl SimctlRunner, target string, store *ConfigStore) (old, created ManagedSimulator, err error) {
+	deviceSetPath, err := AxeDeviceSetPath()
+	if err != nil {
+		return ManagedSimulator{}, ManagedSimulator{}, err
+	}
+	lock, err := lockDeviceSet(context.Background(), deviceSetPath)
+	if err != nil {
+		return ManagedSimulator{}, ManagedSimulator{}, err
+	}
+	defer lock.Unlock()
+
+	listCtx, listCancel := simctlContext()
+	defer listCancel()
+	devices, err := simctl.ListDevices(listCtx, deviceSetPath)
+	if err != nil {
+		return ManagedSimulator{}, ManagedSimulator{}, fmt.Errorf("listing devices: %w", err)
+	}
+	found, err := findManagedDevice(devices, target)
+	if err != nil {
+		return ManagedSimulator{}, ManagedSimulator{}, err
+	}
+	if found.DeviceTypeIdentifier == "" || found.RuntimeID == "" {
+		return ManagedSimulator{}, ManagedSimulator{}, fmt.Errorf("simulator %s (%s) has no recorded device type or runtime; remove it and add a new one instead", found.UDID, found.Name)
+	}
+	old = ManagedSimulator{
+		UDID:      found.UDID,
+		Name:      found.Name,
+		Runtime:   humanReadableRuntime(found.RuntimeID),
+		RuntimeID: found.RuntimeID,
+		State:     found.State,
+	}
+
+	if found.State != "Shutdown" {
+		shutdownCtx, shutdownCancel := simctlContext()
+		if err := simctl.Shutdown(shutdownCtx, found.UDID, deviceSetPath); err != nil {
+			slog.Warn("Failed to shut down simulator before recreating", "udid", found.UDID, "err", err)
+		}
+		shutdownCancel()
+	}
+
+	deleteCtx, deleteCancel := simctlContext()
+	defer deleteCancel()
+	if err := simctl.Delete(deleteCtx, found.UDID, deviceSetPath); err != nil {
+		return old, ManagedSimulator{}, fmt.Errorf("deleting simulator %s: %w", found.UDID, err)
+	}
+
+	createCtx, createCancel := simctlCreateContext()
+	defer createCancel()
+	udid, err := simctl.Create(createCtx, found.Name, found.DeviceTypeIdentifier, found.RuntimeID, deviceSetPath)
+	if err != nil {
+		return old, ManagedSimulator{}, fmt.Errorf("simulator %s was deleted but creating its replacement failed: %w", found.UDID, err)
+	}
+
+	if err := store.ReplaceSimulator(found.UDID, udid); err != nil {
+		slog.Warn("Failed to update simulator references after recreating", "err", err)
+	}
+	defaultUDID, _ := store.GetDefault()
+
+	return old, ManagedSimulator{
+		UDID:      udid,
+		Name:      found.Name,
+		Runtime:   old.Runtime,
+		RuntimeID: found.RuntimeID,
+		State:     "Shutdown",
+		IsDefault: udid == defaultUDID,
+	}, nil
+}
+
+// findManagedDevice returns the device whose UDID or name is target.
+// A name shared by several devices is rejected as ambiguous.
+func findManagedDevice(devices []simDevice, target string) (simDevice, error) {
+	var byName []simDevice
+	for _, d := range devices {
+		if d.UDID == target {
+			return d, nil
+		}
+		if d.Name == target {
+			byName = append(byName, d)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return simDevice{}, fmt.Errorf("simulator %s not found in axe device set", target)
+	case 1:
+		return byName[0], nil
+	default:
+		return simDevice{}, fmt.Errorf("simulator name %q matches %d devices; pass a UDID instead", target, len(byName))
+	}
 }
 
 // sequenceRe matches the "(N)" suffix in device names like "axe iPhone 16 Pro (2)".
@@ -3,7 +3,12 @@ package platform
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +20,7 @@ type managerFakeSimctlRunner struct {
 	createErr       error
 	deleteErr       error
 	createdUDID     string
+	calls           []string // mutating simctl calls in order, e.g. "delete AAA"
 }
 
 func (f *managerFakeSimctlRunner) ListDevices(_ context.Context, _ string) ([]simDevice, error) {
@@ -26,6 +32,7 @@ func (f *managerFakeSimctlRunner) Clone(_ context.Context, _, _, _ string) (stri
 }
 
 func (f *managerFakeSimctlRunner) Create(_ context.Context, name, deviceType, runtime, _ string) (string, error) {
+	f.calls = append(f.calls, "create "+name+" "+deviceType+" "+runtime)
 	if f.createErr != nil {
 		return "", f.createErr
 	}
@@ -43,13 +50,20 @@ func (f *managerFakeSimctlRunner) Create(_ context.Context, name, deviceType, ru
 	return udid, nil
 }
 
-func (f *managerFakeSimctlRunner) Shutdown(_ context.Context, _, _ string) error {
+func (f *managerFakeSimctlRunner) Shutdown(_ context.Context, udid, _ string) error {
+	f.calls = append(f.calls, "shutdown "+udid)
 	return nil
 }
 
 func (f *managerFakeSimctlRunner) Boot(_ context.Context, _ string) error { return nil }
 
+func (f *managerFakeSimctlRunner) Erase(_ context.Context, udid, _ string) error {
+	f.calls = append(f.calls, "erase "+udid)
+	return nil
+}
+
 func (f *managerFakeSimctlRunner) Delete(_ context.Context, udid, _ string) error {
+	f.calls = append(f.calls, "delete "+udid)
 	if f.deleteErr != nil {
 		return f.deleteErr
 	}
@@ -409,7 +423,7 @@ func TestRemove_WithFakeRunner(t *testing.T) {
 		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
 		_ = store.SetDefault("AAA")
 
-		err := Remove(runner, "AAA", store)
+		_, err := Remove(runner, "AAA", store)
 		if err != nil {
 			t.Fatalf("Remove: %v", err)
 		}
@@ -429,7 +443,7 @@ func TestRemove_WithFakeRunner(t *testing.T) {
 		}
 		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
 
-		err := Remove(runner, "AAA", store)
+		_, err := Remove(runner, "AAA", store)
 		if err == nil {
 			t.Fatal("expected error for booted device, got nil")
 		}
@@ -439,7 +453,7 @@ func TestRemove_WithFakeRunner(t *testing.T) {
 		runner := &managerFakeSimctlRunner{}
 		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
 
-		err := Remove(runner, "MISSING", store)
+		_, err := Remove(runner, "MISSING", store)
 		if err == nil {
 			t.Fatal("expected error for missing device, got nil")
 		}
@@ -454,9 +468,229 @@ func TestRemove_WithFakeRunner(t *testing.T) {
 		}
 		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
 
-		err := Remove(runner, "AAA", store)
+		_, err := Remove(runner, "AAA", store)
 		if err == nil {
 			t.Fatal("expected error, got nil")
 		}
 	})
 }
+
+func TestRemove_FreedSpace(t *testing.T) {
+	setPath := t.TempDir()
+	t.Setenv("AXE_DEVICE_SET", setPath)
+	if err := os.MkdirAll(filepath.Join(setPath, "AAA", "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(setPath, "AAA", "data", "blob"), make([]byte, 3000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &managerFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Shutdown", RuntimeID: testRuntime},
+		},
+	}
+	store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+
+	removed, err := Remove(runner, "AAA", store)
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if removed.Name != "axe iPhone 16 Pro (1)" || removed.Freed != 3000 {
+		t.Errorf("removed = %+v, want 3000 bytes freed", removed)
+	}
+}
+
+// axeSetOnly reports an empty axe device set while keeping the standard set.
+type axeSetOnly struct {
+	*managerFakeSimctlRunner
+}
+
+func (axeSetOnly) ListDevices(context.Context, string) ([]simDevice, error) { return nil, nil }
+
+func TestRemove_RefusesStandardSetDevice(t *testing.T) {
+	t.Setenv("AXE_DEVICE_SET", t.TempDir())
+	runner := &managerFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "iPhone 16 Pro", UDID: "STD", State: "Shutdown", RuntimeID: testRuntime},
+		},
+	}
+	axeSet := &axeSetOnly{managerFakeSimctlRunner: runner}
+	store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+
+	_, err := Remove(axeSet, "STD", store)
+	if err == nil || !strings.Contains(err.Error(), "standard Xcode device set") {
+		t.Fatalf("expected a refusal for the standard set device, got %v", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("unexpected simctl calls: %v", runner.calls)
+	}
+}
+
+func TestRemoveAll_WithFakeRunner(t *testing.T) {
+	t.Setenv("AXE_DEVICE_SET", t.TempDir())
+	runner := &managerFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Shutdown", RuntimeID: testRuntime},
+			{Name: "axe iPhone 16 Pro (2)", UDID: "BBB", State: "Booted", RuntimeID: testRuntime},
+			{Name: "axe iPhone 16 Pro (3)", UDID: "CCC", State: "Shutdown", RuntimeID: testRuntime},
+		},
+	}
+	store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+	_ = store.SetDefault("CCC")
+
+	removed, err := RemoveAll(runner, store)
+	if err == nil || !strings.Contains(err.Error(), "BBB") {
+		t.Errorf("expected the booted device to be reported, got %v", err)
+	}
+	if len(removed) != 2 || removed[0].UDID != "AAA" || removed[1].UDID != "CCC" {
+		t.Errorf("removed = %+v, want AAA and CCC", removed)
+	}
+	if want := []string{"delete AAA", "delete CCC"}; !slices.Equal(runner.calls, want) {
+		t.Errorf("calls = %v, want %v", runner.calls, want)
+	}
+	if defaultUDID, _ := store.GetDefault(); defaultUDID != "" {
+		t.Errorf("expected default cleared, got %q", defaultUDID)
+	}
+}
+
+func TestEraseDevice_WithFakeRunner(t *testing.T) {
+	setPath := t.TempDir()
+	runner := &managerFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Shutdown", RuntimeID: testRuntime},
+			{Name: "axe iPhone 16 Pro (2)", UDID: "BBB", State: "Booted", RuntimeID: testRuntime},
+		},
+	}
+
+	// A shut-down device is erased as is; a booted one is shut down first.
+	if err := EraseDevice(runner, setPath, "AAA"); err != nil {
+		t.Fatalf("EraseDevice(AAA): %v", err)
+	}
+	if err := EraseDevice(runner, setPath, "BBB"); err != nil {
+		t.Fatalf("EraseDevice(BBB): %v", err)
+	}
+	if want := []string{"erase AAA", "shutdown BBB", "erase BBB"}; !slices.Equal(runner.calls, want) {
+		t.Errorf("calls = %v, want %v", runner.calls, want)
+	}
+
+	runner.calls = nil
+	if err := EraseDevice(runner, setPath, "GONE"); !errors.Is(err, ErrNoSimulator) {
+		t.Errorf("EraseDevice(GONE) err = %v, want ErrNoSimulator", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("unexpected simctl calls: %v", runner.calls)
+	}
+}
+
+func TestRecreate_WithFakeRunner(t *testing.T) {
+	const deviceType = "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro"
+
+	t.Run("deletes and recreates with same type and runtime", func(t *testing.T) {
+		runner := &managerFakeSimctlRunner{
+			devices: []simDevice{
+				{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Booted", DeviceTypeIdentifier: deviceType, RuntimeID: testRuntime},
+				{Name: "axe iPhone 16 Pro (2)", UDID: "BBB", State: "Shutdown", DeviceTypeIdentifier: deviceType, RuntimeID: testRuntime},
+			},
+			createdUDID: "CCC",
+		}
+		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+		_ = store.SetDefault("AAA")
+		_ = store.SetLastUsed("/p/A.xcodeproj", "AAA")
+		_ = store.SetLastUsed("/p/B.xcodeproj", "BBB")
+
+		old, created, err := Recreate(runner, "axe iPhone 16 Pro (1)", store)
+		if err != nil {
+			t.Fatalf("Recreate: %v", err)
+		}
+
+		wantCalls := []string{
+			"shutdown AAA",
+			"delete AAA",
+			"create axe iPhone 16 Pro (1) " + deviceType + " " + testRuntime,
+		}
+		if fmt.Sprint(runner.calls) != fmt.Sprint(wantCalls) {
+			t.Errorf("calls = %q, want %q", runner.calls, wantCalls)
+		}
+		if old.UDID != "AAA" {
+			t.Errorf("old UDID = %q, want AAA", old.UDID)
+		}
+		if created.UDID != "CCC" || created.Name != "axe iPhone 16 Pro (1)" || created.RuntimeID != testRuntime || !created.IsDefault {
+			t.Errorf("created = %+v", created)
+		}
+
+		if got, _ := store.GetDefault(); got != "CCC" {
+			t.Errorf("default = %q, want CCC", got)
+		}
+		if got, _ := store.GetLastUsed("/p/A.xcodeproj"); got != "CCC" {
+			t.Errorf("last used for A = %q, want CCC", got)
+		}
+		if got, _ := store.GetLastUsed("/p/B.xcodeproj"); got != "BBB" {
+			t.Errorf("last used for B = %q, want BBB (untouched)", got)
+		}
+	})
+
+	t.Run("shutdown device is not shut down again", func(t *testing.T) {
+		runner := &managerFakeSimctlRunner{
+			devices: []simDevice{
+				{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Shutdown", DeviceTypeIdentifier: deviceType, RuntimeID: testRuntime},
+			},
+		}
+		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+
+		if _, _, err := Recreate(runner, "AAA", store); err != nil {
+			t.Fatalf("Recreate: %v", err)
+		}
+		if len(runner.calls) != 2 || runner.calls[0] != "delete AAA" {
+			t.Errorf("calls = %q, want delete then create", runner.calls)
+		}
+	})
+
+	t.Run("ambiguous name", func(t *testing.T) {
+		runner := &managerFakeSimctlRunner{
+			devices: []simDevice{
+				{Name: "axe iPhone", UDID: "AAA", State: "Shutdown", DeviceTypeIdentifier: deviceType, RuntimeID: testRuntime},
+				{Name: "axe iPhone", UDID: "BBB", State: "Shutdown", DeviceTypeIdentifier: deviceType, RuntimeID: testRuntime},
+			},
+		}
+		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+
+		if _, _, err := Recreate(runner, "axe iPhone", store); err == nil {
+			t.Fatal("expected error for ambiguous name, got nil")
+		}
+		if len(runner.calls) != 0 {
+			t.Errorf("calls = %q, want none", runner.calls)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		runner := &managerFakeSimctlRunner{}
+		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+
+		if _, _, err := Recreate(runner, "MISSING", store); err == nil {
+			t.Fatal("expected error for missing device, got nil")
+		}
+	})
+
+	t.Run("delete error keeps references", func(t *testing.T) {
+		runner := &managerFakeSimctlRunner{
+			devices: []simDevice{
+				{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Shutdown", DeviceTypeIdentifier: deviceType, RuntimeID: testRuntime},
+			},
+			deleteErr: fmt.Errorf("simctl delete failed"),
+		}
+		store := NewConfigStoreWithPath(t.TempDir() + "/config.json")
+		_ = store.SetDefault("AAA")
+
+		if _, _, err := Recreate(runner, "AAA", store); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		for _, c := range runner.calls {
+			if c != "delete AAA" {
+				t.Errorf("unexpected call after failed delete: %q", c)
+			}
+		}
+		if got, _ := store.GetDefault(); got != "AAA" {
+			t.Errorf("default = %q, want AAA", got)
+		}
+	})
+}
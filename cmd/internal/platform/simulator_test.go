@@ -1,21 +1,47 @@
 package platform
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/k-kohey/axe/internal/procgroup"
 )
 
+// TestMain points HOME at a temporary directory so that no test reads or
+// writes the user's axe config, which ResolveAxeSimulator updates.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "axe-platform-home-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = os.Setenv("HOME", home)
+	code := m.Run()
+	_ = os.RemoveAll(home)
+	os.Exit(code)
+}
+
 // simFakeSimctlRunner is a SimctlRunner fake for testing ResolveAxeSimulator.
 type simFakeSimctlRunner struct {
 	devices        []simDevice
 	allDevicesJSON []byte
 	createErr      error
 	createdUDID    string
+	runtimesJSON   []byte
+	// deviceTypesJSON is the "list devicetypes" output; empty by default.
+	deviceTypesJSON []byte
+
+	// createdRuntime and createdDeviceType record the last Create call.
+	createdRuntime    string
+	createdDeviceType string
 }
 
 func (f *simFakeSimctlRunner) ListDevices(_ context.Context, _ string) ([]simDevice, error) {
@@ -30,6 +56,8 @@ func (f *simFakeSimctlRunner) Create(_ context.Context, name, deviceType, runtim
 	if f.createErr != nil {
 		return "", f.createErr
 	}
+	f.createdRuntime = runtime
+	f.createdDeviceType = deviceType
 	udid := f.createdUDID
 	if udid == "" {
 		udid = "CREATED-1"
@@ -39,6 +67,7 @@ func (f *simFakeSimctlRunner) Create(_ context.Context, name, deviceType, runtim
 
 func (f *simFakeSimctlRunner) Shutdown(_ context.Context, _, _ string) error { return nil }
 func (f *simFakeSimctlRunner) Delete(_ context.Context, _, _ string) error   { return nil }
+func (f *simFakeSimctlRunner) Erase(_ context.Context, _, _ string) error    { return nil }
 func (f *simFakeSimctlRunner) Boot(_ context.Context, _ string) error        { return nil }
 
 func (f *simFakeSimctlRunner) ListAllDevices(_ context.Context, _ bool) ([]byte, error) {
@@ -58,10 +87,16 @@ func (f *simFakeSimctlRunner) ListAllDevices(_ context.Context, _ bool) ([]byte,
 }
 
 func (f *simFakeSimctlRunner) ListRuntimes(_ context.Context) ([]byte, error) {
+	if f.runtimesJSON != nil {
+		return f.runtimesJSON, nil
+	}
 	return []byte(`{"runtimes":[]}`), nil
 }
 
 func (f *simFakeSimctlRunner) ListDeviceTypes(_ context.Context) ([]byte, error) {
+	if f.deviceTypesJSON != nil {
+		return f.deviceTypesJSON, nil
+	}
 	return []byte(`{"devicetypes":[]}`), nil
 }
 
@@ -82,6 +117,7 @@ func TestResolveSimulator(t *testing.T) {
 }
 
 func TestAxeDeviceSetPath(t *testing.T) {
+	t.Setenv("AXE_DEVICE_SET", "")
 	path, err := AxeDeviceSetPath()
 	if err != nil {
 		t.Fatalf("AxeDeviceSetPath: %v", err)
@@ -119,9 +155,9 @@ func TestSelectLatestIPhone(t *testing.T) {
 		}
 	}`)
 
-	best, runtime, err := selectLatestIPhone(simctlJSON)
+	best, runtime, err := selectLatestDevice(simctlJSON, "", "")
 	if err != nil {
-		t.Fatalf("selectLatestIPhone: %v", err)
+		t.Fatalf("selectLatestDevice: %v", err)
 	}
 
 	// Expect iPhone 16 Pro (iOS 18.2, lexicographically largest on same version).
@@ -145,14 +181,57 @@ func TestSelectLatestIPhone_NoIPhone(t *testing.T) {
 		}
 	}`)
 
-	_, _, err := selectLatestIPhone(simctlJSON)
+	_, _, err := selectLatestDevice(simctlJSON, "", "")
 	if err == nil {
 		t.Fatal("expected error when no iPhone found, got nil")
 	}
+	if !errors.Is(err, ErrNoSimulator) {
+		t.Errorf("expected ErrNoSimulator, got %v", err)
+	}
+}
+
+func TestSelectLatestDevice_IPad(t *testing.T) {
+	simctlJSON := []byte(`{
+		"devices": {
+			"com.apple.CoreSimulator.SimRuntime.iOS-17-0": [
+				{"name": "iPad Pro 13-inch (M4)", "udid": "AAA", "state": "Shutdown", "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPad-Pro-13-inch-M4-8GB"}
+			],
+			"com.apple.CoreSimulator.SimRuntime.iOS-18-2": [
+				{"name": "iPad Air 11-inch (M2)", "udid": "BBB", "state": "Shutdown", "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPad-Air-11-inch-M2"},
+				{"name": "iPad mini (A17 Pro)", "udid": "CCC", "state": "Shutdown", "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPad-mini-A17-Pro"},
+				{"name": "iPhone 16 Pro", "udid": "DDD", "state": "Shutdown", "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro"}
+			]
+		}
+	}`)
+
+	// Highest iOS version first, then the lexicographically largest name;
+	// iPhones are ignored.
+	best, runtime, err := selectLatestDevice(simctlJSON, FamilyIPad, "")
+	if err != nil {
+		t.Fatalf("selectLatestDevice: %v", err)
+	}
+	if best.UDID != "CCC" || runtime != "com.apple.CoreSimulator.SimRuntime.iOS-18-2" {
+		t.Errorf("expected iPad mini (A17 Pro) on iOS 18.2, got %s (%s) on %s", best.Name, best.UDID, runtime)
+	}
+
+	best, _, err = selectLatestDevice(simctlJSON, FamilyIPad, "com.apple.CoreSimulator.SimRuntime.iOS-17-0")
+	if err != nil {
+		t.Fatalf("selectLatestDevice: %v", err)
+	}
+	if best.UDID != "AAA" {
+		t.Errorf("expected the iOS 17.0 iPad (AAA), got %s", best.UDID)
+	}
+
+	noIPad := []byte(`{"devices": {"com.apple.CoreSimulator.SimRuntime.iOS-18-2": [
+		{"name": "iPhone 16", "udid": "EEE", "state": "Shutdown", "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16"}
+	]}}`)
+	if _, _, err := selectLatestDevice(noIPad, FamilyIPad, ""); !errors.Is(err, ErrNoSimulator) || !strings.Contains(err.Error(), "no iPad simulator") {
+		t.Errorf("expected ErrNoSimulator naming iPad, got %v", err)
+	}
 }
 
 func TestSelectLatestIPhone_MalformedJSON(t *testing.T) {
-	_, _, err := selectLatestIPhone([]byte(`{not json`))
+	_, _, err := selectLatestDevice([]byte(`{not json`), "", "")
 	if err == nil {
 		t.Fatal("expected error on malformed JSON, got nil")
 	}
@@ -164,14 +243,14 @@ func TestSelectAvailableSimulator(t *testing.T) {
 			{UDID: "A", State: "Booted"},
 			{UDID: "B", State: "Booted"},
 		}
-		udid, ok := selectAvailableSimulator(devices, "")
+		udid, _, ok := selectAvailableSimulator(devices, "", "")
 		if ok || udid != "" {
 			t.Errorf("expected (\"\", false), got (%q, %v)", udid, ok)
 		}
 	})
 
 	t.Run("empty devices returns empty", func(t *testing.T) {
-		udid, ok := selectAvailableSimulator(nil, "")
+		udid, _, ok := selectAvailableSimulator(nil, "", "")
 		if ok || udid != "" {
 			t.Errorf("expected (\"\", false), got (%q, %v)", udid, ok)
 		}
@@ -182,7 +261,7 @@ func TestSelectAvailableSimulator(t *testing.T) {
 			{UDID: "A", State: "Shutdown"},
 			{UDID: "B", State: "Shutdown"},
 		}
-		udid, ok := selectAvailableSimulator(devices, "B")
+		udid, _, ok := selectAvailableSimulator(devices, "B", "")
 		if !ok || udid != "B" {
 			t.Errorf("expected (\"B\", true), got (%q, %v)", udid, ok)
 		}
@@ -193,7 +272,7 @@ func TestSelectAvailableSimulator(t *testing.T) {
 			{UDID: "A", State: "Booted"},
 			{UDID: "B", State: "Shutdown"},
 		}
-		udid, ok := selectAvailableSimulator(devices, "A")
+		udid, _, ok := selectAvailableSimulator(devices, "A", "")
 		if !ok || udid != "B" {
 			t.Errorf("expected (\"B\", true), got (%q, %v)", udid, ok)
 		}
@@ -205,7 +284,7 @@ func TestSelectAvailableSimulator(t *testing.T) {
 			{UDID: "B", State: "Shutdown"},
 			{UDID: "C", State: "Shutdown"},
 		}
-		udid, ok := selectAvailableSimulator(devices, "MISSING")
+		udid, _, ok := selectAvailableSimulator(devices, "MISSING", "")
 		if !ok || udid != "B" {
 			t.Errorf("expected (\"B\", true), got (%q, %v)", udid, ok)
 		}
@@ -216,7 +295,7 @@ func TestSelectAvailableSimulator(t *testing.T) {
 			{UDID: "A", State: "Booted"},
 			{UDID: "B", State: "Shutdown"},
 		}
-		udid, ok := selectAvailableSimulator(devices, "")
+		udid, _, ok := selectAvailableSimulator(devices, "", "")
 		if !ok || udid != "B" {
 			t.Errorf("expected (\"B\", true), got (%q, %v)", udid, ok)
 		}
@@ -227,7 +306,7 @@ func TestSelectAvailableSimulator(t *testing.T) {
 			{UDID: "A", State: "Booted"},
 			{UDID: "B", State: "Booted"},
 		}
-		udid, ok := selectAvailableSimulator(devices, "A")
+		udid, _, ok := selectAvailableSimulator(devices, "A", "")
 		if ok || udid != "" {
 			t.Errorf("expected (\"\", false), got (%q, %v)", udid, ok)
 		}
@@ -267,14 +346,14 @@ func TestResolveAxeSimulator_PreferredUDID(t *testing.T) {
 		},
 	}
 
-	udid, _, isExternal, err := ResolveAxeSimulator(runner, "BBB")
+	got, err := ResolveAxeSimulator(runner, "BBB", "", "", "", "")
 	if err != nil {
 		t.Fatalf("ResolveAxeSimulator: %v", err)
 	}
-	if udid != "BBB" {
-		t.Errorf("expected BBB, got %s", udid)
+	if got.UDID != "BBB" {
+		t.Errorf("expected BBB, got %s", got.UDID)
 	}
-	if isExternal {
+	if got.IsExternal {
 		t.Error("expected isExternal=false for axe set device")
 	}
 }
@@ -286,12 +365,41 @@ func TestResolveAxeSimulator_PreferredUDID_NotFound(t *testing.T) {
 		},
 	}
 
-	_, _, _, err := ResolveAxeSimulator(runner, "MISSING")
+	_, err := ResolveAxeSimulator(runner, "MISSING", "", "", "", "")
 	if err == nil {
 		t.Fatal("expected error for missing UDID, got nil")
 	}
 }
 
+func TestFindSimulator(t *testing.T) {
+	runner := &simFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 Pro (1)", UDID: "AAA", State: "Shutdown"},
+		},
+		allDevicesJSON: []byte(`{"devices":{"com.apple.CoreSimulator.SimRuntime.iOS-18-2":[{"name":"iPhone 16","udid":"STD-1","state":"Shutdown"}]}}`),
+	}
+
+	setPath, isExternal, err := FindSimulator(runner, "AAA")
+	if err != nil {
+		t.Fatalf("FindSimulator(AAA): %v", err)
+	}
+	if isExternal || setPath == "" {
+		t.Errorf("AAA: got setPath=%q isExternal=%v, want axe set", setPath, isExternal)
+	}
+
+	setPath, isExternal, err = FindSimulator(runner, "STD-1")
+	if err != nil {
+		t.Fatalf("FindSimulator(STD-1): %v", err)
+	}
+	if !isExternal || setPath != "" {
+		t.Errorf("STD-1: got setPath=%q isExternal=%v, want standard set", setPath, isExternal)
+	}
+
+	if _, _, err := FindSimulator(runner, "MISSING"); !errors.Is(err, ErrNoSimulator) {
+		t.Errorf("MISSING: expected ErrNoSimulator, got %v", err)
+	}
+}
+
 func TestResolveAxeSimulator_AutoSelectShutdown(t *testing.T) {
 	runner := &simFakeSimctlRunner{
 		devices: []simDevice{
@@ -300,14 +408,14 @@ func TestResolveAxeSimulator_AutoSelectShutdown(t *testing.T) {
 		},
 	}
 
-	udid, _, isExternal, err := ResolveAxeSimulator(runner, "")
+	got, err := ResolveAxeSimulator(runner, "", "", "", "", "")
 	if err != nil {
 		t.Fatalf("ResolveAxeSimulator: %v", err)
 	}
-	if udid != "BBB" {
-		t.Errorf("expected BBB (shutdown), got %s", udid)
+	if got.UDID != "BBB" {
+		t.Errorf("expected BBB (shutdown), got %s", got.UDID)
 	}
-	if isExternal {
+	if got.IsExternal {
 		t.Error("expected isExternal=false for auto-selected axe device")
 	}
 }
@@ -326,14 +434,14 @@ func TestResolveAxeSimulator_AutoCreate(t *testing.T) {
 		createdUDID: "NEW-1",
 	}
 
-	udid, _, isExternal, err := ResolveAxeSimulator(runner, "")
+	got, err := ResolveAxeSimulator(runner, "", "", "", "", "")
 	if err != nil {
 		t.Fatalf("ResolveAxeSimulator: %v", err)
 	}
-	if udid != "NEW-1" {
-		t.Errorf("expected NEW-1, got %s", udid)
+	if got.UDID != "NEW-1" {
+		t.Errorf("expected NEW-1, got %s", got.UDID)
 	}
-	if isExternal {
+	if got.IsExternal {
 		t.Error("expected isExternal=false for auto-created device")
 	}
 }
@@ -352,12 +460,489 @@ func TestResolveAxeSimulator_CreateError(t *testing.T) {
 		createErr: fmt.Errorf("simctl create failed"),
 	}
 
-	_, _, _, err := ResolveAxeSimulator(runner, "")
+	_, err := ResolveAxeSimulator(runner, "", "", "", "", "")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 }
 
+func TestResolveAxeSimulator_LastUsedPriority(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	store, err := NewConfigStore()
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+	const project = "/src/App.xcodeproj"
+	if err := store.SetDefault("DEF"); err != nil {
+		t.Fatalf("SetDefault: %v", err)
+	}
+	if err := store.SetLastUsed(project, "LAST"); err != nil {
+		t.Fatalf("SetLastUsed: %v", err)
+	}
+
+	resolve := func(devices []simDevice) string {
+		t.Helper()
+		got, err := ResolveAxeSimulator(&simFakeSimctlRunner{devices: devices}, "", "", "", "", project)
+		if err != nil {
+			t.Fatalf("ResolveAxeSimulator: %v", err)
+		}
+		return got.UDID
+	}
+
+	// The global default outranks the project's last-used device.
+	if got := resolve([]simDevice{
+		{UDID: "ANY", State: "Shutdown"},
+		{UDID: "LAST", State: "Shutdown"},
+		{UDID: "DEF", State: "Shutdown"},
+	}); got != "DEF" {
+		t.Errorf("expected default DEF, got %s", got)
+	}
+
+	// The last-used device outranks the first Shutdown device.
+	if got := resolve([]simDevice{
+		{UDID: "ANY", State: "Shutdown"},
+		{UDID: "LAST", State: "Shutdown"},
+		{UDID: "DEF", State: "Booted"},
+	}); got != "LAST" {
+		t.Errorf("expected last-used LAST, got %s", got)
+	}
+
+	// A deleted last-used device falls through and is forgotten.
+	if got := resolve([]simDevice{
+		{UDID: "ANY", State: "Shutdown"},
+	}); got != "ANY" {
+		t.Errorf("expected first Shutdown ANY, got %s", got)
+	}
+	last, err := store.GetLastUsed(project)
+	if err != nil {
+		t.Fatalf("GetLastUsed: %v", err)
+	}
+	if last != "" {
+		t.Errorf("stale last-used record should be cleared, got %q", last)
+	}
+}
+
+func TestResolveAxeSimulator_Source(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AXE_DEVICE_SET", t.TempDir())
+	store, err := NewConfigStore()
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+	const project = "/src/App.xcodeproj"
+	if err := store.SetDefault("DEF"); err != nil {
+		t.Fatalf("SetDefault: %v", err)
+	}
+	if err := store.SetLastUsed(project, "LAST"); err != nil {
+		t.Fatalf("SetLastUsed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		preferred string
+		devices   []simDevice
+		wantUDID  string
+		want      SimulatorSource
+	}{
+		{
+			name:      "specified",
+			preferred: "ANY",
+			devices:   []simDevice{{UDID: "ANY", State: "Shutdown"}, {UDID: "DEF", State: "Shutdown"}},
+			wantUDID:  "ANY",
+			want:      SourceSpecified,
+		},
+		{
+			name:     "default",
+			devices:  []simDevice{{UDID: "LAST", State: "Shutdown"}, {UDID: "DEF", State: "Shutdown"}},
+			wantUDID: "DEF",
+			want:     SourceDefault,
+		},
+		{
+			name:     "last used",
+			devices:  []simDevice{{UDID: "ANY", State: "Shutdown"}, {UDID: "LAST", State: "Shutdown"}, {UDID: "DEF", State: "Booted"}},
+			wantUDID: "LAST",
+			want:     SourceLastUsed,
+		},
+		{
+			name:     "reused",
+			devices:  []simDevice{{UDID: "ANY", State: "Shutdown"}, {UDID: "LAST", State: "Booted"}, {UDID: "DEF", State: "Booted"}},
+			wantUDID: "ANY",
+			want:     SourceReused,
+		},
+		{
+			name:     "created",
+			devices:  []simDevice{{UDID: "DEF", State: "Booted"}},
+			wantUDID: "NEW-1",
+			want:     SourceCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &simFakeSimctlRunner{
+				devices: tt.devices,
+				allDevicesJSON: []byte(`{
+					"devices": {
+						"com.apple.CoreSimulator.SimRuntime.iOS-18-2": [
+							{"name": "iPhone 16 Pro", "udid": "SRC-1", "state": "Shutdown",
+							 "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro"}
+						]
+					}
+				}`),
+				createdUDID: "NEW-1",
+			}
+			got, err := ResolveAxeSimulator(runner, tt.preferred, "", "", "", project)
+			if err != nil {
+				t.Fatalf("ResolveAxeSimulator: %v", err)
+			}
+			if got.UDID != tt.wantUDID || got.Source != tt.want {
+				t.Errorf("got %s (%s), want %s (%s)", got.UDID, got.Source, tt.wantUDID, tt.want)
+			}
+			if got.Created() != (tt.want == SourceCreated) {
+				t.Errorf("Created() = %v for source %s", got.Created(), got.Source)
+			}
+		})
+	}
+}
+
+// testRuntimesJSON lists iOS 17.0 and iOS 18.2 as installed runtimes.
+var testRuntimesJSON = []byte(`{
+	"runtimes": [
+		{"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-17-0", "name": "iOS 17.0"},
+		{"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-18-2", "name": "iOS 18.2"}
+	]
+}`)
+
+func TestMatchRuntime(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"iOS 18.2", "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+		{"ios 17.0", "com.apple.CoreSimulator.SimRuntime.iOS-17-0"},
+		{"com.apple.CoreSimulator.SimRuntime.iOS-18-2", "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchRuntime(testRuntimesJSON, tt.name)
+			if err != nil {
+				t.Fatalf("matchRuntime: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matchRuntime(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRuntime_NotInstalled(t *testing.T) {
+	_, err := matchRuntime(testRuntimesJSON, "iOS 16.4")
+	if !errors.Is(err, ErrNoSimulator) {
+		t.Fatalf("expected ErrNoSimulator, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "iOS 17.0, iOS 18.2") {
+		t.Errorf("error should list available runtimes, got %q", err)
+	}
+}
+
+func TestSelectLatestIPhone_Runtime(t *testing.T) {
+	simctlJSON := []byte(`{
+		"devices": {
+			"com.apple.CoreSimulator.SimRuntime.iOS-17-0": [
+				{"name": "iPhone 15", "udid": "AAA", "state": "Shutdown"}
+			],
+			"com.apple.CoreSimulator.SimRuntime.iOS-18-2": [
+				{"name": "iPhone 16", "udid": "BBB", "state": "Shutdown"}
+			]
+		}
+	}`)
+
+	best, runtime, err := selectLatestDevice(simctlJSON, "", "com.apple.CoreSimulator.SimRuntime.iOS-17-0")
+	if err != nil {
+		t.Fatalf("selectLatestDevice: %v", err)
+	}
+	if best.UDID != "AAA" || runtime != "com.apple.CoreSimulator.SimRuntime.iOS-17-0" {
+		t.Errorf("got %s on %s, want AAA on iOS-17-0", best.UDID, runtime)
+	}
+
+	_, _, err = selectLatestDevice(simctlJSON, "", "com.apple.CoreSimulator.SimRuntime.iOS-16-4")
+	if !errors.Is(err, ErrNoSimulator) {
+		t.Errorf("expected ErrNoSimulator for runtime without iPhones, got %v", err)
+	}
+}
+
+func TestResolveAxeSimulator_RuntimeFiltersDevices(t *testing.T) {
+	runner := &simFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 15 (1)", UDID: "OLD", State: "Shutdown", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-17-0"},
+			{Name: "axe iPhone 16 (1)", UDID: "NEW", State: "Shutdown", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+		},
+		runtimesJSON: testRuntimesJSON,
+	}
+
+	got, err := ResolveAxeSimulator(runner, "", "iOS 18.2", "", "", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if got.UDID != "NEW" {
+		t.Errorf("expected NEW (iOS 18.2), got %s", got.UDID)
+	}
+}
+
+func TestResolveAxeSimulator_RuntimeAutoCreate(t *testing.T) {
+	runner := &simFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 (1)", UDID: "NEW", State: "Shutdown", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+		},
+		allDevicesJSON: []byte(`{
+			"devices": {
+				"com.apple.CoreSimulator.SimRuntime.iOS-17-0": [
+					{"name": "iPhone 15", "udid": "SRC-17", "state": "Shutdown",
+					 "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-15"}
+				],
+				"com.apple.CoreSimulator.SimRuntime.iOS-18-2": [
+					{"name": "iPhone 16", "udid": "SRC-18", "state": "Shutdown",
+					 "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16"}
+				]
+			}
+		}`),
+		runtimesJSON: testRuntimesJSON,
+		createdUDID:  "CREATED-17",
+	}
+
+	got, err := ResolveAxeSimulator(runner, "", "iOS 17.0", "", "", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if got.UDID != "CREATED-17" {
+		t.Errorf("expected a new iOS 17.0 device, got %s", got.UDID)
+	}
+	if runner.createdRuntime != "com.apple.CoreSimulator.SimRuntime.iOS-17-0" {
+		t.Errorf("created on runtime %q, want iOS-17-0", runner.createdRuntime)
+	}
+}
+
+func TestResolveAxeSimulator_FamilyFiltersDevices(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	runner := &simFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 (1)", UDID: "PHONE", State: "Shutdown"},
+			{Name: "axe iPad Air 11-inch (M2) (1)", UDID: "PAD", State: "Shutdown"},
+		},
+	}
+
+	got, err := ResolveAxeSimulator(runner, "", "", "ipad", "", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if got.UDID != "PAD" {
+		t.Errorf("expected the iPad, got %s", got.UDID)
+	}
+}
+
+func TestResolveAxeSimulator_FamilyAutoCreate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	runner := &simFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 (1)", UDID: "PHONE", State: "Shutdown"},
+		},
+		allDevicesJSON: []byte(`{
+			"devices": {
+				"com.apple.CoreSimulator.SimRuntime.iOS-18-2": [
+					{"name": "iPhone 16 Pro", "udid": "SRC-PHONE", "state": "Shutdown",
+					 "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro"},
+					{"name": "iPad Air 11-inch (M2)", "udid": "SRC-PAD", "state": "Shutdown",
+					 "deviceTypeIdentifier": "com.apple.CoreSimulator.SimDeviceType.iPad-Air-11-inch-M2"}
+				]
+			}
+		}`),
+		createdUDID: "CREATED-PAD",
+	}
+
+	got, err := ResolveAxeSimulator(runner, "", "", FamilyIPad, "", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if got.UDID != "CREATED-PAD" || got.Source != SourceCreated {
+		t.Errorf("expected a new iPad, got %s (%s)", got.UDID, got.Source)
+	}
+	if runner.createdDeviceType != "com.apple.CoreSimulator.SimDeviceType.iPad-Air-11-inch-M2" {
+		t.Errorf("created device type %q, want the iPad", runner.createdDeviceType)
+	}
+}
+
+func TestResolveAxeSimulator_UnknownFamily(t *testing.T) {
+	_, err := ResolveAxeSimulator(&simFakeSimctlRunner{}, "", "", "watch", "", "")
+	if err == nil || !strings.Contains(err.Error(), "unknown device family") {
+		t.Errorf("expected an unknown family error, got %v", err)
+	}
+}
+
+// testAvailable has iPhone 15 on iOS 17.0 and iOS 18.2, iPhone 16 on
+// iOS 18.2 only, and an iPad on iOS 18.2.
+var testAvailable = []AvailableDeviceType{
+	{Identifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-15", Name: "iPhone 15", Runtimes: []AvailableRuntime{
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-17-0", Name: "iOS 17.0"},
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-18-2", Name: "iOS 18.2"},
+	}},
+	{Identifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-16", Name: "iPhone 16", Runtimes: []AvailableRuntime{
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-18-2", Name: "iOS 18.2"},
+	}},
+	{Identifier: "com.apple.CoreSimulator.SimDeviceType.iPad-Air-11-inch-M2", Name: "iPad Air 11-inch (M2)", Runtimes: []AvailableRuntime{
+		{Identifier: "com.apple.CoreSimulator.SimRuntime.iOS-18-2", Name: "iOS 18.2"},
+	}},
+}
+
+func TestMatchDeviceType(t *testing.T) {
+	tests := []struct {
+		name, runtimeID string
+		want            string
+		wantRuntimes    int
+	}{
+		{"iPhone 15", "", "com.apple.CoreSimulator.SimDeviceType.iPhone-15", 2},
+		{"iphone 15", "com.apple.CoreSimulator.SimRuntime.iOS-17-0", "com.apple.CoreSimulator.SimDeviceType.iPhone-15", 1},
+		{"com.apple.CoreSimulator.SimDeviceType.iPhone-16", "", "com.apple.CoreSimulator.SimDeviceType.iPhone-16", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchDeviceType(testAvailable, tt.name, tt.runtimeID)
+			if err != nil {
+				t.Fatalf("matchDeviceType: %v", err)
+			}
+			if got.Identifier != tt.want || len(got.Runtimes) != tt.wantRuntimes {
+				t.Errorf("got %s with %d runtimes, want %s with %d", got.Identifier, len(got.Runtimes), tt.want, tt.wantRuntimes)
+			}
+		})
+	}
+}
+
+func TestMatchDeviceType_NotAvailable(t *testing.T) {
+	tests := []struct {
+		name, runtimeID string
+		want            string
+	}{
+		{"iPhone 1", "", `device type "iPhone 1" is not available; close matches: iPhone 15, iPhone 16`},
+		{"Apple Watch", "", `device type "Apple Watch" is not available`},
+		{"iPhone 16", "com.apple.CoreSimulator.SimRuntime.iOS-17-0",
+			`device type "iPhone 16" is not available on iOS 17.0 (it is on iOS 18.2); close matches: iPhone 15`},
+		{"iPad", "com.apple.CoreSimulator.SimRuntime.iOS-18-2",
+			`device type "iPad" is not available on iOS 18.2; close matches: iPad Air 11-inch (M2)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := matchDeviceType(testAvailable, tt.name, tt.runtimeID)
+			if !errors.Is(err, ErrNoSimulator) {
+				t.Fatalf("expected ErrNoSimulator, got %v", err)
+			}
+			if !strings.HasSuffix(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to end with %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAxeSimulator_DeviceType(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	runner := &simFakeSimctlRunner{
+		devices: []simDevice{
+			{Name: "axe iPhone 16 (1)", UDID: "SIXTEEN", State: "Shutdown",
+				DeviceTypeIdentifier: "com.apple.CoreSimulator.SimDeviceType.iPhone-16", RuntimeID: "com.apple.CoreSimulator.SimRuntime.iOS-18-2"},
+		},
+		runtimesJSON: []byte(`{
+			"runtimes": [
+				{"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-17-0", "name": "iOS 17.0",
+				 "supportedDeviceTypes": [{"identifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-15"}]},
+				{"identifier": "com.apple.CoreSimulator.SimRuntime.iOS-18-2", "name": "iOS 18.2",
+				 "supportedDeviceTypes": [{"identifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-15"},
+				                          {"identifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16"}]}
+			]
+		}`),
+		deviceTypesJSON: []byte(`{
+			"devicetypes": [
+				{"identifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-15", "name": "iPhone 15"},
+				{"identifier": "com.apple.CoreSimulator.SimDeviceType.iPhone-16", "name": "iPhone 16"}
+			]
+		}`),
+		allDevicesJSON: []byte(`{"devices": {}}`),
+		createdUDID:    "CREATED-15",
+	}
+
+	// The iPhone 16 in the axe set is skipped and an iPhone 15 is created
+	// on the newest runtime that has it.
+	got, err := ResolveAxeSimulator(runner, "", "", "", "iPhone 15", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if got.UDID != "CREATED-15" {
+		t.Errorf("expected a new iPhone 15, got %s (%s)", got.UDID, got.Source)
+	}
+	if runner.createdDeviceType != "com.apple.CoreSimulator.SimDeviceType.iPhone-15" ||
+		runner.createdRuntime != "com.apple.CoreSimulator.SimRuntime.iOS-18-2" {
+		t.Errorf("created %s on %s, want iPhone-15 on iOS-18-2", runner.createdDeviceType, runner.createdRuntime)
+	}
+
+	got, err = ResolveAxeSimulator(runner, "", "iOS 17.0", "", "iPhone 15", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if runner.createdRuntime != "com.apple.CoreSimulator.SimRuntime.iOS-17-0" {
+		t.Errorf("created on %s, want iOS-17-0", runner.createdRuntime)
+	}
+
+	got, err = ResolveAxeSimulator(runner, "", "", "", "iPhone 16", "")
+	if err != nil {
+		t.Fatalf("ResolveAxeSimulator: %v", err)
+	}
+	if got.UDID != "SIXTEEN" {
+		t.Errorf("expected the existing iPhone 16, got %s", got.UDID)
+	}
+
+	_, err = ResolveAxeSimulator(runner, "", "iOS 17.0", "", "iPhone 16", "")
+	if !errors.Is(err, ErrNoSimulator) || !strings.Contains(err.Error(), "close matches: iPhone 15") {
+		t.Errorf("expected the unavailable combination to list close matches, got %v", err)
+	}
+
+	_, err = ResolveAxeSimulator(runner, "", "", FamilyIPad, "iPhone 15", "")
+	if err == nil || !strings.Contains(err.Error(), "is not an iPad") {
+		t.Errorf("expected a family mismatch error, got %v", err)
+	}
+}
+
+func TestResolveAxeSimulator_RuntimeNotInstalled(t *testing.T) {
+	runner := &simFakeSimctlRunner{runtimesJSON: testRuntimesJSON}
+
+	_, err := ResolveAxeSimulator(runner, "", "iOS 16.4", "", "", "")
+	if !errors.Is(err, ErrNoSimulator) {
+		t.Fatalf("expected ErrNoSimulator, got %v", err)
+	}
+}
+
+// tracedSimctlRunner is a fakeSimctlRunner whose ListRuntimes creates the
+// command RealSimctlRunner runs, which traces it, without running it.
+type tracedSimctlRunner struct {
+	*fakeSimctlRunner
+}
+
+func (r tracedSimctlRunner) ListRuntimes(ctx context.Context) ([]byte, error) {
+	procgroup.Command(ctx, "xcrun", "simctl", "list", "runtimes", "available", "--json")
+	return r.fakeSimctlRunner.ListRuntimes(ctx)
+}
+
+func TestResolveRuntime_TracesSimctl(t *testing.T) {
+	var buf bytes.Buffer
+	t.Cleanup(procgroup.EnableTrace(&buf))
+
+	_, _ = ResolveRuntime(tracedSimctlRunner{newFakeSimctlRunner()}, "iOS 18.2")
+
+	got := buf.String()
+	if !strings.Contains(got, `cmd="xcrun simctl list runtimes available --json"`) {
+		t.Errorf("simctl call not traced, got %q", got)
+	}
+	if !strings.Contains(got, "timeout=") {
+		t.Errorf("trace should carry the simctl timeout, got %q", got)
+	}
+}
+
 func TestParseDevicesJSON(t *testing.T) {
 	data := []byte(`{
 		"devices": {
@@ -407,7 +992,7 @@ func TestFindDefaultDeviceSpec(t *testing.T) {
 		}`),
 	}
 
-	deviceType, runtime, err := FindDefaultDeviceSpec(runner)
+	deviceType, runtime, err := FindDefaultDeviceSpec(runner, "", "", "")
 	if err != nil {
 		t.Fatalf("FindDefaultDeviceSpec: %v", err)
 	}
@@ -431,10 +1016,13 @@ func TestFindDefaultDeviceSpec_NoIPhone(t *testing.T) {
 		}`),
 	}
 
-	_, _, err := FindDefaultDeviceSpec(runner)
+	_, _, err := FindDefaultDeviceSpec(runner, "", "", "")
 	if err == nil {
 		t.Fatal("expected error when no iPhone found, got nil")
 	}
+	if !errors.Is(err, ErrNoSimulator) {
+		t.Errorf("expected ErrNoSimulator, got %v", err)
+	}
 }
 
 func TestParseDevicesJSON_MalformedJSON(t *testing.T) {
@@ -461,17 +1049,17 @@ func TestResolveAxeSimulator_PreferredUDID_FallbackToStandardSet(t *testing.T) {
 		}`),
 	}
 
-	udid, deviceSetPath, isExternal, err := ResolveAxeSimulator(runner, "STD-UUID")
+	got, err := ResolveAxeSimulator(runner, "STD-UUID", "", "", "", "")
 	if err != nil {
 		t.Fatalf("ResolveAxeSimulator: %v", err)
 	}
-	if udid != "STD-UUID" {
-		t.Errorf("expected STD-UUID, got %s", udid)
+	if got.UDID != "STD-UUID" {
+		t.Errorf("expected STD-UUID, got %s", got.UDID)
 	}
-	if deviceSetPath != "" {
-		t.Errorf("expected empty deviceSetPath for standard set device, got %q", deviceSetPath)
+	if got.DeviceSetPath != "" {
+		t.Errorf("expected empty deviceSetPath for standard set device, got %q", got.DeviceSetPath)
 	}
-	if !isExternal {
+	if !got.IsExternal {
 		t.Error("expected isExternal=true for standard set device")
 	}
 }
@@ -491,7 +1079,7 @@ func TestResolveAxeSimulator_PreferredUDID_NotFoundAnywhere(t *testing.T) {
 		}`),
 	}
 
-	_, _, _, err := ResolveAxeSimulator(runner, "NONEXISTENT")
+	_, err := ResolveAxeSimulator(runner, "NONEXISTENT", "", "", "", "")
 	if err == nil {
 		t.Fatal("expected error when UDID not found in either set, got nil")
 	}
@@ -499,3 +1087,80 @@ func TestResolveAxeSimulator_PreferredUDID_NotFoundAnywhere(t *testing.T) {
 		t.Errorf("expected 'not found' in error message, got: %s", err.Error())
 	}
 }
+
+// bootingSimctlRunner reports the next entry of states on each device
+// listing, simulating a device transitioning Shutdown → Booting → Booted.
+type bootingSimctlRunner struct {
+	simFakeSimctlRunner
+	udid   string
+	states []string
+	polls  int
+}
+
+func (f *bootingSimctlRunner) next() []simDevice {
+	state := f.states[min(f.polls, len(f.states)-1)]
+	f.polls++
+	return []simDevice{{Name: "iPhone 16 Pro", UDID: f.udid, State: state, RuntimeID: testRuntime}}
+}
+
+func (f *bootingSimctlRunner) ListDevices(_ context.Context, _ string) ([]simDevice, error) {
+	return f.next(), nil
+}
+
+func (f *bootingSimctlRunner) ListAllDevices(_ context.Context, _ bool) ([]byte, error) {
+	data, _ := json.Marshal(map[string]map[string][]simDevice{
+		"devices": {testRuntime: f.next()},
+	})
+	return data, nil
+}
+
+func TestWaitForBooted(t *testing.T) {
+	orig := bootPollInterval
+	bootPollInterval = time.Millisecond
+	t.Cleanup(func() { bootPollInterval = orig })
+
+	tests := []struct {
+		name          string
+		deviceSetPath string
+		states        []string
+		timeout       time.Duration
+		wantErr       string
+		wantPolls     int
+	}{
+		{name: "already booted", states: []string{"Booted"}, timeout: time.Second, wantPolls: 1},
+		{name: "booting then booted", states: []string{"Shutdown", "Booting", "Booting", "Booted"}, timeout: time.Second, wantPolls: 4},
+		{name: "axe device set", deviceSetPath: "/tmp/axe-set", states: []string{"Booting", "Booted"}, timeout: time.Second, wantPolls: 2},
+		{name: "never boots", states: []string{"Booting"}, timeout: 20 * time.Millisecond, wantErr: `last state "Booting"`},
+		{name: "device missing", states: []string{""}, timeout: time.Second, wantErr: "not found", wantPolls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &bootingSimctlRunner{udid: "AAA", states: tt.states}
+			if tt.states[0] == "" {
+				runner.udid = "OTHER"
+			}
+			err := WaitForBooted(context.Background(), runner, "AAA", tt.deviceSetPath, tt.timeout)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantPolls > 0 && runner.polls != tt.wantPolls {
+				t.Errorf("polls = %d, want %d", runner.polls, tt.wantPolls)
+			}
+		})
+	}
+}
+
+func TestWaitForBooted_ContextCancelled(t *testing.T) {
+	runner := &bootingSimctlRunner{udid: "AAA", states: []string{"Booting"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WaitForBooted(ctx, runner, "AAA", "", time.Minute)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,87 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/k-kohey/axe/internal/procgroup"
+)
+
+// StatusBarOverride describes the status bar shown while capturing
+// screenshots. DefaultStatusBarOverride returns the conventional
+// marketing-style values (9:41, full battery, full signal).
+type StatusBarOverride struct {
+	Time         string // displayed time, e.g. "9:41"
+	BatteryLevel int    // 0-100
+}
+
+// DefaultStatusBarOverride returns a clean status bar configuration.
+func DefaultStatusBarOverride() StatusBarOverride {
+	return StatusBarOverride{
+		Time:         "9:41",
+		BatteryLevel: 100,
+	}
+}
+
+// Validate checks that the override values are accepted by simctl.
+func (o StatusBarOverride) Validate() error {
+	if o.Time == "" {
+		return fmt.Errorf("status bar time must not be empty")
+	}
+	if o.BatteryLevel < 0 || o.BatteryLevel > 100 {
+		return fmt.Errorf("status bar battery level must be between 0 and 100, got %d", o.BatteryLevel)
+	}
+	return nil
+}
+
+// statusBarOverrideArgs builds the xcrun arguments for "simctl status_bar override".
+// Signal and network indicators are always set to full so that screenshots
+// look the same regardless of the host's connectivity.
+func statusBarOverrideArgs(udid, deviceSetPath string, o StatusBarOverride) []string {
+	batteryState := "discharging"
+	if o.BatteryLevel == 100 {
+		batteryState = "charged"
+	}
+	args := []string{"simctl"}
+	if deviceSetPath != "" {
+		args = append(args, "--set", deviceSetPath)
+	}
+	return append(args, "status_bar", udid, "override",
+		"--time", o.Time,
+		"--dataNetwork", "wifi",
+		"--wifiMode", "active",
+		"--wifiBars", "3",
+		"--cellularMode", "active",
+		"--cellularBars", "4",
+		"--batteryState", batteryState,
+		"--batteryLevel", strconv.Itoa(o.BatteryLevel),
+	)
+}
+
+// statusBarClearArgs builds the xcrun arguments for "simctl status_bar clear".
+func statusBarClearArgs(udid, deviceSetPath string) []string {
+	args := []string{"simctl"}
+	if deviceSetPath != "" {
+		args = append(args, "--set", deviceSetPath)
+	}
+	return append(args, "status_bar", udid, "clear")
+}
+
+// OverrideStatusBar applies a status bar override to a booted simulator.
+func OverrideStatusBar(ctx context.Context, udid, deviceSetPath string, o StatusBarOverride) error {
+	out, err := procgroup.Command(ctx, "xcrun", statusBarOverrideArgs(udid, deviceSetPath, o)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("simctl status_bar override: %w\n%s", err, out)
+	}
+	return nil
+}
+
+// ClearStatusBar removes any status bar override from the simulator.
+func ClearStatusBar(ctx context.Context, udid, deviceSetPath string) error {
+	out, err := procgroup.Command(ctx, "xcrun", statusBarClearArgs(udid, deviceSetPath)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("simctl status_bar clear: %w\n%s", err, out)
+	}
+	return nil
+}
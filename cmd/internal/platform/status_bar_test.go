@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStatusBarOverrideArgs_Defaults(t *testing.T) {
+	got := statusBarOverrideArgs("UDID", "/tmp/set", DefaultStatusBarOverride())
+	want := []string{
+		"simctl", "--set", "/tmp/set", "status_bar", "UDID", "override",
+		"--time", "9:41",
+		"--dataNetwork", "wifi",
+		"--wifiMode", "active",
+		"--wifiBars", "3",
+		"--cellularMode", "active",
+		"--cellularBars", "4",
+		"--batteryState", "charged",
+		"--batteryLevel", "100",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("statusBarOverrideArgs() =\n  %v\nwant\n  %v", got, want)
+	}
+}
+
+func TestStatusBarOverrideArgs_Custom(t *testing.T) {
+	got := statusBarOverrideArgs("UDID", "", StatusBarOverride{Time: "10:30", BatteryLevel: 42})
+	if got[1] != "status_bar" {
+		t.Errorf("expected no --set when device set path is empty, got %v", got)
+	}
+	if i := slices.Index(got, "--time"); i < 0 || got[i+1] != "10:30" {
+		t.Errorf("expected --time 10:30, got %v", got)
+	}
+	if i := slices.Index(got, "--batteryLevel"); i < 0 || got[i+1] != "42" {
+		t.Errorf("expected --batteryLevel 42, got %v", got)
+	}
+	if i := slices.Index(got, "--batteryState"); i < 0 || got[i+1] != "discharging" {
+		t.Errorf("expected --batteryState discharging for partial battery, got %v", got)
+	}
+}
+
+func TestStatusBarClearArgs(t *testing.T) {
+	got := statusBarClearArgs("UDID", "/tmp/set")
+	want := []string{"simctl", "--set", "/tmp/set", "status_bar", "UDID", "clear"}
+	if !slices.Equal(got, want) {
+		t.Errorf("statusBarClearArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestStatusBarOverride_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		o       StatusBarOverride
+		wantErr bool
+	}{
+		{"defaults", DefaultStatusBarOverride(), false},
+		{"empty time", StatusBarOverride{BatteryLevel: 50}, true},
+		{"negative battery", StatusBarOverride{Time: "9:41", BatteryLevel: -1}, true},
+		{"battery over 100", StatusBarOverride{Time: "9:41", BatteryLevel: 101}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.o.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
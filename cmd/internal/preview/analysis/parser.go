@@ -23,8 +23,11 @@ var previewableWrapperRewrites = []struct {
 	{from: "@AppStorage", to: "@State"},
 }
 
-// SelectPreview selects a preview block by name or 0-based index string.
+// SelectPreview selects a preview block by name, 0-based index string,
+// /regex/ matched against preview titles, or file:line (see PreviewAtLine).
 // If selector is empty, returns the first block.
+// A regex matching more than one title is an error, since the selection
+// would silently depend on declaration order.
 func SelectPreview(blocks []PreviewBlock, selector string) (PreviewBlock, error) {
 	if len(blocks) == 0 {
 		return PreviewBlock{}, fmt.Errorf("no #Preview blocks found")
@@ -45,6 +48,32 @@ func SelectPreview(blocks []PreviewBlock, selector string) (PreviewBlock, error)
 		return blocks[0], nil
 	}
 
+	re, err := previewPattern(selector)
+	if err != nil {
+		return PreviewBlock{}, err
+	}
+	if re != nil {
+		var matches []int
+		for i, b := range blocks {
+			if re.MatchString(b.Title) {
+				matches = append(matches, i)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return PreviewBlock{}, fmt.Errorf("no preview title matches %s", selector)
+		case 1:
+			return blocks[matches[0]], nil
+		default:
+			titles := make([]string, len(matches))
+			for i, idx := range matches {
+				titles[i] = strconv.Quote(blocks[idx].Title)
+			}
+			return PreviewBlock{}, fmt.Errorf("%s matches %d previews (%s); narrow the pattern or select by index",
+				selector, len(matches), strings.Join(titles, ", "))
+		}
+	}
+
 	// Try as index first
 	if idx, err := strconv.Atoi(selector); err == nil {
 		if idx < 0 || idx >= len(blocks) {
@@ -59,9 +88,67 @@ func SelectPreview(blocks []PreviewBlock, selector string) (PreviewBlock, error)
 			return b, nil
 		}
 	}
+
+	if _, line, ok := ParsePreviewLine(selector); ok {
+		return PreviewAtLine(blocks, line)
+	}
 	return PreviewBlock{}, fmt.Errorf("no preview with title %q found", selector)
 }
 
+// ParsePreviewLine splits a file:line selector, e.g. "Sources/HogeView.swift:42".
+// The file may be empty (":42"). ok is false if selector is not of this form.
+func ParsePreviewLine(selector string) (file string, line int, ok bool) {
+	i := strings.LastIndexByte(selector, ':')
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(selector[i+1:])
+	if err != nil || line < 1 {
+		return "", 0, false
+	}
+	return selector[:i], line, true
+}
+
+// PreviewAtLine returns the preview whose declaration spans line, so that
+// an editor can preview the block under the cursor. If no preview spans it,
+// the nearest preview declared after line is returned.
+func PreviewAtLine(blocks []PreviewBlock, line int) (PreviewBlock, error) {
+	var next *PreviewBlock
+	for i, b := range blocks {
+		if b.StartLine <= line && line <= max(b.EndLine, b.StartLine) {
+			return b, nil
+		}
+		if b.StartLine > line && (next == nil || b.StartLine < next.StartLine) {
+			next = &blocks[i]
+		}
+	}
+	if next == nil {
+		return PreviewBlock{}, fmt.Errorf("no preview at or after line %d", line)
+	}
+	return *next, nil
+}
+
+// ValidatePreviewSelector reports whether selector is usable by
+// SelectPreview without knowing the previews, i.e. that a /regex/
+// selector compiles.
+func ValidatePreviewSelector(selector string) error {
+	_, err := previewPattern(selector)
+	return err
+}
+
+// previewPattern returns the compiled regex for a /regex/ selector, or nil
+// if selector is a plain title or index.
+func previewPattern(selector string) (*regexp.Regexp, error) {
+	if len(selector) < 2 || !strings.HasPrefix(selector, "/") || !strings.HasSuffix(selector, "/") {
+		return nil, nil
+	}
+	re, err := regexp.Compile(selector[1 : len(selector)-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid preview pattern %s: %w", selector, err)
+	}
+	return re, nil
+}
+
 // TransformPreviewBlock splits a #Preview block into @Previewable property
 // declarations and the remaining body source.
 //   - Lines matching `@Previewable <decl>` have the prefix stripped and become properties.
@@ -90,3 +177,111 @@ func TransformPreviewBlock(pb PreviewBlock) TransformedPreview {
 		BodySource: strings.Join(bodyLines, "\n"),
 	}
 }
+
+// CheckPreviewBody reports constructs in a #Preview body that the generated
+// preview wrapper cannot compile. The wrapper's body is a synchronous
+// @MainActor getter, so `await` and `async let` are only valid inside a
+// closure such as `.task { }` or `Task { }`. Catching them here gives a clear
+// error instead of a swiftc diagnostic pointing into the generated thunk.
+func CheckPreviewBody(pb PreviewBlock) error {
+	construct, line, offset, ok := topLevelAsync(pb.Source)
+	if !ok {
+		return nil
+	}
+	// A multi-line body starts on the line after the one holding the
+	// opening brace, which is normally the #Preview line; a single-line
+	// body is on the #Preview line itself.
+	lineNo := pb.StartLine
+	if pb.EndLine > pb.StartLine {
+		lineNo += 1 + offset
+	}
+	name := "#Preview"
+	if pb.Title != "" {
+		name = fmt.Sprintf("#Preview(%q)", pb.Title)
+	}
+	return fmt.Errorf("%s at line %d uses %q outside a closure (%s): preview bodies are synchronous; move async setup into .task { } or Task { }",
+		name, lineNo, construct, strings.TrimSpace(line))
+}
+
+// topLevelAsync finds the first `await` or `async let` in Swift source that
+// is not nested in braces, skipping comments and string literals. It returns
+// the construct, the source line containing it and that line's 0-based
+// index in src.
+func topLevelAsync(src string) (construct, line string, lineIndex int, ok bool) {
+	lineAt := func(i int) string {
+		start := strings.LastIndexByte(src[:i], '\n') + 1
+		end := strings.IndexByte(src[i:], '\n')
+		if end < 0 {
+			return src[start:]
+		}
+		return src[start : i+end]
+	}
+	isIdent := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+
+	depth := 0
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			nl := strings.IndexByte(rest, '\n')
+			if nl < 0 {
+				return "", "", 0, false
+			}
+			i += nl
+		case strings.HasPrefix(rest, "/*"):
+			// Swift block comments nest.
+			nest, j := 1, 2
+			for j < len(rest) && nest > 0 {
+				switch {
+				case strings.HasPrefix(rest[j:], "/*"):
+					nest, j = nest+1, j+2
+				case strings.HasPrefix(rest[j:], "*/"):
+					nest, j = nest-1, j+2
+				default:
+					j++
+				}
+			}
+			i += j
+		case strings.HasPrefix(rest, `"""`):
+			end := strings.Index(rest[3:], `"""`)
+			if end < 0 {
+				return "", "", 0, false
+			}
+			i += 3 + end + 3
+		case rest[0] == '"':
+			j := 1
+			for j < len(rest) && rest[j] != '"' && rest[j] != '\n' {
+				if rest[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			i += j + 1
+		case rest[0] == '{':
+			depth++
+			i++
+		case rest[0] == '}':
+			depth--
+			i++
+		case isIdent(rest[0]):
+			j := 1
+			for j < len(rest) && isIdent(rest[j]) {
+				j++
+			}
+			if depth == 0 {
+				switch word, next := rest[:j], strings.TrimLeft(rest[j:], " \t"); {
+				case word == "await":
+					return "await", lineAt(i), strings.Count(src[:i], "\n"), true
+				case word == "async" && strings.HasPrefix(next, "let") && (len(next) == 3 || !isIdent(next[3])):
+					return "async let", lineAt(i), strings.Count(src[:i], "\n"), true
+				}
+			}
+			i += j
+		default:
+			i++
+		}
+	}
+	return "", "", 0, false
+}
@@ -104,6 +104,7 @@ func convertPreviewBlocks(protoBlocks []*pb.PreviewBlock) []PreviewBlock {
 	for _, block := range protoBlocks {
 		blocks = append(blocks, PreviewBlock{
 			StartLine: int(block.GetStartLine()),
+			EndLine:   int(block.GetEndLine()),
 			Title:     block.GetTitle(),
 			Source:    block.GetSource(),
 		})
@@ -3,6 +3,7 @@ package analysis
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -875,6 +876,9 @@ struct MyView: View {
 	if blocks[0].StartLine != 9 {
 		t.Errorf("StartLine = %d, want 9", blocks[0].StartLine)
 	}
+	if blocks[0].EndLine != 11 {
+		t.Errorf("EndLine = %d, want 11", blocks[0].EndLine)
+	}
 	if blocks[0].Title != "" {
 		t.Errorf("Title = %q, want empty", blocks[0].Title)
 	}
@@ -1024,6 +1028,83 @@ func TestSelectPreview_TitleNotFound(t *testing.T) {
 	}
 }
 
+func TestSelectPreview_Regex(t *testing.T) {
+	blocks := []PreviewBlock{
+		{StartLine: 1, Title: "Light", Source: "ViewA()"},
+		{StartLine: 5, Title: "Dark (ja)", Source: "ViewB()"},
+		{StartLine: 9, Title: "", Source: "ViewC()"},
+	}
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{selector: "/^Dark/", want: "Dark (ja)"},
+		{selector: "/(?i)light/", want: "Light"},
+		{selector: "/^$/", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			b, err := SelectPreview(blocks, tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Title != tt.want {
+				t.Errorf("Title = %q, want %q", b.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectPreview_RegexErrors(t *testing.T) {
+	blocks := []PreviewBlock{
+		{StartLine: 1, Title: "Dark (en)", Source: "ViewA()"},
+		{StartLine: 5, Title: "Dark (ja)", Source: "ViewB()"},
+	}
+	tests := []struct {
+		name     string
+		selector string
+		wantErr  string
+	}{
+		{name: "multiple matches", selector: "/^Dark/", wantErr: "matches 2 previews"},
+		{name: "no match", selector: "/Light/", wantErr: "no preview title matches"},
+		{name: "invalid pattern", selector: "/(/", wantErr: "invalid preview pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SelectPreview(blocks, tt.selector)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectPreview_SlashIsPlainTitle(t *testing.T) {
+	// A lone "/" is not a pattern; it is matched as a title.
+	blocks := []PreviewBlock{
+		{StartLine: 1, Title: "A", Source: "ViewA()"},
+		{StartLine: 5, Title: "/", Source: "ViewB()"},
+	}
+	b, err := SelectPreview(blocks, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Source != "ViewB()" {
+		t.Errorf("Source = %q, want %q", b.Source, "ViewB()")
+	}
+}
+
+func TestValidatePreviewSelector(t *testing.T) {
+	for _, sel := range []string{"", "0", "Dark", "/^Dark/"} {
+		if err := ValidatePreviewSelector(sel); err != nil {
+			t.Errorf("ValidatePreviewSelector(%q) = %v, want nil", sel, err)
+		}
+	}
+	if err := ValidatePreviewSelector("/[/"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
 func TestTransformPreviewBlock_NoPreviewable(t *testing.T) {
 	pb := PreviewBlock{
 		StartLine: 1,
@@ -1813,3 +1894,135 @@ struct HogeView: View {
 		t.Errorf("types count = %d, want 0 with nil cache", len(types))
 	}
 }
+
+func TestCheckPreviewBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		endLine  int
+		wantErr  string
+		wantLine int
+	}{
+		{name: "synchronous", source: "HogeView()"},
+		{name: "await in task modifier", source: "HogeView()\n    .task { await model.load() }"},
+		{name: "await in Task closure", source: "let model = Model()\nTask { await model.load() }\nreturn HogeView(model: model)"},
+		{name: "await in comment", source: "// await model.load()\n/* async let x = f() */\nHogeView()"},
+		{name: "await in string", source: "Text(\"await me\")"},
+		{name: "await in multiline string", source: "Text(\"\"\"\nawait\n\"\"\")"},
+		{name: "async prefix of identifier", source: "let asyncletter = 1\nlet awaited = asyncletter\nHogeView()"},
+		{name: "top-level await", source: "let model = Model()\nawait model.load()\nreturn HogeView(model: model)", wantErr: "await model.load()", wantLine: 12},
+		{name: "async let", source: "async let items = fetch()\nHogeView()", wantErr: "async let items = fetch()", wantLine: 11},
+		{name: "single-line body", source: "await HogeView.make()", endLine: 10, wantErr: "await HogeView.make()", wantLine: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endLine := tt.endLine
+			if endLine == 0 {
+				endLine = 20
+			}
+			err := CheckPreviewBody(PreviewBlock{StartLine: 10, EndLine: endLine, Title: "Loaded", Source: tt.source})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error for top-level async code")
+			}
+			for _, want := range []string{tt.wantErr, `#Preview("Loaded")`, ".task", "at line " + strconv.Itoa(tt.wantLine) + " "} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPreviewAtLine(t *testing.T) {
+	blocks := []PreviewBlock{
+		{StartLine: 10, EndLine: 12, Title: "Light"},
+		{StartLine: 14, EndLine: 18, Title: "Dark"},
+		{StartLine: 20, EndLine: 20, Title: "Compact"},
+	}
+	tests := []struct {
+		line    int
+		want    string
+		wantErr bool
+	}{
+		{line: 10, want: "Light"},   // on the #Preview line
+		{line: 12, want: "Light"},   // on the closing brace
+		{line: 16, want: "Dark"},    // inside the body
+		{line: 20, want: "Compact"}, // single-line preview
+		{line: 1, want: "Light"},    // before all previews: nearest following
+		{line: 13, want: "Dark"},    // between previews: nearest following
+		{line: 21, wantErr: true},   // after the last preview
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.line), func(t *testing.T) {
+			b, err := PreviewAtLine(blocks, tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", b.Title)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Title != tt.want {
+				t.Errorf("Title = %q, want %q", b.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectPreview_ByFileLine(t *testing.T) {
+	blocks := []PreviewBlock{
+		{StartLine: 1, EndLine: 3, Title: "A", Source: "ViewA()"},
+		{StartLine: 5, EndLine: 7, Title: "B", Source: "ViewB()"},
+		{StartLine: 9, EndLine: 11, Title: "Step:1", Source: "ViewC()"},
+	}
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{selector: "Sources/HogeView.swift:6", want: "B"},
+		{selector: ":2", want: "A"},
+		{selector: "/tmp/a:b/HogeView.swift:4", want: "B"},
+		{selector: "Step:1", want: "Step:1"}, // an exact title wins
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			b, err := SelectPreview(blocks, tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Title != tt.want {
+				t.Errorf("Title = %q, want %q", b.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePreviewLine(t *testing.T) {
+	tests := []struct {
+		selector string
+		file     string
+		line     int
+		ok       bool
+	}{
+		{selector: "HogeView.swift:42", file: "HogeView.swift", line: 42, ok: true},
+		{selector: ":7", file: "", line: 7, ok: true},
+		{selector: "Dark Mode", ok: false},
+		{selector: "HogeView.swift:0", ok: false},
+		{selector: "HogeView.swift:x", ok: false},
+	}
+	for _, tt := range tests {
+		file, line, ok := ParsePreviewLine(tt.selector)
+		if file != tt.file || line != tt.line || ok != tt.ok {
+			t.Errorf("ParsePreviewLine(%q) = (%q, %d, %v), want (%q, %d, %v)",
+				tt.selector, file, line, ok, tt.file, tt.line, tt.ok)
+		}
+	}
+}
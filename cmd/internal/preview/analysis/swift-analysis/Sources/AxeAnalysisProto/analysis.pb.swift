@@ -225,6 +225,8 @@ public struct Axe_Analysis_PreviewBlock: Sendable {
 
   public var source: String = String()
 
+  public var endLine: Int32 = 0
+
   public var unknownFields = SwiftProtobuf.UnknownStorage()
 
   public init() {}
@@ -553,7 +555,7 @@ extension Axe_Analysis_TypeInfo: SwiftProtobuf.Message, SwiftProtobuf._MessageIm
 
 extension Axe_Analysis_PreviewBlock: SwiftProtobuf.Message, SwiftProtobuf._MessageImplementationBase, SwiftProtobuf._ProtoNameProviding {
   public static let protoMessageName: String = _protobuf_package + ".PreviewBlock"
-  public static let _protobuf_nameMap = SwiftProtobuf._NameMap(bytecode: "\0\u{3}start_line\0\u{1}title\0\u{1}source\0")
+  public static let _protobuf_nameMap = SwiftProtobuf._NameMap(bytecode: "\0\u{3}start_line\0\u{1}title\0\u{1}source\0\u{3}end_line\0")
 
   public mutating func decodeMessage<D: SwiftProtobuf.Decoder>(decoder: inout D) throws {
     while let fieldNumber = try decoder.nextFieldNumber() {
@@ -564,6 +566,7 @@ extension Axe_Analysis_PreviewBlock: SwiftProtobuf.Message, SwiftProtobuf._Messa
       case 1: try { try decoder.decodeSingularInt32Field(value: &self.startLine) }()
       case 2: try { try decoder.decodeSingularStringField(value: &self.title) }()
       case 3: try { try decoder.decodeSingularStringField(value: &self.source) }()
+      case 4: try { try decoder.decodeSingularInt32Field(value: &self.endLine) }()
       default: break
       }
     }
@@ -579,6 +582,9 @@ extension Axe_Analysis_PreviewBlock: SwiftProtobuf.Message, SwiftProtobuf._Messa
     if !self.source.isEmpty {
       try visitor.visitSingularStringField(value: self.source, fieldNumber: 3)
     }
+    if self.endLine != 0 {
+      try visitor.visitSingularInt32Field(value: self.endLine, fieldNumber: 4)
+    }
     try unknownFields.traverse(visitor: &visitor)
   }
 
@@ -586,6 +592,7 @@ extension Axe_Analysis_PreviewBlock: SwiftProtobuf.Message, SwiftProtobuf._Messa
     if lhs.startLine != rhs.startLine {return false}
     if lhs.title != rhs.title {return false}
     if lhs.source != rhs.source {return false}
+    if lhs.endLine != rhs.endLine {return false}
     if lhs.unknownFields != rhs.unknownFields {return false}
     return true
   }
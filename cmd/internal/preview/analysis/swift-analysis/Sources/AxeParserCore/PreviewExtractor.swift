@@ -50,10 +50,12 @@ final class PreviewExtractor: SyntaxVisitor {
     if let closure = trailingClosure {
       let bodyRange = helper.innerBodyRange(of: closure)
       let bodySource = helper.extractLines(in: bodyRange)
+      let endLine = helper.lineNumber(at: closure.rightBrace.positionAfterSkippingLeadingTrivia)
 
       previews.append(
         PreviewBlock.with {
           $0.startLine = Int32(startLine)
+          $0.endLine = Int32(endLine)
           $0.title = title
           $0.source = bodySource
         })
@@ -64,6 +64,24 @@ struct PreviewExtractorTests {
     #expect(extractor.previews[1].title == "Dark")
   }
 
+  @Test("Records the line range of each #Preview")
+  func previewLineRange() {
+    let source = """
+      #Preview("Light") {
+          Text("A")
+      }
+
+      #Preview("Dark") { Text("B") }
+      """
+    let extractor = extract(from: source)
+
+    #expect(extractor.previews.count == 2)
+    #expect(extractor.previews[0].startLine == Int32(1))
+    #expect(extractor.previews[0].endLine == Int32(3))
+    #expect(extractor.previews[1].startLine == Int32(5))
+    #expect(extractor.previews[1].endLine == Int32(5))
+  }
+
   @Test("Handles traits argument correctly")
   func previewWithTraits() {
     let source = """
@@ -49,6 +49,7 @@ type FileThunkData struct {
 // PreviewBlock describes a #Preview { ... } block in the source.
 type PreviewBlock struct {
 	StartLine int
+	EndLine   int    // line of the closing brace
 	Title     string // e.g. "Dark Mode", empty for unnamed
 	Source    string
 }
@@ -416,6 +416,7 @@ type PreviewBlock struct {
 	StartLine     int32                  `protobuf:"varint,1,opt,name=start_line,json=startLine,proto3" json:"start_line,omitempty"`
 	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
 	Source        string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	EndLine       int32                  `protobuf:"varint,4,opt,name=end_line,json=endLine,proto3" json:"end_line,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -471,6 +472,13 @@ func (x *PreviewBlock) GetSource() string {
 	return ""
 }
 
+func (x *PreviewBlock) GetEndLine() int32 {
+	if x != nil {
+		return x.EndLine
+	}
+	return 0
+}
+
 type MemberSource struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TypeName      string                 `protobuf:"bytes,1,opt,name=type_name,json=typeName,proto3" json:"type_name,omitempty"`
@@ -1046,12 +1054,13 @@ const file_analysis_proto_rawDesc = "" +
 	"\n" +
 	"properties\x18\x05 \x03(\v2\x1a.axe.analysis.PropertyInfoR\n" +
 	"properties\x122\n" +
-	"\amethods\x18\x06 \x03(\v2\x18.axe.analysis.MethodInfoR\amethods\"[\n" +
+	"\amethods\x18\x06 \x03(\v2\x18.axe.analysis.MethodInfoR\amethods\"v\n" +
 	"\fPreviewBlock\x12\x1d\n" +
 	"\n" +
 	"start_line\x18\x01 \x01(\x05R\tstartLine\x12\x14\n" +
 	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
-	"\x06source\x18\x03 \x01(\tR\x06source\"\x93\x02\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\x12\x19\n" +
+	"\bend_line\x18\x04 \x01(\x05R\aendLine\"\x93\x02\n" +
 	"\fMemberSource\x12\x1b\n" +
 	"\ttype_name\x18\x01 \x01(\tR\btypeName\x12\x12\n" +
 	"\x04line\x18\x02 \x01(\x05R\x04line\x122\n" +
@@ -0,0 +1,107 @@
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+)
+
+// appLogTimeLayout is the timestamp format of "log stream --style ndjson".
+const appLogTimeLayout = "2006-01-02 15:04:05.000000-0700"
+
+// appLogLine is one entry printed by "log stream --style ndjson".
+type appLogLine struct {
+	Timestamp   string `json:"timestamp"`
+	MessageType string `json:"messageType"` // "Default", "Info", "Debug", "Error", or "Fault"
+	Subsystem   string `json:"subsystem"`
+	Category    string `json:"category"`
+	Message     string `json:"eventMessage"`
+	PID         uint32 `json:"processID"`
+}
+
+// appLogPredicate returns the log predicate that selects the entries of the
+// previewed app: everything its process logs (NSLog, os_log, Logger), plus
+// entries from other processes under the app's bundle ID as subsystem.
+// Both bundle IDs are matched because apps commonly use either
+// Bundle.main.bundleIdentifier (the axe-prefixed one) or a literal.
+func appLogPredicate(bs *build.Settings) string {
+	return fmt.Sprintf("process == %s OR subsystem IN {%s, %s}",
+		predicateString(bs.ExecutableName), predicateString(bs.BundleID), predicateString(bs.OriginalBundleID))
+}
+
+// predicateString quotes s as an NSPredicate string literal.
+func predicateString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// appLogArgs returns the command run inside the simulator (via simctl
+// spawn) to stream the app's log. Debug entries are left out because the
+// system frameworks in the app process emit a steady stream of them.
+func appLogArgs(bs *build.Settings) []string {
+	return []string{"log", "stream", "--style", "ndjson", "--level", "info", "--predicate", appLogPredicate(bs)}
+}
+
+// parseAppLogLine decodes a line of log stream output. It returns false for
+// lines that are not log entries, such as the "Filtering the log data"
+// banner printed first.
+func parseAppLogLine(line []byte) (appLogLine, bool) {
+	var l appLogLine
+	if err := json.Unmarshal(line, &l); err != nil || l.Timestamp == "" {
+		return appLogLine{}, false
+	}
+	return l, true
+}
+
+// event converts l to a LogStream event payload.
+func (l appLogLine) event() *pb.LogStream {
+	e := &pb.LogStream{
+		Message:   l.Message,
+		Level:     strings.ToLower(l.MessageType),
+		Subsystem: l.Subsystem,
+		Category:  l.Category,
+		Pid:       l.PID,
+	}
+	if t, err := time.Parse(appLogTimeLayout, l.Timestamp); err == nil {
+		e.Timestamp = float64(t.UnixMilli())
+	}
+	return e
+}
+
+// String formats l for terminal output, e.g.
+// "10:20:30.123 [error] com.example.app/network: request failed".
+func (l appLogLine) String() string {
+	var b strings.Builder
+	if t, err := time.Parse(appLogTimeLayout, l.Timestamp); err == nil {
+		b.WriteString(t.Format("15:04:05.000 "))
+	}
+	fmt.Fprintf(&b, "[%s] ", strings.ToLower(l.MessageType))
+	if l.Subsystem != "" {
+		b.WriteString(l.Subsystem)
+		if l.Category != "" {
+			b.WriteString("/" + l.Category)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(l.Message)
+	return b.String()
+}
+
+// followAppLogs streams the app's log on device and passes each entry to
+// emit until ctx is cancelled. The predicate matches by process name, so
+// entries keep flowing across relaunches of the app.
+func followAppLogs(ctx context.Context, ls LogStreamer, device, deviceSetPath string, bs *build.Settings, emit func(appLogLine)) {
+	err := ls.Stream(ctx, device, deviceSetPath, appLogArgs(bs), func(line []byte) {
+		if l, ok := parseAppLogLine(line); ok {
+			emit(l)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("App log stream ended", "device", device, "err", err)
+	}
+}
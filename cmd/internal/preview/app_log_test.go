@@ -0,0 +1,93 @@
+package preview
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+)
+
+func TestAppLogArgs(t *testing.T) {
+	bs := &build.Settings{
+		ExecutableName:   "Hoge App",
+		BundleID:         "axe.com.example.hoge",
+		OriginalBundleID: "com.example.hoge",
+	}
+
+	got := appLogArgs(bs)
+	want := []string{
+		"log", "stream", "--style", "ndjson", "--level", "info",
+		"--predicate", `process == "Hoge App" OR subsystem IN {"axe.com.example.hoge", "com.example.hoge"}`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("appLogArgs =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestAppLogPredicate_Escapes(t *testing.T) {
+	bs := &build.Settings{ExecutableName: `My "Quoted" \App`, BundleID: "axe.a", OriginalBundleID: "a"}
+
+	want := `process == "My \"Quoted\" \\App" OR subsystem IN {"axe.a", "a"}`
+	if got := appLogPredicate(bs); got != want {
+		t.Errorf("appLogPredicate = %s, want %s", got, want)
+	}
+}
+
+func TestParseAppLogLine(t *testing.T) {
+	if _, ok := parseAppLogLine([]byte(`Filtering the log data using "process == \"Hoge\""`)); ok {
+		t.Error("banner line parsed as a log entry")
+	}
+
+	l, ok := parseAppLogLine([]byte(`{"timestamp":"2026-10-16 10:20:30.123456+0900","messageType":"Error","subsystem":"com.example.hoge","category":"network","eventMessage":"request failed","processID":4242}`))
+	if !ok {
+		t.Fatal("log entry not parsed")
+	}
+	if got, want := l.String(), "10:20:30.123 [error] com.example.hoge/network: request failed"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	e := l.event()
+	if e.GetLevel() != "error" || e.GetMessage() != "request failed" || e.GetPid() != 4242 {
+		t.Errorf("unexpected event: %v", e)
+	}
+	// 2026-10-16 01:20:30.123 UTC
+	if e.GetTimestamp() != 1792113630123 {
+		t.Errorf("Timestamp = %v, want 1792113630123", e.GetTimestamp())
+	}
+}
+
+// fakeLogStreamer replays lines to onLine and records the command.
+type fakeLogStreamer struct {
+	lines  []string
+	device string
+	args   []string
+}
+
+func (f *fakeLogStreamer) Stream(_ context.Context, device, _ string, args []string, onLine func([]byte)) error {
+	f.device, f.args = device, args
+	for _, l := range f.lines {
+		onLine([]byte(l))
+	}
+	return nil
+}
+
+func TestFollowAppLogs(t *testing.T) {
+	ls := &fakeLogStreamer{lines: []string{
+		"Filtering the log data using ...",
+		`{"timestamp":"2026-10-16 10:20:30.000000+0900","messageType":"Default","eventMessage":"hello"}`,
+	}}
+	bs := &build.Settings{ExecutableName: "Hoge", BundleID: "axe.hoge", OriginalBundleID: "hoge"}
+
+	var got []string
+	followAppLogs(context.Background(), ls, "UDID", "", bs, func(l appLogLine) {
+		got = append(got, l.String())
+	})
+
+	if ls.device != "UDID" || !slices.Equal(ls.args, appLogArgs(bs)) {
+		t.Errorf("Stream called with device %q args %q", ls.device, ls.args)
+	}
+	if want := []string{"10:20:30.000 [default] hello"}; !slices.Equal(got, want) {
+		t.Errorf("emitted %q, want %q", got, want)
+	}
+}
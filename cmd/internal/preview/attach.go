@@ -0,0 +1,120 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/k-kohey/axe/internal/preview/codegen"
+)
+
+// RunAttach injects the preview thunk into the already running app process
+// pid instead of installing and launching a fresh instance. The process
+// must run the project's app on a booted simulator and must have been
+// launched by axe preview, so that its loader socket is listening. The
+// build of that earlier run is reused, and the thunk is removed again once
+// injected unless KeepThunk is set.
+func RunAttach(opts RunOptions, pid int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	proc, deviceSetPath, err := platform.ValidateSimulatorPID(ctx, &platform.RealSimctlRunner{}, &platform.RealProcessLister{}, pid)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Attaching to process", "pid", pid, "app", proc.App, "device", proc.DeviceUDID)
+
+	dirs, err := newPreviewDirs(opts.PC, proc.DeviceUDID)
+	if err != nil {
+		return fmt.Errorf("preview dirs: %w", err)
+	}
+	if _, err := os.Stat(dirs.Socket); err != nil {
+		return fmt.Errorf("process %d (%s on %s) has no axe loader socket; only apps launched by axe preview can be attached to: %w", pid, proc.App, proc.DeviceName, err)
+	}
+
+	br, tc, _, _, _ := defaultRunners()
+	result, err := build.Prepare(ctx, opts.PC, dirs.ProjectDirs, build.Reuse, br)
+	if err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+	bs := result.Settings
+	if err := checkAttachApp(proc, bs); err != nil {
+		return err
+	}
+	bs.MockSources = opts.MockSources
+	bs.ThunkImports = opts.ThunkImports
+	bs.KeepThunk = opts.KeepThunk
+	bs.Layout = opts.Layout
+	bs.InitArgs = opts.InitArgs
+	bs.InitArgsFile = opts.SourceFile
+
+	overrides := platform.NewDeviceOverrides(proc.DeviceUDID, deviceSetPath)
+	defer func() {
+		restoreCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		restoreAccessibility(restoreCtx, overrides)
+	}()
+	applyAccessibility(ctx, overrides, opts.Accessibility)
+	// The app may already have loaded thunk_0..N from the session that
+	// launched it, and dlopen caches by path, so use a counter that cannot
+	// collide with a session's.
+	counter := int(time.Now().Unix())
+	if !bs.KeepThunk {
+		// The loader has dlopen'ed the dylib by the time it replies, so
+		// nothing of this counter is needed once RunAttach returns.
+		defer removeThunkFiles(dirs.Thunk, counter)
+	}
+	dylibPath, err := compileMainOnlyPipeline(ctx, opts.SourceFile, bs, dirs, opts.PreviewSelector, counter, tc)
+	if err != nil {
+		return fmt.Errorf("compile thunk: %w", err)
+	}
+	if err := codegen.SendReloadCommand(ctx, dirs.Socket, dylibPath); err != nil {
+		return fmt.Errorf("inject into process %d: %w", pid, err)
+	}
+
+	onReady := opts.OnReady
+	if opts.Record != "" {
+		onReady = recordBeforeReady(opts.Record, opts.RecordDuration, onReady)
+	}
+	if onReady != nil {
+		if err := onReady(ctx, proc.DeviceUDID, deviceSetPath); err != nil {
+			return fmt.Errorf("on-ready: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkAttachApp returns an error unless proc runs the app bs describes,
+// so that a thunk built for this project is never injected into another
+// app that happens to be listening on the same loader socket.
+func checkAttachApp(proc platform.SimProcess, bs *build.Settings) error {
+	product := strings.TrimSuffix(bs.ProductName, ".app")
+	if proc.App == product || proc.App == bs.ExecutableName {
+		return nil
+	}
+	return fmt.Errorf("process %d runs %s.app, not %s of this project", proc.PID, proc.App, bs.ProductName)
+}
+
+// removeThunkFiles deletes the thunk sources and dylib of counter from
+// thunkDir.
+func removeThunkFiles(thunkDir string, counter int) {
+	for _, pattern := range []string{
+		filepath.Join(thunkDir, fmt.Sprintf("thunk_%d_*.swift", counter)),
+		filepath.Join(thunkDir, fmt.Sprintf("thunk_%d.dylib", counter)),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			if err := os.Remove(m); err != nil {
+				slog.Debug("Failed to remove thunk file", "path", m, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package preview
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview/build"
+)
+
+func TestCheckAttachApp(t *testing.T) {
+	bs := &build.Settings{ProductName: "MyApp.app", ExecutableName: "MyApp"}
+	if err := checkAttachApp(platform.SimProcess{PID: 1, App: "MyApp"}, bs); err != nil {
+		t.Errorf("own app rejected: %v", err)
+	}
+	if err := checkAttachApp(platform.SimProcess{PID: 2, App: "OtherApp"}, bs); err == nil {
+		t.Error("another project's app was accepted")
+	}
+}
+
+func TestRemoveThunkFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"thunk_7__main.swift", "thunk_7_0_View.swift", "thunk_7.dylib", "thunk_70.dylib", "thunk_1.dylib"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removeThunkFiles(dir, 7)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	if len(left) != 2 || left[0] != "thunk_1.dylib" || left[1] != "thunk_70.dylib" {
+		t.Errorf("left %v, want only the files of other counters", left)
+	}
+}
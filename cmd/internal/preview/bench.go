@@ -0,0 +1,188 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/k-kohey/axe/internal/preview/runner"
+)
+
+// Phases of the preview pipeline measured by RunBench, in pipeline order.
+const (
+	PhaseResolve    = "resolve"     // resolving the simulator
+	PhaseBuild      = "build"       // xcodebuild, or reusing a previous build
+	PhaseBoot       = "boot"        // booting the simulator (parallel to build)
+	PhaseInject     = "inject"      // install, loader, thunk compile, launch
+	PhaseFirstFrame = "first-frame" // capturing the first rendered frame
+)
+
+// BenchPhases lists the measured phases in pipeline order.
+var BenchPhases = []string{PhaseResolve, PhaseBuild, PhaseBoot, PhaseInject, PhaseFirstFrame}
+
+// phaseTimer accumulates the time spent in each phase of one preview run.
+// It is safe for concurrent use since build and boot run in parallel.
+// A nil *phaseTimer records nothing.
+type phaseTimer struct {
+	mu sync.Mutex
+	d  map[string]time.Duration
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{d: make(map[string]time.Duration)}
+}
+
+// start begins timing phase and returns a function that stops it.
+func (t *phaseTimer) start(phase string) func() {
+	if t == nil {
+		return func() {}
+	}
+	begin := time.Now()
+	return func() { t.add(phase, time.Since(begin)) }
+}
+
+func (t *phaseTimer) add(phase string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.d[phase] += d
+}
+
+func (t *phaseTimer) get(phase string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.d[phase]
+}
+
+// BenchResult holds one sample per phase for every bench iteration. The
+// first iteration is the cold run; the rest are warm runs.
+type BenchResult struct {
+	Iterations int
+	Samples    map[string][]time.Duration // phase -> one duration per iteration
+}
+
+// BenchPhase summarizes one phase of a BenchResult.
+type BenchPhase struct {
+	Phase string
+	Cold  time.Duration
+	Warm  time.Duration // mean of the warm runs; 0 with a single iteration
+	Delta time.Duration // Warm - Cold
+}
+
+// Summary returns the cold, mean warm, and delta timings of each phase in
+// pipeline order, followed by a "total" row.
+func (r *BenchResult) Summary() []BenchPhase {
+	var rows []BenchPhase
+	var total BenchPhase
+	for _, phase := range BenchPhases {
+		samples := r.Samples[phase]
+		if len(samples) == 0 {
+			continue
+		}
+		row := BenchPhase{Phase: phase, Cold: samples[0]}
+		if warm := samples[1:]; len(warm) > 0 {
+			var sum time.Duration
+			for _, d := range warm {
+				sum += d
+			}
+			row.Warm = sum / time.Duration(len(warm))
+			row.Delta = row.Warm - row.Cold
+		}
+		total.Cold += row.Cold
+		total.Warm += row.Warm
+		total.Delta += row.Delta
+		rows = append(rows, row)
+	}
+	total.Phase = "total"
+	return append(rows, total)
+}
+
+// collectBench calls run iterations times with a fresh timer and collects
+// one sample per phase from each call. run receives the zero-based
+// iteration; iteration 0 is the cold run.
+func collectBench(ctx context.Context, iterations int, run func(ctx context.Context, iteration int, t *phaseTimer) error) (*BenchResult, error) {
+	res := &BenchResult{Iterations: iterations, Samples: make(map[string][]time.Duration)}
+	for i := range iterations {
+		t := newPhaseTimer()
+		if err := run(ctx, i, t); err != nil {
+			return nil, fmt.Errorf("bench iteration %d: %w", i+1, err)
+		}
+		for _, phase := range BenchPhases {
+			res.Samples[phase] = append(res.Samples[phase], t.get(phase))
+		}
+	}
+	return res, nil
+}
+
+// RunBench runs the oneshot preview pipeline iterations times and returns
+// the per-phase timings. The first run builds as opts.BuildMode requests
+// (cold); the following runs reuse that build (warm). Each run boots the
+// simulator, captures a frame through opts.OnReady, and tears the session
+// down again.
+func RunBench(opts RunOptions, iterations int) (*BenchResult, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	br, tc, ar, fc, _ := defaultRunners()
+	simctl := &platform.RealSimctlRunner{}
+	step := &stepper{total: iterations}
+	return collectBench(ctx, iterations, func(ctx context.Context, i int, t *phaseTimer) error {
+		label, mode := "Cold run...", opts.BuildMode
+		if i > 0 {
+			label, mode = "Warm run...", build.Reuse
+		}
+		defer step.begin(label)()
+
+		device, deviceSetPath, isExternal := opts.DeviceUDID, opts.DeviceSetPath, false
+		accessibility := opts.Accessibility
+		if device == "" {
+			done := t.start(PhaseResolve)
+			sim, err := platform.ResolveAxeSimulator(simctl, opts.PreferredDevice, opts.Runtime, opts.Family, opts.DeviceType, opts.PC.PrimaryPath())
+			done()
+			if err != nil {
+				return err
+			}
+			device, deviceSetPath, isExternal = sim.UDID, sim.DeviceSetPath, sim.IsExternal
+			accessibility = WithDeviceProfile(accessibility, device)
+		}
+
+		sess, err := NewPreviewSession(ctx, SessionConfig{
+			PC:               opts.PC,
+			DeviceUDID:       device,
+			DeviceSetPath:    deviceSetPath,
+			IsExternalDevice: isExternal,
+			NoHeadless:       opts.NoHeadless,
+			BuildMode:        mode,
+			Accessibility:    accessibility,
+			StatusBar:        opts.StatusBar,
+			MockSources:      opts.MockSources,
+			ThunkImports:     opts.ThunkImports,
+			SeedDir:          opts.SeedDir,
+			KeepThunk:        opts.KeepThunk,
+			Layout:           opts.Layout,
+			InitArgs:         opts.InitArgs,
+			InitArgsFile:     opts.SourceFile,
+			BootTimeout:      opts.BootTimeout,
+			BuildRunner:      br,
+			Toolchain:        tc,
+			AppRunner:        ar,
+			Copier:           fc,
+			Crashes:          &runner.CrashLog{},
+			timer:            t,
+		})
+		if err != nil {
+			return err
+		}
+		defer sess.Close()
+
+		return sess.CapturePreview(ctx, CaptureRequest{
+			SourceFile:      opts.SourceFile,
+			PreviewSelector: opts.PreviewSelector,
+			OnReady:         opts.OnReady,
+		})
+	})
+}
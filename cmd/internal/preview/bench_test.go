@@ -0,0 +1,152 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+)
+
+func TestCollectBench_SamplesPerPhase(t *testing.T) {
+	t.Parallel()
+
+	const iterations = 4
+	var runs []int
+	res, err := collectBench(t.Context(), iterations, func(_ context.Context, i int, pt *phaseTimer) error {
+		runs = append(runs, i)
+		// The cold run builds for 10s; warm runs reuse the build.
+		buildTime := time.Second
+		if i == 0 {
+			buildTime = 10 * time.Second
+		}
+		pt.add(PhaseBuild, buildTime)
+		pt.add(PhaseBoot, 2*time.Second)
+		// Inject is accumulated across session setup and capture.
+		pt.add(PhaseInject, time.Second)
+		pt.add(PhaseInject, time.Duration(i)*time.Second)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("collectBench: %v", err)
+	}
+
+	if len(runs) != iterations || runs[0] != 0 || runs[iterations-1] != iterations-1 {
+		t.Errorf("runs = %v, want 0..%d", runs, iterations-1)
+	}
+	for _, phase := range BenchPhases {
+		if got := len(res.Samples[phase]); got != iterations {
+			t.Errorf("%s: %d samples, want %d", phase, got, iterations)
+		}
+	}
+	if got, want := res.Samples[PhaseInject], []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second}; !slices.Equal(got, want) {
+		t.Errorf("inject samples = %v, want %v", got, want)
+	}
+
+	summary := res.Summary()
+	if len(summary) != len(BenchPhases)+1 || summary[len(summary)-1].Phase != "total" {
+		t.Fatalf("summary = %+v, want one row per phase and a total", summary)
+	}
+	byPhase := make(map[string]BenchPhase)
+	for _, row := range summary {
+		byPhase[row.Phase] = row
+	}
+	if got, want := byPhase[PhaseBuild], (BenchPhase{Phase: PhaseBuild, Cold: 10 * time.Second, Warm: time.Second, Delta: -9 * time.Second}); got != want {
+		t.Errorf("build row = %+v, want %+v", got, want)
+	}
+	if got, want := byPhase[PhaseInject], (BenchPhase{Phase: PhaseInject, Cold: time.Second, Warm: 3 * time.Second, Delta: 2 * time.Second}); got != want {
+		t.Errorf("inject row = %+v, want %+v", got, want)
+	}
+	if got, want := byPhase["total"], (BenchPhase{Phase: "total", Cold: 13 * time.Second, Warm: 6 * time.Second, Delta: -7 * time.Second}); got != want {
+		t.Errorf("total row = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollectBench_StopsOnError(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	_, err := collectBench(t.Context(), 3, func(_ context.Context, i int, _ *phaseTimer) error {
+		calls++
+		if i == 1 {
+			return errors.New("boot failed")
+		}
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "bench iteration 2") || !strings.Contains(err.Error(), "boot failed") {
+		t.Errorf("err = %v, want iteration 2's error", err)
+	}
+	if calls != 2 {
+		t.Errorf("run called %d times, want 2", calls)
+	}
+}
+
+func TestPhaseTimer_Nil(t *testing.T) {
+	t.Parallel()
+
+	var pt *phaseTimer
+	pt.start(PhaseBuild)() // must not panic
+}
+
+func TestNewPreviewSession_RecordsPhaseTimings(t *testing.T) {
+	t.Parallel()
+
+	cfg := setupSessionTest(t)
+
+	tmpDir := t.TempDir()
+	buildDir := filepath.Join(tmpDir, "build")
+	builtProducts := filepath.Join(buildDir, "Build", "Products", "Debug-iphonesimulator")
+	appDir := filepath.Join(builtProducts, "TestModule.app")
+	if err := os.MkdirAll(appDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plistContent := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0"><dict>
+<key>CFBundleIdentifier</key><string>com.example.TestModule</string>
+</dict></plist>`
+	if err := os.WriteFile(filepath.Join(appDir, "Info.plist"), []byte(plistContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := &build.Settings{
+		ModuleName:       "TestModule",
+		BundleID:         "axe.com.example.TestModule",
+		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
+		DeploymentTarget: "17.0",
+		SwiftVersion:     "5.9",
+	}
+	cfg.Copier = &sessionFileCopier{bs: bs, src: appDir}
+	cfg.Preparer = sessionPreparer(t, cfg.PC, buildDir, bs)
+
+	const bootTime = 20 * time.Millisecond
+	cfg.BootFunc = func(_ context.Context, _, _ string, _ bool) (companionProcess, error) {
+		time.Sleep(bootTime)
+		return newSessionFakeCompanion(), nil
+	}
+	cfg.timer = newPhaseTimer()
+
+	sess, err := NewPreviewSession(t.Context(), cfg)
+	if err != nil {
+		t.Fatalf("NewPreviewSession() error: %v", err)
+	}
+	defer sess.Close()
+
+	if got := cfg.timer.get(PhaseBoot); got < bootTime {
+		t.Errorf("boot = %v, want >= %v", got, bootTime)
+	}
+	for _, phase := range []string{PhaseBuild, PhaseInject} {
+		if cfg.timer.get(phase) <= 0 {
+			t.Errorf("%s was not timed", phase)
+		}
+	}
+	if got := cfg.timer.get(PhaseFirstFrame); got != 0 {
+		t.Errorf("first-frame = %v before any capture, want 0", got)
+	}
+}
@@ -2,6 +2,7 @@ package preview
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -13,6 +14,36 @@ const (
 	bootMaxRetries = 3
 )
 
+// ErrBootFailed is matched (via errors.Is) by errors returned when the
+// simulator cannot be booted.
+var ErrBootFailed = errors.New("boot failed")
+
+// bootError is a boot failure caused by err. It matches ErrBootFailed and
+// wraps only the cause, so that each is named once in the message.
+type bootError struct {
+	attempts int // boot attempts made; 0 when not retried
+	err      error
+}
+
+func (e *bootError) Error() string {
+	if e.attempts > 1 {
+		return fmt.Sprintf("boot failed after %d attempts: %v", e.attempts, e.err)
+	}
+	return "boot failed: " + e.err.Error()
+}
+
+func (e *bootError) Unwrap() error { return e.err }
+
+func (e *bootError) Is(target error) bool { return target == ErrBootFailed }
+
+// bootFailed marks err as a boot failure, unless it already is one.
+func bootFailed(err error) error {
+	if errors.Is(err, ErrBootFailed) {
+		return err
+	}
+	return &bootError{err: err}
+}
+
 type bootFn func(udid, deviceSetPath string) (*idb.Companion, error)
 
 var (
@@ -27,12 +58,17 @@ var (
 	}
 )
 
-func bootWithRetry(ctx context.Context, udid, deviceSetPath string, headless bool) (*idb.Companion, error) {
-	fn := idb.Boot
+// bootWithRetry boots udid via idb_companion, waiting up to bootTimeout
+// (0 = idb.DefaultBootTimeout) for each attempt to reach "Booted".
+func bootWithRetry(ctx context.Context, udid, deviceSetPath string, headless bool, bootTimeout time.Duration) (*idb.Companion, error) {
+	opts := idb.Options{BootTimeout: bootTimeout}
+	boot := idb.BootWithContext
 	if headless {
-		fn = idb.BootHeadless
+		boot = idb.BootHeadlessWithContext
 	}
-	return bootWithRetryFunc(ctx, udid, deviceSetPath, fn)
+	return bootWithRetryFunc(ctx, udid, deviceSetPath, func(udid, deviceSetPath string) (*idb.Companion, error) {
+		return boot(ctx, idb.DefaultCommander(), udid, deviceSetPath, opts)
+	})
 }
 
 func bootWithRetryFunc(ctx context.Context, udid, deviceSetPath string, fn bootFn) (*idb.Companion, error) {
@@ -65,5 +101,5 @@ func bootWithRetryFunc(ctx context.Context, udid, deviceSetPath string, fn bootF
 		}
 	}
 
-	return nil, fmt.Errorf("boot failed after %d attempts: %w", maxAttempts, lastErr)
+	return nil, &bootError{attempts: maxAttempts, err: lastErr}
 }
@@ -3,6 +3,8 @@ package preview
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,6 +59,9 @@ func TestBootWithRetryFunc_FailsAfterMaxAttempts(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
+	if !errors.Is(err, ErrBootFailed) {
+		t.Errorf("expected ErrBootFailed, got %v", err)
+	}
 	wantAttempts := 1 + bootMaxRetries
 	if attempts != wantAttempts {
 		t.Fatalf("attempts = %d, want %d", attempts, wantAttempts)
@@ -81,3 +86,23 @@ func TestBootWithRetryFunc_StopsOnContextCancel(t *testing.T) {
 		t.Fatalf("attempts = %d, want 0 (no attempt after pre-cancel)", attempts)
 	}
 }
+
+func TestBootFailed_WrapsOnce(t *testing.T) {
+	cause := errors.New("timed out waiting for Booted")
+	err := fmt.Errorf("booting simulator: %w", bootFailed(cause))
+	if got, want := err.Error(), "booting simulator: boot failed: timed out waiting for Booted"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrBootFailed) || !errors.Is(err, cause) {
+		t.Errorf("error %v should match ErrBootFailed and wrap the cause", err)
+	}
+
+	// A failure from bootWithRetry is not marked again.
+	retried := &bootError{attempts: 4, err: cause}
+	if got := bootFailed(retried); got != retried {
+		t.Errorf("bootFailed(%v) = %v, want it unchanged", retried, got)
+	}
+	if n := strings.Count(fmt.Errorf("booting simulator: %w", bootFailed(retried)).Error(), "boot failed"); n != 1 {
+		t.Errorf("message names the boot failure %d times, want once", n)
+	}
+}
@@ -1,39 +1,74 @@
 package build
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/k-kohey/axe/internal/preview/buildlock"
 )
 
+// ErrBuildFailed is returned when xcodebuild exits with an error.
+var ErrBuildFailed = errors.New("xcodebuild build failed")
+
+// ErrBuildWarnings is returned by CheckWarnings when a successful build
+// produced compiler warnings (--fail-on-warning).
+var ErrBuildWarnings = errors.New("xcodebuild build produced warnings")
+
 // Result holds the output of a Prepare call.
 type Result struct {
 	Settings *Settings
 	Dirs     ProjectDirs
 	Built    bool // true if xcodebuild was invoked (false when reusing a previous build)
+
+	// Warnings are the compiler warnings in the output of the xcodebuild
+	// run. Only files compiled in that run report warnings, and a reused
+	// build has none.
+	Warnings []Diagnostic
 }
 
+// Mode selects how Prepare treats the artifacts of a previous build.
+type Mode int
+
+const (
+	// Incremental always runs xcodebuild, which rebuilds what changed.
+	Incremental Mode = iota
+	// Reuse skips xcodebuild when the app of a previous build exists.
+	Reuse
+	// Clean runs "xcodebuild clean build", discarding previous artifacts
+	// even when they look up to date.
+	Clean
+)
+
 // Prepare runs the full build pipeline: fetch settings, optionally build,
 // and extract compiler paths. This is the high-level entry point.
-func Prepare(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, reuse bool, r Runner) (*Result, error) {
+func Prepare(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, mode Mode, r Runner) (*Result, error) {
 	s, err := FetchSettings(ctx, pc, dirs, r)
 	if err != nil {
 		return nil, err
 	}
 
 	built := false
-	if reuse && HasPreviousBuild(s, dirs) {
+	var out []byte
+	switch {
+	case mode == Reuse && HasPreviousBuild(s):
 		slog.Info("Reusing previous build", "buildDir", dirs.Build)
-	} else {
-		if err := Run(ctx, pc, dirs, r); err != nil {
+	case mode == Clean:
+		slog.Info("Forcing a clean build; previous build artifacts are discarded", "buildDir", dirs.Build)
+		if out, err = runBuild(ctx, pc, dirs, r, true); err != nil {
+			return nil, err
+		}
+		built = true
+	default:
+		if out, err = runBuild(ctx, pc, dirs, r, false); err != nil {
 			return nil, err
 		}
 		built = true
@@ -41,89 +76,225 @@ func Prepare(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, reuse bool
 
 	ExtractCompilerPaths(ctx, s, dirs)
 
-	return &Result{Settings: s, Dirs: dirs, Built: built}, nil
+	return &Result{Settings: s, Dirs: dirs, Built: built, Warnings: Warnings(string(out))}, nil
 }
 
-// FetchSettings runs "xcodebuild -showBuildSettings" and parses the output
-// into a Settings struct.
+// FetchSettings runs "xcodebuild -showBuildSettings -json" and resolves the
+// settings of the application target the scheme builds, including the exact
+// path of the built .app. The Preparer caches the result.
 func FetchSettings(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, r Runner) (*Settings, error) {
 	args := append(
-		[]string{"xcodebuild", "-showBuildSettings"},
+		[]string{"xcodebuild", "-showBuildSettings", "-json"},
 		pc.XcodebuildArgs()...,
 	)
-	args = append(args, "-destination", "generic/platform=iOS Simulator")
+	// Same destination and derived data as Run, so that BUILT_PRODUCTS_DIR
+	// is where the build puts the app.
+	args = append(args,
+		"-destination", "generic/platform=iOS Simulator",
+		"-derivedDataPath", dirs.Build,
+	)
 
 	out, err := r.FetchBuildSettings(ctx, args)
 	if err != nil {
 		return nil, fmt.Errorf("xcodebuild -showBuildSettings failed: %w\n%s", err, out)
 	}
+	return parseSettings(out, pc)
+}
+
+// targetSettings is one element of the xcodebuild -showBuildSettings -json
+// output.
+type targetSettings struct {
+	Target        string            `json:"target"`
+	BuildSettings map[string]string `json:"buildSettings"`
+}
 
-	keys := map[string]string{
-		"PRODUCT_MODULE_NAME":        "",
-		"PRODUCT_BUNDLE_IDENTIFIER":  "",
-		"IPHONEOS_DEPLOYMENT_TARGET": "",
-		"SWIFT_VERSION":              "",
+// productTypeApplication is the PRODUCT_TYPE of iOS app targets.
+const productTypeApplication = "com.apple.product-type.application"
+
+// parseSettings extracts Settings from xcodebuild -showBuildSettings -json
+// output. A scheme may build several targets (frameworks, extensions, other
+// apps); the app to launch is chosen by selectApp.
+func parseSettings(out []byte, pc ProjectConfig) (*Settings, error) {
+	var targets []targetSettings
+	if err := json.Unmarshal(out, &targets); err != nil {
+		return nil, fmt.Errorf("parsing xcodebuild -showBuildSettings output: %w", err)
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(string(out)))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		for k := range keys {
-			prefix := k + " = "
-			if after, ok := strings.CutPrefix(line, prefix); ok {
-				keys[k] = strings.TrimSpace(after)
-			}
+	var apps []targetSettings
+	for _, t := range targets {
+		if t.BuildSettings["PRODUCT_TYPE"] == productTypeApplication {
+			apps = append(apps, t)
 		}
 	}
-
-	config := pc.Configuration
-	if config == "" {
-		config = "Debug"
+	app, err := selectApp(apps, pc)
+	if err != nil {
+		return nil, err
 	}
-	builtProductsDir := filepath.Join(dirs.Build, "Build", "Products", config+"-iphonesimulator")
 
+	keys := app.BuildSettings
 	s := &Settings{
 		ModuleName:       keys["PRODUCT_MODULE_NAME"],
 		BundleID:         "axe." + keys["PRODUCT_BUNDLE_IDENTIFIER"],
 		OriginalBundleID: keys["PRODUCT_BUNDLE_IDENTIFIER"],
-		BuiltProductsDir: builtProductsDir,
+		BuiltProductsDir: keys["BUILT_PRODUCTS_DIR"],
+		ProductName:      keys["FULL_PRODUCT_NAME"],
+		ExecutableName:   keys["EXECUTABLE_NAME"],
 		DeploymentTarget: keys["IPHONEOS_DEPLOYMENT_TARGET"],
 		SwiftVersion:     keys["SWIFT_VERSION"],
+		Toolchain:        pc.Toolchain,
 	}
 
 	if s.ModuleName == "" {
-		return nil, fmt.Errorf("PRODUCT_MODULE_NAME not found in build settings")
+		return nil, fmt.Errorf("PRODUCT_MODULE_NAME not found in build settings of target %s", app.Target)
 	}
 	if s.OriginalBundleID == "" {
-		return nil, fmt.Errorf("PRODUCT_BUNDLE_IDENTIFIER not found in build settings")
+		return nil, fmt.Errorf("PRODUCT_BUNDLE_IDENTIFIER not found in build settings of target %s", app.Target)
 	}
 	if s.DeploymentTarget == "" {
-		return nil, fmt.Errorf("IPHONEOS_DEPLOYMENT_TARGET not found in build settings")
+		return nil, fmt.Errorf("IPHONEOS_DEPLOYMENT_TARGET not found in build settings of target %s", app.Target)
+	}
+	if s.BuiltProductsDir == "" || s.ProductName == "" {
+		return nil, fmt.Errorf("cannot resolve the app built by target %s: BUILT_PRODUCTS_DIR or FULL_PRODUCT_NAME not found in build settings", app.Target)
+	}
+	if s.ExecutableName == "" {
+		s.ExecutableName = strings.TrimSuffix(s.ProductName, ".app")
 	}
 
 	slog.Debug("Build settings",
+		"target", app.Target,
 		"module", s.ModuleName,
 		"bundle", s.BundleID,
-		"products", s.BuiltProductsDir,
-		"target", s.DeploymentTarget,
+		"app", s.AppPath(),
+		"deploymentTarget", s.DeploymentTarget,
 		"swiftVersion", s.SwiftVersion,
 	)
 	return s, nil
 }
 
+// selectApp picks the application target to launch: the one whose
+// PRODUCT_NAME is pc.App, the only one, the one owning pc.AppSource, or
+// the one named after the scheme, in that order.
+func selectApp(apps []targetSettings, pc ProjectConfig) (targetSettings, error) {
+	if len(apps) == 0 {
+		return targetSettings{}, fmt.Errorf("scheme %s builds no application target; select a scheme that builds an iOS app", pc.Scheme)
+	}
+	if pc.App != "" {
+		i := slices.IndexFunc(apps, func(t targetSettings) bool { return appProductName(t) == pc.App })
+		if i < 0 {
+			return targetSettings{}, fmt.Errorf("scheme %s builds no app with PRODUCT_NAME %s; candidates: %s",
+				pc.Scheme, pc.App, appProductNames(apps))
+		}
+		return apps[i], nil
+	}
+	if len(apps) == 1 {
+		return apps[0], nil
+	}
+	if pc.AppSource != "" {
+		if t, ok := owningApp(apps, pc.AppSource); ok {
+			slog.Debug("Inferred app from source file", "app", appProductName(t), "source", pc.AppSource)
+			return t, nil
+		}
+	}
+	if i := slices.IndexFunc(apps, func(t targetSettings) bool { return t.Target == pc.Scheme }); i >= 0 {
+		return apps[i], nil
+	}
+	return targetSettings{}, fmt.Errorf("scheme %s builds %d application targets (%s); pass --app-product with the PRODUCT_NAME of the app to preview",
+		pc.Scheme, len(apps), appProductNames(apps))
+}
+
+// appProductName returns the PRODUCT_NAME of an app target, or its target
+// name when the setting is missing.
+func appProductName(t targetSettings) string {
+	return cmp.Or(t.BuildSettings["PRODUCT_NAME"], t.Target)
+}
+
+func appProductNames(apps []targetSettings) string {
+	names := make([]string, len(apps))
+	for i, t := range apps {
+		names[i] = appProductName(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// owningApp returns the app target whose sources contain sourceFile.
+// -showBuildSettings does not list a target's sources, so they are taken
+// to live in the directories its settings point into: the folder named
+// after the target, those of its Info.plist and entitlements, and the
+// parents of its development asset folders (App for "App/Preview
+// Content"). The deepest matching directory wins; false is
+// returned when no app or more than one matches at that depth.
+func owningApp(apps []targetSettings, sourceFile string) (targetSettings, bool) {
+	var (
+		best      targetSettings
+		bestDepth = -1
+		tie       bool
+	)
+	for _, t := range apps {
+		depth := -1
+		for _, dir := range appSourceDirs(t) {
+			if rel, err := filepath.Rel(dir, sourceFile); err == nil && !strings.HasPrefix(rel, "..") {
+				depth = max(depth, len(strings.Split(dir, string(filepath.Separator))))
+			}
+		}
+		switch {
+		case depth < 0:
+		case depth > bestDepth:
+			best, bestDepth, tie = t, depth, false
+		case depth == bestDepth:
+			tie = true
+		}
+	}
+	return best, bestDepth >= 0 && !tie
+}
+
+// appSourceDirs returns the absolute directories an app target's sources
+// are assumed to live in (see owningApp).
+func appSourceDirs(t targetSettings) []string {
+	keys := t.BuildSettings
+	root := keys["SRCROOT"]
+	if root == "" {
+		return nil
+	}
+	dirs := []string{filepath.Join(root, cmp.Or(keys["TARGET_NAME"], t.Target))}
+	for _, f := range []string{keys["INFOPLIST_FILE"], keys["CODE_SIGN_ENTITLEMENTS"]} {
+		if f != "" {
+			dirs = append(dirs, filepath.Join(root, filepath.Dir(f)))
+		}
+	}
+	// DEVELOPMENT_ASSET_PATHS is a space-separated list whose entries are
+	// quoted when they contain spaces.
+	for _, m := range assetPathRe.FindAllStringSubmatch(keys["DEVELOPMENT_ASSET_PATHS"], -1) {
+		dirs = append(dirs, filepath.Join(root, filepath.Dir(cmp.Or(m[1], m[2]))))
+	}
+	// A folder directly at SRCROOT would claim every file in the project.
+	return slices.DeleteFunc(dirs, func(d string) bool { return d == filepath.Clean(root) })
+}
+
+// assetPathRe matches one entry of DEVELOPMENT_ASSET_PATHS.
+var assetPathRe = regexp.MustCompile(`"([^"]+)"|(\S+)`)
+
 // Run executes "xcodebuild build" with the flags required for axe preview
 // (dynamic replacement and private imports).
 func Run(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, r Runner) error {
+	_, err := runBuild(ctx, pc, dirs, r, false)
+	return err
+}
+
+// runBuild is Run with an optional "clean" action before "build". It
+// returns the xcodebuild output of a successful build.
+func runBuild(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, r Runner, clean bool) ([]byte, error) {
 	lock := buildlock.New(dirs.Build)
 	if err := lock.Lock(ctx); err != nil {
-		return fmt.Errorf("acquiring build lock: %w", err)
+		return nil, fmt.Errorf("acquiring build lock: %w", err)
 	}
 	defer lock.Unlock()
 
-	args := append(
-		[]string{"xcodebuild", "build"},
-		pc.XcodebuildArgs()...,
-	)
+	args := []string{"xcodebuild"}
+	if clean {
+		args = append(args, "clean")
+	}
+	args = append(args, "build")
+	args = append(args, pc.XcodebuildArgs()...)
 	args = append(args,
 		"-destination", "generic/platform=iOS Simulator",
 		"-derivedDataPath", dirs.Build,
@@ -132,10 +303,10 @@ func Run(ctx context.Context, pc ProjectConfig, dirs ProjectDirs, r Runner) erro
 
 	out, err := r.Build(ctx, args)
 	if err != nil {
-		return fmt.Errorf("xcodebuild build failed: %w\n%s", err, out)
+		return nil, fmt.Errorf("%w: %w\n%s", ErrBuildFailed, err, out)
 	}
 
-	return nil
+	return out, nil
 }
 
 // ExtractCompilerPaths reads the swiftc response file (.resp) generated
@@ -385,15 +556,9 @@ func extractCompilerPathsFromDependencies(s *Settings, buildDir, manifestPath st
 	return nil
 }
 
-// HasPreviousBuild checks whether a .app bundle exists in the build products
-// directory, indicating that a previous build can be reused.
-func HasPreviousBuild(s *Settings, dirs ProjectDirs) bool {
-	appName := s.ModuleName + ".app"
-	primaryPath := filepath.Join(s.BuiltProductsDir, appName)
-	if _, err := os.Stat(primaryPath); err == nil {
-		return true
-	}
-	pattern := filepath.Join(dirs.Build, "Build", "Products", "*", appName)
-	matches, _ := filepath.Glob(pattern)
-	return len(matches) > 0
+// HasPreviousBuild checks whether the app bundle exists, indicating that a
+// previous build can be reused.
+func HasPreviousBuild(s *Settings) bool {
+	_, err := os.Stat(s.AppPath())
+	return err == nil
 }
@@ -39,14 +39,9 @@ func (f *fakeRunner) Build(_ context.Context, args []string) ([]byte, error) {
 func TestFetchSettings_ParsesAllFields(t *testing.T) {
 	t.Parallel()
 
-	output := `Build settings for action build and target TestModule:
-    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-    SWIFT_VERSION = 5.0
-    OTHER_SETTING = ignored
-`
-	r := &fakeRunner{fetchOutput: []byte(output)}
+	app := appTarget("/tmp/build/Build/Products/Debug-iphonesimulator")
+	app["OTHER_SETTING"] = "ignored"
+	r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, app)}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 	dirs := ProjectDirs{Build: t.TempDir()}
 
@@ -70,54 +65,202 @@ func TestFetchSettings_ParsesAllFields(t *testing.T) {
 	if bs.SwiftVersion != "5.0" {
 		t.Errorf("SwiftVersion = %q, want %q", bs.SwiftVersion, "5.0")
 	}
+	if want := "/tmp/build/Build/Products/Debug-iphonesimulator/TestModule.app"; bs.AppPath() != want {
+		t.Errorf("AppPath() = %q, want %q", bs.AppPath(), want)
+	}
+	// Without EXECUTABLE_NAME the process is named after the product.
+	if bs.ExecutableName != "TestModule" {
+		t.Errorf("ExecutableName = %q, want %q", bs.ExecutableName, "TestModule")
+	}
+}
+
+// sampleShowBuildSettings is trimmed xcodebuild -showBuildSettings -json
+// output of a scheme that builds a framework, an app, and its widget.
+const sampleShowBuildSettings = `[
+  {
+    "action" : "build",
+    "buildSettings" : {
+      "BUILT_PRODUCTS_DIR" : "/tmp/axe/build/Build/Products/Debug-iphonesimulator",
+      "FULL_PRODUCT_NAME" : "DesignSystem.framework",
+      "IPHONEOS_DEPLOYMENT_TARGET" : "17.0",
+      "PRODUCT_BUNDLE_IDENTIFIER" : "com.example.DesignSystem",
+      "PRODUCT_MODULE_NAME" : "DesignSystem",
+      "PRODUCT_TYPE" : "com.apple.product-type.framework",
+      "TARGET_NAME" : "DesignSystem"
+    },
+    "target" : "DesignSystem"
+  },
+  {
+    "action" : "build",
+    "buildSettings" : {
+      "BUILT_PRODUCTS_DIR" : "/tmp/axe/build/Build/Products/Debug-iphonesimulator",
+      "EXECUTABLE_NAME" : "Hoge App",
+      "FULL_PRODUCT_NAME" : "Hoge App.app",
+      "IPHONEOS_DEPLOYMENT_TARGET" : "17.0",
+      "PRODUCT_BUNDLE_IDENTIFIER" : "com.example.hoge",
+      "PRODUCT_MODULE_NAME" : "Hoge_App",
+      "PRODUCT_TYPE" : "com.apple.product-type.application",
+      "SWIFT_VERSION" : "6.0",
+      "TARGET_NAME" : "Hoge App"
+    },
+    "target" : "Hoge App"
+  },
+  {
+    "action" : "build",
+    "buildSettings" : {
+      "BUILT_PRODUCTS_DIR" : "/tmp/axe/build/Build/Products/Debug-iphonesimulator",
+      "FULL_PRODUCT_NAME" : "HogeWidget.appex",
+      "IPHONEOS_DEPLOYMENT_TARGET" : "17.0",
+      "PRODUCT_BUNDLE_IDENTIFIER" : "com.example.hoge.widget",
+      "PRODUCT_MODULE_NAME" : "HogeWidget",
+      "PRODUCT_TYPE" : "com.apple.product-type.app-extension",
+      "TARGET_NAME" : "HogeWidget"
+    },
+    "target" : "HogeWidget"
+  }
+]`
+
+func TestFetchSettings_ResolvesAppProduct(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{fetchOutput: []byte(sampleShowBuildSettings)}
+	pc := ProjectConfig{Project: "/tmp/Hoge.xcodeproj", Scheme: "Hoge"}
+	dirs := ProjectDirs{Build: "/tmp/axe/build"}
+
+	bs, err := FetchSettings(context.Background(), pc, dirs, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/tmp/axe/build/Build/Products/Debug-iphonesimulator/Hoge App.app"; bs.AppPath() != want {
+		t.Errorf("AppPath() = %q, want %q", bs.AppPath(), want)
+	}
+	if bs.ModuleName != "Hoge_App" || bs.OriginalBundleID != "com.example.hoge" || bs.SwiftVersion != "6.0" || bs.ExecutableName != "Hoge App" {
+		t.Errorf("settings not taken from the app target: %+v", bs)
+	}
+
+	// BUILT_PRODUCTS_DIR must match the derived data used by Run.
+	args := strings.Join(r.fetchArgs, " ")
+	if !strings.Contains(args, "-json") || !strings.Contains(args, "-derivedDataPath /tmp/axe/build") {
+		t.Errorf("fetchArgs = %v, want -json and -derivedDataPath", r.fetchArgs)
+	}
+}
+
+func TestFetchSettings_MultipleApps(t *testing.T) {
+	t.Parallel()
+
+	other := appTarget("/tmp/build/Build/Products/Debug-iphonesimulator")
+	other["TARGET_NAME"] = "Other"
+	other["FULL_PRODUCT_NAME"] = "Other.app"
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestModule"}
+
+	// The app named after the scheme wins.
+	r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, other, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator"))}
+	bs, err := FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bs.ProductName != "TestModule.app" {
+		t.Errorf("ProductName = %q, want TestModule.app", bs.ProductName)
+	}
+
+	// Otherwise the choice is ambiguous.
+	pc.Scheme = "All"
+	_, err = FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+	if err == nil || !strings.Contains(err.Error(), "builds 2 application targets (Other, TestModule)") {
+		t.Errorf("error = %v, want ambiguity error", err)
+	}
+}
+
+// multiAppTargets returns the settings of a scheme building a main app, an
+// App Clip, and a watch companion app, laid out as Xcode creates them.
+func multiAppTargets() []map[string]string {
+	const products = "/tmp/build/Build/Products/Debug-iphonesimulator"
+	target := func(name, infoPlist, entitlements, assets string) map[string]string {
+		s := appTarget(products)
+		s["TARGET_NAME"] = name
+		s["PRODUCT_NAME"] = name
+		s["FULL_PRODUCT_NAME"] = name + ".app"
+		s["SRCROOT"] = "/src/Shop"
+		s["INFOPLIST_FILE"] = infoPlist
+		s["CODE_SIGN_ENTITLEMENTS"] = entitlements
+		s["DEVELOPMENT_ASSET_PATHS"] = assets
+		return s
+	}
+	return []map[string]string{
+		target("Shop", "Shop/Info.plist", "Shop/Shop.entitlements", `"Shop/Preview Content"`),
+		target("ShopClip", "ShopClip/Info.plist", "ShopClip/ShopClip.entitlements", `"ShopClip/Preview Content"`),
+		target("ShopWatch", "", "", "Watch/PreviewContent"),
+	}
+}
+
+func TestFetchSettings_AppFlag(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, multiAppTargets()...)}
+	pc := ProjectConfig{Project: "/src/Shop/Shop.xcodeproj", Scheme: "Shop", App: "ShopClip"}
+
+	// --app-product overrides the app named after the scheme.
+	bs, err := FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bs.ProductName != "ShopClip.app" {
+		t.Errorf("ProductName = %q, want ShopClip.app", bs.ProductName)
+	}
+
+	pc.App = "Nope"
+	_, err = FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+	if err == nil || !strings.Contains(err.Error(), "no app with PRODUCT_NAME Nope; candidates: Shop, ShopClip, ShopWatch") {
+		t.Errorf("error = %v, want unknown-app error listing candidates", err)
+	}
 }
 
-func TestFetchSettings_BuiltProductsDir(t *testing.T) {
+func TestFetchSettings_InfersAppFromSource(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name          string
-		configuration string
-		wantSuffix    string
+		source string
+		want   string
 	}{
-		{
-			name:          "default configuration",
-			configuration: "",
-			wantSuffix:    "Build/Products/Debug-iphonesimulator",
-		},
-		{
-			name:          "explicit configuration",
-			configuration: "Release",
-			wantSuffix:    "Build/Products/Release-iphonesimulator",
-		},
+		{"/src/Shop/Shop/Views/CartView.swift", "Shop.app"},
+		{"/src/Shop/ShopClip/ClipView.swift", "ShopClip.app"},
+		{"/src/Shop/ShopClip/Preview Content/Mock.swift", "ShopClip.app"},
+		{"/src/Shop/ShopWatch/Face.swift", "ShopWatch.app"},
+		{"/src/Shop/Watch/Complication.swift", "ShopWatch.app"},
 	}
-
+	r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, multiAppTargets()...)}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
+		pc := ProjectConfig{Project: "/src/Shop/Shop.xcodeproj", Scheme: "All", AppSource: tt.source}
+		bs, err := FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if bs.ProductName != tt.want {
+			t.Errorf("%s: ProductName = %q, want %q", tt.source, bs.ProductName, tt.want)
+		}
+	}
 
-			output := `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-    SWIFT_VERSION = 5.0
-`
-			r := &fakeRunner{fetchOutput: []byte(output)}
-			pc := ProjectConfig{
-				Project:       "/tmp/TestProject.xcodeproj",
-				Scheme:        "TestScheme",
-				Configuration: tt.configuration,
-			}
-			dirs := ProjectDirs{Build: "/tmp/build"}
+	// A shared file owned by no app is ambiguous for a scheme not named
+	// after one of them.
+	pc := ProjectConfig{Project: "/src/Shop/Shop.xcodeproj", Scheme: "All", AppSource: "/src/Shop/Shared/Price.swift"}
+	_, err := FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+	if err == nil || !strings.Contains(err.Error(), "builds 3 application targets (Shop, ShopClip, ShopWatch); pass --app-product") {
+		t.Errorf("error = %v, want ambiguity error listing products", err)
+	}
+}
 
-			bs, err := FetchSettings(context.Background(), pc, dirs, r)
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
+func TestFetchSettings_NoApp(t *testing.T) {
+	t.Parallel()
 
-			if !strings.HasSuffix(bs.BuiltProductsDir, tt.wantSuffix) {
-				t.Errorf("BuiltProductsDir = %q, want suffix %q", bs.BuiltProductsDir, tt.wantSuffix)
-			}
-		})
+	lib := appTarget("/tmp/build/Build/Products/Debug-iphonesimulator")
+	lib["PRODUCT_TYPE"] = "com.apple.product-type.framework"
+	r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, lib)}
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
+
+	_, err := FetchSettings(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, r)
+	if err == nil || !strings.Contains(err.Error(), "builds no application target") {
+		t.Errorf("error = %v, want no-application error", err)
 	}
 }
 
@@ -126,37 +269,21 @@ func TestFetchSettings_MissingFields(t *testing.T) {
 
 	tests := []struct {
 		name      string
-		output    string
+		missing   string
 		wantError string
 	}{
-		{
-			name: "missing module name",
-			output: `    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`,
-			wantError: "PRODUCT_MODULE_NAME not found",
-		},
-		{
-			name: "missing bundle ID",
-			output: `    PRODUCT_MODULE_NAME = TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`,
-			wantError: "PRODUCT_BUNDLE_IDENTIFIER not found",
-		},
-		{
-			name: "missing deployment target",
-			output: `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-`,
-			wantError: "IPHONEOS_DEPLOYMENT_TARGET not found",
-		},
+		{name: "missing module name", missing: "PRODUCT_MODULE_NAME", wantError: "PRODUCT_MODULE_NAME not found"},
+		{name: "missing bundle ID", missing: "PRODUCT_BUNDLE_IDENTIFIER", wantError: "PRODUCT_BUNDLE_IDENTIFIER not found"},
+		{name: "missing deployment target", missing: "IPHONEOS_DEPLOYMENT_TARGET", wantError: "IPHONEOS_DEPLOYMENT_TARGET not found"},
+		{name: "missing products dir", missing: "BUILT_PRODUCTS_DIR", wantError: "cannot resolve the app built by target TestModule"},
+		{name: "missing product name", missing: "FULL_PRODUCT_NAME", wantError: "cannot resolve the app built by target TestModule"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			r := &fakeRunner{fetchOutput: []byte(tt.output)}
+			r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator", tt.missing))}
 			pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 			dirs := ProjectDirs{Build: t.TempDir()}
 
@@ -175,11 +302,8 @@ func TestFetchSettings_SwiftVersionOptional(t *testing.T) {
 	t.Parallel()
 
 	// SwiftVersion is not required; FetchSettings should succeed without it.
-	output := `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`
-	r := &fakeRunner{fetchOutput: []byte(output)}
+	output := showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator", "SWIFT_VERSION"))
+	r := &fakeRunner{fetchOutput: output}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 	dirs := ProjectDirs{Build: t.TempDir()}
 
@@ -214,11 +338,8 @@ func TestFetchSettings_RunnerError(t *testing.T) {
 func TestFetchSettings_PassesCorrectArgs(t *testing.T) {
 	t.Parallel()
 
-	output := `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`
-	r := &fakeRunner{fetchOutput: []byte(output)}
+	output := showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator"))
+	r := &fakeRunner{fetchOutput: output}
 	pc := ProjectConfig{
 		Workspace: "/tmp/TestWorkspace.xcworkspace",
 		Scheme:    "TestScheme",
@@ -245,18 +366,15 @@ func TestFetchSettings_PassesCorrectArgs(t *testing.T) {
 func TestPrepare_Success(t *testing.T) {
 	t.Parallel()
 
-	output := `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`
+	output := showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator"))
 	r := &fakeRunner{
-		fetchOutput: []byte(output),
+		fetchOutput: output,
 		buildOutput: []byte("BUILD SUCCEEDED"),
 	}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 	dirs := ProjectDirs{Build: t.TempDir()}
 
-	result, err := Prepare(context.Background(), pc, dirs, false, r)
+	result, err := Prepare(context.Background(), pc, dirs, Incremental, r)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -271,6 +389,68 @@ func TestPrepare_Success(t *testing.T) {
 	}
 }
 
+func TestPrepare_Toolchain(t *testing.T) {
+	t.Parallel()
+
+	output := showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator"))
+	r := &fakeRunner{
+		fetchOutput: output,
+		buildOutput: []byte("BUILD SUCCEEDED"),
+	}
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme", Toolchain: "org.swift.600202409101a"}
+	dirs := ProjectDirs{Build: t.TempDir()}
+
+	result, err := Prepare(context.Background(), pc, dirs, Incremental, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "-toolchain org.swift.600202409101a"
+	if args := strings.Join(r.fetchArgs, " "); !strings.Contains(args, want) {
+		t.Errorf("fetchArgs = %v, want to contain %q", r.fetchArgs, want)
+	}
+	if args := strings.Join(r.buildArgs, " "); !strings.Contains(args, want) {
+		t.Errorf("buildArgs = %v, want to contain %q", r.buildArgs, want)
+	}
+	// Carried to the thunk compile through the settings.
+	if result.Settings.Toolchain != pc.Toolchain {
+		t.Errorf("Settings.Toolchain = %q, want %q", result.Settings.Toolchain, pc.Toolchain)
+	}
+}
+
+func TestValidateToolchain(t *testing.T) {
+	t.Parallel()
+
+	for _, id := range []string{"", "swift", "org.swift.600202409101a", "swift-5.10-RELEASE"} {
+		if err := ValidateToolchain(id); err != nil {
+			t.Errorf("ValidateToolchain(%q) = %v, want nil", id, err)
+		}
+	}
+	for _, id := range []string{"-sdk", "org swift", "../swift", "swift;rm"} {
+		if err := ValidateToolchain(id); err == nil {
+			t.Errorf("ValidateToolchain(%q) = nil, want an error", id)
+		}
+	}
+}
+
+func TestPrepare_CollectsWarnings(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		fetchOutput: showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator")),
+		buildOutput: []byte("/tmp/App/View.swift:7:13: warning: 'foregroundColor' is deprecated\n/tmp/App/View.swift:9:1: note: see here\n** BUILD SUCCEEDED **\n"),
+	}
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
+
+	result, err := Prepare(context.Background(), pc, ProjectDirs{Build: t.TempDir()}, Incremental, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Diagnostic{{File: "/tmp/App/View.swift", Line: 7, Column: 13, Severity: "warning", Message: "'foregroundColor' is deprecated"}}
+	if !slices.Equal(result.Warnings, want) {
+		t.Errorf("Warnings = %+v, want %+v", result.Warnings, want)
+	}
+}
+
 func TestPrepare_ReuseBuild(t *testing.T) {
 	t.Parallel()
 
@@ -278,19 +458,15 @@ func TestPrepare_ReuseBuild(t *testing.T) {
 	dirs := ProjectDirs{Build: root}
 
 	// Create .app so HasPreviousBuild returns true.
-	appDir := filepath.Join(root, "Build", "Products", "Debug-iphonesimulator", "TestModule.app")
-	if err := os.MkdirAll(appDir, 0o755); err != nil {
+	productsDir := filepath.Join(root, "Build", "Products", "Debug-iphonesimulator")
+	if err := os.MkdirAll(filepath.Join(productsDir, "TestModule.app"), 0o755); err != nil {
 		t.Fatal(err)
 	}
 
-	output := `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`
-	r := &fakeRunner{fetchOutput: []byte(output)}
+	r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, appTarget(productsDir))}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 
-	result, err := Prepare(context.Background(), pc, dirs, true, r)
+	result, err := Prepare(context.Background(), pc, dirs, Reuse, r)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -302,6 +478,36 @@ func TestPrepare_ReuseBuild(t *testing.T) {
 	}
 }
 
+func TestPrepare_CleanIgnoresPreviousBuild(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dirs := ProjectDirs{Build: root}
+
+	// A previous build exists, as in TestPrepare_ReuseBuild.
+	productsDir := filepath.Join(root, "Build", "Products", "Debug-iphonesimulator")
+	if err := os.MkdirAll(filepath.Join(productsDir, "TestModule.app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &fakeRunner{
+		fetchOutput: showBuildSettingsJSON(t, appTarget(productsDir)),
+		buildOutput: []byte("BUILD SUCCEEDED"),
+	}
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
+
+	result, err := Prepare(context.Background(), pc, dirs, Clean, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Built {
+		t.Error("Built = false, want true (clean build must not reuse)")
+	}
+	if len(r.buildArgs) < 3 || r.buildArgs[0] != "xcodebuild" || r.buildArgs[1] != "clean" || r.buildArgs[2] != "build" {
+		t.Errorf("buildArgs = %v, want to start with xcodebuild clean build", r.buildArgs)
+	}
+}
+
 func TestPrepare_FetchError(t *testing.T) {
 	t.Parallel()
 
@@ -312,7 +518,7 @@ func TestPrepare_FetchError(t *testing.T) {
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 	dirs := ProjectDirs{Build: t.TempDir()}
 
-	_, err := Prepare(context.Background(), pc, dirs, false, r)
+	_, err := Prepare(context.Background(), pc, dirs, Incremental, r)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -324,19 +530,16 @@ func TestPrepare_FetchError(t *testing.T) {
 func TestPrepare_BuildError(t *testing.T) {
 	t.Parallel()
 
-	output := `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`
+	output := showBuildSettingsJSON(t, appTarget("/tmp/build/Build/Products/Debug-iphonesimulator"))
 	r := &fakeRunner{
-		fetchOutput: []byte(output),
+		fetchOutput: output,
 		buildOutput: []byte("BUILD FAILED"),
 		buildErr:    errors.New("exit status 65"),
 	}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 	dirs := ProjectDirs{Build: t.TempDir()}
 
-	_, err := Prepare(context.Background(), pc, dirs, false, r)
+	_, err := Prepare(context.Background(), pc, dirs, Incremental, r)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -708,26 +911,59 @@ func TestHasPreviousBuild_True(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	bs := &Settings{ModuleName: "MyApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator")}
-	dirs := ProjectDirs{Build: root}
+	bs := &Settings{ModuleName: "MyApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator"), ProductName: "MyApp.app"}
 
-	if !HasPreviousBuild(bs, dirs) {
+	if !HasPreviousBuild(bs) {
 		t.Error("HasPreviousBuild = false, want true")
 	}
 }
 
 func TestHasPreviousBuild_False(t *testing.T) {
 	root := t.TempDir()
-	bs := &Settings{ModuleName: "MyApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator")}
-	dirs := ProjectDirs{Build: root}
+	bs := &Settings{ModuleName: "MyApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator"), ProductName: "MyApp.app"}
 
-	if HasPreviousBuild(bs, dirs) {
+	if HasPreviousBuild(bs) {
 		t.Error("HasPreviousBuild = true, want false")
 	}
 }
 
 // --- Helpers ---
 
+// appTarget returns the build settings of an application target named
+// TestModule whose products go to builtProductsDir, without the keys in
+// without.
+func appTarget(builtProductsDir string, without ...string) map[string]string {
+	s := map[string]string{
+		"BUILT_PRODUCTS_DIR":         builtProductsDir,
+		"FULL_PRODUCT_NAME":          "TestModule.app",
+		"IPHONEOS_DEPLOYMENT_TARGET": "17.0",
+		"PRODUCT_BUNDLE_IDENTIFIER":  "com.example.TestModule",
+		"PRODUCT_MODULE_NAME":        "TestModule",
+		"PRODUCT_TYPE":               "com.apple.product-type.application",
+		"SWIFT_VERSION":              "5.0",
+		"TARGET_NAME":                "TestModule",
+	}
+	for _, k := range without {
+		delete(s, k)
+	}
+	return s
+}
+
+// showBuildSettingsJSON renders xcodebuild -showBuildSettings -json output
+// for targets, each given by its build settings.
+func showBuildSettingsJSON(t *testing.T, targets ...map[string]string) []byte {
+	t.Helper()
+	out := make([]targetSettings, len(targets))
+	for i, s := range targets {
+		out[i] = targetSettings{Target: s["TARGET_NAME"], BuildSettings: s}
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
 // setupRespFile creates a temporary directory structure mimicking the xcodebuild
 // intermediates layout and writes content as a swiftc response file.
 func setupRespFile(t *testing.T, content string) (*Settings, ProjectDirs) {
@@ -6,6 +6,7 @@ package build
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 )
 
 // ProjectConfig abstracts --project / --workspace + --scheme.
@@ -15,6 +16,18 @@ type ProjectConfig struct {
 	Workspace     string
 	Scheme        string
 	Configuration string // e.g. "Debug", "Release"; empty means xcodebuild default
+
+	// Toolchain is the identifier or name of the Swift toolchain
+	// (--toolchain, .axerc TOOLCHAIN) used by both xcodebuild and the thunk
+	// compile, e.g. "org.swift.600202409101a" or "swift". Empty means the
+	// toolchain bundled with the selected Xcode.
+	Toolchain string
+
+	// App is the PRODUCT_NAME of the app to launch when the scheme builds
+	// several (--app-product). When empty, the app whose target owns AppSource is
+	// launched, falling back to the one named after the scheme.
+	App       string
+	AppSource string // previewed source file; only used to infer App
 }
 
 // NewProjectConfig creates a ProjectConfig with absolute paths resolved.
@@ -48,9 +61,26 @@ func (pc ProjectConfig) XcodebuildArgs() []string {
 	if pc.Configuration != "" {
 		args = append(args, "-configuration", pc.Configuration)
 	}
+	if pc.Toolchain != "" {
+		args = append(args, "-toolchain", pc.Toolchain)
+	}
 	return args
 }
 
+// toolchainPattern matches the toolchain identifiers and aliases accepted
+// by TOOLCHAINS, e.g. "org.swift.600202409101a", "swift" or "swift-5.10".
+var toolchainPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateToolchain reports whether id can be used as ProjectConfig.Toolchain.
+// It only checks the form of the identifier; xcrun reports a toolchain that
+// is not installed.
+func ValidateToolchain(id string) error {
+	if id != "" && !toolchainPattern.MatchString(id) {
+		return fmt.Errorf("invalid toolchain %q: expected an identifier such as org.swift.600202409101a or swift", id)
+	}
+	return nil
+}
+
 // PrimaryPath returns the workspace or project path (whichever is set).
 func (pc ProjectConfig) PrimaryPath() string {
 	if pc.Workspace != "" {
@@ -0,0 +1,106 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// ResolveConfiguration fills in pc.Configuration when it is empty, so that
+// the build directory and product lookup key on the configuration
+// xcodebuild actually builds rather than on "default". It reads the
+// configuration of the scheme's Run action from its .xcscheme file, and
+// falls back to the CONFIGURATION build setting reported by
+// "xcodebuild -showBuildSettings" for schemes without a file (e.g.
+// autocreated ones). When neither answers, pc is returned unchanged and
+// xcodebuild picks its default as before.
+func ResolveConfiguration(ctx context.Context, pc ProjectConfig, r Runner) ProjectConfig {
+	if pc.Configuration != "" {
+		return pc
+	}
+	source := "scheme"
+	configuration := schemeConfiguration(pc)
+	if configuration == "" {
+		source = "build settings"
+		args := append([]string{"xcodebuild", "-showBuildSettings", "-json"}, pc.XcodebuildArgs()...)
+		args = append(args, "-destination", "generic/platform=iOS Simulator")
+		out, err := r.FetchBuildSettings(ctx, args)
+		if err != nil {
+			slog.Debug("Cannot resolve the default build configuration", "err", err)
+			return pc
+		}
+		if configuration, err = parseConfiguration(out); err != nil {
+			slog.Debug("Cannot resolve the default build configuration", "err", err)
+			return pc
+		}
+	}
+	slog.Info("Resolved build configuration", "configuration", configuration, "scheme", pc.Scheme, "source", source)
+	pc.Configuration = configuration
+	return pc
+}
+
+// parseConfiguration returns the CONFIGURATION build setting from
+// xcodebuild -showBuildSettings -json output. Every target of a scheme
+// builds with the same configuration, so the first one that reports it
+// is used.
+func parseConfiguration(out []byte) (string, error) {
+	var targets []targetSettings
+	if err := json.Unmarshal(out, &targets); err != nil {
+		return "", fmt.Errorf("parsing xcodebuild -showBuildSettings output: %w", err)
+	}
+	for _, t := range targets {
+		if c := t.BuildSettings["CONFIGURATION"]; c != "" {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("CONFIGURATION not found in build settings")
+}
+
+// schemeConfiguration returns the Run action's build configuration of
+// pc.Scheme, read from the shared or a user scheme file next to the
+// project or workspace, or "" when there is no such file.
+func schemeConfiguration(pc ProjectConfig) string {
+	for _, path := range schemePaths(pc.PrimaryPath(), pc.Scheme) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		c, err := parseSchemeConfiguration(data)
+		if err != nil {
+			slog.Debug("Cannot parse scheme", "path", path, "err", err)
+			continue
+		}
+		if c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+// schemePaths returns the candidate .xcscheme paths of scheme inside a
+// .xcodeproj or .xcworkspace, shared schemes first.
+func schemePaths(container, scheme string) []string {
+	name := scheme + ".xcscheme"
+	paths := []string{filepath.Join(container, "xcshareddata", "xcschemes", name)}
+	users, _ := filepath.Glob(filepath.Join(container, "xcuserdata", "*.xcuserdatad", "xcschemes", name))
+	return append(paths, users...)
+}
+
+// parseSchemeConfiguration returns the buildConfiguration of the
+// LaunchAction (the Run action, which "xcodebuild build" uses) of a
+// .xcscheme document.
+func parseSchemeConfiguration(data []byte) (string, error) {
+	var scheme struct {
+		LaunchAction struct {
+			BuildConfiguration string `xml:"buildConfiguration,attr"`
+		} `xml:"LaunchAction"`
+	}
+	if err := xml.Unmarshal(data, &scheme); err != nil {
+		return "", err
+	}
+	return scheme.LaunchAction.BuildConfiguration, nil
+}
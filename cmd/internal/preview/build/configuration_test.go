@@ -0,0 +1,100 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseConfiguration(t *testing.T) {
+	t.Parallel()
+
+	app := appTarget("/tmp/build/Build/Products/Staging-iphonesimulator")
+	app["CONFIGURATION"] = "Staging"
+	out := showBuildSettingsJSON(t, app)
+
+	got, err := parseConfiguration(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Staging" {
+		t.Errorf("configuration = %q, want Staging", got)
+	}
+
+	if _, err := parseConfiguration(showBuildSettingsJSON(t, appTarget("/tmp"))); err == nil {
+		t.Error("expected error when CONFIGURATION is missing")
+	}
+}
+
+func TestParseSchemeConfiguration(t *testing.T) {
+	t.Parallel()
+
+	scheme := `<?xml version="1.0" encoding="UTF-8"?>
+<Scheme LastUpgradeVersion = "1600" version = "1.7">
+   <BuildAction parallelizeBuildables = "YES"></BuildAction>
+   <TestAction buildConfiguration = "Debug"></TestAction>
+   <LaunchAction buildConfiguration = "Beta" launchStyle = "0"></LaunchAction>
+   <ArchiveAction buildConfiguration = "Release"></ArchiveAction>
+</Scheme>`
+	got, err := parseSchemeConfiguration([]byte(scheme))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Beta" {
+		t.Errorf("configuration = %q, want the LaunchAction's Beta", got)
+	}
+}
+
+func TestResolveConfiguration(t *testing.T) {
+	t.Parallel()
+
+	app := appTarget("/tmp")
+	app["CONFIGURATION"] = "Debug"
+
+	t.Run("explicit configuration is kept", func(t *testing.T) {
+		r := &fakeRunner{fetchErr: errors.New("must not run")}
+		pc := ProjectConfig{Project: "/tmp/App.xcodeproj", Scheme: "App", Configuration: "Release"}
+		if got := ResolveConfiguration(context.Background(), pc, r); got.Configuration != "Release" || r.fetchArgs != nil {
+			t.Errorf("Configuration = %q, fetchArgs = %v; want Release and no xcodebuild call", got.Configuration, r.fetchArgs)
+		}
+	})
+
+	t.Run("shared scheme file", func(t *testing.T) {
+		project := filepath.Join(t.TempDir(), "App.xcodeproj")
+		dir := filepath.Join(project, "xcshareddata", "xcschemes")
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "App.xcscheme"), []byte(`<Scheme><LaunchAction buildConfiguration = "Beta"/></Scheme>`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, app)}
+		got := ResolveConfiguration(context.Background(), ProjectConfig{Project: project, Scheme: "App"}, r)
+		if got.Configuration != "Beta" || r.fetchArgs != nil {
+			t.Errorf("Configuration = %q, fetchArgs = %v; want Beta from the scheme and no xcodebuild call", got.Configuration, r.fetchArgs)
+		}
+	})
+
+	t.Run("build settings fallback", func(t *testing.T) {
+		project := filepath.Join(t.TempDir(), "App.xcodeproj")
+		r := &fakeRunner{fetchOutput: showBuildSettingsJSON(t, app)}
+		got := ResolveConfiguration(context.Background(), ProjectConfig{Project: project, Scheme: "App"}, r)
+		if got.Configuration != "Debug" {
+			t.Errorf("Configuration = %q, want Debug", got.Configuration)
+		}
+		if !slices.Contains(r.fetchArgs, "-showBuildSettings") || slices.Contains(r.fetchArgs, "-configuration") {
+			t.Errorf("fetchArgs = %v, want -showBuildSettings without -configuration", r.fetchArgs)
+		}
+	})
+
+	t.Run("unresolvable leaves it empty", func(t *testing.T) {
+		r := &fakeRunner{fetchOutput: []byte("error"), fetchErr: errors.New("exit status 65")}
+		got := ResolveConfiguration(context.Background(), ProjectConfig{Project: "/nonexistent/App.xcodeproj", Scheme: "App"}, r)
+		if got.Configuration != "" {
+			t.Errorf("Configuration = %q, want empty", got.Configuration)
+		}
+	})
+}
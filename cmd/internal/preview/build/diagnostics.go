@@ -0,0 +1,98 @@
+package build
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is one compiler message parsed from xcodebuild or swiftc
+// output. File is empty, and Line and Column are 0, for messages without a
+// source location (e.g. "error: no such module 'Foo'"). Column is 0 when
+// the compiler reported only a line.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string // "error", "warning", "note", or "remark"
+	Message  string
+}
+
+var (
+	// diagnosticLineRe matches "<file>:<line>[:<column>]: <severity>: <message>".
+	diagnosticLineRe = regexp.MustCompile(`^(/[^:]+):(\d+):(?:(\d+):)? (?:fatal )?(error|warning|note|remark): (.*)$`)
+	// diagnosticBareRe matches a message without a source location.
+	diagnosticBareRe = regexp.MustCompile(`^(?:fatal )?(error|warning): (.*)$`)
+)
+
+// ParseDiagnostics extracts the compiler diagnostics from build output, in
+// order of appearance. xcodebuild repeats a file's diagnostics in its
+// failure summary, so duplicates are dropped. Lines that are not
+// diagnostics, such as build commands and source excerpts, are ignored.
+func ParseDiagnostics(output string) []Diagnostic {
+	var diags []Diagnostic
+	seen := make(map[Diagnostic]bool)
+	for line := range strings.SplitSeq(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		var d Diagnostic
+		if m := diagnosticLineRe.FindStringSubmatch(line); m != nil {
+			d.File = m[1]
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Column, _ = strconv.Atoi(m[3]) // 0 when absent
+			d.Severity = m[4]
+			d.Message = m[5]
+		} else if m := diagnosticBareRe.FindStringSubmatch(line); m != nil {
+			d.Severity = m[1]
+			d.Message = m[2]
+		} else {
+			continue
+		}
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// Warnings returns the warnings among the diagnostics in build output.
+func Warnings(output string) []Diagnostic {
+	var warnings []Diagnostic
+	for _, d := range ParseDiagnostics(output) {
+		if d.Severity == "warning" {
+			warnings = append(warnings, d)
+		}
+	}
+	return warnings
+}
+
+// CheckWarnings prints each warning to w in compiler format and returns
+// an error wrapping ErrBuildWarnings if there were any (--fail-on-warning).
+func CheckWarnings(w io.Writer, warnings []Diagnostic) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	for _, d := range warnings {
+		fmt.Fprintln(w, d)
+	}
+	return fmt.Errorf("%w: %d warning(s)", ErrBuildWarnings, len(warnings))
+}
+
+// String formats d like the compiler does, e.g.
+// "/path/View.swift:12:9: warning: variable 'x' was never used".
+func (d Diagnostic) String() string {
+	var loc string
+	switch {
+	case d.File == "":
+	case d.Column > 0:
+		loc = fmt.Sprintf("%s:%d:%d: ", d.File, d.Line, d.Column)
+	case d.Line > 0:
+		loc = fmt.Sprintf("%s:%d: ", d.File, d.Line)
+	default:
+		loc = d.File + ": "
+	}
+	return loc + d.Severity + ": " + d.Message
+}
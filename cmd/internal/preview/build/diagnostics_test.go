@@ -0,0 +1,85 @@
+package build
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sampleBuildOutput is trimmed xcodebuild output of a failed build with
+// swiftc diagnostics, a source excerpt, and the failure summary.
+const sampleBuildOutput = `CompileSwift normal arm64 /Users/me/App/Sources/ProfileView.swift (in target 'App' from project 'App')
+    cd /Users/me/App
+    /Applications/Xcode.app/Contents/Developer/Toolchains/XcodeDefault.xctoolchain/usr/bin/swiftc -frontend -c
+/Users/me/App/Sources/ProfileView.swift:12:9: error: cannot find 'Avatar' in scope
+        Avatar(user: user)
+        ^~~~~~
+/Users/me/App/Sources/ProfileView.swift:20:5: warning: variable 'x' was never used; consider replacing with '_' or removing it
+/Users/me/App/Sources/Model.swift:3:1: note: 'User' declared here
+/Users/me/App/Sources/Model.swift:41: error: expected '}' in struct
+error: no such module 'Networking'
+
+/Users/me/App/Sources/ProfileView.swift:12:9: error: cannot find 'Avatar' in scope
+** BUILD FAILED **
+
+
+The following build commands failed:
+	CompileSwift normal arm64 /Users/me/App/Sources/ProfileView.swift (in target 'App' from project 'App')
+(1 failure)
+`
+
+func TestParseDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	got := ParseDiagnostics(sampleBuildOutput)
+	want := []Diagnostic{
+		{File: "/Users/me/App/Sources/ProfileView.swift", Line: 12, Column: 9, Severity: "error", Message: "cannot find 'Avatar' in scope"},
+		{File: "/Users/me/App/Sources/ProfileView.swift", Line: 20, Column: 5, Severity: "warning", Message: "variable 'x' was never used; consider replacing with '_' or removing it"},
+		{File: "/Users/me/App/Sources/Model.swift", Line: 3, Column: 1, Severity: "note", Message: "'User' declared here"},
+		{File: "/Users/me/App/Sources/Model.swift", Line: 41, Severity: "error", Message: "expected '}' in struct"},
+		{Severity: "error", Message: "no such module 'Networking'"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseDiagnostics:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseDiagnostics_NoDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	out := "Command line invocation:\n    xcodebuild build\n** BUILD FAILED **\n"
+	if got := ParseDiagnostics(out); len(got) != 0 {
+		t.Errorf("expected no diagnostics, got %+v", got)
+	}
+}
+
+func TestParseDiagnostics_CRLFAndFatal(t *testing.T) {
+	t.Parallel()
+
+	out := "/tmp/thunk/View.swift:1:8: fatal error: could not build module 'App'\r\n"
+	got := ParseDiagnostics(out)
+	want := []Diagnostic{{File: "/tmp/thunk/View.swift", Line: 1, Column: 8, Severity: "error", Message: "could not build module 'App'"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseDiagnostics:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCheckWarnings(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	err := CheckWarnings(&out, Warnings(sampleBuildOutput))
+	if !errors.Is(err, ErrBuildWarnings) {
+		t.Fatalf("err = %v, want ErrBuildWarnings", err)
+	}
+	want := "/Users/me/App/Sources/ProfileView.swift:20:5: warning: variable 'x' was never used; consider replacing with '_' or removing it\n"
+	if out.String() != want {
+		t.Errorf("reported warnings:\n got %q\nwant %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := CheckWarnings(&out, Warnings("** BUILD SUCCEEDED **\n")); err != nil || out.Len() != 0 {
+		t.Errorf("without warnings: err = %v, output %q; want nil and nothing", err, out.String())
+	}
+}
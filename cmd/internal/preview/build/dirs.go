@@ -5,14 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // ProjectDirs holds device-independent directory paths for build artifacts.
-// These are derived solely from the project path and shared across all
-// simulator devices working with the same project.
+// Root is derived from the project path; Build is further scoped by scheme
+// and configuration. Both are shared across all simulator devices working
+// with the same project and scheme.
 type ProjectDirs struct {
 	Root  string // ~/.cache/axe/preview-<project-hash>
-	Build string // Root/build (shared xcodebuild output)
+	Build string // Root/build/<scheme>-<variant-hash> (shared xcodebuild output)
 }
 
 // IndexStorePath returns the path to the Xcode index store data directory.
@@ -20,26 +22,46 @@ func (d ProjectDirs) IndexStorePath() string {
 	return filepath.Join(d.Build, "Index.noindex", "DataStore")
 }
 
-// NewProjectDirs creates a ProjectDirs from a project/workspace path.
+// NewProjectDirs creates a ProjectDirs for a project configuration.
 // Uses ~/Library/Caches/axe/ so that dylibs are accessible from within
 // the iOS Simulator via dlopen (separated runtimes cannot resolve host
 // /tmp paths).
-func NewProjectDirs(projectPath string) (ProjectDirs, error) {
-	abs, err := filepath.Abs(projectPath)
+func NewProjectDirs(pc ProjectConfig) (ProjectDirs, error) {
+	abs, err := filepath.Abs(pc.PrimaryPath())
 	if err != nil {
 		return ProjectDirs{}, fmt.Errorf("resolving project path: %w", err)
 	}
 	h := sha256.Sum256([]byte(abs))
 	short := fmt.Sprintf("%x", h[:8])
+	root := filepath.Join(CacheRoot(), "preview-"+short)
 
+	return ProjectDirs{
+		Root:  root,
+		Build: filepath.Join(root, "build", buildVariant(pc.Scheme, pc.Configuration, pc.Toolchain)),
+	}, nil
+}
+
+// CacheRoot returns the directory holding every project's Root
+// (~/Library/Caches/axe).
+func CacheRoot() string {
 	cacheDir, err := os.UserCacheDir()
 	if err != nil {
 		cacheDir = filepath.Join(os.Getenv("HOME"), "Library", "Caches")
 	}
-	root := filepath.Join(cacheDir, "axe", "preview-"+short)
+	return filepath.Join(cacheDir, "axe")
+}
 
-	return ProjectDirs{
-		Root:  root,
-		Build: filepath.Join(root, "build"),
-	}, nil
+// buildVariant names the Build subdirectory for a scheme, configuration and
+// toolchain so that different schemes of one project, or the same scheme
+// built by another Swift toolchain, do not overwrite each other's products.
+// The name is the scheme, for readability, followed by a short hash of the
+// whole tuple; joining the raw values would let distinct tuples collide
+// (scheme "A-B" with configuration "C" vs scheme "A" with "B-C").
+func buildVariant(scheme, configuration, toolchain string) string {
+	h := sha256.Sum256([]byte(scheme + "\x00" + configuration + "\x00" + toolchain))
+	name := strings.ReplaceAll(scheme, "/", "_")
+	if name == "" {
+		name = "scheme"
+	}
+	return fmt.Sprintf("%s-%x", name, h[:6])
 }
@@ -0,0 +1,27 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrPackageTarget is returned for a source file that is compiled into a
+// module other than the app module of the scheme, typically a Swift
+// package target. Thunks are compiled against the app module and injected
+// into the app, so such a view cannot be previewed directly.
+var ErrPackageTarget = errors.New("previewing files of Swift package targets is not supported")
+
+// CheckSourceModule returns an error wrapping ErrPackageTarget if the index
+// store records sourceFile in fileModule and that is not bs's app module,
+// so that the preview fails with a precise message instead of in the thunk
+// compilation. An empty fileModule, for a file the index store does not
+// know yet (a new file, or no index store), passes.
+func CheckSourceModule(bs *Settings, sourceFile, fileModule string) error {
+	if fileModule == "" || fileModule == bs.ModuleName {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is compiled into module %s, but axe previews the app module %s; "+
+		"add a #Preview of the view to a file of the app target instead",
+		ErrPackageTarget, filepath.Base(sourceFile), fileModule, bs.ModuleName)
+}
@@ -0,0 +1,30 @@
+package build
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckSourceModule(t *testing.T) {
+	t.Parallel()
+
+	bs := &Settings{ModuleName: "App"}
+	const file = "/repo/Packages/Feature/Sources/Feature/FeedView.swift"
+
+	for _, module := range []string{"App", ""} {
+		if err := CheckSourceModule(bs, file, module); err != nil {
+			t.Errorf("CheckSourceModule(module %q) = %v, want nil", module, err)
+		}
+	}
+
+	err := CheckSourceModule(bs, file, "Feature")
+	if !errors.Is(err, ErrPackageTarget) {
+		t.Fatalf("err = %v, want ErrPackageTarget", err)
+	}
+	for _, want := range []string{"FeedView.swift", "module Feature", "app module App"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -11,14 +11,23 @@ import (
 //
 // Concurrent callers that arrive while a Prepare is already in-flight
 // block on the same operation rather than starting a second one.
+// The in-flight build is reference-counted: it is cancelled (terminating
+// the xcodebuild process group) only once every caller waiting on it has
+// given up, so one stream going away never aborts a build another depends on.
 type Preparer struct {
 	mu     sync.Mutex
 	flight *inFlight
 	cache  *Result
 	pc     ProjectConfig
 	dirs   ProjectDirs
-	reuse  bool
+	mode   Mode
 	r      Runner
+
+	// OnBuilt, if set, is called once for every pipeline run in which
+	// xcodebuild ran and succeeded, before the callers waiting on it are
+	// released. It is not called for a reused build or a cache hit. Set it
+	// before the first Prepare.
+	OnBuilt func(*Result)
 }
 
 // inFlight represents a Prepare() call that is currently executing.
@@ -27,15 +36,20 @@ type inFlight struct {
 	done chan struct{}
 	res  *Result
 	err  error
+
+	// waiters counts callers still waiting on done; guarded by Preparer.mu.
+	// cancel aborts the build once waiters drops to zero.
+	waiters int
+	cancel  context.CancelFunc
 }
 
 // NewPreparer creates a Preparer for the given project configuration.
-func NewPreparer(pc ProjectConfig, dirs ProjectDirs, reuse bool, r Runner) *Preparer {
+func NewPreparer(pc ProjectConfig, dirs ProjectDirs, mode Mode, r Runner) *Preparer {
 	return &Preparer{
-		pc:    pc,
-		dirs:  dirs,
-		reuse: reuse,
-		r:     r,
+		pc:   pc,
+		dirs: dirs,
+		mode: mode,
+		r:    r,
 	}
 }
 
@@ -55,43 +69,72 @@ func (p *Preparer) Prepare(ctx context.Context) (*Result, error) {
 		return r, nil
 	}
 
-	// In-flight: another goroutine is already running Prepare.
-	// Wait for it to finish, but bail out if our own context is cancelled.
-	if p.flight != nil {
-		f := p.flight
-		p.mu.Unlock()
-		select {
-		case <-f.done:
-			if f.err != nil {
-				return nil, f.err
-			}
-			r := cloneResult(f.res)
-			r.Built = false
-			return r, nil
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
+	// In-flight: join the running build. Otherwise start a new one on a
+	// context detached from ours so that it outlives an early-leaving caller;
+	// it is cancelled explicitly when the last waiter leaves.
+	leader := false
+	f := p.flight
+	if f == nil {
+		buildCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &inFlight{done: make(chan struct{}), cancel: cancel}
+		p.flight = f
+		leader = true
+		go p.run(buildCtx, f)
 	}
-
-	// Miss: start a new Prepare.
-	f := &inFlight{done: make(chan struct{})}
-	p.flight = f
+	f.waiters++
 	p.mu.Unlock()
 
-	f.res, f.err = Prepare(ctx, p.pc, p.dirs, p.reuse, p.r)
-	close(f.done)
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		p.leave(f)
+		return nil, ctx.Err()
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	r := cloneResult(f.res)
+	if !leader {
+		r.Built = false
+	}
+	return r, nil
+}
+
+// run executes the build pipeline for f and publishes its result.
+func (p *Preparer) run(ctx context.Context, f *inFlight) {
+	defer f.cancel()
+	res, err := Prepare(ctx, p.pc, p.dirs, p.mode, p.r)
 
 	p.mu.Lock()
-	p.flight = nil
-	if f.err == nil {
-		p.cache = f.res
+	f.res, f.err = res, err
+	if p.flight == f {
+		p.flight = nil
+		if err == nil {
+			p.cache = res
+		}
 	}
 	p.mu.Unlock()
+	if err == nil && res.Built && p.OnBuilt != nil {
+		p.OnBuilt(cloneResult(res))
+	}
+	close(f.done)
+}
 
-	if f.err != nil {
-		return nil, f.err
+// leave deregisters a caller that stopped waiting on f. When no caller is
+// left the build is cancelled and detached so the next Prepare starts afresh
+// instead of joining a build that is being torn down.
+func (p *Preparer) leave(f *inFlight) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	if p.flight == f {
+		p.flight = nil
 	}
-	return cloneResult(f.res), nil
+	f.cancel()
 }
 
 // Invalidate clears the cached result so that the next Prepare() call
@@ -123,5 +166,6 @@ func cloneResult(in *Result) *Result {
 		Settings: in.Settings.Clone(),
 		Dirs:     in.Dirs,
 		Built:    in.Built,
+		Warnings: in.Warnings,
 	}
 }
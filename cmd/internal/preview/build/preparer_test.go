@@ -6,6 +6,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // countingRunner tracks how many times FetchBuildSettings and Build are called.
@@ -28,15 +29,18 @@ func (c *countingRunner) Build(_ context.Context, _ []string) ([]byte, error) {
 	return c.buildOutput, c.buildErr
 }
 
-const validOutput = `    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`
+const validOutput = `[{"action":"build","target":"TestModule","buildSettings":{
+  "BUILT_PRODUCTS_DIR":"/tmp/build/Build/Products/Debug-iphonesimulator",
+  "FULL_PRODUCT_NAME":"TestModule.app",
+  "IPHONEOS_DEPLOYMENT_TARGET":"17.0",
+  "PRODUCT_BUNDLE_IDENTIFIER":"com.example.TestModule",
+  "PRODUCT_MODULE_NAME":"TestModule",
+  "PRODUCT_TYPE":"com.apple.product-type.application"}}]`
 
 func newTestPreparer(r Runner) *Preparer {
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
 	dirs := ProjectDirs{Build: "/tmp/build"}
-	return NewPreparer(pc, dirs, false, r)
+	return NewPreparer(pc, dirs, Incremental, r)
 }
 
 func TestPreparer_FirstCall(t *testing.T) {
@@ -180,6 +184,41 @@ func TestPreparer_InFlightWait(t *testing.T) {
 	}
 }
 
+// TestPreparer_OnBuiltOncePerBuild verifies that callers sharing one build
+// see one OnBuilt call, and that a cache hit does not report another.
+func TestPreparer_OnBuiltOncePerBuild(t *testing.T) {
+	t.Parallel()
+
+	gate := make(chan struct{})
+	entered := make(chan struct{})
+	r := &gatedRunner{output: []byte(validOutput), gate: gate, entered: entered}
+	p := NewPreparer(ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}, ProjectDirs{Build: t.TempDir()}, Incremental, r)
+	var built atomic.Int32
+	p.OnBuilt = func(*Result) { built.Add(1) }
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Prepare(context.Background()); err != nil {
+				t.Errorf("Prepare: %v", err)
+			}
+		}()
+	}
+	<-entered
+	waitForWaiters(t, p, 2)
+	close(gate)
+	wg.Wait()
+
+	if _, err := p.Prepare(context.Background()); err != nil {
+		t.Fatalf("cached Prepare: %v", err)
+	}
+	if got := built.Load(); got != 1 {
+		t.Errorf("OnBuilt called %d times, want 1", got)
+	}
+}
+
 func TestPreparer_ErrorNotCached(t *testing.T) {
 	t.Parallel()
 
@@ -392,3 +431,123 @@ func (g *gatedRunner) FetchBuildSettings(_ context.Context, _ []string) ([]byte,
 func (g *gatedRunner) Build(_ context.Context, _ []string) ([]byte, error) {
 	return []byte("BUILD SUCCEEDED"), nil
 }
+
+func TestPreparer_LastWaiterCancelsBuild(t *testing.T) {
+	t.Parallel()
+
+	r := &blockingRunner{entered: make(chan struct{}), cancelled: make(chan struct{})}
+	p := newTestPreparer(r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := p.Prepare(ctx)
+		errCh <- err
+	}()
+
+	<-r.entered
+	cancel()
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-r.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("build context was not cancelled after the only waiter left")
+	}
+}
+
+func TestPreparer_SharedBuildSurvivesOneWaiterLeaving(t *testing.T) {
+	t.Parallel()
+
+	r := &blockingRunner{
+		output:    []byte(validOutput),
+		entered:   make(chan struct{}),
+		cancelled: make(chan struct{}),
+		gate:      make(chan struct{}),
+	}
+	p := newTestPreparer(r)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	err1 := make(chan error, 1)
+	go func() {
+		_, err := p.Prepare(ctx1)
+		err1 <- err
+	}()
+	<-r.entered
+
+	res2 := make(chan *Result, 1)
+	err2 := make(chan error, 1)
+	go func() {
+		res, err := p.Prepare(context.Background())
+		res2 <- res
+		err2 <- err
+	}()
+
+	// Wait until the second caller has joined the in-flight build.
+	waitForWaiters(t, p, 2)
+
+	cancel1()
+	if err := <-err1; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-r.cancelled:
+		t.Fatal("build was cancelled while another caller still depends on it")
+	default:
+	}
+
+	close(r.gate)
+	if err := <-err2; err != nil {
+		t.Fatalf("follower err = %v", err)
+	}
+	if res := <-res2; res.Settings.ModuleName != "TestModule" {
+		t.Errorf("ModuleName = %q, want %q", res.Settings.ModuleName, "TestModule")
+	}
+	if p.Cached() == nil {
+		t.Error("expected the shared build result to be cached")
+	}
+}
+
+func waitForWaiters(t *testing.T, p *Preparer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		got := 0
+		if p.flight != nil {
+			got = p.flight.waiters
+		}
+		p.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters", n)
+}
+
+// blockingRunner blocks FetchBuildSettings until gate is closed or the
+// build context is cancelled. cancelled is closed when the latter happens.
+type blockingRunner struct {
+	output    []byte
+	gate      chan struct{}
+	entered   chan struct{}
+	cancelled chan struct{}
+}
+
+func (b *blockingRunner) FetchBuildSettings(ctx context.Context, _ []string) ([]byte, error) {
+	close(b.entered)
+	select {
+	case <-b.gate:
+		return b.output, nil
+	case <-ctx.Done():
+		close(b.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
+func (b *blockingRunner) Build(_ context.Context, _ []string) ([]byte, error) {
+	return []byte("BUILD SUCCEEDED"), nil
+}
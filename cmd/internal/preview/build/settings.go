@@ -1,5 +1,11 @@
 package build
 
+import (
+	"path/filepath"
+
+	"github.com/k-kohey/axe/internal/preview/codegen"
+)
+
 // Settings holds values extracted from xcodebuild -showBuildSettings,
 // plus additional compiler paths extracted from the swiftc response file.
 type Settings struct {
@@ -7,13 +13,59 @@ type Settings struct {
 	BundleID         string // axe-prefixed bundle ID (used for terminate/launch)
 	OriginalBundleID string // original bundle ID from xcodebuild
 	BuiltProductsDir string
+	ProductName      string // FULL_PRODUCT_NAME of the app, e.g. "MyApp.app"
+	ExecutableName   string // EXECUTABLE_NAME, the app's process name
 	DeploymentTarget string
 	SwiftVersion     string
+	Toolchain        string // ProjectConfig.Toolchain the app is built with
 
 	// Fields below are populated by ExtractCompilerPaths after build.
 	ExtraIncludePaths   []string // additional -I paths (SPM C module headers)
 	ExtraFrameworkPaths []string // additional -F paths (e.g. PackageFrameworks)
 	ExtraModuleMapFiles []string // -fmodule-map-file= paths (generated ObjC module maps)
+
+	// MockSources are preview-only Swift files (--mock) compiled into every
+	// thunk. Set by the preview layer, not by xcodebuild.
+	MockSources []string
+
+	// ThunkImports are extra modules (--thunk-import) imported by every
+	// thunk. Set by the preview layer, not by xcodebuild.
+	ThunkImports []string
+
+	// SeedDir is a directory (--seed) mirrored into the app's data container
+	// after every install. Set by the preview layer, not by xcodebuild.
+	SeedDir string
+
+	// KeepThunk keeps the generated thunk sources (--keep-thunk) instead
+	// of cleaning them up after each reload. Set by the preview layer, not
+	// by xcodebuild.
+	KeepThunk bool
+
+	// Layout is the iPad multitasking layout (--layout, --size-class)
+	// rendered by the main thunk. Set by the preview layer, not by
+	// xcodebuild.
+	Layout codegen.Layout
+
+	// InitArgs is the view expression (--init-args) previewed instead of
+	// the #Preview body of InitArgsFile, the file it was given for. Set by
+	// the preview layer, not by xcodebuild; see InitArgsFor.
+	InitArgs     string
+	InitArgsFile string
+}
+
+// InitArgsFor returns InitArgs if file is the file it was given for, and
+// "" for any other file, such as one the watcher switched to, whose views
+// the expression does not construct.
+func (s *Settings) InitArgsFor(file string) string {
+	if s.InitArgs == "" || filepath.Clean(file) != filepath.Clean(s.InitArgsFile) {
+		return ""
+	}
+	return s.InitArgs
+}
+
+// AppPath returns the path of the app bundle xcodebuild builds.
+func (s *Settings) AppPath() string {
+	return filepath.Join(s.BuiltProductsDir, s.ProductName)
 }
 
 // Clone returns a deep copy of the Settings. Use this when multiple goroutines
@@ -24,5 +76,7 @@ func (s *Settings) Clone() *Settings {
 	c.ExtraIncludePaths = append([]string(nil), s.ExtraIncludePaths...)
 	c.ExtraFrameworkPaths = append([]string(nil), s.ExtraFrameworkPaths...)
 	c.ExtraModuleMapFiles = append([]string(nil), s.ExtraModuleMapFiles...)
+	c.MockSources = append([]string(nil), s.MockSources...)
+	c.ThunkImports = append([]string(nil), s.ThunkImports...)
 	return &c
 }
@@ -10,6 +10,7 @@ func TestSettingsClone_DeepCopySlices(t *testing.T) {
 		ExtraIncludePaths:   []string{"/I/orig"},
 		ExtraFrameworkPaths: []string{"/F/orig"},
 		ExtraModuleMapFiles: []string{"/M/orig.modulemap"},
+		MockSources:         []string{"/mocks/orig.swift"},
 	}
 
 	clone := orig.Clone()
@@ -20,6 +21,7 @@ func TestSettingsClone_DeepCopySlices(t *testing.T) {
 	clone.ExtraIncludePaths[0] = "/I/clone"
 	clone.ExtraFrameworkPaths[0] = "/F/clone"
 	clone.ExtraModuleMapFiles[0] = "/M/clone.modulemap"
+	clone.MockSources[0] = "/mocks/clone.swift"
 
 	clone.ExtraIncludePaths = append(clone.ExtraIncludePaths, "/I/clone2")
 	clone.ExtraFrameworkPaths = append(clone.ExtraFrameworkPaths, "/F/clone2")
@@ -34,8 +36,23 @@ func TestSettingsClone_DeepCopySlices(t *testing.T) {
 	if got := orig.ExtraModuleMapFiles[0]; got != "/M/orig.modulemap" {
 		t.Fatalf("orig modulemap path mutated: got %q", got)
 	}
+	if got := orig.MockSources[0]; got != "/mocks/orig.swift" {
+		t.Fatalf("orig mock source mutated: got %q", got)
+	}
 	if len(orig.ExtraIncludePaths) != 1 || len(orig.ExtraFrameworkPaths) != 1 || len(orig.ExtraModuleMapFiles) != 1 {
 		t.Fatalf("orig slice lengths mutated: include=%d framework=%d modulemap=%d",
 			len(orig.ExtraIncludePaths), len(orig.ExtraFrameworkPaths), len(orig.ExtraModuleMapFiles))
 	}
 }
+
+func TestSettingsInitArgsFor(t *testing.T) {
+	t.Parallel()
+
+	s := &Settings{InitArgs: `MyView(title: "Hi")`, InitArgsFile: "/src/./MyView.swift"}
+	if got := s.InitArgsFor("/src/MyView.swift"); got != s.InitArgs {
+		t.Errorf("InitArgsFor(original) = %q, want %q", got, s.InitArgs)
+	}
+	if got := s.InitArgsFor("/src/OtherView.swift"); got != "" {
+		t.Errorf("InitArgsFor(switched file) = %q, want empty", got)
+	}
+}
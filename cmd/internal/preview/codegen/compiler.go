@@ -2,14 +2,20 @@ package codegen
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"path/filepath"
 	"strings"
 
 	"github.com/k-kohey/axe/internal/preview/buildlock"
+	"github.com/k-kohey/axe/internal/procgroup"
 )
 
+// ErrCompileFailed is returned when swiftc fails to compile a thunk, e.g.
+// because of an error in the previewed source.
+var ErrCompileFailed = errors.New("compiling thunk")
+
 // ReplacementModuleName generates the module name for a thunk dylib, matching
 // Xcode's convention: {Module}_PreviewReplacement_{FileName}_{N}.
 func ReplacementModuleName(moduleName, sourceFileName string, counter int) string {
@@ -56,9 +62,14 @@ func compileAndLink(ctx context.Context, cfg CompileConfig, buildDir, sdk, targe
 	}
 	defer lock.RUnlock()
 
-	// .swift files -> .dylib (unified compile+link)
-	args := []string{
-		"xcrun", "swiftc",
+	// .swift files -> .dylib (unified compile+link). The thunk must be
+	// compiled by the toolchain that built the app to be ABI-compatible.
+	args := []string{"xcrun"}
+	if cfg.Toolchain != "" {
+		args = append(args, "--toolchain", cfg.Toolchain)
+	}
+	args = append(args,
+		"swiftc",
 		"-emit-library",
 		"-enforce-exclusivity=checked",
 		"-DDEBUG",
@@ -79,8 +90,9 @@ func compileAndLink(ctx context.Context, cfg CompileConfig, buildDir, sdk, targe
 		"-Xlinker", "suppress",
 		"-Xlinker", "-flat_namespace",
 		"-o", dylibPath,
-	}
+	)
 	args = append(args, thunkPaths...)
+	args = append(args, cfg.ExtraSources...)
 	for _, p := range cfg.ExtraIncludePaths {
 		args = append(args, "-I", p)
 	}
@@ -100,8 +112,21 @@ func compileAndLink(ctx context.Context, cfg CompileConfig, buildDir, sdk, targe
 	}
 	slog.Debug("Compiling thunk .swift -> .dylib", "args", args)
 	if out, err := tc.CompileSwift(ctx, args); err != nil {
-		return fmt.Errorf("compiling thunk: %w\n%s", err, out)
+		// The sources of a failed compile are never cleaned up, so point
+		// the user at them for debugging.
+		return fmt.Errorf("%w: %w\n%s\n%s", ErrCompileFailed, err, out, describeThunk(thunkPaths, args))
+	}
+	if cfg.KeepThunk {
+		// Warn so that the paths asked for show without --verbose.
+		slog.Warn("Kept thunk sources", "sources", thunkPaths, "command", procgroup.FormatCommand(args))
 	}
 
 	return nil
 }
+
+// describeThunk lists the thunk sources and the swiftc command that
+// compiled them, in a form that can be pasted into a shell.
+func describeThunk(thunkPaths, args []string) string {
+	return fmt.Sprintf("Thunk sources:\n  %s\nswiftc command:\n  %s",
+		strings.Join(thunkPaths, "\n  "), procgroup.FormatCommand(args))
+}
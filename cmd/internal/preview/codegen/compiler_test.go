@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -189,6 +190,74 @@ func TestCompileThunk_CompileSwiftArgs(t *testing.T) {
 	if !strings.Contains(compileArgs, "thunk_0__main.swift") {
 		t.Error("compile args should contain main thunk path")
 	}
+	if slices.Contains(tc.compileSwiftArgs, "--toolchain") {
+		t.Errorf("compile args should not select a toolchain by default, got: %s", compileArgs)
+	}
+}
+
+func TestCompileThunk_Toolchain(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	tc := &fakeToolchainRunner{sdkPathResult: "/sdk/iphonesimulator"}
+	cfg := CompileConfig{
+		ModuleName:       "TestModule",
+		BuiltProductsDir: filepath.Join(tmpDir, "products"),
+		DeploymentTarget: "17.0",
+		Toolchain:        "org.swift.600202409101a",
+	}
+
+	_, err := CompileThunk(
+		context.Background(),
+		[]string{filepath.Join(tmpDir, "thunk_0__main.swift")},
+		cfg, filepath.Join(tmpDir, "thunk"), tmpDir, 0, "HogeView.swift",
+		tc,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"xcrun", "--toolchain", "org.swift.600202409101a", "swiftc"}
+	if got := tc.compileSwiftArgs[:min(len(want), len(tc.compileSwiftArgs))]; !slices.Equal(got, want) {
+		t.Errorf("compile command = %v, want to start with %v", tc.compileSwiftArgs, want)
+	}
+}
+
+func TestCompileThunk_ExtraSources(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	tc := &fakeToolchainRunner{sdkPathResult: "/sdk/iphonesimulator"}
+	cfg := CompileConfig{
+		ModuleName:       "TestModule",
+		BuiltProductsDir: filepath.Join(tmpDir, "products"),
+		DeploymentTarget: "17.0",
+		ExtraSources:     []string{"/mocks/PreviewMocks.swift", "/mocks/Fixtures.swift"},
+	}
+	thunkPaths := []string{
+		filepath.Join(tmpDir, "thunk_0_HogeView.swift"),
+		filepath.Join(tmpDir, "thunk_0__main.swift"),
+	}
+
+	_, err := CompileThunk(
+		context.Background(),
+		thunkPaths,
+		cfg, filepath.Join(tmpDir, "thunk"), tmpDir, 0, "HogeView.swift",
+		tc,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Extra sources are compiled in the same invocation, right after the thunks.
+	mainIdx := slices.Index(tc.compileSwiftArgs, thunkPaths[1])
+	if mainIdx < 0 {
+		t.Fatalf("compile args missing main thunk: %v", tc.compileSwiftArgs)
+	}
+	got := tc.compileSwiftArgs[mainIdx+1 : mainIdx+1+len(cfg.ExtraSources)]
+	if !slices.Equal(got, cfg.ExtraSources) {
+		t.Errorf("args after thunks = %v, want %v", got, cfg.ExtraSources)
+	}
 }
 
 func TestCompileThunk_SwiftVersionTrimming(t *testing.T) {
@@ -319,3 +388,34 @@ func TestCompileThunk_ErrorPropagation(t *testing.T) {
 		})
 	}
 }
+
+func TestCompileThunk_FailureDescribesThunk(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	tc := &fakeToolchainRunner{
+		sdkPathResult:   "/sdk/iphonesimulator",
+		compileSwiftErr: errors.New("exit status 1"),
+	}
+	cfg := CompileConfig{
+		ModuleName:       "TestModule",
+		BuiltProductsDir: filepath.Join(tmpDir, "Build Products"),
+		DeploymentTarget: "17.0",
+	}
+	thunkPaths := []string{
+		filepath.Join(tmpDir, "thunk_0_HogeView.swift"),
+		filepath.Join(tmpDir, "thunk_0__main.swift"),
+	}
+
+	_, err := CompileThunk(context.Background(), thunkPaths, cfg, filepath.Join(tmpDir, "thunk"), tmpDir, 0, "HogeView.swift", tc)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// The error points at the kept sources and the exact swiftc command,
+	// with paths containing spaces quoted for the shell.
+	for _, want := range append(thunkPaths, "xcrun swiftc -emit-library", `"`+cfg.BuiltProductsDir+`"`) {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
@@ -7,8 +7,18 @@ type CompileConfig struct {
 	BuiltProductsDir string
 	DeploymentTarget string
 	SwiftVersion     string
+	Toolchain        string // passed to "xcrun --toolchain"; empty uses Xcode's
 
 	ExtraIncludePaths   []string // additional -I paths (SPM C module headers)
 	ExtraFrameworkPaths []string // additional -F paths (e.g. PackageFrameworks)
 	ExtraModuleMapFiles []string // -fmodule-map-file= paths (generated ObjC module maps)
+
+	// ExtraSources are user-supplied .swift files compiled into the thunk
+	// module alongside the generated thunks (e.g. preview-only mocks).
+	ExtraSources []string
+
+	// KeepThunk logs the generated thunk sources and the swiftc command
+	// after every compile (--keep-thunk). The caller keeps the sources
+	// instead of cleaning them up in later reload cycles.
+	KeepThunk bool
 }
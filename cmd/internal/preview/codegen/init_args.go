@@ -0,0 +1,58 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// initArgsSourceName is the file name swiftc reports for diagnostics in an
+// --init-args expression, so that they point at the flag instead of into
+// the generated thunk.
+const initArgsSourceName = "--init-args"
+
+// ValidateInitArgs checks that expr, a Swift expression constructing the
+// view to preview (--init-args, e.g. `MyView(title: "Hi")`), is non-empty
+// and has balanced brackets and string literals. Whether it type-checks is
+// left to the thunk compile, whose diagnostics point at --init-args.
+func ValidateInitArgs(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return errors.New("invalid --init-args: empty expression")
+	}
+	closers := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var open []rune
+	inString := false
+	escaped := false
+	for i, c := range expr {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[' || c == '{':
+			open = append(open, c)
+		case closers[c] != 0:
+			if len(open) == 0 || open[len(open)-1] != closers[c] {
+				return fmt.Errorf("invalid --init-args %q: unbalanced %q at offset %d", expr, c, i)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if inString {
+		return fmt.Errorf("invalid --init-args %q: unterminated string literal", expr)
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("invalid --init-args %q: unclosed %q", expr, open[len(open)-1])
+	}
+	return nil
+}
+
+// initArgsBody returns the preview wrapper body for an --init-args
+// expression, mapped to the pseudo file initArgsSourceName so that swiftc
+// reports its errors as "--init-args:1:<column>: error: ...".
+func initArgsBody(expr string) string {
+	return fmt.Sprintf("        #sourceLocation(file: %q, line: 1)\n%s\n        #sourceLocation()", initArgsSourceName, expr)
+}
@@ -0,0 +1,77 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateInitArgs(t *testing.T) {
+	for _, expr := range []string{
+		`MyView(title: "Hi")`,
+		`MyView(items: [1, 2], onTap: { print("(") })`,
+		`MyView(title: "say \"hi\" \(name)")`,
+	} {
+		if err := ValidateInitArgs(expr); err != nil {
+			t.Errorf("ValidateInitArgs(%s) = %v, want nil", expr, err)
+		}
+	}
+
+	for expr, want := range map[string]string{
+		"  ":                   "empty expression",
+		`MyView(title: "Hi"`:   `unclosed '('`,
+		`MyView(title: "Hi"))`: `unbalanced ')'`,
+		`MyView(items: [1, 2)`: `unbalanced ')'`,
+		`MyView(title: "Hi)`:   "unterminated string literal",
+	} {
+		err := ValidateInitArgs(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateInitArgs(%s) = %v, want an error containing %q", expr, err, want)
+		}
+	}
+}
+
+func TestGenerateMainOnlyThunk_InitArgs(t *testing.T) {
+	dir := t.TempDir()
+	thunkDir := filepath.Join(dir, "thunk")
+	// No #Preview block: the expression provides the preview.
+	srcPath := filepath.Join(dir, "MyView.swift")
+	src := "import SwiftUI\nstruct MyView: View {\n    let title: String\n    var body: some View { Text(title) }\n}\n"
+	if err := os.WriteFile(srcPath, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	expr := `MyView(title: "Hi")`
+	thunkPaths, err := GenerateMainOnlyThunk([]string{"import SwiftUI"}, ThunkOptions{ModuleName: "MyApp", InitArgs: expr, Dir: thunkDir, SourceFile: srcPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(thunkPaths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(content)
+	for _, want := range []string{
+		"struct _AxePreviewWrapper: View {",
+		"    var body: some View {\n        #sourceLocation(file: \"--init-args\", line: 1)\n" + expr + "\n        #sourceLocation()\n    }",
+		"UIHostingController(rootView: AnyView(_AxePreviewWrapper()",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("main thunk missing %q\n\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateThunks_InvalidInitArgs(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "MyView.swift")
+	if err := os.WriteFile(srcPath, []byte("import SwiftUI\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := GenerateThunks(nil, ThunkOptions{ModuleName: "MyApp", InitArgs: `MyView(title: "Hi"`, Dir: filepath.Join(dir, "thunk"), SourceFile: srcPath})
+	if err == nil || !strings.Contains(err.Error(), "--init-args") {
+		t.Errorf("GenerateThunks error = %v, want an --init-args error", err)
+	}
+}
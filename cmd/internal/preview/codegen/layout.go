@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// layoutSpec describes how an iPad multitasking layout renders: the
+// horizontal size class the app gets and the width of its window, either
+// as a fraction of the screen or as a fixed width in points.
+type layoutSpec struct {
+	sizeClass     string
+	widthFraction float64
+	width         float64
+}
+
+// layouts maps each --layout value to its rendering configuration. Size
+// classes follow the common iPad case; a 12.9" iPad in landscape reports
+// regular width for split-half, which --size-class can reproduce.
+var layouts = map[string]layoutSpec{
+	"full-screen":      {sizeClass: "regular", widthFraction: 1},
+	"split-two-thirds": {sizeClass: "regular", widthFraction: 2.0 / 3},
+	"split-half":       {sizeClass: "compact", widthFraction: 1.0 / 2},
+	"split-one-third":  {sizeClass: "compact", widthFraction: 1.0 / 3},
+	"slide-over":       {sizeClass: "compact", width: 320},
+}
+
+// sizeClasses lists the values accepted by --size-class.
+var sizeClasses = []string{"compact", "regular"}
+
+// Layout selects the multitasking layout and horizontal size class the
+// preview renders with (--layout, --size-class). The zero value renders
+// the preview as the app's window lays it out, without overrides.
+type Layout struct {
+	Name      string // key of layouts (e.g. "split-half"); empty = no width override
+	SizeClass string // "compact" or "regular"; empty = the layout's default
+}
+
+// IsZero reports whether no layout override is requested.
+func (l Layout) IsZero() bool {
+	return l == Layout{}
+}
+
+// Validate checks that Name and SizeClass hold known values.
+func (l Layout) Validate() error {
+	var errs []error
+	if _, ok := layouts[l.Name]; l.Name != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid layout %q (valid: %s)", l.Name, strings.Join(LayoutNames(), ", ")))
+	}
+	if l.SizeClass != "" && !slices.Contains(sizeClasses, l.SizeClass) {
+		errs = append(errs, fmt.Errorf("invalid size class %q (valid: %s)", l.SizeClass, strings.Join(sizeClasses, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// LayoutNames returns the accepted --layout values, sorted.
+func LayoutNames() []string {
+	names := make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// EffectiveSizeClass returns the horizontal size class injected into the
+// preview: SizeClass if set, otherwise the layout's default, or "" when
+// neither is requested.
+func (l Layout) EffectiveSizeClass() string {
+	if l.SizeClass != "" {
+		return l.SizeClass
+	}
+	return layouts[l.Name].sizeClass
+}
+
+// widthExpr returns the Swift expression for the preview's width inside a
+// GeometryReader whose proxy is named proxy, or "" to keep the full width.
+func (l Layout) widthExpr() string {
+	spec, ok := layouts[l.Name]
+	switch {
+	case !ok || spec.widthFraction == 1:
+		return ""
+	case spec.width > 0:
+		return fmt.Sprintf("%g", spec.width)
+	default:
+		return fmt.Sprintf("proxy.size.width * %.4f", spec.widthFraction)
+	}
+}
+
+// ThunkLayout is the layout data rendered into the main thunk.
+type ThunkLayout struct {
+	SizeClass string // Swift UserInterfaceSizeClass case; empty = unchanged
+	WidthExpr string // Swift width expression; empty = full width
+}
+
+func (l Layout) thunk() ThunkLayout {
+	return ThunkLayout{SizeClass: l.EffectiveSizeClass(), WidthExpr: l.widthExpr()}
+}
@@ -0,0 +1,53 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayout_Thunk(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		layout Layout
+		want   ThunkLayout
+	}{
+		{Layout{}, ThunkLayout{}},
+		{Layout{Name: "full-screen"}, ThunkLayout{SizeClass: "regular"}},
+		{Layout{Name: "split-two-thirds"}, ThunkLayout{SizeClass: "regular", WidthExpr: "proxy.size.width * 0.6667"}},
+		{Layout{Name: "split-half"}, ThunkLayout{SizeClass: "compact", WidthExpr: "proxy.size.width * 0.5000"}},
+		{Layout{Name: "split-one-third"}, ThunkLayout{SizeClass: "compact", WidthExpr: "proxy.size.width * 0.3333"}},
+		{Layout{Name: "slide-over"}, ThunkLayout{SizeClass: "compact", WidthExpr: "320"}},
+		// --size-class overrides the layout's default.
+		{Layout{Name: "split-half", SizeClass: "regular"}, ThunkLayout{SizeClass: "regular", WidthExpr: "proxy.size.width * 0.5000"}},
+		{Layout{SizeClass: "compact"}, ThunkLayout{SizeClass: "compact"}},
+	}
+	for _, tt := range tests {
+		if got := tt.layout.thunk(); got != tt.want {
+			t.Errorf("%+v.thunk() = %+v, want %+v", tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestLayout_Validate(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range LayoutNames() {
+		if err := (Layout{Name: name}).Validate(); err != nil {
+			t.Errorf("Validate(%q): %v", name, err)
+		}
+	}
+	if err := (Layout{SizeClass: "regular"}).Validate(); err != nil {
+		t.Errorf("Validate(size class regular): %v", err)
+	}
+
+	err := Layout{Name: "split", SizeClass: "wide"}.Validate()
+	if err == nil {
+		t.Fatal("expected error for unknown layout and size class")
+	}
+	for _, want := range []string{`invalid layout "split"`, "split-half", `invalid size class "wide"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
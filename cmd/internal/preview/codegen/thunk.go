@@ -48,12 +48,22 @@ import SwiftUI
 // refresh entry point. It uses @_private(sourceFile:) import for the target file
 // so that #Preview blocks can reference private/fileprivate types defined in
 // that file.
+//
+// With a Layout, the wrapper is rendered through _AxeLayoutModifier, which
+// injects the horizontal size class and narrows the preview to the width of
+// the multitasking window.
+//
+// The wrapper is @MainActor like the #Preview closure it replaces, so preview
+// bodies may call main-actor APIs. The loader invokes axe_preview_refresh on
+// the main queue, which lets the entry point assume main-actor isolation; this
+// also keeps it valid under Swift 6 strict concurrency.
 var MainThunkTmpl = template.Must(template.New("mainThunk").Funcs(thunkFuncMap).Parse(
 	`import SwiftUI
 {{ range .ExtraImports }}{{ . }}
 {{ end }}{{ if .HasPreview }}
 @_private(sourceFile: "{{ .TargetFileName | escapeSwiftString }}") import {{ .ModuleName }}
 
+@MainActor
 struct _AxePreviewWrapper: View {
 {{ range .PreviewProps }}    {{ .Source }}
 {{ end }}
@@ -61,19 +71,32 @@ struct _AxePreviewWrapper: View {
 {{ .PreviewBody }}
     }
 }
-{{ end }}
+{{ with .Layout }}{{ if or .SizeClass .WidthExpr }}
+struct _AxeLayoutModifier: ViewModifier {
+    func body(content: Content) -> some View {
+        GeometryReader { proxy in
+            content
+{{ if .SizeClass }}                .environment(\.horizontalSizeClass, .{{ .SizeClass }})
+{{ end }}{{ if .WidthExpr }}                .frame(width: {{ .WidthExpr }})
+{{ end }}                .frame(maxWidth: .infinity, maxHeight: .infinity, alignment: .leading)
+        }
+    }
+}
+{{ end }}{{ end }}{{ end }}
 import UIKit
 
 @_cdecl("axe_preview_refresh")
 public func _axePreviewRefresh() {
 {{ if .HasPreview }}
-    let hc = UIHostingController(rootView: AnyView(_AxePreviewWrapper()))
-    for scene in UIApplication.shared.connectedScenes {
-        guard let ws = scene as? UIWindowScene else { continue }
-        guard let window = ws.windows.first else { continue }
-        window.rootViewController = hc
-        window.makeKeyAndVisible()
-        break
+    MainActor.assumeIsolated {
+        let hc = UIHostingController(rootView: AnyView(_AxePreviewWrapper(){{ if or .Layout.SizeClass .Layout.WidthExpr }}.modifier(_AxeLayoutModifier()){{ end }}))
+        for scene in UIApplication.shared.connectedScenes {
+            guard let ws = scene as? UIWindowScene else { continue }
+            guard let window = ws.windows.first else { continue }
+            window.rootViewController = hc
+            window.makeKeyAndVisible()
+            break
+        }
     }
 {{ end }}
 }
@@ -96,6 +119,32 @@ type MainThunkData struct {
 	HasPreview     bool
 	PreviewProps   []analysis.PreviewableProperty
 	PreviewBody    string
+	Layout         ThunkLayout // iPad layout overrides (--layout, --size-class)
+}
+
+// ThunkOptions configures GenerateThunks and GenerateMainOnlyThunk.
+type ThunkOptions struct {
+	// ModuleName is the app module the thunks import via @_private.
+	ModuleName string
+	// ExtraModules (--thunk-import) are imported by every thunk in addition
+	// to the imports of the source files.
+	ExtraModules []string
+	// Layout applies the iPad layout overrides to the preview.
+	Layout Layout
+	// InitArgs (--init-args), when set, is the view expression previewed
+	// instead of the selected #Preview body.
+	InitArgs string
+
+	// Dir is the directory the thunks are written to.
+	Dir string
+	// SourceFile is the file whose preview the main thunk renders.
+	SourceFile string
+	// PreviewSelector selects the #Preview block of SourceFile: a title, a
+	// 0-based index, or a /regex/. Empty selects the first.
+	PreviewSelector string
+	// ReloadCounter prefixes the thunk file names, so that each reload
+	// compiles files of its own.
+	ReloadCounter int
 }
 
 // GenerateThunks generates per-file thunks and a main thunk.
@@ -104,15 +153,9 @@ type MainThunkData struct {
 // and refresh entry point.
 //
 // Returns the list of all generated thunk paths (per-file + main).
-func GenerateThunks(
-	files []analysis.FileThunkData,
-	moduleName string,
-	thunkDir string,
-	previewSelector string,
-	targetSourceFile string,
-	reloadCounter int,
-) (thunkPaths []string, retErr error) {
+func GenerateThunks(files []analysis.FileThunkData, opts ThunkOptions) (thunkPaths []string, retErr error) {
 	slog.Debug("Generating per-file thunks")
+	moduleName, thunkDir, reloadCounter := opts.ModuleName, opts.Dir, opts.ReloadCounter
 
 	if err := os.MkdirAll(thunkDir, 0o755); err != nil {
 		return nil, fmt.Errorf("creating thunk dir: %w", err)
@@ -163,7 +206,7 @@ func GenerateThunks(
 			FileName:     f.FileName,
 			AbsPath:      f.AbsPath,
 			ModuleName:   fileModuleName,
-			ExtraImports: f.Imports,
+			ExtraImports: thunkImports(fileModuleName, f.Imports, opts.ExtraModules),
 			Types:        f.Types,
 		}
 
@@ -176,26 +219,20 @@ func GenerateThunks(
 
 	// Collect imports from all files because the preview body may reference
 	// symbols that are only imported in dependency files.
-	seenImports := make(map[string]struct{})
-	var mergedImports []string
+	var allImports []string
 	for _, f := range files {
-		for _, imp := range f.Imports {
-			if _, exists := seenImports[imp]; exists {
-				continue
-			}
-			seenImports[imp] = struct{}{}
-			mergedImports = append(mergedImports, imp)
-		}
+		allImports = append(allImports, f.Imports...)
 	}
 
 	// Build main thunk data.
 	mtd := MainThunkData{
 		ModuleName:     moduleName,
-		TargetFileName: filepath.Base(targetSourceFile),
-		ExtraImports:   mergedImports,
+		TargetFileName: filepath.Base(opts.SourceFile),
+		ExtraImports:   thunkImports(moduleName, allImports, opts.ExtraModules),
+		Layout:         opts.Layout.thunk(),
 	}
 
-	if err := resolvePreview(&mtd, targetSourceFile, previewSelector); err != nil {
+	if err := resolvePreview(&mtd, opts); err != nil {
 		return nil, err
 	}
 
@@ -212,34 +249,29 @@ func GenerateThunks(
 // GenerateMainOnlyThunk generates a single main thunk file without per-file
 // dynamic replacements. This is the "degraded" / "lightweight" thunk that only
 // contains the preview wrapper and refresh entry point. It is used when full
-// thunk compilation fails or is not requested.
+// thunk compilation fails or is not requested. imports are the import lines
+// of the source file.
 //
 // Returns the list of generated thunk paths (always a single main thunk).
-func GenerateMainOnlyThunk(
-	moduleName string,
-	thunkDir string,
-	targetSourceFile string,
-	previewSelector string,
-	imports []string,
-	reloadCounter int,
-) ([]string, error) {
-	if err := os.MkdirAll(thunkDir, 0o755); err != nil {
+func GenerateMainOnlyThunk(imports []string, opts ThunkOptions) ([]string, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
 		return nil, fmt.Errorf("creating thunk dir: %w", err)
 	}
 
-	cleanOldThunkFiles(thunkDir, reloadCounter)
+	cleanOldThunkFiles(opts.Dir, opts.ReloadCounter)
 
 	mtd := MainThunkData{
-		ModuleName:     moduleName,
-		TargetFileName: filepath.Base(targetSourceFile),
-		ExtraImports:   imports,
+		ModuleName:     opts.ModuleName,
+		TargetFileName: filepath.Base(opts.SourceFile),
+		ExtraImports:   thunkImports(opts.ModuleName, imports, opts.ExtraModules),
+		Layout:         opts.Layout.thunk(),
 	}
 
-	if err := resolvePreview(&mtd, targetSourceFile, previewSelector); err != nil {
+	if err := resolvePreview(&mtd, opts); err != nil {
 		return nil, err
 	}
 
-	mainThunkPath := filepath.Join(thunkDir, fmt.Sprintf("thunk_%d__main.swift", reloadCounter))
+	mainThunkPath := filepath.Join(opts.Dir, fmt.Sprintf("thunk_%d__main.swift", opts.ReloadCounter))
 	if err := writeTemplate(mainThunkPath, MainThunkTmpl, mtd); err != nil {
 		return nil, fmt.Errorf("generating main-only thunk: %w", err)
 	}
@@ -248,18 +280,65 @@ func GenerateMainOnlyThunk(
 	return []string{mainThunkPath}, nil
 }
 
-// resolvePreview parses #Preview blocks from targetSourceFile and populates
+// thunkImports returns the import lines of a thunk that imports moduleName
+// via @_private: the source imports, then an "import X" for each extra
+// module, without duplicates.
+//
+// A "@testable import" of another module is kept as is. axe builds every
+// target with -enable-private-imports, which also permits @testable
+// imports; only prebuilt binary modules reject it. A "@testable import" of
+// moduleName itself is dropped because the @_private import already
+// exposes its internal symbols.
+func thunkImports(moduleName string, imports, extraModules []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(line string) {
+		if seen[line] {
+			return
+		}
+		seen[line] = true
+		out = append(out, line)
+	}
+	for _, imp := range imports {
+		if imp == "@testable import "+moduleName {
+			slog.Debug("Dropping @testable import of the previewed module from the thunk", "module", moduleName)
+			continue
+		}
+		add(imp)
+	}
+	for _, m := range extraModules {
+		if m != moduleName && !seen["@testable import "+m] {
+			add("import " + m)
+		}
+	}
+	return out
+}
+
+// resolvePreview parses the #Preview blocks of opts.SourceFile and populates
 // the preview-related fields on mtd. If no previews are found, mtd is unchanged.
-func resolvePreview(mtd *MainThunkData, targetSourceFile, previewSelector string) error {
-	previews, err := analysis.PreviewBlocks(targetSourceFile)
+// A non-empty opts.InitArgs replaces the selected preview, and also provides
+// one for a file without #Preview blocks.
+func resolvePreview(mtd *MainThunkData, opts ThunkOptions) error {
+	if opts.InitArgs != "" {
+		if err := ValidateInitArgs(opts.InitArgs); err != nil {
+			return err
+		}
+		mtd.HasPreview = true
+		mtd.PreviewBody = initArgsBody(opts.InitArgs)
+		return nil
+	}
+	previews, err := analysis.PreviewBlocks(opts.SourceFile)
 	if err != nil {
 		slog.Warn("Failed to parse #Preview blocks", "err", err)
 	}
 	if len(previews) > 0 {
-		selected, err := analysis.SelectPreview(previews, previewSelector)
+		selected, err := analysis.SelectPreview(previews, opts.PreviewSelector)
 		if err != nil {
 			return err
 		}
+		if err := analysis.CheckPreviewBody(selected); err != nil {
+			return err
+		}
 		tp := analysis.TransformPreviewBlock(selected)
 		mtd.HasPreview = true
 		mtd.PreviewProps = tp.Properties
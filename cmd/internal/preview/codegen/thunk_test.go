@@ -3,6 +3,7 @@ package codegen
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -97,7 +98,7 @@ struct FugaView: View {
 		},
 	}
 
-	thunkPaths, err := GenerateThunks(files, "MyApp", thunkDir, "", targetPath, 0)
+	thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: "MyApp", Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -188,7 +189,7 @@ func TestGenerateThunks_SingleFile(t *testing.T) {
 		ftd.AbsPath = srcPath
 		files := []analysis.FileThunkData{ftd}
 
-		thunkPaths, err := GenerateThunks(files, moduleName, thunkDir, "", srcPath, 0)
+		thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: moduleName, Dir: thunkDir, SourceFile: srcPath})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -276,7 +277,7 @@ func TestGenerateThunks_SingleFile(t *testing.T) {
 			},
 		}
 
-		thunkPaths, err := GenerateThunks(files, "MyApp", thunkDir, "", srcPath, 0)
+		thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: "MyApp", Dir: thunkDir, SourceFile: srcPath})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -638,7 +639,7 @@ struct DepView: View {
 		},
 	}
 
-	thunkPaths, err := GenerateThunks(files, "MyApp", thunkDir, "", targetPath, 0)
+	thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: "MyApp", Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -653,6 +654,69 @@ struct DepView: View {
 	}
 }
 
+func TestThunkImports(t *testing.T) {
+	got := thunkImports("MyApp",
+		[]string{"import SwiftUI", "@testable import MyApp", "@testable import Core", "import SwiftUI"},
+		[]string{"DSKit", "MyApp", "Core", "SwiftUI", "DSKit"})
+	want := []string{"import SwiftUI", "@testable import Core", "import DSKit"}
+	if !slices.Equal(got, want) {
+		t.Errorf("thunkImports = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateThunks_ExtraModules(t *testing.T) {
+	dir := t.TempDir()
+	thunkDir := filepath.Join(dir, "thunk")
+
+	srcContent := `import SwiftUI
+@testable import MyApp
+struct V: View {
+    var body: some View { Text("Hi") }
+}
+#Preview {
+    V()
+}`
+	srcPath := filepath.Join(dir, "V.swift")
+	if err := os.WriteFile(srcPath, []byte(srcContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []analysis.FileThunkData{
+		{
+			FileName: "V.swift",
+			AbsPath:  srcPath,
+			Types: []analysis.TypeInfo{
+				{
+					Name:           "V",
+					Kind:           "struct",
+					InheritedTypes: []string{"View"},
+					Properties:     []analysis.PropertyInfo{{Name: "body", TypeExpr: "some View", BodyLine: 4, Source: "Text(\"Hi\")"}},
+				},
+			},
+			Imports: []string{"@testable import MyApp"},
+		},
+	}
+
+	thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: "MyApp", ExtraModules: []string{"DSKit"}, Dir: thunkDir, SourceFile: srcPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range thunkPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := string(data)
+		if !strings.Contains(content, "\nimport DSKit\n") {
+			t.Errorf("%s missing extra module import\n\n%s", filepath.Base(p), content)
+		}
+		if strings.Contains(content, "@testable import MyApp") {
+			t.Errorf("%s kept @testable import of the previewed module\n\n%s", filepath.Base(p), content)
+		}
+	}
+}
+
 func TestGenerateThunks_DuplicateBasenames(t *testing.T) {
 	dir := t.TempDir()
 	thunkDir := filepath.Join(dir, "thunk")
@@ -696,7 +760,7 @@ func TestGenerateThunks_DuplicateBasenames(t *testing.T) {
 		},
 	}
 
-	thunkPaths, err := GenerateThunks(files, "MyApp", thunkDir, "", src1, 0)
+	thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: "MyApp", Dir: thunkDir, SourceFile: src1})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -768,7 +832,7 @@ func TestGenerateThunks_CrossModuleDependency(t *testing.T) {
 		},
 	}
 
-	thunkPaths, err := GenerateThunks(files, "MainApp", thunkDir, "", targetPath, 0)
+	thunkPaths, err := GenerateThunks(files, ThunkOptions{ModuleName: "MainApp", Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -870,3 +934,68 @@ func TestTypeInfo_IsView(t *testing.T) {
 		})
 	}
 }
+
+func TestMainThunkTmpl_MainActorIsolation(t *testing.T) {
+	var buf strings.Builder
+	err := MainThunkTmpl.Execute(&buf, MainThunkData{
+		ModuleName:     "MyModule",
+		TargetFileName: "HogeView.swift",
+		HasPreview:     true,
+		PreviewBody:    "HogeView()\n    .task { await model.load() }",
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	got := buf.String()
+
+	for _, c := range []string{
+		"@MainActor\nstruct _AxePreviewWrapper: View {",
+		"MainActor.assumeIsolated {",
+		".task { await model.load() }",
+	} {
+		if !strings.Contains(got, c) {
+			t.Errorf("main thunk missing %q\n\nGot:\n%s", c, got)
+		}
+	}
+}
+
+func TestMainThunkTmpl_Layout(t *testing.T) {
+	render := func(l Layout) string {
+		t.Helper()
+		var buf strings.Builder
+		err := MainThunkTmpl.Execute(&buf, MainThunkData{
+			ModuleName:     "MyModule",
+			TargetFileName: "HogeView.swift",
+			HasPreview:     true,
+			PreviewBody:    "HogeView()",
+			Layout:         l.thunk(),
+		})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		return buf.String()
+	}
+
+	got := render(Layout{Name: "split-half"})
+	for _, c := range []string{
+		"struct _AxeLayoutModifier: ViewModifier {",
+		".environment(\\.horizontalSizeClass, .compact)",
+		".frame(width: proxy.size.width * 0.5000)",
+		"AnyView(_AxePreviewWrapper().modifier(_AxeLayoutModifier()))",
+	} {
+		if !strings.Contains(got, c) {
+			t.Errorf("split-half thunk missing %q\n\nGot:\n%s", c, got)
+		}
+	}
+
+	// A size class alone injects the environment without narrowing the view.
+	got = render(Layout{SizeClass: "regular"})
+	if !strings.Contains(got, ".environment(\\.horizontalSizeClass, .regular)") || strings.Contains(got, ".frame(width:") {
+		t.Errorf("size-class-only thunk has wrong layout\n\nGot:\n%s", got)
+	}
+
+	got = render(Layout{})
+	if strings.Contains(got, "_AxeLayoutModifier") {
+		t.Errorf("thunk without layout applies _AxeLayoutModifier\n\nGot:\n%s", got)
+	}
+}
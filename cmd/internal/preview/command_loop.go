@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
@@ -21,6 +22,133 @@ func runCommandLoop(ctx context.Context, r io.Reader, ew *protocol.EventWriter,
 	})
 }
 
+// serveCommands runs the command loop of a serve session (runOnceCommandLoop
+// with once) until r is exhausted, the --once stream is done, idleExit
+// fires, or a Shutdown command arrives. It then stops dispatching commands,
+// tears every stream down, shutting down the pooled simulators, and
+// garbage-collects the pool. After a Shutdown it also removes the session
+// directories of the streams, sends ShutdownComplete as the last event and
+// returns nil so that serve exits with status 0.
+//
+// On Shutdown and idle exit the reader goroutine is left blocked on r.
+func serveCommands(ctx context.Context, r io.Reader, ew *protocol.EventWriter, sm *StreamManager, once bool, idleExit <-chan struct{}) error {
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	defer stopLoop()
+
+	shutdown := make(chan struct{}, 1)
+	sm.onShutdown = func() {
+		select {
+		case shutdown <- struct{}{}:
+		default:
+		}
+	}
+
+	loopDone := make(chan error, 1)
+	go func() {
+		if once {
+			loopDone <- runOnceCommandLoop(loopCtx, r, ew, sm)
+			return
+		}
+		runCommandLoop(loopCtx, r, ew, sm)
+		loopDone <- nil
+	}()
+
+	var loopErr error
+	requested := false
+	select {
+	case loopErr = <-loopDone:
+	case <-idleExit:
+		slog.Info("Exiting after idle shutdown")
+	case <-shutdown:
+		requested = true
+	}
+	if !requested {
+		// A Shutdown may be the last command before r ends.
+		select {
+		case <-shutdown:
+			requested = true
+		default:
+		}
+	}
+
+	sm.shuttingDown.Store(true)
+	stopLoop()
+	sm.StopAll()
+	sm.pool.GarbageCollect(ctx)
+
+	if !requested {
+		return loopErr
+	}
+	sm.removeSessionDirs()
+	if err := ew.Send(&pb.Event{Payload: &pb.Event_ShutdownComplete{ShutdownComplete: &pb.ShutdownComplete{}}}); err != nil {
+		slog.Warn("Failed to send ShutdownComplete", "err", err)
+	}
+	return nil
+}
+
+// runOnceCommandLoop implements serve --once. It dispatches commands from r
+// until the first AddStream, then waits for that stream to emit its first
+// frame. It returns nil on the first frame, or an error if the stream stops
+// first or r is exhausted before any AddStream arrives. Later AddStreams are
+// ignored; other commands are still routed so inputs can reach the stream.
+func runOnceCommandLoop(ctx context.Context, r io.Reader, ew *protocol.EventWriter, sm *StreamManager) error {
+	firstFrame := make(chan struct{}, 1)
+	sm.onFrame = func(string) {
+		select {
+		case firstFrame <- struct{}{}:
+		default:
+		}
+	}
+
+	added := make(chan string, 1)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		var streamID string
+		protocol.ReadCommands(ctx, r, ew, func(cmd *pb.Command) {
+			if cmd.GetAddStream() != nil {
+				if streamID != "" {
+					slog.Warn("Ignoring additional AddStream in --once mode", "streamId", cmd.GetStreamId())
+					return
+				}
+				streamID = cmd.GetStreamId()
+				sm.HandleCommand(ctx, cmd)
+				added <- streamID
+				return
+			}
+			sm.HandleCommand(ctx, cmd)
+		})
+	}()
+
+	var streamID string
+	select {
+	case streamID = <-added:
+	case <-readerDone:
+		select {
+		case streamID = <-added:
+		default:
+			return fmt.Errorf("stdin closed before an AddStream was received")
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-firstFrame:
+		return nil
+	case <-sm.streamDone(streamID):
+		// The relay may have sent a frame just before the stream ended.
+		select {
+		case <-firstFrame:
+			return nil
+		default:
+		}
+		return fmt.Errorf("stream %s stopped before emitting a frame", streamID)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // stdinCommand represents a command received from stdin (JSON Lines protocol).
 type stdinCommand struct {
 	Type     string  `json:"type"`
@@ -3,7 +3,12 @@ package preview
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -175,3 +180,160 @@ func TestRunCommandLoop_SendsProtocolErrorForInvalidJSON(t *testing.T) {
 		t.Errorf("expected ProtocolError event in output, got: %s", output)
 	}
 }
+
+func TestRunOnceCommandLoop_ReturnsAfterFirstFrame(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManagerWithRunners(pool, ew)
+
+	var launches atomic.Int32
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		launches.Add(1)
+		udid, _ := sm.pool.Acquire(ctx, s.deviceType, s.runtime)
+		s.deviceUDID = udid
+
+		_ = sm.ew.Send(&pb.Event{
+			StreamId: s.id,
+			Payload:  &pb.Event_Frame{Frame: &pb.Frame{Device: udid, File: s.file, Data: "AAAA", Seq: 1}},
+		})
+		sm.frameSent(s.id)
+
+		<-ctx.Done()
+	}
+
+	// The second AddStream must be ignored in --once mode.
+	input := `{"streamId":"stream-a","addStream":{"file":"HogeView.swift","deviceType":"iPhone16,1","runtime":"iOS-18-0"}}
+{"streamId":"stream-b","addStream":{"file":"FugaView.swift","deviceType":"iPhone16,1","runtime":"iOS-18-0"}}
+`
+	done := make(chan error, 1)
+	go func() {
+		done <- runOnceCommandLoop(t.Context(), strings.NewReader(input), ew, sm)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runOnceCommandLoop() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("runOnceCommandLoop did not return after the first frame")
+	}
+	sm.StopAll()
+
+	if got := launches.Load(); got != 1 {
+		t.Errorf("launched %d streams, want 1", got)
+	}
+	events := filterEvents(collectEvents(t, &buf), "stream-a")
+	if len(events) == 0 || events[0].Frame == nil {
+		t.Errorf("expected a Frame event for stream-a, got %+v", events)
+	}
+}
+
+func TestRunOnceCommandLoop_StreamStopsBeforeFrame(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManagerWithRunners(pool, ew)
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		s.sendStopped(sm.ew, "build_error", "build failed", "")
+	}
+
+	input := `{"streamId":"stream-a","addStream":{"file":"HogeView.swift","deviceType":"iPhone16,1","runtime":"iOS-18-0"}}
+`
+	err := runOnceCommandLoop(t.Context(), strings.NewReader(input), ew, sm)
+	if err == nil || !strings.Contains(err.Error(), "stopped before emitting a frame") {
+		t.Fatalf("runOnceCommandLoop() error = %v, want stopped-before-frame error", err)
+	}
+}
+
+func TestRunOnceCommandLoop_NoAddStream(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+
+	err := runOnceCommandLoop(t.Context(), strings.NewReader(""), ew, sm)
+	if err == nil || !strings.Contains(err.Error(), "before an AddStream") {
+		t.Fatalf("runOnceCommandLoop() error = %v, want missing AddStream error", err)
+	}
+}
+
+// TestServeCommands_ShutdownTearsDownAndExits verifies that a Shutdown
+// command ends the session while stdin is still open: streams are torn
+// down, the pool's simulators are shut down, session directories are
+// removed, later commands are ignored, and ShutdownComplete is the last
+// event.
+func TestServeCommands_ShutdownTearsDownAndExits(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	var stopped atomic.Bool
+	session := filepath.Join(t.TempDir(), "devices", "UDID-1")
+	if err := os.MkdirAll(filepath.Join(session, "thunk"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	launch := sm.StreamLauncher
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		sm.trackSessionDir(session)
+		launch(ctx, sm, s)
+		stopped.Store(true)
+	}
+
+	stdin, w := io.Pipe()
+	defer w.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- serveCommands(t.Context(), stdin, ew, sm, false, nil)
+	}()
+
+	if _, err := io.WriteString(w, `{"streamId":"stream-a","addStream":{"file":"HogeView.swift","deviceType":"iPhone16,1","runtime":"iOS-18-0"}}`+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents(t, &buf, 1, 2*time.Second)
+	if _, err := io.WriteString(w, `{"shutdown":{}}`+"\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serveCommands() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveCommands did not return after Shutdown")
+	}
+
+	if !stopped.Load() {
+		t.Error("stream was not torn down")
+	}
+	pool.mu.Lock()
+	shutdownAll := pool.shutdownAll
+	pool.mu.Unlock()
+	if !shutdownAll {
+		t.Error("pool simulators were not shut down")
+	}
+	if _, err := os.Stat(session); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("session directory not removed: stat err = %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if last := string(lines[len(lines)-1]); !strings.Contains(last, `"shutdownComplete"`) {
+		t.Errorf("last event = %s, want ShutdownComplete", last)
+	}
+
+	sm.HandleCommand(t.Context(), &pb.Command{
+		StreamId: "stream-b",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "HogeView.swift"}},
+	})
+	sm.mu.Lock()
+	n := len(sm.streams)
+	sm.mu.Unlock()
+	if n != 0 {
+		t.Errorf("AddStream after Shutdown started %d stream(s), want none", n)
+	}
+}
@@ -2,8 +2,11 @@ package preview
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+
+	"github.com/k-kohey/axe/internal/preview/build"
 )
 
 // CompileMode represents a thunk compilation strategy.
@@ -70,7 +73,8 @@ func ExecuteCompileStrategy(ctx context.Context, strategy CompileStrategy, compi
 		return &CompileResult{DylibPath: dylibPath, Degraded: false}, nil
 	}
 
-	if strategy.Fallback == nil {
+	// A file of another module fails the same way in every mode.
+	if strategy.Fallback == nil || errors.Is(err, build.ErrPackageTarget) {
 		return nil, err
 	}
 
@@ -3,7 +3,10 @@ package preview
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+
+	"github.com/k-kohey/axe/internal/preview/build"
 )
 
 func TestNewCompileStrategy_OneshotDefault(t *testing.T) {
@@ -164,3 +167,28 @@ func TestExecuteCompileStrategy_SkipsFallbackOnCancelledContext(t *testing.T) {
 		t.Error("fallback should not be called when context is cancelled")
 	}
 }
+
+func TestExecuteCompileStrategy_SkipsFallbackForOtherModule(t *testing.T) {
+	t.Parallel()
+	mainOnly := CompileModeMainOnly
+	s := CompileStrategy{Primary: CompileModeFull, Fallback: &mainOnly}
+
+	fallbackCalled := false
+	compilers := map[CompileMode]CompileFunc{
+		CompileModeFull: func(_ context.Context) (string, error) {
+			return "", fmt.Errorf("%w: FeedView.swift", build.ErrPackageTarget)
+		},
+		CompileModeMainOnly: func(_ context.Context) (string, error) {
+			fallbackCalled = true
+			return "/path/to/main_only.dylib", nil
+		},
+	}
+
+	_, err := ExecuteCompileStrategy(context.Background(), s, compilers)
+	if !errors.Is(err, build.ErrPackageTarget) {
+		t.Fatalf("err = %v, want ErrPackageTarget", err)
+	}
+	if fallbackCalled {
+		t.Error("fallback should not be called for a file of another module")
+	}
+}
@@ -12,13 +12,15 @@ import (
 func TestStreamManager_UsesSharedPreparer(t *testing.T) {
 	t.Parallel()
 
-	output := []byte(`    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`)
+	output := showBuildSettingsOutput(build.Settings{
+		ModuleName:       "TestModule",
+		OriginalBundleID: "com.example.TestModule",
+		DeploymentTarget: "17.0",
+		BuiltProductsDir: "/tmp/build/Build/Products/Debug-iphonesimulator",
+	})
 	br := &fakeBuildRunner{fetchOutput: output}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
-	preparer := build.NewPreparer(pc, build.ProjectDirs{Build: t.TempDir()}, false, br)
+	preparer := build.NewPreparer(pc, build.ProjectDirs{Build: t.TempDir()}, build.Incremental, br)
 
 	sm := NewStreamManager(newFakeDevicePool(), protocol.NewEventWriter(&syncBuffer{}),
 		pc, "", preparer,
@@ -1,6 +1,11 @@
 package preview
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+)
 
 // fakeBuildRunner is a test double for build.Runner, used by
 // ensure_build_settings_test.go and stream_manager_test.go.
@@ -24,3 +29,32 @@ func (f *fakeBuildRunner) Build(_ context.Context, args []string) ([]byte, error
 	f.buildArgs = args
 	return f.buildOutput, f.buildErr
 }
+
+// showBuildSettingsOutput renders xcodebuild -showBuildSettings -json output
+// for a single application target with the given settings. The product name
+// defaults to ModuleName + ".app".
+func showBuildSettingsOutput(bs build.Settings) []byte {
+	if bs.ProductName == "" {
+		bs.ProductName = bs.ModuleName + ".app"
+	}
+	settings := map[string]string{
+		"PRODUCT_TYPE":               "com.apple.product-type.application",
+		"PRODUCT_MODULE_NAME":        bs.ModuleName,
+		"PRODUCT_BUNDLE_IDENTIFIER":  bs.OriginalBundleID,
+		"IPHONEOS_DEPLOYMENT_TARGET": bs.DeploymentTarget,
+		"BUILT_PRODUCTS_DIR":         bs.BuiltProductsDir,
+		"FULL_PRODUCT_NAME":          bs.ProductName,
+	}
+	if bs.SwiftVersion != "" {
+		settings["SWIFT_VERSION"] = bs.SwiftVersion
+	}
+	out, err := json.Marshal([]map[string]any{{
+		"action":        "build",
+		"target":        bs.ModuleName,
+		"buildSettings": settings,
+	}})
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
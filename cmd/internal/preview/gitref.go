@@ -0,0 +1,214 @@
+package preview
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/k-kohey/axe/internal/preview/runner"
+)
+
+// gitRefPlan describes how a preview of a git ref is materialized: which
+// commit is checked out into which worktree, and the run options rewritten
+// to point into that worktree.
+type gitRefPlan struct {
+	RepoRoot   string
+	Ref        string
+	Commit     string
+	Worktree   string
+	Submodules bool       // the commit has a .gitmodules to check out
+	Opts       RunOptions // paths inside RepoRoot moved into Worktree
+}
+
+// RunGitRef runs a oneshot preview of opts.SourceFile as it exists at the
+// git ref, without touching the working tree. The commit is checked out
+// into a detached worktree under the cache root, previewed from there, and
+// the worktree is removed afterwards. The whole tree is checked out rather
+// than just the source file because xcodebuild needs the full project, but
+// git shares the object store so no history is copied.
+//
+// The worktree path is derived from the repository and commit, so a second
+// preview of the same commit reuses the build cache of the first.
+func RunGitRef(opts RunOptions, ref string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	git := &runner.Git{}
+	plan, err := planGitRef(ctx, git, opts, ref, build.CacheRoot())
+	if err != nil {
+		return err
+	}
+	slog.Debug("Materializing git ref", "ref", ref, "commit", plan.Commit, "worktree", plan.Worktree)
+
+	// Cleanup must run even after Ctrl+C cancelled ctx.
+	cleanup, err := materializeGitRef(ctx, git, plan)
+	if err != nil {
+		return err
+	}
+	defer cleanup(context.WithoutCancel(ctx))
+
+	return Run(plan.Opts)
+}
+
+// planGitRef resolves ref in the repository containing the project and
+// rewrites opts to the worktree that will hold it. It fails when the
+// project is not in a git repository, the ref is unknown, the project or
+// source file does not exist at the ref, or a --mock / --seed dependency
+// inside the repository has uncommitted changes that the ref cannot carry.
+func planGitRef(ctx context.Context, git GitRunner, opts RunOptions, ref, worktreeBase string) (gitRefPlan, error) {
+	primary := opts.PC.PrimaryPath()
+	out, err := git.Run(ctx, filepath.Dir(primary), "rev-parse", "--show-toplevel")
+	if err != nil {
+		return gitRefPlan{}, fmt.Errorf("--git-ref requires a git repository, but %s is not in one: %w", primary, err)
+	}
+	root := strings.TrimSpace(string(out))
+
+	out, err = git.Run(ctx, root, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return gitRefPlan{}, fmt.Errorf("unknown git ref %q: %w", ref, err)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	h := sha256.Sum256([]byte(root + "\x00" + commit))
+	plan := gitRefPlan{
+		RepoRoot: root,
+		Ref:      ref,
+		Commit:   commit,
+		Worktree: filepath.Join(worktreeBase, fmt.Sprintf("gitref-%x", h[:8])),
+		Opts:     opts,
+	}
+
+	// existsAtRef moves a required path into the worktree after checking
+	// that the commit contains it.
+	existsAtRef := func(p string) (string, error) {
+		rel, ok := repoRelative(root, p)
+		if !ok {
+			return "", fmt.Errorf("--git-ref: %s is outside the repository %s", p, root)
+		}
+		if _, err := git.Run(ctx, root, "cat-file", "-e", commit+":"+filepath.ToSlash(rel)); err != nil {
+			return "", fmt.Errorf("--git-ref: %s does not exist at %s", rel, ref)
+		}
+		return filepath.Join(plan.Worktree, rel), nil
+	}
+	if plan.Opts.SourceFile, err = existsAtRef(opts.SourceFile); err != nil {
+		return gitRefPlan{}, err
+	}
+	if opts.PC.AppSource != "" {
+		plan.Opts.PC.AppSource = plan.Opts.SourceFile
+	}
+	if opts.PC.Workspace != "" {
+		if plan.Opts.PC.Workspace, err = existsAtRef(opts.PC.Workspace); err != nil {
+			return gitRefPlan{}, err
+		}
+	}
+	if opts.PC.Project != "" {
+		if plan.Opts.PC.Project, err = existsAtRef(opts.PC.Project); err != nil {
+			return gitRefPlan{}, err
+		}
+	}
+
+	// Preview dependencies outside the repository are used as they are.
+	// Those inside it are taken from the ref, which is only what the user
+	// expects if the working tree does not differ from HEAD there.
+	deps := append([]string(nil), opts.MockSources...)
+	if opts.SeedDir != "" {
+		deps = append(deps, opts.SeedDir)
+	}
+	var inRepo []string
+	moved := make(map[string]string)
+	for _, d := range deps {
+		if rel, ok := repoRelative(root, d); ok {
+			inRepo = append(inRepo, filepath.ToSlash(rel))
+			moved[d] = filepath.Join(plan.Worktree, rel)
+		}
+	}
+	if len(inRepo) > 0 {
+		out, err := git.Run(ctx, root, append([]string{"status", "--porcelain", "--untracked-files=all", "--"}, inRepo...)...)
+		if err != nil {
+			return gitRefPlan{}, fmt.Errorf("checking preview dependencies: %w", err)
+		}
+		if dirty := strings.TrimSpace(string(out)); dirty != "" {
+			return gitRefPlan{}, fmt.Errorf("--git-ref: preview dependencies have uncommitted changes that %s would not include; commit or stash them, or move them outside the repository:\n%s", ref, dirty)
+		}
+		for _, rel := range inRepo {
+			if _, err := git.Run(ctx, root, "cat-file", "-e", commit+":"+rel); err != nil {
+				return gitRefPlan{}, fmt.Errorf("--git-ref: %s does not exist at %s", rel, ref)
+			}
+		}
+	}
+	if len(opts.MockSources) > 0 {
+		plan.Opts.MockSources = make([]string, len(opts.MockSources))
+		for i, m := range opts.MockSources {
+			plan.Opts.MockSources[i] = cmp.Or(moved[m], m)
+		}
+	}
+	if opts.SeedDir != "" {
+		plan.Opts.SeedDir = cmp.Or(moved[opts.SeedDir], opts.SeedDir)
+	}
+
+	if _, err := git.Run(ctx, root, "cat-file", "-e", commit+":.gitmodules"); err == nil {
+		plan.Submodules = true
+	}
+	return plan, nil
+}
+
+// materializeGitRef checks plan.Commit out into plan.Worktree and returns
+// a function that removes the worktree again. A worktree left at that path
+// by an interrupted run is removed first.
+func materializeGitRef(ctx context.Context, git GitRunner, plan gitRefPlan) (func(context.Context), error) {
+	cleanup := func(ctx context.Context) { removeGitWorktree(ctx, git, plan) }
+	if _, err := os.Stat(plan.Worktree); err == nil {
+		cleanup(ctx)
+	}
+	if _, err := git.Run(ctx, plan.RepoRoot, "worktree", "add", "--detach", plan.Worktree, plan.Commit); err != nil {
+		return nil, fmt.Errorf("checking out %s: %w", plan.Ref, err)
+	}
+	if plan.Submodules {
+		if _, err := git.Run(ctx, plan.Worktree, "submodule", "update", "--init", "--recursive"); err != nil {
+			cleanup(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("checking out submodules at %s: %w", plan.Ref, err)
+		}
+	}
+	return cleanup, nil
+}
+
+// removeGitWorktree removes plan.Worktree and unregisters it from the
+// repository. Failures are logged since they only leave stale files behind.
+func removeGitWorktree(ctx context.Context, git GitRunner, plan gitRefPlan) {
+	_, err := git.Run(ctx, plan.RepoRoot, "worktree", "remove", "--force", plan.Worktree)
+	if err == nil {
+		return
+	}
+	slog.Debug("git worktree remove failed, deleting directly", "path", plan.Worktree, "err", err)
+	if err := os.RemoveAll(plan.Worktree); err != nil {
+		slog.Warn("Failed to remove git worktree", "path", plan.Worktree, "err", err)
+	}
+	if _, err := git.Run(ctx, plan.RepoRoot, "worktree", "prune"); err != nil {
+		slog.Warn("Failed to prune git worktrees", "repo", plan.RepoRoot, "err", err)
+	}
+}
+
+// repoRelative returns p relative to the repository root, and false when
+// p lies outside it. Symlinks are resolved on both sides because git
+// reports the physical top-level directory (e.g. /private/var on macOS).
+func repoRelative(root, p string) (string, bool) {
+	resolve := func(s string) string {
+		if r, err := filepath.EvalSymlinks(s); err == nil {
+			return r
+		}
+		return s
+	}
+	rel, err := filepath.Rel(resolve(root), resolve(p))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
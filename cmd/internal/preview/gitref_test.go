@@ -0,0 +1,271 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeGitRunner answers git commands from a table keyed by the
+// space-joined arguments. Unlisted commands fail like a git error.
+type fakeGitRunner struct {
+	out   map[string]string
+	calls []string // "<dir>: <args>"
+}
+
+func (f *fakeGitRunner) Run(_ context.Context, dir string, args ...string) ([]byte, error) {
+	key := strings.Join(args, " ")
+	f.calls = append(f.calls, dir+": "+key)
+	if out, ok := f.out[key]; ok {
+		return []byte(out), nil
+	}
+	return nil, errors.New("exit status 128")
+}
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+// newRepoGit returns a fake for a repository at /repo where ref resolves
+// to testCommit and the given paths exist at that commit.
+func newRepoGit(ref string, paths ...string) *fakeGitRunner {
+	f := &fakeGitRunner{out: map[string]string{
+		"rev-parse --show-toplevel":                       "/repo\n",
+		"rev-parse --verify --quiet " + ref + "^{commit}": testCommit + "\n",
+	}}
+	for _, p := range paths {
+		f.out["cat-file -e "+testCommit+":"+p] = ""
+	}
+	return f
+}
+
+func TestPlanGitRef(t *testing.T) {
+	opts := RunOptions{
+		SourceFile:  "/repo/App/Views/Profile.swift",
+		PC:          ProjectConfig{Project: "/repo/App.xcodeproj", Scheme: "App"},
+		MockSources: []string{"/repo/Mocks/ProfileMock.swift", "/elsewhere/Stub.swift"},
+		SeedDir:     "/repo/Seed",
+	}
+	git := newRepoGit("feature", "App/Views/Profile.swift", "App.xcodeproj", "Mocks/ProfileMock.swift", "Seed")
+	git.out["status --porcelain --untracked-files=all -- Mocks/ProfileMock.swift Seed"] = ""
+
+	plan, err := planGitRef(context.Background(), git, opts, "feature", "/cache")
+	if err != nil {
+		t.Fatalf("planGitRef: %v", err)
+	}
+
+	if plan.RepoRoot != "/repo" || plan.Commit != testCommit {
+		t.Errorf("RepoRoot=%q Commit=%q", plan.RepoRoot, plan.Commit)
+	}
+	if filepath.Dir(plan.Worktree) != "/cache" || !strings.HasPrefix(filepath.Base(plan.Worktree), "gitref-") {
+		t.Errorf("Worktree = %q, want /cache/gitref-<hash>", plan.Worktree)
+	}
+	wt := plan.Worktree
+	if want := filepath.Join(wt, "App/Views/Profile.swift"); plan.Opts.SourceFile != want {
+		t.Errorf("SourceFile = %q, want %q", plan.Opts.SourceFile, want)
+	}
+	if want := filepath.Join(wt, "App.xcodeproj"); plan.Opts.PC.Project != want {
+		t.Errorf("Project = %q, want %q", plan.Opts.PC.Project, want)
+	}
+	if plan.Opts.PC.Scheme != "App" {
+		t.Errorf("Scheme = %q, want App", plan.Opts.PC.Scheme)
+	}
+	wantMocks := []string{filepath.Join(wt, "Mocks/ProfileMock.swift"), "/elsewhere/Stub.swift"}
+	if !slices.Equal(plan.Opts.MockSources, wantMocks) {
+		t.Errorf("MockSources = %v, want %v", plan.Opts.MockSources, wantMocks)
+	}
+	if want := filepath.Join(wt, "Seed"); plan.Opts.SeedDir != want {
+		t.Errorf("SeedDir = %q, want %q", plan.Opts.SeedDir, want)
+	}
+	if plan.Submodules {
+		t.Error("Submodules should be false without .gitmodules at the ref")
+	}
+	// The caller's options must not be modified.
+	if opts.MockSources[0] != "/repo/Mocks/ProfileMock.swift" {
+		t.Errorf("planGitRef modified the input options: %v", opts.MockSources)
+	}
+
+	// Same repository and commit map to the same worktree so the build
+	// cache is reused.
+	again, err := planGitRef(context.Background(), git, opts, "feature", "/cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Worktree != wt {
+		t.Errorf("worktree not stable: %q vs %q", again.Worktree, wt)
+	}
+}
+
+func TestPlanGitRef_Submodules(t *testing.T) {
+	opts := RunOptions{
+		SourceFile: "/repo/View.swift",
+		PC:         ProjectConfig{Workspace: "/repo/App.xcworkspace"},
+	}
+	git := newRepoGit("main", "View.swift", "App.xcworkspace", ".gitmodules")
+
+	plan, err := planGitRef(context.Background(), git, opts, "main", "/cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Submodules {
+		t.Error("Submodules should be true when .gitmodules exists at the ref")
+	}
+	if want := filepath.Join(plan.Worktree, "App.xcworkspace"); plan.Opts.PC.Workspace != want {
+		t.Errorf("Workspace = %q, want %q", plan.Opts.PC.Workspace, want)
+	}
+}
+
+func TestPlanGitRef_Errors(t *testing.T) {
+	base := RunOptions{
+		SourceFile: "/repo/View.swift",
+		PC:         ProjectConfig{Project: "/repo/App.xcodeproj"},
+	}
+	tests := []struct {
+		name    string
+		opts    func(RunOptions) RunOptions
+		git     func() *fakeGitRunner
+		wantErr string
+	}{
+		{
+			name:    "not a git repository",
+			git:     func() *fakeGitRunner { return &fakeGitRunner{} },
+			wantErr: "requires a git repository",
+		},
+		{
+			name: "unknown ref",
+			git: func() *fakeGitRunner {
+				return &fakeGitRunner{out: map[string]string{"rev-parse --show-toplevel": "/repo\n"}}
+			},
+			wantErr: `unknown git ref "feature"`,
+		},
+		{
+			name:    "source file missing at ref",
+			git:     func() *fakeGitRunner { return newRepoGit("feature", "App.xcodeproj") },
+			wantErr: "View.swift does not exist at feature",
+		},
+		{
+			name: "source file outside repository",
+			opts: func(o RunOptions) RunOptions {
+				o.SourceFile = "/other/View.swift"
+				return o
+			},
+			git:     func() *fakeGitRunner { return newRepoGit("feature", "View.swift", "App.xcodeproj") },
+			wantErr: "outside the repository",
+		},
+		{
+			name: "dirty mock",
+			opts: func(o RunOptions) RunOptions {
+				o.MockSources = []string{"/repo/Mock.swift"}
+				return o
+			},
+			git: func() *fakeGitRunner {
+				g := newRepoGit("feature", "View.swift", "App.xcodeproj", "Mock.swift")
+				g.out["status --porcelain --untracked-files=all -- Mock.swift"] = " M Mock.swift\n"
+				return g
+			},
+			wantErr: "uncommitted changes",
+		},
+		{
+			name: "mock missing at ref",
+			opts: func(o RunOptions) RunOptions {
+				o.MockSources = []string{"/repo/Mock.swift"}
+				return o
+			},
+			git: func() *fakeGitRunner {
+				g := newRepoGit("feature", "View.swift", "App.xcodeproj")
+				g.out["status --porcelain --untracked-files=all -- Mock.swift"] = ""
+				return g
+			},
+			wantErr: "Mock.swift does not exist at feature",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := base
+			if tt.opts != nil {
+				opts = tt.opts(opts)
+			}
+			_, err := planGitRef(context.Background(), tt.git(), opts, "feature", "/cache")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaterializeGitRef(t *testing.T) {
+	plan := gitRefPlan{
+		RepoRoot:   "/repo",
+		Ref:        "feature",
+		Commit:     testCommit,
+		Worktree:   filepath.Join(t.TempDir(), "gitref-missing"),
+		Submodules: true,
+	}
+	git := &fakeGitRunner{out: map[string]string{
+		"worktree add --detach " + plan.Worktree + " " + testCommit: "",
+		"submodule update --init --recursive":                       "",
+		"worktree remove --force " + plan.Worktree:                  "",
+	}}
+
+	cleanup, err := materializeGitRef(context.Background(), git, plan)
+	if err != nil {
+		t.Fatalf("materializeGitRef: %v", err)
+	}
+	cleanup(context.Background())
+
+	want := []string{
+		"/repo: worktree add --detach " + plan.Worktree + " " + testCommit,
+		plan.Worktree + ": submodule update --init --recursive",
+		"/repo: worktree remove --force " + plan.Worktree,
+	}
+	if !slices.Equal(git.calls, want) {
+		t.Errorf("calls:\n got %q\nwant %q", git.calls, want)
+	}
+}
+
+func TestMaterializeGitRef_RemovesStaleWorktree(t *testing.T) {
+	wt := filepath.Join(t.TempDir(), "gitref-stale")
+	if err := os.MkdirAll(wt, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plan := gitRefPlan{RepoRoot: "/repo", Ref: "feature", Commit: testCommit, Worktree: wt}
+	// worktree remove fails (not registered), so the directory is
+	// deleted and the worktree list pruned before checking out.
+	git := &fakeGitRunner{out: map[string]string{
+		"worktree prune": "",
+		"worktree add --detach " + wt + " " + testCommit: "",
+	}}
+
+	if _, err := materializeGitRef(context.Background(), git, plan); err != nil {
+		t.Fatalf("materializeGitRef: %v", err)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Errorf("stale worktree should have been deleted, stat err = %v", err)
+	}
+	want := []string{
+		"/repo: worktree remove --force " + wt,
+		"/repo: worktree prune",
+		"/repo: worktree add --detach " + wt + " " + testCommit,
+	}
+	if !slices.Equal(git.calls, want) {
+		t.Errorf("calls:\n got %q\nwant %q", git.calls, want)
+	}
+}
+
+func TestMaterializeGitRef_SubmoduleFailureCleansUp(t *testing.T) {
+	plan := gitRefPlan{RepoRoot: "/repo", Ref: "feature", Commit: testCommit, Worktree: filepath.Join(t.TempDir(), "wt"), Submodules: true}
+	git := &fakeGitRunner{out: map[string]string{
+		"worktree add --detach " + plan.Worktree + " " + testCommit: "",
+		"worktree remove --force " + plan.Worktree:                  "",
+	}}
+
+	_, err := materializeGitRef(context.Background(), git, plan)
+	if err == nil || !strings.Contains(err.Error(), "submodules") {
+		t.Fatalf("err = %v, want submodule error", err)
+	}
+	if last := git.calls[len(git.calls)-1]; last != "/repo: worktree remove --force "+plan.Worktree {
+		t.Errorf("worktree not removed after failure, last call %q", last)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/k-kohey/axe/internal/preview/analysis"
 	"github.com/k-kohey/axe/internal/preview/build"
@@ -25,13 +26,27 @@ func sendWatchStatus(wctx watchContext, phase string) {
 	if err := wctx.ew.Send(&pb.Event{
 		StreamId: wctx.streamID,
 		Payload: &pb.Event_StreamStatus{
-			StreamStatus: &pb.StreamStatus{Phase: phase},
+			StreamStatus: newStreamStatus(phase, wctx.accessibility, wctx.layout),
 		},
 	}); err != nil {
 		slog.Warn("Failed to send StreamStatus in watcher", "phase", phase, "err", err)
 	}
 }
 
+// sendWatchBuildFailed sends a BuildFailed event in serve mode when err is
+// a build failure (no-op otherwise).
+func sendWatchBuildFailed(wctx watchContext, err error) {
+	if !wctx.serve || wctx.ew == nil || !isBuildFailure(err) {
+		return
+	}
+	if err := wctx.ew.Send(&pb.Event{
+		StreamId: wctx.streamID,
+		Payload:  &pb.Event_BuildFailed{BuildFailed: newBuildFailed(err.Error())},
+	}); err != nil {
+		slog.Warn("Failed to send BuildFailed in watcher", "err", err)
+	}
+}
+
 // runWatcher sets up file watching and command dispatching, then delegates
 // to the unified event loop. It uses SharedWatcher for file change detection
 // and dispatchStdinCommands / dispatchProtocolCommands for stdin routing.
@@ -142,7 +157,7 @@ func reloadMultiFile(ctx context.Context, sourceFile string, bs *build.Settings,
 	for _, f := range ws.trackedFiles {
 		touchTrackedFile(ws, filepath.Clean(f))
 	}
-	cleanOldDylibs(dirs.Thunk, counter-1)
+	cleanOldDylibs(dirs.Thunk, counter-1, bs.KeepThunk)
 	ws.mu.Unlock()
 
 	return nil
@@ -183,6 +198,9 @@ func switchFile(ctx context.Context, newSourceFile string, pc ProjectConfig, bs
 	}
 
 	// 2. Parse source and dependency files.
+	if err := checkSourceModule(bs, newSourceFile, cache); err != nil {
+		return err
+	}
 	files, trackedFiles, err := parseAndFilterTrackedFiles(newSourceFile, trackedFiles, cache)
 	if err != nil {
 		return err
@@ -201,7 +219,16 @@ func switchFile(ctx context.Context, newSourceFile string, pc ProjectConfig, bs
 
 	// 3. Fast path: generate thunk → compile → hot-reload.
 	cfg := compileConfigFromSettings(bs)
-	thunkPaths, err := codegen.GenerateThunks(files, bs.ModuleName, dirs.Thunk, "0", newSourceFile, counter)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{
+		ModuleName:      bs.ModuleName,
+		ExtraModules:    bs.ThunkImports,
+		Layout:          bs.Layout,
+		InitArgs:        bs.InitArgsFor(newSourceFile),
+		Dir:             dirs.Thunk,
+		SourceFile:      newSourceFile,
+		PreviewSelector: "0",
+		ReloadCounter:   counter,
+	})
 	if err != nil {
 		return fmt.Errorf("thunk: %w", err)
 	}
@@ -235,7 +262,7 @@ func switchFile(ctx context.Context, newSourceFile string, pc ProjectConfig, bs
 
 	ws.mu.Lock()
 	ws.reloadCounter++
-	cleanOldDylibs(dirs.Thunk, counter-1)
+	cleanOldDylibs(dirs.Thunk, counter-1, bs.KeepThunk)
 	ws.trackedFiles = trackedFiles
 	ws.skeletonMap = newSkeletonMap
 	ws.depGraph = newGraph
@@ -261,22 +288,49 @@ func switchFile(ctx context.Context, newSourceFile string, pc ProjectConfig, bs
 // This function does NOT use compilePipeline because it has a unique fallback:
 // when parseTrackedFiles returns empty, it retries with sourceFile only.
 // It also uses terminate → install → launch (not the hot-reload deploy path).
+//
+// A call made while another is building is coalesced: it only records the
+// request, and the running call rebuilds once more when it finishes, so
+// the change that triggered it is not lost.
 func rebuildAndRelaunch(ctx context.Context, sourceFile string, pc ProjectConfig, bs *build.Settings, dirs previewDirs, wctx watchContext, ws *watchState) error {
 	ws.mu.Lock()
 	if ws.building {
+		ws.rebuildPending = sourceFile
 		ws.mu.Unlock()
-		slog.Info("Build already in progress, skipping")
+		slog.Info("Build already in progress, rebuilding again after it")
+		sendWatchStatus(wctx, "coalesced")
 		return nil
 	}
 	ws.building = true
-	tracked := append([]string{}, ws.trackedFiles...)
 	ws.mu.Unlock()
 
-	defer func() {
+	for {
+		err := rebuildAndRelaunchOnce(ctx, sourceFile, pc, bs, dirs, wctx, ws)
 		ws.mu.Lock()
-		ws.building = false
+		next := ws.rebuildPending
+		ws.rebuildPending = ""
+		if next == "" || ctx.Err() != nil {
+			ws.building = false
+			ws.mu.Unlock()
+			return err
+		}
 		ws.mu.Unlock()
-	}()
+		if err != nil {
+			// The coalesced change may fix it; the rebuild below reports
+			// its own result.
+			slog.Warn("Rebuild error", "err", err)
+		}
+		slog.Info("Rebuilding for changes made during the last build")
+		sourceFile = next
+	}
+}
+
+// rebuildAndRelaunchOnce is one rebuild of rebuildAndRelaunch. The caller
+// holds ws.building.
+func rebuildAndRelaunchOnce(ctx context.Context, sourceFile string, pc ProjectConfig, bs *build.Settings, dirs previewDirs, wctx watchContext, ws *watchState) error {
+	ws.mu.Lock()
+	tracked := append([]string{}, ws.trackedFiles...)
+	ws.mu.Unlock()
 
 	fmt.Fprintln(os.Stderr, "\nDependency changed, rebuilding...")
 	sendWatchStatus(wctx, "building")
@@ -316,7 +370,16 @@ func rebuildAndRelaunch(ctx context.Context, sourceFile string, pc ProjectConfig
 	selector := ws.previewSelector
 	ws.mu.Unlock()
 
-	thunkPaths, err := codegen.GenerateThunks(files, bs.ModuleName, dirs.Thunk, selector, sourceFile, counter)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{
+		ModuleName:      bs.ModuleName,
+		ExtraModules:    bs.ThunkImports,
+		Layout:          bs.Layout,
+		InitArgs:        bs.InitArgsFor(sourceFile),
+		Dir:             dirs.Thunk,
+		SourceFile:      sourceFile,
+		PreviewSelector: selector,
+		ReloadCounter:   counter,
+	})
 	if err != nil {
 		return fmt.Errorf("thunk: %w", err)
 	}
@@ -338,7 +401,12 @@ func rebuildAndRelaunch(ctx context.Context, sourceFile string, pc ProjectConfig
 	}
 
 	sendWatchStatus(wctx, "running")
+	applyAccessibility(ctx, wctx.overrides, wctx.accessibility)
+	launchedAt := time.Now()
 	if err := launchWithHotReload(ctx, bs, wctx.loaderPath, dylibPath, dirs.Socket, wctx.device, wctx.deviceSetPath, wctx.app); err != nil {
+		return fmt.Errorf("launch: %w", explainLaunchFailure(ctx, wctx.crashes, bs.BundleID, launchedAt, err))
+	}
+	if err := awaitRelaunch(ctx, wctx, bs, dirs.Socket, launchedAt); err != nil {
 		return fmt.Errorf("launch: %w", err)
 	}
 
@@ -387,7 +455,7 @@ func rebuildAndRelaunch(ctx context.Context, sourceFile string, pc ProjectConfig
 	for _, f := range newTracked {
 		touchTrackedFile(ws, filepath.Clean(f))
 	}
-	cleanOldDylibs(dirs.Thunk, counter-1)
+	cleanOldDylibs(dirs.Thunk, counter-1, bs.KeepThunk)
 	ws.mu.Unlock()
 
 	fmt.Fprintln(os.Stderr, "Preview rebuilt and relaunched.")
@@ -557,7 +625,7 @@ func tryIncrementalReload(
 	for _, nf := range newFiles {
 		touchTrackedFile(ws, filepath.Clean(nf))
 	}
-	cleanOldDylibs(dirs.Thunk, counter-1)
+	cleanOldDylibs(dirs.Thunk, counter-1, bs.KeepThunk)
 	ws.mu.Unlock()
 
 	fmt.Fprintln(os.Stderr, "Preview incrementally reloaded.")
@@ -681,8 +749,31 @@ func handleNextPreviewCmd(
 	}
 }
 
-// cleanOldDylibs removes thunk dylib, object, and Swift files older than keepAfter.
-func cleanOldDylibs(thunkDir string, keepAfter int) {
+// handleSelectPreviewCmd selects the preview at index and hot-reloads.
+// It reports false without reloading when that preview is already shown.
+func handleSelectPreviewCmd(
+	ctx context.Context, sourceFile string, index int,
+	bs *build.Settings, dirs previewDirs, wctx watchContext, ws *watchState,
+) bool {
+	ws.mu.Lock()
+	if ws.previewIndex == index {
+		ws.mu.Unlock()
+		return false
+	}
+	ws.previewIndex = index
+	ws.previewSelector = strconv.Itoa(index)
+	ws.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "\nSwitching to preview %d...\n", index+1)
+	if err := reloadMultiFile(ctx, sourceFile, bs, dirs, wctx, ws); err != nil {
+		fmt.Fprintf(os.Stderr, "Reload error: %v\n", err)
+	}
+	return true
+}
+
+// cleanOldDylibs removes thunk dylib, object, and Swift files older than
+// keepAfter. With keepSources (--keep-thunk) the Swift files are left in
+// place for inspection.
+func cleanOldDylibs(thunkDir string, keepAfter int, keepSources bool) {
 	for i := range keepAfter {
 		// Remove dylib and legacy .o files.
 		for _, ext := range []string{".dylib", ".o"} {
@@ -691,6 +782,9 @@ func cleanOldDylibs(thunkDir string, keepAfter int) {
 				slog.Debug("Cleaned old thunk artifact", "path", p)
 			}
 		}
+		if keepSources {
+			continue
+		}
 		// Remove per-file thunk .swift files (thunk_{counter}_*.swift).
 		pattern := filepath.Join(thunkDir, fmt.Sprintf("thunk_%d_*.swift", i))
 		matches, _ := filepath.Glob(pattern)
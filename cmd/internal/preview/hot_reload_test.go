@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/k-kohey/axe/internal/preview/analysis"
+	"github.com/k-kohey/axe/internal/preview/codegen"
 	"github.com/k-kohey/axe/internal/preview/protocol"
 )
 
@@ -39,6 +40,30 @@ func TestSendWatchStatus_StreamScoped(t *testing.T) {
 	}
 }
 
+func TestSendWatchStatus_ReportsLayout(t *testing.T) {
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	wctx := watchContext{
+		serve:    true,
+		streamID: "stream-a",
+		ew:       ew,
+		layout:   codegen.Layout{Name: "split-half"},
+	}
+	sendWatchStatus(wctx, "running")
+
+	events := collectEvents(t, &buf)
+	if len(events) != 1 || events[0].StreamStatus == nil {
+		t.Fatalf("expected 1 streamStatus event, got %+v", events)
+	}
+	if layout, _ := events[0].StreamStatus["layout"].(string); layout != "split-half" {
+		t.Errorf("layout = %q, want %q", layout, "split-half")
+	}
+	if sc, _ := events[0].StreamStatus["sizeClass"].(string); sc != "compact" {
+		t.Errorf("sizeClass = %q, want %q", sc, "compact")
+	}
+}
+
 func TestSendWatchStatus_NoServeNoEvent(t *testing.T) {
 	var buf syncBuffer
 	ew := protocol.NewEventWriter(&buf)
@@ -709,3 +734,49 @@ func TestStdinCommand_JSONRoundtrip(t *testing.T) {
 		}
 	}
 }
+
+// failingGatedBuildRunner fails every build, holding each until gate is
+// closed. started receives once per build.
+type failingGatedBuildRunner struct {
+	fakeBuildRunner
+	gate    chan struct{}
+	started chan struct{}
+}
+
+func (b *failingGatedBuildRunner) Build(_ context.Context, _ []string) ([]byte, error) {
+	b.started <- struct{}{}
+	<-b.gate
+	return nil, errors.New("build failed")
+}
+
+func TestRebuildAndRelaunch_CoalescedRequestRebuildsAgain(t *testing.T) {
+	br := &failingGatedBuildRunner{gate: make(chan struct{}), started: make(chan struct{}, 2)}
+	pc := ProjectConfig{Project: "/tmp/App.xcodeproj", Scheme: "App"}
+	dirs := previewDirs{ProjectDirs: build.ProjectDirs{Build: t.TempDir()}}
+	wctx := watchContext{build: br}
+	ws := &watchState{trackedFiles: []string{"/src/Target.swift"}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- rebuildAndRelaunch(t.Context(), "/src/Target.swift", pc, &build.Settings{}, dirs, wctx, ws)
+	}()
+	<-br.started
+
+	// A request during the build returns at once and is not dropped.
+	if err := rebuildAndRelaunch(t.Context(), "/src/Target.swift", pc, &build.Settings{}, dirs, wctx, ws); err != nil {
+		t.Fatalf("coalesced rebuild returned %v", err)
+	}
+	close(br.gate)
+
+	if err := <-done; err == nil {
+		t.Fatal("expected the second build's error")
+	}
+	if got := len(br.started); got != 1 {
+		t.Errorf("builds after the coalesced request = %d, want 1", got)
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.building || ws.rebuildPending != "" {
+		t.Errorf("building = %v, rebuildPending = %q after the rebuilds", ws.building, ws.rebuildPending)
+	}
+}
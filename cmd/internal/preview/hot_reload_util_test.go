@@ -25,7 +25,7 @@ func TestCleanOldDylibs_RemovesOldArtifacts(t *testing.T) {
 	}
 
 	// Clean artifacts with index < 2 (i.e., thunk_0 and thunk_1).
-	cleanOldDylibs(dir, 2)
+	cleanOldDylibs(dir, 2, false)
 
 	// thunk_0 and thunk_1 should be removed.
 	for i := range 2 {
@@ -46,13 +46,36 @@ func TestCleanOldDylibs_RemovesOldArtifacts(t *testing.T) {
 	}
 }
 
+func TestCleanOldDylibs_KeepSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"thunk_0.dylib", "thunk_0_HogeView.swift", "thunk_0__main.swift", "thunk_1.dylib", "thunk_1_HogeView.swift"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("fake"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// --keep-thunk: old dylibs go, but every thunk source stays.
+	cleanOldDylibs(dir, 1, true)
+
+	if _, err := os.Stat(filepath.Join(dir, "thunk_0.dylib")); err == nil {
+		t.Error("expected thunk_0.dylib to be removed")
+	}
+	for _, name := range []string{"thunk_0_HogeView.swift", "thunk_0__main.swift", "thunk_1.dylib", "thunk_1_HogeView.swift"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to still exist", name)
+		}
+	}
+}
+
 func TestCleanOldDylibs_NoopWhenNoFiles(t *testing.T) {
 	t.Parallel()
 
 	dir := t.TempDir()
 
 	// Should not panic on empty directory.
-	cleanOldDylibs(dir, 5)
+	cleanOldDylibs(dir, 5, false)
 }
 
 func TestCleanOldDylibs_KeepAfterZero(t *testing.T) {
@@ -66,7 +89,7 @@ func TestCleanOldDylibs_KeepAfterZero(t *testing.T) {
 	}
 
 	// keepAfter=0 means range(0) = nothing to clean.
-	cleanOldDylibs(dir, 0)
+	cleanOldDylibs(dir, 0, false)
 
 	if _, err := os.Stat(p); err != nil {
 		t.Errorf("expected %s to still exist with keepAfter=0", p)
@@ -0,0 +1,292 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/k-kohey/axe/internal/preview/codegen"
+)
+
+// ErrAppCrashed is returned when the previewed app crashes while launching.
+var ErrAppCrashed = errors.New("app crashed on launch")
+
+const (
+	// maxCrashFrames bounds the backtrace reported for a launch crash.
+	maxCrashFrames = 16
+	// maxCrashReasonLen bounds the application-specific message (for
+	// example a Swift fatalError message) reported for a launch crash.
+	maxCrashReasonLen = 512
+)
+
+// The crash report is written by ReportCrash a moment after the app dies,
+// so it is polled for a while after the launch failed.
+var (
+	crashReportPollInterval = 500 * time.Millisecond
+	crashReportWait         = 5 * time.Second
+)
+
+// ipsReport is the body of an .ips crash report, the JSON document that
+// follows the one-line header. Only the fields summarized are decoded.
+type ipsReport struct {
+	Exception struct {
+		Type   string `json:"type"`
+		Signal string `json:"signal"`
+	} `json:"exception"`
+	// ASI is the "Application Specific Information": messages keyed by the
+	// image that recorded them, such as Swift's fatalError message.
+	ASI            map[string][]string `json:"asi"`
+	FaultingThread int                 `json:"faultingThread"`
+	Threads        []struct {
+		Frames []ipsFrame `json:"frames"`
+	} `json:"threads"`
+	UsedImages []ipsImage `json:"usedImages"`
+}
+
+type ipsFrame struct {
+	ImageOffset    uint64 `json:"imageOffset"`
+	ImageIndex     int    `json:"imageIndex"`
+	Symbol         string `json:"symbol"`
+	SymbolLocation uint64 `json:"symbolLocation"`
+}
+
+type ipsImage struct {
+	Base uint64 `json:"base"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// launchCrash is the summary of a crash report shown to the user.
+type launchCrash struct {
+	exception string   // e.g. "EXC_BAD_ACCESS (SIGSEGV)"
+	reason    string   // application-specific information, if any
+	backtrace []string // frames of the crashed thread, innermost first
+}
+
+// summary returns a one-line description of the crash.
+func (c *launchCrash) summary() string {
+	if c.reason == "" {
+		return c.exception
+	}
+	return c.exception + ": " + c.reason
+}
+
+// diagnostic returns the backtrace, one frame per line.
+func (c *launchCrash) diagnostic() string {
+	return strings.Join(c.backtrace, "\n")
+}
+
+func (c *launchCrash) err() error {
+	if len(c.backtrace) == 0 {
+		return fmt.Errorf("%w: %s", ErrAppCrashed, c.summary())
+	}
+	return fmt.Errorf("%w: %s\n%s", ErrAppCrashed, c.summary(), c.diagnostic())
+}
+
+// parseCrashReport decodes an .ips crash report: a one-line JSON header
+// followed by the JSON body.
+func parseCrashReport(data []byte) (ipsReport, error) {
+	var r ipsReport
+	_, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return r, fmt.Errorf("crash report has no body")
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return r, fmt.Errorf("decoding crash report: %w", err)
+	}
+	return r, nil
+}
+
+// summarizeCrash builds the summary of r. Frames of the crashed thread that
+// the report left unsymbolicated (typically those in the app and the preview
+// thunk) are resolved with cr on a best-effort basis; frames that cannot be
+// resolved are shown as addresses.
+func summarizeCrash(ctx context.Context, cr CrashReporter, r ipsReport) *launchCrash {
+	c := &launchCrash{exception: r.Exception.Type}
+	if r.Exception.Signal != "" {
+		c.exception = fmt.Sprintf("%s (%s)", r.Exception.Type, r.Exception.Signal)
+	}
+	if c.exception == "" {
+		c.exception = "unknown exception"
+	}
+	var reasons []string
+	for _, image := range slices.Sorted(maps.Keys(r.ASI)) {
+		reasons = append(reasons, r.ASI[image]...)
+	}
+	c.reason = truncateRunes(strings.TrimSpace(strings.Join(reasons, " ")), maxCrashReasonLen)
+
+	if r.FaultingThread < 0 || r.FaultingThread >= len(r.Threads) {
+		return c
+	}
+	frames := r.Threads[r.FaultingThread].Frames
+	omitted := 0
+	if len(frames) > maxCrashFrames {
+		omitted = len(frames) - maxCrashFrames
+		frames = frames[:maxCrashFrames]
+	}
+
+	symbols := make([]string, len(frames))
+	unresolved := make(map[int][]int) // image index -> frame indexes
+	for i, f := range frames {
+		switch {
+		case f.Symbol != "":
+			symbols[i] = fmt.Sprintf("%s + %d", f.Symbol, f.SymbolLocation)
+		case f.ImageIndex >= 0 && f.ImageIndex < len(r.UsedImages):
+			img := r.UsedImages[f.ImageIndex]
+			symbols[i] = fmt.Sprintf("0x%x", img.Base+f.ImageOffset)
+			if img.Path != "" {
+				unresolved[f.ImageIndex] = append(unresolved[f.ImageIndex], i)
+			}
+		default:
+			symbols[i] = fmt.Sprintf("0x%x", f.ImageOffset)
+		}
+	}
+	for idx, frameIdx := range unresolved {
+		img := r.UsedImages[idx]
+		addrs := make([]uint64, len(frameIdx))
+		for j, i := range frameIdx {
+			addrs[j] = img.Base + frames[i].ImageOffset
+		}
+		resolved, err := cr.Symbolicate(ctx, img.Path, img.Base, addrs)
+		if err != nil {
+			slog.Debug("Failed to symbolicate crash frames", "image", img.Path, "err", err)
+			continue
+		}
+		for j, i := range frameIdx {
+			if resolved[j] != "" {
+				symbols[i] = resolved[j]
+			}
+		}
+	}
+
+	for i, f := range frames {
+		image := "???"
+		if f.ImageIndex >= 0 && f.ImageIndex < len(r.UsedImages) && r.UsedImages[f.ImageIndex].Name != "" {
+			image = r.UsedImages[f.ImageIndex].Name
+		}
+		c.backtrace = append(c.backtrace, fmt.Sprintf("%-3d %-30s %s", i, image, symbols[i]))
+	}
+	if omitted > 0 {
+		c.backtrace = append(c.backtrace, fmt.Sprintf("... %d more frames", omitted))
+	}
+	return c
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut with "...".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+// detectLaunchCrash looks for a crash report of bundleID written since
+// launchedAt, polling until crashReportWait elapses. It returns nil when no
+// report appears.
+func detectLaunchCrash(ctx context.Context, cr CrashReporter, bundleID string, launchedAt time.Time) *launchCrash {
+	deadline := time.Now().Add(crashReportWait)
+	for {
+		if c := latestLaunchCrash(ctx, cr, bundleID, launchedAt); c != nil {
+			return c
+		}
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(crashReportPollInterval):
+		}
+	}
+}
+
+// latestLaunchCrash returns the crash of the newest report of bundleID
+// written since launchedAt, or nil when there is none yet.
+func latestLaunchCrash(ctx context.Context, cr CrashReporter, bundleID string, launchedAt time.Time) *launchCrash {
+	data, err := cr.Latest(ctx, bundleID, launchedAt)
+	if err != nil {
+		slog.Debug("Failed to read crash reports", "bundleID", bundleID, "err", err)
+	}
+	if data == nil {
+		return nil
+	}
+	r, err := parseCrashReport(data)
+	if err != nil {
+		slog.Debug("Failed to parse crash report", "bundleID", bundleID, "err", err)
+		return &launchCrash{exception: "crash report could not be parsed"}
+	}
+	return summarizeCrash(ctx, cr, r)
+}
+
+// awaitLoader waits for the loader of the app launched at launchedAt to
+// listen on socketPath, watching for a crash report of bundleID meanwhile
+// so that a crash ends the wait as soon as it is reported rather than when
+// WaitForReady gives up. It returns the crash, if any, and otherwise
+// WaitForReady's error. Without a CrashReporter it only waits.
+func awaitLoader(ctx context.Context, cr CrashReporter, bundleID, socketPath string, launchedAt time.Time) (*launchCrash, error) {
+	if cr == nil {
+		return nil, codegen.WaitForReady(ctx, socketPath)
+	}
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ready := make(chan error, 1)
+	go func() { ready <- codegen.WaitForReady(waitCtx, socketPath) }()
+
+	ticker := time.NewTicker(crashReportPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-ready:
+			if err == nil || ctx.Err() != nil {
+				return nil, err
+			}
+			// The loader never came up; its report may still be written.
+			return detectLaunchCrash(ctx, cr, bundleID, launchedAt), err
+		case <-ticker.C:
+			if c := latestLaunchCrash(ctx, cr, bundleID, launchedAt); c != nil {
+				return c, nil
+			}
+		}
+	}
+}
+
+// awaitRelaunch waits for the app that rebuildAndRelaunch or deploy
+// relaunched at launchedAt, and returns its crash as an error. A loader
+// that does not come up without a crash is only logged, as on the first
+// launch, and without a CrashReporter the app is not waited for.
+func awaitRelaunch(ctx context.Context, wctx watchContext, bs *build.Settings, socketPath string, launchedAt time.Time) error {
+	if wctx.crashes == nil {
+		return nil
+	}
+	c, err := awaitLoader(ctx, wctx.crashes, bs.BundleID, socketPath, launchedAt)
+	if c != nil {
+		return c.err()
+	}
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("Loader did not become ready after relaunch", "streamId", wctx.streamID, "err", err)
+	}
+	return nil
+}
+
+// explainLaunchFailure replaces err, a failure to launch the app or to
+// reach its loader, with the crash of the app when one was reported.
+// Without a CrashReporter, or when no crash report appears, err is
+// returned unchanged.
+func explainLaunchFailure(ctx context.Context, cr CrashReporter, bundleID string, launchedAt time.Time, err error) error {
+	if cr == nil || ctx.Err() != nil {
+		return err
+	}
+	if c := detectLaunchCrash(ctx, cr, bundleID, launchedAt); c != nil {
+		return c.err()
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k-kohey/axe/internal/preview/build"
+)
+
+// fakeCrashReporter returns report once Latest has been called misses times,
+// simulating ReportCrash writing the report a moment after the crash.
+type fakeCrashReporter struct {
+	report  []byte
+	misses  int
+	symbols map[uint64]string
+	symErr  error
+
+	calls     int
+	bundleIDs []string
+	symImages []string
+}
+
+func (f *fakeCrashReporter) Latest(_ context.Context, bundleID string, _ time.Time) ([]byte, error) {
+	f.calls++
+	f.bundleIDs = append(f.bundleIDs, bundleID)
+	if f.calls <= f.misses {
+		return nil, nil
+	}
+	return f.report, nil
+}
+
+func (f *fakeCrashReporter) Symbolicate(_ context.Context, imagePath string, _ uint64, addrs []uint64) ([]string, error) {
+	f.symImages = append(f.symImages, imagePath)
+	if f.symErr != nil {
+		return nil, f.symErr
+	}
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = f.symbols[a]
+	}
+	return out, nil
+}
+
+// crashReportFixture is a trimmed .ips report of a Swift fatalError in the
+// app's main executable, whose frames the report left unsymbolicated.
+const crashReportFixture = `{"app_name":"Demo","bundleID":"axe.com.example.demo","bug_type":"309"}
+{
+  "exception": {"type": "EXC_BREAKPOINT", "signal": "SIGTRAP"},
+  "asi": {"libswiftCore.dylib": ["Demo/ContentView.swift:12: Fatal error: boom"]},
+  "faultingThread": 0,
+  "threads": [
+    {"frames": [
+      {"imageOffset": 4096, "symbol": "_assertionFailure(_:_:file:line:flags:)", "symbolLocation": 300, "imageIndex": 0},
+      {"imageOffset": 16, "imageIndex": 1},
+      {"imageOffset": 32, "imageIndex": 1}
+    ]},
+    {"frames": [{"imageOffset": 1, "symbol": "mach_msg", "symbolLocation": 8, "imageIndex": 0}]}
+  ],
+  "usedImages": [
+    {"base": 4096, "name": "libswiftCore.dylib", "path": "/usr/lib/swift/libswiftCore.dylib"},
+    {"base": 65536, "name": "Demo", "path": "/tmp/Demo.app/Demo"}
+  ]
+}`
+
+func withFastCrashPolling(t *testing.T) {
+	t.Helper()
+	interval, wait := crashReportPollInterval, crashReportWait
+	crashReportPollInterval, crashReportWait = time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() { crashReportPollInterval, crashReportWait = interval, wait })
+}
+
+func TestExplainLaunchFailure_CrashReport(t *testing.T) {
+	withFastCrashPolling(t)
+	cr := &fakeCrashReporter{
+		report:  []byte(crashReportFixture),
+		misses:  2,
+		symbols: map[uint64]string{65536 + 16: "ContentView.body.getter (in Demo) (ContentView.swift:12)"},
+	}
+	cause := errors.New("waiting for loader ready: connecting to loader socket: no such file")
+
+	err := explainLaunchFailure(context.Background(), cr, "axe.com.example.demo", time.Now(), cause)
+	if !errors.Is(err, ErrAppCrashed) {
+		t.Fatalf("err = %v, want ErrAppCrashed", err)
+	}
+	if cr.calls != 3 {
+		t.Errorf("Latest called %d times, want 3 (polled until the report appeared)", cr.calls)
+	}
+	if cr.bundleIDs[0] != "axe.com.example.demo" {
+		t.Errorf("Latest bundleID = %q", cr.bundleIDs[0])
+	}
+	if len(cr.symImages) != 1 || cr.symImages[0] != "/tmp/Demo.app/Demo" {
+		t.Errorf("Symbolicate images = %v, want only the app's executable", cr.symImages)
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"EXC_BREAKPOINT (SIGTRAP): Demo/ContentView.swift:12: Fatal error: boom",
+		"libswiftCore.dylib",
+		"_assertionFailure(_:_:file:line:flags:) + 300",
+		"ContentView.body.getter (in Demo) (ContentView.swift:12)",
+		"0x10020", // left as an address: atos returned nothing for it
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "mach_msg") {
+		t.Errorf("error includes frames of a thread that did not crash:\n%s", msg)
+	}
+}
+
+func TestExplainLaunchFailure_NoReport(t *testing.T) {
+	withFastCrashPolling(t)
+	cause := errors.New("simctl launch failed: exit status 1")
+
+	cr := &fakeCrashReporter{}
+	if err := explainLaunchFailure(context.Background(), cr, "b", time.Now(), cause); err != cause {
+		t.Errorf("err = %v, want the launch error unchanged", err)
+	}
+	if cr.calls < 2 {
+		t.Errorf("Latest called %d times, want polling until the wait elapses", cr.calls)
+	}
+
+	if err := explainLaunchFailure(context.Background(), nil, "b", time.Now(), cause); err != cause {
+		t.Errorf("err without a CrashReporter = %v, want the launch error unchanged", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cr = &fakeCrashReporter{report: []byte(crashReportFixture)}
+	if err := explainLaunchFailure(ctx, cr, "b", time.Now(), cause); err != cause || cr.calls != 0 {
+		t.Errorf("cancelled: err = %v, calls = %d; want the launch error and no lookup", err, cr.calls)
+	}
+}
+
+func TestExplainLaunchFailure_UnreadableReport(t *testing.T) {
+	withFastCrashPolling(t)
+	cr := &fakeCrashReporter{report: []byte(`{"bundleID":"b"}` + "\n{truncated")}
+
+	err := explainLaunchFailure(context.Background(), cr, "b", time.Now(), errors.New("launch"))
+	if !errors.Is(err, ErrAppCrashed) {
+		t.Errorf("err = %v, want ErrAppCrashed even when the report cannot be parsed", err)
+	}
+}
+
+func TestSummarizeCrash_Bounded(t *testing.T) {
+	var frames []string
+	for i := range maxCrashFrames + 5 {
+		frames = append(frames, fmt.Sprintf(`{"imageOffset": %d, "symbol": "f%d", "imageIndex": 0}`, i, i))
+	}
+	report := `{"bundleID":"b"}
+{"exception": {"type": "EXC_BAD_ACCESS", "signal": "SIGSEGV"},
+ "asi": {"Demo": ["` + strings.Repeat("x", maxCrashReasonLen+100) + `"]},
+ "faultingThread": 0,
+ "threads": [{"frames": [` + strings.Join(frames, ",") + `]}],
+ "usedImages": [{"base": 0, "name": "Demo"}]}`
+
+	r, err := parseCrashReport([]byte(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := summarizeCrash(context.Background(), &fakeCrashReporter{symErr: errors.New("unused")}, r)
+
+	if got := len(c.backtrace); got != maxCrashFrames+1 {
+		t.Errorf("backtrace has %d lines, want %d frames and an omission note", got, maxCrashFrames)
+	}
+	if last := c.backtrace[len(c.backtrace)-1]; last != "... 5 more frames" {
+		t.Errorf("last line = %q", last)
+	}
+	if n := len([]rune(c.reason)); n != maxCrashReasonLen+len("...") {
+		t.Errorf("reason has %d runes, want it cut to %d", n, maxCrashReasonLen)
+	}
+}
+
+func TestAwaitLoader_CrashBeforeReady(t *testing.T) {
+	withFastCrashPolling(t)
+	cr := &fakeCrashReporter{report: []byte(crashReportFixture), misses: 2}
+	socket := filepath.Join(t.TempDir(), "never.sock")
+
+	start := time.Now()
+	c, err := awaitLoader(context.Background(), cr, "axe.com.example.demo", socket, start)
+	if c == nil {
+		t.Fatalf("crash = nil, err = %v; want the reported crash", err)
+	}
+	if !strings.Contains(c.summary(), "Fatal error: boom") {
+		t.Errorf("summary = %q", c.summary())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("crash reported after %v; want it to end the wait for the loader", elapsed)
+	}
+}
+
+func TestAwaitLoader_Ready(t *testing.T) {
+	withFastCrashPolling(t)
+	socket := filepath.Join(t.TempDir(), "ready.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("cannot listen on a unix socket:", err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	c, err := awaitLoader(context.Background(), &fakeCrashReporter{}, "b", socket, time.Now())
+	if c != nil || err != nil {
+		t.Errorf("awaitLoader = %v, %v; want a ready loader", c, err)
+	}
+}
+
+func TestAwaitRelaunch_Crash(t *testing.T) {
+	withFastCrashPolling(t)
+	wctx := watchContext{crashes: &fakeCrashReporter{report: []byte(crashReportFixture)}}
+	bs := &build.Settings{BundleID: "axe.com.example.demo"}
+	socket := filepath.Join(t.TempDir(), "never.sock")
+
+	if err := awaitRelaunch(context.Background(), wctx, bs, socket, time.Now()); !errors.Is(err, ErrAppCrashed) {
+		t.Errorf("err = %v, want ErrAppCrashed", err)
+	}
+	if err := awaitRelaunch(context.Background(), watchContext{}, bs, socket, time.Now()); err != nil {
+		t.Errorf("without a CrashReporter err = %v, want no wait", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/k-kohey/axe/internal/preview/analysis"
 	"github.com/k-kohey/axe/internal/preview/build"
@@ -53,6 +54,15 @@ func parseTrackedFiles(sourceFile string, trackedFiles []string, cache *analysis
 	return files
 }
 
+// checkSourceModule rejects sourceFile if the index store records it in a
+// module other than the app module of bs. See build.CheckSourceModule.
+func checkSourceModule(bs *build.Settings, sourceFile string, cache *analysis.IndexStoreCache) error {
+	if cache == nil {
+		return nil
+	}
+	return build.CheckSourceModule(bs, sourceFile, cache.FileModuleName(sourceFile))
+}
+
 // hasFile reports whether files contains an entry for the given absolute path.
 func hasFile(files []analysis.FileThunkData, absPath string) bool {
 	for _, f := range files {
@@ -116,7 +126,16 @@ func compilePipeline(
 		return "", fmt.Errorf("no types found in tracked files")
 	}
 
-	thunkPaths, err := codegen.GenerateThunks(files, bs.ModuleName, dirs.Thunk, previewSelector, sourceFile, counter)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{
+		ModuleName:      bs.ModuleName,
+		ExtraModules:    bs.ThunkImports,
+		Layout:          bs.Layout,
+		InitArgs:        bs.InitArgsFor(sourceFile),
+		Dir:             dirs.Thunk,
+		SourceFile:      sourceFile,
+		PreviewSelector: previewSelector,
+		ReloadCounter:   counter,
+	})
 	if err != nil {
 		return "", fmt.Errorf("thunk: %w", err)
 	}
@@ -146,7 +165,16 @@ func compileMainOnlyPipeline(
 		return "", fmt.Errorf("source imports: %w", err)
 	}
 
-	thunkPaths, err := codegen.GenerateMainOnlyThunk(bs.ModuleName, dirs.Thunk, sourceFile, previewSelector, imports, reloadCounter)
+	thunkPaths, err := codegen.GenerateMainOnlyThunk(imports, codegen.ThunkOptions{
+		ModuleName:      bs.ModuleName,
+		ExtraModules:    bs.ThunkImports,
+		Layout:          bs.Layout,
+		InitArgs:        bs.InitArgsFor(sourceFile),
+		Dir:             dirs.Thunk,
+		SourceFile:      sourceFile,
+		PreviewSelector: previewSelector,
+		ReloadCounter:   reloadCounter,
+	})
 	if err != nil {
 		return "", fmt.Errorf("main-only thunk: %w", err)
 	}
@@ -161,10 +189,15 @@ func compileMainOnlyPipeline(
 
 // deploy attempts hot-reload via socket, falling back to full app relaunch.
 func deploy(ctx context.Context, dylibPath string, dirs previewDirs, bs *build.Settings, wctx watchContext) error {
+	applyAccessibility(ctx, wctx.overrides, wctx.accessibility)
 	if err := codegen.SendReloadCommand(ctx, dirs.Socket, dylibPath); err != nil {
 		slog.Warn("Hot-reload failed, falling back to full relaunch", "err", err)
 		terminateApp(ctx, bs, wctx.device, wctx.deviceSetPath, wctx.app)
+		launchedAt := time.Now()
 		if err := launchWithHotReload(ctx, bs, wctx.loaderPath, dylibPath, dirs.Socket, wctx.device, wctx.deviceSetPath, wctx.app); err != nil {
+			return fmt.Errorf("launch: %w", explainLaunchFailure(ctx, wctx.crashes, bs.BundleID, launchedAt, err))
+		}
+		if err := awaitRelaunch(ctx, wctx, bs, dirs.Socket, launchedAt); err != nil {
 			return fmt.Errorf("launch: %w", err)
 		}
 		fmt.Fprintln(os.Stderr, "Preview relaunched (full restart).")
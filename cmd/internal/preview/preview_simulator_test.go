@@ -3,6 +3,7 @@ package preview
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/k-kohey/axe/internal/preview/build"
 	"os"
 	"path/filepath"
@@ -30,6 +31,12 @@ type fakeAppRunner struct {
 	launchEnv          map[string]string
 	launchArgs         []string
 
+	// dataContainer is returned by DataContainer once the app is installed;
+	// before that DataContainer fails like simctl does for unknown apps.
+	dataContainer      string
+	dataContainerErr   error
+	dataContainerCalls int
+
 	// Optional callback invoked on Launch for test observation.
 	onLaunch func()
 }
@@ -60,6 +67,17 @@ func (f *fakeAppRunner) Launch(_ context.Context, device, bundleID, deviceSetPat
 	return f.launchErr
 }
 
+func (f *fakeAppRunner) DataContainer(_ context.Context, _, bundleID, _ string) (string, error) {
+	f.dataContainerCalls++
+	if f.dataContainerErr != nil {
+		return "", f.dataContainerErr
+	}
+	if f.installDevice == "" {
+		return "", fmt.Errorf("no such app: %s", bundleID)
+	}
+	return f.dataContainer, nil
+}
+
 // --- Fake FileCopier ---
 
 type fakeFileCopier struct {
@@ -125,6 +143,7 @@ func TestStageAppBundle_Success(t *testing.T) {
 	bs := &build.Settings{
 		ModuleName:       "TestModule",
 		BuiltProductsDir: productsDir,
+		ProductName:      "TestModule.app",
 	}
 	stagingDir := filepath.Join(t.TempDir(), "staging")
 	dirs := previewDirs{
@@ -161,6 +180,7 @@ func TestStageAppBundle_AppNotFound(t *testing.T) {
 	bs := &build.Settings{
 		ModuleName:       "NoSuchModule",
 		BuiltProductsDir: filepath.Join(tmpDir, "Build", "Products", "Debug-iphonesimulator"),
+		ProductName:      "NoSuchModule.app",
 	}
 	dirs := previewDirs{
 		ProjectDirs: build.ProjectDirs{Build: tmpDir},
@@ -190,6 +210,7 @@ func TestStageAppBundle_CopyError(t *testing.T) {
 	bs := &build.Settings{
 		ModuleName:       "TestModule",
 		BuiltProductsDir: productsDir,
+		ProductName:      "TestModule.app",
 	}
 	dirs := previewDirs{
 		ProjectDirs: build.ProjectDirs{Build: tmpDir},
@@ -222,6 +243,7 @@ func TestInstallApp_Success(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: productsDir,
+		ProductName:      "TestModule.app",
 	}
 	stagingDir := filepath.Join(t.TempDir(), "staging")
 	dirs := previewDirs{
@@ -262,6 +284,7 @@ func TestInstallApp_InstallError(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: productsDir,
+		ProductName:      "TestModule.app",
 	}
 	dirs := previewDirs{
 		ProjectDirs: build.ProjectDirs{Build: tmpDir},
@@ -279,6 +302,108 @@ func TestInstallApp_InstallError(t *testing.T) {
 	}
 }
 
+func TestInstallApp_SeedsDataContainer(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	productsDir := filepath.Join(tmpDir, "Build", "Products", "Debug-iphonesimulator")
+	if err := os.MkdirAll(filepath.Join(productsDir, "TestModule.app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	seedDir := t.TempDir()
+	writeSeedFile(t, seedDir, "Documents/notes.json", `{"notes":[]}`)
+
+	bs := &build.Settings{
+		ModuleName:       "TestModule",
+		BundleID:         "axe.com.example.TestModule",
+		BuiltProductsDir: productsDir,
+		ProductName:      "TestModule.app",
+		SeedDir:          seedDir,
+	}
+	dirs := previewDirs{
+		ProjectDirs: build.ProjectDirs{Build: tmpDir},
+		Staging:     filepath.Join(t.TempDir(), "staging"),
+	}
+	container := t.TempDir()
+	// The fake fails DataContainer until Install ran, so success here also
+	// asserts the seed happens after install.
+	ar := &fakeAppRunner{dataContainer: container}
+
+	if _, err := installApp(context.Background(), bs, dirs, "device-uuid", "", ar, &fakeFileCopier{}); err != nil {
+		t.Fatalf("installApp: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(container, "Documents", "notes.json"))
+	if err != nil {
+		t.Fatalf("seed file not copied: %v", err)
+	}
+	if string(got) != `{"notes":[]}` {
+		t.Errorf("seeded content = %q", got)
+	}
+}
+
+func TestSeedAppData_NoSeedDir(t *testing.T) {
+	t.Parallel()
+
+	ar := &fakeAppRunner{}
+	if err := seedAppData(context.Background(), &build.Settings{BundleID: "axe.app"}, "device-uuid", "", ar); err != nil {
+		t.Fatalf("seedAppData: %v", err)
+	}
+	if ar.dataContainerCalls != 0 {
+		t.Errorf("DataContainer called %d times without a seed dir", ar.dataContainerCalls)
+	}
+}
+
+func TestSeedAppData_ContainerError(t *testing.T) {
+	t.Parallel()
+
+	ar := &fakeAppRunner{dataContainerErr: errors.New("simctl get_app_container failed")}
+	bs := &build.Settings{BundleID: "axe.app", SeedDir: t.TempDir()}
+	err := seedAppData(context.Background(), bs, "device-uuid", "", ar)
+	if err == nil || !strings.Contains(err.Error(), "app data container") {
+		t.Fatalf("expected container resolution error, got %v", err)
+	}
+}
+
+func TestSeedCopyPlan(t *testing.T) {
+	t.Parallel()
+
+	seedDir := t.TempDir()
+	writeSeedFile(t, seedDir, "Documents/notes.json", "{}")
+	writeSeedFile(t, seedDir, "Library/Application Support/store.sqlite", "db")
+	if err := os.Symlink("/etc/hosts", filepath.Join(seedDir, "Documents", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := seedCopyPlan(seedDir, "/container")
+	if err != nil {
+		t.Fatalf("seedCopyPlan: %v", err)
+	}
+	want := []seedCopy{
+		{src: filepath.Join(seedDir, "Documents", "notes.json"), dst: "/container/Documents/notes.json"},
+		{src: filepath.Join(seedDir, "Library", "Application Support", "store.sqlite"), dst: "/container/Library/Application Support/store.sqlite"},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("plan = %v, want %v", plan, want)
+	}
+	for i := range want {
+		if plan[i] != want[i] {
+			t.Errorf("plan[%d] = %v, want %v", i, plan[i], want[i])
+		}
+	}
+}
+
+func writeSeedFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestInstallApp_StageError(t *testing.T) {
 	t.Parallel()
 
@@ -287,6 +412,7 @@ func TestInstallApp_StageError(t *testing.T) {
 	bs := &build.Settings{
 		ModuleName:       "MissingModule",
 		BuiltProductsDir: filepath.Join(tmpDir, "Build", "Products", "Debug-iphonesimulator"),
+		ProductName:      "MissingModule.app",
 	}
 	dirs := previewDirs{
 		ProjectDirs: build.ProjectDirs{Build: tmpDir},
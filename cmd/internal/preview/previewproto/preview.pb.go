@@ -39,6 +39,14 @@ type Command struct {
 	//	*Command_NextPreview
 	//	*Command_Input
 	//	*Command_ForceRebuild
+	//	*Command_SetWatch
+	//	*Command_ListDevices
+	//	*Command_SetDevice
+	//	*Command_StartRecording
+	//	*Command_StopRecording
+	//	*Command_Shutdown
+	//	*Command_Reload
+	//	*Command_PoolStatus
 	Payload       isCommand_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -142,6 +150,78 @@ func (x *Command) GetForceRebuild() *ForceRebuild {
 	return nil
 }
 
+func (x *Command) GetSetWatch() *SetWatch {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_SetWatch); ok {
+			return x.SetWatch
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetListDevices() *ListDevices {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_ListDevices); ok {
+			return x.ListDevices
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetSetDevice() *SetDevice {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_SetDevice); ok {
+			return x.SetDevice
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetStartRecording() *StartRecording {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_StartRecording); ok {
+			return x.StartRecording
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetStopRecording() *StopRecording {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_StopRecording); ok {
+			return x.StopRecording
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetShutdown() *Shutdown {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_Shutdown); ok {
+			return x.Shutdown
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetReload() *Reload {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_Reload); ok {
+			return x.Reload
+		}
+	}
+	return nil
+}
+
+func (x *Command) GetPoolStatus() *PoolStatus {
+	if x != nil {
+		if x, ok := x.Payload.(*Command_PoolStatus); ok {
+			return x.PoolStatus
+		}
+	}
+	return nil
+}
+
 type isCommand_Payload interface {
 	isCommand_Payload()
 }
@@ -170,6 +250,38 @@ type Command_ForceRebuild struct {
 	ForceRebuild *ForceRebuild `protobuf:"bytes,7,opt,name=force_rebuild,json=forceRebuild,proto3,oneof"`
 }
 
+type Command_SetWatch struct {
+	SetWatch *SetWatch `protobuf:"bytes,8,opt,name=set_watch,json=setWatch,proto3,oneof"`
+}
+
+type Command_ListDevices struct {
+	ListDevices *ListDevices `protobuf:"bytes,9,opt,name=list_devices,json=listDevices,proto3,oneof"`
+}
+
+type Command_SetDevice struct {
+	SetDevice *SetDevice `protobuf:"bytes,10,opt,name=set_device,json=setDevice,proto3,oneof"`
+}
+
+type Command_StartRecording struct {
+	StartRecording *StartRecording `protobuf:"bytes,11,opt,name=start_recording,json=startRecording,proto3,oneof"`
+}
+
+type Command_StopRecording struct {
+	StopRecording *StopRecording `protobuf:"bytes,12,opt,name=stop_recording,json=stopRecording,proto3,oneof"`
+}
+
+type Command_Shutdown struct {
+	Shutdown *Shutdown `protobuf:"bytes,13,opt,name=shutdown,proto3,oneof"`
+}
+
+type Command_Reload struct {
+	Reload *Reload `protobuf:"bytes,14,opt,name=reload,proto3,oneof"`
+}
+
+type Command_PoolStatus struct {
+	PoolStatus *PoolStatus `protobuf:"bytes,15,opt,name=pool_status,json=poolStatus,proto3,oneof"`
+}
+
 func (*Command_AddStream) isCommand_Payload() {}
 
 func (*Command_RemoveStream) isCommand_Payload() {}
@@ -182,20 +294,110 @@ func (*Command_Input) isCommand_Payload() {}
 
 func (*Command_ForceRebuild) isCommand_Payload() {}
 
+func (*Command_SetWatch) isCommand_Payload() {}
+
+func (*Command_ListDevices) isCommand_Payload() {}
+
+func (*Command_SetDevice) isCommand_Payload() {}
+
+func (*Command_StartRecording) isCommand_Payload() {}
+
+func (*Command_StopRecording) isCommand_Payload() {}
+
+func (*Command_Shutdown) isCommand_Payload() {}
+
+func (*Command_Reload) isCommand_Payload() {}
+
+func (*Command_PoolStatus) isCommand_Payload() {}
+
+// Shutdown ends the serve session gracefully: every stream is removed,
+// companions are stopped, the pooled simulators axe booted are shut down,
+// and the CLI exits with status 0 after sending ShutdownComplete. stream_id
+// is ignored.
+type Shutdown struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shutdown) Reset() {
+	*x = Shutdown{}
+	mi := &file_preview_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shutdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shutdown) ProtoMessage() {}
+
+func (x *Shutdown) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shutdown.ProtoReflect.Descriptor instead.
+func (*Shutdown) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{1}
+}
+
 // AddStream creates a new preview stream.
 // The CLI allocates a simulator from the device pool based on device_type + runtime.
+// project/workspace/scheme/configuration optionally select a different project
+// than the one serve was started with. The CLI switches the active project
+// only when no streams of the previous project remain.
+// An AddStream for a stream_id that is already running updates that stream:
+// a new file or preview is switched in place, while a new device, runtime,
+// or project restarts it.
 type AddStream struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	File          string                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`                               // Swift file path to preview
 	DeviceType    string                 `protobuf:"bytes,2,opt,name=device_type,json=deviceType,proto3" json:"device_type,omitempty"` // e.g. "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro"
 	Runtime       string                 `protobuf:"bytes,3,opt,name=runtime,proto3" json:"runtime,omitempty"`                         // e.g. "com.apple.CoreSimulator.SimRuntime.iOS-18-2"
+	Project       string                 `protobuf:"bytes,4,opt,name=project,proto3" json:"project,omitempty"`                         // path to .xcodeproj (empty = keep active project)
+	Workspace     string                 `protobuf:"bytes,5,opt,name=workspace,proto3" json:"workspace,omitempty"`                     // path to .xcworkspace (empty = keep active project)
+	Scheme        string                 `protobuf:"bytes,6,opt,name=scheme,proto3" json:"scheme,omitempty"`                           // scheme for project/workspace (empty = active scheme)
+	Configuration string                 `protobuf:"bytes,7,opt,name=configuration,proto3" json:"configuration,omitempty"`             // build configuration (empty = active configuration)
+	Preview       string                 `protobuf:"bytes,8,opt,name=preview,proto3" json:"preview,omitempty"`                         // #Preview title, 0-based index, /regex/, or file:line (empty = first, or keep current on update)
+	// Requested Frame codec: "jpeg" (default), "png" (lossless, compressed at
+	// serve's --png-compression level), or "h264". Codecs the simulator
+	// cannot produce (e.g. "hevc", "webp") fall back to "jpeg"; the codec in
+	// use is reported in StreamStarted.codec.
+	Codec string `protobuf:"bytes,9,opt,name=codec,proto3" json:"codec,omitempty"`
+	// Whether the stream reloads when watched source files change. Defaults to
+	// true; false pins the stream to what it currently shows. Toggle at runtime
+	// with SetWatch.
+	Watch *bool `protobuf:"varint,10,opt,name=watch,proto3,oneof" json:"watch,omitempty"`
+	// Build only on request: file changes mark the stream "dirty" (a
+	// StreamStatus phase) instead of reloading it, and a Reload command
+	// applies them. Set when the stream starts; an update does not change it.
+	ManualBuild bool `protobuf:"varint,11,opt,name=manual_build,json=manualBuild,proto3" json:"manual_build,omitempty"`
+	// Appearance of this stream's simulator: "light" or "dark". Empty uses
+	// serve's --appearance, or leaves the simulator as it is. A different
+	// value in an update restarts the stream.
+	Appearance string `protobuf:"bytes,12,opt,name=appearance,proto3" json:"appearance,omitempty"`
+	// Locale ("ja_JP") and UI language ("ja") of this stream's simulator.
+	// Empty uses serve's --locale and --language. A locale replaces serve's
+	// --region and --language too. Apps read them at launch, so a different
+	// value in an update relaunches the app rather than hot-reloading.
+	Locale        string `protobuf:"bytes,13,opt,name=locale,proto3" json:"locale,omitempty"`
+	Language      string `protobuf:"bytes,14,opt,name=language,proto3" json:"language,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AddStream) Reset() {
 	*x = AddStream{}
-	mi := &file_preview_proto_msgTypes[1]
+	mi := &file_preview_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -207,7 +409,7 @@ func (x *AddStream) String() string {
 func (*AddStream) ProtoMessage() {}
 
 func (x *AddStream) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[1]
+	mi := &file_preview_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -220,7 +422,7 @@ func (x *AddStream) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddStream.ProtoReflect.Descriptor instead.
 func (*AddStream) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{1}
+	return file_preview_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *AddStream) GetFile() string {
@@ -244,6 +446,83 @@ func (x *AddStream) GetRuntime() string {
 	return ""
 }
 
+func (x *AddStream) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *AddStream) GetWorkspace() string {
+	if x != nil {
+		return x.Workspace
+	}
+	return ""
+}
+
+func (x *AddStream) GetScheme() string {
+	if x != nil {
+		return x.Scheme
+	}
+	return ""
+}
+
+func (x *AddStream) GetConfiguration() string {
+	if x != nil {
+		return x.Configuration
+	}
+	return ""
+}
+
+func (x *AddStream) GetPreview() string {
+	if x != nil {
+		return x.Preview
+	}
+	return ""
+}
+
+func (x *AddStream) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *AddStream) GetWatch() bool {
+	if x != nil && x.Watch != nil {
+		return *x.Watch
+	}
+	return false
+}
+
+func (x *AddStream) GetManualBuild() bool {
+	if x != nil {
+		return x.ManualBuild
+	}
+	return false
+}
+
+func (x *AddStream) GetAppearance() string {
+	if x != nil {
+		return x.Appearance
+	}
+	return ""
+}
+
+func (x *AddStream) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *AddStream) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
 // RemoveStream stops and removes a preview stream.
 type RemoveStream struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -253,7 +532,7 @@ type RemoveStream struct {
 
 func (x *RemoveStream) Reset() {
 	*x = RemoveStream{}
-	mi := &file_preview_proto_msgTypes[2]
+	mi := &file_preview_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -265,7 +544,7 @@ func (x *RemoveStream) String() string {
 func (*RemoveStream) ProtoMessage() {}
 
 func (x *RemoveStream) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[2]
+	mi := &file_preview_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -278,7 +557,7 @@ func (x *RemoveStream) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoveStream.ProtoReflect.Descriptor instead.
 func (*RemoveStream) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{2}
+	return file_preview_proto_rawDescGZIP(), []int{3}
 }
 
 // SwitchFile changes the previewed file within an existing stream (hot-reload).
@@ -291,7 +570,7 @@ type SwitchFile struct {
 
 func (x *SwitchFile) Reset() {
 	*x = SwitchFile{}
-	mi := &file_preview_proto_msgTypes[3]
+	mi := &file_preview_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -303,7 +582,7 @@ func (x *SwitchFile) String() string {
 func (*SwitchFile) ProtoMessage() {}
 
 func (x *SwitchFile) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[3]
+	mi := &file_preview_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -316,7 +595,7 @@ func (x *SwitchFile) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SwitchFile.ProtoReflect.Descriptor instead.
 func (*SwitchFile) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{3}
+	return file_preview_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *SwitchFile) GetFile() string {
@@ -335,7 +614,7 @@ type NextPreview struct {
 
 func (x *NextPreview) Reset() {
 	*x = NextPreview{}
-	mi := &file_preview_proto_msgTypes[4]
+	mi := &file_preview_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -347,7 +626,7 @@ func (x *NextPreview) String() string {
 func (*NextPreview) ProtoMessage() {}
 
 func (x *NextPreview) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[4]
+	mi := &file_preview_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -360,7 +639,54 @@ func (x *NextPreview) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NextPreview.ProtoReflect.Descriptor instead.
 func (*NextPreview) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{4}
+	return file_preview_proto_rawDescGZIP(), []int{5}
+}
+
+// SetWatch turns file watching on or off for the stream. While off, file
+// changes are ignored by this stream; turning it back on does not replay
+// changes made in the meantime (send ForceRebuild for that).
+type SetWatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetWatch) Reset() {
+	*x = SetWatch{}
+	mi := &file_preview_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetWatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetWatch) ProtoMessage() {}
+
+func (x *SetWatch) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetWatch.ProtoReflect.Descriptor instead.
+func (*SetWatch) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SetWatch) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
 }
 
 // ForceRebuild triggers a full rebuild + relaunch for the current stream.
@@ -372,7 +698,7 @@ type ForceRebuild struct {
 
 func (x *ForceRebuild) Reset() {
 	*x = ForceRebuild{}
-	mi := &file_preview_proto_msgTypes[5]
+	mi := &file_preview_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -384,7 +710,7 @@ func (x *ForceRebuild) String() string {
 func (*ForceRebuild) ProtoMessage() {}
 
 func (x *ForceRebuild) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[5]
+	mi := &file_preview_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -397,38 +723,33 @@ func (x *ForceRebuild) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ForceRebuild.ProtoReflect.Descriptor instead.
 func (*ForceRebuild) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{5}
+	return file_preview_proto_rawDescGZIP(), []int{7}
 }
 
-// Input forwards user interaction (touch/text) to the simulator.
-type Input struct {
-	state protoimpl.MessageState `protogen:"open.v1"`
-	// Types that are valid to be assigned to Event:
-	//
-	//	*Input_TouchDown
-	//	*Input_TouchMove
-	//	*Input_TouchUp
-	//	*Input_Text
-	Event         isInput_Event `protobuf_oneof:"event"`
+// Reload applies the file changes a manual_build stream has seen since its
+// last build, hot-reloading or rebuilding as a watched change would.
+// A stream without such changes ignores it.
+type Reload struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Input) Reset() {
-	*x = Input{}
-	mi := &file_preview_proto_msgTypes[6]
+func (x *Reload) Reset() {
+	*x = Reload{}
+	mi := &file_preview_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Input) String() string {
+func (x *Reload) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Input) ProtoMessage() {}
+func (*Reload) ProtoMessage() {}
 
-func (x *Input) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[6]
+func (x *Reload) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -439,25 +760,283 @@ func (x *Input) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Input.ProtoReflect.Descriptor instead.
-func (*Input) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use Reload.ProtoReflect.Descriptor instead.
+func (*Reload) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *Input) GetEvent() isInput_Event {
-	if x != nil {
-		return x.Event
-	}
-	return nil
+// ListDevices asks for the simulators in axe's device set. The CLI answers
+// with a DeviceList event carrying the command's stream_id, which may be
+// empty since the list is not stream-specific.
+type ListDevices struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Input) GetTouchDown() *TouchEvent {
-	if x != nil {
-		if x, ok := x.Event.(*Input_TouchDown); ok {
-			return x.TouchDown
-		}
-	}
-	return nil
+func (x *ListDevices) Reset() {
+	*x = ListDevices{}
+	mi := &file_preview_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevices) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevices) ProtoMessage() {}
+
+func (x *ListDevices) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevices.ProtoReflect.Descriptor instead.
+func (*ListDevices) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{9}
+}
+
+// PoolStatus asks for the state of the idb companions that relay video and
+// input for the session's devices, to diagnose leaked companions or stuck
+// starts. The CLI answers with a CompanionPoolStatus event carrying the
+// command's stream_id, which may be empty.
+type PoolStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PoolStatus) Reset() {
+	*x = PoolStatus{}
+	mi := &file_preview_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PoolStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PoolStatus) ProtoMessage() {}
+
+func (x *PoolStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PoolStatus.ProtoReflect.Descriptor instead.
+func (*PoolStatus) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{10}
+}
+
+// SetDevice moves the stream to the simulator with the given UDID from
+// axe's device set (see ListDevices). The stream restarts on that device
+// with its current file and preview. The device must not be used by
+// another stream; streams of serve --preview-all cannot be moved. A
+// rejected SetDevice is answered with a ProtocolError giving the reason.
+type SetDevice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Udid          string                 `protobuf:"bytes,1,opt,name=udid,proto3" json:"udid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetDevice) Reset() {
+	*x = SetDevice{}
+	mi := &file_preview_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetDevice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetDevice) ProtoMessage() {}
+
+func (x *SetDevice) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetDevice.ProtoReflect.Descriptor instead.
+func (*SetDevice) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetDevice) GetUdid() string {
+	if x != nil {
+		return x.Udid
+	}
+	return ""
+}
+
+// StartRecording records the stream's simulator screen to an H.264 mp4 at
+// path (absolute, overwritten if it exists) until StopRecording, the
+// stream stopping, or the CLI exiting. Recording events report the
+// progress. A stream records to one file at a time.
+type StartRecording struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRecording) Reset() {
+	*x = StartRecording{}
+	mi := &file_preview_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRecording) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRecording) ProtoMessage() {}
+
+func (x *StartRecording) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRecording.ProtoReflect.Descriptor instead.
+func (*StartRecording) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StartRecording) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+// StopRecording ends the stream's recording. A Recording event with
+// active = false follows once the file is finalized.
+type StopRecording struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopRecording) Reset() {
+	*x = StopRecording{}
+	mi := &file_preview_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopRecording) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopRecording) ProtoMessage() {}
+
+func (x *StopRecording) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopRecording.ProtoReflect.Descriptor instead.
+func (*StopRecording) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{13}
+}
+
+// Input forwards user interaction (touch/text) to the simulator.
+type Input struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*Input_TouchDown
+	//	*Input_TouchMove
+	//	*Input_TouchUp
+	//	*Input_Text
+	Event         isInput_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Input) Reset() {
+	*x = Input{}
+	mi := &file_preview_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Input) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Input) ProtoMessage() {}
+
+func (x *Input) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Input.ProtoReflect.Descriptor instead.
+func (*Input) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Input) GetEvent() isInput_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *Input) GetTouchDown() *TouchEvent {
+	if x != nil {
+		if x, ok := x.Event.(*Input_TouchDown); ok {
+			return x.TouchDown
+		}
+	}
+	return nil
 }
 
 func (x *Input) GetTouchMove() *TouchEvent {
@@ -525,7 +1104,7 @@ type TouchEvent struct {
 
 func (x *TouchEvent) Reset() {
 	*x = TouchEvent{}
-	mi := &file_preview_proto_msgTypes[7]
+	mi := &file_preview_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -537,7 +1116,7 @@ func (x *TouchEvent) String() string {
 func (*TouchEvent) ProtoMessage() {}
 
 func (x *TouchEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[7]
+	mi := &file_preview_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -550,7 +1129,7 @@ func (x *TouchEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TouchEvent.ProtoReflect.Descriptor instead.
 func (*TouchEvent) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{7}
+	return file_preview_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *TouchEvent) GetX() float64 {
@@ -576,7 +1155,7 @@ type TextEvent struct {
 
 func (x *TextEvent) Reset() {
 	*x = TextEvent{}
-	mi := &file_preview_proto_msgTypes[8]
+	mi := &file_preview_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -588,7 +1167,7 @@ func (x *TextEvent) String() string {
 func (*TextEvent) ProtoMessage() {}
 
 func (x *TextEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[8]
+	mi := &file_preview_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -601,7 +1180,7 @@ func (x *TextEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TextEvent.ProtoReflect.Descriptor instead.
 func (*TextEvent) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{8}
+	return file_preview_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *TextEvent) GetValue() string {
@@ -624,26 +1203,932 @@ type Event struct {
 	//	*Event_StreamStatus
 	//	*Event_ProtocolError
 	//	*Event_Hello
+	//	*Event_LogStream
+	//	*Event_DeviceList
+	//	*Event_BuildFailed
+	//	*Event_Recording
+	//	*Event_BuildComplete
+	//	*Event_ShutdownComplete
+	//	*Event_CompanionPoolStatus
 	Payload       isEvent_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Event) Reset() {
-	*x = Event{}
-	mi := &file_preview_proto_msgTypes[9]
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_preview_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Event) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *Event) GetPayload() isEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Event) GetFrame() *Frame {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Frame); ok {
+			return x.Frame
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetStreamStarted() *StreamStarted {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_StreamStarted); ok {
+			return x.StreamStarted
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetStreamStopped() *StreamStopped {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_StreamStopped); ok {
+			return x.StreamStopped
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetStreamStatus() *StreamStatus {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_StreamStatus); ok {
+			return x.StreamStatus
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetProtocolError() *ProtocolError {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ProtocolError); ok {
+			return x.ProtocolError
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetHello() *Hello {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetLogStream() *LogStream {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_LogStream); ok {
+			return x.LogStream
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetDeviceList() *DeviceList {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_DeviceList); ok {
+			return x.DeviceList
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetBuildFailed() *BuildFailed {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_BuildFailed); ok {
+			return x.BuildFailed
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetRecording() *Recording {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Recording); ok {
+			return x.Recording
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetBuildComplete() *BuildComplete {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_BuildComplete); ok {
+			return x.BuildComplete
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetShutdownComplete() *ShutdownComplete {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ShutdownComplete); ok {
+			return x.ShutdownComplete
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetCompanionPoolStatus() *CompanionPoolStatus {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_CompanionPoolStatus); ok {
+			return x.CompanionPoolStatus
+		}
+	}
+	return nil
+}
+
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_Frame struct {
+	Frame *Frame `protobuf:"bytes,2,opt,name=frame,proto3,oneof"`
+}
+
+type Event_StreamStarted struct {
+	StreamStarted *StreamStarted `protobuf:"bytes,3,opt,name=stream_started,json=streamStarted,proto3,oneof"`
+}
+
+type Event_StreamStopped struct {
+	StreamStopped *StreamStopped `protobuf:"bytes,4,opt,name=stream_stopped,json=streamStopped,proto3,oneof"`
+}
+
+type Event_StreamStatus struct {
+	StreamStatus *StreamStatus `protobuf:"bytes,5,opt,name=stream_status,json=streamStatus,proto3,oneof"`
+}
+
+type Event_ProtocolError struct {
+	ProtocolError *ProtocolError `protobuf:"bytes,6,opt,name=protocol_error,json=protocolError,proto3,oneof"`
+}
+
+type Event_Hello struct {
+	Hello *Hello `protobuf:"bytes,7,opt,name=hello,proto3,oneof"`
+}
+
+type Event_LogStream struct {
+	LogStream *LogStream `protobuf:"bytes,8,opt,name=log_stream,json=logStream,proto3,oneof"`
+}
+
+type Event_DeviceList struct {
+	DeviceList *DeviceList `protobuf:"bytes,9,opt,name=device_list,json=deviceList,proto3,oneof"`
+}
+
+type Event_BuildFailed struct {
+	BuildFailed *BuildFailed `protobuf:"bytes,10,opt,name=build_failed,json=buildFailed,proto3,oneof"`
+}
+
+type Event_Recording struct {
+	Recording *Recording `protobuf:"bytes,11,opt,name=recording,proto3,oneof"`
+}
+
+type Event_BuildComplete struct {
+	BuildComplete *BuildComplete `protobuf:"bytes,12,opt,name=build_complete,json=buildComplete,proto3,oneof"`
+}
+
+type Event_ShutdownComplete struct {
+	ShutdownComplete *ShutdownComplete `protobuf:"bytes,13,opt,name=shutdown_complete,json=shutdownComplete,proto3,oneof"`
+}
+
+type Event_CompanionPoolStatus struct {
+	CompanionPoolStatus *CompanionPoolStatus `protobuf:"bytes,14,opt,name=companion_pool_status,json=companionPoolStatus,proto3,oneof"`
+}
+
+func (*Event_Frame) isEvent_Payload() {}
+
+func (*Event_StreamStarted) isEvent_Payload() {}
+
+func (*Event_StreamStopped) isEvent_Payload() {}
+
+func (*Event_StreamStatus) isEvent_Payload() {}
+
+func (*Event_ProtocolError) isEvent_Payload() {}
+
+func (*Event_Hello) isEvent_Payload() {}
+
+func (*Event_LogStream) isEvent_Payload() {}
+
+func (*Event_DeviceList) isEvent_Payload() {}
+
+func (*Event_BuildFailed) isEvent_Payload() {}
+
+func (*Event_Recording) isEvent_Payload() {}
+
+func (*Event_BuildComplete) isEvent_Payload() {}
+
+func (*Event_ShutdownComplete) isEvent_Payload() {}
+
+func (*Event_CompanionPoolStatus) isEvent_Payload() {}
+
+// Frame contains a base64-encoded JPEG preview image.
+type Frame struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Device string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"` // device name, e.g. "iPhone 16 Pro"
+	File   string                 `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`     // previewed file path
+	Data   string                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`     // base64-encoded JPEG, or a data: URL with --frame-encoding dataurl
+	// Per-stream sequence number of the frame received from the simulator,
+	// starting at 1 and reset when the stream's device changes. Frames skipped
+	// by the backpressure drop policy leave gaps in the sequence.
+	Seq uint32 `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Capture time in Unix milliseconds. A double rather than int64 so that
+	// it stays a JSON number in the JSON Lines encoding.
+	Timestamp float64 `protobuf:"fixed64,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Preview this frame renders when serve runs with --preview-all: the
+	// #Preview title, or "#<index>" for untitled previews. Empty otherwise.
+	Label string `protobuf:"bytes,6,opt,name=label,proto3" json:"label,omitempty"`
+	// Path of a JPEG file holding the frame when serve runs with
+	// --frame-encoding file; data is empty in that case. Only the most recent
+	// few frame files are kept on disk.
+	Path string `protobuf:"bytes,7,opt,name=path,proto3" json:"path,omitempty"`
+	// Codec of the image in data/path: "jpeg", "png", or "h264" for the next
+	// chunk of an H.264 Annex B byte stream. H.264 chunks depend on earlier ones,
+	// so none are dropped; feed them to the decoder in seq order. With
+	// --frame-encoding file an "h264" request falls back to "jpeg", since
+	// only the latest frame files are kept.
+	Codec string `protobuf:"bytes,8,opt,name=codec,proto3" json:"codec,omitempty"`
+	// Region that changed since the previous frame of the stream, in frame
+	// pixels. Only set when serve runs with --frame-diff and the codec is
+	// "jpeg" or "png". An empty rectangle (width and height 0) means the frame is
+	// identical to the previous one; the first frame, and the first after a
+	// size change or reconnect, covers the whole frame. When absent, redraw
+	// the whole frame.
+	Dirty         *Rect `protobuf:"bytes,9,opt,name=dirty,proto3" json:"dirty,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Frame) Reset() {
+	*x = Frame{}
+	mi := &file_preview_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Frame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frame) ProtoMessage() {}
+
+func (x *Frame) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
+func (*Frame) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Frame) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *Frame) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Frame) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *Frame) GetSeq() uint32 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Frame) GetTimestamp() float64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Frame) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Frame) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Frame) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *Frame) GetDirty() *Rect {
+	if x != nil {
+		return x.Dirty
+	}
+	return nil
+}
+
+// Rect is an axis-aligned rectangle in frame pixels, origin at the top left.
+type Rect struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	X             uint32                 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y             uint32                 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+	Width         uint32                 `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`
+	Height        uint32                 `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rect) Reset() {
+	*x = Rect{}
+	mi := &file_preview_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rect) ProtoMessage() {}
+
+func (x *Rect) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rect.ProtoReflect.Descriptor instead.
+func (*Rect) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Rect) GetX() uint32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Rect) GetY() uint32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+func (x *Rect) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Rect) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+// StreamStarted is sent when an AddStream completes successfully.
+type StreamStarted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PreviewCount  int32                  `protobuf:"varint,1,opt,name=preview_count,json=previewCount,proto3" json:"preview_count,omitempty"` // number of #Preview blocks in the file
+	Codec         string                 `protobuf:"bytes,2,opt,name=codec,proto3" json:"codec,omitempty"`                                    // Frame codec negotiated for the stream, e.g. "jpeg"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamStarted) Reset() {
+	*x = StreamStarted{}
+	mi := &file_preview_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamStarted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStarted) ProtoMessage() {}
+
+func (x *StreamStarted) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStarted.ProtoReflect.Descriptor instead.
+func (*StreamStarted) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *StreamStarted) GetPreviewCount() int32 {
+	if x != nil {
+		return x.PreviewCount
+	}
+	return 0
+}
+
+func (x *StreamStarted) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+// StreamStopped is sent when a stream ends (error or user action).
+type StreamStopped struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`         // e.g. "build_error", "runtime_error", "app_crashed", "user_removed"
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`       // human-readable detail
+	Diagnostic    string                 `protobuf:"bytes,3,opt,name=diagnostic,proto3" json:"diagnostic,omitempty"` // compiler output excerpt for build errors, crashed thread's backtrace for app_crashed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamStopped) Reset() {
+	*x = StreamStopped{}
+	mi := &file_preview_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamStopped) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStopped) ProtoMessage() {}
+
+func (x *StreamStopped) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStopped.ProtoReflect.Descriptor instead.
+func (*StreamStopped) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *StreamStopped) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *StreamStopped) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StreamStopped) GetDiagnostic() string {
+	if x != nil {
+		return x.Diagnostic
+	}
+	return ""
+}
+
+// BuildFailed is sent when building the app or compiling the preview thunk
+// fails. Unlike a StreamStopped with reason "build_error", the stream stays
+// alive: it retries once a watched file changes or on ForceRebuild, and a
+// successful retry continues with the usual StreamStatus phases.
+type BuildFailed struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Diagnostics   []*BuildDiagnostic     `protobuf:"bytes,1,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"` // in order of appearance, duplicates removed
+	Log           string                 `protobuf:"bytes,2,opt,name=log,proto3" json:"log,omitempty"`                 // raw xcodebuild / swiftc output
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildFailed) Reset() {
+	*x = BuildFailed{}
+	mi := &file_preview_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildFailed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildFailed) ProtoMessage() {}
+
+func (x *BuildFailed) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildFailed.ProtoReflect.Descriptor instead.
+func (*BuildFailed) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *BuildFailed) GetDiagnostics() []*BuildDiagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *BuildFailed) GetLog() string {
+	if x != nil {
+		return x.Log
+	}
+	return ""
+}
+
+// BuildComplete is sent once when the app build shared by all streams of
+// the project succeeds, with an empty stream_id. Streams waiting on the
+// build move on to compiling and injecting their thunks afterwards. It is
+// not sent when a previous build is reused (streams then report the
+// "reusing_build" phase).
+type BuildComplete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Project       string                 `protobuf:"bytes,1,opt,name=project,proto3" json:"project,omitempty"`   // project or workspace path
+	Warnings      []*BuildDiagnostic     `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty"` // compiler warnings of the build
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildComplete) Reset() {
+	*x = BuildComplete{}
+	mi := &file_preview_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildComplete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildComplete) ProtoMessage() {}
+
+func (x *BuildComplete) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildComplete.ProtoReflect.Descriptor instead.
+func (*BuildComplete) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BuildComplete) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *BuildComplete) GetWarnings() []*BuildDiagnostic {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+// BuildDiagnostic is one compiler message parsed from the build output.
+type BuildDiagnostic struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          string                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`         // absolute path; empty for messages without a location
+	Line          uint32                 `protobuf:"varint,2,opt,name=line,proto3" json:"line,omitempty"`        // 1-based; 0 when unknown
+	Column        uint32                 `protobuf:"varint,3,opt,name=column,proto3" json:"column,omitempty"`    // 1-based; 0 when unknown
+	Severity      string                 `protobuf:"bytes,4,opt,name=severity,proto3" json:"severity,omitempty"` // "error", "warning", "note", or "remark"
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildDiagnostic) Reset() {
+	*x = BuildDiagnostic{}
+	mi := &file_preview_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildDiagnostic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildDiagnostic) ProtoMessage() {}
+
+func (x *BuildDiagnostic) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildDiagnostic.ProtoReflect.Descriptor instead.
+func (*BuildDiagnostic) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BuildDiagnostic) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *BuildDiagnostic) GetLine() uint32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *BuildDiagnostic) GetColumn() uint32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+func (x *BuildDiagnostic) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *BuildDiagnostic) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// StreamStatus reports progress during stream initialization.
+type StreamStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "booting", "building", "installing", "running", "degraded", "updating",
+	// "compiling_thunk", or, when a saved change waits for the debounce
+	// window or an in-flight build, "pending" (first change) / "coalesced"
+	// (merged into the pending reload). A manual_build stream reports "dirty"
+	// when a file changes and waits for Reload.
+	Phase         string   `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"`
+	Overrides     []string `protobuf:"bytes,2,rep,name=overrides,proto3" json:"overrides,omitempty"`                  // active simulator overrides, e.g. "dynamic_type=accessibility-large", "bold_text"
+	Layout        string   `protobuf:"bytes,3,opt,name=layout,proto3" json:"layout,omitempty"`                        // active iPad multitasking layout (--layout), e.g. "split-half"; empty = none
+	SizeClass     string   `protobuf:"bytes,4,opt,name=size_class,json=sizeClass,proto3" json:"size_class,omitempty"` // horizontal size class injected into the preview: "compact", "regular", or empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamStatus) Reset() {
+	*x = StreamStatus{}
+	mi := &file_preview_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStatus) ProtoMessage() {}
+
+func (x *StreamStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStatus.ProtoReflect.Descriptor instead.
+func (*StreamStatus) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *StreamStatus) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *StreamStatus) GetOverrides() []string {
+	if x != nil {
+		return x.Overrides
+	}
+	return nil
+}
+
+func (x *StreamStatus) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *StreamStatus) GetSizeClass() string {
+	if x != nil {
+		return x.SizeClass
+	}
+	return ""
+}
+
+// ProtocolError reports a protocol-level error (e.g. invalid command JSON)
+// or a rejected command (e.g. SetDevice to a device another stream uses).
+// stream_id on the parent Event is the rejected command's, and may be empty
+// for errors that are not stream-specific.
+type ProtocolError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`                                // offending input line, truncated
+	ErrorCount    uint32                 `protobuf:"varint,3,opt,name=error_count,json=errorCount,proto3" json:"error_count,omitempty"` // invalid input lines received so far
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtocolError) Reset() {
+	*x = ProtocolError{}
+	mi := &file_preview_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtocolError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtocolError) ProtoMessage() {}
+
+func (x *ProtocolError) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtocolError.ProtoReflect.Descriptor instead.
+func (*ProtocolError) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ProtocolError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ProtocolError) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+func (x *ProtocolError) GetErrorCount() uint32 {
+	if x != nil {
+		return x.ErrorCount
+	}
+	return 0
+}
+
+// LogStream carries one unified log entry of the previewed app. Sent only
+// when serve runs with --logs, from launch until the stream stops.
+type LogStream struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Message   string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Level     string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`         // "default", "info", "debug", "error", or "fault"
+	Subsystem string                 `protobuf:"bytes,3,opt,name=subsystem,proto3" json:"subsystem,omitempty"` // os_log subsystem; empty for NSLog and untagged entries
+	Category  string                 `protobuf:"bytes,4,opt,name=category,proto3" json:"category,omitempty"`
+	// Log time in Unix milliseconds, as Frame.timestamp.
+	Timestamp     float64 `protobuf:"fixed64,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Pid           uint32  `protobuf:"varint,6,opt,name=pid,proto3" json:"pid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogStream) Reset() {
+	*x = LogStream{}
+	mi := &file_preview_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Event) String() string {
+func (x *LogStream) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Event) ProtoMessage() {}
+func (*LogStream) ProtoMessage() {}
 
-func (x *Event) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[9]
+func (x *LogStream) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -654,144 +2139,136 @@ func (x *Event) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Event.ProtoReflect.Descriptor instead.
-func (*Event) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use LogStream.ProtoReflect.Descriptor instead.
+func (*LogStream) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{27}
 }
 
-func (x *Event) GetStreamId() string {
+func (x *LogStream) GetMessage() string {
 	if x != nil {
-		return x.StreamId
+		return x.Message
 	}
 	return ""
 }
 
-func (x *Event) GetPayload() isEvent_Payload {
-	if x != nil {
-		return x.Payload
-	}
-	return nil
-}
-
-func (x *Event) GetFrame() *Frame {
+func (x *LogStream) GetLevel() string {
 	if x != nil {
-		if x, ok := x.Payload.(*Event_Frame); ok {
-			return x.Frame
-		}
+		return x.Level
 	}
-	return nil
+	return ""
 }
 
-func (x *Event) GetStreamStarted() *StreamStarted {
+func (x *LogStream) GetSubsystem() string {
 	if x != nil {
-		if x, ok := x.Payload.(*Event_StreamStarted); ok {
-			return x.StreamStarted
-		}
+		return x.Subsystem
 	}
-	return nil
+	return ""
 }
 
-func (x *Event) GetStreamStopped() *StreamStopped {
+func (x *LogStream) GetCategory() string {
 	if x != nil {
-		if x, ok := x.Payload.(*Event_StreamStopped); ok {
-			return x.StreamStopped
-		}
+		return x.Category
 	}
-	return nil
+	return ""
 }
 
-func (x *Event) GetStreamStatus() *StreamStatus {
+func (x *LogStream) GetTimestamp() float64 {
 	if x != nil {
-		if x, ok := x.Payload.(*Event_StreamStatus); ok {
-			return x.StreamStatus
-		}
+		return x.Timestamp
 	}
-	return nil
+	return 0
 }
 
-func (x *Event) GetProtocolError() *ProtocolError {
+func (x *LogStream) GetPid() uint32 {
 	if x != nil {
-		if x, ok := x.Payload.(*Event_ProtocolError); ok {
-			return x.ProtocolError
-		}
+		return x.Pid
 	}
-	return nil
+	return 0
 }
 
-func (x *Event) GetHello() *Hello {
-	if x != nil {
-		if x, ok := x.Payload.(*Event_Hello); ok {
-			return x.Hello
-		}
-	}
-	return nil
+// DeviceList answers ListDevices with every simulator in axe's device set,
+// sorted by name. State is live, so devices booted for this session's
+// streams are reported as such.
+type DeviceList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*Device              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // set when the devices could not be listed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-type isEvent_Payload interface {
-	isEvent_Payload()
+func (x *DeviceList) Reset() {
+	*x = DeviceList{}
+	mi := &file_preview_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-type Event_Frame struct {
-	Frame *Frame `protobuf:"bytes,2,opt,name=frame,proto3,oneof"`
+func (x *DeviceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-type Event_StreamStarted struct {
-	StreamStarted *StreamStarted `protobuf:"bytes,3,opt,name=stream_started,json=streamStarted,proto3,oneof"`
-}
+func (*DeviceList) ProtoMessage() {}
 
-type Event_StreamStopped struct {
-	StreamStopped *StreamStopped `protobuf:"bytes,4,opt,name=stream_stopped,json=streamStopped,proto3,oneof"`
+func (x *DeviceList) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-type Event_StreamStatus struct {
-	StreamStatus *StreamStatus `protobuf:"bytes,5,opt,name=stream_status,json=streamStatus,proto3,oneof"`
+// Deprecated: Use DeviceList.ProtoReflect.Descriptor instead.
+func (*DeviceList) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{28}
 }
 
-type Event_ProtocolError struct {
-	ProtocolError *ProtocolError `protobuf:"bytes,6,opt,name=protocol_error,json=protocolError,proto3,oneof"`
+func (x *DeviceList) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
 }
 
-type Event_Hello struct {
-	Hello *Hello `protobuf:"bytes,7,opt,name=hello,proto3,oneof"`
+func (x *DeviceList) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
 }
 
-func (*Event_Frame) isEvent_Payload() {}
-
-func (*Event_StreamStarted) isEvent_Payload() {}
-
-func (*Event_StreamStopped) isEvent_Payload() {}
-
-func (*Event_StreamStatus) isEvent_Payload() {}
-
-func (*Event_ProtocolError) isEvent_Payload() {}
-
-func (*Event_Hello) isEvent_Payload() {}
-
-// Frame contains a base64-encoded JPEG preview image.
-type Frame struct {
+type Device struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"` // device name, e.g. "iPhone 16 Pro"
-	File          string                 `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`     // previewed file path
-	Data          string                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`     // base64-encoded JPEG
+	Udid          string                 `protobuf:"bytes,1,opt,name=udid,proto3" json:"udid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                               // e.g. "axe iPhone 16 Pro (1)"
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`                             // simctl state, e.g. "Booted", "Booting", or "Shutdown"
+	DeviceType    string                 `protobuf:"bytes,4,opt,name=device_type,json=deviceType,proto3" json:"device_type,omitempty"` // e.g. "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro"
+	Runtime       string                 `protobuf:"bytes,5,opt,name=runtime,proto3" json:"runtime,omitempty"`                         // e.g. "com.apple.CoreSimulator.SimRuntime.iOS-18-2"
+	StreamId      string                 `protobuf:"bytes,6,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"`       // stream running on the device in this session; empty if none
+	InUse         bool                   `protobuf:"varint,7,opt,name=in_use,json=inUse,proto3" json:"in_use,omitempty"`               // acquired by this session, with or without a stream
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Frame) Reset() {
-	*x = Frame{}
-	mi := &file_preview_proto_msgTypes[10]
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_preview_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Frame) String() string {
+func (x *Device) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Frame) ProtoMessage() {}
+func (*Device) ProtoMessage() {}
 
-func (x *Frame) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[10]
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -802,55 +2279,85 @@ func (x *Frame) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
-func (*Frame) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *Frame) GetDevice() string {
+func (x *Device) GetUdid() string {
 	if x != nil {
-		return x.Device
+		return x.Udid
 	}
 	return ""
 }
 
-func (x *Frame) GetFile() string {
+func (x *Device) GetName() string {
 	if x != nil {
-		return x.File
+		return x.Name
 	}
 	return ""
 }
 
-func (x *Frame) GetData() string {
+func (x *Device) GetState() string {
 	if x != nil {
-		return x.Data
+		return x.State
 	}
 	return ""
 }
 
-// StreamStarted is sent when an AddStream completes successfully.
-type StreamStarted struct {
+func (x *Device) GetDeviceType() string {
+	if x != nil {
+		return x.DeviceType
+	}
+	return ""
+}
+
+func (x *Device) GetRuntime() string {
+	if x != nil {
+		return x.Runtime
+	}
+	return ""
+}
+
+func (x *Device) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *Device) GetInUse() bool {
+	if x != nil {
+		return x.InUse
+	}
+	return false
+}
+
+// CompanionPoolStatus answers PoolStatus with the companion of every device,
+// sorted by UDID. A companion is kept for a short while after its last
+// stream ends, so one without streams is not necessarily leaked.
+type CompanionPoolStatus struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	PreviewCount  int32                  `protobuf:"varint,1,opt,name=preview_count,json=previewCount,proto3" json:"preview_count,omitempty"` // number of #Preview blocks in the file
+	Companions    []*CompanionStatus     `protobuf:"bytes,1,rep,name=companions,proto3" json:"companions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamStarted) Reset() {
-	*x = StreamStarted{}
-	mi := &file_preview_proto_msgTypes[11]
+func (x *CompanionPoolStatus) Reset() {
+	*x = CompanionPoolStatus{}
+	mi := &file_preview_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamStarted) String() string {
+func (x *CompanionPoolStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamStarted) ProtoMessage() {}
+func (*CompanionPoolStatus) ProtoMessage() {}
 
-func (x *StreamStarted) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[11]
+func (x *CompanionPoolStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -861,43 +2368,46 @@ func (x *StreamStarted) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamStarted.ProtoReflect.Descriptor instead.
-func (*StreamStarted) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use CompanionPoolStatus.ProtoReflect.Descriptor instead.
+func (*CompanionPoolStatus) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{30}
 }
 
-func (x *StreamStarted) GetPreviewCount() int32 {
+func (x *CompanionPoolStatus) GetCompanions() []*CompanionStatus {
 	if x != nil {
-		return x.PreviewCount
+		return x.Companions
 	}
-	return 0
+	return nil
 }
 
-// StreamStopped is sent when a stream ends (error or user action).
-type StreamStopped struct {
+type CompanionStatus struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`         // e.g. "build_error", "runtime_error", "removed"
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`       // human-readable detail
-	Diagnostic    string                 `protobuf:"bytes,3,opt,name=diagnostic,proto3" json:"diagnostic,omitempty"` // compiler output excerpt for build errors
+	Udid          string                 `protobuf:"bytes,1,opt,name=udid,proto3" json:"udid,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`                                    // gRPC address; empty while starting or once exited
+	Refs          int32                  `protobuf:"varint,3,opt,name=refs,proto3" json:"refs,omitempty"`                                         // streams using the companion
+	Starting      bool                   `protobuf:"varint,4,opt,name=starting,proto3" json:"starting,omitempty"`                                 // a start is in progress
+	UptimeSeconds float64                `protobuf:"fixed64,5,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"` // since the running companion started; 0 if none runs
+	Restarts      int32                  `protobuf:"varint,6,opt,name=restarts,proto3" json:"restarts,omitempty"`                                 // exited companions replaced by a new start
+	LastError     string                 `protobuf:"bytes,7,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`               // last start or exit error; empty if none
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamStopped) Reset() {
-	*x = StreamStopped{}
-	mi := &file_preview_proto_msgTypes[12]
+func (x *CompanionStatus) Reset() {
+	*x = CompanionStatus{}
+	mi := &file_preview_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamStopped) String() string {
+func (x *CompanionStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamStopped) ProtoMessage() {}
+func (*CompanionStatus) ProtoMessage() {}
 
-func (x *StreamStopped) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[12]
+func (x *CompanionStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -908,55 +2418,89 @@ func (x *StreamStopped) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamStopped.ProtoReflect.Descriptor instead.
-func (*StreamStopped) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use CompanionStatus.ProtoReflect.Descriptor instead.
+func (*CompanionStatus) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *StreamStopped) GetReason() string {
+func (x *CompanionStatus) GetUdid() string {
 	if x != nil {
-		return x.Reason
+		return x.Udid
 	}
 	return ""
 }
 
-func (x *StreamStopped) GetMessage() string {
+func (x *CompanionStatus) GetAddress() string {
 	if x != nil {
-		return x.Message
+		return x.Address
 	}
 	return ""
 }
 
-func (x *StreamStopped) GetDiagnostic() string {
+func (x *CompanionStatus) GetRefs() int32 {
 	if x != nil {
-		return x.Diagnostic
+		return x.Refs
+	}
+	return 0
+}
+
+func (x *CompanionStatus) GetStarting() bool {
+	if x != nil {
+		return x.Starting
+	}
+	return false
+}
+
+func (x *CompanionStatus) GetUptimeSeconds() float64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *CompanionStatus) GetRestarts() int32 {
+	if x != nil {
+		return x.Restarts
+	}
+	return 0
+}
+
+func (x *CompanionStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
 	}
 	return ""
 }
 
-// StreamStatus reports progress during stream initialization.
-type StreamStatus struct {
+// Recording reports a stream's screen recording (StartRecording). It is
+// sent with active = true once recording has started, and with
+// active = false when it has ended, on StopRecording or otherwise. error
+// is set when recording could not start or the file could not be
+// finalized.
+type Recording struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Phase         string                 `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"` // "booting", "building", "installing", "running", "degraded"
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Active        bool                   `protobuf:"varint,2,opt,name=active,proto3" json:"active,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamStatus) Reset() {
-	*x = StreamStatus{}
-	mi := &file_preview_proto_msgTypes[13]
+func (x *Recording) Reset() {
+	*x = Recording{}
+	mi := &file_preview_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamStatus) String() string {
+func (x *Recording) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamStatus) ProtoMessage() {}
+func (*Recording) ProtoMessage() {}
 
-func (x *StreamStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[13]
+func (x *Recording) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -967,42 +2511,56 @@ func (x *StreamStatus) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamStatus.ProtoReflect.Descriptor instead.
-func (*StreamStatus) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use Recording.ProtoReflect.Descriptor instead.
+func (*Recording) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *StreamStatus) GetPhase() string {
+func (x *Recording) GetPath() string {
 	if x != nil {
-		return x.Phase
+		return x.Path
 	}
 	return ""
 }
 
-// ProtocolError reports a protocol-level error (e.g. invalid command JSON).
-// stream_id on the parent Event may be empty since these errors are not stream-specific.
-type ProtocolError struct {
+func (x *Recording) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Recording) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ShutdownComplete is the last event of a serve session ended by a
+// Shutdown command, sent after every stream has been torn down. The CLI
+// exits with status 0 right after it.
+type ShutdownComplete struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProtocolError) Reset() {
-	*x = ProtocolError{}
-	mi := &file_preview_proto_msgTypes[14]
+func (x *ShutdownComplete) Reset() {
+	*x = ShutdownComplete{}
+	mi := &file_preview_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProtocolError) String() string {
+func (x *ShutdownComplete) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProtocolError) ProtoMessage() {}
+func (*ShutdownComplete) ProtoMessage() {}
 
-func (x *ProtocolError) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[14]
+func (x *ShutdownComplete) ProtoReflect() protoreflect.Message {
+	mi := &file_preview_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1013,16 +2571,9 @@ func (x *ProtocolError) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProtocolError.ProtoReflect.Descriptor instead.
-func (*ProtocolError) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{14}
-}
-
-func (x *ProtocolError) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+// Deprecated: Use ShutdownComplete.ProtoReflect.Descriptor instead.
+func (*ShutdownComplete) Descriptor() ([]byte, []int) {
+	return file_preview_proto_rawDescGZIP(), []int{33}
 }
 
 // Hello is sent by the CLI at startup to advertise the protocol version.
@@ -1036,7 +2587,7 @@ type Hello struct {
 
 func (x *Hello) Reset() {
 	*x = Hello{}
-	mi := &file_preview_proto_msgTypes[15]
+	mi := &file_preview_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1048,7 +2599,7 @@ func (x *Hello) String() string {
 func (*Hello) ProtoMessage() {}
 
 func (x *Hello) ProtoReflect() protoreflect.Message {
-	mi := &file_preview_proto_msgTypes[15]
+	mi := &file_preview_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1061,7 +2612,7 @@ func (x *Hello) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Hello.ProtoReflect.Descriptor instead.
 func (*Hello) Descriptor() ([]byte, []int) {
-	return file_preview_proto_rawDescGZIP(), []int{15}
+	return file_preview_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *Hello) GetProtocolVersion() int32 {
@@ -1075,7 +2626,7 @@ var File_preview_proto protoreflect.FileDescriptor
 
 const file_preview_proto_rawDesc = "" +
 	"\n" +
-	"\rpreview.proto\x12\vaxe.preview\"\x95\x03\n" +
+	"\rpreview.proto\x12\vaxe.preview\"\xf0\x06\n" +
 	"\aCommand\x12\x1b\n" +
 	"\tstream_id\x18\x01 \x01(\tR\bstreamId\x127\n" +
 	"\n" +
@@ -1085,19 +2636,58 @@ const file_preview_proto_rawDesc = "" +
 	"switchFile\x12=\n" +
 	"\fnext_preview\x18\x05 \x01(\v2\x18.axe.preview.NextPreviewH\x00R\vnextPreview\x12*\n" +
 	"\x05input\x18\x06 \x01(\v2\x12.axe.preview.InputH\x00R\x05input\x12@\n" +
-	"\rforce_rebuild\x18\a \x01(\v2\x19.axe.preview.ForceRebuildH\x00R\fforceRebuildB\t\n" +
-	"\apayload\"Z\n" +
+	"\rforce_rebuild\x18\a \x01(\v2\x19.axe.preview.ForceRebuildH\x00R\fforceRebuild\x124\n" +
+	"\tset_watch\x18\b \x01(\v2\x15.axe.preview.SetWatchH\x00R\bsetWatch\x12=\n" +
+	"\flist_devices\x18\t \x01(\v2\x18.axe.preview.ListDevicesH\x00R\vlistDevices\x127\n" +
+	"\n" +
+	"set_device\x18\n" +
+	" \x01(\v2\x16.axe.preview.SetDeviceH\x00R\tsetDevice\x12F\n" +
+	"\x0fstart_recording\x18\v \x01(\v2\x1b.axe.preview.StartRecordingH\x00R\x0estartRecording\x12C\n" +
+	"\x0estop_recording\x18\f \x01(\v2\x1a.axe.preview.StopRecordingH\x00R\rstopRecording\x123\n" +
+	"\bshutdown\x18\r \x01(\v2\x15.axe.preview.ShutdownH\x00R\bshutdown\x12-\n" +
+	"\x06reload\x18\x0e \x01(\v2\x13.axe.preview.ReloadH\x00R\x06reload\x12:\n" +
+	"\vpool_status\x18\x0f \x01(\v2\x17.axe.preview.PoolStatusH\x00R\n" +
+	"poolStatusB\t\n" +
+	"\apayload\"\n" +
+	"\n" +
+	"\bShutdown\"\x9c\x03\n" +
 	"\tAddStream\x12\x12\n" +
 	"\x04file\x18\x01 \x01(\tR\x04file\x12\x1f\n" +
 	"\vdevice_type\x18\x02 \x01(\tR\n" +
 	"deviceType\x12\x18\n" +
-	"\aruntime\x18\x03 \x01(\tR\aruntime\"\x0e\n" +
+	"\aruntime\x18\x03 \x01(\tR\aruntime\x12\x18\n" +
+	"\aproject\x18\x04 \x01(\tR\aproject\x12\x1c\n" +
+	"\tworkspace\x18\x05 \x01(\tR\tworkspace\x12\x16\n" +
+	"\x06scheme\x18\x06 \x01(\tR\x06scheme\x12$\n" +
+	"\rconfiguration\x18\a \x01(\tR\rconfiguration\x12\x18\n" +
+	"\apreview\x18\b \x01(\tR\apreview\x12\x14\n" +
+	"\x05codec\x18\t \x01(\tR\x05codec\x12\x19\n" +
+	"\x05watch\x18\n" +
+	" \x01(\bH\x00R\x05watch\x88\x01\x01\x12!\n" +
+	"\fmanual_build\x18\v \x01(\bR\vmanualBuild\x12\x1e\n" +
+	"\n" +
+	"appearance\x18\f \x01(\tR\n" +
+	"appearance\x12\x16\n" +
+	"\x06locale\x18\r \x01(\tR\x06locale\x12\x1a\n" +
+	"\blanguage\x18\x0e \x01(\tR\blanguageB\b\n" +
+	"\x06_watch\"\x0e\n" +
 	"\fRemoveStream\" \n" +
 	"\n" +
 	"SwitchFile\x12\x12\n" +
 	"\x04file\x18\x01 \x01(\tR\x04file\"\r\n" +
-	"\vNextPreview\"\x0e\n" +
-	"\fForceRebuild\"\xe8\x01\n" +
+	"\vNextPreview\"$\n" +
+	"\bSetWatch\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\"\x0e\n" +
+	"\fForceRebuild\"\b\n" +
+	"\x06Reload\"\r\n" +
+	"\vListDevices\"\f\n" +
+	"\n" +
+	"PoolStatus\"\x1f\n" +
+	"\tSetDevice\x12\x12\n" +
+	"\x04udid\x18\x01 \x01(\tR\x04udid\"$\n" +
+	"\x0eStartRecording\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x0f\n" +
+	"\rStopRecording\"\xe8\x01\n" +
 	"\x05Input\x128\n" +
 	"\n" +
 	"touch_down\x18\x01 \x01(\v2\x17.axe.preview.TouchEventH\x00R\ttouchDown\x128\n" +
@@ -1111,7 +2701,7 @@ const file_preview_proto_rawDesc = "" +
 	"\x01x\x18\x01 \x01(\x01R\x01x\x12\f\n" +
 	"\x01y\x18\x02 \x01(\x01R\x01y\"!\n" +
 	"\tTextEvent\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\tR\x05value\"\x98\x03\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\"\xef\x06\n" +
 	"\x05Event\x12\x1b\n" +
 	"\tstream_id\x18\x01 \x01(\tR\bstreamId\x12*\n" +
 	"\x05frame\x18\x02 \x01(\v2\x12.axe.preview.FrameH\x00R\x05frame\x12C\n" +
@@ -1119,24 +2709,103 @@ const file_preview_proto_rawDesc = "" +
 	"\x0estream_stopped\x18\x04 \x01(\v2\x1a.axe.preview.StreamStoppedH\x00R\rstreamStopped\x12@\n" +
 	"\rstream_status\x18\x05 \x01(\v2\x19.axe.preview.StreamStatusH\x00R\fstreamStatus\x12C\n" +
 	"\x0eprotocol_error\x18\x06 \x01(\v2\x1a.axe.preview.ProtocolErrorH\x00R\rprotocolError\x12*\n" +
-	"\x05hello\x18\a \x01(\v2\x12.axe.preview.HelloH\x00R\x05helloB\t\n" +
-	"\apayload\"G\n" +
+	"\x05hello\x18\a \x01(\v2\x12.axe.preview.HelloH\x00R\x05hello\x127\n" +
+	"\n" +
+	"log_stream\x18\b \x01(\v2\x16.axe.preview.LogStreamH\x00R\tlogStream\x12:\n" +
+	"\vdevice_list\x18\t \x01(\v2\x17.axe.preview.DeviceListH\x00R\n" +
+	"deviceList\x12=\n" +
+	"\fbuild_failed\x18\n" +
+	" \x01(\v2\x18.axe.preview.BuildFailedH\x00R\vbuildFailed\x126\n" +
+	"\trecording\x18\v \x01(\v2\x16.axe.preview.RecordingH\x00R\trecording\x12C\n" +
+	"\x0ebuild_complete\x18\f \x01(\v2\x1a.axe.preview.BuildCompleteH\x00R\rbuildComplete\x12L\n" +
+	"\x11shutdown_complete\x18\r \x01(\v2\x1d.axe.preview.ShutdownCompleteH\x00R\x10shutdownComplete\x12V\n" +
+	"\x15companion_pool_status\x18\x0e \x01(\v2 .axe.preview.CompanionPoolStatusH\x00R\x13companionPoolStatusB\t\n" +
+	"\apayload\"\xe0\x01\n" +
 	"\x05Frame\x12\x16\n" +
 	"\x06device\x18\x01 \x01(\tR\x06device\x12\x12\n" +
 	"\x04file\x18\x02 \x01(\tR\x04file\x12\x12\n" +
-	"\x04data\x18\x03 \x01(\tR\x04data\"4\n" +
+	"\x04data\x18\x03 \x01(\tR\x04data\x12\x10\n" +
+	"\x03seq\x18\x04 \x01(\rR\x03seq\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x01R\ttimestamp\x12\x14\n" +
+	"\x05label\x18\x06 \x01(\tR\x05label\x12\x12\n" +
+	"\x04path\x18\a \x01(\tR\x04path\x12\x14\n" +
+	"\x05codec\x18\b \x01(\tR\x05codec\x12'\n" +
+	"\x05dirty\x18\t \x01(\v2\x11.axe.preview.RectR\x05dirty\"P\n" +
+	"\x04Rect\x12\f\n" +
+	"\x01x\x18\x01 \x01(\rR\x01x\x12\f\n" +
+	"\x01y\x18\x02 \x01(\rR\x01y\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\rR\x05width\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\rR\x06height\"J\n" +
 	"\rStreamStarted\x12#\n" +
-	"\rpreview_count\x18\x01 \x01(\x05R\fpreviewCount\"a\n" +
+	"\rpreview_count\x18\x01 \x01(\x05R\fpreviewCount\x12\x14\n" +
+	"\x05codec\x18\x02 \x01(\tR\x05codec\"a\n" +
 	"\rStreamStopped\x12\x16\n" +
 	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1e\n" +
 	"\n" +
 	"diagnostic\x18\x03 \x01(\tR\n" +
-	"diagnostic\"$\n" +
+	"diagnostic\"_\n" +
+	"\vBuildFailed\x12>\n" +
+	"\vdiagnostics\x18\x01 \x03(\v2\x1c.axe.preview.BuildDiagnosticR\vdiagnostics\x12\x10\n" +
+	"\x03log\x18\x02 \x01(\tR\x03log\"c\n" +
+	"\rBuildComplete\x12\x18\n" +
+	"\aproject\x18\x01 \x01(\tR\aproject\x128\n" +
+	"\bwarnings\x18\x02 \x03(\v2\x1c.axe.preview.BuildDiagnosticR\bwarnings\"\x87\x01\n" +
+	"\x0fBuildDiagnostic\x12\x12\n" +
+	"\x04file\x18\x01 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\rR\x04line\x12\x16\n" +
+	"\x06column\x18\x03 \x01(\rR\x06column\x12\x1a\n" +
+	"\bseverity\x18\x04 \x01(\tR\bseverity\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"y\n" +
 	"\fStreamStatus\x12\x14\n" +
-	"\x05phase\x18\x01 \x01(\tR\x05phase\")\n" +
+	"\x05phase\x18\x01 \x01(\tR\x05phase\x12\x1c\n" +
+	"\toverrides\x18\x02 \x03(\tR\toverrides\x12\x16\n" +
+	"\x06layout\x18\x03 \x01(\tR\x06layout\x12\x1d\n" +
+	"\n" +
+	"size_class\x18\x04 \x01(\tR\tsizeClass\"^\n" +
 	"\rProtocolError\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"2\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\tR\x04line\x12\x1f\n" +
+	"\verror_count\x18\x03 \x01(\rR\n" +
+	"errorCount\"\xa5\x01\n" +
+	"\tLogStream\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12\x1c\n" +
+	"\tsubsystem\x18\x03 \x01(\tR\tsubsystem\x12\x1a\n" +
+	"\bcategory\x18\x04 \x01(\tR\bcategory\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x01R\ttimestamp\x12\x10\n" +
+	"\x03pid\x18\x06 \x01(\rR\x03pid\"Q\n" +
+	"\n" +
+	"DeviceList\x12-\n" +
+	"\adevices\x18\x01 \x03(\v2\x13.axe.preview.DeviceR\adevices\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xb5\x01\n" +
+	"\x06Device\x12\x12\n" +
+	"\x04udid\x18\x01 \x01(\tR\x04udid\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12\x1f\n" +
+	"\vdevice_type\x18\x04 \x01(\tR\n" +
+	"deviceType\x12\x18\n" +
+	"\aruntime\x18\x05 \x01(\tR\aruntime\x12\x1b\n" +
+	"\tstream_id\x18\x06 \x01(\tR\bstreamId\x12\x15\n" +
+	"\x06in_use\x18\a \x01(\bR\x05inUse\"S\n" +
+	"\x13CompanionPoolStatus\x12<\n" +
+	"\n" +
+	"companions\x18\x01 \x03(\v2\x1c.axe.preview.CompanionStatusR\n" +
+	"companions\"\xd1\x01\n" +
+	"\x0fCompanionStatus\x12\x12\n" +
+	"\x04udid\x18\x01 \x01(\tR\x04udid\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04refs\x18\x03 \x01(\x05R\x04refs\x12\x1a\n" +
+	"\bstarting\x18\x04 \x01(\bR\bstarting\x12%\n" +
+	"\x0euptime_seconds\x18\x05 \x01(\x01R\ruptimeSeconds\x12\x1a\n" +
+	"\brestarts\x18\x06 \x01(\x05R\brestarts\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\a \x01(\tR\tlastError\"M\n" +
+	"\tRecording\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06active\x18\x02 \x01(\bR\x06active\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x12\n" +
+	"\x10ShutdownComplete\"2\n" +
 	"\x05Hello\x12)\n" +
 	"\x10protocol_version\x18\x01 \x01(\x05R\x0fprotocolVersionB6Z4github.com/k-kohey/axe/internal/preview/previewprotob\x06proto3"
 
@@ -1152,47 +2821,86 @@ func file_preview_proto_rawDescGZIP() []byte {
 	return file_preview_proto_rawDescData
 }
 
-var file_preview_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_preview_proto_msgTypes = make([]protoimpl.MessageInfo, 35)
 var file_preview_proto_goTypes = []any{
-	(*Command)(nil),       // 0: axe.preview.Command
-	(*AddStream)(nil),     // 1: axe.preview.AddStream
-	(*RemoveStream)(nil),  // 2: axe.preview.RemoveStream
-	(*SwitchFile)(nil),    // 3: axe.preview.SwitchFile
-	(*NextPreview)(nil),   // 4: axe.preview.NextPreview
-	(*ForceRebuild)(nil),  // 5: axe.preview.ForceRebuild
-	(*Input)(nil),         // 6: axe.preview.Input
-	(*TouchEvent)(nil),    // 7: axe.preview.TouchEvent
-	(*TextEvent)(nil),     // 8: axe.preview.TextEvent
-	(*Event)(nil),         // 9: axe.preview.Event
-	(*Frame)(nil),         // 10: axe.preview.Frame
-	(*StreamStarted)(nil), // 11: axe.preview.StreamStarted
-	(*StreamStopped)(nil), // 12: axe.preview.StreamStopped
-	(*StreamStatus)(nil),  // 13: axe.preview.StreamStatus
-	(*ProtocolError)(nil), // 14: axe.preview.ProtocolError
-	(*Hello)(nil),         // 15: axe.preview.Hello
+	(*Command)(nil),             // 0: axe.preview.Command
+	(*Shutdown)(nil),            // 1: axe.preview.Shutdown
+	(*AddStream)(nil),           // 2: axe.preview.AddStream
+	(*RemoveStream)(nil),        // 3: axe.preview.RemoveStream
+	(*SwitchFile)(nil),          // 4: axe.preview.SwitchFile
+	(*NextPreview)(nil),         // 5: axe.preview.NextPreview
+	(*SetWatch)(nil),            // 6: axe.preview.SetWatch
+	(*ForceRebuild)(nil),        // 7: axe.preview.ForceRebuild
+	(*Reload)(nil),              // 8: axe.preview.Reload
+	(*ListDevices)(nil),         // 9: axe.preview.ListDevices
+	(*PoolStatus)(nil),          // 10: axe.preview.PoolStatus
+	(*SetDevice)(nil),           // 11: axe.preview.SetDevice
+	(*StartRecording)(nil),      // 12: axe.preview.StartRecording
+	(*StopRecording)(nil),       // 13: axe.preview.StopRecording
+	(*Input)(nil),               // 14: axe.preview.Input
+	(*TouchEvent)(nil),          // 15: axe.preview.TouchEvent
+	(*TextEvent)(nil),           // 16: axe.preview.TextEvent
+	(*Event)(nil),               // 17: axe.preview.Event
+	(*Frame)(nil),               // 18: axe.preview.Frame
+	(*Rect)(nil),                // 19: axe.preview.Rect
+	(*StreamStarted)(nil),       // 20: axe.preview.StreamStarted
+	(*StreamStopped)(nil),       // 21: axe.preview.StreamStopped
+	(*BuildFailed)(nil),         // 22: axe.preview.BuildFailed
+	(*BuildComplete)(nil),       // 23: axe.preview.BuildComplete
+	(*BuildDiagnostic)(nil),     // 24: axe.preview.BuildDiagnostic
+	(*StreamStatus)(nil),        // 25: axe.preview.StreamStatus
+	(*ProtocolError)(nil),       // 26: axe.preview.ProtocolError
+	(*LogStream)(nil),           // 27: axe.preview.LogStream
+	(*DeviceList)(nil),          // 28: axe.preview.DeviceList
+	(*Device)(nil),              // 29: axe.preview.Device
+	(*CompanionPoolStatus)(nil), // 30: axe.preview.CompanionPoolStatus
+	(*CompanionStatus)(nil),     // 31: axe.preview.CompanionStatus
+	(*Recording)(nil),           // 32: axe.preview.Recording
+	(*ShutdownComplete)(nil),    // 33: axe.preview.ShutdownComplete
+	(*Hello)(nil),               // 34: axe.preview.Hello
 }
 var file_preview_proto_depIdxs = []int32{
-	1,  // 0: axe.preview.Command.add_stream:type_name -> axe.preview.AddStream
-	2,  // 1: axe.preview.Command.remove_stream:type_name -> axe.preview.RemoveStream
-	3,  // 2: axe.preview.Command.switch_file:type_name -> axe.preview.SwitchFile
-	4,  // 3: axe.preview.Command.next_preview:type_name -> axe.preview.NextPreview
-	6,  // 4: axe.preview.Command.input:type_name -> axe.preview.Input
-	5,  // 5: axe.preview.Command.force_rebuild:type_name -> axe.preview.ForceRebuild
-	7,  // 6: axe.preview.Input.touch_down:type_name -> axe.preview.TouchEvent
-	7,  // 7: axe.preview.Input.touch_move:type_name -> axe.preview.TouchEvent
-	7,  // 8: axe.preview.Input.touch_up:type_name -> axe.preview.TouchEvent
-	8,  // 9: axe.preview.Input.text:type_name -> axe.preview.TextEvent
-	10, // 10: axe.preview.Event.frame:type_name -> axe.preview.Frame
-	11, // 11: axe.preview.Event.stream_started:type_name -> axe.preview.StreamStarted
-	12, // 12: axe.preview.Event.stream_stopped:type_name -> axe.preview.StreamStopped
-	13, // 13: axe.preview.Event.stream_status:type_name -> axe.preview.StreamStatus
-	14, // 14: axe.preview.Event.protocol_error:type_name -> axe.preview.ProtocolError
-	15, // 15: axe.preview.Event.hello:type_name -> axe.preview.Hello
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	2,  // 0: axe.preview.Command.add_stream:type_name -> axe.preview.AddStream
+	3,  // 1: axe.preview.Command.remove_stream:type_name -> axe.preview.RemoveStream
+	4,  // 2: axe.preview.Command.switch_file:type_name -> axe.preview.SwitchFile
+	5,  // 3: axe.preview.Command.next_preview:type_name -> axe.preview.NextPreview
+	14, // 4: axe.preview.Command.input:type_name -> axe.preview.Input
+	7,  // 5: axe.preview.Command.force_rebuild:type_name -> axe.preview.ForceRebuild
+	6,  // 6: axe.preview.Command.set_watch:type_name -> axe.preview.SetWatch
+	9,  // 7: axe.preview.Command.list_devices:type_name -> axe.preview.ListDevices
+	11, // 8: axe.preview.Command.set_device:type_name -> axe.preview.SetDevice
+	12, // 9: axe.preview.Command.start_recording:type_name -> axe.preview.StartRecording
+	13, // 10: axe.preview.Command.stop_recording:type_name -> axe.preview.StopRecording
+	1,  // 11: axe.preview.Command.shutdown:type_name -> axe.preview.Shutdown
+	8,  // 12: axe.preview.Command.reload:type_name -> axe.preview.Reload
+	10, // 13: axe.preview.Command.pool_status:type_name -> axe.preview.PoolStatus
+	15, // 14: axe.preview.Input.touch_down:type_name -> axe.preview.TouchEvent
+	15, // 15: axe.preview.Input.touch_move:type_name -> axe.preview.TouchEvent
+	15, // 16: axe.preview.Input.touch_up:type_name -> axe.preview.TouchEvent
+	16, // 17: axe.preview.Input.text:type_name -> axe.preview.TextEvent
+	18, // 18: axe.preview.Event.frame:type_name -> axe.preview.Frame
+	20, // 19: axe.preview.Event.stream_started:type_name -> axe.preview.StreamStarted
+	21, // 20: axe.preview.Event.stream_stopped:type_name -> axe.preview.StreamStopped
+	25, // 21: axe.preview.Event.stream_status:type_name -> axe.preview.StreamStatus
+	26, // 22: axe.preview.Event.protocol_error:type_name -> axe.preview.ProtocolError
+	34, // 23: axe.preview.Event.hello:type_name -> axe.preview.Hello
+	27, // 24: axe.preview.Event.log_stream:type_name -> axe.preview.LogStream
+	28, // 25: axe.preview.Event.device_list:type_name -> axe.preview.DeviceList
+	22, // 26: axe.preview.Event.build_failed:type_name -> axe.preview.BuildFailed
+	32, // 27: axe.preview.Event.recording:type_name -> axe.preview.Recording
+	23, // 28: axe.preview.Event.build_complete:type_name -> axe.preview.BuildComplete
+	33, // 29: axe.preview.Event.shutdown_complete:type_name -> axe.preview.ShutdownComplete
+	30, // 30: axe.preview.Event.companion_pool_status:type_name -> axe.preview.CompanionPoolStatus
+	19, // 31: axe.preview.Frame.dirty:type_name -> axe.preview.Rect
+	24, // 32: axe.preview.BuildFailed.diagnostics:type_name -> axe.preview.BuildDiagnostic
+	24, // 33: axe.preview.BuildComplete.warnings:type_name -> axe.preview.BuildDiagnostic
+	29, // 34: axe.preview.DeviceList.devices:type_name -> axe.preview.Device
+	31, // 35: axe.preview.CompanionPoolStatus.companions:type_name -> axe.preview.CompanionStatus
+	36, // [36:36] is the sub-list for method output_type
+	36, // [36:36] is the sub-list for method input_type
+	36, // [36:36] is the sub-list for extension type_name
+	36, // [36:36] is the sub-list for extension extendee
+	0,  // [0:36] is the sub-list for field type_name
 }
 
 func init() { file_preview_proto_init() }
@@ -1207,20 +2915,36 @@ func file_preview_proto_init() {
 		(*Command_NextPreview)(nil),
 		(*Command_Input)(nil),
 		(*Command_ForceRebuild)(nil),
+		(*Command_SetWatch)(nil),
+		(*Command_ListDevices)(nil),
+		(*Command_SetDevice)(nil),
+		(*Command_StartRecording)(nil),
+		(*Command_StopRecording)(nil),
+		(*Command_Shutdown)(nil),
+		(*Command_Reload)(nil),
+		(*Command_PoolStatus)(nil),
 	}
-	file_preview_proto_msgTypes[6].OneofWrappers = []any{
+	file_preview_proto_msgTypes[2].OneofWrappers = []any{}
+	file_preview_proto_msgTypes[14].OneofWrappers = []any{
 		(*Input_TouchDown)(nil),
 		(*Input_TouchMove)(nil),
 		(*Input_TouchUp)(nil),
 		(*Input_Text)(nil),
 	}
-	file_preview_proto_msgTypes[9].OneofWrappers = []any{
+	file_preview_proto_msgTypes[17].OneofWrappers = []any{
 		(*Event_Frame)(nil),
 		(*Event_StreamStarted)(nil),
 		(*Event_StreamStopped)(nil),
 		(*Event_StreamStatus)(nil),
 		(*Event_ProtocolError)(nil),
 		(*Event_Hello)(nil),
+		(*Event_LogStream)(nil),
+		(*Event_DeviceList)(nil),
+		(*Event_BuildFailed)(nil),
+		(*Event_Recording)(nil),
+		(*Event_BuildComplete)(nil),
+		(*Event_ShutdownComplete)(nil),
+		(*Event_CompanionPoolStatus)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1228,7 +2952,7 @@ func file_preview_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_preview_proto_rawDesc), len(file_preview_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   35,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,63 @@
+package protocol
+
+import "github.com/k-kohey/axe/internal/idb"
+
+// FrameCodec is the image format a Frame event carries.
+type FrameCodec string
+
+const (
+	// CodecJPEG is a JPEG image converted from raw simulator pixels (default).
+	CodecJPEG FrameCodec = "jpeg"
+	// CodecPNG is a lossless PNG image converted from raw simulator pixels,
+	// encoded at VideoOutputConfig.PNGCompression.
+	CodecPNG FrameCodec = "png"
+	// CodecH264 is a chunk of the H.264 Annex B byte stream from
+	// idb_companion, passed through unchanged.
+	CodecH264 FrameCodec = "h264"
+)
+
+// NegotiateCodec returns the codec to use for a stream that requested
+// requested (AddStream.codec, empty for the default). idb_companion can only
+// encode H.264 besides raw pixels, which axe converts to JPEG or PNG, so any
+// other codec, such as HEVC or WebP, falls back to JPEG; supported is false
+// in that case.
+func NegotiateCodec(requested string) (codec FrameCodec, supported bool) {
+	switch c := FrameCodec(requested); c {
+	case "", CodecJPEG:
+		return CodecJPEG, true
+	case CodecPNG, CodecH264:
+		return c, true
+	default:
+		return CodecJPEG, false
+	}
+}
+
+// videoFormat returns the idb_companion stream format that produces c.
+func (c FrameCodec) videoFormat() idb.VideoFormat {
+	if c == CodecH264 {
+		return idb.VideoFormatH264
+	}
+	return idb.VideoFormatRBGA
+}
+
+// mimeType returns the MIME type used for data URLs of c.
+func (c FrameCodec) mimeType() string {
+	switch c {
+	case CodecH264:
+		return "video/h264"
+	case CodecPNG:
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// fileExt returns the extension of frame files holding c.
+func (c FrameCodec) fileExt() string {
+	switch c {
+	case CodecH264:
+		return ".h264"
+	case CodecPNG:
+		return ".png"
+	}
+	return ".jpg"
+}
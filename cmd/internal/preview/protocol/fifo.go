@@ -0,0 +1,86 @@
+//go:build darwin || linux
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// FIFO is a named pipe opened for writing events, e.g. by serve
+// --events-fifo for clients that read the protocol from a dedicated
+// channel instead of stdout.
+type FIFO struct {
+	*os.File
+	path    string
+	created bool // made by OpenFIFO, so removed by Close
+}
+
+// OpenFIFO opens the named pipe at path for writing, creating it if it
+// does not exist. Opening blocks until a reader connects, so no event is
+// written before someone reads it; it returns ctx.Err() if ctx is done
+// first. An existing path that is not a named pipe is an error.
+func OpenFIFO(ctx context.Context, path string) (*FIFO, error) {
+	created := false
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := syscall.Mkfifo(path, 0o600); err != nil {
+			return nil, fmt.Errorf("creating FIFO %s: %w", path, err)
+		}
+		created = true
+	case err != nil:
+		return nil, err
+	case info.Mode()&fs.ModeNamedPipe == 0:
+		return nil, fmt.Errorf("%s exists and is not a FIFO", path)
+	}
+
+	type result struct {
+		f   *os.File
+		err error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		opened <- result{f, err}
+	}()
+
+	select {
+	case r := <-opened:
+		if r.err != nil {
+			if created {
+				_ = os.Remove(path)
+			}
+			return nil, fmt.Errorf("opening FIFO %s: %w", path, r.err)
+		}
+		return &FIFO{File: r.f, path: path, created: created}, nil
+	case <-ctx.Done():
+		// Connect a reader ourselves to unblock the pending open.
+		if rd, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0); err == nil {
+			if r := <-opened; r.f != nil {
+				_ = r.f.Close()
+			}
+			_ = rd.Close()
+		}
+		if created {
+			_ = os.Remove(path)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the pipe, which the reader sees as end of file, and removes
+// it if OpenFIFO created it.
+func (f *FIFO) Close() error {
+	err := f.File.Close()
+	if f.created {
+		if rmErr := os.Remove(f.path); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) && err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+//go:build !darwin && !linux
+
+package protocol
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// FIFO is a named pipe opened for writing events. Named pipes are not
+// supported on this platform.
+type FIFO struct {
+	*os.File
+}
+
+// OpenFIFO is not implemented on this platform.
+func OpenFIFO(context.Context, string) (*FIFO, error) {
+	return nil, errors.New("named pipes are not supported on this platform")
+}
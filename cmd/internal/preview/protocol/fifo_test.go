@@ -0,0 +1,110 @@
+//go:build darwin || linux
+
+package protocol
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+)
+
+func TestOpenFIFO_WritesFrame(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+
+	type result struct {
+		fifo *FIFO
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		f, err := OpenFIFO(context.Background(), path)
+		opened <- result{f, err}
+	}()
+
+	// OpenFIFO creates the pipe and waits for a reader.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if info.Mode()&fs.ModeNamedPipe == 0 {
+				t.Fatalf("%s is not a named pipe: %v", path, info.Mode())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("FIFO was not created: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case r := <-opened:
+		t.Fatalf("OpenFIFO returned before a reader connected: %v", r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	reader, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reader.Close() }()
+	r := <-opened
+	if r.err != nil {
+		t.Fatalf("OpenFIFO: %v", r.err)
+	}
+
+	ew := NewEventWriter(r.fifo)
+	if err := ew.Send(&pb.Event{StreamId: "s1", Payload: &pb.Event_Frame{Frame: &pb.Frame{Data: "base64data"}}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading FIFO: %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", line, err)
+	}
+	if frame, ok := raw["frame"].(map[string]any); raw["streamId"] != "s1" || !ok || frame["data"] != "base64data" {
+		t.Errorf("event = %s, want the frame of s1", line)
+	}
+
+	// Close removes the pipe OpenFIFO created.
+	if err := r.fifo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("FIFO still exists after Close: %v", err)
+	}
+}
+
+func TestOpenFIFO_CancelWithoutReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := OpenFIFO(ctx, path); err != context.DeadlineExceeded {
+		t.Fatalf("OpenFIFO error = %v, want context.DeadlineExceeded", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("FIFO not removed after cancellation: %v", err)
+	}
+}
+
+func TestOpenFIFO_RejectsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := OpenFIFO(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), "not a FIFO") {
+		t.Fatalf("OpenFIFO error = %v, want a not-a-FIFO error", err)
+	}
+}
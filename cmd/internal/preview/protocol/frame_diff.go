@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"bytes"
+	"image"
+
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+)
+
+// dirtyRect returns the bounding box of the pixels that differ between two
+// w×h frames of 4-byte pixels. It returns the whole frame when prev is
+// missing or has a different size, and an empty rectangle when the frames
+// are identical.
+func dirtyRect(prev, cur []byte, w, h int) image.Rectangle {
+	stride := w * 4
+	if len(prev) != len(cur) || len(cur) != stride*h {
+		return image.Rect(0, 0, w, h)
+	}
+	row := func(b []byte, y int) []byte { return b[y*stride : (y+1)*stride] }
+
+	top := 0
+	for top < h && bytes.Equal(row(prev, top), row(cur, top)) {
+		top++
+	}
+	if top == h {
+		return image.Rectangle{}
+	}
+	bottom := h
+	for bytes.Equal(row(prev, bottom-1), row(cur, bottom-1)) {
+		bottom--
+	}
+
+	left, right := w, 0
+	for y := top; y < bottom; y++ {
+		p, c := row(prev, y), row(cur, y)
+		for x := 0; x < left; x++ {
+			if !bytes.Equal(p[x*4:x*4+4], c[x*4:x*4+4]) {
+				left = x
+				break
+			}
+		}
+		for x := w - 1; x >= right; x-- {
+			if !bytes.Equal(p[x*4:x*4+4], c[x*4:x*4+4]) {
+				right = x + 1
+				break
+			}
+		}
+	}
+	return image.Rect(left, top, right, bottom)
+}
+
+// diffFrame returns the region of pixels that changed since the last
+// frame passed to diffFrame, and remembers pixels for the next call.
+func (voc *VideoOutputConfig) diffFrame(pixels []byte, w, h int) *pb.Rect {
+	r := dirtyRect(voc.prevPixels, pixels, w, h)
+	voc.prevPixels = append(voc.prevPixels[:0], pixels...)
+	return &pb.Rect{
+		X:      uint32(r.Min.X),
+		Y:      uint32(r.Min.Y),
+		Width:  uint32(r.Dx()),
+		Height: uint32(r.Dy()),
+	}
+}
@@ -0,0 +1,76 @@
+package protocol
+
+import (
+	"image"
+	"testing"
+)
+
+// solidFrame returns a w×h frame of 4-byte pixels all set to v.
+func solidFrame(w, h int, v byte) []byte {
+	f := make([]byte, w*h*4)
+	for i := range f {
+		f[i] = v
+	}
+	return f
+}
+
+// setPixel changes one channel of the pixel at (x, y).
+func setPixel(f []byte, w, x, y int, v byte) {
+	f[(y*w+x)*4+1] = v
+}
+
+func TestDirtyRect(t *testing.T) {
+	const w, h = 8, 6
+
+	tests := []struct {
+		name    string
+		prev    []byte
+		changes [][2]int // pixels changed in cur
+		want    image.Rectangle
+	}{
+		{"no previous frame", nil, nil, image.Rect(0, 0, w, h)},
+		{"size change", solidFrame(w, h-1, 0), nil, image.Rect(0, 0, w, h)},
+		{"identical", solidFrame(w, h, 0), nil, image.Rectangle{}},
+		{"single pixel", solidFrame(w, h, 0), [][2]int{{3, 2}}, image.Rect(3, 2, 4, 3)},
+		{"bounding box of scattered pixels", solidFrame(w, h, 0), [][2]int{{5, 1}, {2, 3}, {4, 4}}, image.Rect(2, 1, 6, 5)},
+		{"corners", solidFrame(w, h, 0), [][2]int{{0, 0}, {w - 1, h - 1}}, image.Rect(0, 0, w, h)},
+		{"last column only", solidFrame(w, h, 0), [][2]int{{w - 1, 2}, {w - 1, 3}}, image.Rect(w-1, 2, w, 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := solidFrame(w, h, 0)
+			for _, p := range tt.changes {
+				setPixel(cur, w, p[0], p[1], 0xFF)
+			}
+			if got := dirtyRect(tt.prev, cur, w, h); got != tt.want {
+				t.Errorf("dirtyRect = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffFrame(t *testing.T) {
+	const w, h = 4, 4
+	voc := &VideoOutputConfig{FrameDiff: true}
+
+	first := solidFrame(w, h, 0)
+	if r := voc.diffFrame(first, w, h); r.GetX() != 0 || r.GetY() != 0 || r.GetWidth() != w || r.GetHeight() != h {
+		t.Errorf("first frame dirty = %v, want the whole %dx%d frame", r, w, h)
+	}
+
+	second := solidFrame(w, h, 0)
+	setPixel(second, w, 1, 2, 0xFF)
+	setPixel(second, w, 2, 3, 0xFF)
+	if r := voc.diffFrame(second, w, h); r.GetX() != 1 || r.GetY() != 2 || r.GetWidth() != 2 || r.GetHeight() != 2 {
+		t.Errorf("second frame dirty = %v, want x=1 y=2 2x2", r)
+	}
+
+	// Mutating the caller's buffer must not affect the remembered frame.
+	second[0] = 0xFF
+	if r := voc.diffFrame(solidFrame(w, h, 0), w, h); r.GetX() != 1 || r.GetY() != 2 || r.GetWidth() != 2 || r.GetHeight() != 2 {
+		t.Errorf("third frame dirty = %v, want x=1 y=2 2x2 (relative to the second frame)", r)
+	}
+	if r := voc.diffFrame(solidFrame(w, h, 0), w, h); r.GetWidth() != 0 || r.GetHeight() != 0 {
+		t.Errorf("unchanged frame dirty = %v, want empty", r)
+	}
+}
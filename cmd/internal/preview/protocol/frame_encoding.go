@@ -0,0 +1,88 @@
+package protocol
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+)
+
+// FrameEncoding selects how a Frame event carries its image.
+type FrameEncoding string
+
+const (
+	// FrameEncodingBase64 puts the base64-encoded JPEG in Frame.data (default).
+	FrameEncodingBase64 FrameEncoding = "base64"
+	// FrameEncodingDataURL puts a data:image/jpeg;base64 URL in Frame.data,
+	// ready for an <img> src.
+	FrameEncodingDataURL FrameEncoding = "dataurl"
+	// FrameEncodingFile writes the JPEG to disk and puts its path in
+	// Frame.path, leaving Frame.data empty.
+	FrameEncodingFile FrameEncoding = "file"
+)
+
+// retainedFrameFiles is how many of the most recent frame files are kept in
+// file mode, so a consumer that lags a few frames behind can still read them.
+const retainedFrameFiles = 4
+
+// ParseFrameEncoding validates a --frame-encoding value.
+// An empty string selects FrameEncodingBase64.
+func ParseFrameEncoding(s string) (FrameEncoding, error) {
+	switch e := FrameEncoding(s); e {
+	case "":
+		return FrameEncodingBase64, nil
+	case FrameEncodingBase64, FrameEncodingDataURL, FrameEncodingFile:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown frame encoding %q (want base64, dataurl, or file)", s)
+	}
+}
+
+// setPayload fills the image fields of f according to voc.Encoding.
+// encoded is the base64 image and data its raw bytes, in voc's codec.
+func (voc *VideoOutputConfig) setPayload(f *pb.Frame, encoded string, data []byte) error {
+	switch voc.Encoding {
+	case FrameEncodingDataURL:
+		f.Data = "data:" + voc.codec().mimeType() + ";base64," + encoded
+	case FrameEncodingFile:
+		path, err := voc.writeFrameFile(f.GetSeq(), data)
+		if err != nil {
+			return err
+		}
+		f.Path = path
+	default:
+		f.Data = encoded
+	}
+	return nil
+}
+
+// writeFrameFile writes one frame to FrameDir and removes frame files older
+// than the retained window. The file is renamed into place so a consumer
+// never observes a partially written image.
+func (voc *VideoOutputConfig) writeFrameFile(seq uint32, data []byte) (string, error) {
+	if voc.FrameDir == "" {
+		return "", fmt.Errorf("no frame directory configured")
+	}
+	if err := os.MkdirAll(voc.FrameDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating frame directory: %w", err)
+	}
+	path := filepath.Join(voc.FrameDir, fmt.Sprintf("frame-%d%s", seq, voc.codec().fileExt()))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", fmt.Errorf("writing frame: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("writing frame: %w", err)
+	}
+
+	voc.frameFiles = append(voc.frameFiles, path)
+	for len(voc.frameFiles) > retainedFrameFiles {
+		if err := os.Remove(voc.frameFiles[0]); err != nil && !os.IsNotExist(err) {
+			slog.Debug("Failed to remove old frame file", "path", voc.frameFiles[0], "err", err)
+		}
+		voc.frameFiles = voc.frameFiles[1:]
+	}
+	return path, nil
+}
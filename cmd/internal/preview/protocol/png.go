@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// ParsePNGCompression parses a --png-compression value: a zlib-style level
+// from 0 (store) to 9 (smallest), or "fast" / "best". An empty string
+// selects png.DefaultCompression, which balances CPU and size.
+//
+// image/png supports four levels, so 1-3 map to BestSpeed, 4-6 to the
+// default, and 7-9 to BestCompression.
+func ParsePNGCompression(s string) (png.CompressionLevel, error) {
+	switch s {
+	case "":
+		return png.DefaultCompression, nil
+	case "fast":
+		return png.BestSpeed, nil
+	case "best":
+		return png.BestCompression, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 9 {
+		return 0, fmt.Errorf("invalid PNG compression %q (want 0-9, fast, or best)", s)
+	}
+	switch {
+	case n == 0:
+		return png.NoCompression, nil
+	case n <= 3:
+		return png.BestSpeed, nil
+	case n <= 6:
+		return png.DefaultCompression, nil
+	default:
+		return png.BestCompression, nil
+	}
+}
+
+// encodePNG writes img to w as PNG at the given compression level.
+func encodePNG(w io.Writer, img image.Image, level png.CompressionLevel) error {
+	enc := &png.Encoder{CompressionLevel: level}
+	return enc.Encode(w, img)
+}
+
+// encodeRBGAFramePNG is the PNG counterpart of EncodeRBGAFrame. It encodes
+// with voc.PNGCompression.
+func (voc *VideoOutputConfig) encodeRBGAFramePNG(data []byte, frameW, frameH int, buf *bytes.Buffer) (string, error) {
+	encode := encodePNG
+	if voc.encodePNG != nil {
+		encode = voc.encodePNG
+	}
+	buf.Reset()
+	if err := encode(buf, rbgaImage(data, frameW, frameH), voc.PNGCompression); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -357,7 +357,7 @@ func TestStreamStopped_EmptyDiagnostic(t *testing.T) {
 	// With EmitDefaultValues, empty strings are emitted. Verify reason is present.
 	e := &pb.Event{
 		StreamId: "s1",
-		Payload:  &pb.Event_StreamStopped{StreamStopped: &pb.StreamStopped{Reason: "removed"}},
+		Payload:  &pb.Event_StreamStopped{StreamStopped: &pb.StreamStopped{Reason: "user_removed"}},
 	}
 
 	data, err := MarshalEvent(e)
@@ -379,8 +379,8 @@ func TestStreamStopped_EmptyDiagnostic(t *testing.T) {
 	if !ok {
 		t.Fatal("expected streamStopped to be present")
 	}
-	if ss["reason"] != "removed" {
-		t.Errorf("reason = %v, want %q", ss["reason"], "removed")
+	if ss["reason"] != "user_removed" {
+		t.Errorf("reason = %v, want %q", ss["reason"], "user_removed")
 	}
 }
 
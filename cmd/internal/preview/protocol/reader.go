@@ -3,6 +3,7 @@ package protocol
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,38 +12,107 @@ import (
 	pb "github.com/k-kohey/axe/internal/preview/previewproto"
 )
 
+const (
+	// maxCommandLine is the longest accepted command line. Longer lines are
+	// skipped and reported like malformed JSON.
+	maxCommandLine = 64 * 1024
+
+	// maxEchoedLine bounds how much of an invalid line is echoed back in
+	// ProtocolError.line and in logs.
+	maxEchoedLine = 256
+
+	// parseErrorWarnThreshold is the number of invalid lines after which
+	// the reader additionally reports a likely protocol version mismatch.
+	parseErrorWarnThreshold = 5
+)
+
 // ReadCommands reads Command JSON Lines from r and calls handle for each.
-// Empty lines are skipped; invalid JSON lines are logged and notified via ew.
+// Empty lines are skipped. Invalid lines (malformed JSON, mistyped fields, or
+// lines over 64 KiB) are logged and reported via ew as a ProtocolError that
+// carries the truncated line, and reading continues with the next line.
 // Returns when the reader is exhausted (EOF) or context is cancelled.
 func ReadCommands(ctx context.Context, r io.Reader, ew *EventWriter, handle func(*pb.Command)) {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024)
-	for scanner.Scan() {
+	br := bufio.NewReaderSize(r, maxCommandLine)
+	var errCount uint32
+	reportInvalid := func(line string, err error) {
+		errCount++
+		echoed := truncateLine(line)
+		slog.Warn("Invalid command, skipping", "err", err, "line", echoed, "errorCount", errCount)
+		sendProtocolError(ew, &pb.ProtocolError{
+			Message:    fmt.Sprintf("invalid command: %v", err),
+			Line:       echoed,
+			ErrorCount: errCount,
+		})
+		if errCount == parseErrorWarnThreshold {
+			msg := fmt.Sprintf("received %d invalid commands; the client may be using a different protocol version (CLI speaks version %d)",
+				errCount, ProtocolVersion)
+			slog.Warn(msg)
+			sendProtocolError(ew, &pb.ProtocolError{Message: msg, ErrorCount: errCount})
+		}
+	}
+
+	for {
+		raw, tooLong, readErr := readLine(br)
+
 		select {
 		case <-ctx.Done():
 			return
 		default:
 		}
 
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+		line := strings.TrimSpace(string(raw))
+		switch {
+		case tooLong:
+			reportInvalid(line, fmt.Errorf("line exceeds %d bytes", maxCommandLine))
+		case line == "":
+		default:
+			cmd, err := UnmarshalCommand([]byte(line))
+			if err != nil {
+				reportInvalid(line, err)
+			} else {
+				handle(cmd)
+			}
 		}
-		cmd, err := UnmarshalCommand([]byte(line))
-		if err != nil {
-			slog.Warn("Invalid command JSON, skipping", "err", err, "line", line)
-			if ew != nil {
-				_ = ew.Send(&pb.Event{
-					Payload: &pb.Event_ProtocolError{
-						ProtocolError: &pb.ProtocolError{Message: fmt.Sprintf("invalid command: %v", err)},
-					},
-				})
+
+		if readErr != nil {
+			if !errors.Is(readErr, io.EOF) {
+				slog.Warn("stdin read error", "err", readErr)
 			}
-			continue
+			return
 		}
-		handle(cmd)
 	}
-	if err := scanner.Err(); err != nil {
-		slog.Warn("stdin scanner error", "err", err)
+}
+
+// readLine returns the next line from br without requiring a trailing
+// newline on the last line. A line longer than the reader's buffer is
+// consumed through its newline and reported as tooLong, with line holding
+// only a prefix. The returned slice is valid until the next read.
+func readLine(br *bufio.Reader) (line []byte, tooLong bool, err error) {
+	line, err = br.ReadSlice('\n')
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return line, false, err
+	}
+	prefix := append([]byte(nil), line[:maxEchoedLine]...)
+	for errors.Is(err, bufio.ErrBufferFull) {
+		_, err = br.ReadSlice('\n')
+	}
+	return prefix, true, err
+}
+
+// truncateLine shortens s to at most maxEchoedLine bytes without splitting
+// a UTF-8 sequence.
+func truncateLine(s string) string {
+	if len(s) <= maxEchoedLine {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxEchoedLine], "") + "…"
+}
+
+func sendProtocolError(ew *EventWriter, pe *pb.ProtocolError) {
+	if ew == nil {
+		return
+	}
+	if err := ew.Send(&pb.Event{Payload: &pb.Event_ProtocolError{ProtocolError: pe}}); err != nil {
+		slog.Debug("Failed to send ProtocolError", "err", err)
 	}
 }
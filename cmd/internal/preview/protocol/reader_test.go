@@ -141,3 +141,78 @@ func TestReadCommands_SendsProtocolErrorOnInvalidJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestReadCommands_RecoversFromGarbage(t *testing.T) {
+	huge := `{"streamId":"x","addStream":{"file":"` + strings.Repeat("A", maxCommandLine) + `"}}`
+	input := strings.Join([]string{
+		`{"streamId":"a","nextPreview":{}}`,
+		`garbage`,
+		`{"streamId":"b","forceRebuild":{}}`,
+		huge,
+		`{"streamId":"c","nextPreview":{}}`,
+		`{"streamId":"d","nextPreview":"oops"}`,
+		`[1,2,3]`,
+		`{"streamId":`,
+		`{"streamId":"e","removeStream":{}}`,
+		`}{`,
+		`{"streamId":"f","nextPreview":{}}`,
+	}, "\n")
+
+	var buf bytes.Buffer
+	ew := NewEventWriter(&buf)
+	var ids []string
+	ReadCommands(context.Background(), strings.NewReader(input), ew, func(cmd *pb.Command) {
+		ids = append(ids, cmd.GetStreamId())
+	})
+
+	// Every valid command is handled, including those after an overlong line
+	// and the final line without a trailing newline.
+	if got := strings.Join(ids, ","); got != "a,b,c,e,f" {
+		t.Errorf("handled streamIds = %s, want a,b,c,e,f", got)
+	}
+
+	var errs []*pb.ProtocolError
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		event, err := UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("invalid event JSON: %v", err)
+		}
+		errs = append(errs, event.GetProtocolError())
+	}
+	// Six invalid lines plus one protocol-mismatch warning at the threshold.
+	if len(errs) != 7 {
+		t.Fatalf("got %d ProtocolError events, want 7", len(errs))
+	}
+
+	if errs[0].GetLine() != "garbage" || errs[0].GetErrorCount() != 1 {
+		t.Errorf("first error: line=%q count=%d, want line=%q count=1", errs[0].GetLine(), errs[0].GetErrorCount(), "garbage")
+	}
+	long := errs[1]
+	if !strings.Contains(long.GetMessage(), "exceeds") {
+		t.Errorf("overlong line message = %q, want mention of the size limit", long.GetMessage())
+	}
+	if n := len(long.GetLine()); n == 0 || n > maxEchoedLine+len("…") {
+		t.Errorf("overlong line echoed with %d bytes, want 1..%d", n, maxEchoedLine+len("…"))
+	}
+
+	warning := errs[parseErrorWarnThreshold]
+	if !strings.Contains(warning.GetMessage(), "protocol version") || warning.GetLine() != "" {
+		t.Errorf("threshold event = %+v, want a protocol version mismatch warning", warning)
+	}
+	if last := errs[len(errs)-1]; last.GetLine() != "}{" || last.GetErrorCount() != 6 {
+		t.Errorf("last error: line=%q count=%d, want line=%q count=6", last.GetLine(), last.GetErrorCount(), "}{")
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	short := "short"
+	if got := truncateLine(short); got != short {
+		t.Errorf("truncateLine(%q) = %q", short, got)
+	}
+	// A multi-byte rune straddling the limit must not be split.
+	long := strings.Repeat("a", maxEchoedLine-1) + "日本"
+	got := truncateLine(long)
+	if want := strings.Repeat("a", maxEchoedLine-1) + "…"; got != want {
+		t.Errorf("truncateLine = %q, want %q", got, want)
+	}
+}
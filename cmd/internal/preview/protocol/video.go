@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	"image/png"
+	"io"
 	"log/slog"
 	"math"
 	"time"
@@ -32,11 +34,74 @@ var DefaultRetryConfig = StreamRetryConfig{
 // VideoOutputConfig controls how video frames are output.
 // When EW is non-nil, frames are sent as JSON Lines Events.
 // When EW is nil, frames are written as raw base64 lines to stdout (legacy mode).
+//
+// A config numbers the frames it relays; create a new config per device so
+// the sequence restarts at 1 after a device switch.
 type VideoOutputConfig struct {
 	EW       *EventWriter
 	StreamID string
 	Device   string
 	File     string
+	Label    string // preview label for --preview-all streams (empty otherwise)
+
+	// Encoding selects how frames are carried (default FrameEncodingBase64).
+	// FrameDir is where FrameEncodingFile writes frame files.
+	Encoding FrameEncoding
+	FrameDir string
+	// Codec selects the image format of frames (default CodecJPEG); see
+	// NegotiateCodec.
+	Codec FrameCodec
+	// PNGCompression is the compression level of CodecPNG frames (default
+	// png.DefaultCompression); see ParsePNGCompression.
+	PNGCompression png.CompressionLevel
+	// FrameDiff sets Frame.dirty to the region that changed since the
+	// previous frame, found by comparing pixels. JPEG and PNG frames only.
+	FrameDiff bool
+	// prevPixels holds the pixels of the last frame sent with FrameDiff.
+	prevPixels []byte
+	// frameFiles lists written frame files, oldest first, for cleanup.
+	frameFiles []string
+
+	// seq counts frames received from idb_companion, including frames that
+	// are drained or skipped, so consumers can detect drops from gaps.
+	// Only accessed by the relay goroutine; it persists across reconnects.
+	seq uint32
+
+	// OnFrame, if set, is called after each Frame event is sent.
+	OnFrame func()
+
+	// now returns the capture time. Defaults to time.Now.
+	now func() time.Time
+	// encodePNG encodes CodecPNG frames. Defaults to encodePNG.
+	encodePNG func(w io.Writer, img image.Image, level png.CompressionLevel) error
+}
+
+// codec returns the frame codec, defaulting to JPEG. It is nil-safe so that
+// legacy (stdout) mode needs no config.
+func (voc *VideoOutputConfig) codec() FrameCodec {
+	if voc == nil || voc.Codec == "" {
+		return CodecJPEG
+	}
+	return voc.Codec
+}
+
+// nextSeq records the receipt of a frame and returns its sequence number.
+// It is nil-safe so that legacy (stdout) mode needs no config.
+func (voc *VideoOutputConfig) nextSeq() uint32 {
+	if voc == nil {
+		return 0
+	}
+	voc.seq++
+	return voc.seq
+}
+
+// captureTime returns the current time in Unix milliseconds.
+func (voc *VideoOutputConfig) captureTime() float64 {
+	now := time.Now
+	if voc != nil && voc.now != nil {
+		now = voc.now
+	}
+	return float64(now().UnixMilli())
 }
 
 // RelayVideoStream opens a raw-pixel video stream from idb_companion, converts
@@ -80,16 +145,24 @@ func RelayVideoStreamWithConfig(ctx context.Context, client idb.IDBClient, errCh
 
 // RunVideoStreamLoop handles a single RBGA video stream session.
 // idb_companion streams raw RGBA pixels (no inter-frame compression), which
-// are converted to JPEG and written as base64 lines to stdout.
+// are converted to JPEG (or PNG with CodecPNG) and written as base64 lines
+// to stdout.
 //
 // RBGA format is used instead of H264 because idb_companion's H264 encoder
 // produces severe ghosting artifacts during rapid screen changes.
 // See survey/idb_companion_h264_issue.md for details.
+//
+// With CodecH264 the H.264 stream is passed through instead; see
+// runH264StreamLoop.
 func RunVideoStreamLoop(ctx context.Context, client idb.IDBClient, voc *VideoOutputConfig) error {
-	frameCh, err := client.VideoStream(ctx, 30)
+	codec := voc.codec()
+	frameCh, err := client.VideoStream(ctx, 30, codec.videoFormat())
 	if err != nil {
 		return fmt.Errorf("video stream open: %w", err)
 	}
+	if codec == CodecH264 {
+		return runH264StreamLoop(ctx, frameCh, voc)
+	}
 
 	// Get screen dimensions to compute RBGA pixel dimensions.
 	sw, sh, err := client.ScreenSize(ctx)
@@ -99,6 +172,10 @@ func RunVideoStreamLoop(ctx context.Context, client idb.IDBClient, voc *VideoOut
 
 	var frameW, frameH int
 	var buf bytes.Buffer
+	if voc != nil {
+		// Frames after a reconnect may differ in size; diff from scratch.
+		voc.prevPixels = nil
+	}
 
 	for {
 		select {
@@ -108,9 +185,12 @@ func RunVideoStreamLoop(ctx context.Context, client idb.IDBClient, voc *VideoOut
 			if !ok {
 				return fmt.Errorf("video stream closed unexpectedly")
 			}
+			seq := voc.nextSeq()
+			captured := voc.captureTime()
 
 			// Drain: RBGA frames are independent (no inter-frame dependencies),
-			// so we can safely skip to the latest queued frame.
+			// so we can safely skip to the latest queued frame. Skipped frames
+			// still consume a sequence number, leaving a gap for consumers.
 		drain:
 			for {
 				select {
@@ -119,6 +199,8 @@ func RunVideoStreamLoop(ctx context.Context, client idb.IDBClient, voc *VideoOut
 						return fmt.Errorf("video stream closed unexpectedly")
 					}
 					data = newer
+					seq = voc.nextSeq()
+					captured = voc.captureTime()
 				default:
 					break drain
 				}
@@ -141,18 +223,24 @@ func RunVideoStreamLoop(ctx context.Context, client idb.IDBClient, voc *VideoOut
 				continue
 			}
 
-			encoded, err := EncodeRBGAFrame(data, frameW, frameH, &buf)
+			var encoded string
+			if codec == CodecPNG {
+				encoded, err = voc.encodeRBGAFramePNG(data, frameW, frameH, &buf)
+			} else {
+				encoded, err = EncodeRBGAFrame(data, frameW, frameH, &buf)
+			}
 			if err != nil {
-				slog.Debug("JPEG encode failed", "err", err)
+				slog.Debug("Frame encode failed", "codec", codec, "err", err)
 				continue
 			}
 
 			if voc != nil && voc.EW != nil {
-				if sendErr := voc.EW.Send(&pb.Event{
-					StreamId: voc.StreamID,
-					Payload:  &pb.Event_Frame{Frame: &pb.Frame{Device: voc.Device, File: voc.File, Data: encoded}},
-				}); sendErr != nil {
-					return fmt.Errorf("frame send: %w", sendErr)
+				var dirty *pb.Rect
+				if voc.FrameDiff {
+					dirty = voc.diffFrame(data, frameW, frameH)
+				}
+				if err := voc.sendFrame(seq, captured, encoded, buf.Bytes(), dirty); err != nil {
+					return err
 				}
 			} else {
 				fmt.Println(encoded)
@@ -161,24 +249,114 @@ func RunVideoStreamLoop(ctx context.Context, client idb.IDBClient, voc *VideoOut
 	}
 }
 
+// runH264StreamLoop relays an H.264 stream from idb_companion as-is. Unlike
+// RBGA frames, H.264 frames depend on earlier ones, so every payload is
+// forwarded in order and none are drained.
+func runH264StreamLoop(ctx context.Context, frameCh <-chan []byte, voc *VideoOutputConfig) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case data, ok := <-frameCh:
+			if !ok {
+				return fmt.Errorf("video stream closed unexpectedly")
+			}
+			seq := voc.nextSeq()
+			encoded := base64.StdEncoding.EncodeToString(data)
+			if voc == nil || voc.EW == nil {
+				fmt.Println(encoded)
+				continue
+			}
+			if err := voc.sendFrame(seq, voc.captureTime(), encoded, data, nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendFrame sends one Frame event. encoded is the base64 form of raw, the
+// image in voc's codec; dirty is the changed region, if known. A payload
+// that cannot be encoded is logged and skipped; only a failure to send is
+// returned.
+func (voc *VideoOutputConfig) sendFrame(seq uint32, captured float64, encoded string, raw []byte, dirty *pb.Rect) error {
+	frame := &pb.Frame{
+		Device:    voc.Device,
+		File:      voc.File,
+		Label:     voc.Label,
+		Seq:       seq,
+		Timestamp: captured,
+		Codec:     string(voc.codec()),
+		Dirty:     dirty,
+	}
+	if err := voc.setPayload(frame, encoded, raw); err != nil {
+		slog.Warn("Failed to encode frame payload", "encoding", voc.Encoding, "err", err)
+		// The consumer never sees this frame, so the next one cannot be
+		// described relative to it.
+		voc.prevPixels = nil
+		return nil
+	}
+	if err := voc.EW.Send(&pb.Event{
+		StreamId: voc.StreamID,
+		Payload:  &pb.Event_Frame{Frame: frame},
+	}); err != nil {
+		return fmt.Errorf("frame send: %w", err)
+	}
+	if voc.OnFrame != nil {
+		voc.OnFrame()
+	}
+	return nil
+}
+
+// SendScreenshot sends data, a screenshot in a format image.Decode knows
+// (idb_companion returns PNG), as the next Frame event in voc's codec.
+// --preview-all groups use it for previews that take turns on one device.
+// H.264 has no still-image form, so CodecH264 is rejected.
+func (voc *VideoOutputConfig) SendScreenshot(data []byte) error {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decoding screenshot: %w", err)
+	}
+	var buf bytes.Buffer
+	switch codec := voc.codec(); codec {
+	case CodecJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case CodecPNG:
+		encode := encodePNG
+		if voc.encodePNG != nil {
+			encode = voc.encodePNG
+		}
+		err = encode(&buf, img, voc.PNGCompression)
+	default:
+		return fmt.Errorf("%s frames cannot carry a screenshot", codec)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding screenshot: %w", err)
+	}
+	return voc.sendFrame(voc.nextSeq(), voc.captureTime(), base64.StdEncoding.EncodeToString(buf.Bytes()), buf.Bytes(), nil)
+}
+
 // EncodeRBGAFrame converts raw BGRA pixel data (from idb_companion) into a base64-encoded JPEG string.
 // Despite the protobuf enum name "RBGA", idb_companion maps it to BGRA encoding internally,
 // so the byte order is B, G, R, A. We swap R and B in-place before encoding.
 func EncodeRBGAFrame(data []byte, frameW, frameH int, buf *bytes.Buffer) (string, error) {
-	// Swap B and R channels: idb_companion sends BGRA, but image.NRGBA expects RGBA.
+	buf.Reset()
+	if err := jpeg.Encode(buf, rbgaImage(data, frameW, frameH), &jpeg.Options{Quality: 85}); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// rbgaImage wraps raw BGRA pixel data as an image, swapping the B and R
+// channels in place: idb_companion sends BGRA, but image.NRGBA expects RGBA.
+func rbgaImage(data []byte, frameW, frameH int) *image.NRGBA {
 	for i := 0; i+2 < len(data); i += 4 {
 		data[i], data[i+2] = data[i+2], data[i]
 	}
-	img := &image.NRGBA{
+	return &image.NRGBA{
 		Pix:    data,
 		Stride: frameW * 4,
 		Rect:   image.Rect(0, 0, frameW, frameH),
 	}
-	buf.Reset()
-	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 85}); err != nil {
-		return "", err
-	}
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
 }
 
 // DetectFrameDimensions determines RBGA pixel dimensions from the data size
@@ -5,12 +5,20 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"image"
 	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/k-kohey/axe/internal/idb"
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
 )
 
 // fakeIDBClient implements idb.IDBClient for testing.
@@ -21,15 +29,17 @@ type fakeIDBClient struct {
 	screenW        int
 	screenH        int
 	screenErr      error
-	videoFrames    [][]byte // frames to send on VideoStream
-	videoErr       error    // error from VideoStream open
+	videoFrames    [][]byte        // frames to send on VideoStream
+	videoErr       error           // error from VideoStream open
+	videoFormat    idb.VideoFormat // format requested by the last VideoStream
 }
 
 func (f *fakeIDBClient) ScreenSize(_ context.Context) (int, int, error) {
 	return f.screenW, f.screenH, f.screenErr
 }
 
-func (f *fakeIDBClient) VideoStream(_ context.Context, _ int) (<-chan []byte, error) {
+func (f *fakeIDBClient) VideoStream(_ context.Context, _ int, format idb.VideoFormat) (<-chan []byte, error) {
+	f.videoFormat = format
 	if f.videoErr != nil {
 		return nil, f.videoErr
 	}
@@ -146,7 +156,7 @@ type delayCloseIDBClient struct {
 	frameCh chan []byte
 }
 
-func (d *delayCloseIDBClient) VideoStream(_ context.Context, _ int) (<-chan []byte, error) {
+func (d *delayCloseIDBClient) VideoStream(_ context.Context, _ int, _ idb.VideoFormat) (<-chan []byte, error) {
 	if d.videoErr != nil {
 		return nil, d.videoErr
 	}
@@ -242,6 +252,150 @@ func TestRunVideoStreamLoop_EventWriter(t *testing.T) {
 	}
 }
 
+func TestSendScreenshot(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	var shot bytes.Buffer
+	if err := png.Encode(&shot, img); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, codec := range []FrameCodec{CodecJPEG, CodecPNG} {
+		t.Run(string(codec), func(t *testing.T) {
+			var buf bytes.Buffer
+			voc := &VideoOutputConfig{EW: NewEventWriter(&buf), StreamID: "s#1", Label: "Dark", Codec: codec}
+			for range 2 {
+				if err := voc.SendScreenshot(shot.Bytes()); err != nil {
+					t.Fatalf("SendScreenshot: %v", err)
+				}
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d events, want 2", len(lines))
+			}
+			event, err := UnmarshalEvent([]byte(lines[1]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := event.GetFrame()
+			if event.GetStreamId() != "s#1" || f.GetLabel() != "Dark" || f.GetSeq() != 2 || f.GetCodec() != string(codec) {
+				t.Errorf("frame = %v for stream %s, want label Dark, seq 2, codec %s on s#1", f, event.GetStreamId(), codec)
+			}
+			data, err := base64.StdEncoding.DecodeString(f.GetData())
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("frame is not a valid %s image: %v", codec, err)
+			}
+			if b := decoded.Bounds(); b.Dx() != 3 || b.Dy() != 2 {
+				t.Errorf("frame size = %dx%d, want 3x2", b.Dx(), b.Dy())
+			}
+		})
+	}
+
+	voc := &VideoOutputConfig{EW: NewEventWriter(io.Discard), Codec: CodecH264}
+	if err := voc.SendScreenshot(shot.Bytes()); err == nil {
+		t.Error("SendScreenshot with h264 succeeded, want an error")
+	}
+}
+
+// gatedWriter blocks the first Write until gate is closed, letting a test
+// queue frames while the relay loop is busy sending.
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	gate    chan struct{}
+	blocked chan struct{} // closed when the first Write starts waiting
+	once    sync.Once
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	g.once.Do(func() {
+		close(g.blocked)
+		<-g.gate
+	})
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gatedWriter) lines() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return strings.Split(strings.TrimSpace(g.buf.String()), "\n")
+}
+
+func TestRunVideoStreamLoop_FrameSeqAndTimestamp(t *testing.T) {
+	const w, h = 4, 4
+	newFrame := func() []byte {
+		frame := make([]byte, w*h*4)
+		for i := 0; i < len(frame); i += 4 {
+			frame[i], frame[i+1], frame[i+2], frame[i+3] = 0x00, 0x00, 0xFF, 0xFF // BGRA
+		}
+		return frame
+	}
+
+	frameCh := make(chan []byte, 4)
+	client := &delayCloseIDBClient{
+		fakeIDBClient: fakeIDBClient{screenW: w, screenH: h},
+		frameCh:       frameCh,
+	}
+
+	gw := &gatedWriter{gate: make(chan struct{}), blocked: make(chan struct{})}
+	voc := &VideoOutputConfig{
+		EW:       NewEventWriter(gw),
+		StreamID: "test-stream",
+		now:      func() time.Time { return time.UnixMilli(1700000000123) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunVideoStreamLoop(ctx, client, voc)
+	}()
+
+	// Frame 1 is sent; its Write blocks while frames 2-4 queue up.
+	frameCh <- newFrame()
+	select {
+	case <-gw.blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for first frame send")
+	}
+	frameCh <- newFrame()
+	frameCh <- newFrame()
+	frameCh <- newFrame()
+	close(gw.gate)
+
+	// Frames 2 and 3 are drained in favor of frame 4.
+	deadline := time.After(5 * time.Second)
+	for len(gw.lines()) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for second frame, got %v", gw.lines())
+		default:
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	cancel()
+	<-done
+
+	var seqs []uint32
+	for _, line := range gw.lines() {
+		event, err := UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("invalid JSON: %v\nline: %q", err, line)
+		}
+		if got := event.GetFrame().GetTimestamp(); got != 1700000000123 {
+			t.Errorf("Frame.Timestamp = %v, want 1700000000123", got)
+		}
+		seqs = append(seqs, event.GetFrame().GetSeq())
+	}
+	if want := []uint32{1, 4}; !slices.Equal(seqs, want) {
+		t.Errorf("frame seqs = %v, want %v (gap for dropped frames 2-3)", seqs, want)
+	}
+}
+
 // errWriter always returns an error on Write, simulating a broken pipe.
 type errWriter struct{}
 
@@ -281,6 +435,297 @@ func TestRunVideoStreamLoop_SendFailureStopsLoop(t *testing.T) {
 	}
 }
 
+// relayFrames feeds n identical frames through RunVideoStreamLoop and
+// returns the resulting Frame events.
+func relayFrames(t *testing.T, voc *VideoOutputConfig, n int) []*pb.Frame {
+	t.Helper()
+	const w, h = 4, 4
+	frameCh := make(chan []byte, 1)
+	client := &delayCloseIDBClient{
+		fakeIDBClient: fakeIDBClient{screenW: w, screenH: h},
+		frameCh:       frameCh,
+	}
+	gate := make(chan struct{})
+	close(gate)
+	gw := &gatedWriter{gate: gate, blocked: make(chan struct{})}
+	voc.EW = NewEventWriter(gw)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunVideoStreamLoop(ctx, client, voc)
+	}()
+
+	var frames []*pb.Frame
+	for i := range n {
+		frame := make([]byte, w*h*4)
+		for j := 0; j < len(frame); j += 4 {
+			frame[j], frame[j+1], frame[j+2], frame[j+3] = 0x00, 0x00, 0xFF, 0xFF // BGRA
+		}
+		frameCh <- frame
+		deadline := time.After(5 * time.Second)
+		for lines := gw.lines(); len(lines) <= i || lines[i] == ""; lines = gw.lines() {
+			select {
+			case <-deadline:
+				t.Fatalf("timed out waiting for frame %d", i+1)
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+	cancel()
+	<-done
+
+	for _, line := range gw.lines() {
+		event, err := UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("invalid JSON: %v\nline: %q", err, line)
+		}
+		frames = append(frames, event.GetFrame())
+	}
+	return frames
+}
+
+func TestRunVideoStreamLoop_FrameEncodings(t *testing.T) {
+	decodeJPEG := func(t *testing.T, data []byte) {
+		t.Helper()
+		if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+			t.Fatalf("not a valid JPEG: %v", err)
+		}
+	}
+
+	t.Run("base64", func(t *testing.T) {
+		frames := relayFrames(t, &VideoOutputConfig{StreamID: "s", Encoding: FrameEncodingBase64}, 1)
+		if frames[0].GetPath() != "" {
+			t.Errorf("Frame.Path = %q, want empty", frames[0].GetPath())
+		}
+		data, err := base64.StdEncoding.DecodeString(frames[0].GetData())
+		if err != nil {
+			t.Fatalf("Frame.Data is not base64: %v", err)
+		}
+		decodeJPEG(t, data)
+	})
+
+	t.Run("dataurl", func(t *testing.T) {
+		frames := relayFrames(t, &VideoOutputConfig{StreamID: "s", Encoding: FrameEncodingDataURL}, 1)
+		const prefix = "data:image/jpeg;base64,"
+		got := frames[0].GetData()
+		if !strings.HasPrefix(got, prefix) {
+			t.Fatalf("Frame.Data = %.40q..., want %q prefix", got, prefix)
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(got, prefix))
+		if err != nil {
+			t.Fatalf("data URL payload is not base64: %v", err)
+		}
+		decodeJPEG(t, data)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		n := retainedFrameFiles + 2
+		frames := relayFrames(t, &VideoOutputConfig{StreamID: "s", Encoding: FrameEncodingFile, FrameDir: dir}, n)
+		if len(frames) != n {
+			t.Fatalf("got %d frames, want %d", len(frames), n)
+		}
+		for _, f := range frames {
+			if f.GetData() != "" {
+				t.Errorf("Frame.Data should be empty in file mode, got %d bytes", len(f.GetData()))
+			}
+			if filepath.Dir(f.GetPath()) != dir {
+				t.Errorf("Frame.Path = %q, want a file in %q", f.GetPath(), dir)
+			}
+		}
+		data, err := os.ReadFile(frames[n-1].GetPath())
+		if err != nil {
+			t.Fatalf("reading latest frame: %v", err)
+		}
+		decodeJPEG(t, data)
+
+		// Only the most recent frames are kept on disk.
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != retainedFrameFiles {
+			t.Errorf("%d files in frame dir, want %d", len(entries), retainedFrameFiles)
+		}
+		if _, err := os.Stat(frames[0].GetPath()); !os.IsNotExist(err) {
+			t.Errorf("oldest frame %s should have been removed (err=%v)", frames[0].GetPath(), err)
+		}
+	})
+}
+
+func TestRunVideoStreamLoop_H264PassThrough(t *testing.T) {
+	chunks := [][]byte{{0, 0, 0, 1, 0x67}, {0, 0, 0, 1, 0x68}, {0, 0, 0, 1, 0x65}}
+	client := &fakeIDBClient{videoFrames: chunks}
+	var buf bytes.Buffer
+	voc := &VideoOutputConfig{EW: NewEventWriter(&buf), StreamID: "s", Codec: CodecH264}
+
+	// The fake closes the stream after the queued chunks.
+	if err := RunVideoStreamLoop(context.Background(), client, voc); err == nil {
+		t.Fatal("expected error when the stream closes")
+	}
+	if client.videoFormat != idb.VideoFormatH264 {
+		t.Errorf("requested format = %v, want H264", client.videoFormat)
+	}
+
+	// Every chunk is relayed in order: H.264 frames must not be drained.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(chunks) {
+		t.Fatalf("got %d frames, want %d", len(lines), len(chunks))
+	}
+	for i, line := range lines {
+		event, err := UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("invalid JSON: %v\nline: %q", err, line)
+		}
+		f := event.GetFrame()
+		if f.GetCodec() != "h264" {
+			t.Errorf("frame %d: Codec = %q, want h264", i, f.GetCodec())
+		}
+		if f.GetSeq() != uint32(i+1) {
+			t.Errorf("frame %d: Seq = %d, want %d", i, f.GetSeq(), i+1)
+		}
+		if want := base64.StdEncoding.EncodeToString(chunks[i]); f.GetData() != want {
+			t.Errorf("frame %d: Data = %q, want %q", i, f.GetData(), want)
+		}
+	}
+}
+
+func TestRunVideoStreamLoop_JPEGCodecField(t *testing.T) {
+	client := &fakeIDBClient{}
+	frames := relayFrames(t, &VideoOutputConfig{StreamID: "s"}, 1)
+	if got := frames[0].GetCodec(); got != "jpeg" {
+		t.Errorf("Frame.Codec = %q, want jpeg", got)
+	}
+
+	_ = RunVideoStreamLoop(context.Background(), client, &VideoOutputConfig{StreamID: "s"})
+	if client.videoFormat != idb.VideoFormatRBGA {
+		t.Errorf("requested format = %v, want RBGA", client.videoFormat)
+	}
+}
+
+func TestRunVideoStreamLoop_PNGCodec(t *testing.T) {
+	frames := relayFrames(t, &VideoOutputConfig{StreamID: "s", Codec: CodecPNG, Encoding: FrameEncodingDataURL}, 1)
+	if got := frames[0].GetCodec(); got != "png" {
+		t.Errorf("Frame.Codec = %q, want png", got)
+	}
+	const prefix = "data:image/png;base64,"
+	got := frames[0].GetData()
+	if !strings.HasPrefix(got, prefix) {
+		t.Fatalf("Frame.Data = %.40q..., want %q prefix", got, prefix)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(got, prefix))
+	if err != nil {
+		t.Fatalf("data URL payload is not base64: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("not a valid PNG: %v", err)
+	}
+	// PNG is lossless: the BGRA red input decodes to exactly red.
+	if r, g, b, a := img.At(0, 0).RGBA(); r != 0xFFFF || g != 0 || b != 0 || a != 0xFFFF {
+		t.Errorf("pixel = (%d, %d, %d, %d), want opaque red", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+func TestRunVideoStreamLoop_PNGCompressionLevel(t *testing.T) {
+	for _, level := range []png.CompressionLevel{png.DefaultCompression, png.NoCompression, png.BestSpeed, png.BestCompression} {
+		var got []png.CompressionLevel
+		voc := &VideoOutputConfig{
+			StreamID:       "s",
+			Codec:          CodecPNG,
+			PNGCompression: level,
+			encodePNG: func(w io.Writer, img image.Image, l png.CompressionLevel) error {
+				got = append(got, l)
+				return encodePNG(w, img, l)
+			},
+		}
+		relayFrames(t, voc, 2)
+		if want := []png.CompressionLevel{level, level}; !slices.Equal(got, want) {
+			t.Errorf("PNGCompression %d: encoder levels = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestParsePNGCompression(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    png.CompressionLevel
+		wantErr bool
+	}{
+		{in: "", want: png.DefaultCompression},
+		{in: "fast", want: png.BestSpeed},
+		{in: "best", want: png.BestCompression},
+		{in: "0", want: png.NoCompression},
+		{in: "1", want: png.BestSpeed},
+		{in: "3", want: png.BestSpeed},
+		{in: "4", want: png.DefaultCompression},
+		{in: "6", want: png.DefaultCompression},
+		{in: "7", want: png.BestCompression},
+		{in: "9", want: png.BestCompression},
+		{in: "10", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "smallest", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePNGCompression(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePNGCompression(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePNGCompression(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	tests := []struct {
+		requested     string
+		want          FrameCodec
+		wantSupported bool
+	}{
+		{requested: "", want: CodecJPEG, wantSupported: true},
+		{requested: "jpeg", want: CodecJPEG, wantSupported: true},
+		{requested: "png", want: CodecPNG, wantSupported: true},
+		{requested: "h264", want: CodecH264, wantSupported: true},
+		// idb_companion cannot produce these; fall back to JPEG.
+		{requested: "hevc", want: CodecJPEG},
+		{requested: "webp", want: CodecJPEG},
+	}
+	for _, tt := range tests {
+		got, supported := NegotiateCodec(tt.requested)
+		if got != tt.want || supported != tt.wantSupported {
+			t.Errorf("NegotiateCodec(%q) = (%q, %v), want (%q, %v)", tt.requested, got, supported, tt.want, tt.wantSupported)
+		}
+	}
+}
+
+func TestParseFrameEncoding(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FrameEncoding
+		wantErr bool
+	}{
+		{in: "", want: FrameEncodingBase64},
+		{in: "base64", want: FrameEncodingBase64},
+		{in: "dataurl", want: FrameEncodingDataURL},
+		{in: "file", want: FrameEncodingFile},
+		{in: "png", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFrameEncoding(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFrameEncoding(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFrameEncoding(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestEncodeRBGAFrame(t *testing.T) {
 	const w, h = 4, 4
 	// Create a small 4x4 BGRA frame (red pixels in BGRA byte order).
@@ -0,0 +1,87 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/k-kohey/axe/internal/platform"
+)
+
+// screenRecording is a screen recording in progress. *platform.Recording
+// implements it; tests substitute a fake.
+type screenRecording interface {
+	Stop() error
+	Done() <-chan struct{}
+}
+
+// startScreenRecording starts recording device's screen to path (serve
+// StartRecording).
+func startScreenRecording(device, deviceSetPath, path string) (screenRecording, error) {
+	rec, err := platform.StartRecording(device, deviceSetPath, path)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// recordSession starts recording the screen of device to path (--record)
+// and returns a function that stops the recording and waits for the file
+// to be finalized. With a positive duration the recording also stops by
+// itself after that long while the session goes on. The stop function may
+// be called more than once; only the first call reports the result.
+func recordSession(device, deviceSetPath, path string, duration time.Duration) (func(), error) {
+	rec, err := platform.StartRecording(device, deviceSetPath, path)
+	if err != nil {
+		return nil, fmt.Errorf("recording screen: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Recording screen to %s\n", path)
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			if err := rec.Stop(); err != nil {
+				slog.Warn("Screen recording failed", "path", path, "err", err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Saved screen recording to %s\n", path)
+		})
+	}
+	if duration > 0 {
+		time.AfterFunc(duration, stop)
+	}
+	return stop, nil
+}
+
+// recordBeforeReady wraps onReady (oneshot --record) so that the screen
+// is recorded for duration before onReady runs, e.g. before the
+// screenshot is taken. Ctrl+C ends the recording early but still
+// finalizes the file.
+func recordBeforeReady(path string, duration time.Duration, onReady func(ctx context.Context, device, deviceSetPath string) error) func(ctx context.Context, device, deviceSetPath string) error {
+	return func(ctx context.Context, device, deviceSetPath string) error {
+		rec, err := platform.StartRecording(device, deviceSetPath, path)
+		if err != nil {
+			return fmt.Errorf("recording screen: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "\nRecording screen to %s for %s\n", path, duration)
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+		case <-rec.Done():
+		}
+		if err := rec.Stop(); err != nil {
+			return fmt.Errorf("recording screen: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Saved screen recording to %s\n", path)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if onReady == nil {
+			return nil
+		}
+		return onReady(ctx, device, deviceSetPath)
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/k-kohey/axe/internal/preview"
 	"github.com/k-kohey/axe/internal/preview/analysis"
 	"github.com/k-kohey/axe/internal/preview/build"
+	"github.com/k-kohey/axe/internal/preview/codegen"
+	"github.com/k-kohey/axe/internal/procgroup"
 )
 
 // ReportOptions holds parameters for the preview report command.
@@ -33,8 +35,20 @@ type ReportOptions struct {
 	Format      string        // png, md, or html
 	PC          build.ProjectConfig
 	Device      string
-	Concurrency int  // 0 = auto, 1 = sequential (existing path)
-	ReuseBuild  bool // skip xcodebuild and reuse artifacts from a previous build
+	Runtime     string     // e.g. "iOS 18.2"; constrains simulator reuse and auto-create
+	Family      string     // "iPhone" or "iPad"; constrains simulator reuse and auto-create
+	DeviceType  string     // e.g. "iPhone 15"; constrains simulator reuse and auto-create
+	Concurrency int        // 0 = auto, 1 = sequential (existing path)
+	BuildMode   build.Mode // whether to reuse, incrementally build, or clean build the project
+
+	Accessibility platform.AccessibilityOverrides
+	StatusBar     *platform.StatusBarOverride // nil = leave the status bar unchanged
+	MockSources   []string                    // preview-only Swift files compiled into every thunk
+	ThunkImports  []string                    // extra modules imported by every thunk
+	SeedDir       string                      // copied into the app data container before launch
+	KeepThunk     bool                        // keep thunk sources and log the swiftc command
+	Layout        codegen.Layout              // iPad layout and size class
+	BootTimeout   time.Duration               // wait for a reused simulator to reach Booted (0 = default)
 }
 
 const (
@@ -108,12 +122,12 @@ func RunReport(opts ReportOptions) error {
 		return err
 	}
 
-	dirs, err := build.NewProjectDirs(opts.PC.PrimaryPath())
+	dirs, err := build.NewProjectDirs(opts.PC)
 	if err != nil {
 		return fmt.Errorf("resolving build directories: %w", err)
 	}
 	br := build.NewRunner()
-	preparer := build.NewPreparer(opts.PC, dirs, opts.ReuseBuild, br)
+	preparer := build.NewPreparer(opts.PC, dirs, opts.BuildMode, br)
 
 	useParallel := len(blocks) > 1 && opts.Concurrency != 1
 	if useParallel && opts.Device != "" {
@@ -229,18 +243,26 @@ func runReportDocument(
 // Build and Boot in parallel.
 func createReportSession(ctx context.Context, opts ReportOptions, preparer *build.Preparer) (*preview.PreviewSession, error) {
 	simctl := &platform.RealSimctlRunner{}
-	device, setPath, isExternal, err := platform.ResolveAxeSimulator(simctl, opts.Device)
+	sim, err := platform.ResolveAxeSimulator(simctl, opts.Device, opts.Runtime, opts.Family, opts.DeviceType, opts.PC.PrimaryPath())
 	if err != nil {
 		return nil, fmt.Errorf("resolving simulator: %w", err)
 	}
 	br, tc, ar, fc := preview.DefaultSessionRunners()
 	return preview.NewPreviewSession(ctx, preview.SessionConfig{
 		PC:               opts.PC,
-		DeviceUDID:       device,
-		DeviceSetPath:    setPath,
-		IsExternalDevice: isExternal,
+		DeviceUDID:       sim.UDID,
+		DeviceSetPath:    sim.DeviceSetPath,
+		IsExternalDevice: sim.IsExternal,
 		Preparer:         preparer,
-		ReuseBuild:       opts.ReuseBuild,
+		BuildMode:        opts.BuildMode,
+		Accessibility:    preview.WithDeviceProfile(opts.Accessibility, sim.UDID),
+		StatusBar:        opts.StatusBar,
+		MockSources:      opts.MockSources,
+		ThunkImports:     opts.ThunkImports,
+		SeedDir:          opts.SeedDir,
+		KeepThunk:        opts.KeepThunk,
+		Layout:           opts.Layout,
+		BootTimeout:      opts.BootTimeout,
 		BuildRunner:      br,
 		Toolchain:        tc,
 		AppRunner:        ar,
@@ -381,7 +403,7 @@ func captureLoopPartial(opts ReportOptions, blocks []fileBlocks, preparer *build
 func resolveVersion() string {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--short", "HEAD").Output()
+	out, err := procgroup.Command(ctx, "git", "rev-parse", "--short", "HEAD").Output()
 	if err == nil {
 		hash := strings.TrimSpace(string(out))
 		if hash != "" {
@@ -50,20 +50,18 @@ func effectiveConcurrency(totalFiles, requested int) int {
 	return limit
 }
 
-// setupReportPool creates a DevicePool and resolves the default device spec.
-func setupReportPool(ctx context.Context) (pool *platform.DevicePool, setPath, deviceType, runtime string, err error) {
+// setupReportPool creates a DevicePool and resolves the default device spec,
+// restricted to runtimeName, family and deviceTypeName when set.
+func setupReportPool(ctx context.Context, runtimeName, family, deviceTypeName string) (pool *platform.DevicePool, setPath, deviceType, runtime string, err error) {
 	simctl := &platform.RealSimctlRunner{}
-	deviceType, runtime, err = platform.FindDefaultDeviceSpec(simctl)
+	deviceType, runtime, err = platform.FindDefaultDeviceSpec(simctl, runtimeName, family, deviceTypeName)
 	if err != nil {
 		return nil, "", "", "", fmt.Errorf("resolving device spec: %w", err)
 	}
-	setPath, err = platform.AxeDeviceSetPath()
+	setPath, err = platform.PrepareAxeDeviceSet()
 	if err != nil {
 		return nil, "", "", "", err
 	}
-	if mkErr := os.MkdirAll(setPath, 0o755); mkErr != nil {
-		return nil, "", "", "", fmt.Errorf("creating device set directory: %w", mkErr)
-	}
 	pool = platform.NewDevicePool(simctl, setPath)
 	if cleanErr := pool.CleanupOrphans(ctx); cleanErr != nil {
 		slog.Warn("orphan cleanup failed", "err", cleanErr)
@@ -95,7 +93,7 @@ func runParallelCapture(ctx context.Context, opts ReportOptions, blocks []fileBl
 	preparer *build.Preparer, failFast bool) captureResult {
 
 	// 1. DevicePool setup
-	pool, setPath, deviceType, runtime, err := setupReportPool(ctx)
+	pool, setPath, deviceType, runtime, err := setupReportPool(ctx, opts.Runtime, opts.Family, opts.DeviceType)
 	if err != nil {
 		return allFailures(blocks, err)
 	}
@@ -169,7 +167,14 @@ func runParallelCapture(ctx context.Context, opts ReportOptions, blocks []fileBl
 				DeviceUDID:    udid,
 				DeviceSetPath: setPath,
 				Preparer:      preparer,
-				ReuseBuild:    opts.ReuseBuild,
+				BuildMode:     opts.BuildMode,
+				Accessibility: preview.WithDeviceProfile(opts.Accessibility, udid),
+				StatusBar:     opts.StatusBar,
+				MockSources:   opts.MockSources,
+				ThunkImports:  opts.ThunkImports,
+				SeedDir:       opts.SeedDir,
+				KeepThunk:     opts.KeepThunk,
+				Layout:        opts.Layout,
 				BuildRunner:   br,
 				Toolchain:     tc,
 				AppRunner:     ar,
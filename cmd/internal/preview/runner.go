@@ -2,6 +2,7 @@ package preview
 
 import (
 	"context"
+	"time"
 
 	"github.com/k-kohey/axe/internal/preview/build"
 	"github.com/k-kohey/axe/internal/preview/runner"
@@ -23,6 +24,25 @@ type AppRunner interface {
 	Terminate(ctx context.Context, device, bundleID, deviceSetPath string) error
 	Install(ctx context.Context, device, appPath, deviceSetPath string) error
 	Launch(ctx context.Context, device, bundleID, deviceSetPath string, env map[string]string, args []string) error
+	// DataContainer returns the host path of the installed app's data container.
+	DataContainer(ctx context.Context, device, bundleID, deviceSetPath string) (string, error)
+}
+
+// LogStreamer abstracts running a log command inside a simulator for
+// testability.
+type LogStreamer interface {
+	Stream(ctx context.Context, device, deviceSetPath string, args []string, onLine func([]byte)) error
+}
+
+// CrashReporter abstracts reading the crash reports (.ips) that simulator
+// apps leave on the host, for testability.
+type CrashReporter interface {
+	// Latest returns the newest crash report of the app with bundleID written
+	// at or after since, or nil when there is none.
+	Latest(ctx context.Context, bundleID string, since time.Time) ([]byte, error)
+	// Symbolicate resolves addresses in the image at imagePath, loaded at
+	// loadAddr, to one symbol name per address.
+	Symbolicate(ctx context.Context, imagePath string, loadAddr uint64, addrs []uint64) ([]string, error)
 }
 
 // FileCopier abstracts file copy operations for testability.
@@ -36,10 +56,19 @@ type SourceLister interface {
 	SwiftDirs(ctx context.Context, root string) ([]string, error)
 }
 
+// GitRunner abstracts git commands run in a repository directory for
+// testability. Run returns the command's stdout.
+type GitRunner interface {
+	Run(ctx context.Context, dir string, args ...string) ([]byte, error)
+}
+
 // Compile-time interface compliance checks.
 var (
 	_ ToolchainRunner = (*runner.Toolchain)(nil)
 	_ AppRunner       = (*runner.App)(nil)
+	_ LogStreamer     = (*runner.Log)(nil)
+	_ CrashReporter   = (*runner.CrashLog)(nil)
 	_ FileCopier      = (*runner.FileCopy)(nil)
 	_ SourceLister    = (*runner.SourceList)(nil)
+	_ GitRunner       = (*runner.Git)(nil)
 )
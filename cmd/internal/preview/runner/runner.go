@@ -5,12 +5,18 @@
 package runner
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/k-kohey/axe/internal/procgroup"
 )
@@ -101,6 +107,131 @@ func (r *App) Launch(ctx context.Context, device, bundleID, deviceSetPath string
 	return nil
 }
 
+func (r *App) DataContainer(ctx context.Context, device, bundleID, deviceSetPath string) (string, error) {
+	out, err := simctlCmd(ctx, deviceSetPath, "get_app_container", device, bundleID, "data").Output()
+	if err != nil {
+		return "", fmt.Errorf("simctl get_app_container: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// --- Log ---
+
+// Log executes commands inside a simulator to read its unified log.
+type Log struct{}
+
+// Stream runs "simctl spawn <device> <args...>" and calls onLine with each
+// line it prints until the command exits or ctx is cancelled.
+func (r *Log) Stream(ctx context.Context, device, deviceSetPath string, args []string, onLine func([]byte)) error {
+	cmd := simctlCmd(ctx, deviceSetPath, append([]string{"spawn", device}, args...)...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("simctl spawn: %w", err)
+	}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		onLine(scanner.Bytes())
+	}
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("simctl spawn: %w", err)
+	}
+	return nil
+}
+
+// --- CrashLog ---
+
+// maxCrashReportSize bounds how much of a crash report is read. Reports of
+// launch crashes are far smaller; anything larger is cut off.
+const maxCrashReportSize = 4 << 20
+
+// CrashLog reads the crash reports the simulator writes to
+// ~/Library/Logs/DiagnosticReports and symbolicates them with atos.
+type CrashLog struct{}
+
+// Latest scans the host's diagnostic reports for the newest .ips file written
+// at or after since whose header names bundleID.
+func (r *CrashLog) Latest(ctx context.Context, bundleID string, since time.Time) ([]byte, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(home, "Library", "Logs", "DiagnosticReports")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var newest string
+	var newestTime time.Time
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".ips" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().Before(since) || info.ModTime().Before(newestTime) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if crashReportBundleID(path) != bundleID {
+			continue
+		}
+		newest, newestTime = path, info.ModTime()
+	}
+	if newest == "" {
+		return nil, ctx.Err()
+	}
+
+	f, err := os.Open(newest)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(io.LimitReader(f, maxCrashReportSize))
+}
+
+// crashReportBundleID returns the bundle ID named in the one-line JSON header
+// of an .ips report, or "" when it cannot be read.
+func crashReportBundleID(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	line, _ := bufio.NewReader(io.LimitReader(f, 64*1024)).ReadBytes('\n')
+	var header struct {
+		BundleID string `json:"bundleID"`
+	}
+	if json.Unmarshal(line, &header) != nil {
+		return ""
+	}
+	return header.BundleID
+}
+
+// Symbolicate runs "xcrun atos", which prints one line per address.
+func (r *CrashLog) Symbolicate(ctx context.Context, imagePath string, loadAddr uint64, addrs []uint64) ([]string, error) {
+	args := []string{"atos", "-o", imagePath, "-l", fmt.Sprintf("0x%x", loadAddr)}
+	for _, a := range addrs {
+		args = append(args, fmt.Sprintf("0x%x", a))
+	}
+	out, err := procgroup.Command(ctx, "xcrun", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("atos: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) != len(addrs) {
+		return nil, fmt.Errorf("atos: got %d symbols for %d addresses", len(lines), len(addrs))
+	}
+	return lines, nil
+}
+
 // --- FileCopy ---
 
 // FileCopy executes real file copy commands.
@@ -170,3 +301,19 @@ func (r *SourceList) SwiftDirs(ctx context.Context, root string) ([]string, erro
 	}
 	return dirs, nil
 }
+
+// --- Git ---
+
+// Git executes real git commands.
+type Git struct{}
+
+func (r *Git) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	out, err := procgroup.Command(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return out, fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(ee.Stderr)))
+	}
+	if err != nil {
+		return out, fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
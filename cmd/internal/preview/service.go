@@ -2,7 +2,10 @@ package preview
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"image/png"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -20,6 +23,7 @@ import (
 	"github.com/k-kohey/axe/internal/preview/protocol"
 	"github.com/k-kohey/axe/internal/preview/runner"
 	"github.com/k-kohey/axe/internal/preview/watch"
+	"github.com/k-kohey/axe/internal/termcolor"
 )
 
 // stepper tracks the current step number and total for progress output.
@@ -55,6 +59,8 @@ func Run(opts RunOptions) error {
 	// In serve mode, create an EventWriter to send JSON Lines to stdout.
 	var ew *protocol.EventWriter
 	if opts.Serve {
+		// Serve output is read by the extension, never by a terminal.
+		termcolor.SetMode(termcolor.Never)
 		ew = protocol.NewEventWriter(os.Stdout)
 
 		// Advertise the protocol version to the extension.
@@ -79,7 +85,7 @@ func Run(opts RunOptions) error {
 	// sendStatus sends a StreamStatus event in serve mode (no-op otherwise).
 	sendStatus := func(phase string) {
 		if ew != nil {
-			if err := ew.Send(&pb.Event{StreamId: defaultStreamID, Payload: &pb.Event_StreamStatus{StreamStatus: &pb.StreamStatus{Phase: phase}}}); err != nil {
+			if err := ew.Send(&pb.Event{StreamId: defaultStreamID, Payload: &pb.Event_StreamStatus{StreamStatus: newStreamStatus(phase, opts.Accessibility, opts.Layout)}}); err != nil {
 				slog.Warn("Failed to send StreamStatus", "phase", phase, "err", err)
 			}
 		}
@@ -106,16 +112,25 @@ func Run(opts RunOptions) error {
 		deviceSetPath = opts.DeviceSetPath
 	} else {
 		done = step.begin("Resolving simulator...")
-		device, deviceSetPath, isExternalDevice, err = platform.ResolveAxeSimulator(simctl, opts.PreferredDevice)
+		var sim platform.ResolvedSimulator
+		sim, err = platform.ResolveAxeSimulator(simctl, opts.PreferredDevice, opts.Runtime, opts.Family, opts.DeviceType, opts.PC.PrimaryPath())
 		done()
 		if err != nil {
 			sendStopped("resource_error", err.Error(), "")
 			return err
 		}
+		device, deviceSetPath, isExternalDevice = sim.UDID, sim.DeviceSetPath, sim.IsExternal
+		opts.Accessibility = WithDeviceProfile(opts.Accessibility, device)
+	}
+	if opts.Fresh {
+		if err = eraseFresh(simctl, device, deviceSetPath, isExternalDevice); err != nil {
+			sendStopped("resource_error", err.Error(), "")
+			return err
+		}
 	}
 
 	var dirs previewDirs
-	dirs, err = newPreviewDirs(opts.PC.PrimaryPath(), device)
+	dirs, err = newPreviewDirs(opts.PC, device)
 	if err != nil {
 		sendStopped("resource_error", err.Error(), "")
 		return err
@@ -127,7 +142,7 @@ func Run(opts RunOptions) error {
 	if opts.Preparer != nil {
 		result, err = opts.Preparer.Prepare(ctx)
 	} else {
-		result, err = build.Prepare(ctx, opts.PC, dirs.ProjectDirs, opts.ReuseBuild, br)
+		result, err = build.Prepare(ctx, opts.PC, dirs.ProjectDirs, opts.BuildMode, br)
 	}
 	done()
 	if err != nil {
@@ -135,6 +150,13 @@ func Run(opts RunOptions) error {
 		return err
 	}
 	bs := result.Settings
+	bs.MockSources = opts.MockSources
+	bs.ThunkImports = opts.ThunkImports
+	bs.SeedDir = opts.SeedDir
+	bs.KeepThunk = opts.KeepThunk
+	bs.Layout = opts.Layout
+	bs.InitArgs = opts.InitArgs
+	bs.InitArgsFile = opts.SourceFile
 
 	// Use CompileStrategy to decide between full and main-only thunk compilation.
 	var depGraph *analysis.DependencyGraph
@@ -151,6 +173,9 @@ func Run(opts RunOptions) error {
 				slog.Warn("Index store cache unavailable", "err", cacheErr)
 			}
 			indexCache = newSharedIndexCache(rawCache)
+			if err := checkSourceModule(bs, opts.SourceFile, indexCache.Get()); err != nil {
+				return "", err
+			}
 
 			dg, _, err := analysis.ResolveTransitiveDependencies(ctx, opts.SourceFile, indexCache.Get())
 			if err != nil && ctx.Err() == nil {
@@ -170,7 +195,15 @@ func Run(opts RunOptions) error {
 			}
 			trackedFiles = tf
 
-			thunkPaths, err := codegen.GenerateThunks(files, bs.ModuleName, dirs.Thunk, opts.PreviewSelector, opts.SourceFile, 0)
+			thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{
+				ModuleName:      bs.ModuleName,
+				ExtraModules:    bs.ThunkImports,
+				Layout:          bs.Layout,
+				InitArgs:        bs.InitArgsFor(opts.SourceFile),
+				Dir:             dirs.Thunk,
+				SourceFile:      opts.SourceFile,
+				PreviewSelector: opts.PreviewSelector,
+			})
 			if err != nil {
 				return "", err
 			}
@@ -202,14 +235,17 @@ func Run(opts RunOptions) error {
 		bootCtx, bootCancel := context.WithTimeout(ctx, 30*time.Second)
 		err = simctl.Boot(bootCtx, device)
 		bootCancel()
+		if err == nil {
+			err = platform.WaitForBooted(ctx, simctl, device, "", opts.BootTimeout)
+		}
 		done()
 		if err != nil {
 			sendStopped("boot_error", fmt.Sprintf("booting simulator: %v", err), "")
-			return fmt.Errorf("booting simulator: %w", err)
+			return fmt.Errorf("booting simulator: %w", bootFailed(err))
 		}
 	} else {
 		done = step.begin("Booting simulator...")
-		bootCompanion, err = bootWithRetry(ctx, device, deviceSetPath, !opts.NoHeadless)
+		bootCompanion, err = bootWithRetry(ctx, device, deviceSetPath, !opts.NoHeadless, opts.BootTimeout)
 		done()
 		if err != nil {
 			sendStopped("boot_error", fmt.Sprintf("booting simulator: %v", err), "")
@@ -223,7 +259,7 @@ func Run(opts RunOptions) error {
 		case <-bootCompanion.Done():
 			msg := fmt.Sprintf("simulator crashed immediately after boot: %v", bootCompanion.Err())
 			sendStopped("boot_error", msg, "")
-			return fmt.Errorf("%s", msg)
+			return bootFailed(errors.New(msg))
 		default:
 		}
 	}
@@ -232,6 +268,7 @@ func Run(opts RunOptions) error {
 	var idbClient idb.IDBClient
 	var idbCompanion *idb.Companion
 	var cancelStream func()
+	overrides := platform.NewDeviceOverrides(device, deviceSetPath)
 	defer func() {
 		if cancelStream != nil {
 			cancelStream()
@@ -239,6 +276,7 @@ func Run(opts RunOptions) error {
 		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cleanupCancel()
 		terminateApp(cleanupCtx, bs, device, deviceSetPath, ar)
+		restoreAccessibility(cleanupCtx, overrides)
 		if err := os.Remove(dirs.Socket); err != nil && !os.IsNotExist(err) {
 			slog.Debug("Failed to remove socket", "path", dirs.Socket, "err", err)
 		}
@@ -278,12 +316,58 @@ func Run(opts RunOptions) error {
 
 	sendStatus("running")
 	done = step.begin("Launching app...")
+	applyAccessibility(ctx, overrides, opts.Accessibility)
+	crashes := &runner.CrashLog{}
+	launchedAt := time.Now()
 	err = launchWithHotReload(ctx, bs, loaderPath, dylibPath, dirs.Socket, device, deviceSetPath, ar)
+	var crash *launchCrash
+	if err != nil {
+		if ctx.Err() == nil {
+			crash = detectLaunchCrash(ctx, crashes, bs.BundleID, launchedAt)
+		}
+	} else {
+		var readyErr error
+		crash, readyErr = awaitLoader(ctx, crashes, bs.BundleID, dirs.Socket, launchedAt)
+		if crash == nil && readyErr != nil && ctx.Err() == nil {
+			slog.Warn("Loader did not become ready", "err", readyErr)
+		}
+	}
 	done()
+	if crash != nil {
+		sendStopped("app_crashed", crash.summary(), crash.diagnostic())
+		return crash.err()
+	}
 	if err != nil {
 		sendStopped("runtime_error", err.Error(), "")
 		return err
 	}
+	if opts.Logs {
+		// Cancelled before the deferred cleanup above terminates the app.
+		logCtx, cancelLogs := context.WithCancel(ctx)
+		defer cancelLogs()
+		go followAppLogs(logCtx, &runner.Log{}, device, deviceSetPath, bs, func(l appLogLine) {
+			if ew == nil {
+				fmt.Fprintln(os.Stderr, l)
+				return
+			}
+			if err := ew.Send(&pb.Event{StreamId: defaultStreamID, Payload: &pb.Event_LogStream{LogStream: l.event()}}); err != nil {
+				slog.Warn("Failed to send LogStream", "err", err)
+			}
+		})
+	}
+	if opts.Record != "" {
+		// Deferred after the cleanup above, so the recording is finalized
+		// before the app is terminated.
+		stopRecording, err := recordSession(device, deviceSetPath, opts.Record, opts.RecordDuration)
+		if err != nil {
+			sendStopped("runtime_error", err.Error(), "")
+			return err
+		}
+		defer stopRecording()
+	}
+	if opts.DeviceUDID == "" && !isExternalDevice {
+		platform.RecordLastUsedSimulator(opts.PC.PrimaryPath(), device)
+	}
 
 	// Count previews for StreamStarted.
 	previewCount := 0
@@ -293,7 +377,7 @@ func Run(opts RunOptions) error {
 
 	// Send StreamStarted event in serve mode.
 	if ew != nil {
-		if err := ew.Send(&pb.Event{StreamId: defaultStreamID, Payload: &pb.Event_StreamStarted{StreamStarted: &pb.StreamStarted{PreviewCount: int32(previewCount)}}}); err != nil {
+		if err := ew.Send(&pb.Event{StreamId: defaultStreamID, Payload: &pb.Event_StreamStarted{StreamStarted: &pb.StreamStarted{PreviewCount: int32(previewCount), Codec: string(protocol.CodecJPEG)}}}); err != nil {
 			slog.Warn("Failed to send StreamStarted", "err", err)
 		}
 	}
@@ -302,10 +386,15 @@ func Run(opts RunOptions) error {
 	var idbErrCh chan error
 
 	if opts.Serve {
-		companion, err := idb.Start(device, deviceSetPath)
-		if err != nil {
-			sendStopped("runtime_error", fmt.Sprintf("starting idb_companion: %v", err), "")
-			return fmt.Errorf("starting idb_companion: %w", err)
+		companion, ok := idb.DiscoverRunning(dirs.Session, device)
+		if ok {
+			slog.Info("Reusing running idb_companion", "udid", device, "port", companion.Port())
+		} else {
+			companion, err = idb.StartWithContext(ctx, idb.DefaultCommander(), device, deviceSetPath, idb.Options{StateDir: dirs.Session})
+			if err != nil {
+				sendStopped("runtime_error", fmt.Sprintf("starting idb_companion: %v", err), "")
+				return fmt.Errorf("starting idb_companion: %w", err)
+			}
 		}
 		idbCompanion = companion
 
@@ -378,11 +467,15 @@ func Run(opts RunOptions) error {
 		streamID:      defaultStreamID,
 		serve:         opts.Serve,
 		ew:            ew,
+		accessibility: opts.Accessibility,
+		overrides:     overrides,
+		layout:        opts.Layout,
 		build:         br,
 		toolchain:     tc,
 		app:           ar,
 		copier:        fc,
 		sources:       sl,
+		crashes:       crashes,
 	}
 
 	initialIndex := 0
@@ -440,12 +533,18 @@ func runOneshot(ctx context.Context, opts RunOptions, br BuildRunner, tc Toolcha
 		deviceSetPath = opts.DeviceSetPath
 	} else {
 		done := step.begin("Resolving simulator...")
-		var err error
-		device, deviceSetPath, isExternalDevice, err = platform.ResolveAxeSimulator(simctl, opts.PreferredDevice)
+		sim, err := platform.ResolveAxeSimulator(simctl, opts.PreferredDevice, opts.Runtime, opts.Family, opts.DeviceType, opts.PC.PrimaryPath())
 		done()
 		if err != nil {
 			return err
 		}
+		device, deviceSetPath, isExternalDevice = sim.UDID, sim.DeviceSetPath, sim.IsExternal
+		opts.Accessibility = WithDeviceProfile(opts.Accessibility, device)
+	}
+	if opts.Fresh {
+		if err := eraseFresh(simctl, device, deviceSetPath, isExternalDevice); err != nil {
+			return err
+		}
 	}
 
 	done := step.begin("Preparing session...")
@@ -456,11 +555,20 @@ func runOneshot(ctx context.Context, opts RunOptions, br BuildRunner, tc Toolcha
 		IsExternalDevice: isExternalDevice,
 		NoHeadless:       opts.NoHeadless,
 		Preparer:         opts.Preparer,
-		ReuseBuild:       opts.ReuseBuild,
+		BuildMode:        oneshotBuildMode(opts),
+		Accessibility:    opts.Accessibility,
+		StatusBar:        opts.StatusBar,
+		ThunkImports:     opts.ThunkImports,
+		KeepThunk:        opts.KeepThunk,
+		Layout:           opts.Layout,
+		InitArgs:         opts.InitArgs,
+		InitArgsFile:     opts.SourceFile,
+		BootTimeout:      opts.BootTimeout,
 		BuildRunner:      br,
 		Toolchain:        tc,
 		AppRunner:        ar,
 		Copier:           fc,
+		Crashes:          &runner.CrashLog{},
 	})
 	done()
 	if err != nil {
@@ -468,24 +576,150 @@ func runOneshot(ctx context.Context, opts RunOptions, br BuildRunner, tc Toolcha
 	}
 	defer sess.Close()
 
+	onReady := opts.OnReady
+	if opts.Record != "" {
+		onReady = recordBeforeReady(opts.Record, opts.RecordDuration, onReady)
+	}
 	done = step.begin("Capturing preview...")
 	err = sess.CapturePreview(ctx, CaptureRequest{
 		SourceFile:      opts.SourceFile,
 		PreviewSelector: opts.PreviewSelector,
-		OnReady:         opts.OnReady,
+		OnReady:         onReady,
 	})
 	done()
+	if err == nil && opts.DeviceUDID == "" && !isExternalDevice {
+		platform.RecordLastUsedSimulator(opts.PC.PrimaryPath(), device)
+	}
+	if err == nil && opts.FailOnWarning {
+		// The preview has been captured; warnings only change the outcome.
+		return build.CheckWarnings(os.Stderr, sess.Warnings())
+	}
 	return err
 }
 
+// oneshotBuildMode returns the build mode of a oneshot preview. xcodebuild
+// only prints the warnings of the files it compiles, so --fail-on-warning
+// forces a clean build; otherwise an up-to-date DerivedData would let a
+// target with warnings pass.
+func oneshotBuildMode(opts RunOptions) build.Mode {
+	if opts.FailOnWarning && opts.BuildMode != build.Clean {
+		slog.Info("Forcing a clean build so that every warning is reported", "flag", "--fail-on-warning")
+		return build.Clean
+	}
+	return opts.BuildMode
+}
+
+// ServeOptions holds all parameters for a multi-stream RunServe invocation.
+type ServeOptions struct {
+	PC            ProjectConfig
+	Strict        bool
+	MaxThunkFiles int // max tracked files for incremental thunk (0 = unlimited)
+	PreThunkDepth int // initial thunk generation depth (0 = target only, 1 = direct deps)
+
+	// CleanBuild makes the first stream run a clean build instead of
+	// reusing the artifacts of a previous build.
+	CleanBuild bool
+
+	// Accessibility overrides applied to every stream's simulator.
+	Accessibility platform.AccessibilityOverrides
+
+	// StatusBar, when non-nil, overrides every stream's status bar before
+	// launch so that frames are reproducible. Cleared when the stream stops.
+	StatusBar *platform.StatusBarOverride
+
+	// BootTimeout bounds the wait for every stream's simulator to reach
+	// "Booted" (0 = idb.DefaultBootTimeout).
+	BootTimeout time.Duration
+
+	// MockSources are preview-only Swift files compiled into every thunk.
+	MockSources []string
+
+	// ThunkImports are extra modules imported by every thunk.
+	ThunkImports []string
+
+	// SeedDir is copied into every stream's app data container before launch.
+	SeedDir string
+
+	// KeepThunk keeps every stream's thunk sources for inspection.
+	KeepThunk bool
+
+	// Layout is the iPad layout every stream renders with.
+	Layout codegen.Layout
+
+	// Once processes a single AddStream, waits for its first frame, tears
+	// everything down, and returns. Used for one-shot machine-readable runs.
+	Once bool
+
+	// PreviewAll expands each AddStream into one stream per #Preview block
+	// in the file, with streamIds "<streamId>#<index>" and labeled frames.
+	PreviewAll bool
+
+	// RejectDuplicateStreams ignores an AddStream whose streamId is already
+	// active instead of updating that stream in place.
+	RejectDuplicateStreams bool
+
+	// FrameEncoding selects how Frame events carry images (default base64).
+	FrameEncoding protocol.FrameEncoding
+
+	// FrameDiff sets Frame.dirty on JPEG and PNG frames by diffing consecutive
+	// frames. Off by default because comparing every frame costs CPU.
+	FrameDiff bool
+
+	// PNGCompression is the compression level of streams using the PNG
+	// codec (default png.DefaultCompression).
+	PNGCompression png.CompressionLevel
+
+	// Logs streams each stream's app log as LogStream events.
+	Logs bool
+
+	// IdleTimeout, when positive, shuts down pooled simulators and the file
+	// watcher after this long without active streams. A later AddStream
+	// starts them again.
+	IdleTimeout time.Duration
+
+	// IdleExit makes serve exit after an idle shutdown.
+	IdleExit bool
+
+	// WarmPool is the number of simulators kept booted for the device type
+	// and runtime of the last AddStream, so that the next stream of that
+	// kind skips the boot (0 = boot on demand). Ignored with Once.
+	WarmPool int
+
+	// EventsFIFO, if set, is a named pipe that events are written to instead
+	// of stdout. It is created if absent and removed on exit if so.
+	EventsFIFO string
+
+	// ReconcileInterval, when positive, re-lists the device set this often
+	// and stops the streams whose simulator was deleted outside axe.
+	ReconcileInterval time.Duration
+}
+
 // RunServe is the multi-stream entry point for serve mode.
 // It reads AddStream/RemoveStream commands from stdin and manages
 // multiple preview streams concurrently via StreamManager.
-func RunServe(pc ProjectConfig, strict bool, maxThunkFiles, preThunkDepth int) error {
+func RunServe(opts ServeOptions) error {
+	pc := opts.PC
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer stop()
 
-	ew := protocol.NewEventWriter(os.Stdout)
+	// Serve output is read by the extension, never by a terminal.
+	termcolor.SetMode(termcolor.Never)
+	var out io.Writer = os.Stdout
+	if opts.EventsFIFO != "" {
+		slog.Info("Waiting for a reader on the events FIFO", "path", opts.EventsFIFO)
+		fifo, err := protocol.OpenFIFO(ctx, opts.EventsFIFO)
+		if err != nil {
+			return fmt.Errorf("opening events FIFO: %w", err)
+		}
+		defer func() {
+			if err := fifo.Close(); err != nil {
+				slog.Warn("Failed to close events FIFO", "path", opts.EventsFIFO, "err", err)
+			}
+		}()
+		out = fifo
+	}
+	ew := protocol.NewEventWriter(out)
 
 	// Advertise the protocol version to the extension.
 	if err := ew.Send(&pb.Event{
@@ -496,12 +730,9 @@ func RunServe(pc ProjectConfig, strict bool, maxThunkFiles, preThunkDepth int) e
 		return fmt.Errorf("sending hello: %w", err)
 	}
 
-	deviceSetPath, err := platform.AxeDeviceSetPath()
+	deviceSetPath, err := platform.PrepareAxeDeviceSet()
 	if err != nil {
-		return fmt.Errorf("resolving device set path: %w", err)
-	}
-	if err := os.MkdirAll(deviceSetPath, 0o755); err != nil {
-		return fmt.Errorf("creating device set directory: %w", err)
+		return fmt.Errorf("preparing device set: %w", err)
 	}
 
 	pool := platform.NewDevicePool(&platform.RealSimctlRunner{}, deviceSetPath)
@@ -512,30 +743,72 @@ func RunServe(pc ProjectConfig, strict bool, maxThunkFiles, preThunkDepth int) e
 
 	br, tc, ar, fc, sl := defaultRunners()
 
-	projDirs, err := build.NewProjectDirs(pc.PrimaryPath())
+	projDirs, err := build.NewProjectDirs(pc)
 	if err != nil {
 		return fmt.Errorf("resolving build directories: %w", err)
 	}
-	preparer := build.NewPreparer(pc, projDirs, true, br)
-
-	sm := NewStreamManager(pool, ew, pc, deviceSetPath, preparer, br, tc, ar, fc, sl, strict, maxThunkFiles, preThunkDepth)
-
-	// Start shared file watcher for all streams.
-	watcher, err := watch.NewSharedWatcher(ctx, filepath.Dir(pc.PrimaryPath()), sl)
+	mode := build.Reuse
+	if opts.CleanBuild {
+		mode = build.Clean
+	}
+	preparer := build.NewPreparer(pc, projDirs, mode, br)
+
+	sm := NewStreamManager(pool, ew, pc, deviceSetPath, preparer, br, tc, ar, fc, sl, opts.Strict, opts.MaxThunkFiles, opts.PreThunkDepth)
+	sm.buildMode = mode
+	sm.accessibility = opts.Accessibility
+	sm.statusBar = opts.StatusBar
+	sm.bootTimeout = opts.BootTimeout
+	if opts.WarmPool > 0 && !opts.Once {
+		sm.warm = newWarmPool(opts.WarmPool, pool, deviceSetPath, opts.BootTimeout)
+	}
+	sm.mockSources = opts.MockSources
+	sm.thunkImports = opts.ThunkImports
+	sm.seedDir = opts.SeedDir
+	sm.keepThunk = opts.KeepThunk
+	sm.layout = opts.Layout
+	sm.previewAll = opts.PreviewAll
+	sm.rejectDuplicates = opts.RejectDuplicateStreams
+	sm.frameEncoding = opts.FrameEncoding
+	sm.frameDiff = opts.FrameDiff
+	sm.pngCompression = opts.PNGCompression
+	if opts.Logs {
+		sm.logs = &runner.Log{}
+	}
+	sm.crashes = &runner.CrashLog{}
+
+	// Start shared file watcher for all streams. The stream manager restarts
+	// it via newWatcher when an AddStream switches to another project.
+	sm.newWatcher = func(root string) (*watch.SharedWatcher, error) {
+		return watch.NewSharedWatcher(ctx, root, sl)
+	}
+	watcher, err := sm.newWatcher(filepath.Dir(pc.PrimaryPath()))
 	if err != nil {
 		return fmt.Errorf("creating shared file watcher: %w", err)
 	}
 	sm.watcher = watcher
-	defer watcher.Close()
-
-	// Read commands from stdin. When stdin closes (extension crash/exit),
-	// the loop returns and we proceed to cleanup.
-	runCommandLoop(ctx, os.Stdin, ew, sm)
+	defer sm.closeWatcher()
+
+	// Read commands from stdin. When stdin closes (extension crash/exit) or
+	// a Shutdown command arrives, the loop returns and we proceed to cleanup.
+	// In --once mode the loop returns as soon as the first stream has
+	// emitted a frame.
+	sm.idleTimeout = opts.IdleTimeout
+	idleExit := make(chan struct{}, 1)
+	if opts.IdleExit {
+		sm.onIdle = func() {
+			select {
+			case idleExit <- struct{}{}:
+			default:
+			}
+		}
+	}
+	sm.startIdleTimer()
 
-	sm.StopAll()
-	pool.GarbageCollect(ctx)
+	if opts.ReconcileInterval > 0 {
+		go sm.runReconciler(ctx, opts.ReconcileInterval)
+	}
 
-	return nil
+	return serveCommands(ctx, os.Stdin, ew, sm, opts.Once, idleExit)
 }
 
 // RunBuild executes only the xcodebuild build phase.
@@ -546,7 +819,7 @@ func RunBuild(pc ProjectConfig) error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer stop()
 
-	dirs, err := build.NewProjectDirs(pc.PrimaryPath())
+	dirs, err := build.NewProjectDirs(pc)
 	if err != nil {
 		return fmt.Errorf("resolving build directories: %w", err)
 	}
@@ -556,9 +829,9 @@ func RunBuild(pc ProjectConfig) error {
 
 	step := &stepper{total: 1}
 	done := step.begin("Building...")
-	// Always build (reuse=false): the purpose of this command is to populate
-	// the build cache, so skipping the build would defeat its intent.
-	result, err := build.Prepare(ctx, pc, dirs, false, br)
+	// Always build (never build.Reuse): the purpose of this command is to
+	// populate the build cache, so skipping the build would defeat its intent.
+	result, err := build.Prepare(ctx, pc, dirs, build.Incremental, br)
 	done()
 	if err != nil {
 		return err
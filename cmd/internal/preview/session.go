@@ -23,17 +23,38 @@ type SessionConfig struct {
 	IsExternalDevice bool
 	NoHeadless       bool
 	Preparer         *build.Preparer
-	ReuseBuild       bool
+	BuildMode        build.Mode
+	Accessibility    platform.AccessibilityOverrides
+	MockSources      []string       // preview-only Swift files compiled into every thunk
+	ThunkImports     []string       // extra modules imported by every thunk
+	SeedDir          string         // copied into the app data container after install
+	KeepThunk        bool           // keep thunk sources and log the swiftc command
+	Layout           codegen.Layout // iPad layout and size class rendered by the thunk
+	InitArgs         string         // view expression previewed instead of the #Preview body of InitArgsFile
+	InitArgsFile     string         // source file InitArgs was given for
+	BootTimeout      time.Duration  // wait for the device to reach Booted (0 = default)
+
+	// StatusBar, when non-nil, overrides the simulator status bar for the
+	// lifetime of the session. The override is cleared on Close.
+	StatusBar *platform.StatusBarOverride
 
 	BuildRunner BuildRunner
 	Toolchain   ToolchainRunner
 	AppRunner   AppRunner
 	Copier      FileCopier
 
+	// Crashes, when set, explains a launch that never reaches the loader
+	// with the app's crash report (see ErrAppCrashed).
+	Crashes CrashReporter
+
 	// BootFunc overrides the default boot function for testing.
 	// When nil, bootWithRetry is used for axe-managed devices,
 	// or simctl.Boot for external devices.
 	BootFunc func(ctx context.Context, udid, setPath string, headless bool) (companionProcess, error)
+
+	// timer, when set, records the time spent in each pipeline phase
+	// (used by RunBench).
+	timer *phaseTimer
 }
 
 // CaptureRequest describes a single preview capture within an existing session.
@@ -55,6 +76,8 @@ type PreviewSession struct {
 	bs            *build.Settings
 	bootCompanion companionProcess // nil for external devices
 	loaderPath    string
+	warnings      []build.Diagnostic        // compiler warnings of the session's build
+	overrides     *platform.DeviceOverrides // applies cfg.Accessibility; restored by Close
 
 	// Hot-reload state (mutable, not goroutine-safe).
 	reloadCounter int  // incremented after each successful reload/launch
@@ -64,7 +87,7 @@ type PreviewSession struct {
 // NewPreviewSession creates a PreviewSession by running Build and Boot in parallel,
 // then installing the app and compiling the loader.
 func NewPreviewSession(ctx context.Context, cfg SessionConfig) (*PreviewSession, error) {
-	dirs, err := newPreviewDirs(cfg.PC.PrimaryPath(), cfg.DeviceUDID)
+	dirs, err := newPreviewDirs(cfg.PC, cfg.DeviceUDID)
 	if err != nil {
 		return nil, fmt.Errorf("preview dirs: %w", err)
 	}
@@ -73,29 +96,43 @@ func NewPreviewSession(ctx context.Context, cfg SessionConfig) (*PreviewSession,
 	g, gctx := errgroup.WithContext(ctx)
 
 	var bs *build.Settings
+	var warnings []build.Diagnostic
 	g.Go(func() error {
+		defer cfg.timer.start(PhaseBuild)()
 		var result *build.Result
 		var bErr error
 		if cfg.Preparer != nil {
 			result, bErr = cfg.Preparer.Prepare(gctx)
 		} else {
-			result, bErr = build.Prepare(gctx, cfg.PC, dirs.ProjectDirs, cfg.ReuseBuild, cfg.BuildRunner)
+			result, bErr = build.Prepare(gctx, cfg.PC, dirs.ProjectDirs, cfg.BuildMode, cfg.BuildRunner)
 		}
 		if bErr != nil {
 			return fmt.Errorf("build: %w", bErr)
 		}
 		bs = result.Settings
+		warnings = result.Warnings
+		bs.MockSources = cfg.MockSources
+		bs.ThunkImports = cfg.ThunkImports
+		bs.SeedDir = cfg.SeedDir
+		bs.KeepThunk = cfg.KeepThunk
+		bs.Layout = cfg.Layout
+		bs.InitArgs = cfg.InitArgs
+		bs.InitArgsFile = cfg.InitArgsFile
 		return nil
 	})
 
 	var bootComp companionProcess
 	g.Go(func() error {
+		defer cfg.timer.start(PhaseBoot)()
 		if cfg.IsExternalDevice {
 			simctl := &platform.RealSimctlRunner{}
 			bootCtx, bootCancel := context.WithTimeout(gctx, 30*time.Second)
 			defer bootCancel()
 			if bErr := simctl.Boot(bootCtx, cfg.DeviceUDID); bErr != nil {
-				return fmt.Errorf("booting simulator (external): %w", bErr)
+				return fmt.Errorf("booting simulator (external): %w", bootFailed(bErr))
+			}
+			if wErr := platform.WaitForBooted(gctx, simctl, cfg.DeviceUDID, "", cfg.BootTimeout); wErr != nil {
+				return fmt.Errorf("booting simulator (external): %w", bootFailed(wErr))
 			}
 			return nil
 		}
@@ -103,11 +140,11 @@ func NewPreviewSession(ctx context.Context, cfg SessionConfig) (*PreviewSession,
 			var bErr error
 			bootComp, bErr = cfg.BootFunc(gctx, cfg.DeviceUDID, cfg.DeviceSetPath, !cfg.NoHeadless)
 			if bErr != nil {
-				return fmt.Errorf("booting simulator: %w", bErr)
+				return fmt.Errorf("booting simulator: %w", bootFailed(bErr))
 			}
 			return nil
 		}
-		comp, bErr := bootWithRetry(gctx, cfg.DeviceUDID, cfg.DeviceSetPath, !cfg.NoHeadless)
+		comp, bErr := bootWithRetry(gctx, cfg.DeviceUDID, cfg.DeviceSetPath, !cfg.NoHeadless, cfg.BootTimeout)
 		if bErr != nil {
 			return fmt.Errorf("booting simulator: %w", bErr)
 		}
@@ -129,12 +166,19 @@ func NewPreviewSession(ctx context.Context, cfg SessionConfig) (*PreviewSession,
 	if bootComp != nil {
 		select {
 		case <-bootComp.Done():
-			return nil, fmt.Errorf("simulator crashed immediately after boot: %w", bootComp.Err())
+			return nil, bootFailed(fmt.Errorf("simulator crashed immediately after boot: %w", bootComp.Err()))
 		default:
 		}
 	}
 
+	if cfg.StatusBar != nil {
+		if err := platform.OverrideStatusBar(ctx, cfg.DeviceUDID, cfg.DeviceSetPath, *cfg.StatusBar); err != nil {
+			slog.Warn("Failed to override status bar", "err", err)
+		}
+	}
+
 	// Sequential: Install + Loader (requires both Build result and Boot completion)
+	defer cfg.timer.start(PhaseInject)()
 	terminateApp(ctx, bs, cfg.DeviceUDID, cfg.DeviceSetPath, cfg.AppRunner)
 
 	if _, err := installApp(ctx, bs, dirs, cfg.DeviceUDID, cfg.DeviceSetPath, cfg.AppRunner, cfg.Copier); err != nil {
@@ -162,15 +206,25 @@ func NewPreviewSession(ctx context.Context, cfg SessionConfig) (*PreviewSession,
 		bs:            bs,
 		bootCompanion: bootComp,
 		loaderPath:    loaderPath,
+		warnings:      warnings,
+		overrides:     platform.NewDeviceOverrides(cfg.DeviceUDID, cfg.DeviceSetPath),
 	}, nil
 }
 
+// Warnings returns the compiler warnings reported by the session's build.
+// A reused build reports none.
+func (s *PreviewSession) Warnings() []build.Diagnostic {
+	return s.warnings
+}
+
 // CapturePreview compiles a main-only thunk for the given source file and
 // delivers it to the running app. On the first call, a cold start (terminate →
 // launch → WaitForReady) is performed. Subsequent calls use hot-reload via
 // SendReloadCommand, falling back to cold start on failure.
 func (s *PreviewSession) CapturePreview(ctx context.Context, req CaptureRequest) error {
+	injected := s.cfg.timer.start(PhaseInject)
 	counter := s.reloadCounter
+	applyAccessibility(ctx, s.overrides, s.cfg.Accessibility)
 	dylibPath, err := compileMainOnlyPipeline(ctx, req.SourceFile, s.bs, s.dirs, req.PreviewSelector, counter, s.cfg.Toolchain)
 	if err != nil {
 		return fmt.Errorf("compile thunk: %w", err)
@@ -195,9 +249,11 @@ func (s *PreviewSession) CapturePreview(ctx context.Context, req CaptureRequest)
 	// Increment counter AFTER successful reload/launch, before OnReady.
 	// This ensures dlopen sees a unique path on retry (avoids cache hit).
 	s.reloadCounter++
-	cleanOldDylibs(s.dirs.Thunk, counter-1)
+	cleanOldDylibs(s.dirs.Thunk, counter-1, s.bs.KeepThunk)
+	injected()
 
 	if req.OnReady != nil {
+		defer s.cfg.timer.start(PhaseFirstFrame)()
 		if err := req.OnReady(ctx, s.cfg.DeviceUDID, s.cfg.DeviceSetPath); err != nil {
 			return fmt.Errorf("on-ready: %w", err)
 		}
@@ -211,11 +267,14 @@ func (s *PreviewSession) CapturePreview(ctx context.Context, req CaptureRequest)
 func (s *PreviewSession) coldStart(ctx context.Context, dylibPath string) error {
 	terminateApp(ctx, s.bs, s.cfg.DeviceUDID, s.cfg.DeviceSetPath, s.cfg.AppRunner)
 
+	launchedAt := time.Now()
 	if err := launchWithHotReload(ctx, s.bs, s.loaderPath, dylibPath, s.dirs.Socket, s.cfg.DeviceUDID, s.cfg.DeviceSetPath, s.cfg.AppRunner); err != nil {
-		return fmt.Errorf("launch: %w", err)
+		return fmt.Errorf("launch: %w", explainLaunchFailure(ctx, s.cfg.Crashes, s.bs.BundleID, launchedAt, err))
 	}
 
-	if err := codegen.WaitForReady(ctx, s.dirs.Socket); err != nil {
+	if c, err := awaitLoader(ctx, s.cfg.Crashes, s.bs.BundleID, s.dirs.Socket, launchedAt); c != nil {
+		return fmt.Errorf("wait for ready: %w", c.err())
+	} else if err != nil {
 		return fmt.Errorf("wait for ready: %w", err)
 	}
 
@@ -230,6 +289,15 @@ func (s *PreviewSession) Close() {
 
 	terminateApp(cleanupCtx, s.bs, s.cfg.DeviceUDID, s.cfg.DeviceSetPath, s.cfg.AppRunner)
 
+	// Restore the accessibility settings and clear the status bar override
+	// so external (standard set) devices are left as the user had them.
+	restoreAccessibility(cleanupCtx, s.overrides)
+	if s.cfg.StatusBar != nil {
+		if err := platform.ClearStatusBar(cleanupCtx, s.cfg.DeviceUDID, s.cfg.DeviceSetPath); err != nil {
+			slog.Debug("Failed to clear status bar override", "err", err)
+		}
+	}
+
 	if err := os.Remove(s.dirs.Socket); err != nil && !os.IsNotExist(err) {
 		slog.Debug("Failed to remove socket", "path", s.dirs.Socket, "err", err)
 	}
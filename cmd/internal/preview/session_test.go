@@ -3,10 +3,13 @@ package preview
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync/atomic"
 	"testing"
 
@@ -101,6 +104,7 @@ func setupSessionTest(t *testing.T) SessionConfig {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -108,7 +112,7 @@ func setupSessionTest(t *testing.T) SessionConfig {
 	// Create a Preparer with cached result so it returns immediately.
 	dirs := build.ProjectDirs{Root: tmpDir, Build: buildDir}
 	br := &fakeBuildRunner{}
-	preparer := build.NewPreparer(pc, dirs, true, br)
+	preparer := build.NewPreparer(pc, dirs, build.Reuse, br)
 
 	// Pre-populate the cache by calling Prepare once with the right setup.
 	// We need a way to seed the preparer's cache. Since Preparer calls
@@ -163,27 +167,20 @@ func sessionPreparer(t *testing.T, pc build.ProjectConfig, buildDir string, bs *
 
 	// build.Prepare calls FetchBuildSettings then Build. We need FetchBuildSettings
 	// to return parseable output. Build can no-op if HasPreviousBuild returns true.
-	fetchOutput := fmt.Sprintf(
-		"BUILD_DIR = %s\n"+
-			"PRODUCT_MODULE_NAME = %s\n"+
-			"PRODUCT_BUNDLE_IDENTIFIER = %s\n"+
-			"BUILT_PRODUCTS_DIR = %s\n"+
-			"IPHONEOS_DEPLOYMENT_TARGET = %s\n"+
-			"SWIFT_VERSION = %s\n",
-		filepath.Join(buildDir, "Build", "Products"),
-		bs.ModuleName,
-		bs.BundleID,
-		bs.BuiltProductsDir,
-		bs.DeploymentTarget,
-		bs.SwiftVersion,
-	)
+	fetchOutput := showBuildSettingsOutput(build.Settings{
+		ModuleName:       bs.ModuleName,
+		OriginalBundleID: bs.BundleID,
+		BuiltProductsDir: bs.BuiltProductsDir,
+		DeploymentTarget: bs.DeploymentTarget,
+		SwiftVersion:     bs.SwiftVersion,
+	})
 
 	br := &fakeBuildRunner{
-		fetchOutput: []byte(fetchOutput),
+		fetchOutput: fetchOutput,
 	}
 
 	dirs := build.ProjectDirs{Root: filepath.Dir(buildDir), Build: buildDir}
-	return build.NewPreparer(pc, dirs, true, br)
+	return build.NewPreparer(pc, dirs, build.Reuse, br)
 }
 
 func TestNewPreviewSession_Success(t *testing.T) {
@@ -211,6 +208,7 @@ func TestNewPreviewSession_Success(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -250,7 +248,7 @@ func TestNewPreviewSession_BuildFailure(t *testing.T) {
 		fetchErr: fmt.Errorf("xcodebuild failed"),
 	}
 	dirs := build.ProjectDirs{Root: tmpDir, Build: buildDir}
-	cfg.Preparer = build.NewPreparer(cfg.PC, dirs, false, br)
+	cfg.Preparer = build.NewPreparer(cfg.PC, dirs, build.Incremental, br)
 
 	_, err := NewPreviewSession(t.Context(), cfg)
 	if err == nil {
@@ -282,6 +280,7 @@ func TestNewPreviewSession_BootFailure(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -318,6 +317,7 @@ func TestCapturePreview_SingleCapture(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -390,6 +390,7 @@ func TestCapturePreview_MultipleCaptures_UsesHotReload(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -494,6 +495,7 @@ func TestCapturePreview_HotReloadFailure_FallsBackToColdStart(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -599,6 +601,7 @@ func TestCapturePreview_ContextCanceled_NoFallback(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -693,6 +696,7 @@ func TestClose_StopsCompanion(t *testing.T) {
 		ModuleName:       "TestModule",
 		BundleID:         "axe.com.example.TestModule",
 		BuiltProductsDir: builtProducts,
+		ProductName:      "TestModule.app",
 		DeploymentTarget: "17.0",
 		SwiftVersion:     "5.9",
 	}
@@ -795,3 +799,50 @@ func startFakeSocket(t *testing.T, socketPath string, onReload ...func(string))
 		}
 	}()
 }
+
+// cachedBuildRunner is a build runner for a project whose DerivedData is
+// up to date: an incremental build compiles nothing and prints no
+// warnings, and only a clean build shows the warning in the target.
+type cachedBuildRunner struct {
+	fakeBuildRunner
+}
+
+func (r *cachedBuildRunner) Build(ctx context.Context, args []string) ([]byte, error) {
+	r.buildArgs = args
+	if slices.Contains(args, "clean") {
+		return []byte("/src/FeedView.swift:12:9: warning: initialization of immutable value 'x' was never used\n** BUILD SUCCEEDED **\n"), nil
+	}
+	return []byte("** BUILD SUCCEEDED **\n"), nil
+}
+
+// TestOneshotBuildMode_FailOnWarningWithCachedBuild verifies that
+// --fail-on-warning reports a warning in an already built target, which an
+// incremental build would not print again.
+func TestOneshotBuildMode_FailOnWarningWithCachedBuild(t *testing.T) {
+	pc := ProjectConfig{Project: "/tmp/App.xcodeproj", Scheme: "App"}
+	dirs := build.ProjectDirs{Build: t.TempDir()}
+	br := &cachedBuildRunner{fakeBuildRunner{fetchOutput: showBuildSettingsOutput(build.Settings{
+		ModuleName:       "App",
+		OriginalBundleID: "com.example.App",
+		DeploymentTarget: "17.0",
+		BuiltProductsDir: filepath.Join(dirs.Build, "Build", "Products", "Debug-iphonesimulator"),
+	})}}
+
+	for _, mode := range []build.Mode{build.Incremental, build.Clean} {
+		opts := RunOptions{PC: pc, BuildMode: mode, FailOnWarning: true}
+		res, err := build.Prepare(t.Context(), pc, dirs, oneshotBuildMode(opts), br)
+		if err != nil {
+			t.Fatalf("mode %d: Prepare: %v", mode, err)
+		}
+		if !slices.Contains(br.buildArgs, "clean") {
+			t.Errorf("mode %d: build args %v, want a clean build", mode, br.buildArgs)
+		}
+		if err := build.CheckWarnings(io.Discard, res.Warnings); !errors.Is(err, build.ErrBuildWarnings) {
+			t.Errorf("mode %d: CheckWarnings = %v, want ErrBuildWarnings", mode, err)
+		}
+	}
+
+	if got := oneshotBuildMode(RunOptions{BuildMode: build.Incremental}); got != build.Incremental {
+		t.Errorf("without --fail-on-warning: mode = %d, want Incremental", got)
+	}
+}
@@ -0,0 +1,182 @@
+package preview
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/k-kohey/axe/internal/platform"
+)
+
+// SidecarExt is the extension of a preview sidecar file. The sidecar of
+// ProfileView.swift is ProfileView.axpreview in the same directory.
+const SidecarExt = ".axpreview"
+
+// Sidecar holds per-view preview settings read from a sidecar file, so
+// that mock data and device preferences can live next to a view without
+// being compiled into the app.
+//
+// The format matches .axerc: KEY=VALUE, one per line, '#' comments.
+// IMPORT and MOCK may be repeated; MOCK paths are relative to the sidecar.
+//
+//	DEVICE=8A1C2F3E-5B6D-4E7F-9A0B-1C2D3E4F5A6B
+//	APPEARANCE=dark
+//	LOCALE=ja_JP
+//	IMPORT=DesignSystem
+//	MOCK=Mocks/ProfileMock.swift
+type Sidecar struct {
+	Path       string   // absolute path of the sidecar file
+	Device     string   // preferred simulator UDID, as --device
+	Appearance string   // as --appearance
+	Locale     string   // as --locale
+	Imports    []string // extra thunk imports, as --thunk-import
+	Mocks      []string // absolute paths of mock sources, as --mock
+}
+
+// sidecarModuleName matches a Swift module name, optionally with submodules.
+var sidecarModuleName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// SidecarPath returns the sidecar path for sourceFile.
+func SidecarPath(sourceFile string) string {
+	return strings.TrimSuffix(sourceFile, filepath.Ext(sourceFile)) + SidecarExt
+}
+
+// LoadSidecar reads the sidecar of sourceFile. It returns nil without an
+// error when the view has no sidecar.
+func LoadSidecar(sourceFile string) (*Sidecar, error) {
+	path := SidecarPath(sourceFile)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening sidecar: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	sc, err := parseSidecar(f, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	sc.Path = path
+	return sc, nil
+}
+
+// parseSidecar parses sidecar content, resolving MOCK paths against dir.
+func parseSidecar(r io.Reader, dir string) (*Sidecar, error) {
+	sc := &Sidecar{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE, got %q", n, line)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch k {
+		case "DEVICE":
+			sc.Device = v
+		case "APPEARANCE":
+			sc.Appearance = v
+		case "LOCALE":
+			sc.Locale = v
+		case "IMPORT":
+			if !sidecarModuleName.MatchString(v) {
+				return nil, fmt.Errorf("line %d: IMPORT %q is not a module name", n, v)
+			}
+			sc.Imports = append(sc.Imports, v)
+		case "MOCK":
+			if filepath.Ext(v) != ".swift" {
+				return nil, fmt.Errorf("line %d: MOCK %s is not a .swift file", n, v)
+			}
+			p := v
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+			if _, err := os.Stat(p); err != nil {
+				return nil, fmt.Errorf("line %d: MOCK source file not found: %s", n, p)
+			}
+			sc.Mocks = append(sc.Mocks, p)
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q (valid: DEVICE, APPEARANCE, LOCALE, IMPORT, MOCK)", n, k)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	a := platform.AccessibilityOverrides{Appearance: sc.Appearance, Locale: sc.Locale}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// Apply merges the sidecar into opts. Command-line flags win: the sidecar
+// only fills the appearance and locale when they are unset, and the device
+// unless deviceFlag reports that --device was given (a DEVICE from .axerc
+// is less specific than the sidecar and is replaced). Imports and mocks
+// are added to those from the flags.
+func (s *Sidecar) Apply(opts *RunOptions, deviceFlag bool) {
+	if s == nil {
+		return
+	}
+	if s.Device != "" && !deviceFlag {
+		opts.PreferredDevice = s.Device
+	}
+	if opts.Accessibility.Appearance == "" {
+		opts.Accessibility.Appearance = s.Appearance
+	}
+	if opts.Accessibility.Locale == "" {
+		opts.Accessibility.Locale = s.Locale
+	}
+	for _, m := range s.Imports {
+		if !slices.Contains(opts.ThunkImports, m) {
+			opts.ThunkImports = append(opts.ThunkImports, m)
+		}
+	}
+	for _, m := range s.Mocks {
+		if !slices.Contains(opts.MockSources, m) {
+			opts.MockSources = append(opts.MockSources, m)
+		}
+	}
+}
+
+// streamOverrides returns a, the overrides of a serve stream, with the
+// sidecar's appearance and locale filling in what neither AddStream nor
+// serve's flags set.
+func (s *Sidecar) streamOverrides(a platform.AccessibilityOverrides) platform.AccessibilityOverrides {
+	if s == nil {
+		return a
+	}
+	return a.Or(platform.AccessibilityOverrides{Appearance: s.Appearance, Locale: s.Locale})
+}
+
+// withThunkSources returns imports and mocks with the sidecar's added,
+// leaving the given slices untouched since serve shares them across
+// streams.
+func (s *Sidecar) withThunkSources(imports, mocks []string) ([]string, []string) {
+	if s == nil {
+		return imports, mocks
+	}
+	imports, mocks = slices.Clone(imports), slices.Clone(mocks)
+	for _, m := range s.Imports {
+		if !slices.Contains(imports, m) {
+			imports = append(imports, m)
+		}
+	}
+	for _, m := range s.Mocks {
+		if !slices.Contains(mocks, m) {
+			mocks = append(mocks, m)
+		}
+	}
+	return imports, mocks
+}
@@ -0,0 +1,151 @@
+package preview
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/k-kohey/axe/internal/platform"
+)
+
+func TestLoadSidecar(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "FeedView.swift")
+	mock := filepath.Join(dir, "Mocks", "FeedMocks.swift")
+	if err := os.MkdirAll(filepath.Dir(mock), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mock, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	content := `# Feed preview settings
+DEVICE=8A1C2F3E-5B6D-4E7F-9A0B-1C2D3E4F5A6B
+APPEARANCE=dark
+LOCALE = ja_JP
+IMPORT=DSKit
+IMPORT=Networking
+MOCK=Mocks/FeedMocks.swift
+`
+	if err := os.WriteFile(filepath.Join(dir, "FeedView.axpreview"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := LoadSidecar(source)
+	if err != nil {
+		t.Fatalf("LoadSidecar: %v", err)
+	}
+	if sc == nil {
+		t.Fatal("LoadSidecar returned nil sidecar")
+	}
+	want := Sidecar{
+		Path:       filepath.Join(dir, "FeedView.axpreview"),
+		Device:     "8A1C2F3E-5B6D-4E7F-9A0B-1C2D3E4F5A6B",
+		Appearance: "dark",
+		Locale:     "ja_JP",
+		Imports:    []string{"DSKit", "Networking"},
+		Mocks:      []string{mock},
+	}
+	if sc.Path != want.Path || sc.Device != want.Device || sc.Appearance != want.Appearance || sc.Locale != want.Locale ||
+		!slices.Equal(sc.Imports, want.Imports) || !slices.Equal(sc.Mocks, want.Mocks) {
+		t.Errorf("LoadSidecar = %+v, want %+v", *sc, want)
+	}
+
+	t.Run("resolves into run options", func(t *testing.T) {
+		opts := RunOptions{
+			SourceFile:      source,
+			PreferredDevice: "RC-DEVICE",
+			Accessibility:   platform.AccessibilityOverrides{Locale: "en_GB"},
+			ThunkImports:    []string{"DSKit"},
+			MockSources:     []string{"/flag/Mock.swift"},
+		}
+		sc.Apply(&opts, false)
+
+		if opts.PreferredDevice != want.Device {
+			t.Errorf("PreferredDevice = %q, want %q", opts.PreferredDevice, want.Device)
+		}
+		if opts.Accessibility.Appearance != "dark" {
+			t.Errorf("Appearance = %q, want dark", opts.Accessibility.Appearance)
+		}
+		if opts.Accessibility.Locale != "en_GB" {
+			t.Errorf("Locale = %q, want the flag's en_GB", opts.Accessibility.Locale)
+		}
+		if want := []string{"DSKit", "Networking"}; !slices.Equal(opts.ThunkImports, want) {
+			t.Errorf("ThunkImports = %v, want %v", opts.ThunkImports, want)
+		}
+		if want := []string{"/flag/Mock.swift", mock}; !slices.Equal(opts.MockSources, want) {
+			t.Errorf("MockSources = %v, want %v", opts.MockSources, want)
+		}
+	})
+
+	t.Run("device flag wins", func(t *testing.T) {
+		opts := RunOptions{PreferredDevice: "FLAG-DEVICE"}
+		sc.Apply(&opts, true)
+		if opts.PreferredDevice != "FLAG-DEVICE" {
+			t.Errorf("PreferredDevice = %q, want FLAG-DEVICE", opts.PreferredDevice)
+		}
+	})
+}
+
+func TestLoadSidecar_Missing(t *testing.T) {
+	sc, err := LoadSidecar(filepath.Join(t.TempDir(), "FeedView.swift"))
+	if err != nil || sc != nil {
+		t.Errorf("LoadSidecar = %v, %v; want nil, nil", sc, err)
+	}
+
+	// A nil sidecar leaves the options untouched.
+	opts := RunOptions{PreferredDevice: "X"}
+	sc.Apply(&opts, false)
+	if opts.PreferredDevice != "X" {
+		t.Errorf("PreferredDevice = %q, want X", opts.PreferredDevice)
+	}
+}
+
+func TestParseSidecar_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown key", "THEME=dark", `line 1: unknown key "THEME"`},
+		{"missing separator", "# c\nAPPEARANCE", "line 2: expected KEY=VALUE"},
+		{"bad import", "IMPORT=Not A Module", "is not a module name"},
+		{"non-swift mock", "MOCK=data.json", "is not a .swift file"},
+		{"missing mock", "MOCK=Missing.swift", "MOCK source file not found"},
+		{"bad appearance", "APPEARANCE=sepia", "invalid appearance"},
+		{"bad locale", "LOCALE=japanese", "invalid locale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSidecar(strings.NewReader(tt.content), t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseSidecar error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSidecarStreamSettings(t *testing.T) {
+	sc := &Sidecar{Appearance: "dark", Locale: "ja_JP", Imports: []string{"DSKit"}, Mocks: []string{"/m/FeedMocks.swift"}}
+
+	a := sc.streamOverrides(platform.AccessibilityOverrides{Appearance: "light"})
+	if a.Appearance != "light" || a.Locale != "ja_JP" {
+		t.Errorf("overrides = %+v, want the stream's appearance and the sidecar's locale", a)
+	}
+
+	serveImports := make([]string, 1, 4) // spare capacity must not be written
+	serveImports[0] = "Shared"
+	imports, mocks := sc.withThunkSources(serveImports, nil)
+	if !slices.Equal(imports, []string{"Shared", "DSKit"}) || !slices.Equal(mocks, sc.Mocks) {
+		t.Errorf("imports, mocks = %v, %v", imports, mocks)
+	}
+	if got := serveImports[:2][1]; got != "" {
+		t.Errorf("serve's imports were modified: %q", got)
+	}
+
+	var none *Sidecar
+	if a := none.streamOverrides(platform.AccessibilityOverrides{Locale: "en_US"}); a.Locale != "en_US" {
+		t.Errorf("nil sidecar changed overrides: %+v", a)
+	}
+}
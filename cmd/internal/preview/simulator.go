@@ -3,10 +3,14 @@ package preview
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/k-kohey/axe/internal/platform"
 	"github.com/k-kohey/axe/internal/preview/build"
 	"github.com/k-kohey/axe/internal/preview/buildlock"
 	"howett.net/plist"
@@ -18,21 +22,25 @@ func terminateApp(ctx context.Context, bs *build.Settings, device, deviceSetPath
 	}
 }
 
-// resolveAppBundle locates the .app bundle in the build products directory.
-// It first checks BuiltProductsDir (configuration-specific), then falls back
-// to a glob across all configuration directories.
-func resolveAppBundle(bs *build.Settings, dirs previewDirs) (string, error) {
-	appName := bs.ModuleName + ".app"
-	srcAppPath := filepath.Join(bs.BuiltProductsDir, appName)
+// eraseFresh erases device for --fresh before it boots. Simulators outside
+// the axe device set belong to the user and are refused rather than erased.
+func eraseFresh(simctl platform.SimctlRunner, device, deviceSetPath string, isExternal bool) error {
+	if isExternal || deviceSetPath == "" {
+		return fmt.Errorf("--fresh only erases simulators in the axe device set; %s is in the standard Xcode set", device)
+	}
+	fmt.Fprintln(os.Stderr, "Erasing simulator...")
+	if err := platform.EraseDevice(simctl, deviceSetPath, device); err != nil {
+		return fmt.Errorf("erasing simulator: %w", err)
+	}
+	return nil
+}
 
+// resolveAppBundle returns the .app bundle built for the app target, as
+// reported by xcodebuild -showBuildSettings.
+func resolveAppBundle(bs *build.Settings) (string, error) {
+	srcAppPath := bs.AppPath()
 	if _, err := os.Stat(srcAppPath); err != nil {
-		pattern := filepath.Join(dirs.Build, "Build", "Products", "*", appName)
-		matches, _ := filepath.Glob(pattern)
-		if len(matches) == 0 {
-			return "", fmt.Errorf("app bundle not found: %s", srcAppPath)
-		}
-		srcAppPath = matches[0]
-		slog.Debug("Found app bundle via glob", "path", srcAppPath)
+		return "", fmt.Errorf("app bundle not found: %s", srcAppPath)
 	}
 	return srcAppPath, nil
 }
@@ -46,7 +54,7 @@ func stageAppBundle(ctx context.Context, bs *build.Settings, dirs previewDirs, f
 	}
 	defer lock.RUnlock()
 
-	srcAppPath, err := resolveAppBundle(bs, dirs)
+	srcAppPath, err := resolveAppBundle(bs)
 	if err != nil {
 		return "", err
 	}
@@ -86,9 +94,104 @@ func installApp(ctx context.Context, bs *build.Settings, dirs previewDirs, devic
 		return "", fmt.Errorf("install: %w", err)
 	}
 
+	// The data container only exists once the app is installed, and the app
+	// must find the seed files on its first launch, so seed right here.
+	if err := seedAppData(ctx, bs, device, deviceSetPath, ar); err != nil {
+		return "", fmt.Errorf("seed: %w", err)
+	}
+
 	return stagedAppPath, nil
 }
 
+// seedCopy is a single file copy from the seed directory into the app's
+// data container.
+type seedCopy struct {
+	src string
+	dst string
+}
+
+// seedCopyPlan lists the regular files under seedDir together with their
+// destination in container, preserving relative paths
+// (seedDir/Documents/a.json → container/Documents/a.json).
+// Symlinks and other non-regular files are skipped.
+func seedCopyPlan(seedDir, container string) ([]seedCopy, error) {
+	var plan []seedCopy
+	err := filepath.WalkDir(seedDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			slog.Debug("Skipping non-regular seed file", "path", path)
+			return nil
+		}
+		rel, err := filepath.Rel(seedDir, path)
+		if err != nil {
+			return err
+		}
+		plan = append(plan, seedCopy{src: path, dst: filepath.Join(container, rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking seed directory: %w", err)
+	}
+	return plan, nil
+}
+
+// seedAppData copies bs.SeedDir into the installed app's data container,
+// overwriting files left by a previous run so every launch starts from the
+// same seed. No-op when no seed directory is configured.
+func seedAppData(ctx context.Context, bs *build.Settings, device, deviceSetPath string, ar AppRunner) error {
+	if bs.SeedDir == "" {
+		return nil
+	}
+	container, err := ar.DataContainer(ctx, device, bs.BundleID, deviceSetPath)
+	if err != nil {
+		return fmt.Errorf("resolving app data container: %w", err)
+	}
+	if container == "" {
+		return fmt.Errorf("resolving app data container: empty path for %s", bs.BundleID)
+	}
+	plan, err := seedCopyPlan(bs.SeedDir, container)
+	if err != nil {
+		return err
+	}
+	for _, c := range plan {
+		if err := copySeedFile(c.src, c.dst); err != nil {
+			return err
+		}
+	}
+	slog.Debug("Seeded app data container", "files", len(plan), "container", container)
+	return nil
+}
+
+// copySeedFile copies src to dst, creating parent directories as needed.
+func copySeedFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening seed file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating container directory: %w", err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("creating seeded file: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copying seed file %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("closing seeded file: %w", err)
+	}
+	return nil
+}
+
 // rewriteInfoPlist overwrites CFBundleIdentifier and CFBundleDisplayName
 // in the given Info.plist file. Errors are logged as warnings without
 // failing the build — the subsequent simctl install/launch will simply
@@ -120,6 +223,42 @@ func rewriteInfoPlist(plistPath, bundleID, displayName string) {
 	}
 }
 
+// applyAccessibility applies accessibility overrides to the device before the
+// app renders, restoring the settings d overrode earlier that a leaves
+// unset. Failures are logged rather than returned so that a simctl
+// incompatibility does not prevent the preview from launching. A nil d
+// leaves the device unchanged.
+func applyAccessibility(ctx context.Context, d *platform.DeviceOverrides, a platform.AccessibilityOverrides) {
+	if d == nil {
+		return
+	}
+	if err := d.Apply(ctx, a); err != nil {
+		slog.Warn("Failed to apply accessibility overrides", "err", err)
+	}
+}
+
+// restoreAccessibility writes back the device settings d overrode, so that
+// a reused or pinned device is left as it was. A nil d is a no-op.
+func restoreAccessibility(ctx context.Context, d *platform.DeviceOverrides) {
+	if d == nil {
+		return
+	}
+	if err := d.Restore(ctx); err != nil {
+		slog.Debug("Failed to restore accessibility settings", "err", err)
+	}
+}
+
+// WithDeviceProfile layers the profile saved with `axe preview simulator
+// config` for device underneath the run's own overrides.
+func WithDeviceProfile(a platform.AccessibilityOverrides, device string) platform.AccessibilityOverrides {
+	profile := platform.LoadDeviceProfile(device)
+	if profile.IsZero() {
+		return a
+	}
+	slog.Info("Applying saved device profile", "udid", device, "overrides", strings.Join(profile.Labels(), ", "))
+	return a.Or(profile)
+}
+
 // launchWithHotReload launches the app with both the loader dylib and the
 // initial thunk dylib injected, plus the socket path for hot-reload communication.
 func launchWithHotReload(ctx context.Context, bs *build.Settings, loaderPath, thunkPath, socketPath string, device, deviceSetPath string, ar AppRunner) error {
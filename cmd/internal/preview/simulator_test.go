@@ -15,10 +15,9 @@ func TestResolveAppBundle_DirectPath(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	bs := &build.Settings{ModuleName: "MyApp", BuiltProductsDir: productsDir}
-	dirs := previewDirs{ProjectDirs: build.ProjectDirs{Build: root}}
+	bs := &build.Settings{ModuleName: "MyApp", BuiltProductsDir: productsDir, ProductName: "MyApp.app"}
 
-	got, err := resolveAppBundle(bs, dirs)
+	got, err := resolveAppBundle(bs)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -27,20 +26,16 @@ func TestResolveAppBundle_DirectPath(t *testing.T) {
 	}
 }
 
-func TestResolveAppBundle_GlobFallback(t *testing.T) {
-	root := t.TempDir()
-	// Place app in a different configuration directory than BuiltProductsDir.
-	altDir := filepath.Join(root, "Build", "Products", "Release-iphonesimulator")
-	appDir := filepath.Join(altDir, "MyApp.app")
+func TestResolveAppBundle_ProductNameDiffersFromModule(t *testing.T) {
+	productsDir := filepath.Join(t.TempDir(), "Build", "Products", "Debug-iphonesimulator")
+	appDir := filepath.Join(productsDir, "My App.app")
 	if err := os.MkdirAll(appDir, 0o755); err != nil {
 		t.Fatal(err)
 	}
 
-	// BuiltProductsDir points to a non-existent configuration.
-	bs := &build.Settings{ModuleName: "MyApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator")}
-	dirs := previewDirs{ProjectDirs: build.ProjectDirs{Build: root}}
+	bs := &build.Settings{ModuleName: "My_App", BuiltProductsDir: productsDir, ProductName: "My App.app"}
 
-	got, err := resolveAppBundle(bs, dirs)
+	got, err := resolveAppBundle(bs)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -51,10 +46,9 @@ func TestResolveAppBundle_GlobFallback(t *testing.T) {
 
 func TestResolveAppBundle_NotFound(t *testing.T) {
 	root := t.TempDir()
-	bs := &build.Settings{ModuleName: "NoApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator")}
-	dirs := previewDirs{ProjectDirs: build.ProjectDirs{Build: root}}
+	bs := &build.Settings{ModuleName: "NoApp", BuiltProductsDir: filepath.Join(root, "Build", "Products", "Debug-iphonesimulator"), ProductName: "NoApp.app"}
 
-	_, err := resolveAppBundle(bs, dirs)
+	_, err := resolveAppBundle(bs)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -0,0 +1,149 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+	"github.com/k-kohey/axe/internal/preview/protocol"
+)
+
+// A --preview-all group renders every #Preview of a file on one device.
+// The stream of the first preview is the group's host: it owns the device
+// and the app, and after each build or reload it selects each preview in
+// turn and sends a screenshot of it as a Frame of that preview's stream.
+// The other streams only carry those frames and stop with the host.
+
+// groupSettle is how long the host of a --preview-all group waits after
+// selecting a preview before taking its screenshot, so that the app has
+// rendered it.
+var groupSettle = 300 * time.Millisecond
+
+// groupCommandTarget returns the ID of the stream that handles cmd when
+// cmd names a --preview-all group or one of its streams. Builds, reloads
+// and file watching belong to the group's host, and removing the host
+// removes the whole group, which cannot render without it. Commands that would drive
+// or move the shared device for one preview (SwitchFile, NextPreview,
+// Input, SetDevice, recordings) are rejected, and ok is false.
+func (sm *StreamManager) groupCommandTarget(cmd *pb.Command) (target string, ok bool) {
+	id := cmd.GetStreamId()
+	sm.mu.Lock()
+	group := ""
+	if _, isGroup := sm.groups[id]; isGroup {
+		group = id
+	} else if s, isStream := sm.streams[id]; isStream {
+		group = s.group
+	}
+	sm.mu.Unlock()
+	if group == "" {
+		return id, true
+	}
+
+	switch {
+	case cmd.GetRemoveStream() != nil && id == previewStreamID(group, 0):
+		return group, true
+	case cmd.GetForceRebuild() != nil, cmd.GetReload() != nil, cmd.GetSetWatch() != nil:
+		return previewStreamID(group, 0), true
+	case cmd.GetSwitchFile() != nil, cmd.GetNextPreview() != nil, cmd.GetInput() != nil,
+		cmd.GetSetDevice() != nil, cmd.GetStartRecording() != nil, cmd.GetStopRecording() != nil:
+		if cmd.GetSetDevice() != nil {
+			sm.rejectCommand(id, "SetDevice", "the previews of a preview-all group share one device")
+			return "", false
+		}
+		slog.Warn("Rejecting command for a preview-all group: its previews share one device", "streamId", id, "group", group)
+		if sr := cmd.GetStartRecording(); sr != nil {
+			sm.sendRecording(id, sr.GetPath(), false, errPreviewAllRecording)
+		}
+		return "", false
+	default:
+		return id, true
+	}
+}
+
+// errPreviewAllRecording rejects StartRecording for a --preview-all group,
+// whose device shows its previews in turn.
+var errPreviewAllRecording = errors.New("recording is not supported for preview-all streams")
+
+// runGroupMember runs a --preview-all stream other than the group's host.
+// The host renders its frames, so it only waits, and stops with the host.
+func (sm *StreamManager) runGroupMember(ctx context.Context, s *stream) {
+	select {
+	case <-ctx.Done():
+	case <-s.host.done:
+		// A host that failed has stopped s along with itself already, and
+		// streams being removed are no longer registered. Anything else
+		// would leave s without a StreamStopped.
+		sm.mu.Lock()
+		cur, registered := sm.streams[s.id]
+		sm.mu.Unlock()
+		if registered && cur == s {
+			s.sendStopped(sm.ew, "runtime_error", "the stream rendering the preview-all group stopped", "")
+		}
+	}
+}
+
+// groupStreams returns the streams events of s go to: the streams of its
+// --preview-all group that are still active when s is a group host, and s
+// itself otherwise.
+func (sm *StreamManager) groupStreams(s *stream) []*stream {
+	if len(s.members) == 0 {
+		return []*stream{s}
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	var live []*stream
+	for _, m := range s.members {
+		if cur, ok := sm.streams[m.id]; ok && cur == m {
+			live = append(live, m)
+		}
+	}
+	return live
+}
+
+// groupRenderer returns the function that renders every preview of the
+// --preview-all group hosted by s on s's device. selectPreview shows the
+// preview of the given index, reloading the app as NextPreview does.
+// Frames of a member are staged in a directory of their own.
+func (sm *StreamManager) groupRenderer(s *stream) func(ctx context.Context, selectPreview func(i int)) {
+	vocs := make(map[*stream]*protocol.VideoOutputConfig, len(s.members))
+	for _, m := range s.members {
+		vocs[m] = &protocol.VideoOutputConfig{
+			EW:             sm.ew,
+			StreamID:       m.id,
+			Device:         s.deviceUDID,
+			File:           m.file,
+			Label:          m.label,
+			Encoding:       sm.frameEncoding,
+			FrameDir:       filepath.Join(s.frameDir(), strconv.Itoa(m.preview)),
+			Codec:          m.codec,
+			PNGCompression: sm.pngCompression,
+			OnFrame:        func() { sm.frameSent(m.id) },
+		}
+	}
+
+	return func(ctx context.Context, selectPreview func(i int)) {
+		for _, m := range sm.groupStreams(s) {
+			selectPreview(m.preview)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(groupSettle):
+			}
+			data, err := s.idbClient.Screenshot(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Warn("Failed to take preview-all screenshot", "streamId", m.id, "err", err)
+				continue
+			}
+			if err := vocs[m].SendScreenshot(data); err != nil {
+				slog.Warn("Failed to send preview-all frame", "streamId", m.id, "err", err)
+			}
+		}
+	}
+}
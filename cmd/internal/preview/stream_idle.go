@@ -0,0 +1,115 @@
+package preview
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// startIdleTimer arms the idle timer if idle shutdown is enabled and no
+// streams are active. Called at startup and whenever a stream ends.
+func (sm *StreamManager) startIdleTimer() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.idleTimeout <= 0 || sm.idle || len(sm.streams) > 0 || sm.idleTimer != nil {
+		return
+	}
+	gen := sm.idleGen
+	sm.idleTimer = time.AfterFunc(sm.idleTimeout, func() { sm.idleShutdown(gen) })
+}
+
+// stopIdleTimerLocked disarms the idle timer. A timer that already fired
+// but has not taken sm.mu yet sees a newer generation and does nothing.
+// Must be called with sm.mu held.
+func (sm *StreamManager) stopIdleTimerLocked() {
+	sm.idleGen++
+	if sm.idleTimer != nil {
+		sm.idleTimer.Stop()
+		sm.idleTimer = nil
+	}
+}
+
+// idleShutdown releases the resources kept for future streams once no
+// stream has been active for idleTimeout: the warm and pooled simulators,
+// their idle companions, and the shared file watcher. The next AddStream
+// restores them (see wakeLocked) and cold-starts like the first stream.
+// onIdle, if set, runs afterwards.
+//
+// The simulators are shut down without holding sm.mu, which may take up to
+// 30 seconds, so that commands are still served meanwhile. idleDrain is
+// set for that time; acquireDevice waits for it so that no stream takes a
+// device from the pool while it is being emptied.
+func (sm *StreamManager) idleShutdown(gen int) {
+	sm.mu.Lock()
+	if gen != sm.idleGen || len(sm.streams) > 0 {
+		sm.mu.Unlock()
+		return
+	}
+	sm.idleTimer = nil
+	sm.idle = true
+	slog.Info("No active streams; shutting down idle simulators and file watcher", "idleTimeout", sm.idleTimeout)
+
+	if sm.watcher != nil {
+		sm.watcher.Close()
+		sm.watcher = nil
+	}
+	drained := make(chan struct{})
+	sm.idleDrain = drained
+	sm.mu.Unlock()
+
+	if sm.warm != nil {
+		sm.warm.drain()
+	}
+	sm.companions.StopIdle()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	sm.pool.ShutdownAll(shutdownCtx)
+	shutdownCancel()
+
+	sm.mu.Lock()
+	sm.idleDrain = nil
+	close(drained)
+	onIdle := sm.onIdle
+	sm.mu.Unlock()
+
+	if onIdle != nil {
+		onIdle()
+	}
+}
+
+// waitIdleDrain blocks while an idle shutdown is emptying the device pool.
+func (sm *StreamManager) waitIdleDrain(ctx context.Context) error {
+	sm.mu.Lock()
+	drained := sm.idleDrain
+	sm.mu.Unlock()
+	if drained == nil {
+		return nil
+	}
+	slog.Debug("Waiting for the idle shutdown to finish")
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wakeLocked disarms the idle timer for a starting stream and, after an
+// idle shutdown, restarts the shared watcher. Must be called with sm.mu held.
+func (sm *StreamManager) wakeLocked() {
+	sm.stopIdleTimerLocked()
+	if !sm.idle {
+		return
+	}
+	sm.idle = false
+	slog.Info("Resuming after idle shutdown")
+	if sm.watcher != nil || sm.newWatcher == nil {
+		return
+	}
+	w, err := sm.newWatcher(filepath.Dir(sm.pc.PrimaryPath()))
+	if err != nil {
+		slog.Warn("Failed to restart file watcher after idle shutdown; streams will not reload on file changes", "err", err)
+		return
+	}
+	sm.watcher = w
+}
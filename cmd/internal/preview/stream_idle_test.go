@@ -0,0 +1,152 @@
+package preview
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+	"github.com/k-kohey/axe/internal/preview/protocol"
+	"github.com/k-kohey/axe/internal/preview/watch"
+)
+
+func TestStreamManager_IdleShutdown(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	sm := newTestStreamManager(pool, protocol.NewEventWriter(&buf))
+
+	dir := t.TempDir()
+	sm.pc, _ = NewProjectConfig(filepath.Join(dir, "App.xcodeproj"), "", "App", "")
+	ctx := t.Context()
+	_, _, _, _, sl := nopRunners()
+	watcherStarts := 0
+	sm.newWatcher = func(root string) (*watch.SharedWatcher, error) {
+		watcherStarts++
+		return watch.NewSharedWatcher(ctx, root, sl)
+	}
+	w, err := sm.newWatcher(dir)
+	if err != nil {
+		t.Fatalf("creating watcher: %v", err)
+	}
+	sm.watcher = w
+	defer sm.closeWatcher()
+
+	idled := make(chan struct{}, 1)
+	sm.onIdle = func() { idled <- struct{}{} }
+	sm.idleTimeout = 50 * time.Millisecond
+
+	addStream := func() {
+		sm.HandleCommand(ctx, &pb.Command{
+			StreamId: "stream-a",
+			Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: filepath.Join(dir, "View.swift")}},
+		})
+		waitForStreamCount(t, sm, 1, 2*time.Second)
+	}
+
+	// An active stream keeps everything running past the timeout.
+	sm.startIdleTimer()
+	addStream()
+	select {
+	case <-idled:
+		t.Fatal("idle shutdown with an active stream")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// Reaching zero streams for the timeout shuts down the pool and watcher.
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}}})
+	select {
+	case <-idled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no idle shutdown after the last stream was removed")
+	}
+	pool.mu.Lock()
+	shutdown := pool.shutdownAll
+	pool.shutdownAll = false
+	pool.mu.Unlock()
+	if !shutdown {
+		t.Error("pool was not shut down")
+	}
+	if sm.currentWatcher() != nil {
+		t.Error("watcher still running after idle shutdown")
+	}
+
+	// A new stream restarts the watcher and disarms the timer.
+	addStream()
+	if sm.currentWatcher() == nil || watcherStarts != 2 {
+		t.Errorf("watcher not restarted (starts = %d)", watcherStarts)
+	}
+	select {
+	case <-idled:
+		t.Fatal("idle shutdown while the restarted stream is active")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	sm.StopAll()
+	select {
+	case <-idled:
+		t.Fatal("idle shutdown after StopAll")
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+// TestStreamManager_IdleShutdownServesCommands verifies that commands are
+// handled while an idle shutdown empties the pool, and that a stream added
+// meanwhile acquires its device only once the pool has been shut down.
+func TestStreamManager_IdleShutdownServesCommands(t *testing.T) {
+	pool := newFakeDevicePool()
+	pool.shutdownBlock = make(chan struct{})
+	var buf syncBuffer
+	sm := newTestStreamManager(pool, protocol.NewEventWriter(&buf))
+	defer sm.StopAll()
+
+	idled := make(chan struct{}, 1)
+	sm.onIdle = func() { idled <- struct{}{} }
+	sm.idleTimeout = 10 * time.Millisecond
+	sm.startIdleTimer()
+
+	// Wait until idleShutdown is blocked in ShutdownAll.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		sm.mu.Lock()
+		draining := sm.idleDrain != nil
+		sm.mu.Unlock()
+		if draining {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle shutdown did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	handled := make(chan struct{})
+	go func() {
+		sm.HandleCommand(t.Context(), &pb.Command{
+			StreamId: "stream-a",
+			Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift"}},
+		})
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("AddStream blocked behind the idle shutdown")
+	}
+	waitForEvents(t, &buf, 1, 2*time.Second) // booting
+	pool.mu.Lock()
+	acquired := len(pool.acquired)
+	pool.mu.Unlock()
+	if acquired != 0 {
+		t.Errorf("stream acquired a device while the pool was shutting down")
+	}
+
+	close(pool.shutdownBlock)
+	<-idled
+	waitForEvents(t, &buf, 2, 2*time.Second) // running
+	pool.mu.Lock()
+	acquired = len(pool.acquired)
+	pool.mu.Unlock()
+	if acquired != 1 {
+		t.Errorf("acquired %d devices after the idle shutdown, want 1", acquired)
+	}
+}
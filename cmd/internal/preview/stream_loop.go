@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"slices"
 
 	"github.com/k-kohey/axe/internal/preview/build"
 	pb "github.com/k-kohey/axe/internal/preview/previewproto"
@@ -30,10 +31,20 @@ type eventLoopConfig struct {
 	switchFileCh   <-chan string
 	nextPreviewCh  <-chan struct{}
 	forceRebuildCh <-chan struct{}
+	reloadCh       <-chan struct{}
 	inputCh        <-chan *pb.Input
+	updateCh       <-chan streamUpdate
 	idbErrCh       <-chan error
 	bootDiedCh     <-chan struct{}
 
+	// afterReload, when set, is called after each hot-reload or rebuild
+	// of sourceFile. --preview-all hosts render their group with it.
+	afterReload func(ctx context.Context, sourceFile string)
+
+	// manualBuild holds file changes back as "dirty" until reloadCh fires
+	// instead of reloading after the debounce window.
+	manualBuild bool
+
 	// onCancel is called when the context is cancelled (e.g. Ctrl+C).
 	// May be nil if no cleanup message is needed.
 	onCancel func()
@@ -60,6 +71,9 @@ func runEventLoop(ctx context.Context, cfg *eventLoopConfig) error {
 	db := watch.NewDebouncer()
 	defer db.Stop()
 
+	// Changes held back until Reload (manualBuild only).
+	var dirty []string
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -78,7 +92,22 @@ func runEventLoop(ctx context.Context, cfg *eventLoopConfig) error {
 				slog.Debug("Ignoring file change outside dependency graph", "path", path)
 				continue
 			}
-			db.HandleFileChange(path, trackedSet)
+			if cfg.manualBuild {
+				if len(dirty) == 0 {
+					sendWatchStatus(cfg.wctx, "dirty")
+				}
+				if !slices.Contains(dirty, path) {
+					dirty = append(dirty, path)
+				}
+				continue
+			}
+			// Tell the client the save was seen even though the reload
+			// waits for the debounce window to close.
+			if db.HandleFileChange(path, trackedSet) {
+				sendWatchStatus(cfg.wctx, "coalesced")
+			} else {
+				sendWatchStatus(cfg.wctx, "pending")
+			}
 
 		case changedFile := <-db.TrackedCh:
 			cfg.ws.mu.Lock()
@@ -94,6 +123,7 @@ func runEventLoop(ctx context.Context, cfg *eventLoopConfig) error {
 				cfg.ws.mu.Unlock()
 				if err := reloadMultiFile(ctx, sourceFile, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws); err != nil {
 					slog.Warn("Hot-reload error", "err", err)
+					sendWatchBuildFailed(cfg.wctx, err)
 				}
 				// NOTE: The depGraph is intentionally NOT refreshed after hot-reload.
 				// A body-only change could introduce a new type reference (e.g. NewView()),
@@ -102,21 +132,25 @@ func runEventLoop(ctx context.Context, cfg *eventLoopConfig) error {
 			case strategyRebuild:
 				if err := rebuildAndRelaunch(ctx, sourceFile, cfg.pc, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws); err != nil {
 					slog.Warn("Rebuild error", "err", err)
+					sendWatchBuildFailed(cfg.wctx, err)
 				}
 				// Recompute skeletons and trackedSet after rebuild
 				// (rebuildAndRelaunch may update trackedFiles and depGraph).
 				trackedSet = refreshTrackedState(cfg.ws)
 			}
+			cfg.reloaded(ctx, sourceFile)
 
 		case depFiles := <-db.DepCh:
 			db.ClearDepTimer()
 			if !tryIncrementalReload(ctx, depFiles, sourceFile, cfg.pc, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws) {
 				if err := rebuildAndRelaunch(ctx, sourceFile, cfg.pc, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws); err != nil {
 					slog.Warn("Dependency rebuild error", "err", err)
+					sendWatchBuildFailed(cfg.wctx, err)
 				}
 			}
 			// Rebuild skeletonMap and trackedSet after potential changes.
 			trackedSet = refreshTrackedState(cfg.ws)
+			cfg.reloaded(ctx, sourceFile)
 
 		case newFile := <-cfg.switchFileCh:
 			db.Reset()
@@ -127,12 +161,55 @@ func runEventLoop(ctx context.Context, cfg *eventLoopConfig) error {
 		case <-cfg.nextPreviewCh:
 			handleNextPreviewCmd(ctx, sourceFile, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws)
 
+		case upd := <-cfg.updateCh:
+			if upd.file != "" && upd.file != sourceFile {
+				db.Reset()
+				sourceFile, trackedSet = handleSwitchFileCmd(ctx, upd.file, sourceFile, trackedSet, cfg.pc, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws)
+			}
+			// A preview index refers to upd.file; skip it if the switch failed.
+			reloaded := false
+			if upd.preview >= 0 && (upd.file == "" || upd.file == sourceFile) {
+				reloaded = handleSelectPreviewCmd(ctx, sourceFile, upd.preview, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws)
+			}
+			// Apps read the locale and language at launch only, so a new
+			// one takes a relaunch; hot-reload would keep the old strings.
+			if upd.accessibility != nil {
+				sidecar := loadStreamSidecar(cfg.wctx.streamID, sourceFile)
+				cfg.wctx.accessibility = WithDeviceProfile(sidecar.streamOverrides(*upd.accessibility), cfg.wctx.device)
+				if err := rebuildAndRelaunch(ctx, sourceFile, cfg.pc, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws); err != nil {
+					slog.Warn("Relaunch error", "err", err)
+					sendWatchBuildFailed(cfg.wctx, err)
+				}
+				trackedSet = refreshTrackedState(cfg.ws)
+				reloaded = true
+			}
+			// A hot-reload reports its own phases; otherwise end "updating" here.
+			if !reloaded {
+				sendWatchStatus(cfg.wctx, "running")
+			}
+
+		case <-cfg.reloadCh:
+			if len(dirty) == 0 {
+				slog.Debug("Ignoring Reload: no changes since the last build")
+				continue
+			}
+			// Replay the held-back changes through the debouncer so that
+			// they hot-reload or rebuild as watched changes would.
+			for _, path := range dirty {
+				db.HandleFileChange(path, trackedSet)
+			}
+			dirty = nil
+			sendWatchStatus(cfg.wctx, "pending")
+
 		case <-cfg.forceRebuildCh:
+			dirty = nil // the rebuild includes them
 			if err := rebuildAndRelaunch(ctx, sourceFile, cfg.pc, cfg.bs, cfg.dirs, cfg.wctx, cfg.ws); err != nil {
 				slog.Warn("Force rebuild error", "err", err)
+				sendWatchBuildFailed(cfg.wctx, err)
 			}
 			// rebuildAndRelaunch updates ws.trackedFiles; sync local state.
 			trackedSet = refreshTrackedState(cfg.ws)
+			cfg.reloaded(ctx, sourceFile)
 
 		case input := <-cfg.inputCh:
 			if cfg.hid != nil {
@@ -163,6 +240,13 @@ func runEventLoop(ctx context.Context, cfg *eventLoopConfig) error {
 	}
 }
 
+// reloaded calls the afterReload hook, if any.
+func (cfg *eventLoopConfig) reloaded(ctx context.Context, sourceFile string) {
+	if cfg.afterReload != nil {
+		cfg.afterReload(ctx, sourceFile)
+	}
+}
+
 // runStreamLoop is the per-stream event loop for multi-stream mode.
 // It assembles an eventLoopConfig from the stream and delegates to runEventLoop.
 func runStreamLoop(ctx context.Context, s *stream, sm *StreamManager,
@@ -175,11 +259,15 @@ func runStreamLoop(ctx context.Context, s *stream, sm *StreamManager,
 		streamID:      s.id,
 		serve:         true,
 		ew:            sm.ew,
+		accessibility: s.accessibility,
+		overrides:     s.overrides,
+		layout:        sm.layout,
 		build:         sm.build,
 		toolchain:     sm.toolchain,
 		app:           sm.app,
 		copier:        sm.copier,
 		sources:       sm.sources,
+		crashes:       sm.crashes,
 	}
 
 	var bootDiedCh <-chan struct{}
@@ -189,7 +277,7 @@ func runStreamLoop(ctx context.Context, s *stream, sm *StreamManager,
 
 	cfg := &eventLoopConfig{
 		sourceFile:     s.file,
-		pc:             sm.pc,
+		pc:             s.project.pc,
 		bs:             bs,
 		dirs:           s.dirs,
 		wctx:           wctx,
@@ -199,9 +287,12 @@ func runStreamLoop(ctx context.Context, s *stream, sm *StreamManager,
 		switchFileCh:   s.switchFileCh,
 		nextPreviewCh:  s.nextPreviewCh,
 		forceRebuildCh: s.forceRebuildCh,
+		reloadCh:       s.reloadCh,
 		inputCh:        s.inputCh,
+		updateCh:       s.updateCh,
 		idbErrCh:       idbErrCh,
 		bootDiedCh:     bootDiedCh,
+		manualBuild:    s.manualBuild,
 		bootErr: func() error {
 			if s.bootCompanion != nil {
 				return s.bootCompanion.Err()
@@ -213,18 +304,28 @@ func runStreamLoop(ctx context.Context, s *stream, sm *StreamManager,
 		},
 	}
 
+	if len(s.members) > 0 {
+		render := sm.groupRenderer(s)
+		cfg.afterReload = func(ctx context.Context, sourceFile string) {
+			render(ctx, func(i int) {
+				handleSelectPreviewCmd(ctx, sourceFile, i, bs, cfg.dirs, cfg.wctx, cfg.ws)
+			})
+		}
+		cfg.afterReload(ctx, s.file)
+	}
+
 	return runEventLoop(ctx, cfg)
 }
 
 // runDegradedStreamLoop handles a degraded stream where hot-reload is unavailable.
 // Only Input events and fatal events (boot crash, idb error) are processed.
-// SwitchFile, NextPreview, and ForceRebuild commands are rejected with a
+// SwitchFile, NextPreview, ForceRebuild, Reload, and AddStream updates are rejected with a
 // "degraded" status re-send to inform the extension.
 func runDegradedStreamLoop(ctx context.Context, s *stream, sm *StreamManager, idbErrCh <-chan error) error {
 	sendDegradedRejection := func() {
 		if err := sm.ew.Send(&pb.Event{
 			StreamId: s.id,
-			Payload:  &pb.Event_StreamStatus{StreamStatus: &pb.StreamStatus{Phase: "degraded"}},
+			Payload:  &pb.Event_StreamStatus{StreamStatus: newStreamStatus("degraded", s.accessibility, sm.layout)},
 		}); err != nil {
 			slog.Warn("Failed to re-send degraded status", "streamId", s.id, "err", err)
 		}
@@ -252,6 +353,14 @@ func runDegradedStreamLoop(ctx context.Context, s *stream, sm *StreamManager, id
 			slog.Info("ForceRebuild rejected in degraded mode", "streamId", s.id)
 			sendDegradedRejection()
 
+		case <-s.reloadCh:
+			slog.Info("Reload rejected in degraded mode", "streamId", s.id)
+			sendDegradedRejection()
+
+		case <-s.updateCh:
+			slog.Info("AddStream update rejected in degraded mode", "streamId", s.id)
+			sendDegradedRejection()
+
 		case input := <-s.inputCh:
 			if s.hid != nil {
 				s.hid.HandleInput(ctx, input)
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"github.com/k-kohey/axe/internal/preview/build"
+	"slices"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	pb "github.com/k-kohey/axe/internal/preview/previewproto"
 	"github.com/k-kohey/axe/internal/preview/protocol"
+	"github.com/k-kohey/axe/internal/preview/watch"
 )
 
 // fakeCompanion implements companionProcess for testing.
@@ -41,6 +43,7 @@ func newTestStream(id string) *stream {
 		nextPreviewCh: make(chan struct{}, 1),
 		inputCh:       make(chan *pb.Input, 1),
 		fileChangeCh:  make(chan string, 1),
+		updateCh:      make(chan streamUpdate, 1),
 		ws: &watchState{
 			reloadCounter:   1,
 			previewSelector: "0",
@@ -148,6 +151,182 @@ func TestStreamLoop_NextPreview(t *testing.T) {
 	}
 }
 
+// TestStreamLoop_UpdateSelectsPreview verifies that an AddStream update for
+// the current file selects the preview in place, without a file switch.
+func TestStreamLoop_UpdateSelectsPreview(t *testing.T) {
+	s := newTestStream("test-update")
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManagerWithRunners(newFakeDevicePool(), ew)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runStreamLoop(ctx, s, sm, &build.Settings{}, nil)
+	}()
+
+	// The reload itself fails without a real project, but the selection
+	// should be applied.
+	s.updateCh <- streamUpdate{file: s.file, preview: 2}
+
+	var (
+		idx     int
+		sel     string
+		tracked []string
+	)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.ws.mu.Lock()
+		idx, sel, tracked = s.ws.previewIndex, s.ws.previewSelector, s.ws.trackedFiles
+		s.ws.mu.Unlock()
+		if idx == 2 && sel == "2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("previewIndex=%d previewSelector=%q, want 2 and \"2\"", idx, sel)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(tracked) != 1 || tracked[0] != s.file {
+		t.Errorf("trackedFiles = %v, want unchanged", tracked)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runStreamLoop did not exit")
+	}
+}
+
+// TestStreamLoop_UpdateNoChange verifies that an AddStream update matching
+// the current file and preview reloads nothing and reports "running".
+func TestStreamLoop_UpdateNoChange(t *testing.T) {
+	s := newTestStream("test-update-noop")
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManagerWithRunners(newFakeDevicePool(), ew)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runStreamLoop(ctx, s, sm, &build.Settings{}, nil)
+	}()
+
+	s.updateCh <- streamUpdate{file: s.file, preview: 0}
+	waitForEvents(t, &buf, 1, 2*time.Second)
+
+	events := collectEvents(t, &buf)
+	if len(events) != 1 || events[0].StreamStatus == nil || events[0].StreamStatus["phase"] != "running" {
+		t.Errorf("expected a single StreamStatus{running}, got %+v", events)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runStreamLoop did not exit")
+	}
+}
+
+// TestStreamLoop_RapidEditsReportCoalesced verifies that saves arriving
+// within the debounce window report "pending" for the first change and
+// "coalesced" for the ones merged into it, before any reload starts.
+func TestStreamLoop_RapidEditsReportCoalesced(t *testing.T) {
+	s := newTestStream("test-coalesce")
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManagerWithRunners(newFakeDevicePool(), ew)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runStreamLoop(ctx, s, sm, &build.Settings{}, nil)
+	}()
+
+	for range 3 {
+		s.fileChangeCh <- s.file
+	}
+	waitForEvents(t, &buf, 3, time.Second)
+	// Stop before the debounce delay elapses so no reload is attempted.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runStreamLoop did not exit")
+	}
+
+	var phases []string
+	for _, e := range collectEvents(t, &buf) {
+		if e.StreamStatus != nil {
+			phases = append(phases, fmt.Sprint(e.StreamStatus["phase"]))
+		}
+	}
+	want := []string{"pending", "coalesced", "coalesced"}
+	if !slices.Equal(phases, want) {
+		t.Errorf("phases = %v, want %v", phases, want)
+	}
+}
+
+// TestStreamLoop_ManualBuildWaitsForReload verifies that a manualBuild
+// stream reports file changes as "dirty" without reloading, and builds
+// only once Reload arrives.
+func TestStreamLoop_ManualBuildWaitsForReload(t *testing.T) {
+	s := newTestStream("test-manual")
+	s.manualBuild = true
+	s.reloadCh = make(chan struct{}, 1)
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManagerWithRunners(newFakeDevicePool(), ew)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runStreamLoop(ctx, s, sm, &build.Settings{}, nil)
+	}()
+
+	phases := func() []string {
+		var phases []string
+		for _, e := range collectEvents(t, &buf) {
+			if e.StreamStatus != nil {
+				phases = append(phases, fmt.Sprint(e.StreamStatus["phase"]))
+			}
+		}
+		return phases
+	}
+
+	for range 2 {
+		s.fileChangeCh <- s.file
+	}
+	waitForEvents(t, &buf, 1, time.Second)
+	// Well past the debounce delay, the stream is still only dirty.
+	time.Sleep(3 * watch.TrackedDebounceDelay)
+	if got := phases(); !slices.Equal(got, []string{"dirty"}) {
+		t.Fatalf("phases before Reload = %v, want [dirty]", got)
+	}
+
+	// HogeView.swift does not exist, so the change is applied by a rebuild.
+	s.reloadCh <- struct{}{}
+	waitForEvents(t, &buf, 3, 2*time.Second)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runStreamLoop did not exit")
+	}
+	if got := phases(); len(got) < 3 || !slices.Equal(got[:3], []string{"dirty", "pending", "building"}) {
+		t.Errorf("phases = %v, want [dirty pending building ...]", got)
+	}
+}
+
 func TestStreamLoop_BootCrash(t *testing.T) {
 	s := newTestStream("test-crash")
 	var buf syncBuffer
@@ -1,15 +1,23 @@
 package preview
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"image/png"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/k-kohey/axe/internal/idb"
+	"github.com/k-kohey/axe/internal/platform"
 	"github.com/k-kohey/axe/internal/preview/analysis"
 	"github.com/k-kohey/axe/internal/preview/build"
 	"github.com/k-kohey/axe/internal/preview/codegen"
@@ -21,10 +29,13 @@ import (
 // DevicePoolInterface abstracts DevicePool for testability.
 type DevicePoolInterface interface {
 	Acquire(ctx context.Context, deviceType, runtime string) (string, error)
+	AcquireUDID(ctx context.Context, udid string) error
+	Devices(ctx context.Context) ([]platform.PoolDevice, error)
 	Release(ctx context.Context, udid string) error
 	ShutdownAll(ctx context.Context)
 	CleanupOrphans(ctx context.Context) error
 	GarbageCollect(ctx context.Context)
+	Reconcile(ctx context.Context) (platform.DeviceSetChanges, error)
 }
 
 // companionProcess abstracts idb.Companion for testability.
@@ -35,6 +46,26 @@ type companionProcess interface {
 	Stop() error
 }
 
+// companionIdleTTL is how long the video/HID companion of a device is kept
+// after its last stream ends, so that a stream added again for the device
+// reuses it instead of starting another.
+const companionIdleTTL = 30 * time.Second
+
+// pooledCompanion is a stream's reference to a companion of the stream
+// manager's CompanionPool. Stop returns the reference; the pool stops the
+// process once no stream uses it.
+type pooledCompanion struct {
+	*idb.Companion
+	pool *idb.CompanionPool
+	udid string
+	once sync.Once
+}
+
+func (c *pooledCompanion) Stop() error {
+	c.once.Do(func() { c.pool.Release(c.udid) })
+	return nil
+}
+
 // stream represents a single preview stream's state.
 type stream struct {
 	id         string
@@ -42,9 +73,20 @@ type stream struct {
 	deviceType string
 	runtime    string
 	deviceUDID string
+	pinnedUDID string // device chosen by SetDevice; empty = acquire by deviceType and runtime
+	preview    int    // index of the #Preview block rendered at launch
+	label      string // preview label attached to frames (--preview-all only)
+	codec      protocol.FrameCodec
+	group      string // AddStream streamId this stream was expanded from (--preview-all only)
 	cancel     context.CancelFunc
 	done       chan struct{} // closed when stream goroutine exits
 
+	// members are the streams of the --preview-all group, in preview order,
+	// on its host (the stream of the first preview); host is the group's
+	// host on the other streams. See stream_group.go.
+	members []*stream
+	host    *stream
+
 	// degraded is true when the stream launched using main-only thunk fallback.
 	// Hot-reload is not available in this mode.
 	degraded bool
@@ -53,8 +95,15 @@ type stream struct {
 	switchFileCh   chan string
 	nextPreviewCh  chan struct{}
 	forceRebuildCh chan struct{}
+	reloadCh       chan struct{}
 	inputCh        chan *pb.Input
-	fileChangeCh   chan string // from shared watcher
+	fileChangeCh   chan string       // from shared watcher
+	updateCh       chan streamUpdate // from AddStream for a running stream
+
+	// project is the shared project state when the stream started. The
+	// launcher uses it instead of the StreamManager fields, which a project
+	// switch replaces under sm.mu.
+	project streamProject
 
 	// Runtime state (set during stream initialization in the launcher).
 	dirs          previewDirs
@@ -64,6 +113,32 @@ type stream struct {
 	hid           *protocol.HIDHandler
 	ws            *watchState
 	loaderPath    string
+	statusBarSet  bool                      // the status bar of the device is overridden
+	overrides     *platform.DeviceOverrides // applies accessibility to the device; restored on cleanup
+	stopLogs      context.CancelFunc        // stops following the app log of the last launch
+
+	// recording is the screen recording started by StartRecording, nil
+	// when not recording. Guarded by sm.mu.
+	recording screenRecording
+
+	// File watching. watch is whether file changes reach fileChangeCh
+	// (AddStream.watch, toggled by SetWatch); watcher is the shared watcher
+	// the stream registers on (nil until registered, and after cleanup).
+	watchMu sync.Mutex
+	watch   bool
+	watcher *watch.SharedWatcher
+
+	// manualBuild defers reloads for file changes until a Reload command
+	// (AddStream.manual_build).
+	manualBuild bool
+
+	// appearance, locale and language are from AddStream (guarded by
+	// StreamManager.mu); accessibility is serve's overrides with them
+	// applied, used for the stream's device and owned by its goroutine.
+	appearance    string
+	locale        string
+	language      string
+	accessibility platform.AccessibilityOverrides
 
 	// Prevents duplicate StreamStopped events.
 	stoppedOnce sync.Once
@@ -72,6 +147,84 @@ type stream struct {
 	cleanupOnce sync.Once
 }
 
+// frameDir is where FrameEncodingFile writes the stream's frames; the
+// members of a --preview-all group it hosts use subdirectories.
+func (s *stream) frameDir() string {
+	return filepath.Join(s.dirs.Staging, "frames")
+}
+
+// removeFrameFiles deletes the frame files written for the stream.
+func (s *stream) removeFrameFiles() {
+	if s.dirs.Staging == "" {
+		return
+	}
+	if err := os.RemoveAll(s.frameDir()); err != nil {
+		slog.Debug("Failed to remove frame files", "streamId", s.id, "err", err)
+	}
+}
+
+// registerWatcher records w as the stream's watcher and subscribes to it,
+// unless watching is turned off for the stream.
+func (s *stream) registerWatcher(w *watch.SharedWatcher) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	s.watcher = w
+	if s.watch {
+		w.AddListener(s.id, s.fileChangeCh)
+	}
+}
+
+// unregisterWatcher unsubscribes from the stream's watcher until the next
+// registerWatcher.
+func (s *stream) unregisterWatcher() {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watcher != nil {
+		s.watcher.RemoveListener(s.id)
+		s.watcher = nil
+	}
+}
+
+// setWatch turns file watching on or off. Turning it off also discards a
+// pending change so that the stream does not reload after being pinned.
+func (s *stream) setWatch(enabled bool) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if s.watch == enabled {
+		return
+	}
+	s.watch = enabled
+	if s.watcher == nil {
+		return // registerWatcher applies s.watch later
+	}
+	if enabled {
+		s.watcher.AddListener(s.id, s.fileChangeCh)
+		return
+	}
+	s.watcher.RemoveListener(s.id)
+	select {
+	case <-s.fileChangeCh:
+	default:
+	}
+}
+
+// streamUpdate is an in-place change requested by an AddStream for a stream
+// that is already running.
+type streamUpdate struct {
+	file    string // source file to show
+	preview int    // #Preview index to select, or -1 to keep the current one
+	// accessibility, when set, replaces the stream's overrides; the app is
+	// relaunched since it reads the locale and language only at launch.
+	accessibility *platform.AccessibilityOverrides
+}
+
+// streamProject is a snapshot of the project state a stream builds with.
+type streamProject struct {
+	pc         ProjectConfig
+	preparer   *build.Preparer
+	indexCache *sharedIndexCache
+}
+
 // sendStopped sends a StreamStopped event exactly once per stream.
 // Safe to call multiple times (from launcher error and from RemoveStream).
 func (s *stream) sendStopped(ew *protocol.EventWriter, reason, message, diagnostic string) {
@@ -87,6 +240,27 @@ func (s *stream) sendStopped(ew *protocol.EventWriter, reason, message, diagnost
 			slog.Warn("Failed to send StreamStopped", "streamId", s.id, "err", err)
 		}
 	})
+	// The other streams of a --preview-all group render on the host's
+	// device and stop with it.
+	for _, m := range s.members {
+		if m != s {
+			m.sendStopped(ew, reason, message, diagnostic)
+		}
+	}
+}
+
+// sendLaunchCrash sends a StreamStopped with reason "app_crashed" when the
+// app launched at launchedAt left a crash report, and reports whether it did.
+func (s *stream) sendLaunchCrash(ctx context.Context, sm *StreamManager, bs *build.Settings, launchedAt time.Time) bool {
+	if sm.crashes == nil || ctx.Err() != nil {
+		return false
+	}
+	c := detectLaunchCrash(ctx, sm.crashes, bs.BundleID, launchedAt)
+	if c == nil {
+		return false
+	}
+	s.sendStopped(sm.ew, "app_crashed", c.summary(), c.diagnostic())
+	return true
 }
 
 // StreamManager manages multiple preview streams.
@@ -97,10 +271,20 @@ type StreamManager struct {
 	pool    DevicePoolInterface
 	ew      *protocol.EventWriter
 
+	// groups maps an AddStream streamId to the per-preview streams it was
+	// expanded into when previewAll is set.
+	groups map[string][]string
+
 	// strict mode disables degraded fallback.
 	strict bool
 
-	// Shared project configuration.
+	// rejectDuplicates ignores an AddStream whose streamId is already active
+	// instead of updating that stream.
+	rejectDuplicates bool
+
+	// Shared project configuration. pc, preparer, indexCache, and watcher
+	// are replaced by switchProjectLocked, which only runs while no streams
+	// are active.
 	pc            ProjectConfig
 	deviceSetPath string
 
@@ -108,18 +292,116 @@ type StreamManager struct {
 	// ExtractCompilerPaths) so only the first stream pays the cost.
 	preparer *build.Preparer
 
+	// buildMode is the build.Mode of the preparers switchProjectLocked
+	// creates; RunServe sets it to the mode of the initial preparer.
+	buildMode build.Mode
+
 	// Shared Index Store cache across all streams.
 	// When any stream rebuilds, it updates this cache so other streams
 	// see fresh type/reference data without a stale in-memory snapshot.
 	indexCache *sharedIndexCache
 
 	// Shared file watcher (set by RunServe before starting command loop).
+	// Guarded by mu because a project switch replaces it.
 	watcher *watch.SharedWatcher
 
+	// newWatcher creates a SharedWatcher rooted at the given directory.
+	// Used to restart the watcher when the active project changes.
+	// When nil, project switches leave the stream manager without a watcher.
+	newWatcher func(root string) (*watch.SharedWatcher, error)
+
 	// Incremental thunk configuration.
 	maxThunkFiles int // max tracked files for incremental thunk
 	preThunkDepth int // initial thunk generation depth
 
+	// Accessibility overrides applied to every stream's device.
+	accessibility platform.AccessibilityOverrides
+
+	// Status bar override applied to every stream's device (nil = unchanged).
+	statusBar *platform.StatusBarOverride
+
+	// bootTimeout bounds the wait for a stream's device to reach "Booted"
+	// (0 = idb.DefaultBootTimeout).
+	bootTimeout time.Duration
+
+	// warm keeps simulators booted ahead of AddStream (nil = boot on demand).
+	warm *warmPool
+
+	// companions runs the video/HID idb_companion of each device, shared by
+	// the streams on it.
+	companions *idb.CompanionPool
+
+	// Preview-only Swift files compiled into every stream's thunk.
+	mockSources []string
+
+	// Extra modules imported by every stream's thunk.
+	thunkImports []string
+
+	// Directory copied into every stream's app data container before launch.
+	seedDir string
+
+	// Keep thunk sources instead of cleaning them up (--keep-thunk).
+	keepThunk bool
+
+	// iPad layout and size class every stream renders with.
+	layout codegen.Layout
+
+	// previewAll expands each AddStream into one stream per #Preview block
+	// in the file, all rendered on one device (see stream_group.go).
+	previewAll bool
+
+	// frameEncoding selects how Frame events carry images. File mode writes
+	// into each stream's staging directory.
+	frameEncoding protocol.FrameEncoding
+
+	// frameDiff adds the changed region to JPEG and PNG Frame events.
+	frameDiff bool
+
+	// pngCompression is the compression level of PNG Frame events.
+	pngCompression png.CompressionLevel
+
+	// startRecording starts a screen recording of a device for
+	// StartRecording. Defaults to startScreenRecording; tests override it.
+	startRecording func(device, deviceSetPath, path string) (screenRecording, error)
+
+	// listPreviews enumerates the #Preview blocks of a file for previewAll.
+	// Defaults to analysis.PreviewBlocks; tests override it.
+	listPreviews func(file string) ([]analysis.PreviewBlock, error)
+
+	// Idle shutdown (serve --idle-timeout): while no streams are active,
+	// idleTimer runs for idleTimeout and then calls idleShutdown. idle is
+	// set until the next AddStream restores the released resources.
+	// idleGen invalidates timers stopped after they fired. idleDrain is
+	// closed once idleShutdown has emptied the pool, and nil otherwise.
+	// Guarded by mu.
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+	idleGen     int
+	idle        bool
+	idleDrain   chan struct{}
+
+	// onIdle, if set, is called after an idle shutdown.
+	// Used by serve --idle-exit to exit.
+	onIdle func()
+
+	// onShutdown, if set, is called when a Shutdown command arrives.
+	// serveCommands uses it to end the session.
+	onShutdown func()
+
+	// shuttingDown is set once serveCommands starts tearing the session
+	// down. HandleCommand ignores commands from then on, so that nothing
+	// starts behind StopAll.
+	shuttingDown atomic.Bool
+
+	// sessionDirs holds the per-device session directories the streams
+	// of this session used. serveCommands removes them after a Shutdown.
+	// Guarded by mu.
+	sessionDirs map[string]struct{}
+
+	// onFrame, if set, is called after a stream emits a Frame event.
+	// Used by serve --once to detect the first frame.
+	onFrame func(streamID string)
+
 	// Injected runners for testability.
 	build     BuildRunner
 	toolchain ToolchainRunner
@@ -127,6 +409,15 @@ type StreamManager struct {
 	copier    FileCopier
 	sources   SourceLister
 
+	// logs streams each stream's app log as LogStream events after launch.
+	// Nil (the default) leaves app logs off.
+	logs LogStreamer
+
+	// crashes, when set, turns a stream whose app crashes on launch into a
+	// StreamStopped with reason "app_crashed" and the crashed thread's
+	// backtrace. Nil skips the check.
+	crashes CrashReporter
+
 	// StreamLauncher is called per-stream in a goroutine.
 	// It should block until the stream ends (context cancelled or error).
 	// The default implementation performs the full preview lifecycle
@@ -139,97 +430,564 @@ func NewStreamManager(pool DevicePoolInterface, ew *protocol.EventWriter, pc Pro
 	preparer *build.Preparer, br BuildRunner, tc ToolchainRunner, ar AppRunner, fc FileCopier, sl SourceLister,
 	strict bool, maxThunkFiles, preThunkDepth int) *StreamManager {
 	sm := &StreamManager{
-		streams:       make(map[string]*stream),
-		pool:          pool,
-		ew:            ew,
-		strict:        strict,
-		pc:            pc,
-		deviceSetPath: deviceSetPath,
-		preparer:      preparer,
-		indexCache:    newSharedIndexCache(nil),
-		maxThunkFiles: maxThunkFiles,
-		preThunkDepth: preThunkDepth,
-		build:         br,
-		toolchain:     tc,
-		app:           ar,
-		copier:        fc,
-		sources:       sl,
+		streams:        make(map[string]*stream),
+		groups:         make(map[string][]string),
+		sessionDirs:    make(map[string]struct{}),
+		listPreviews:   analysis.PreviewBlocks,
+		startRecording: startScreenRecording,
+		pool:           pool,
+		ew:             ew,
+		strict:         strict,
+		pc:             pc,
+		deviceSetPath:  deviceSetPath,
+		preparer:       preparer,
+		indexCache:     newSharedIndexCache(nil),
+		companions:     idb.NewCompanionPool(idb.DefaultCommander(), idb.Options{}, companionIdleTTL),
+		maxThunkFiles:  maxThunkFiles,
+		preThunkDepth:  preThunkDepth,
+		build:          br,
+		toolchain:      tc,
+		app:            ar,
+		copier:         fc,
+		sources:        sl,
 	}
 	sm.StreamLauncher = sm.defaultStreamLauncher
+	sm.observeBuilds(preparer)
 	return sm
 }
 
+// observeBuilds makes preparer report each successful shared build with a
+// project-scoped BuildComplete event.
+func (sm *StreamManager) observeBuilds(preparer *build.Preparer) {
+	if preparer == nil {
+		return
+	}
+	project := sm.pc.PrimaryPath()
+	preparer.OnBuilt = func(res *build.Result) {
+		bc := &pb.BuildComplete{Project: project, Warnings: buildDiagnostics(res.Warnings)}
+		if err := sm.ew.Send(&pb.Event{Payload: &pb.Event_BuildComplete{BuildComplete: bc}}); err != nil {
+			slog.Warn("Failed to send BuildComplete", "err", err)
+		}
+	}
+}
+
 // HandleCommand dispatches a Command to the appropriate stream.
+// Commands arriving after the session began shutting down are ignored.
 func (sm *StreamManager) HandleCommand(ctx context.Context, cmd *pb.Command) {
+	if sm.shuttingDown.Load() {
+		slog.Warn("Ignoring command: serve is shutting down", "streamId", cmd.GetStreamId())
+		return
+	}
+	streamID, ok := sm.groupCommandTarget(cmd)
+	if !ok {
+		return
+	}
 	switch {
 	case cmd.GetAddStream() != nil:
-		sm.handleAddStream(ctx, cmd.GetStreamId(), cmd.GetAddStream())
+		sm.handleAddStream(ctx, streamID, cmd.GetAddStream())
 	case cmd.GetRemoveStream() != nil:
-		sm.handleRemoveStream(cmd.GetStreamId())
+		sm.handleRemoveStream(streamID)
 	case cmd.GetSwitchFile() != nil:
-		sm.handleSwitchFile(cmd.GetStreamId(), cmd.GetSwitchFile())
+		sm.handleSwitchFile(streamID, cmd.GetSwitchFile())
 	case cmd.GetNextPreview() != nil:
-		sm.handleNextPreview(cmd.GetStreamId())
+		sm.handleNextPreview(streamID)
 	case cmd.GetForceRebuild() != nil:
-		sm.handleForceRebuild(cmd.GetStreamId())
+		sm.handleForceRebuild(streamID)
+	case cmd.GetReload() != nil:
+		sm.handleReload(streamID)
 	case cmd.GetInput() != nil:
-		sm.handleInput(cmd.GetStreamId(), cmd.GetInput())
+		sm.handleInput(streamID, cmd.GetInput())
+	case cmd.GetSetWatch() != nil:
+		sm.handleSetWatch(streamID, cmd.GetSetWatch())
+	case cmd.GetListDevices() != nil:
+		sm.handleListDevices(ctx, streamID)
+	case cmd.GetPoolStatus() != nil:
+		sm.handlePoolStatus(streamID)
+	case cmd.GetSetDevice() != nil:
+		sm.handleSetDevice(ctx, streamID, cmd.GetSetDevice())
+	case cmd.GetStartRecording() != nil:
+		sm.handleStartRecording(streamID, cmd.GetStartRecording())
+	case cmd.GetStopRecording() != nil:
+		sm.handleStopRecording(streamID)
+	case cmd.GetShutdown() != nil:
+		sm.handleShutdown()
 	default:
-		slog.Warn("Command has no payload", "streamId", cmd.GetStreamId())
+		slog.Warn("Command has no payload", "streamId", streamID)
 	}
 }
 
 func (sm *StreamManager) handleAddStream(ctx context.Context, streamID string, add *pb.AddStream) {
 	sm.mu.Lock()
+	s, isStream := sm.streams[streamID]
+	_, isGroup := sm.groups[streamID]
+	sm.mu.Unlock()
+	switch {
+	case (isStream || isGroup) && sm.rejectDuplicates:
+		slog.Warn("Duplicate streamId in AddStream, ignoring", "streamId", streamID)
+		return
+	case isStream:
+		sm.updateStream(ctx, s, add)
+		return
+	case isGroup:
+		// The file's set of previews may have changed, so re-expand.
+		slog.Info("Restarting preview-all streams for AddStream update", "streamId", streamID)
+		sm.stopStreams(streamID)
+	}
+
+	var blocks []analysis.PreviewBlock
+	var blocksErr error
+	preview := 0
+	if sm.previewAll {
+		blocks, blocksErr = sm.listPreviews(add.GetFile())
+		if blocksErr == nil && len(blocks) == 0 {
+			blocksErr = fmt.Errorf("no #Preview blocks found in %s", add.GetFile())
+		}
+	} else if add.GetPreview() != "" {
+		preview, blocksErr = sm.previewIndex(add.GetFile(), add.GetPreview())
+	}
+
+	sm.mu.Lock()
+	pc, err := sm.resolvedProjectLocked(ctx, add)
 	if _, exists := sm.streams[streamID]; exists {
 		sm.mu.Unlock()
 		slog.Warn("Duplicate streamId in AddStream, ignoring", "streamId", streamID)
 		return
 	}
 
-	streamCtx, cancel := context.WithCancel(ctx)
-	s := &stream{
-		id:             streamID,
-		file:           add.GetFile(),
-		deviceType:     add.GetDeviceType(),
-		runtime:        add.GetRuntime(),
-		cancel:         cancel,
-		done:           make(chan struct{}),
-		switchFileCh:   make(chan string, 1),
-		nextPreviewCh:  make(chan struct{}, 1),
-		forceRebuildCh: make(chan struct{}, 1),
-		inputCh:        make(chan *pb.Input, 1),
-		fileChangeCh:   make(chan string, 1),
-	}
-	sm.streams[streamID] = s
+	if err == nil && pc != sm.pc {
+		if len(sm.streams) > 0 {
+			err = fmt.Errorf("cannot switch project to %s while %d stream(s) of %s are active",
+				pc.PrimaryPath(), len(sm.streams), sm.pc.PrimaryPath())
+		} else {
+			err = sm.switchProjectLocked(pc)
+		}
+	}
+	if err == nil {
+		err = blocksErr
+	}
+	if err == nil {
+		err = platform.AccessibilityOverrides{Appearance: add.GetAppearance(), Locale: add.GetLocale(), Language: add.GetLanguage()}.Validate()
+	}
+	if err != nil {
+		sm.mu.Unlock()
+		slog.Warn("Rejecting AddStream", "streamId", streamID, "err", err)
+		(&stream{id: streamID}).sendStopped(sm.ew, "resource_error", err.Error(), "")
+		return
+	}
+
+	if !sm.previewAll {
+		sm.startStreamLocked(ctx, &stream{id: streamID, preview: preview}, add)
+		sm.mu.Unlock()
+		return
+	}
+
+	ids := make([]string, len(blocks))
+	members := make([]*stream, len(blocks))
+	for i, b := range blocks {
+		ids[i] = previewStreamID(streamID, i)
+		members[i] = &stream{id: ids[i], preview: i, label: previewLabel(i, b), group: streamID}
+		if i > 0 {
+			members[i].host = members[0]
+		}
+	}
+	members[0].members = members
+	for _, m := range members {
+		sm.startStreamLocked(ctx, m, add)
+	}
+	sm.groups[streamID] = ids
 	sm.mu.Unlock()
+	slog.Info("Expanded AddStream into per-preview streams", "streamId", streamID, "streams", len(ids))
+}
+
+// updateStream applies an AddStream to the already running stream s with the
+// fewest changes. A different device, runtime, codec, appearance, or
+// project restarts the stream; a different file or preview is handed to the
+// stream's event loop, which switches in place and rebuilds only if
+// hot-reload is not enough. A different locale or language relaunches the
+// app. An explicit watch setting is applied as SetWatch would.
+func (sm *StreamManager) updateStream(ctx context.Context, s *stream, add *pb.AddStream) {
+	if s.group != "" {
+		slog.Warn("AddStream for a preview-all stream, ignoring; update its group instead", "streamId", s.id, "group", s.group)
+		return
+	}
+
+	sm.mu.Lock()
+	pc, err := sm.requestedProject(add)
+	if err == nil && pc != sm.pc && len(sm.streams) > 1 {
+		err = fmt.Errorf("cannot switch project to %s while %d other stream(s) of %s are active",
+			pc.PrimaryPath(), len(sm.streams)-1, sm.pc.PrimaryPath())
+	}
+	codec, _ := sm.streamCodec(add, s.group)
+	restart := pc != sm.pc || add.GetDeviceType() != s.deviceType || add.GetRuntime() != s.runtime || codec != s.codec ||
+		add.GetAppearance() != s.appearance
+	if err == nil {
+		err = platform.AccessibilityOverrides{Locale: add.GetLocale(), Language: add.GetLanguage()}.Validate()
+	}
+	relaunch := add.GetLocale() != s.locale || add.GetLanguage() != s.language
+	sm.mu.Unlock()
+	if err != nil {
+		slog.Warn("Rejecting AddStream update", "streamId", s.id, "err", err)
+		return
+	}
+
+	if restart {
+		slog.Info("Restarting stream for AddStream update", "streamId", s.id)
+		sm.stopStreams(s.id)
+		sm.handleAddStream(ctx, s.id, add)
+		return
+	}
+
+	if add.Watch != nil {
+		s.setWatch(add.GetWatch())
+	}
+
+	upd := streamUpdate{file: add.GetFile(), preview: -1}
+	if sel := add.GetPreview(); sel != "" {
+		idx, err := sm.previewIndex(upd.file, sel)
+		if err != nil {
+			slog.Warn("Rejecting AddStream update", "streamId", s.id, "err", err)
+			return
+		}
+		upd.preview = idx
+	}
+	accessibility := sm.streamAccessibility(add)
+	if relaunch {
+		sm.mu.Lock()
+		s.locale, s.language = add.GetLocale(), add.GetLanguage()
+		sm.mu.Unlock()
+		upd.accessibility = &accessibility
+	}
+
+	s.queueUpdate(upd)
+	if err := sm.ew.Send(&pb.Event{
+		StreamId: s.id,
+		Payload:  &pb.Event_StreamStatus{StreamStatus: newStreamStatus("updating", accessibility, sm.layout)},
+	}); err != nil {
+		slog.Warn("Failed to send updating status", "streamId", s.id, "err", err)
+	}
+}
+
+// queueUpdate hands upd to the stream's event loop. An update the loop has
+// not picked up yet is replaced, so the latest AddStream wins; a relaunch
+// it asked for is kept.
+func (s *stream) queueUpdate(upd streamUpdate) {
+	for {
+		select {
+		case s.updateCh <- upd:
+			return
+		default:
+		}
+		select {
+		case old := <-s.updateCh:
+			slog.Debug("Replacing pending AddStream update", "streamId", s.id)
+			if upd.accessibility == nil {
+				upd.accessibility = old.accessibility
+			}
+		default:
+		}
+	}
+}
+
+// previewIndex resolves a preview selector (title, 0-based index, or
+// /regex/) to the index of the matching #Preview block in file.
+func (sm *StreamManager) previewIndex(file, selector string) (int, error) {
+	blocks, err := sm.listPreviews(file)
+	if err != nil {
+		return 0, err
+	}
+	b, err := analysis.SelectPreview(blocks, selector)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", file, err)
+	}
+	return slices.Index(blocks, b), nil
+}
+
+// startStreamLocked fills in s from add, registers it, and starts its
+// goroutine. Must be called with sm.mu held.
+func (sm *StreamManager) startStreamLocked(ctx context.Context, s *stream, add *pb.AddStream) {
+	sm.wakeLocked()
+	streamCtx, cancel := context.WithCancel(ctx)
+	s.file = add.GetFile()
+	s.deviceType = add.GetDeviceType()
+	s.runtime = add.GetRuntime()
+	codec, fallback := sm.streamCodec(add, s.group)
+	if fallback != "" {
+		slog.Warn(fallback+", falling back", "streamId", s.id, "codec", add.GetCodec(), "fallback", codec)
+	}
+	s.codec = codec
+	s.watch = add.Watch == nil || add.GetWatch()
+	s.manualBuild = add.GetManualBuild()
+	s.appearance = add.GetAppearance()
+	s.locale = add.GetLocale()
+	s.language = add.GetLanguage()
+	s.accessibility = sm.streamAccessibility(add)
+	s.project = streamProject{pc: sm.pc, preparer: sm.preparer, indexCache: sm.indexCache}
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	s.switchFileCh = make(chan string, 1)
+	s.nextPreviewCh = make(chan struct{}, 1)
+	s.forceRebuildCh = make(chan struct{}, 1)
+	s.reloadCh = make(chan struct{}, 1)
+	s.inputCh = make(chan *pb.Input, 1)
+	s.fileChangeCh = make(chan string, 1)
+	s.updateCh = make(chan streamUpdate, 1)
+	sm.streams[s.id] = s
 
 	go sm.runStream(streamCtx, s)
 }
 
-func (sm *StreamManager) handleRemoveStream(streamID string) {
-	sm.mu.Lock()
-	s, exists := sm.streams[streamID]
-	if !exists {
+// streamCodec returns the codec of a stream in group (empty for none)
+// requested by add. Besides the codecs NegotiateCodec rejects, H.264 falls
+// back to JPEG where it cannot be delivered: preview-all frames are
+// screenshots, and file encoding keeps only the latest few frame files while
+// every H.264 chunk must reach the decoder. fallback says why the requested
+// codec was not used, or is empty.
+func (sm *StreamManager) streamCodec(add *pb.AddStream, group string) (codec protocol.FrameCodec, fallback string) {
+	codec, supported := protocol.NegotiateCodec(add.GetCodec())
+	switch {
+	case !supported:
+		return codec, "Requested frame codec is not supported"
+	case codec != protocol.CodecH264:
+		return codec, ""
+	case group != "":
+		return protocol.CodecJPEG, "Preview-all streams do not support h264"
+	case sm.frameEncoding == protocol.FrameEncodingFile:
+		return protocol.CodecJPEG, "File frame encoding does not support h264"
+	}
+	return codec, ""
+}
+
+// streamAccessibility returns serve's overrides with the appearance, locale
+// and language requested by add in place of serve's own.
+func (sm *StreamManager) streamAccessibility(add *pb.AddStream) platform.AccessibilityOverrides {
+	return platform.AccessibilityOverrides{Appearance: add.GetAppearance(), Locale: add.GetLocale(), Language: add.GetLanguage()}.Or(sm.accessibility)
+}
+
+// loadStreamSidecar returns the sidecar of file for stream id, or nil. It
+// ranks below serve's flags and AddStream, as it ranks below flags on the
+// command line; serve picks devices by type and runtime, so its DEVICE is
+// ignored. An invalid sidecar is logged and ignored rather than stopping
+// the stream.
+func loadStreamSidecar(id, file string) *Sidecar {
+	sc, err := LoadSidecar(file)
+	if err != nil {
+		slog.Warn("Ignoring preview sidecar", "streamId", id, "err", err)
+	}
+	return sc
+}
+
+// previewStreamID returns the streamId of the stream rendering preview
+// index i of the AddStream identified by parent.
+func previewStreamID(parent string, i int) string {
+	return parent + "#" + strconv.Itoa(i)
+}
+
+// previewLabel returns the Frame label for a preview block: its title, or
+// "#<index>" when the #Preview is untitled.
+func previewLabel(i int, b analysis.PreviewBlock) string {
+	if b.Title != "" {
+		return b.Title
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+// requestedProject returns the ProjectConfig an AddStream asks for.
+// Fields left empty fall back to the active project. The AddStream file
+// becomes AppSource, which picks the app of a multi-app scheme, when the
+// project changes or has none yet; later streams of the project share the
+// app built for the first one.
+// Must be called with sm.mu held.
+func (sm *StreamManager) requestedProject(add *pb.AddStream) (ProjectConfig, error) {
+	pc, err := sm.requestedBuild(add)
+	if err != nil {
+		return pc, err
+	}
+	pc.AppSource = sm.pc.AppSource
+	if pc != sm.pc || pc.AppSource == "" {
+		pc.AppSource = add.GetFile()
+	}
+	return pc, nil
+}
+
+// requestedBuild returns the project, scheme and configuration an
+// AddStream asks for, without AppSource.
+// Must be called with sm.mu held.
+func (sm *StreamManager) requestedBuild(add *pb.AddStream) (ProjectConfig, error) {
+	project, workspace := add.GetProject(), add.GetWorkspace()
+	if project == "" && workspace == "" && add.GetScheme() == "" && add.GetConfiguration() == "" {
+		return sm.pc, nil
+	}
+	if project != "" && workspace != "" {
+		return ProjectConfig{}, fmt.Errorf("project and workspace are mutually exclusive")
+	}
+	if project == "" && workspace == "" {
+		project, workspace = sm.pc.Project, sm.pc.Workspace
+	}
+	scheme := cmp.Or(add.GetScheme(), sm.pc.Scheme)
+	pc, err := NewProjectConfig(project, workspace, scheme, add.GetConfiguration())
+	if err != nil {
+		return pc, err
+	}
+	pc.Toolchain = sm.pc.Toolchain // --toolchain applies to every project
+	if pc.PrimaryPath() != sm.pc.PrimaryPath() {
+		return pc, nil
+	}
+	pc.App = sm.pc.App // --app-product names a product of the serve project
+	// The active configuration was chosen for the active scheme. Another
+	// scheme gets its own default from resolvedProjectLocked.
+	if pc.Configuration == "" && pc.Scheme == sm.pc.Scheme {
+		pc.Configuration = sm.pc.Configuration
+	}
+	return pc, nil
+}
+
+// resolvedProjectLocked returns requestedProject(add) with the scheme's
+// default build configuration filled in when add switches to a project or
+// scheme without one. Resolving may run xcodebuild, so sm.mu is released
+// meanwhile; if the active project changed by the time it is reacquired,
+// the request is resolved again against the new one.
+// Must be called with sm.mu held; it is held again on return.
+func (sm *StreamManager) resolvedProjectLocked(ctx context.Context, add *pb.AddStream) (ProjectConfig, error) {
+	for {
+		pc, err := sm.requestedProject(add)
+		active := sm.pc
+		active.AppSource = pc.AppSource
+		if err != nil || pc == active || pc.Configuration != "" {
+			return pc, err
+		}
+
 		sm.mu.Unlock()
+		resolveCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		resolved := build.ResolveConfiguration(resolveCtx, pc, sm.build)
+		cancel()
+		sm.mu.Lock()
+
+		if again, err := sm.requestedProject(add); err != nil || again == pc {
+			return resolved, err
+		}
+	}
+}
+
+// switchProjectLocked makes pc the active project. It replaces the
+// preparer, resets the shared index cache, and restarts the shared watcher
+// rooted at the new project directory. The old watcher is closed before the
+// new one is published, so no events from the old root reach new listeners.
+// A pc that only adds AppSource, as the first stream of serve does, keeps
+// the watcher. pc's configuration is resolved by resolvedProjectLocked.
+// Must be called with sm.mu held and no streams active.
+func (sm *StreamManager) switchProjectLocked(pc ProjectConfig) error {
+	dirs, err := build.NewProjectDirs(pc)
+	if err != nil {
+		return fmt.Errorf("resolving build directories: %w", err)
+	}
+
+	if root := filepath.Dir(pc.PrimaryPath()); sm.watcher == nil || root != filepath.Dir(sm.pc.PrimaryPath()) {
+		var newWatcher *watch.SharedWatcher
+		if sm.newWatcher != nil {
+			newWatcher, err = sm.newWatcher(root)
+			if err != nil {
+				return fmt.Errorf("creating shared file watcher: %w", err)
+			}
+		}
+		if sm.watcher != nil {
+			sm.watcher.Close()
+		}
+		sm.watcher = newWatcher
+	}
+
+	slog.Info("Switching active project", "from", sm.pc.PrimaryPath(), "to", pc.PrimaryPath(), "scheme", pc.Scheme)
+	sm.pc = pc
+	sm.preparer = build.NewPreparer(pc, dirs, sm.buildMode, sm.build)
+	sm.observeBuilds(sm.preparer)
+	sm.indexCache = newSharedIndexCache(nil)
+	return nil
+}
+
+// frameSent notifies the onFrame hook that streamID emitted a Frame event.
+func (sm *StreamManager) frameSent(streamID string) {
+	if sm.onFrame != nil {
+		sm.onFrame(streamID)
+	}
+}
+
+// streamDone returns a channel that is closed when the stream's goroutine
+// finishes. A stream that is not (or no longer) active yields a closed channel.
+func (sm *StreamManager) streamDone(streamID string) <-chan struct{} {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if s, ok := sm.streams[streamID]; ok {
+		return s.done
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+// currentWatcher returns the active shared watcher (nil if none).
+func (sm *StreamManager) currentWatcher() *watch.SharedWatcher {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.watcher
+}
+
+// closeWatcher stops the active shared watcher, if any.
+func (sm *StreamManager) closeWatcher() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.watcher != nil {
+		sm.watcher.Close()
+		sm.watcher = nil
+	}
+}
+
+func (sm *StreamManager) handleRemoveStream(streamID string) {
+	stopped := sm.stopStreams(streamID)
+	if len(stopped) == 0 {
 		slog.Warn("RemoveStream for unknown streamId", "streamId", streamID)
 		return
 	}
-	delete(sm.streams, streamID)
+	for _, s := range stopped {
+		s.sendStopped(sm.ew, "user_removed", "", "")
+	}
+}
+
+// stopStreams unregisters the stream streamID, or every stream of the
+// --preview-all group it names, cancels them, and waits for their cleanup.
+// It returns the stopped streams without sending StreamStopped, so callers
+// restarting a stream under the same ID can do so silently.
+func (sm *StreamManager) stopStreams(streamID string) []*stream {
+	sm.mu.Lock()
+	ids := []string{streamID}
+	if group, ok := sm.groups[streamID]; ok {
+		delete(sm.groups, streamID)
+		ids = group
+	}
+	var stopped []*stream
+	for _, id := range ids {
+		if s, ok := sm.streams[id]; ok {
+			delete(sm.streams, id)
+			stopped = append(stopped, s)
+		}
+	}
 	sm.mu.Unlock()
 
-	// Cancel the stream goroutine and wait for cleanup to finish.
+	// Cancel the stream goroutines and wait for cleanup to finish.
 	// Resource cleanup (device release, companion stop, etc.) is handled by
 	// runStream's defer chain, not here.
 	// A 30-second timeout prevents a hung stream from blocking the command loop.
-	s.cancel()
-	select {
-	case <-s.done:
-	case <-time.After(30 * time.Second):
-		slog.Error("Stream cleanup timed out, proceeding without waiting", "streamId", streamID)
+	// All are canceled first so that the streams of a --preview-all group
+	// see the stop rather than their host ending.
+	for _, s := range stopped {
+		s.cancel()
 	}
-
-	s.sendStopped(sm.ew, "removed", "", "")
+	for _, s := range stopped {
+		select {
+		case <-s.done:
+		case <-time.After(30 * time.Second):
+			slog.Error("Stream cleanup timed out, proceeding without waiting", "streamId", s.id)
+		}
+	}
+	return stopped
 }
 
 func (sm *StreamManager) handleSwitchFile(streamID string, sf *pb.SwitchFile) {
@@ -277,6 +1035,285 @@ func (sm *StreamManager) handleForceRebuild(streamID string) {
 	}
 }
 
+func (sm *StreamManager) handleReload(streamID string) {
+	sm.mu.Lock()
+	s, ok := sm.streams[streamID]
+	sm.mu.Unlock()
+	if !ok {
+		slog.Warn("Reload for unknown streamId", "streamId", streamID)
+		return
+	}
+	select {
+	case s.reloadCh <- struct{}{}:
+	default:
+		slog.Warn("Reload command dropped (stream busy)", "streamId", streamID)
+	}
+}
+
+func (sm *StreamManager) handleSetWatch(streamID string, sw *pb.SetWatch) {
+	sm.mu.Lock()
+	s, ok := sm.streams[streamID]
+	sm.mu.Unlock()
+	if !ok {
+		slog.Warn("SetWatch for unknown streamId", "streamId", streamID)
+		return
+	}
+	slog.Info("Setting file watching", "streamId", streamID, "enabled", sw.GetEnabled())
+	s.setWatch(sw.GetEnabled())
+}
+
+// handleShutdown asks the serve session to end (Shutdown command). The
+// teardown itself runs in serveCommands once the request is seen.
+func (sm *StreamManager) handleShutdown() {
+	if sm.onShutdown == nil {
+		slog.Warn("Ignoring Shutdown: no serve session to end")
+		return
+	}
+	slog.Info("Shutdown requested")
+	sm.onShutdown()
+}
+
+// trackSessionDir records a session directory for removeSessionDirs.
+func (sm *StreamManager) trackSessionDir(dir string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.sessionDirs[dir] = struct{}{}
+}
+
+// removeSessionDirs deletes the session directories used by the streams of
+// this session: their thunks, loader and staged frames. Call it after
+// StopAll, when no stream uses them any more.
+func (sm *StreamManager) removeSessionDirs() {
+	sm.mu.Lock()
+	dirs := slices.Collect(maps.Keys(sm.sessionDirs))
+	clear(sm.sessionDirs)
+	sm.mu.Unlock()
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("Failed to remove session directory", "path", dir, "err", err)
+		}
+	}
+}
+
+// handleListDevices sends a DeviceList of the simulators in the device set,
+// marking the ones this session's streams run on.
+func (sm *StreamManager) handleListDevices(ctx context.Context, streamID string) {
+	list := &pb.DeviceList{}
+	devices, err := sm.pool.Devices(ctx)
+	if err != nil {
+		slog.Warn("Failed to list devices", "err", err)
+		list.Error = err.Error()
+	}
+
+	sm.mu.Lock()
+	streamOn := make(map[string]string, len(sm.streams))
+	for id, s := range sm.streams {
+		if udid := cmp.Or(s.deviceUDID, s.pinnedUDID); udid != "" {
+			streamOn[udid] = id
+		}
+	}
+	sm.mu.Unlock()
+
+	for _, d := range devices {
+		list.Devices = append(list.Devices, &pb.Device{
+			Udid:       d.UDID,
+			Name:       d.Name,
+			State:      d.State,
+			DeviceType: d.DeviceType,
+			Runtime:    d.Runtime,
+			StreamId:   streamOn[d.UDID],
+			InUse:      d.InUse,
+		})
+	}
+	if err := sm.ew.Send(&pb.Event{StreamId: streamID, Payload: &pb.Event_DeviceList{DeviceList: list}}); err != nil {
+		slog.Warn("Failed to send DeviceList", "err", err)
+	}
+}
+
+// handlePoolStatus sends a CompanionPoolStatus of the video/HID companions
+// shared by the streams.
+func (sm *StreamManager) handlePoolStatus(streamID string) {
+	status := &pb.CompanionPoolStatus{}
+	for _, c := range sm.companions.Status() {
+		status.Companions = append(status.Companions, &pb.CompanionStatus{
+			Udid:          c.UDID,
+			Address:       c.Address,
+			Refs:          int32(c.Refs),
+			Starting:      c.Starting,
+			UptimeSeconds: c.Uptime.Seconds(),
+			Restarts:      int32(c.Restarts),
+			LastError:     c.LastError,
+		})
+	}
+	if err := sm.ew.Send(&pb.Event{StreamId: streamID, Payload: &pb.Event_CompanionPoolStatus{CompanionPoolStatus: status}}); err != nil {
+		slog.Warn("Failed to send CompanionPoolStatus", "err", err)
+	}
+}
+
+// handleSetDevice restarts a stream on the device sd names, keeping its
+// file, preview, codec, and watch setting. The stream takes on the
+// device's type and runtime, so a later AddStream with the stream's
+// original device type moves it back to a pooled device.
+func (sm *StreamManager) handleSetDevice(ctx context.Context, streamID string, sd *pb.SetDevice) {
+	sm.mu.Lock()
+	s, ok := sm.streams[streamID]
+	sm.mu.Unlock()
+	if !ok {
+		sm.rejectCommand(streamID, "SetDevice", "unknown stream")
+		return
+	}
+	udid := sd.GetUdid()
+	if udid == "" || udid == cmp.Or(s.deviceUDID, s.pinnedUDID) {
+		return
+	}
+
+	devices, err := sm.pool.Devices(ctx)
+	if err != nil {
+		sm.rejectCommand(streamID, "SetDevice", fmt.Sprintf("listing devices: %v", err))
+		return
+	}
+	i := slices.IndexFunc(devices, func(d platform.PoolDevice) bool { return d.UDID == udid })
+	if i < 0 {
+		sm.rejectCommand(streamID, "SetDevice", fmt.Sprintf("device %s is not in the device set", udid))
+		return
+	}
+	d := devices[i]
+
+	sm.mu.Lock()
+	for id, other := range sm.streams {
+		if id != streamID && cmp.Or(other.deviceUDID, other.pinnedUDID) == udid {
+			sm.mu.Unlock()
+			sm.rejectCommand(streamID, "SetDevice", fmt.Sprintf("device %s is used by stream %s", udid, id))
+			return
+		}
+	}
+	s.watchMu.Lock()
+	watching := s.watch
+	s.watchMu.Unlock()
+	add := &pb.AddStream{
+		File:        s.file,
+		DeviceType:  d.DeviceType,
+		Runtime:     d.Runtime,
+		Codec:       string(s.codec),
+		Watch:       &watching,
+		ManualBuild: s.manualBuild,
+		Appearance:  s.appearance,
+		Locale:      s.locale,
+		Language:    s.language,
+	}
+	preview := s.preview
+	sm.mu.Unlock()
+
+	slog.Info("Moving stream to device", "streamId", streamID, "udid", udid, "name", d.Name)
+	sm.stopStreams(streamID)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, exists := sm.streams[streamID]; exists {
+		slog.Warn("Stream was re-added during SetDevice, keeping it", "streamId", streamID)
+		return
+	}
+	sm.startStreamLocked(ctx, &stream{id: streamID, preview: preview, pinnedUDID: udid}, add)
+}
+
+// rejectCommand logs a rejected command and reports the reason to the
+// client with a ProtocolError carrying the command's stream ID.
+func (sm *StreamManager) rejectCommand(streamID, command, reason string) {
+	slog.Warn("Rejecting "+command, "streamId", streamID, "reason", reason)
+	msg := fmt.Sprintf("%s rejected: %s", command, reason)
+	if err := sm.ew.Send(&pb.Event{StreamId: streamID, Payload: &pb.Event_ProtocolError{ProtocolError: &pb.ProtocolError{Message: msg}}}); err != nil {
+		slog.Warn("Failed to send ProtocolError", "err", err)
+	}
+}
+
+// handleStartRecording starts recording the stream's device to the path sr
+// names. The result is reported with Recording events, including when the
+// request is rejected.
+func (sm *StreamManager) handleStartRecording(streamID string, sr *pb.StartRecording) {
+	path := sr.GetPath()
+	sm.mu.Lock()
+	s, ok := sm.streams[streamID]
+	var reason string
+	switch {
+	case !ok:
+		reason = "unknown stream"
+	case !filepath.IsAbs(path):
+		reason = "path must be absolute"
+	case s.deviceUDID == "":
+		reason = "stream has no device yet"
+	case s.recording != nil:
+		reason = "stream is already recording"
+	}
+	var device string
+	if ok {
+		device = s.deviceUDID
+	}
+	sm.mu.Unlock()
+	if reason != "" {
+		slog.Warn("Rejecting StartRecording", "streamId", streamID, "path", path, "reason", reason)
+		sm.sendRecording(streamID, path, false, errors.New(reason))
+		return
+	}
+
+	// Commands are handled one at a time, so no other StartRecording can
+	// slip in while the lock is released.
+	rec, err := sm.startRecording(device, sm.deviceSetPath, path)
+	if err != nil {
+		slog.Warn("Failed to start recording", "streamId", streamID, "path", path, "err", err)
+		sm.sendRecording(streamID, path, false, err)
+		return
+	}
+	sm.mu.Lock()
+	s.recording = rec
+	sm.mu.Unlock()
+	slog.Info("Recording stream", "streamId", streamID, "path", path)
+	sm.sendRecording(streamID, path, true, nil)
+
+	// Report the end of the recording however it comes about: StopRecording,
+	// stream cleanup, or the recorder exiting on its own.
+	go func() {
+		<-rec.Done()
+		err := rec.Stop()
+		sm.mu.Lock()
+		if s.recording == rec {
+			s.recording = nil
+		}
+		sm.mu.Unlock()
+		if err != nil {
+			slog.Warn("Screen recording failed", "streamId", streamID, "path", path, "err", err)
+		}
+		sm.sendRecording(streamID, path, false, err)
+	}()
+}
+
+// handleStopRecording ends the stream's recording. Finalizing the file can
+// take a moment, so it happens in the background and the Recording event
+// is sent once it is done.
+func (sm *StreamManager) handleStopRecording(streamID string) {
+	sm.mu.Lock()
+	var rec screenRecording
+	if s, ok := sm.streams[streamID]; ok {
+		rec = s.recording
+	}
+	sm.mu.Unlock()
+	if rec == nil {
+		slog.Warn("StopRecording for a stream that is not recording", "streamId", streamID)
+		return
+	}
+	go func() { _ = rec.Stop() }()
+}
+
+// sendRecording sends a Recording event for the stream's recording to path.
+func (sm *StreamManager) sendRecording(streamID, path string, active bool, err error) {
+	r := &pb.Recording{Path: path, Active: active}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if err := sm.ew.Send(&pb.Event{StreamId: streamID, Payload: &pb.Event_Recording{Recording: r}}); err != nil {
+		slog.Warn("Failed to send Recording", "streamId", streamID, "err", err)
+	}
+}
+
 func (sm *StreamManager) handleInput(streamID string, input *pb.Input) {
 	sm.mu.Lock()
 	s, ok := sm.streams[streamID]
@@ -295,14 +1332,18 @@ func (sm *StreamManager) handleInput(streamID string, input *pb.Input) {
 // runStream executes the stream lifecycle in a goroutine with panic recovery
 // and coordinated cleanup.
 func (sm *StreamManager) runStream(ctx context.Context, s *stream) {
+	defer sm.startIdleTimer() // after cleanup, if this was the last stream
 	defer close(s.done)
 	defer s.cancel() // Ensure launcher goroutines (e.g. RelayVideoStreamEvents) stop on normal return.
 	defer sm.cleanupStreamResources(s)
 	defer func() {
-		// Self-remove from map. If handleRemoveStream already deleted us,
-		// this is a no-op.
+		// Self-remove from map. If stopStreams already deleted us, or a
+		// restarted stream now owns the ID, the map is left alone.
 		sm.mu.Lock()
-		delete(sm.streams, s.id)
+		if cur, ok := sm.streams[s.id]; !ok || cur == s {
+			delete(sm.streams, s.id)
+			sm.leaveGroupLocked(s)
+		}
 		sm.mu.Unlock()
 	}()
 	defer func() {
@@ -312,27 +1353,73 @@ func (sm *StreamManager) runStream(ctx context.Context, s *stream) {
 		}
 	}()
 
+	if s.host != nil {
+		sm.runGroupMember(ctx, s)
+		return
+	}
 	sm.StreamLauncher(ctx, sm, s)
 }
 
+// leaveGroupLocked drops s from its --preview-all group, forgetting the
+// group once its last stream is gone. Must be called with sm.mu held.
+func (sm *StreamManager) leaveGroupLocked(s *stream) {
+	ids, ok := sm.groups[s.group]
+	if s.group == "" || !ok {
+		return
+	}
+	ids = slices.DeleteFunc(ids, func(id string) bool { return id == s.id })
+	if len(ids) == 0 {
+		delete(sm.groups, s.group)
+		return
+	}
+	sm.groups[s.group] = ids
+}
+
 // cleanupStreamResources releases all per-stream resources. Each nil check
 // makes this function idempotent and safe when called from partial initialization.
 func (sm *StreamManager) cleanupStreamResources(s *stream) {
 	s.cleanupOnce.Do(func() {
-		// Unregister from shared watcher.
-		if sm.watcher != nil {
-			sm.watcher.RemoveListener(s.id)
+		// Unregister from the watcher this stream registered on. After a
+		// project switch that watcher is already closed, which is harmless.
+		s.unregisterWatcher()
+
+		// Finalize a recording before the app and device go away.
+		sm.mu.Lock()
+		rec := s.recording
+		sm.mu.Unlock()
+		if rec != nil {
+			_ = rec.Stop() // reported by the goroutine started with the recording
 		}
 
 		// Terminate the app on the device.
 		if s.deviceUDID != "" {
-			if p := sm.preparer.Cached(); p != nil {
+			if p := s.project.preparer.Cached(); p != nil {
 				cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 30*time.Second)
 				terminateApp(cleanupCtx, p.Settings, s.deviceUDID, sm.deviceSetPath, sm.app)
 				cleanupCancel()
 			}
 		}
 
+		// Restore the accessibility settings and clear the status bar
+		// override of the device released below while it is still booted,
+		// so that a device pinned with SetDevice, or pooled for the next
+		// stream, is left as it was. A stream that never got to override
+		// them leaves them alone.
+		if s.overrides != nil {
+			cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			restoreAccessibility(cleanupCtx, s.overrides)
+			cleanupCancel()
+		}
+		if s.deviceUDID != "" && s.statusBarSet {
+			cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := platform.ClearStatusBar(cleanupCtx, s.deviceUDID, sm.deviceSetPath); err != nil {
+				slog.Debug("Failed to clear status bar override", "streamId", s.id, "err", err)
+			}
+			cleanupCancel()
+		}
+
+		s.removeFrameFiles()
+
 		// Remove loader socket.
 		if s.dirs.Socket != "" {
 			if err := os.Remove(s.dirs.Socket); err != nil && !os.IsNotExist(err) {
@@ -347,7 +1434,7 @@ func (sm *StreamManager) cleanupStreamResources(s *stream) {
 			}
 		}
 
-		// Stop idb companion (video/HID).
+		// Release the idb companion (video/HID).
 		if s.idbCompanion != nil {
 			if err := s.idbCompanion.Stop(); err != nil {
 				slog.Debug("Failed to stop idb companion", "streamId", s.id, "err", err)
@@ -372,32 +1459,119 @@ func (sm *StreamManager) cleanupStreamResources(s *stream) {
 	})
 }
 
+// acquireDevice obtains the device s runs on: the one pinned by SetDevice,
+// or any pooled device of its type and runtime, preferring one the warm
+// pool has already booted. It returns the boot companion of a pre-booted
+// device, nil otherwise. It records the device in s.deviceUDID under sm.mu,
+// since ListDevices and SetDevice read it from the command loop. A running
+// idle shutdown is waited for first.
+func (sm *StreamManager) acquireDevice(ctx context.Context, s *stream) (string, companionProcess, error) {
+	if err := sm.waitIdleDrain(ctx); err != nil {
+		return "", nil, err
+	}
+	var companion companionProcess
+	udid := s.pinnedUDID
+	if udid != "" {
+		var warm bool
+		if sm.warm != nil {
+			companion, warm = sm.warm.claimUDID(udid)
+		}
+		if !warm {
+			if err := sm.pool.AcquireUDID(ctx, udid); err != nil {
+				return "", nil, err
+			}
+		}
+	} else {
+		if sm.warm != nil {
+			if d, ok := sm.warm.claim(s.deviceType, s.runtime); ok {
+				udid, companion = d.udid, d.companion
+			}
+		}
+		if udid == "" {
+			var err error
+			if udid, err = sm.pool.Acquire(ctx, s.deviceType, s.runtime); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	sm.mu.Lock()
+	s.deviceUDID = udid
+	sm.mu.Unlock()
+	return udid, companion, nil
+}
+
+// followStreamLogs forwards the app log of s on udid as LogStream events
+// until ctx is done. It first stops following the log of an earlier
+// launch of s, so that a relaunch after a build fix does not forward each
+// line twice. SetDevice replaces the stream, so the stream it starts
+// follows the log of the new device from its own launch.
+func (sm *StreamManager) followStreamLogs(ctx context.Context, s *stream, udid string, bs *build.Settings) {
+	if s.stopLogs != nil {
+		s.stopLogs()
+	}
+	logCtx, cancel := context.WithCancel(ctx)
+	s.stopLogs = cancel
+	go followAppLogs(logCtx, sm.logs, udid, sm.deviceSetPath, bs, func(l appLogLine) {
+		if err := sm.ew.Send(&pb.Event{StreamId: s.id, Payload: &pb.Event_LogStream{LogStream: l.event()}}); err != nil {
+			slog.Warn("Failed to send LogStream", "streamId", s.id, "err", err)
+		}
+	})
+}
+
 // defaultStreamLauncher is the production stream lifecycle.
 // Steps: Boot → Build → Install → Launch → Video relay → event loop.
+// When the build fails, the stream stays alive and launches again once
+// the user has had a chance to fix it (see awaitBuildFix).
 func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamManager, s *stream) {
+	for sm.launchStream(ctx, s) {
+		if !sm.awaitBuildFix(ctx, s) {
+			return
+		}
+	}
+}
+
+// launchStream runs one launch attempt of s and blocks in the stream's
+// event loop once it is up. It returns true when the attempt failed to
+// build, after sending BuildFailed; every other failure stops the stream.
+func (sm *StreamManager) launchStream(ctx context.Context, s *stream) bool {
+	// The host of a --preview-all group reports its launch on every
+	// stream of the group.
 	sendStatus := func(phase string) {
-		if err := sm.ew.Send(&pb.Event{StreamId: s.id, Payload: &pb.Event_StreamStatus{StreamStatus: &pb.StreamStatus{Phase: phase}}}); err != nil {
-			slog.Warn("Failed to send StreamStatus", "streamId", s.id, "phase", phase, "err", err)
+		for _, m := range sm.groupStreams(s) {
+			if err := sm.ew.Send(&pb.Event{StreamId: m.id, Payload: &pb.Event_StreamStatus{StreamStatus: newStreamStatus(phase, s.accessibility, sm.layout)}}); err != nil {
+				slog.Warn("Failed to send StreamStatus", "streamId", m.id, "phase", phase, "err", err)
+			}
 		}
 	}
 
-	// 1. Acquire a device from pool.
+	// 1. Acquire a device from pool, unless s still holds the one of a
+	// launch that failed to build.
 	sendStatus("booting")
 
-	udid, err := sm.pool.Acquire(ctx, s.deviceType, s.runtime)
-	if err != nil {
-		s.sendStopped(sm.ew, "resource_error", fmt.Sprintf("acquiring device: %v", err), "")
-		return
+	udid := s.deviceUDID
+	var warmCompanion companionProcess
+	if udid == "" {
+		var err error
+		if udid, warmCompanion, err = sm.acquireDevice(ctx, s); err != nil {
+			s.sendStopped(sm.ew, "resource_error", fmt.Sprintf("acquiring device: %v", err), "")
+			return false
+		}
 	}
-	s.deviceUDID = udid
 
 	// 2. Create per-stream preview directories.
-	dirs, err := newPreviewDirs(sm.pc.PrimaryPath(), udid)
+	proj := s.project
+	dirs, err := newPreviewDirs(proj.pc, udid)
 	if err != nil {
+		if warmCompanion != nil {
+			s.bootCompanion = warmCompanion // stopped by cleanupStreamResources
+		}
 		s.sendStopped(sm.ew, "resource_error", err.Error(), "")
-		return
+		return false
 	}
 	s.dirs = dirs
+	sm.trackSessionDir(dirs.Session)
+	sidecar := loadStreamSidecar(s.id, s.file)
+	s.accessibility = WithDeviceProfile(sidecar.streamOverrides(s.accessibility), udid)
 
 	launcherCtx, launcherCancel := context.WithCancel(ctx)
 	defer launcherCancel()
@@ -420,10 +1594,15 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 	bootResCh := make(chan bootResult, 1)
 	compileResCh := make(chan compileResult, 1)
 
-	// 3. Boot simulator in parallel with build/compile preparation.
+	// 3. Boot simulator in parallel with build/compile preparation, unless
+	// the warm pool already did.
 	go func() {
+		if warmCompanion != nil {
+			bootResCh <- bootResult{companion: warmCompanion}
+			return
+		}
 		var res bootResult
-		res.companion, res.err = bootWithRetry(launcherCtx, udid, sm.deviceSetPath, true)
+		res.companion, res.err = bootWithRetry(launcherCtx, udid, sm.deviceSetPath, true, sm.bootTimeout)
 		if res.err != nil {
 			res.err = fmt.Errorf("booting simulator: %w", res.err)
 		}
@@ -436,11 +1615,18 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 
 		// Prepare: fetch settings + build (if needed) + extract compiler paths.
 		// Preparer caches the result so only the first stream pays the cost.
-		prepared, err := sm.preparer.Prepare(launcherCtx)
+		// Only a failed xcodebuild can be fixed by editing the sources;
+		// anything else (unreadable settings, a missing scheme) stops the
+		// stream with its own error.
+		prepared, err := proj.preparer.Prepare(launcherCtx)
 		if err != nil {
-			res.buildFailed = true
-			res.buildDiag = err.Error()
-			res.err = fmt.Errorf("build failed")
+			if isBuildFailure(err) {
+				res.buildFailed = true
+				res.buildDiag = err.Error()
+				res.err = fmt.Errorf("build failed")
+			} else {
+				res.err = fmt.Errorf("preparing build: %w", err)
+			}
 			compileResCh <- res
 			return
 		}
@@ -448,6 +1634,10 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 		// ExtractCompilerPaths mutates slice fields, so sharing the pointer
 		// across concurrent streams would cause a data race.
 		bs := prepared.Settings.Clone()
+		bs.ThunkImports, bs.MockSources = sidecar.withThunkSources(sm.thunkImports, sm.mockSources)
+		bs.SeedDir = sm.seedDir
+		bs.KeepThunk = sm.keepThunk
+		bs.Layout = sm.layout
 		res.bs = bs
 		builtThisLaunch := prepared.Built
 
@@ -458,7 +1648,7 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 			sendStatus("building")
 		}
 
-		projectRoot := filepath.Dir(sm.pc.PrimaryPath())
+		projectRoot := filepath.Dir(proj.pc.PrimaryPath())
 		compileAttempt := func() (*analysis.DependencyGraph, []string, string, error) {
 			sendStatus("compiling_thunk")
 			// Load (or refresh) the shared Index Store cache. The first stream to
@@ -468,9 +1658,12 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 				slog.Warn("Index store cache unavailable for stream",
 					"streamId", s.id, "err", cacheErr)
 			}
-			sm.indexCache.Set(cache)
+			proj.indexCache.Set(cache)
+			if err := checkSourceModule(bs, s.file, proj.indexCache.Get()); err != nil {
+				return nil, nil, "", err
+			}
 
-			depGraph, _, err := analysis.ResolveTransitiveDependencies(launcherCtx, s.file, sm.indexCache.Get())
+			depGraph, _, err := analysis.ResolveTransitiveDependencies(launcherCtx, s.file, proj.indexCache.Get())
 			if err != nil && launcherCtx.Err() == nil {
 				slog.Warn("Failed to resolve dependencies, proceeding with target only",
 					"streamId", s.id, "err", err)
@@ -480,12 +1673,20 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 				trackedFiles = append(trackedFiles, depGraph.DepsUpTo(sm.preThunkDepth)...)
 			}
 
-			files, trackedFiles, err := parseAndFilterTrackedFiles(s.file, trackedFiles, sm.indexCache.Get())
+			files, trackedFiles, err := parseAndFilterTrackedFiles(s.file, trackedFiles, proj.indexCache.Get())
 			if err != nil {
 				return nil, nil, "", err
 			}
 
-			thunkPaths, err := codegen.GenerateThunks(files, bs.ModuleName, s.dirs.Thunk, "0", s.file, 0)
+			thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{
+				ModuleName:      bs.ModuleName,
+				ExtraModules:    bs.ThunkImports,
+				Layout:          bs.Layout,
+				InitArgs:        bs.InitArgsFor(s.file),
+				Dir:             s.dirs.Thunk,
+				SourceFile:      s.file,
+				PreviewSelector: strconv.Itoa(s.preview),
+			})
 			if err != nil {
 				return nil, nil, "", err
 			}
@@ -501,10 +1702,13 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 		compilers := map[CompileMode]CompileFunc{
 			CompileModeFull: func(_ context.Context) (string, error) {
 				dg, tf, dylibPath, err := compileAttempt()
-				if err != nil && !builtThisLaunch {
+				if err != nil && !builtThisLaunch && !errors.Is(err, build.ErrPackageTarget) {
 					slog.Info("Optimistic launch failed; rebuilding and retrying once", "streamId", s.id, "err", err)
 					sendStatus("building")
-					if buildErr := build.Run(launcherCtx, sm.pc, s.dirs.ProjectDirs, sm.build); buildErr != nil {
+					if buildErr := build.Run(launcherCtx, proj.pc, s.dirs.ProjectDirs, sm.build); buildErr != nil {
+						if !isBuildFailure(buildErr) {
+							return "", buildErr
+						}
 						res.buildFailed = true
 						res.buildDiag = buildErr.Error()
 						return "", fmt.Errorf("build failed")
@@ -520,7 +1724,7 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 				return dylibPath, nil
 			},
 			CompileModeMainOnly: func(_ context.Context) (string, error) {
-				return compileMainOnlyPipeline(launcherCtx, s.file, bs, s.dirs, "0", 0, sm.toolchain)
+				return compileMainOnlyPipeline(launcherCtx, s.file, bs, s.dirs, strconv.Itoa(s.preview), 0, sm.toolchain)
 			},
 		}
 
@@ -539,6 +1743,9 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 		compileRes compileResult
 		bootDone   bool
 		compDone   bool
+		// compileFirst is set when the compile failed while the boot was
+		// still running; the boot is then canceled and its error is noise.
+		compileFirst bool
 	)
 	for !bootDone || !compDone {
 		select {
@@ -552,6 +1759,7 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 			compileRes = cr
 			compDone = true
 			if cr.err != nil {
+				compileFirst = !bootDone
 				launcherCancel()
 			}
 		}
@@ -563,16 +1771,28 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 				slog.Debug("Failed to stop boot companion after parallel launch failure", "streamId", s.id, "err", stopErr)
 			}
 		}
-		if bootRes.err != nil {
+		if bootRes.err != nil && !compileFirst {
 			s.sendStopped(sm.ew, "boot_error", bootRes.err.Error(), "")
-			return
+			return false
+		}
+		if ctx.Err() == nil && (compileRes.buildFailed || isBuildFailure(compileRes.err)) {
+			log := compileRes.buildDiag
+			if !compileRes.buildFailed {
+				log = compileRes.err.Error()
+			}
+			for _, m := range sm.groupStreams(s) {
+				if err := sm.ew.Send(&pb.Event{StreamId: m.id, Payload: &pb.Event_BuildFailed{BuildFailed: newBuildFailed(log)}}); err != nil {
+					slog.Warn("Failed to send BuildFailed", "streamId", m.id, "err", err)
+				}
+			}
+			return true
 		}
 		if compileRes.buildFailed {
 			s.sendStopped(sm.ew, "build_error", "Build failed", compileRes.buildDiag)
-			return
+			return false
 		}
 		s.sendStopped(sm.ew, "build_error", compileRes.err.Error(), "")
-		return
+		return false
 	}
 
 	s.bootCompanion = bootRes.companion
@@ -587,31 +1807,57 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 	case <-s.bootCompanion.Done():
 		s.sendStopped(sm.ew, "boot_error",
 			fmt.Sprintf("simulator crashed immediately after boot: %v", s.bootCompanion.Err()), "")
-		return
+		return false
 	default:
 	}
 
+	if sm.statusBar != nil {
+		if err := platform.OverrideStatusBar(ctx, udid, sm.deviceSetPath, *sm.statusBar); err != nil {
+			slog.Warn("Failed to override status bar", "streamId", s.id, "err", err)
+		} else {
+			s.statusBarSet = true
+		}
+	}
+
 	// 8. Install app and compile loader.
 	sendStatus("installing")
 	terminateApp(ctx, bs, udid, sm.deviceSetPath, sm.app)
 
 	if _, err := installApp(ctx, bs, s.dirs, udid, sm.deviceSetPath, sm.app, sm.copier); err != nil {
 		s.sendStopped(sm.ew, "install_error", err.Error(), "")
-		return
+		return false
 	}
 
 	loaderPath, err := codegen.CompileLoader(ctx, s.dirs.Loader, bs.DeploymentTarget, sm.toolchain)
 	if err != nil {
 		s.sendStopped(sm.ew, "build_error", err.Error(), "")
-		return
+		return false
 	}
 	s.loaderPath = loaderPath
 
 	// 9. Launch app with hot-reload.
 	sendStatus("running")
+	s.overrides = platform.NewDeviceOverrides(udid, sm.deviceSetPath)
+	applyAccessibility(ctx, s.overrides, s.accessibility)
+	launchedAt := time.Now()
 	if err := launchWithHotReload(ctx, bs, loaderPath, dylibPath, s.dirs.Socket, udid, sm.deviceSetPath, sm.app); err != nil {
-		s.sendStopped(sm.ew, "runtime_error", err.Error(), "")
-		return
+		if !s.sendLaunchCrash(ctx, sm, bs, launchedAt) {
+			s.sendStopped(sm.ew, "runtime_error", err.Error(), "")
+		}
+		return false
+	}
+	if sm.crashes != nil {
+		c, err := awaitLoader(ctx, sm.crashes, bs.BundleID, s.dirs.Socket, launchedAt)
+		if c != nil {
+			s.sendStopped(sm.ew, "app_crashed", c.summary(), c.diagnostic())
+			return false
+		}
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("Loader did not become ready", "streamId", s.id, "err", err)
+		}
+	}
+	if sm.logs != nil {
+		sm.followStreamLogs(ctx, s, udid, bs)
 	}
 
 	// 10. Count previews and send StreamStarted.
@@ -619,36 +1865,52 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 	if blocks, parseErr := analysis.PreviewBlocks(s.file); parseErr == nil {
 		previewCount = len(blocks)
 	}
-	if err := sm.ew.Send(&pb.Event{
-		StreamId: s.id,
-		Payload:  &pb.Event_StreamStarted{StreamStarted: &pb.StreamStarted{PreviewCount: int32(previewCount)}},
-	}); err != nil {
-		slog.Warn("Failed to send StreamStarted", "streamId", s.id, "err", err)
+	for _, m := range sm.groupStreams(s) {
+		if err := sm.ew.Send(&pb.Event{
+			StreamId: m.id,
+			Payload:  &pb.Event_StreamStarted{StreamStarted: &pb.StreamStarted{PreviewCount: int32(previewCount), Codec: string(m.codec)}},
+		}); err != nil {
+			slog.Warn("Failed to send StreamStarted", "streamId", m.id, "err", err)
+		}
 	}
 
-	// 13. Start idb_companion for video relay and HID.
-	companion, err := idb.Start(udid, sm.deviceSetPath)
+	// 13. Start idb_companion for video relay and HID, or reuse the one
+	// already running for the device.
+	companion, err := sm.companions.Acquire(ctx, udid, sm.deviceSetPath, s.dirs.Session)
 	if err != nil {
 		s.sendStopped(sm.ew, "runtime_error", fmt.Sprintf("starting idb_companion: %v", err), "")
-		return
+		return false
 	}
-	s.idbCompanion = companion
+	s.idbCompanion = &pooledCompanion{Companion: companion, pool: sm.companions, udid: udid}
 
 	idbClient, err := idb.NewClient(companion.Address())
 	if err != nil {
 		s.sendStopped(sm.ew, "runtime_error", fmt.Sprintf("connecting to idb_companion: %v", err), "")
-		return
+		return false
 	}
 	s.idbClient = idbClient
 
+	// Frame files of an earlier run on this device are stale.
+	s.removeFrameFiles()
 	idbErrCh := make(chan error, 1)
 	voc := &protocol.VideoOutputConfig{
-		EW:       sm.ew,
-		StreamID: s.id,
-		Device:   udid,
-		File:     s.file,
+		EW:             sm.ew,
+		StreamID:       s.id,
+		Device:         udid,
+		File:           s.file,
+		Label:          s.label,
+		Encoding:       sm.frameEncoding,
+		FrameDir:       s.frameDir(),
+		Codec:          s.codec,
+		FrameDiff:      sm.frameDiff,
+		PNGCompression: sm.pngCompression,
+		OnFrame:        func() { sm.frameSent(s.id) },
+	}
+	// A --preview-all host sends screenshots instead (groupRenderer), unless
+	// degraded mode, which cannot switch previews, leaves it its own.
+	if len(s.members) == 0 || s.degraded {
+		go protocol.RelayVideoStreamEvents(ctx, idbClient, idbErrCh, voc)
 	}
-	go protocol.RelayVideoStreamEvents(ctx, idbClient, idbErrCh, voc)
 
 	// 14. Create HID handler.
 	if w, h, err := idbClient.ScreenSize(ctx); err == nil {
@@ -657,12 +1919,18 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 
 	// 15. Degraded mode: skip watcher, run simplified event loop.
 	if s.degraded {
+		if len(s.members) > 1 {
+			for _, m := range s.members[1:] {
+				m.sendStopped(sm.ew, "build_error", "hot-reload is unavailable, so preview-all can only show the first preview", "")
+				m.cancel()
+			}
+		}
 		sendStatus("degraded")
 		slog.Warn("Stream running in degraded mode: hot-reload not available", "streamId", s.id)
 		if err := runDegradedStreamLoop(ctx, s, sm, idbErrCh); err != nil {
 			slog.Info("Degraded stream loop exited", "streamId", s.id, "err", err)
 		}
-		return
+		return false
 	}
 
 	// 16. Initialize watch state (full mode only).
@@ -672,33 +1940,78 @@ func (sm *StreamManager) defaultStreamLauncher(ctx context.Context, _ *StreamMan
 	}
 	s.ws = &watchState{
 		reloadCounter:   1, // 0 was used for the initial launch
-		previewSelector: "0",
-		previewIndex:    0,
+		previewSelector: strconv.Itoa(s.preview),
+		previewIndex:    s.preview,
 		previewCount:    previewCount,
 		skeletonMap:     buildSkeletonMap(trackedFiles),
 		trackedFiles:    trackedFiles,
 		depGraph:        depGraph,
-		indexCache:      sm.indexCache, // shared across all streams
+		indexCache:      proj.indexCache, // shared across all streams
 		maxThunkFiles:   sm.maxThunkFiles,
 		preThunkDepth:   sm.preThunkDepth,
 		usageTick:       int64(len(trackedFiles)),
 		lastUsed:        smInitialLastUsed,
 	}
 
-	// 17. Register with shared watcher for file change notifications.
-	if sm.watcher != nil {
-		sm.watcher.AddListener(s.id, s.fileChangeCh)
+	// 17. Register with shared watcher for file change notifications
+	// (delivered only while watching is on for the stream).
+	if w := sm.currentWatcher(); w != nil {
+		s.registerWatcher(w)
 	}
 
 	// 18. Enter the per-stream event loop (blocks until context cancelled or crash).
 	if err := runStreamLoop(ctx, s, sm, bs, idbErrCh); err != nil {
 		slog.Info("Stream loop exited", "streamId", s.id, "err", err)
 	}
+	return false
+}
+
+// awaitBuildFix blocks a stream whose launch failed to build until a
+// watched file changes (a Reload for manualBuild streams), a ForceRebuild
+// arrives, or an AddStream update selects another file, preview, locale or
+// language. It
+// reports whether to launch again; false means the stream was stopped
+// meanwhile.
+func (sm *StreamManager) awaitBuildFix(ctx context.Context, s *stream) bool {
+	if w := sm.currentWatcher(); w != nil && !s.manualBuild {
+		s.registerWatcher(w)
+		// launchStream registers again once the stream is running.
+		defer s.unregisterWatcher()
+	}
+	slog.Info("Build failed, waiting for a fix", "streamId", s.id)
+	select {
+	case <-ctx.Done():
+		return false
+	case path := <-s.fileChangeCh:
+		slog.Debug("File changed after build failure, relaunching", "streamId", s.id, "path", path)
+	case <-s.forceRebuildCh:
+	case <-s.reloadCh:
+	case file := <-s.switchFileCh:
+		sm.mu.Lock()
+		s.file = file
+		sm.mu.Unlock()
+	case upd := <-s.updateCh:
+		sm.mu.Lock()
+		if upd.file != "" {
+			s.file = upd.file
+		}
+		if upd.preview >= 0 {
+			s.preview = upd.preview
+		}
+		sm.mu.Unlock()
+		if upd.accessibility != nil {
+			s.accessibility = *upd.accessibility
+		}
+	}
+	return true
 }
 
-// StopAll stops all active streams and shuts down the device pool.
+// StopAll stops all active streams, drains the warm pool, and shuts down
+// the device pool.
 func (sm *StreamManager) StopAll() {
 	sm.mu.Lock()
+	sm.stopIdleTimerLocked()
+	sm.idleTimeout = 0 // streams ending below must not re-arm it
 	streams := make([]*stream, 0, len(sm.streams))
 	for _, s := range sm.streams {
 		streams = append(streams, s)
@@ -719,6 +2032,10 @@ func (sm *StreamManager) StopAll() {
 		}
 	}
 
+	if sm.warm != nil {
+		sm.warm.drain()
+	}
+	sm.companions.StopIdle()
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	sm.pool.ShutdownAll(shutdownCtx)
 	shutdownCancel()
@@ -5,18 +5,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/k-kohey/axe/internal/idb"
+	"github.com/k-kohey/axe/internal/platform"
+	"github.com/k-kohey/axe/internal/preview/analysis"
 	"github.com/k-kohey/axe/internal/preview/build"
 	pb "github.com/k-kohey/axe/internal/preview/previewproto"
 	"github.com/k-kohey/axe/internal/preview/protocol"
+	"github.com/k-kohey/axe/internal/preview/watch"
 )
 
 // fakeDevicePool implements DevicePoolInterface for testing.
@@ -27,8 +35,17 @@ type fakeDevicePool struct {
 	released    []string        // UDIDs that were released
 	shutdownAll bool
 
+	// idle lists Shutdown devices in the set besides the acquired ones.
+	idle []platform.PoolDevice
+
+	// vanished is reported by the next Reconcile.
+	vanished []string
+
 	acquireErr error
 	releaseErr error
+
+	// shutdownBlock, if set, makes ShutdownAll wait until it is closed.
+	shutdownBlock chan struct{}
 }
 
 func newFakeDevicePool() *fakeDevicePool {
@@ -49,6 +66,34 @@ func (p *fakeDevicePool) Acquire(_ context.Context, _, _ string) (string, error)
 	return udid, nil
 }
 
+func (p *fakeDevicePool) AcquireUDID(_ context.Context, udid string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.acquired[udid] {
+		return fmt.Errorf("device %s is already in use", udid)
+	}
+	p.acquired[udid] = true
+	return nil
+}
+
+// Devices reports the idle devices, and the acquired ones as booted, as a
+// live simctl listing would.
+func (p *fakeDevicePool) Devices(_ context.Context) ([]platform.PoolDevice, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var devices []platform.PoolDevice
+	for _, d := range p.idle {
+		if !p.acquired[d.UDID] {
+			devices = append(devices, d)
+		}
+	}
+	for udid := range p.acquired {
+		devices = append(devices, platform.PoolDevice{UDID: udid, Name: "axe " + udid, State: "Booted", InUse: true})
+	}
+	slices.SortFunc(devices, func(a, b platform.PoolDevice) int { return strings.Compare(a.UDID, b.UDID) })
+	return devices, nil
+}
+
 func (p *fakeDevicePool) Release(_ context.Context, udid string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -61,6 +106,9 @@ func (p *fakeDevicePool) Release(_ context.Context, udid string) error {
 }
 
 func (p *fakeDevicePool) ShutdownAll(_ context.Context) {
+	if p.shutdownBlock != nil {
+		<-p.shutdownBlock
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.shutdownAll = true
@@ -72,6 +120,14 @@ func (p *fakeDevicePool) CleanupOrphans(_ context.Context) error {
 
 func (p *fakeDevicePool) GarbageCollect(_ context.Context) {}
 
+func (p *fakeDevicePool) Reconcile(_ context.Context) (platform.DeviceSetChanges, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changes := platform.DeviceSetChanges{Vanished: p.vanished}
+	p.vanished = nil
+	return changes, nil
+}
+
 // parsedEvent is a loosely-typed event representation for test assertions.
 // We parse the JSON Lines output generically because the EventWriter now uses protojson,
 // which differs from encoding/json in zero-value omission.
@@ -81,6 +137,10 @@ type parsedEvent struct {
 	StreamStarted map[string]any
 	StreamStopped map[string]any
 	StreamStatus  map[string]any
+	BuildFailed   map[string]any
+	Recording     map[string]any
+	BuildComplete map[string]any
+	ProtocolError map[string]any
 }
 
 // collectEvents parses all JSON Lines from a buffer into parsedEvents.
@@ -107,6 +167,18 @@ func collectEvents(t *testing.T, buf *syncBuffer) []parsedEvent {
 		if v, ok := raw["streamStatus"].(map[string]any); ok {
 			e.StreamStatus = v
 		}
+		if v, ok := raw["buildFailed"].(map[string]any); ok {
+			e.BuildFailed = v
+		}
+		if v, ok := raw["recording"].(map[string]any); ok {
+			e.Recording = v
+		}
+		if v, ok := raw["buildComplete"].(map[string]any); ok {
+			e.BuildComplete = v
+		}
+		if v, ok := raw["protocolError"].(map[string]any); ok {
+			e.ProtocolError = v
+		}
 		events = append(events, e)
 	}
 	return events
@@ -137,7 +209,7 @@ func nopRunners() (BuildRunner, ToolchainRunner, AppRunner, FileCopier, SourceLi
 func newTestStreamManagerWithRunners(pool DevicePoolInterface, ew *protocol.EventWriter) *StreamManager {
 	br, tc, ar, fc, sl := nopRunners()
 	pc := ProjectConfig{}
-	preparer := build.NewPreparer(pc, build.ProjectDirs{}, false, br)
+	preparer := build.NewPreparer(pc, build.ProjectDirs{}, build.Incremental, br)
 	return NewStreamManager(pool, ew, pc, "", preparer, br, tc, ar, fc, sl, false, 32, 0)
 }
 
@@ -146,7 +218,7 @@ func newTestStreamManagerWithRunners(pool DevicePoolInterface, ew *protocol.Even
 func newTestStreamManager(pool DevicePoolInterface, ew *protocol.EventWriter) *StreamManager {
 	br, tc, ar, fc, sl := nopRunners()
 	pc := ProjectConfig{}
-	preparer := build.NewPreparer(pc, build.ProjectDirs{}, false, br)
+	preparer := build.NewPreparer(pc, build.ProjectDirs{}, build.Incremental, br)
 	sm := NewStreamManager(pool, ew, pc, "", preparer, br, tc, ar, fc, sl, false, 32, 0)
 	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
 		if err := sm.ew.Send(&pb.Event{
@@ -156,12 +228,17 @@ func newTestStreamManager(pool DevicePoolInterface, ew *protocol.EventWriter) *S
 			return
 		}
 
-		udid, err := sm.pool.Acquire(ctx, s.deviceType, s.runtime)
+		_, companion, err := sm.acquireDevice(ctx, s)
 		if err != nil {
 			s.sendStopped(sm.ew, "resource_error", fmt.Sprintf("acquiring device: %v", err), "")
 			return
 		}
-		s.deviceUDID = udid
+		// The test manager has no warm pool, so no boot companion is handed
+		// over. Surface a violation as a stop event the test will notice.
+		if companion != nil {
+			s.sendStopped(sm.ew, "resource_error", "acquireDevice returned a warm companion without a warm pool", "")
+			return
+		}
 
 		if err := sm.ew.Send(&pb.Event{
 			StreamId: s.id,
@@ -234,18 +311,18 @@ func TestStreamManager_RemoveStream(t *testing.T) {
 	sm.StopAll()
 
 	events := filterEvents(collectEvents(t, &buf), "stream-a")
-	// Should have a StreamStopped with reason "removed".
+	// Should have a StreamStopped with reason "user_removed".
 	var foundStopped bool
 	for _, e := range events {
 		if e.StreamStopped != nil {
-			if reason, ok := e.StreamStopped["reason"].(string); ok && reason == "removed" {
+			if reason, ok := e.StreamStopped["reason"].(string); ok && reason == "user_removed" {
 				foundStopped = true
 				break
 			}
 		}
 	}
 	if !foundStopped {
-		t.Errorf("expected StreamStopped{reason:removed}, events: %+v", events)
+		t.Errorf("expected StreamStopped{reason:user_removed}, events: %+v", events)
 	}
 
 	// Pool.Release should have been called.
@@ -256,6 +333,278 @@ func TestStreamManager_RemoveStream(t *testing.T) {
 	}
 }
 
+func TestStreamManager_RemoveStreamCancelsInFlightBuild(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	br := &blockingBuildRunner{entered: make(chan struct{}), cancelled: make(chan struct{})}
+	_, tc, ar, fc, sl := nopRunners()
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
+	preparer := build.NewPreparer(pc, build.ProjectDirs{Build: t.TempDir()}, build.Incremental, br)
+	sm := NewStreamManager(pool, ew, pc, "", preparer, br, tc, ar, fc, sl, false, 32, 0)
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		_, _ = sm.preparer.Prepare(ctx)
+	}
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+
+	select {
+	case <-br.entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("build did not start")
+	}
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}},
+	})
+
+	select {
+	case <-br.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RemoveStream did not cancel the in-flight build")
+	}
+
+	var reasons []string
+	for _, e := range filterEvents(collectEvents(t, &buf), "stream-a") {
+		if e.StreamStopped != nil {
+			reasons = append(reasons, fmt.Sprint(e.StreamStopped["reason"]))
+		}
+	}
+	if !slices.Equal(reasons, []string{"user_removed"}) {
+		t.Errorf("StreamStopped reasons = %v, want [user_removed]", reasons)
+	}
+}
+
+// blockingBuildRunner blocks FetchBuildSettings until its context is
+// cancelled, closing cancelled when that happens.
+type blockingBuildRunner struct {
+	entered   chan struct{}
+	cancelled chan struct{}
+}
+
+func (b *blockingBuildRunner) FetchBuildSettings(ctx context.Context, _ []string) ([]byte, error) {
+	close(b.entered)
+	<-ctx.Done()
+	close(b.cancelled)
+	return nil, ctx.Err()
+}
+
+func (b *blockingBuildRunner) Build(context.Context, []string) ([]byte, error) {
+	return nil, nil
+}
+
+func TestStreamManager_PreviewAllExpandsStreams(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	sm.previewAll = true
+	sm.listPreviews = func(string) ([]analysis.PreviewBlock, error) {
+		return []analysis.PreviewBlock{
+			{StartLine: 10, Title: "Light"},
+			{StartLine: 14, Title: "Dark"},
+			{StartLine: 18},
+		}, nil
+	}
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	waitForStreamCount(t, sm, 3, 2*time.Second)
+
+	want := []struct {
+		id      string
+		preview int
+		label   string
+	}{
+		{id: "stream-a#0", preview: 0, label: "Light"},
+		{id: "stream-a#1", preview: 1, label: "Dark"},
+		{id: "stream-a#2", preview: 2, label: "#2"},
+	}
+	sm.mu.Lock()
+	for _, w := range want {
+		s, ok := sm.streams[w.id]
+		if !ok {
+			t.Errorf("missing stream %s", w.id)
+			continue
+		}
+		if s.preview != w.preview || s.label != w.label || s.file != "/path/to/HogeView.swift" {
+			t.Errorf("stream %s: preview=%d label=%q file=%q, want preview=%d label=%q",
+				w.id, s.preview, s.label, s.file, w.preview, w.label)
+		}
+	}
+	sm.mu.Unlock()
+
+	// The first stream hosts the group on the one device all previews share.
+	waitForEvents(t, &buf, 2, 2*time.Second) // booting + running of the host
+	pool.mu.Lock()
+	acquired := len(pool.acquired)
+	pool.mu.Unlock()
+	if acquired != 1 {
+		t.Errorf("acquired %d devices, want 1", acquired)
+	}
+	sm.mu.Lock()
+	host := sm.streams["stream-a#0"]
+	if len(host.members) != 3 || host.host != nil {
+		t.Errorf("host has %d members and host %v, want 3 and none", len(host.members), host.host)
+	}
+	for _, id := range []string{"stream-a#1", "stream-a#2"} {
+		if s := sm.streams[id]; s.host != host {
+			t.Errorf("stream %s is not hosted by stream-a#0", id)
+		}
+	}
+	sm.mu.Unlock()
+
+	// Removing the original streamId stops every expanded stream.
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}},
+	})
+	waitForStreamCount(t, sm, 0, 2*time.Second)
+	stopped := map[string]bool{}
+	for _, e := range collectEvents(t, &buf) {
+		if e.StreamStopped != nil && e.StreamStopped["reason"] == "user_removed" {
+			stopped[e.StreamID] = true
+		}
+	}
+	for _, w := range want {
+		if !stopped[w.id] {
+			t.Errorf("expected StreamStopped{user_removed} for %s", w.id)
+		}
+	}
+	sm.mu.Lock()
+	groups := len(sm.groups)
+	sm.mu.Unlock()
+	if groups != 0 {
+		t.Errorf("groups = %d after RemoveStream, want 0", groups)
+	}
+}
+
+func TestStreamManager_FileEncodingRejectsH264(t *testing.T) {
+	pool := newFakeDevicePool()
+	ew := protocol.NewEventWriter(&syncBuffer{})
+
+	sm := newTestStreamManager(pool, ew)
+	sm.frameEncoding = protocol.FrameEncodingFile
+	defer sm.StopAll()
+
+	add := &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2", Codec: "h264"}
+	sm.HandleCommand(t.Context(), &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: add}})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+	sm.mu.Lock()
+	s := sm.streams["stream-a"]
+	sm.mu.Unlock()
+	if s.codec != protocol.CodecJPEG {
+		t.Errorf("codec = %s, want jpeg: frame files are pruned, H.264 chunks must not be", s.codec)
+	}
+	if codec, _ := sm.streamCodec(add, ""); codec != s.codec {
+		t.Errorf("an identical AddStream negotiates %s, which would restart the stream", codec)
+	}
+}
+
+func TestStreamManager_PreviewAllRoutesGroupCommands(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	sm.previewAll = true
+	sm.listPreviews = func(string) ([]analysis.PreviewBlock, error) {
+		return []analysis.PreviewBlock{{Title: "Light"}, {Title: "Dark"}}, nil
+	}
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2", Codec: "h264"}},
+	})
+	waitForStreamCount(t, sm, 2, 2*time.Second)
+	sm.mu.Lock()
+	host, member := sm.streams["stream-a#0"], sm.streams["stream-a#1"]
+	sm.mu.Unlock()
+	if host.codec != protocol.CodecJPEG || member.codec != protocol.CodecJPEG {
+		t.Errorf("codecs = %s, %s, want jpeg for screenshots", host.codec, member.codec)
+	}
+
+	// Rebuilds of the group or of any of its streams go to the host.
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_ForceRebuild{ForceRebuild: &pb.ForceRebuild{}}})
+	if len(host.forceRebuildCh) != 1 {
+		t.Error("ForceRebuild for the group did not reach the host")
+	}
+	<-host.forceRebuildCh
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a#1", Payload: &pb.Command_Reload{Reload: &pb.Reload{}}})
+	if len(host.reloadCh) != 1 || len(member.reloadCh) != 0 {
+		t.Error("Reload for a group stream did not reach the host")
+	}
+
+	// Commands driving the shared device for one preview are rejected.
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_NextPreview{NextPreview: &pb.NextPreview{}}})
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a#1", Payload: &pb.Command_SwitchFile{SwitchFile: &pb.SwitchFile{File: "/path/to/Other.swift"}}})
+	if len(host.nextPreviewCh)+len(member.nextPreviewCh)+len(host.switchFileCh)+len(member.switchFileCh) != 0 {
+		t.Error("NextPreview or SwitchFile for the group reached a stream")
+	}
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_StartRecording{StartRecording: &pb.StartRecording{Path: "/tmp/a.mov"}}})
+	var rejected bool
+	for _, e := range filterEvents(collectEvents(t, &buf), "stream-a") {
+		if e.Recording != nil && e.Recording["error"] != nil {
+			rejected = true
+		}
+	}
+	if !rejected {
+		t.Error("StartRecording for the group was not rejected with a Recording error")
+	}
+
+	// The other streams stop with the host.
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a#0", Payload: &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}}})
+	waitForStreamCount(t, sm, 0, 2*time.Second)
+	for _, id := range []string{"stream-a#0", "stream-a#1"} {
+		var reasons []string
+		for _, e := range filterEvents(collectEvents(t, &buf), id) {
+			if e.StreamStopped != nil {
+				reasons = append(reasons, fmt.Sprint(e.StreamStopped["reason"]))
+			}
+		}
+		if !slices.Equal(reasons, []string{"user_removed"}) {
+			t.Errorf("%s StreamStopped reasons = %v, want [user_removed]", id, reasons)
+		}
+	}
+}
+
+func TestStreamManager_PreviewAllNoPreviews(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	sm.previewAll = true
+	sm.listPreviews = func(string) ([]analysis.PreviewBlock, error) { return nil, nil }
+	defer sm.StopAll()
+
+	sm.HandleCommand(t.Context(), &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+
+	events := collectEvents(t, &buf)
+	if len(events) != 1 || events[0].StreamStopped == nil || events[0].StreamStopped["reason"] != "resource_error" {
+		t.Fatalf("expected a single StreamStopped{resource_error}, got %+v", events)
+	}
+	if n := len(sm.streams); n != 0 {
+		t.Errorf("streams = %d, want 0", n)
+	}
+}
+
 func TestStreamManager_NonexistentRemove(t *testing.T) {
 	pool := newFakeDevicePool()
 	var buf syncBuffer
@@ -373,6 +722,7 @@ func TestStreamManager_DuplicateStreamID(t *testing.T) {
 	ew := protocol.NewEventWriter(&buf)
 
 	sm := newTestStreamManager(pool, ew)
+	sm.rejectDuplicates = true
 	defer sm.StopAll()
 
 	ctx := t.Context()
@@ -390,71 +740,789 @@ func TestStreamManager_DuplicateStreamID(t *testing.T) {
 		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/FugaView.swift", DeviceType: "iPad-Air", Runtime: "iOS-18-2"}},
 	})
 
-	// Should not panic — that's the test.
+	sm.mu.Lock()
+	s := sm.streams["stream-a"]
+	sm.mu.Unlock()
+	if s == nil || s.deviceType != "iPhone-16-Pro" || s.file != "/path/to/HogeView.swift" {
+		t.Errorf("stream changed by rejected AddStream: %+v", s)
+	}
 }
 
-func TestStreamManager_EmptyCommand(t *testing.T) {
+// TestStreamManager_AddStreamUpdatesPreviewInPlace verifies that an AddStream
+// for a running stream with the same device only hands the new preview to
+// the stream, without launching it again.
+func TestStreamManager_AddStreamUpdatesPreviewInPlace(t *testing.T) {
 	pool := newFakeDevicePool()
 	var buf syncBuffer
 	ew := protocol.NewEventWriter(&buf)
 
-	sm := newTestStreamManager(pool, ew)
+	sm := newTestStreamManagerWithRunners(pool, ew)
+	sm.listPreviews = func(string) ([]analysis.PreviewBlock, error) {
+		return []analysis.PreviewBlock{
+			{StartLine: 10, Title: "Light"},
+			{StartLine: 14, Title: "Dark"},
+		}, nil
+	}
+
+	var launches atomic.Int32
+	received := make(chan streamUpdate, 1)
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		launches.Add(1)
+		select {
+		case upd := <-s.updateCh:
+			received <- upd
+			<-ctx.Done()
+		case <-ctx.Done():
+		}
+	}
 	defer sm.StopAll()
 
-	// Command with no payload should not panic.
-	sm.HandleCommand(context.Background(), &pb.Command{StreamId: "x"})
+	ctx := t.Context()
+	add := &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: add}})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+
+	update := &pb.AddStream{File: add.File, DeviceType: add.DeviceType, Runtime: add.Runtime, Preview: "Dark"}
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: update}})
+
+	select {
+	case upd := <-received:
+		if upd.file != add.File || upd.preview != 1 {
+			t.Errorf("update = %+v, want file %s preview 1", upd, add.File)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("update not received by stream")
+	}
+	if n := launches.Load(); n != 1 {
+		t.Errorf("launcher ran %d times, want 1 (no restart)", n)
+	}
+
+	events := filterEvents(collectEvents(t, &buf), "stream-a")
+	if len(events) != 1 || events[0].StreamStatus == nil || events[0].StreamStatus["phase"] != "updating" {
+		t.Errorf("expected a single StreamStatus{updating}, got %+v", events)
+	}
 }
 
-// TestStreamManager_FullLifecycle verifies the fake launcher sends StreamStarted
-// and Frame events, and RemoveStream produces StreamStopped{removed}.
-func TestStreamManager_FullLifecycle(t *testing.T) {
+// TestStreamManager_AddStreamReplacesPendingUpdate verifies that an
+// AddStream for a stream that has not picked up the previous update yet
+// replaces it, so the latest selection wins, and that "updating" is only
+// reported for updates that were queued.
+func TestStreamManager_AddStreamReplacesPendingUpdate(t *testing.T) {
 	pool := newFakeDevicePool()
 	var buf syncBuffer
 	ew := protocol.NewEventWriter(&buf)
 
 	sm := newTestStreamManagerWithRunners(pool, ew)
-	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
-		udid, err := sm.pool.Acquire(ctx, s.deviceType, s.runtime)
-		if err != nil {
-			s.sendStopped(sm.ew, "resource_error", err.Error(), "")
-			return
-		}
-		s.deviceUDID = udid
+	sm.listPreviews = func(string) ([]analysis.PreviewBlock, error) {
+		return []analysis.PreviewBlock{
+			{StartLine: 10, Title: "Light"},
+			{StartLine: 14, Title: "Dark"},
+			{StartLine: 18, Title: "Large"},
+		}, nil
+	}
+	// A busy stream: it never reads its updates.
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) { <-ctx.Done() }
+	defer sm.StopAll()
 
-		_ = sm.ew.Send(&pb.Event{
-			StreamId: s.id,
-			Payload:  &pb.Event_StreamStarted{StreamStarted: &pb.StreamStarted{PreviewCount: 2}},
-		})
+	ctx := t.Context()
+	add := &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: add}})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
 
-		// Simulate frame sending.
-		_ = sm.ew.Send(&pb.Event{
-			StreamId: s.id,
-			Payload:  &pb.Event_Frame{Frame: &pb.Frame{Device: udid, File: s.file, Data: "AAAA"}},
-		})
+	for _, preview := range []string{"Dark", "Large", "Light", "Large"} {
+		update := &pb.AddStream{File: add.File, DeviceType: add.DeviceType, Runtime: add.Runtime, Preview: preview}
+		sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: update}})
+	}
 
-		<-ctx.Done()
+	sm.mu.Lock()
+	s := sm.streams["stream-a"]
+	sm.mu.Unlock()
+	select {
+	case upd := <-s.updateCh:
+		if upd.file != add.File || upd.preview != 2 {
+			t.Errorf("pending update = %+v, want file %s preview 2", upd, add.File)
+		}
+	default:
+		t.Fatal("no update pending")
+	}
+	select {
+	case upd := <-s.updateCh:
+		t.Errorf("more than one update pending: %+v", upd)
+	default:
 	}
 
-	ctx := t.Context()
+	events := filterEvents(collectEvents(t, &buf), "stream-a")
+	if len(events) != 4 {
+		t.Fatalf("expected 4 StreamStatus{updating}, got %+v", events)
+	}
+	for _, e := range events {
+		if e.StreamStatus == nil || e.StreamStatus["phase"] != "updating" {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+}
+
+// TestStreamManager_AddStreamRestartsOnDeviceChange verifies that an
+// AddStream for a running stream with a different device restarts it under
+// the same streamId without reporting it as removed.
+func TestStreamManager_AddStreamRestartsOnDeviceChange(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	defer sm.StopAll()
 
+	ctx := t.Context()
 	sm.HandleCommand(ctx, &pb.Command{
 		StreamId: "stream-a",
 		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
 	})
-
-	waitForEvents(t, &buf, 2, 2*time.Second) // StreamStarted + Frame
+	waitForEvents(t, &buf, 2, 2*time.Second) // booting + running
 
 	sm.HandleCommand(ctx, &pb.Command{
 		StreamId: "stream-a",
-		Payload:  &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}},
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPad-Air", Runtime: "iOS-18-2"}},
 	})
+	waitForEvents(t, &buf, 4, 2*time.Second)
 
-	waitForEvents(t, &buf, 3, 2*time.Second) // + StreamStopped
-
-	sm.StopAll()
-
-	events := filterEvents(collectEvents(t, &buf), "stream-a")
-	var hasStarted, hasFrame, hasStopped bool
+	sm.mu.Lock()
+	s := sm.streams["stream-a"]
+	sm.mu.Unlock()
+	if s == nil || s.deviceType != "iPad-Air" {
+		t.Fatalf("stream not restarted with the new device: %+v", s)
+	}
+	for _, e := range filterEvents(collectEvents(t, &buf), "stream-a") {
+		if e.StreamStopped != nil {
+			t.Errorf("unexpected StreamStopped on restart: %+v", e.StreamStopped)
+		}
+	}
+	pool.mu.Lock()
+	released := len(pool.released)
+	pool.mu.Unlock()
+	if released != 1 {
+		t.Errorf("released %d devices, want 1 (the old one)", released)
+	}
+}
+
+func TestStreamManager_AddStreamAppearance(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	sm.accessibility = platform.AccessibilityOverrides{Appearance: "light", Locale: "ja_JP"}
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	waitForEvents(t, &buf, 2, 2*time.Second) // booting + running
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2", Appearance: "dark"}},
+	})
+	waitForEvents(t, &buf, 4, 2*time.Second)
+
+	sm.mu.Lock()
+	s := sm.streams["stream-a"]
+	sm.mu.Unlock()
+	want := platform.AccessibilityOverrides{Appearance: "dark", Locale: "ja_JP"}
+	if s == nil || s.accessibility != want {
+		t.Fatalf("stream not restarted with the new appearance: %+v", s)
+	}
+
+	// An invalid appearance is rejected without touching the stream.
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-b",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2", Appearance: "dim"}},
+	})
+	sm.mu.Lock()
+	_, added := sm.streams["stream-b"]
+	sm.mu.Unlock()
+	if added {
+		t.Error("stream with an invalid appearance was added")
+	}
+}
+
+func TestStreamManager_AddStreamLocaleRelaunches(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	sm.accessibility = platform.AccessibilityOverrides{Appearance: "dark", Locale: "en_US", Region: "GB"}
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	add := &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: add}})
+	waitForEvents(t, &buf, 2, 2*time.Second) // booting + running
+
+	sm.mu.Lock()
+	first := sm.streams["stream-a"]
+	sm.mu.Unlock()
+
+	add = &pb.AddStream{File: add.File, DeviceType: add.DeviceType, Runtime: add.Runtime, Locale: "ja_JP", Language: "ja"}
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: add}})
+
+	// The stream keeps running; its event loop is asked to relaunch the app
+	// with the new locale in place of serve's locale and region.
+	sm.mu.Lock()
+	s := sm.streams["stream-a"]
+	sm.mu.Unlock()
+	if s != first {
+		t.Fatal("stream restarted for a locale change")
+	}
+	var upd streamUpdate
+	select {
+	case upd = <-s.updateCh:
+	default:
+		t.Fatal("no update sent to the stream")
+	}
+	want := platform.AccessibilityOverrides{Appearance: "dark", Locale: "ja_JP", Language: "ja"}
+	if upd.accessibility == nil || *upd.accessibility != want {
+		t.Errorf("update accessibility = %+v, want %+v", upd.accessibility, want)
+	}
+
+	// The same locale again is not a change.
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: add}})
+	select {
+	case upd = <-s.updateCh:
+		if upd.accessibility != nil {
+			t.Errorf("unchanged locale relaunched the app: %+v", upd.accessibility)
+		}
+	default:
+		t.Fatal("no update sent to the stream")
+	}
+}
+
+// deviceLists returns the DeviceList events written to buf.
+func deviceLists(t *testing.T, buf *syncBuffer) []*pb.DeviceList {
+	t.Helper()
+	var lists []*pb.DeviceList
+	for line := range strings.SplitSeq(strings.TrimSpace(string(buf.Bytes())), "\n") {
+		event, err := protocol.UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("invalid JSON: %v\nline: %q", err, line)
+		}
+		if l := event.GetDeviceList(); l != nil {
+			lists = append(lists, l)
+		}
+	}
+	return lists
+}
+
+func TestStreamManager_ListDevicesIncludesSessionDevice(t *testing.T) {
+	pool := newFakeDevicePool()
+	pool.idle = []platform.PoolDevice{{UDID: "IDLE-1", Name: "axe iPad Air (1)", State: "Shutdown"}}
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	waitForEvents(t, &buf, 2, 2*time.Second) // booting + running
+
+	sm.HandleCommand(ctx, &pb.Command{Payload: &pb.Command_ListDevices{ListDevices: &pb.ListDevices{}}})
+
+	lists := deviceLists(t, &buf)
+	if len(lists) != 1 {
+		t.Fatalf("got %d DeviceList events, want 1", len(lists))
+	}
+	devices := lists[0].GetDevices()
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want the session's device and IDLE-1: %v", len(devices), devices)
+	}
+	booted := devices[0]
+	if booted.GetUdid() != "FAKE-1" || booted.GetState() != "Booted" || !booted.GetInUse() || booted.GetStreamId() != "stream-a" {
+		t.Errorf("session device = %v, want FAKE-1 Booted, in use by stream-a", booted)
+	}
+	if idle := devices[1]; idle.GetUdid() != "IDLE-1" || idle.GetState() != "Shutdown" || idle.GetInUse() || idle.GetStreamId() != "" {
+		t.Errorf("idle device = %v, want IDLE-1 Shutdown and unused", idle)
+	}
+}
+
+// shCompanionCommander runs a shell stand-in for idb_companion that reports
+// a port and sleeps until it is stopped.
+type shCompanionCommander struct{}
+
+func (shCompanionCommander) Command(string, ...string) idb.CmdRunner {
+	cmd := exec.Command("sh", "-c", `echo '{"grpc_port":10000}'; exec sleep 30`)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} // Stop signals the group
+	return &shCompanionCmd{cmd: cmd}
+}
+
+type shCompanionCmd struct {
+	cmd    *exec.Cmd
+	writes []*os.File // pipe write ends, closed once the process has them
+}
+
+func (c *shCompanionCmd) pipe(dst **os.File) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	*dst = w
+	c.writes = append(c.writes, w)
+	return r, nil
+}
+
+func (c *shCompanionCmd) StdoutPipe() (*os.File, error) {
+	var w *os.File
+	r, err := c.pipe(&w)
+	c.cmd.Stdout = w
+	return r, err
+}
+
+func (c *shCompanionCmd) StderrPipe() (*os.File, error) {
+	var w *os.File
+	r, err := c.pipe(&w)
+	c.cmd.Stderr = w
+	return r, err
+}
+
+func (c *shCompanionCmd) Start() error {
+	err := c.cmd.Start()
+	for _, w := range c.writes {
+		_ = w.Close()
+	}
+	return err
+}
+
+func (c *shCompanionCmd) Process() *os.Process { return c.cmd.Process }
+func (c *shCompanionCmd) Wait() error          { return c.cmd.Wait() }
+
+func TestStreamManager_PoolStatus(t *testing.T) {
+	// Companions record state files in the user cache directory.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	sm.companions = idb.NewCompanionPool(shCompanionCommander{}, idb.Options{SkipReadyProbe: true}, time.Minute)
+	defer func() { _ = sm.companions.Close() }()
+
+	ctx := t.Context()
+	for range 2 {
+		if _, err := sm.companions.Acquire(ctx, "UDID-1", "", ""); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+	sm.companions.Release("UDID-1")
+
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "probe", Payload: &pb.Command_PoolStatus{PoolStatus: &pb.PoolStatus{}}})
+
+	var statuses []*pb.CompanionPoolStatus
+	for line := range strings.SplitSeq(strings.TrimSpace(string(buf.Bytes())), "\n") {
+		event, err := protocol.UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("invalid JSON: %v\nline: %q", err, line)
+		}
+		if st := event.GetCompanionPoolStatus(); st != nil {
+			if event.GetStreamId() != "probe" {
+				t.Errorf("streamId = %q, want the command's", event.GetStreamId())
+			}
+			statuses = append(statuses, st)
+		}
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("got %d CompanionPoolStatus events, want 1", len(statuses))
+	}
+	companions := statuses[0].GetCompanions()
+	if len(companions) != 1 {
+		t.Fatalf("got %d companions, want 1: %v", len(companions), companions)
+	}
+	c := companions[0]
+	if c.GetUdid() != "UDID-1" || c.GetRefs() != 1 || c.GetAddress() == "" || c.GetStarting() || c.GetRestarts() != 0 || c.GetLastError() != "" {
+		t.Errorf("companion = %v, want UDID-1 running with 1 reference", c)
+	}
+}
+
+func TestStreamManager_SetDeviceMovesStream(t *testing.T) {
+	pool := newFakeDevicePool()
+	pool.idle = []platform.PoolDevice{{UDID: "IDLE-1", Name: "axe iPad Air (1)", State: "Shutdown", DeviceType: "iPad-Air", Runtime: "iOS-18-2"}}
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	for _, id := range []string{"stream-a", "stream-b"} {
+		sm.HandleCommand(ctx, &pb.Command{
+			StreamId: id,
+			Payload: &pb.Command_AddStream{AddStream: &pb.AddStream{
+				File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2",
+				ManualBuild: true, Appearance: "dark", Locale: "ja_JP", Language: "ja",
+			}},
+		})
+	}
+	waitForEvents(t, &buf, 4, 2*time.Second)
+
+	streamOf := func(id string) *stream {
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		return sm.streams[id]
+	}
+	otherUDID := streamOf("stream-b").deviceUDID
+
+	// A device used by another stream or outside the set is rejected, and
+	// the client is told why.
+	before := streamOf("stream-a")
+	for _, udid := range []string{otherUDID, "MISSING-1"} {
+		sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_SetDevice{SetDevice: &pb.SetDevice{Udid: udid}}})
+		if streamOf("stream-a") != before {
+			t.Fatalf("SetDevice to %s restarted the stream", udid)
+		}
+	}
+	waitForEvents(t, &buf, 6, 2*time.Second)
+	var rejections []string
+	for _, e := range filterEvents(collectEvents(t, &buf), "stream-a") {
+		if e.ProtocolError != nil {
+			rejections = append(rejections, fmt.Sprint(e.ProtocolError["message"]))
+		}
+	}
+	if len(rejections) != 2 || !strings.Contains(rejections[0], "used by stream stream-b") || !strings.Contains(rejections[1], "MISSING-1 is not in the device set") {
+		t.Errorf("rejections = %q, want one for the used device and one for MISSING-1", rejections)
+	}
+
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_SetDevice{SetDevice: &pb.SetDevice{Udid: "IDLE-1"}}})
+	waitForEvents(t, &buf, 8, 2*time.Second)
+
+	s := streamOf("stream-a")
+	if s == before || s.deviceUDID != "IDLE-1" {
+		t.Fatalf("stream not moved to IDLE-1: %+v", s)
+	}
+	if s.file != "/path/to/HogeView.swift" || s.deviceType != "iPad-Air" {
+		t.Errorf("moved stream file = %q, deviceType = %q; want the same file on iPad-Air", s.file, s.deviceType)
+	}
+	if !s.manualBuild || s.appearance != "dark" || s.locale != "ja_JP" || s.language != "ja" {
+		t.Errorf("moved stream manualBuild = %v, appearance = %q, locale = %q, language = %q; want the original settings",
+			s.manualBuild, s.appearance, s.locale, s.language)
+	}
+	for _, e := range filterEvents(collectEvents(t, &buf), "stream-a") {
+		if e.StreamStopped != nil {
+			t.Errorf("unexpected StreamStopped on SetDevice: %+v", e.StreamStopped)
+		}
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if !slices.Equal(pool.released, []string{before.deviceUDID}) {
+		t.Errorf("released = %v, want the old device %s", pool.released, before.deviceUDID)
+	}
+}
+
+// blockingLogStreamer records the devices whose log it follows and
+// blocks each follower until its context is done.
+type blockingLogStreamer struct {
+	mu      sync.Mutex
+	started []string
+	stopped []string
+}
+
+func (f *blockingLogStreamer) Stream(ctx context.Context, device, _ string, _ []string, _ func([]byte)) error {
+	f.mu.Lock()
+	f.started = append(f.started, device)
+	f.mu.Unlock()
+	<-ctx.Done()
+	f.mu.Lock()
+	f.stopped = append(f.stopped, device)
+	f.mu.Unlock()
+	return ctx.Err()
+}
+
+// waitFor polls the follower lists until cond holds.
+func (f *blockingLogStreamer) waitFor(t *testing.T, cond func(started, stopped []string) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		f.mu.Lock()
+		ok := cond(f.started, f.stopped)
+		started, stopped := slices.Clone(f.started), slices.Clone(f.stopped)
+		f.mu.Unlock()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out: log followers started %v, stopped %v", started, stopped)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamManager_SetDeviceRestartsAppLogs(t *testing.T) {
+	pool := newFakeDevicePool()
+	pool.idle = []platform.PoolDevice{{UDID: "IDLE-1", Name: "axe iPad Air (1)", State: "Shutdown", DeviceType: "iPad-Air", Runtime: "iOS-18-2"}}
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	defer sm.StopAll()
+	logs := &blockingLogStreamer{}
+	sm.logs = logs
+	// Each stream launches twice, as after a build fix, and follows the
+	// app log of its device on each launch.
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		udid, _, err := sm.acquireDevice(ctx, s)
+		if err != nil {
+			s.sendStopped(sm.ew, "resource_error", err.Error(), "")
+			return
+		}
+		bs := &build.Settings{ExecutableName: "Hoge"}
+		sm.followStreamLogs(ctx, s, udid, bs)
+		sm.followStreamLogs(ctx, s, udid, bs)
+		<-ctx.Done()
+	}
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload: &pb.Command_AddStream{AddStream: &pb.AddStream{
+			File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2",
+		}},
+	})
+	var first string
+	logs.waitFor(t, func(started, stopped []string) bool {
+		if len(started) < 2 || len(stopped) < 1 {
+			return false
+		}
+		first = started[0]
+		return true
+	})
+
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_SetDevice{SetDevice: &pb.SetDevice{Udid: "IDLE-1"}}})
+	logs.waitFor(t, func(started, stopped []string) bool {
+		return len(started) == 4 && len(stopped) == 3
+	})
+
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	if want := []string{first, first, "IDLE-1", "IDLE-1"}; !slices.Equal(logs.started, want) {
+		t.Errorf("followed the logs of %v, want %v", logs.started, want)
+	}
+	if want := []string{first, first, "IDLE-1"}; !slices.Equal(logs.stopped, want) {
+		t.Errorf("stopped following %v, want %v", logs.stopped, want)
+	}
+}
+
+// fakeRecording is a screenRecording whose Stop ends it immediately.
+type fakeRecording struct {
+	device, path string
+	once         sync.Once
+	done         chan struct{}
+	stops        atomic.Int32
+}
+
+func (f *fakeRecording) Stop() error {
+	f.stops.Add(1)
+	f.once.Do(func() { close(f.done) })
+	return nil
+}
+
+func (f *fakeRecording) Done() <-chan struct{} { return f.done }
+
+// recordingEvents returns the Recording payloads sent for streamID.
+func recordingEvents(t *testing.T, buf *syncBuffer, streamID string) []map[string]any {
+	t.Helper()
+	var recs []map[string]any
+	for _, e := range filterEvents(collectEvents(t, buf), streamID) {
+		if e.Recording != nil {
+			recs = append(recs, e.Recording)
+		}
+	}
+	return recs
+}
+
+func TestStreamManager_StartStopRecording(t *testing.T) {
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManager(newFakeDevicePool(), ew)
+	defer sm.StopAll()
+
+	var recs []*fakeRecording
+	sm.startRecording = func(device, _, path string) (screenRecording, error) {
+		r := &fakeRecording{device: device, path: path, done: make(chan struct{})}
+		recs = append(recs, r)
+		return r, nil
+	}
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	waitForEvents(t, &buf, 2, 2*time.Second) // booting, running
+
+	start := &pb.Command{StreamId: "stream-a", Payload: &pb.Command_StartRecording{StartRecording: &pb.StartRecording{Path: "/tmp/a.mp4"}}}
+	sm.HandleCommand(ctx, start)
+	// A second recording of the same stream is rejected.
+	sm.HandleCommand(ctx, start)
+	if len(recs) != 1 {
+		t.Fatalf("started %d recordings, want 1", len(recs))
+	}
+	sm.mu.Lock()
+	udid := sm.streams["stream-a"].deviceUDID
+	sm.mu.Unlock()
+	if recs[0].device != udid || recs[0].path != "/tmp/a.mp4" {
+		t.Errorf("recording device=%q path=%q, want %q and /tmp/a.mp4", recs[0].device, recs[0].path, udid)
+	}
+
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_StopRecording{StopRecording: &pb.StopRecording{}}})
+	waitForEvents(t, &buf, 5, 2*time.Second)
+
+	got := recordingEvents(t, &buf, "stream-a")
+	if len(got) != 3 {
+		t.Fatalf("Recording events = %v, want started, rejected, stopped", got)
+	}
+	if got[0]["path"] != "/tmp/a.mp4" || got[0]["active"] != true || got[0]["error"] != "" {
+		t.Errorf("first event = %v, want active recording of /tmp/a.mp4", got[0])
+	}
+	if got[1]["active"] == true || !strings.Contains(fmt.Sprint(got[1]["error"]), "already recording") {
+		t.Errorf("second event = %v, want already-recording error", got[1])
+	}
+	if got[2]["path"] != "/tmp/a.mp4" || got[2]["active"] == true || got[2]["error"] != "" {
+		t.Errorf("last event = %v, want finished recording of /tmp/a.mp4", got[2])
+	}
+
+	// The stream can record again once the previous file is finalized.
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_StartRecording{StartRecording: &pb.StartRecording{Path: "/tmp/b.mp4"}}})
+	if len(recs) != 2 {
+		t.Fatalf("started %d recordings after the first stopped, want 2", len(recs))
+	}
+}
+
+// TestStreamManager_RemoveStreamFinalizesRecording verifies that removing a
+// recording stream stops the recording before the stream is cleaned up.
+func TestStreamManager_RemoveStreamFinalizesRecording(t *testing.T) {
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManager(newFakeDevicePool(), ew)
+	defer sm.StopAll()
+
+	rec := &fakeRecording{done: make(chan struct{})}
+	sm.startRecording = func(_, _, _ string) (screenRecording, error) { return rec, nil }
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	waitForEvents(t, &buf, 2, 2*time.Second)
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_StartRecording{StartRecording: &pb.StartRecording{Path: "/tmp/a.mp4"}}})
+
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}}})
+	if rec.stops.Load() == 0 {
+		t.Fatal("recording not stopped when the stream was removed")
+	}
+	waitForEvents(t, &buf, 5, 2*time.Second) // + Recording started, StreamStopped, Recording stopped
+	got := recordingEvents(t, &buf, "stream-a")
+	if len(got) != 2 || got[1]["active"] == true {
+		t.Errorf("Recording events = %v, want started then stopped", got)
+	}
+}
+
+func TestStreamManager_StartRecordingRejected(t *testing.T) {
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManager(newFakeDevicePool(), ew)
+	defer sm.StopAll()
+	sm.startRecording = func(_, _, _ string) (screenRecording, error) {
+		t.Error("recording started for an invalid request")
+		return nil, errors.New("unexpected")
+	}
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	waitForEvents(t, &buf, 2, 2*time.Second)
+
+	for _, tt := range []struct{ streamID, path, wantErr string }{
+		{"stream-a", "relative.mp4", "absolute"},
+		{"missing", "/tmp/a.mp4", "unknown stream"},
+	} {
+		sm.HandleCommand(ctx, &pb.Command{StreamId: tt.streamID, Payload: &pb.Command_StartRecording{StartRecording: &pb.StartRecording{Path: tt.path}}})
+		got := recordingEvents(t, &buf, tt.streamID)
+		if len(got) == 0 || !strings.Contains(fmt.Sprint(got[len(got)-1]["error"]), tt.wantErr) {
+			t.Errorf("StartRecording(%s, %s): events %v, want error containing %q", tt.streamID, tt.path, got, tt.wantErr)
+		}
+	}
+}
+
+func TestStreamManager_EmptyCommand(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	defer sm.StopAll()
+
+	// Command with no payload should not panic.
+	sm.HandleCommand(context.Background(), &pb.Command{StreamId: "x"})
+}
+
+// TestStreamManager_FullLifecycle verifies the fake launcher sends StreamStarted
+// and Frame events, and RemoveStream produces StreamStopped{removed}.
+func TestStreamManager_FullLifecycle(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManagerWithRunners(pool, ew)
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		udid, err := sm.pool.Acquire(ctx, s.deviceType, s.runtime)
+		if err != nil {
+			s.sendStopped(sm.ew, "resource_error", err.Error(), "")
+			return
+		}
+		s.deviceUDID = udid
+
+		_ = sm.ew.Send(&pb.Event{
+			StreamId: s.id,
+			Payload:  &pb.Event_StreamStarted{StreamStarted: &pb.StreamStarted{PreviewCount: 2}},
+		})
+
+		// Simulate frame sending.
+		_ = sm.ew.Send(&pb.Event{
+			StreamId: s.id,
+			Payload:  &pb.Event_Frame{Frame: &pb.Frame{Device: udid, File: s.file, Data: "AAAA"}},
+		})
+
+		<-ctx.Done()
+	}
+
+	ctx := t.Context()
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+
+	waitForEvents(t, &buf, 2, 2*time.Second) // StreamStarted + Frame
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}},
+	})
+
+	waitForEvents(t, &buf, 3, 2*time.Second) // + StreamStopped
+
+	sm.StopAll()
+
+	events := filterEvents(collectEvents(t, &buf), "stream-a")
+	var hasStarted, hasFrame, hasStopped bool
 	for _, e := range events {
 		if e.StreamStarted != nil {
 			hasStarted = true
@@ -463,7 +1531,7 @@ func TestStreamManager_FullLifecycle(t *testing.T) {
 			hasFrame = true
 		}
 		if e.StreamStopped != nil {
-			if reason, ok := e.StreamStopped["reason"].(string); ok && reason == "removed" {
+			if reason, ok := e.StreamStopped["reason"].(string); ok && reason == "user_removed" {
 				hasStopped = true
 			}
 		}
@@ -475,7 +1543,7 @@ func TestStreamManager_FullLifecycle(t *testing.T) {
 		t.Error("expected Frame event")
 	}
 	if !hasStopped {
-		t.Error("expected StreamStopped{removed} event")
+		t.Error("expected StreamStopped{user_removed} event")
 	}
 }
 
@@ -503,26 +1571,109 @@ func TestStreamManager_TwoStreamsWithFrames(t *testing.T) {
 
 	sm.HandleCommand(ctx, &pb.Command{
 		StreamId: "stream-a",
-		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
-	})
-	sm.HandleCommand(ctx, &pb.Command{
-		StreamId: "stream-b",
-		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/FugaView.swift", DeviceType: "iPad-Air", Runtime: "iOS-18-2"}},
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+	})
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-b",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/FugaView.swift", DeviceType: "iPad-Air", Runtime: "iOS-18-2"}},
+	})
+
+	waitForEvents(t, &buf, 2, 2*time.Second)
+
+	sm.StopAll()
+
+	events := collectEvents(t, &buf)
+	eventsA := filterEvents(events, "stream-a")
+	eventsB := filterEvents(events, "stream-b")
+
+	if len(eventsA) == 0 || eventsA[0].Frame == nil {
+		t.Error("expected Frame for stream-a")
+	}
+	if len(eventsB) == 0 || eventsB[0].Frame == nil {
+		t.Error("expected Frame for stream-b")
+	}
+}
+
+// TestStreamManager_BuildFailureKeepsStreamAlive verifies that a launch
+// that fails to build reports BuildFailed, keeps the stream, and launches
+// again with the file selected while it waited.
+func TestStreamManager_BuildFailureKeepsStreamAlive(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+	sm := newTestStreamManagerWithRunners(pool, ew)
+
+	const (
+		broken = "/path/to/Broken.swift"
+		fixed  = "/path/to/Fixed.swift"
+	)
+	attempts := make(chan string, 4)
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		for {
+			sm.mu.Lock()
+			file := s.file
+			sm.mu.Unlock()
+			attempts <- file
+			if file == fixed {
+				<-ctx.Done()
+				return
+			}
+			log := broken + ":3:5: error: cannot find 'Foo' in scope\n** BUILD FAILED **"
+			if err := sm.ew.Send(&pb.Event{StreamId: s.id, Payload: &pb.Event_BuildFailed{BuildFailed: newBuildFailed(log)}}); err != nil {
+				t.Error(err)
+			}
+			if !sm.awaitBuildFix(ctx, s) {
+				return
+			}
+		}
+	}
+
+	ctx := t.Context()
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: broken, DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
 	})
-
-	waitForEvents(t, &buf, 2, 2*time.Second)
-
+	for _, want := range []string{broken, fixed} {
+		select {
+		case got := <-attempts:
+			if got != want {
+				t.Fatalf("launch attempt for %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no launch attempt for %s", want)
+		}
+		if want == broken {
+			sm.HandleCommand(ctx, &pb.Command{
+				StreamId: "stream-a",
+				Payload:  &pb.Command_SwitchFile{SwitchFile: &pb.SwitchFile{File: fixed}},
+			})
+		}
+	}
+	waitForStreamCount(t, sm, 1, 2*time.Second)
 	sm.StopAll()
 
-	events := collectEvents(t, &buf)
-	eventsA := filterEvents(events, "stream-a")
-	eventsB := filterEvents(events, "stream-b")
-
-	if len(eventsA) == 0 || eventsA[0].Frame == nil {
-		t.Error("expected Frame for stream-a")
+	var failed []map[string]any
+	for _, e := range filterEvents(collectEvents(t, &buf), "stream-a") {
+		if e.StreamStopped != nil && e.StreamStopped["reason"] == "build_error" {
+			t.Errorf("build failure should not stop the stream: %v", e.StreamStopped)
+		}
+		if e.BuildFailed != nil {
+			failed = append(failed, e.BuildFailed)
+		}
 	}
-	if len(eventsB) == 0 || eventsB[0].Frame == nil {
-		t.Error("expected Frame for stream-b")
+	if len(failed) != 1 {
+		t.Fatalf("expected 1 BuildFailed event, got %d", len(failed))
+	}
+	diags, _ := failed[0]["diagnostics"].([]any)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", failed[0]["diagnostics"])
+	}
+	d := diags[0].(map[string]any)
+	if d["file"] != broken || d["line"] != float64(3) || d["column"] != float64(5) || d["severity"] != "error" {
+		t.Errorf("unexpected diagnostic: %v", d)
+	}
+	if log, _ := failed[0]["log"].(string); !strings.Contains(log, "BUILD FAILED") {
+		t.Errorf("log should carry the raw output, got %q", log)
 	}
 }
 
@@ -922,6 +2073,423 @@ func TestStreamManager_SharedIndexCache(t *testing.T) {
 	sm.StopAll()
 }
 
+// TestStreamManager_ProjectSwitchRestartsWatcher verifies that an AddStream
+// for a different project, sent after all streams of the previous project
+// have been removed, restarts the shared watcher on the new project root.
+func TestStreamManager_ProjectSwitchRestartsWatcher(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	for _, d := range []string{dirA, dirB} {
+		if err := os.WriteFile(filepath.Join(d, "View.swift"), []byte("// v1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pcA, _ := NewProjectConfig(filepath.Join(dirA, "A.xcodeproj"), "", "Scheme", "")
+
+	br, tc, ar, fc, sl := nopRunners()
+	sm := NewStreamManager(pool, ew, pcA, "", build.NewPreparer(pcA, build.ProjectDirs{}, build.Incremental, br), br, tc, ar, fc, sl, false, 32, 0)
+
+	ctx := t.Context()
+	// A failing lister makes the watcher walk the root instead of asking git.
+	walk := &errSourceLister{err: fmt.Errorf("not a git repository")}
+	sm.newWatcher = func(root string) (*watch.SharedWatcher, error) {
+		return watch.NewSharedWatcher(ctx, root, walk)
+	}
+	w, err := sm.newWatcher(dirA)
+	if err != nil {
+		t.Fatalf("creating watcher: %v", err)
+	}
+	sm.watcher = w
+	defer sm.closeWatcher()
+
+	changes := make(chan string, 16)
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		if w := sm.currentWatcher(); w != nil {
+			w.AddListener(s.id, s.fileChangeCh)
+			s.watcher = w
+			defer w.RemoveListener(s.id)
+		}
+		for {
+			select {
+			case path := <-s.fileChangeCh:
+				changes <- path
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: filepath.Join(dirA, "View.swift")}},
+	})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "stream-a", Payload: &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}}})
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-b",
+		Payload: &pb.Command_AddStream{AddStream: &pb.AddStream{
+			File:    filepath.Join(dirB, "View.swift"),
+			Project: filepath.Join(dirB, "B.xcodeproj"),
+		}},
+	})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+
+	if got := sm.currentWatcher().Root(); got != dirB {
+		t.Fatalf("watcher root = %q, want %q", got, dirB)
+	}
+	if got := sm.pc.Project; got != filepath.Join(dirB, "B.xcodeproj") {
+		t.Errorf("active project = %q, want B.xcodeproj", got)
+	}
+	if sm.pc.Scheme != "Scheme" {
+		t.Errorf("scheme should be inherited from the previous project, got %q", sm.pc.Scheme)
+	}
+
+	// Changes under the old root must not reach the new stream.
+	if err := os.WriteFile(filepath.Join(dirA, "View.swift"), []byte("// v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case path := <-changes:
+		t.Fatalf("unexpected change from old project root: %s", path)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(filepath.Join(dirB, "View.swift"), []byte("// v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case path := <-changes:
+		if filepath.Dir(path) != dirB {
+			t.Errorf("change path = %q, want file under %q", path, dirB)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("file change under new project root not delivered")
+	}
+
+	sm.StopAll()
+}
+
+// TestStreamManager_ProjectSwitchResolvesConfiguration verifies that the
+// configuration resolved for the serve project is not carried over to
+// another project or scheme, which gets its own scheme default instead.
+func TestStreamManager_ProjectSwitchResolvesConfiguration(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	schemes := filepath.Join(dirB, "B.xcodeproj", "xcshareddata", "xcschemes")
+	if err := os.MkdirAll(schemes, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(schemes, "Scheme.xcscheme"), []byte(`<Scheme><LaunchAction buildConfiguration = "Staging"/></Scheme>`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pcA, _ := NewProjectConfig(filepath.Join(dirA, "A.xcodeproj"), "", "Scheme", "Beta") // as resolved by previewPreamble
+	pcA.Toolchain = "swift"
+
+	br, tc, ar, fc, sl := nopRunners()
+	sm := NewStreamManager(pool, ew, pcA, "", build.NewPreparer(pcA, build.ProjectDirs{}, build.Incremental, br), br, tc, ar, fc, sl, false, 32, 0)
+	sm.StreamLauncher = func(ctx context.Context, _ *StreamManager, _ *stream) { <-ctx.Done() }
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	add := func(id string, a *pb.AddStream) {
+		t.Helper()
+		sm.HandleCommand(ctx, &pb.Command{StreamId: id, Payload: &pb.Command_AddStream{AddStream: a}})
+		waitForStreamCount(t, sm, 1, 2*time.Second)
+		sm.HandleCommand(ctx, &pb.Command{StreamId: id, Payload: &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}}})
+		waitForStreamCount(t, sm, 0, 2*time.Second)
+	}
+
+	add("same", &pb.AddStream{File: filepath.Join(dirA, "View.swift"), Scheme: "Scheme"})
+	if got := sm.pc.Configuration; got != "Beta" {
+		t.Errorf("configuration = %q for the serve project and scheme, want Beta", got)
+	}
+
+	add("other-scheme", &pb.AddStream{File: filepath.Join(dirA, "View.swift"), Scheme: "Other"})
+	if got := sm.pc.Configuration; got != "" {
+		t.Errorf("configuration = %q for another scheme without a default, want none", got)
+	}
+
+	add("other-project", &pb.AddStream{File: filepath.Join(dirB, "View.swift"), Project: filepath.Join(dirB, "B.xcodeproj"), Scheme: "Scheme"})
+	if got := sm.pc.Configuration; got != "Staging" {
+		t.Errorf("configuration = %q for project B, want its scheme's Staging", got)
+	}
+	if got := sm.pc.Toolchain; got != "swift" {
+		t.Errorf("toolchain = %q for project B, want the serve toolchain swift", got)
+	}
+}
+
+// resolveGateBuildRunner blocks FetchBuildSettings until release is closed, then
+// reports configuration.
+type resolveGateBuildRunner struct {
+	fakeBuildRunner
+	configuration string
+	entered       chan struct{}
+	release       chan struct{}
+}
+
+func (b *resolveGateBuildRunner) FetchBuildSettings(ctx context.Context, _ []string) ([]byte, error) {
+	close(b.entered)
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []byte(`[{"buildSettings":{"CONFIGURATION":"` + b.configuration + `"}}]`), nil
+}
+
+// TestStreamManager_ProjectSwitchResolvesWithoutLock verifies that the
+// xcodebuild call resolving a switched-to scheme's configuration does not
+// hold sm.mu, so other commands are not stalled behind it.
+func TestStreamManager_ProjectSwitchResolvesWithoutLock(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	dir := t.TempDir()
+	pc, _ := NewProjectConfig(filepath.Join(dir, "A.xcodeproj"), "", "Scheme", "Debug")
+	br := &resolveGateBuildRunner{configuration: "Release", entered: make(chan struct{}), release: make(chan struct{})}
+	_, tc, ar, fc, sl := nopRunners()
+	sm := NewStreamManager(pool, ew, pc, "", build.NewPreparer(pc, build.ProjectDirs{}, build.Incremental, br), br, tc, ar, fc, sl, false, 32, 0)
+	sm.StreamLauncher = func(ctx context.Context, _ *StreamManager, _ *stream) { <-ctx.Done() }
+	defer sm.StopAll()
+
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		sm.HandleCommand(t.Context(), &pb.Command{StreamId: "stream-a", Payload: &pb.Command_AddStream{AddStream: &pb.AddStream{File: filepath.Join(dir, "View.swift"), Scheme: "Other"}}})
+	}()
+
+	select {
+	case <-br.entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("configuration not resolved")
+	}
+	if !sm.mu.TryLock() {
+		t.Error("sm.mu held while resolving the configuration")
+	} else {
+		sm.mu.Unlock()
+	}
+	close(br.release)
+	<-added
+
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+	sm.mu.Lock()
+	got := sm.pc
+	sm.mu.Unlock()
+	if got.Scheme != "Other" || got.Configuration != "Release" {
+		t.Errorf("active project = %s/%s, want Other/Release", got.Scheme, got.Configuration)
+	}
+}
+
+// TestStreamManager_AppSourceFromFirstStream verifies that the file of the
+// first stream of a project becomes AppSource, which picks the app of a
+// multi-app scheme, and that later streams of the project keep it.
+func TestStreamManager_AppSourceFromFirstStream(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	pcA, _ := NewProjectConfig(filepath.Join(dirA, "A.xcodeproj"), "", "Scheme", "Debug")
+
+	br, tc, ar, fc, sl := nopRunners()
+	sm := NewStreamManager(pool, ew, pcA, "", build.NewPreparer(pcA, build.ProjectDirs{}, build.Incremental, br), br, tc, ar, fc, sl, false, 32, 0)
+	sm.StreamLauncher = func(ctx context.Context, _ *StreamManager, _ *stream) { <-ctx.Done() }
+	defer sm.StopAll()
+
+	ctx := t.Context()
+	add := func(id string, a *pb.AddStream, n int) {
+		t.Helper()
+		sm.HandleCommand(ctx, &pb.Command{StreamId: id, Payload: &pb.Command_AddStream{AddStream: a}})
+		waitForStreamCount(t, sm, n, 2*time.Second)
+	}
+	remove := func(ids ...string) {
+		t.Helper()
+		for _, id := range ids {
+			sm.HandleCommand(ctx, &pb.Command{StreamId: id, Payload: &pb.Command_RemoveStream{RemoveStream: &pb.RemoveStream{}}})
+		}
+		waitForStreamCount(t, sm, 0, 2*time.Second)
+	}
+
+	first, second := filepath.Join(dirA, "Widget", "WidgetView.swift"), filepath.Join(dirA, "App", "AppView.swift")
+	add("first", &pb.AddStream{File: first}, 1)
+	add("second", &pb.AddStream{File: second}, 2)
+	if got := sm.pc.AppSource; got != first {
+		t.Errorf("AppSource = %q, want the first stream's %q", got, first)
+	}
+	if got := sm.pc.Configuration; got != "Debug" {
+		t.Errorf("configuration = %q after setting AppSource, want Debug", got)
+	}
+	remove("first", "second")
+
+	other := filepath.Join(dirB, "View.swift")
+	add("other-project", &pb.AddStream{File: other, Project: filepath.Join(dirB, "B.xcodeproj")}, 1)
+	if got := sm.pc.AppSource; got != other {
+		t.Errorf("AppSource = %q for project B, want %q", got, other)
+	}
+}
+
+// TestStreamManager_ProjectSwitchKeepsBuildMode verifies that the preparer
+// of a switched-to project builds with the serve build mode, so that
+// --no-reuse still forces a clean build.
+func TestStreamManager_ProjectSwitchKeepsBuildMode(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	output := showBuildSettingsOutput(build.Settings{
+		ModuleName:       "B",
+		OriginalBundleID: "com.example.B",
+		DeploymentTarget: "17.0",
+		BuiltProductsDir: filepath.Join(t.TempDir(), "Debug-iphonesimulator"),
+	})
+	br := &fakeBuildRunner{fetchOutput: output}
+	pcA, _ := NewProjectConfig("/a/A.xcodeproj", "", "Scheme", "Debug")
+
+	_, tc, ar, fc, sl := nopRunners()
+	sm := NewStreamManager(pool, ew, pcA, "", build.NewPreparer(pcA, build.ProjectDirs{}, build.Clean, br), br, tc, ar, fc, sl, false, 32, 0)
+	sm.buildMode = build.Clean
+	sm.StreamLauncher = func(ctx context.Context, _ *StreamManager, _ *stream) { <-ctx.Done() }
+	defer sm.StopAll()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	sm.HandleCommand(t.Context(), &pb.Command{
+		StreamId: "b",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/b/View.swift", Project: "/b/B.xcodeproj"}},
+	})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+
+	_, _ = sm.preparer.Prepare(t.Context())
+	if !slices.Contains(br.buildArgs, "clean") {
+		t.Errorf("build args after a project switch = %v, want a clean build", br.buildArgs)
+	}
+}
+
+// TestStreamManager_WatchToggle verifies that a stream added with watch=false
+// ignores file changes while a default stream reloads, and that SetWatch
+// toggles this at runtime.
+func TestStreamManager_WatchToggle(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "View.swift")
+	if err := os.WriteFile(file, []byte("// v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := newTestStreamManagerWithRunners(pool, ew)
+	ctx := t.Context()
+	// A failing lister makes the watcher walk dir instead of asking git.
+	w, err := watch.NewSharedWatcher(ctx, dir, &errSourceLister{err: fmt.Errorf("not a git repository")})
+	if err != nil {
+		t.Fatalf("creating watcher: %v", err)
+	}
+	sm.watcher = w
+	defer sm.closeWatcher()
+
+	reloads := map[string]chan string{"live": make(chan string, 16), "pinned": make(chan string, 16)}
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		if w := sm.currentWatcher(); w != nil {
+			s.registerWatcher(w)
+		}
+		for {
+			select {
+			case path := <-s.fileChangeCh:
+				reloads[s.id] <- path
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	noWatch := false
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "live", Payload: &pb.Command_AddStream{AddStream: &pb.AddStream{File: file}}})
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "pinned", Payload: &pb.Command_AddStream{AddStream: &pb.AddStream{File: file, Watch: &noWatch}}})
+	waitForStreamCount(t, sm, 2, 2*time.Second)
+	time.Sleep(100 * time.Millisecond) // let the stream goroutines register on the watcher
+
+	expectReload := func(id string, want bool) {
+		t.Helper()
+		select {
+		case path := <-reloads[id]:
+			if !want {
+				t.Errorf("stream %s reloaded for %s, want no reload", id, path)
+			}
+		case <-time.After(500 * time.Millisecond):
+			if want {
+				t.Errorf("stream %s did not reload", id)
+			}
+		}
+		// Drain duplicate events from the same write.
+		for {
+			select {
+			case <-reloads[id]:
+			case <-time.After(100 * time.Millisecond):
+				return
+			}
+		}
+	}
+
+	if err := os.WriteFile(file, []byte("// v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectReload("live", true)
+	expectReload("pinned", false)
+
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "pinned", Payload: &pb.Command_SetWatch{SetWatch: &pb.SetWatch{Enabled: true}}})
+	sm.HandleCommand(ctx, &pb.Command{StreamId: "live", Payload: &pb.Command_SetWatch{SetWatch: &pb.SetWatch{Enabled: false}}})
+
+	if err := os.WriteFile(file, []byte("// v3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectReload("pinned", true)
+	expectReload("live", false)
+
+	sm.StopAll()
+}
+
+// TestStreamManager_ProjectSwitchRejectedWhileBusy verifies that an AddStream
+// for a different project is rejected while streams of the active project remain.
+func TestStreamManager_ProjectSwitchRejectedWhileBusy(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	sm := newTestStreamManager(pool, ew)
+	ctx := t.Context()
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-a",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/a/HogeView.swift"}},
+	})
+	waitForStreamCount(t, sm, 1, 2*time.Second)
+
+	sm.HandleCommand(ctx, &pb.Command{
+		StreamId: "stream-b",
+		Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/b/FugaView.swift", Project: "/b/B.xcodeproj"}},
+	})
+
+	events := filterEvents(collectEvents(t, &buf), "stream-b")
+	if len(events) != 1 || events[0].StreamStopped == nil || events[0].StreamStopped["reason"] != "resource_error" {
+		t.Fatalf("expected a single resource_error StreamStopped for stream-b, got %+v", events)
+	}
+	if sm.pc.Project != "" {
+		t.Errorf("active project should be unchanged, got %q", sm.pc.Project)
+	}
+
+	sm.StopAll()
+}
+
 // syncBuffer is a thread-safe bytes.Buffer wrapper for use as an io.Writer
 // shared between goroutines (e.g. EventWriter + test assertions).
 type syncBuffer struct {
@@ -959,6 +2527,20 @@ func waitForEvents(t *testing.T, buf *syncBuffer, n int, timeout time.Duration)
 	}
 }
 
+// waitForCounter polls until c reaches n.
+func waitForCounter(t *testing.T, c *atomic.Int32, n int32, timeout time.Duration) {
+	t.Helper()
+	deadline := time.After(timeout)
+	for c.Load() < n {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for counter %d (got %d)", n, c.Load())
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
 // waitForStreamCount polls until sm.streams has exactly n entries.
 func waitForStreamCount(t *testing.T, sm *StreamManager, n int, timeout time.Duration) {
 	t.Helper()
@@ -1123,7 +2705,7 @@ type cleanupCountingIDBClient struct {
 }
 
 func (c *cleanupCountingIDBClient) ScreenSize(context.Context) (int, int, error) { return 0, 0, nil }
-func (c *cleanupCountingIDBClient) VideoStream(context.Context, int) (<-chan []byte, error) {
+func (c *cleanupCountingIDBClient) VideoStream(context.Context, int, idb.VideoFormat) (<-chan []byte, error) {
 	return nil, nil
 }
 func (c *cleanupCountingIDBClient) Tap(context.Context, float64, float64) error { return nil }
@@ -1159,6 +2741,9 @@ func (a *cleanupCountingAppRunner) Install(context.Context, string, string, stri
 func (a *cleanupCountingAppRunner) Launch(context.Context, string, string, string, map[string]string, []string) error {
 	return nil
 }
+func (a *cleanupCountingAppRunner) DataContainer(context.Context, string, string, string) (string, error) {
+	return "", nil
+}
 
 func TestStreamManager_CleanupStreamResources_Idempotent(t *testing.T) {
 	t.Parallel()
@@ -1169,13 +2754,15 @@ func TestStreamManager_CleanupStreamResources_Idempotent(t *testing.T) {
 
 	// Create a preparer with a fake runner that returns valid settings,
 	// then call Prepare() to populate the cache so that Cached() returns non-nil.
-	output := []byte(`    PRODUCT_MODULE_NAME = TestModule
-    PRODUCT_BUNDLE_IDENTIFIER = com.example.TestModule
-    IPHONEOS_DEPLOYMENT_TARGET = 17.0
-`)
+	output := showBuildSettingsOutput(build.Settings{
+		ModuleName:       "TestModule",
+		OriginalBundleID: "com.example.TestModule",
+		DeploymentTarget: "17.0",
+		BuiltProductsDir: "/tmp/build/Build/Products/Debug-iphonesimulator",
+	})
 	br := &fakeBuildRunner{fetchOutput: output}
 	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
-	preparer := build.NewPreparer(pc, build.ProjectDirs{Build: t.TempDir()}, false, br)
+	preparer := build.NewPreparer(pc, build.ProjectDirs{Build: t.TempDir()}, build.Incremental, br)
 	if _, err := preparer.Prepare(context.Background()); err != nil {
 		t.Fatalf("seeding preparer cache: %v", err)
 	}
@@ -1190,13 +2777,23 @@ func TestStreamManager_CleanupStreamResources_Idempotent(t *testing.T) {
 		t.Fatalf("creating socket placeholder: %v", err)
 	}
 
+	staging := t.TempDir()
+	frameFile := filepath.Join(staging, "frames", "0", "frame-1.png")
+	if err := os.MkdirAll(filepath.Dir(frameFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(frameFile, []byte("x"), 0o644); err != nil {
+		t.Fatalf("creating frame file: %v", err)
+	}
+
 	idbClient := &cleanupCountingIDBClient{}
 	bootComp := &cleanupCountingCompanion{doneCh: make(chan struct{})}
 	idbComp := &cleanupCountingCompanion{doneCh: make(chan struct{})}
 	s := &stream{
 		id:            "stream-cleanup",
+		project:       streamProject{pc: pc, preparer: preparer},
 		deviceUDID:    "FAKE-1",
-		dirs:          previewDirs{Socket: socketPath},
+		dirs:          previewDirs{Socket: socketPath, Staging: staging},
 		idbClient:     idbClient,
 		bootCompanion: bootComp,
 		idbCompanion:  idbComp,
@@ -1233,4 +2830,86 @@ func TestStreamManager_CleanupStreamResources_Idempotent(t *testing.T) {
 	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
 		t.Fatalf("socket file still exists after cleanup: %v", err)
 	}
+	if _, err := os.Stat(filepath.Join(staging, "frames")); !os.IsNotExist(err) {
+		t.Fatalf("frame dir still exists after cleanup: %v", err)
+	}
+}
+
+// gatedBuildRunner is a fakeBuildRunner whose Build blocks until gate is
+// closed, so that several streams can be made to wait on one build.
+type gatedBuildRunner struct {
+	fakeBuildRunner
+	gate chan struct{}
+}
+
+func (g *gatedBuildRunner) Build(ctx context.Context, args []string) ([]byte, error) {
+	<-g.gate
+	return g.fakeBuildRunner.Build(ctx, args)
+}
+
+// TestStreamManager_SharedBuildSendsOneBuildComplete verifies that two
+// streams waiting on the same shared build get a single project-scoped
+// BuildComplete event between them.
+func TestStreamManager_SharedBuildSendsOneBuildComplete(t *testing.T) {
+	var buf syncBuffer
+	ew := protocol.NewEventWriter(&buf)
+
+	br := &gatedBuildRunner{
+		fakeBuildRunner: fakeBuildRunner{
+			fetchOutput: showBuildSettingsOutput(build.Settings{
+				ModuleName:       "TestModule",
+				OriginalBundleID: "com.example.TestModule",
+				DeploymentTarget: "17.0",
+				BuiltProductsDir: "/tmp/build/Build/Products/Debug-iphonesimulator",
+			}),
+			buildOutput: []byte("/tmp/App/View.swift:7:13: warning: 'foregroundColor' is deprecated\n** BUILD SUCCEEDED **\n"),
+		},
+		gate: make(chan struct{}),
+	}
+	pc := ProjectConfig{Project: "/tmp/TestProject.xcodeproj", Scheme: "TestScheme"}
+	preparer := build.NewPreparer(pc, build.ProjectDirs{Build: t.TempDir()}, build.Incremental, br)
+	_, tc, ar, fc, sl := nopRunners()
+	sm := NewStreamManager(newFakeDevicePool(), ew, pc, "", preparer, br, tc, ar, fc, sl, false, 32, 0)
+	defer sm.StopAll()
+
+	var waiting, prepared atomic.Int32
+	sm.StreamLauncher = func(ctx context.Context, sm *StreamManager, s *stream) {
+		waiting.Add(1)
+		if _, err := sm.preparer.Prepare(ctx); err != nil {
+			t.Errorf("stream %s: Prepare: %v", s.id, err)
+			return
+		}
+		prepared.Add(1)
+		<-ctx.Done()
+	}
+
+	for _, id := range []string{"stream-a", "stream-b"} {
+		sm.HandleCommand(t.Context(), &pb.Command{
+			StreamId: id,
+			Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+		})
+	}
+	waitForCounter(t, &waiting, 2, 5*time.Second)
+	close(br.gate)
+	waitForCounter(t, &prepared, 2, 5*time.Second)
+
+	var completes []parsedEvent
+	for _, e := range collectEvents(t, &buf) {
+		if e.BuildComplete != nil {
+			completes = append(completes, e)
+		}
+	}
+	if len(completes) != 1 {
+		t.Fatalf("got %d BuildComplete events, want 1", len(completes))
+	}
+	bc := completes[0]
+	if bc.StreamID != "" {
+		t.Errorf("BuildComplete streamId = %q, want empty (project-scoped)", bc.StreamID)
+	}
+	if bc.BuildComplete["project"] != "/tmp/TestProject.xcodeproj" {
+		t.Errorf("project = %v, want /tmp/TestProject.xcodeproj", bc.BuildComplete["project"])
+	}
+	if warnings, _ := bc.BuildComplete["warnings"].([]any); len(warnings) != 1 {
+		t.Errorf("warnings = %v, want the one warning of the build", bc.BuildComplete["warnings"])
+	}
 }
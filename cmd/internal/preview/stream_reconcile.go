@@ -0,0 +1,60 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// runReconciler calls reconcileDevices every interval until ctx is done.
+// Used by serve --reconcile-interval.
+func (sm *StreamManager) runReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.reconcileDevices(ctx)
+		}
+	}
+}
+
+// reconcileDevices refreshes the pool's view of the device set, which can
+// change outside axe (e.g. simulators deleted or created in Xcode) during
+// a long serve session. Streams whose simulator vanished are stopped with
+// reason "device_removed".
+func (sm *StreamManager) reconcileDevices(ctx context.Context) {
+	changes, err := sm.pool.Reconcile(ctx)
+	if err != nil {
+		slog.Warn("Failed to reconcile device set", "err", err)
+		return
+	}
+	for _, udid := range changes.Appeared {
+		slog.Info("Simulator added to the device set", "udid", udid)
+	}
+	if len(changes.Vanished) == 0 {
+		return
+	}
+	slog.Info("Simulators removed from the device set", "udids", changes.Vanished)
+
+	sm.mu.Lock()
+	var ids []string
+	for id, s := range sm.streams {
+		if s.deviceUDID != "" && slices.Contains(changes.Vanished, s.deviceUDID) {
+			ids = append(ids, id)
+		}
+	}
+	sm.mu.Unlock()
+
+	for _, id := range ids {
+		for _, s := range sm.stopStreams(id) {
+			slog.Warn("Stopping stream: its simulator was removed", "streamId", s.id, "udid", s.deviceUDID)
+			s.sendStopped(sm.ew, "device_removed",
+				fmt.Sprintf("simulator %s was removed from the device set", s.deviceUDID), "")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package preview
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
+	"github.com/k-kohey/axe/internal/preview/protocol"
+)
+
+func TestStreamManager_ReconcileStopsStreamsOnVanishedDevices(t *testing.T) {
+	pool := newFakeDevicePool()
+	var buf syncBuffer
+	sm := newTestStreamManager(pool, protocol.NewEventWriter(&buf))
+	ctx := t.Context()
+
+	for _, id := range []string{"stream-a", "stream-b"} {
+		sm.HandleCommand(ctx, &pb.Command{
+			StreamId: id,
+			Payload:  &pb.Command_AddStream{AddStream: &pb.AddStream{File: "/path/to/HogeView.swift", DeviceType: "iPhone-16-Pro", Runtime: "iOS-18-2"}},
+		})
+	}
+	waitForStreamCount(t, sm, 2, 2*time.Second)
+	waitForEvents(t, &buf, 4, 2*time.Second) // booting and running for both
+
+	sm.mu.Lock()
+	vanished := sm.streams["stream-a"].deviceUDID
+	sm.mu.Unlock()
+	pool.mu.Lock()
+	pool.vanished = []string{vanished, "NOT-IN-USE"}
+	pool.mu.Unlock()
+
+	sm.reconcileDevices(ctx)
+
+	sm.mu.Lock()
+	_, aActive := sm.streams["stream-a"]
+	_, bActive := sm.streams["stream-b"]
+	sm.mu.Unlock()
+	if aActive || !bActive {
+		t.Errorf("after reconcile: stream-a active = %v, stream-b active = %v; want only stream-b", aActive, bActive)
+	}
+
+	var stopped []parsedEvent
+	for _, e := range collectEvents(t, &buf) {
+		if e.StreamStopped != nil {
+			stopped = append(stopped, e)
+		}
+	}
+	if len(stopped) != 1 || stopped[0].StreamID != "stream-a" || stopped[0].StreamStopped["reason"] != "device_removed" {
+		t.Fatalf("StreamStopped events = %+v, want one device_removed for stream-a", stopped)
+	}
+	if msg := fmt.Sprint(stopped[0].StreamStopped["message"]); msg != fmt.Sprintf("simulator %s was removed from the device set", vanished) {
+		t.Errorf("message = %q", msg)
+	}
+
+	// The stream's teardown still releases its device.
+	pool.mu.Lock()
+	released := pool.released
+	pool.mu.Unlock()
+	if len(released) != 1 || released[0] != vanished {
+		t.Errorf("released = %v, want [%s]", released, vanished)
+	}
+
+	sm.StopAll()
+}
@@ -1085,7 +1085,7 @@ func runThunkCompileTestWithPaths(t *testing.T, sdk string, sources map[string]s
 			ModuleName: remappedCache.FileModuleName(path),
 		})
 	}
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", targetPath, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
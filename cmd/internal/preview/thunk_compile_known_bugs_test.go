@@ -117,7 +117,7 @@ func generateThunksForTest(t *testing.T, sdk string, sources map[string]string,
 		})
 	}
 
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", targetPath, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -331,7 +331,7 @@ struct SameBaseHost: View {
 	}
 
 	thunkDir := filepath.Join(t.TempDir(), "thunk")
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", srcTarget, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: srcTarget})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -434,7 +434,7 @@ struct LowerItemView: View {
 	}
 
 	thunkDir := filepath.Join(t.TempDir(), "thunk")
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", srcPathA, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: srcPathA})
 	if err != nil {
 		t.Fatal(err)
 	}
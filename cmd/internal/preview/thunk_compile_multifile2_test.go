@@ -431,7 +431,7 @@ struct MissingIndexDep {
 	}
 
 	thunkDir := filepath.Join(t.TempDir(), "thunk")
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", targetPath, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -594,3 +594,64 @@ func TestMultiFile_DependencyChain(t *testing.T) {
 		t.Errorf("expected 4 thunk files, got %d", len(thunkPaths))
 	}
 }
+
+// ============================================================
+// Multi 8: Preview-only mock source compiled alongside the thunks
+//
+// A --mock file replaces a data-loading method via @_dynamicReplacement.
+// It is compiled into the same replacement module as the generated thunks,
+// so it must typecheck together with them against the app module.
+// ============================================================
+
+const fixtureMockLoader = `import SwiftUI
+
+struct FeedLoader {
+    func load() -> [String] {
+        []
+    }
+}
+`
+
+const fixtureMockTarget = `import SwiftUI
+
+struct FeedView: View {
+    var items: [String] { FeedLoader().load() }
+    var body: some View {
+        List(items, id: \.self) { Text($0) }
+    }
+}
+
+#Preview {
+    FeedView()
+}
+`
+
+const fixtureMockSource = `@testable import TestModule
+
+extension FeedLoader {
+    @_dynamicReplacement(for: load())
+    func __mock_load() -> [String] {
+        ["Mock 1", "Mock 2"]
+    }
+}
+`
+
+func TestMultiFile_MockSource(t *testing.T) {
+	sdk := simulatorSDKPath(t)
+
+	sources := map[string]string{
+		"FeedLoader.swift": fixtureMockLoader,
+		"FeedView.swift":   fixtureMockTarget,
+	}
+	thunkPaths, _ := runThunkCompileTestWithPaths(t, sdk, sources, "FeedView.swift")
+
+	moduleSrcDir := t.TempDir()
+	var moduleSrcPaths []string
+	for name, src := range sources {
+		moduleSrcPaths = append(moduleSrcPaths, writeFixtureFile(t, moduleSrcDir, name, stripPreviewBlocks(src)))
+	}
+	moduleDir, _ := buildFixtureModule(t, moduleSrcPaths, compileTestModuleName, sdk)
+
+	mockPath := writeFixtureFile(t, t.TempDir(), "PreviewMocks.swift", fixtureMockSource)
+	typecheckGeneratedThunks(t, append(thunkPaths, mockPath), moduleDir, compileTestModuleName, sdk)
+}
@@ -635,7 +635,7 @@ func TestRealWorld_VeryLongFilePath(t *testing.T) {
 	}}
 
 	thunkDir := filepath.Join(t.TempDir(), "thunk")
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", parsePath, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: parsePath})
 	if err != nil {
 		t.Fatal(err)
 	}
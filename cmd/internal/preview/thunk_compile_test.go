@@ -230,7 +230,7 @@ func runThunkTestWithSharedModule(t *testing.T, sdk string,
 			ModuleName: remappedCache.FileModuleName(path),
 		})
 	}
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, thunkDir, "", targetPath, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: thunkDir, SourceFile: targetPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1105,7 +1105,7 @@ func TestThunkCompilation_PathEscaping(t *testing.T) {
 			ModuleName: remappedCache.FileModuleName(srcPath),
 		},
 	}
-	thunkPaths, err := codegen.GenerateThunks(files, compileTestModuleName, dirs.Thunk, "", srcPath, 0)
+	thunkPaths, err := codegen.GenerateThunks(files, codegen.ThunkOptions{ModuleName: compileTestModuleName, Dir: dirs.Thunk, SourceFile: srcPath})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -3,13 +3,18 @@ package preview
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/k-kohey/axe/internal/platform"
 	"github.com/k-kohey/axe/internal/preview/analysis"
 	"github.com/k-kohey/axe/internal/preview/build"
 	"github.com/k-kohey/axe/internal/preview/codegen"
+	pb "github.com/k-kohey/axe/internal/preview/previewproto"
 	"github.com/k-kohey/axe/internal/preview/protocol"
 )
 
@@ -30,13 +35,72 @@ type RunOptions struct {
 	PreviewSelector string
 	Serve           bool
 	PreferredDevice string
-	ReuseBuild      bool
+	Runtime         string // e.g. "iOS 18.2"; constrains simulator reuse and auto-create
+	Family          string // "iPhone" or "iPad"; constrains simulator reuse and auto-create
+	DeviceType      string // e.g. "iPhone 15"; constrains simulator reuse and auto-create
+	BuildMode       build.Mode
 	FullThunk       bool
 	Strict          bool
 	NoHeadless      bool
 	MaxThunkFiles   int // max tracked files for incremental thunk (0 = unlimited)
 	PreThunkDepth   int // initial thunk generation depth (0 = target only, 1 = direct deps)
 
+	// Accessibility overrides applied to the simulator before the preview
+	// renders and re-applied on every reload.
+	Accessibility platform.AccessibilityOverrides
+
+	// StatusBar, when non-nil, overrides the simulator status bar before
+	// capture and clears it on exit. Only used in oneshot mode.
+	StatusBar *platform.StatusBarOverride
+
+	// MockSources are preview-only Swift files compiled into every thunk,
+	// e.g. to replace data-fetching methods via @_dynamicReplacement.
+	MockSources []string
+
+	// ThunkImports are extra modules imported by every thunk (--thunk-import).
+	ThunkImports []string
+
+	// SeedDir, when set, is copied into the app's data container after
+	// every install and before launch (see seedAppData).
+	SeedDir string
+
+	// KeepThunk keeps the generated thunk sources for inspection and
+	// logs their paths with the swiftc command (--keep-thunk).
+	KeepThunk bool
+
+	// Layout is the iPad multitasking layout and size class the preview
+	// renders with (--layout, --size-class).
+	Layout codegen.Layout
+
+	// InitArgs is a Swift expression constructing the view to preview
+	// (--init-args), e.g. `MyView(title: "Hi")`. It replaces the selected
+	// #Preview body, and provides one for a file without #Preview blocks.
+	InitArgs string
+
+	// BootTimeout bounds the wait for the simulator to reach "Booted"
+	// (0 = platform.DefaultBootTimeout).
+	BootTimeout time.Duration
+
+	// Fresh erases the simulator before it boots (--fresh), so that no app
+	// data or defaults carry over from earlier sessions. Only simulators in
+	// the axe device set are erased.
+	Fresh bool
+
+	// Logs prints the app's log to stderr (or sends LogStream events in
+	// serve mode) from launch until exit. Only used in watch and serve mode.
+	Logs bool
+
+	// Record, when set, records the simulator screen to this mp4 file from
+	// launch until exit, or for RecordDuration if positive. The file is
+	// finalized on Ctrl+C as well. Oneshot mode requires RecordDuration.
+	Record         string
+	RecordDuration time.Duration
+
+	// FailOnWarning makes a oneshot preview fail with build.ErrBuildWarnings
+	// after capturing when the xcodebuild output contained warnings. It
+	// forces a clean build (see oneshotBuildMode).
+	FailOnWarning bool
+
 	// Preparer caches FetchSettings results across multiple Run invocations.
 	// When set, Run() delegates to Preparer.Prepare() instead of calling
 	// build.Prepare() directly. This avoids redundant xcodebuild
@@ -63,12 +127,53 @@ func compileConfigFromSettings(s *build.Settings) codegen.CompileConfig {
 		BuiltProductsDir:    s.BuiltProductsDir,
 		DeploymentTarget:    s.DeploymentTarget,
 		SwiftVersion:        s.SwiftVersion,
+		Toolchain:           s.Toolchain,
 		ExtraIncludePaths:   s.ExtraIncludePaths,
 		ExtraFrameworkPaths: s.ExtraFrameworkPaths,
 		ExtraModuleMapFiles: s.ExtraModuleMapFiles,
+		ExtraSources:        s.MockSources,
+		KeepThunk:           s.KeepThunk,
 	}
 }
 
+// newStreamStatus builds a StreamStatus for phase that reports the active
+// simulator overrides and layout.
+func newStreamStatus(phase string, a platform.AccessibilityOverrides, l codegen.Layout) *pb.StreamStatus {
+	return &pb.StreamStatus{
+		Phase:     phase,
+		Overrides: a.Labels(),
+		Layout:    l.Name,
+		SizeClass: l.EffectiveSizeClass(),
+	}
+}
+
+// newBuildFailed builds a BuildFailed from the raw output of a failed
+// build or thunk compile, with the diagnostics parsed out of it.
+func newBuildFailed(log string) *pb.BuildFailed {
+	return &pb.BuildFailed{Log: log, Diagnostics: buildDiagnostics(build.ParseDiagnostics(log))}
+}
+
+// buildDiagnostics converts parsed compiler diagnostics to their protocol form.
+func buildDiagnostics(ds []build.Diagnostic) []*pb.BuildDiagnostic {
+	var out []*pb.BuildDiagnostic
+	for _, d := range ds {
+		out = append(out, &pb.BuildDiagnostic{
+			File:     d.File,
+			Line:     uint32(d.Line),
+			Column:   uint32(d.Column),
+			Severity: d.Severity,
+			Message:  d.Message,
+		})
+	}
+	return out
+}
+
+// isBuildFailure reports whether err is a failed xcodebuild or thunk
+// compile, i.e. a failure the user fixes by editing source.
+func isBuildFailure(err error) bool {
+	return errors.Is(err, build.ErrBuildFailed) || errors.Is(err, codegen.ErrCompileFailed)
+}
+
 // sharedIndexCache is a thread-safe wrapper around IndexStoreCache.
 // In multi-stream mode a single instance is shared across all streams via
 // StreamManager, so that when any stream rebuilds (refreshing the on-disk
@@ -109,6 +214,7 @@ type watchState struct {
 	previewIndex    int                       // current 0-based preview index
 	previewCount    int                       // total number of #Preview blocks (0 = unknown)
 	building        bool                      // true while rebuildAndRelaunch is running
+	rebuildPending  string                    // source file of a rebuild requested while building; "" = none
 	skeletonMap     map[string]string         // file path → skeleton hash
 	trackedFiles    []string                  // target + dependency file paths
 	depGraph        *analysis.DependencyGraph // transitive dependency graph (nil = fallback to rebuild)
@@ -134,12 +240,17 @@ type watchContext struct {
 	serve         bool   // true when running in serve mode (IDE integration)
 	ew            *protocol.EventWriter
 
+	accessibility platform.AccessibilityOverrides
+	overrides     *platform.DeviceOverrides // applies accessibility to device; nil leaves it unchanged
+	layout        codegen.Layout
+
 	// Injected runners for testability.
 	build     build.Runner
 	toolchain ToolchainRunner
 	app       AppRunner
 	copier    FileCopier
 	sources   SourceLister
+	crashes   CrashReporter // explains a relaunch that crashes; nil skips the check
 }
 
 // previewDirs manages temp directories scoped per project path.
@@ -151,7 +262,7 @@ type previewDirs struct {
 	Thunk   string // Session/thunk
 	Loader  string // Session/loader
 	Staging string // Session/staging
-	Socket  string // Session/loader.sock
+	Socket  string // Root/<hash>.sock, see loaderSocketPath
 }
 
 // maxSunPathLen is the maximum length of sockaddr_un.sun_path on macOS.
@@ -159,30 +270,24 @@ type previewDirs struct {
 const maxSunPathLen = 104
 
 // newPreviewDirs creates a previewDirs based on a hash of the project/workspace
-// path, with session-specific directories scoped by deviceUDID.
+// path, with the Build directory scoped by scheme and configuration and
+// session-specific directories scoped by deviceUDID.
 //
 // The Unix domain socket is placed directly under Root (not under Session)
 // because macOS limits sun_path to 104 bytes. The full Session path with a
-// UUID device identifier easily exceeds that limit.
-func newPreviewDirs(projectPath string, deviceUDID string) (previewDirs, error) {
-	pd, err := build.NewProjectDirs(projectPath)
+// UUID device identifier easily exceeds that limit. See loaderSocketPath for
+// how an overly long cache directory is handled.
+func newPreviewDirs(pc ProjectConfig, deviceUDID string) (previewDirs, error) {
+	pd, err := build.NewProjectDirs(pc)
 	if err != nil {
 		return previewDirs{}, err
 	}
 
 	session := filepath.Join(pd.Root, "devices", deviceUDID)
 
-	// Hash the UDID to keep the socket path short while guaranteeing
-	// uniqueness per device. 8 bytes (16 hex chars) gives 64-bit space,
-	// more than enough for the handful of concurrent devices we support.
-	uh := sha256.Sum256([]byte(deviceUDID))
-	socketPath := filepath.Join(pd.Root, fmt.Sprintf("%x.sock", uh[:8]))
-
-	if len(socketPath) >= maxSunPathLen {
-		return previewDirs{}, fmt.Errorf(
-			"socket path exceeds Unix domain socket limit (%d >= %d): %s. "+
-				"Consider using a shorter cache directory path",
-			len(socketPath), maxSunPathLen, socketPath)
+	socketPath, err := loaderSocketPath(build.CacheRoot(), pd.Root, deviceUDID)
+	if err != nil {
+		return previewDirs{}, err
 	}
 
 	return previewDirs{
@@ -195,6 +300,78 @@ func newPreviewDirs(projectPath string, deviceUDID string) (previewDirs, error)
 	}, nil
 }
 
+// socketName returns the loader socket file name for deviceUDID.
+// 8 bytes (16 hex chars) of the hash give a 64-bit space, more than
+// enough for the handful of concurrent devices we support.
+func socketName(deviceUDID string) string {
+	uh := sha256.Sum256([]byte(deviceUDID))
+	return fmt.Sprintf("%x.sock", uh[:8])
+}
+
+// sessionSocketName returns the socket file name used directly under the
+// cache root when <root>/<socketName> does not fit. It hashes the project
+// root together with the UDID so that sockets of different projects on
+// the same device stay distinct.
+func sessionSocketName(root, deviceUDID string) string {
+	h := sha256.Sum256([]byte(root + "\x00" + deviceUDID))
+	return fmt.Sprintf("%x.sock", h[:8])
+}
+
+// loaderSocketPath returns the loader socket path for deviceUDID in the
+// project cache root. Normally that is <root>/<socketName>; when a long
+// cache directory pushes it over maxSunPathLen, the project and device are
+// hashed into a single segment directly under cacheRoot, which saves the
+// preview-<hash>/ component. An error is returned only if even that form
+// does not fit.
+func loaderSocketPath(cacheRoot, root, deviceUDID string) (string, error) {
+	p := filepath.Join(root, socketName(deviceUDID))
+	if len(p) < maxSunPathLen {
+		return p, nil
+	}
+	p = filepath.Join(cacheRoot, sessionSocketName(root, deviceUDID))
+	if len(p) >= maxSunPathLen {
+		return "", fmt.Errorf(
+			"socket path exceeds Unix domain socket limit (%d >= %d): %s. "+
+				"Consider using a shorter cache directory path",
+			len(p), maxSunPathLen, p)
+	}
+	return p, nil
+}
+
+// RemoveDeviceSessions deletes the session directories and loader sockets
+// that newPreviewDirs allocated for deviceUDID, across every project cache.
+// Used when a simulator is deleted so its stale state does not linger.
+func RemoveDeviceSessions(deviceUDID string) error {
+	return removeDeviceSessions(build.CacheRoot(), deviceUDID)
+}
+
+// removeDeviceSessions implements RemoveDeviceSessions for the project
+// caches under cacheRoot.
+func removeDeviceSessions(cacheRoot, deviceUDID string) error {
+	if deviceUDID == "" {
+		return fmt.Errorf("empty device UDID")
+	}
+	roots, err := filepath.Glob(filepath.Join(cacheRoot, "preview-*"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, root := range roots {
+		if err := os.RemoveAll(filepath.Join(root, "devices", deviceUDID)); err != nil {
+			errs = append(errs, err)
+		}
+		for _, sock := range []string{
+			filepath.Join(root, socketName(deviceUDID)),
+			filepath.Join(cacheRoot, sessionSocketName(root, deviceUDID)),
+		} {
+			if err := os.Remove(sock); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // buildSkeletonMap computes skeleton hashes for the given files.
 func buildSkeletonMap(files []string) map[string]string {
 	m := make(map[string]string, len(files))
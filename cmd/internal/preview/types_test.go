@@ -1,6 +1,8 @@
 package preview
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -9,12 +11,19 @@ import (
 	pb "github.com/k-kohey/axe/internal/preview/analysisproto"
 )
 
-// mustNewPreviewDirs is a test helper that calls newPreviewDirs and fails on error.
+// mustNewPreviewDirs is a test helper that calls newPreviewDirs for the
+// "App" scheme of projectPath and fails on error.
 func mustNewPreviewDirs(t *testing.T, projectPath, deviceUDID string) previewDirs {
 	t.Helper()
-	dirs, err := newPreviewDirs(projectPath, deviceUDID)
+	return mustNewPreviewDirsForConfig(t, ProjectConfig{Project: projectPath, Scheme: "App"}, deviceUDID)
+}
+
+// mustNewPreviewDirsForConfig is like mustNewPreviewDirs with an explicit ProjectConfig.
+func mustNewPreviewDirsForConfig(t *testing.T, pc ProjectConfig, deviceUDID string) previewDirs {
+	t.Helper()
+	dirs, err := newPreviewDirs(pc, deviceUDID)
 	if err != nil {
-		t.Fatalf("newPreviewDirs(%q, %q): %v", projectPath, deviceUDID, err)
+		t.Fatalf("newPreviewDirs(%+v, %q): %v", pc, deviceUDID, err)
 	}
 	return dirs
 }
@@ -102,15 +111,59 @@ func TestNewPreviewDirs_DifferentProjectsDifferentBuild(t *testing.T) {
 	}
 }
 
+func TestNewPreviewDirs_SchemeIsolatesBuild(t *testing.T) {
+	const project = "/workspace/MyApp.xcodeproj"
+	appDev1 := mustNewPreviewDirsForConfig(t, ProjectConfig{Project: project, Scheme: "App", Configuration: "Debug"}, "device-1")
+	appDev2 := mustNewPreviewDirsForConfig(t, ProjectConfig{Project: project, Scheme: "App", Configuration: "Debug"}, "device-2")
+	widget := mustNewPreviewDirsForConfig(t, ProjectConfig{Project: project, Scheme: "Widget", Configuration: "Debug"}, "device-1")
+	release := mustNewPreviewDirsForConfig(t, ProjectConfig{Project: project, Scheme: "App", Configuration: "Release"}, "device-1")
+	swift6 := mustNewPreviewDirsForConfig(t, ProjectConfig{Project: project, Scheme: "App", Configuration: "Debug", Toolchain: "swift-6.0"}, "device-1")
+
+	if appDev1.Build != appDev2.Build {
+		t.Errorf("same scheme should share Build across devices: %s vs %s", appDev1.Build, appDev2.Build)
+	}
+	if appDev1.Build == widget.Build {
+		t.Errorf("different schemes should have different Build dirs, both %s", appDev1.Build)
+	}
+	if appDev1.Build == release.Build {
+		t.Errorf("different configurations should have different Build dirs, both %s", appDev1.Build)
+	}
+	if appDev1.Build == swift6.Build {
+		t.Errorf("different toolchains should have different Build dirs, both %s", appDev1.Build)
+	}
+	if appDev1.Root != widget.Root {
+		t.Errorf("schemes of one project should share Root: %s vs %s", appDev1.Root, widget.Root)
+	}
+	if !strings.HasPrefix(widget.Build, widget.Root) {
+		t.Errorf("Build should be under Root: Build=%s Root=%s", widget.Build, widget.Root)
+	}
+}
+
+func TestNewPreviewDirs_AmbiguousVariantsDoNotCollide(t *testing.T) {
+	const project = "/workspace/MyApp.xcodeproj"
+	pairs := [][2]ProjectConfig{
+		{{Project: project, Scheme: "A-B", Configuration: "C"}, {Project: project, Scheme: "A", Configuration: "B-C"}},
+		{{Project: project, Scheme: "App"}, {Project: project, Scheme: "App", Configuration: "default"}},
+		{{Project: project, Scheme: "a/b"}, {Project: project, Scheme: "a_b"}},
+	}
+	for _, p := range pairs {
+		a := mustNewPreviewDirsForConfig(t, p[0], "device-1")
+		b := mustNewPreviewDirsForConfig(t, p[1], "device-1")
+		if a.Build == b.Build {
+			t.Errorf("%+v and %+v share Build dir %s", p[0], p[1], a.Build)
+		}
+	}
+}
+
 func TestNewPreviewDirs_SocketPathTooLong(t *testing.T) {
 	// The socket path is <cacheDir>/axe/preview-<hash>/<hash>.sock.
 	// Since project/device inputs are hashed, only a long HOME (cache dir
-	// fallback) can push the path over 104 bytes. Set HOME to a long path
-	// to trigger the error.
+	// fallback) can push the path over 104 bytes. Set HOME to a path long
+	// enough that even the hashed fallback under the cache root overflows.
 	longHome := "/" + strings.Repeat("a", 120)
 	t.Setenv("HOME", longHome)
 
-	_, err := newPreviewDirs("/project", "device-1")
+	_, err := newPreviewDirs(ProjectConfig{Project: "/project", Scheme: "App"}, "device-1")
 	if err == nil {
 		t.Fatal("expected error for overly long socket path, got nil")
 	}
@@ -119,6 +172,114 @@ func TestNewPreviewDirs_SocketPathTooLong(t *testing.T) {
 	}
 }
 
+func TestLoaderSocketPath(t *testing.T) {
+	const udid = "12345678-ABCD-EF01-2345-6789ABCDEF01"
+	projectRoot := func(cacheRoot string) string {
+		return filepath.Join(cacheRoot, "preview-0123456789abcdef")
+	}
+
+	t.Run("short cache root uses project root", func(t *testing.T) {
+		cacheRoot := "/Users/me/Library/Caches/axe"
+		got, err := loaderSocketPath(cacheRoot, projectRoot(cacheRoot), udid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(projectRoot(cacheRoot), socketName(udid)); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("long cache root falls back to hashed segment", func(t *testing.T) {
+		cacheRoot := "/" + strings.Repeat("c", 69)
+		root := projectRoot(cacheRoot)
+		got, err := loaderSocketPath(cacheRoot, root, udid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(got) != cacheRoot {
+			t.Errorf("socket should be directly under cache root: %s", got)
+		}
+		if len(got) >= maxSunPathLen {
+			t.Errorf("socket path too long: len=%d path=%s", len(got), got)
+		}
+
+		other, err := loaderSocketPath(cacheRoot, filepath.Join(cacheRoot, "preview-fedcba9876543210"), udid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other == got {
+			t.Errorf("different projects should not share a socket: %s", got)
+		}
+		otherDevice, err := loaderSocketPath(cacheRoot, root, "other-device")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if otherDevice == got {
+			t.Errorf("different devices should not share a socket: %s", got)
+		}
+	})
+
+	t.Run("long UDID does not change length", func(t *testing.T) {
+		cacheRoot := "/" + strings.Repeat("c", 69)
+		short, err := loaderSocketPath(cacheRoot, projectRoot(cacheRoot), udid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		long, err := loaderSocketPath(cacheRoot, projectRoot(cacheRoot), strings.Repeat("U", 4096))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(long) != len(short) {
+			t.Errorf("hashed path length should be fixed: %d vs %d", len(long), len(short))
+		}
+	})
+
+	t.Run("cache root too long even for hashed form", func(t *testing.T) {
+		cacheRoot := "/" + strings.Repeat("c", 90)
+		_, err := loaderSocketPath(cacheRoot, projectRoot(cacheRoot), udid)
+		if err == nil || !strings.Contains(err.Error(), "socket path exceeds") {
+			t.Errorf("expected socket path error, got %v", err)
+		}
+	})
+}
+
+func TestRemoveDeviceSessions(t *testing.T) {
+	cacheRoot := t.TempDir()
+	var paths []string
+	for _, project := range []string{"preview-aaaa", "preview-bbbb"} {
+		root := filepath.Join(cacheRoot, project)
+		for _, udid := range []string{"OLD", "KEEP"} {
+			thunk := filepath.Join(root, "devices", udid, "thunk")
+			if err := os.MkdirAll(thunk, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			sock := filepath.Join(root, socketName(udid))
+			hashed := filepath.Join(cacheRoot, sessionSocketName(root, udid))
+			for _, f := range []string{sock, hashed} {
+				if err := os.WriteFile(f, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			paths = append(paths, filepath.Join(root, "devices", udid), sock, hashed)
+		}
+	}
+
+	if err := removeDeviceSessions(cacheRoot, "OLD"); err != nil {
+		t.Fatalf("removeDeviceSessions: %v", err)
+	}
+
+	for _, p := range paths {
+		_, err := os.Stat(p)
+		removed := os.IsNotExist(err)
+		wantRemoved := strings.Contains(p, "OLD") || strings.HasSuffix(p, socketName("OLD")) ||
+			p == filepath.Join(cacheRoot, sessionSocketName(filepath.Join(cacheRoot, "preview-aaaa"), "OLD")) ||
+			p == filepath.Join(cacheRoot, sessionSocketName(filepath.Join(cacheRoot, "preview-bbbb"), "OLD"))
+		if removed != wantRemoved {
+			t.Errorf("%s: removed=%v, want %v", p, removed, wantRemoved)
+		}
+	}
+}
+
 // --- sharedIndexCache tests ---
 
 func makeTestCache(typeName, filePath string) *analysis.IndexStoreCache {
@@ -0,0 +1,218 @@
+package preview
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// warmDevice is a pooled device booted ahead of the stream that claims it.
+type warmDevice struct {
+	udid      string
+	companion companionProcess
+}
+
+// warmKey is the device type and runtime a warmPool boots.
+type warmKey struct {
+	deviceType string
+	runtime    string
+}
+
+// warmPool keeps up to size serve simulators booted so that a stream can
+// skip the cold boot. It warms the device type and runtime last claimed:
+// every claim boots replacements in the background until size devices are
+// ready or booting again, and a claim for another device type discards the
+// devices warmed for the previous one. Warm devices are acquired from the
+// device pool and held, with their boot companions, until claimed or drained.
+type warmPool struct {
+	size          int
+	devices       DevicePoolInterface
+	deviceSetPath string
+
+	// boot boots udid and returns its boot companion.
+	boot func(ctx context.Context, udid, deviceSetPath string) (companionProcess, error)
+
+	mu       sync.Mutex
+	key      warmKey
+	ready    []warmDevice
+	booting  int
+	gen      int // incremented when key changes or the pool is drained
+	draining bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// newWarmPool returns a pool keeping size devices of devices booted with
+// headless boot companions, each waiting up to bootTimeout to boot.
+func newWarmPool(size int, devices DevicePoolInterface, deviceSetPath string, bootTimeout time.Duration) *warmPool {
+	p := &warmPool{
+		size:          size,
+		devices:       devices,
+		deviceSetPath: deviceSetPath,
+		boot: func(ctx context.Context, udid, deviceSetPath string) (companionProcess, error) {
+			c, err := bootWithRetry(ctx, udid, deviceSetPath, true, bootTimeout)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+// claim returns a booted device of deviceType and runtime, if one is ready,
+// and starts booting its replacement. A miss also starts warming, so that
+// the next stream of that kind finds a device ready.
+func (p *warmPool) claim(deviceType, runtime string) (warmDevice, bool) {
+	key := warmKey{deviceType: deviceType, runtime: runtime}
+	p.mu.Lock()
+	var stale []warmDevice
+	if key != p.key {
+		stale = p.ready
+		p.resetLocked(key)
+	}
+	var d warmDevice
+	found := false
+	for len(p.ready) > 0 && !found {
+		d, p.ready = p.ready[0], p.ready[1:]
+		select {
+		case <-d.companion.Done():
+			// The simulator died while waiting; boot a fresh one instead.
+			stale = append(stale, d)
+		default:
+			found = true
+		}
+	}
+	p.fillLocked()
+	p.mu.Unlock()
+
+	for _, s := range stale {
+		p.discard(s)
+	}
+	if found {
+		slog.Info("Claimed pre-booted simulator", "udid", d.udid, "deviceType", deviceType, "runtime", runtime)
+	}
+	return d, found
+}
+
+// claimUDID removes udid from the ready devices and returns its boot
+// companion, for a stream pinned to a device that happens to be warm.
+func (p *warmPool) claimUDID(udid string) (companionProcess, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, d := range p.ready {
+		if d.udid == udid {
+			p.ready = append(p.ready[:i], p.ready[i+1:]...)
+			p.fillLocked()
+			return d.companion, true
+		}
+	}
+	return nil, false
+}
+
+// drain stops warming, waits for boots in flight, and shuts down the ready
+// devices by releasing them to the device pool. A later claim warms again.
+func (p *warmPool) drain() {
+	p.mu.Lock()
+	p.draining = true
+	p.cancel()
+	stale := p.ready
+	p.resetLocked(p.key)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	for _, d := range stale {
+		p.discard(d)
+	}
+
+	p.mu.Lock()
+	p.draining = false
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.mu.Unlock()
+}
+
+// resetLocked forgets the ready devices and boots in flight and warms key
+// from now on. Boots of the previous generation discard their device when
+// they finish. Must be called with p.mu held.
+func (p *warmPool) resetLocked(key warmKey) {
+	p.key = key
+	p.ready = nil
+	p.booting = 0
+	p.gen++
+}
+
+// fillLocked starts booting devices until size are ready or booting.
+// Must be called with p.mu held.
+func (p *warmPool) fillLocked() {
+	if p.draining {
+		return
+	}
+	for len(p.ready)+p.booting < p.size {
+		p.booting++
+		p.wg.Add(1)
+		go p.warm(p.ctx, p.key, p.gen)
+	}
+}
+
+// warm acquires and boots one device of key and adds it to the ready
+// devices, unless the pool moved on to another generation meanwhile.
+func (p *warmPool) warm(ctx context.Context, key warmKey, gen int) {
+	defer p.wg.Done()
+
+	d, err := p.bootDevice(ctx, key)
+
+	p.mu.Lock()
+	current := gen == p.gen
+	if current {
+		p.booting--
+	}
+	if err == nil && current {
+		p.ready = append(p.ready, d)
+		p.mu.Unlock()
+		slog.Info("Pre-booted simulator ready", "udid", d.udid, "deviceType", key.deviceType, "runtime", key.runtime)
+		return
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Failed to pre-boot simulator", "deviceType", key.deviceType, "runtime", key.runtime, "err", err)
+		}
+		return
+	}
+	p.discard(d)
+}
+
+// bootDevice acquires a device of key from the device pool and boots it.
+func (p *warmPool) bootDevice(ctx context.Context, key warmKey) (warmDevice, error) {
+	udid, err := p.devices.Acquire(ctx, key.deviceType, key.runtime)
+	if err != nil {
+		return warmDevice{}, err
+	}
+	companion, err := p.boot(ctx, udid, p.deviceSetPath)
+	if err != nil {
+		p.release(udid)
+		return warmDevice{}, err
+	}
+	return warmDevice{udid: udid, companion: companion}, nil
+}
+
+// discard stops d's boot companion and releases it to the device pool,
+// which shuts it down.
+func (p *warmPool) discard(d warmDevice) {
+	if err := d.companion.Stop(); err != nil {
+		slog.Debug("Failed to stop boot companion of pre-booted simulator", "udid", d.udid, "err", err)
+	}
+	p.release(d.udid)
+}
+
+func (p *warmPool) release(udid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := p.devices.Release(ctx, udid); err != nil {
+		slog.Warn("Failed to release pre-booted simulator", "udid", udid, "err", err)
+	}
+}
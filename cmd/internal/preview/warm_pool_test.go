@@ -0,0 +1,153 @@
+package preview
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestWarmPool returns a warm pool over pool whose boots succeed at
+// once, and the boot companions it created by UDID.
+func newTestWarmPool(size int, pool *fakeDevicePool) (*warmPool, func(udid string) *fakeCompanion) {
+	p := newWarmPool(size, pool, "/tmp/set", 0)
+	var mu sync.Mutex
+	companions := make(map[string]*fakeCompanion)
+	p.boot = func(_ context.Context, udid, _ string) (companionProcess, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		c := &fakeCompanion{doneCh: make(chan struct{})}
+		companions[udid] = c
+		return c, nil
+	}
+	return p, func(udid string) *fakeCompanion {
+		mu.Lock()
+		defer mu.Unlock()
+		return companions[udid]
+	}
+}
+
+// waitForWarm polls until p has n ready devices and returns their UDIDs.
+func waitForWarm(t *testing.T, p *warmPool, n int, timeout time.Duration) []string {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		p.mu.Lock()
+		var udids []string
+		for _, d := range p.ready {
+			udids = append(udids, d.udid)
+		}
+		p.mu.Unlock()
+		if len(udids) == n {
+			return udids
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d warm devices (got %v)", n, udids)
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestWarmPool_ClaimedDeviceIsReplaced(t *testing.T) {
+	pool := newFakeDevicePool()
+	p, companion := newTestWarmPool(1, pool)
+
+	// Nothing is warm before the first claim, which starts warming its kind.
+	if _, ok := p.claim("iPhone", "iOS-18"); ok {
+		t.Fatal("claim on an empty pool succeeded")
+	}
+	warm := waitForWarm(t, p, 1, 2*time.Second)
+
+	d, ok := p.claim("iPhone", "iOS-18")
+	if !ok || d.udid != warm[0] {
+		t.Fatalf("claim = (%q, %v), want the warm device %q", d.udid, ok, warm[0])
+	}
+	if d.companion != companion(d.udid) {
+		t.Error("claim did not hand over the boot companion")
+	}
+
+	// A replacement is booted in the background.
+	replacement := waitForWarm(t, p, 1, 2*time.Second)
+	if replacement[0] == d.udid {
+		t.Fatalf("replacement is the claimed device %q", d.udid)
+	}
+
+	// Draining shuts down the spare but leaves the claimed device alone.
+	p.drain()
+	if !companion(replacement[0]).stopped.Load() {
+		t.Error("drain did not stop the spare's boot companion")
+	}
+	if companion(d.udid).stopped.Load() {
+		t.Error("drain stopped the claimed device's boot companion")
+	}
+	pool.mu.Lock()
+	released := slices.Clone(pool.released)
+	pool.mu.Unlock()
+	if !slices.Equal(released, replacement) {
+		t.Errorf("released = %v, want %v", released, replacement)
+	}
+}
+
+func TestWarmPool_OtherDeviceTypeDiscardsSpares(t *testing.T) {
+	pool := newFakeDevicePool()
+	p, companion := newTestWarmPool(1, pool)
+
+	p.claim("iPhone", "iOS-18")
+	iphone := waitForWarm(t, p, 1, 2*time.Second)
+
+	if _, ok := p.claim("iPad", "iOS-18"); ok {
+		t.Fatal("claim for another device type got the iPhone")
+	}
+	ipad := waitForWarm(t, p, 1, 2*time.Second)
+	if ipad[0] == iphone[0] {
+		t.Fatalf("iPad spare is the iPhone %q", iphone[0])
+	}
+	if !companion(iphone[0]).stopped.Load() {
+		t.Error("the iPhone spare was not shut down")
+	}
+	p.drain()
+}
+
+func TestWarmPool_SkipsDeadDevices(t *testing.T) {
+	pool := newFakeDevicePool()
+	p, companion := newTestWarmPool(1, pool)
+
+	p.claim("iPhone", "iOS-18")
+	dead := waitForWarm(t, p, 1, 2*time.Second)
+	close(companion(dead[0]).doneCh) // the simulator crashed while waiting
+
+	if d, ok := p.claim("iPhone", "iOS-18"); ok {
+		t.Fatalf("claim returned the dead device %q", d.udid)
+	}
+	pool.mu.Lock()
+	released := slices.Clone(pool.released)
+	pool.mu.Unlock()
+	if !slices.Equal(released, dead) {
+		t.Errorf("released = %v, want the dead device %v", released, dead)
+	}
+	waitForWarm(t, p, 1, 2*time.Second)
+	p.drain()
+}
+
+func TestWarmPool_ClaimUDID(t *testing.T) {
+	pool := newFakeDevicePool()
+	p, companion := newTestWarmPool(1, pool)
+
+	p.claim("iPhone", "iOS-18")
+	warm := waitForWarm(t, p, 1, 2*time.Second)
+
+	if _, ok := p.claimUDID("OTHER"); ok {
+		t.Error("claimUDID succeeded for a device that is not warm")
+	}
+	c, ok := p.claimUDID(warm[0])
+	if !ok || c != companion(warm[0]) {
+		t.Fatalf("claimUDID(%q) = (%v, %v), want its boot companion", warm[0], c, ok)
+	}
+	if replacement := waitForWarm(t, p, 1, 2*time.Second); replacement[0] == warm[0] {
+		t.Fatalf("replacement is the claimed device %q", warm[0])
+	}
+	p.drain()
+}
@@ -54,7 +54,10 @@ func NewDebouncer() *Debouncer {
 // HandleFileChange classifies a file change and starts/resets the
 // appropriate debounce timer. trackedSet contains the set of tracked
 // file paths (cleaned) for efficient lookup.
-func (d *Debouncer) HandleFileChange(cleanPath string, trackedSet map[string]bool) {
+//
+// It reports whether the change was coalesced into a signal that was
+// already pending, i.e. it did not start a new debounce window.
+func (d *Debouncer) HandleFileChange(cleanPath string, trackedSet map[string]bool) (coalesced bool) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -63,10 +66,10 @@ func (d *Debouncer) HandleFileChange(cleanPath string, trackedSet map[string]boo
 		// If a dependency rebuild is already pending, it will include
 		// this change too, so skip the fast path.
 		if d.depTimer != nil {
-			return
+			return true
 		}
 		if d.trackedTimer != nil {
-			d.trackedTimer.Stop()
+			coalesced = d.trackedTimer.Stop()
 		}
 		changedFile := cleanPath
 		d.trackedSeq++
@@ -85,12 +88,13 @@ func (d *Debouncer) HandleFileChange(cleanPath string, trackedSet map[string]boo
 		})
 	} else {
 		// Untracked .swift file changed → dependency rebuild path.
+		// A pending hot-reload is folded into the rebuild.
 		if d.trackedTimer != nil {
-			d.trackedTimer.Stop()
+			coalesced = d.trackedTimer.Stop()
 			d.trackedTimer = nil
 		}
-		if d.depTimer != nil {
-			d.depTimer.Stop()
+		if d.depTimer != nil && d.depTimer.Stop() {
+			coalesced = true
 		}
 
 		// Accumulate untracked files with deduplication.
@@ -117,6 +121,7 @@ func (d *Debouncer) HandleFileChange(cleanPath string, trackedSet map[string]boo
 			}
 		})
 	}
+	return coalesced
 }
 
 // ClearDepTimer resets the dependency timer reference and accumulated files
@@ -265,3 +265,30 @@ func TestDebouncer_ConcurrentAccess(t *testing.T) {
 	wg.Wait()
 	// No race detector errors means success.
 }
+
+func TestDebouncer_ReportsCoalescedChanges(t *testing.T) {
+	db := NewDebouncer()
+	defer db.Stop()
+
+	trackedSet := map[string]bool{"/src/HogeView.swift": true}
+	if db.HandleFileChange("/src/HogeView.swift", trackedSet) {
+		t.Error("first change should start a new debounce window")
+	}
+	if !db.HandleFileChange("/src/HogeView.swift", trackedSet) {
+		t.Error("second change within the window should be coalesced")
+	}
+	// The pending hot-reload is folded into the dependency rebuild.
+	if !db.HandleFileChange("/src/Other.swift", trackedSet) {
+		t.Error("untracked change should coalesce the pending hot-reload")
+	}
+	// Tracked changes are absorbed while a dependency rebuild is pending.
+	if !db.HandleFileChange("/src/HogeView.swift", trackedSet) {
+		t.Error("tracked change during a pending rebuild should be coalesced")
+	}
+
+	<-db.DepCh
+	db.ClearDepTimer()
+	if db.HandleFileChange("/src/HogeView.swift", trackedSet) {
+		t.Error("change after the signal was consumed should start a new window")
+	}
+}
@@ -14,6 +14,7 @@ import (
 // change events to all registered stream listeners.
 // Debounce is the stream's responsibility; the watcher delivers raw events.
 type SharedWatcher struct {
+	root      string
 	mu        sync.Mutex
 	watcher   *fsnotify.Watcher
 	listeners map[string]chan<- string // streamID → fileChangeCh
@@ -47,6 +48,7 @@ func NewSharedWatcher(ctx context.Context, watchRoot string, dl DirLister) (*Sha
 
 	loopCtx, cancel := context.WithCancel(ctx)
 	sw := &SharedWatcher{
+		root:      watchRoot,
 		watcher:   watcher,
 		listeners: make(map[string]chan<- string),
 		cancel:    cancel,
@@ -56,6 +58,11 @@ func NewSharedWatcher(ctx context.Context, watchRoot string, dl DirLister) (*Sha
 	return sw, nil
 }
 
+// Root returns the directory this watcher was created for.
+func (sw *SharedWatcher) Root() string {
+	return sw.root
+}
+
 // AddListener registers a stream to receive file change paths.
 func (sw *SharedWatcher) AddListener(streamID string, ch chan<- string) {
 	sw.mu.Lock()
@@ -77,7 +84,21 @@ func (sw *SharedWatcher) Close() {
 	_ = sw.watcher.Close()
 }
 
-// loop reads fsnotify events, filters for .swift Write/Create, and broadcasts
+// isReloadEvent reports whether an fsnotify event should trigger a reload.
+//
+// Only Write and Create on .swift files qualify. Watches are placed on
+// directories, never on individual files, so an editor that saves by deleting
+// and recreating a file (or renaming a temp file over it) produces a Remove or
+// Rename followed by a Create in the same watched directory. The Remove/Rename
+// is ignored and listeners stay registered, so the Create triggers the reload.
+func isReloadEvent(event fsnotify.Event) bool {
+	if !strings.HasSuffix(event.Name, ".swift") {
+		return false
+	}
+	return event.Has(fsnotify.Write) || event.Has(fsnotify.Create)
+}
+
+// loop reads fsnotify events, filters them with isReloadEvent, and broadcasts
 // the cleaned file path to all listeners with non-blocking sends.
 func (sw *SharedWatcher) loop(ctx context.Context) {
 	defer close(sw.done)
@@ -89,10 +110,7 @@ func (sw *SharedWatcher) loop(ctx context.Context) {
 			if !ok {
 				return
 			}
-			if !strings.HasSuffix(event.Name, ".swift") {
-				continue
-			}
-			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+			if !isReloadEvent(event) {
 				continue
 			}
 			cleanPath := filepath.Clean(event.Name)
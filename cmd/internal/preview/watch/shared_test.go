@@ -1,9 +1,9 @@
 package watch
 
 import (
+	"context"
 	"os"
 	"path/filepath"
-	"sync"
 	"testing"
 	"time"
 
@@ -24,41 +24,15 @@ func newTestSharedWatcher(t *testing.T, dir string) *SharedWatcher {
 		t.Fatalf("watching dir: %v", err)
 	}
 
-	stopCh := make(chan struct{})
-	loopDone := make(chan struct{})
-
-	var closeOnce sync.Once
+	ctx, cancel := context.WithCancel(context.Background())
 	sw := &SharedWatcher{
+		root:      dir,
 		watcher:   watcher,
 		listeners: make(map[string]chan<- string),
-		cancel:    func() { closeOnce.Do(func() { close(stopCh) }) },
-		done:      loopDone,
+		cancel:    cancel,
+		done:      make(chan struct{}),
 	}
-
-	go func() {
-		defer close(loopDone)
-		for {
-			select {
-			case <-stopCh:
-				return
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return
-				}
-				if filepath.Ext(event.Name) != ".swift" {
-					continue
-				}
-				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
-					continue
-				}
-				sw.broadcast(filepath.Clean(event.Name))
-			case _, ok := <-watcher.Errors:
-				if !ok {
-					return
-				}
-			}
-		}
-	}()
+	go sw.loop(ctx)
 
 	t.Cleanup(func() {
 		sw.Close()
@@ -162,3 +136,89 @@ func TestSharedWatcher_NonSwiftIgnored(t *testing.T) {
 		// Expected: no event.
 	}
 }
+
+// TestSharedWatcher_DeleteRecreate verifies that saving via delete+recreate or
+// rename-over (as some editors do) still delivers a change for the file.
+func TestSharedWatcher_DeleteRecreate(t *testing.T) {
+	tests := []struct {
+		name string
+		save func(t *testing.T, path string)
+	}{
+		{"delete then recreate", func(t *testing.T, path string) {
+			if err := os.Remove(path); err != nil {
+				t.Fatalf("removing file: %v", err)
+			}
+			if err := os.WriteFile(path, []byte("struct PreviewView { var v = 2 }"), 0o644); err != nil {
+				t.Fatalf("recreating file: %v", err)
+			}
+		}},
+		{"rename temp file over", func(t *testing.T, path string) {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, []byte("struct PreviewView { var v = 2 }"), 0o644); err != nil {
+				t.Fatalf("writing temp file: %v", err)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				t.Fatalf("renaming temp file: %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "PreviewView.swift")
+			if err := os.WriteFile(path, []byte("struct PreviewView {}"), 0o644); err != nil {
+				t.Fatalf("writing file: %v", err)
+			}
+
+			sw := newTestSharedWatcher(t, dir)
+			ch := make(chan string, 8)
+			sw.AddListener("a", ch)
+
+			tt.save(t, path)
+
+			select {
+			case got := <-ch:
+				if got != filepath.Clean(path) {
+					t.Errorf("got %s, want %s", got, path)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for event after recreate")
+			}
+
+			// The listener must survive the churn and see later edits too.
+			for len(ch) > 0 {
+				<-ch
+			}
+			if err := os.WriteFile(path, []byte("struct PreviewView { var v = 3 }"), 0o644); err != nil {
+				t.Fatalf("writing file: %v", err)
+			}
+			select {
+			case <-ch:
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for event after subsequent write")
+			}
+		})
+	}
+}
+
+func TestIsReloadEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event fsnotify.Event
+		want  bool
+	}{
+		{"write swift", fsnotify.Event{Name: "/p/A.swift", Op: fsnotify.Write}, true},
+		{"create swift", fsnotify.Event{Name: "/p/A.swift", Op: fsnotify.Create}, true},
+		{"remove swift", fsnotify.Event{Name: "/p/A.swift", Op: fsnotify.Remove}, false},
+		{"rename swift", fsnotify.Event{Name: "/p/A.swift", Op: fsnotify.Rename}, false},
+		{"chmod swift", fsnotify.Event{Name: "/p/A.swift", Op: fsnotify.Chmod}, false},
+		{"write non-swift", fsnotify.Event{Name: "/p/A.swift.tmp", Op: fsnotify.Write}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReloadEvent(tt.event); got != tt.want {
+				t.Errorf("isReloadEvent(%v) = %v, want %v", tt.event, got, tt.want)
+			}
+		})
+	}
+}
@@ -9,18 +9,70 @@ package procgroup
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
+// TraceEnv is the environment variable that enables command tracing when set to "1".
+const TraceEnv = "AXE_TRACE"
+
+// tracer logs every command created through this package; nil disables tracing.
+var tracer atomic.Pointer[slog.Logger]
+
+// EnableTrace makes Command and Setup log each external command with its full
+// arguments to w before it runs. w should be stderr so that serve-mode stdout
+// stays a clean protocol stream. Passing nil disables tracing. The returned
+// function restores the previous setting, e.g. for t.Cleanup.
+func EnableTrace(w io.Writer) (restore func()) {
+	var l *slog.Logger
+	if w != nil {
+		l = slog.New(slog.NewTextHandler(w, nil))
+	}
+	prev := tracer.Swap(l)
+	return func() { tracer.Store(prev) }
+}
+
+// trace logs cmd if tracing is enabled, along with the time left before ctx's
+// deadline (e.g. the simctl timeout) when it has one.
+func trace(ctx context.Context, cmd *exec.Cmd) {
+	l := tracer.Load()
+	if l == nil {
+		return
+	}
+	attrs := []any{"cmd", FormatCommand(cmd.Args)}
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs, "timeout", time.Until(deadline).Round(time.Millisecond))
+	}
+	l.Info("exec", attrs...)
+}
+
+// FormatCommand joins args into a single line, quoting arguments that contain
+// whitespace or quotes so that the command can be copied into a shell.
+func FormatCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'\\") {
+			a = strconv.Quote(a)
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
+}
+
 // Command creates an *exec.Cmd bound to the given context with process-group
 // isolation. When the context is cancelled the entire process group (not just
 // the leader) receives SIGKILL.
 func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, name, args...)
-	Setup(cmd)
+	setpgid(cmd)
+	trace(ctx, cmd)
 	cmd.Cancel = func() error {
 		return killProcessGroup(cmd.Process)
 	}
@@ -28,10 +80,16 @@ func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
 	return cmd
 }
 
-// Setup configures an existing *exec.Cmd to start in its own process group.
+// Setup configures an existing *exec.Cmd to start in its own process group
+// and traces it when tracing is enabled, so call it once the args are final.
 // Use this for commands that are not context-based (no Cancel override).
 // The caller is responsible for calling KillProcess when done.
 func Setup(cmd *exec.Cmd) {
+	setpgid(cmd)
+	trace(context.Background(), cmd)
+}
+
+func setpgid(cmd *exec.Cmd) {
 	if cmd.SysProcAttr == nil {
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 	}
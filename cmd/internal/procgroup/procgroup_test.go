@@ -1,6 +1,7 @@
 package procgroup_test
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"os/exec"
@@ -57,6 +58,32 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+// TestCommand_Trace verifies that enabled tracing logs the full command line
+// and the context's remaining timeout, and that nothing is logged otherwise.
+func TestCommand_Trace(t *testing.T) {
+	var buf bytes.Buffer
+	t.Cleanup(procgroup.EnableTrace(&buf))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	procgroup.Command(ctx, "echo", "hello world")
+
+	got := buf.String()
+	if !strings.Contains(got, `cmd="echo \"hello world\""`) {
+		t.Errorf("trace should contain the quoted command line, got %q", got)
+	}
+	if !strings.Contains(got, "timeout=") {
+		t.Errorf("trace should contain the timeout, got %q", got)
+	}
+
+	buf.Reset()
+	procgroup.EnableTrace(nil)
+	procgroup.Setup(exec.Command("echo", "hello"))
+	if buf.Len() != 0 {
+		t.Errorf("disabled trace should log nothing, got %q", buf.String())
+	}
+}
+
 // TestSignalProcess verifies that SignalProcess sends a signal to the process group.
 func TestSignalProcess(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
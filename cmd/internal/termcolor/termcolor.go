@@ -0,0 +1,78 @@
+// Package termcolor decides whether axe's human-facing output may use ANSI
+// colors.
+package termcolor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// Mode is the value of the --color flag.
+type Mode string
+
+const (
+	Auto   Mode = "auto"   // color only when writing to a terminal and NO_COLOR is unset
+	Always Mode = "always" // color even when redirected, ignoring NO_COLOR
+	Never  Mode = "never"  // never emit escape sequences
+)
+
+// NoColorEnv is the environment variable (https://no-color.org) that
+// disables color in Auto mode when set to a non-empty value.
+const NoColorEnv = "NO_COLOR"
+
+// ParseMode validates a --color flag value.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case Auto, Always, Never:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid color mode %q (must be auto, always, or never)", s)
+}
+
+// mode is the process-wide color mode; the zero value behaves as Auto.
+var mode atomic.Value
+
+// SetMode sets the color mode used by Enabled.
+func SetMode(m Mode) {
+	mode.Store(m)
+}
+
+func currentMode() Mode {
+	if m, ok := mode.Load().(Mode); ok {
+		return m
+	}
+	return Auto
+}
+
+// Enabled reports whether output written to w may be colored.
+func Enabled(w io.Writer) bool {
+	return resolve(currentMode(), os.Getenv(NoColorEnv) != "", isTerminal(w))
+}
+
+// resolve applies the mode to the environment. An explicit mode wins over
+// NO_COLOR, which only affects Auto.
+func resolve(m Mode, noColor, tty bool) bool {
+	switch m {
+	case Always:
+		return true
+	case Never:
+		return false
+	default:
+		return tty && !noColor
+	}
+}
+
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
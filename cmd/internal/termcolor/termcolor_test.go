@@ -0,0 +1,55 @@
+package termcolor
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"auto", "always", "never"} {
+		m, err := ParseMode(s)
+		if err != nil {
+			t.Fatalf("ParseMode(%q): %v", s, err)
+		}
+		if string(m) != s {
+			t.Errorf("ParseMode(%q) = %q", s, m)
+		}
+	}
+	if _, err := ParseMode("yes"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		mode    Mode
+		noColor bool
+		tty     bool
+		want    bool
+	}{
+		{Auto, false, true, true},
+		{Auto, false, false, false},
+		{Auto, true, true, false},
+		{Always, true, false, true},
+		{Never, false, true, false},
+	}
+	for _, tt := range tests {
+		if got := resolve(tt.mode, tt.noColor, tt.tty); got != tt.want {
+			t.Errorf("resolve(%s, noColor=%v, tty=%v) = %v, want %v", tt.mode, tt.noColor, tt.tty, got, tt.want)
+		}
+	}
+}
+
+func TestEnabled_NoColorEnv(t *testing.T) {
+	t.Cleanup(func() { SetMode(Auto) })
+	t.Setenv(NoColorEnv, "1")
+
+	SetMode(Auto)
+	if Enabled(os.Stderr) {
+		t.Error("auto should honor NO_COLOR")
+	}
+	SetMode(Always)
+	if !Enabled(os.Stderr) {
+		t.Error("always should override NO_COLOR")
+	}
+}
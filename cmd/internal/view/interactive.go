@@ -5,6 +5,7 @@ import (
 	"io"
 	"log/slog"
 	"math"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -24,7 +25,9 @@ const (
 	swiftUIFull
 )
 
-func RunInteractive(appName string, device string) error {
+// RunInteractive runs the view hierarchy browser. With color false the
+// TUI and the fzf filter draw without color.
+func RunInteractive(appName string, device string, color bool) error {
 	// Suppress all logs during TUI to avoid corrupting the terminal output.
 	prev := slog.Default()
 	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
@@ -210,7 +213,7 @@ func RunInteractive(appName string, device string) error {
 					return nil
 				}
 				app.Suspend(func() {
-					addr, err := runFzfFilter(lines)
+					addr, err := runFzfFilter(lines, color)
 					if err != nil || addr == "" {
 						return
 					}
@@ -372,6 +375,28 @@ func RunInteractive(appName string, device string) error {
 		})
 	}()
 
+	if !color {
+		screen, err := monochromeScreen()
+		if err != nil {
+			return fmt.Errorf("creating terminal screen: %w", err)
+		}
+		app.SetScreen(screen)
+	}
+
 	app.SetRoot(pages, true)
 	return app.Run()
 }
+
+// monochromeScreen returns a screen for the terminal named by $TERM with
+// its color capabilities removed, so that tcell draws without color.
+func monochromeScreen() (tcell.Screen, error) {
+	ti, err := tcell.LookupTerminfo(os.Getenv("TERM"))
+	if err != nil {
+		return nil, err
+	}
+	mono := *ti
+	mono.Colors = 0
+	mono.SetFg, mono.SetBg, mono.SetFgBg = "", "", ""
+	mono.SetFgRGB, mono.SetBgRGB, mono.SetFgBgRGB = "", "", ""
+	return tcell.NewTerminfoScreenFromTtyTerminfo(nil, &mono)
+}
@@ -0,0 +1,16 @@
+package view
+
+import "testing"
+
+func TestMonochromeScreen_NoColors(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("NO_COLOR", "")
+
+	screen, err := monochromeScreen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := screen.Colors(); got != 0 {
+		t.Errorf("Colors() = %d, want 0", got)
+	}
+}
@@ -262,8 +262,13 @@ func flattenTreeNodes(node *tview.TreeNode, depth int) []string {
 }
 
 // runFzfFilter launches fzf with the given lines and returns the selected address.
-func runFzfFilter(lines []string) (string, error) {
-	cmd := exec.Command("fzf", "--ansi", "--no-sort", "--header=Select a view", "--with-nth=2..")
+// With color false, fzf draws without color.
+func runFzfFilter(lines []string, color bool) (string, error) {
+	args := []string{"--ansi", "--no-sort", "--header=Select a view", "--with-nth=2.."}
+	if !color {
+		args = append(args, "--no-color")
+	}
+	cmd := exec.Command("fzf", args...)
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = nil
 
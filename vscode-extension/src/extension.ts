@@ -87,8 +87,8 @@ export function activate(context: vscode.ExtensionContext): void {
 					outputChannel.appendLine(event.streamStopped.diagnostic);
 				}
 
-				// "removed" is user-initiated — card already removed by untrackStream.
-				if (event.streamStopped.reason !== "removed") {
+				// "user_removed" is user-initiated — card already removed by untrackStream.
+				if (event.streamStopped.reason !== "user_removed") {
 					// CLI-side error — show error status on the card and remove from activeStreams.
 					const msg = event.streamStopped.diagnostic
 						? `${event.streamStopped.message}\n${event.streamStopped.diagnostic}`
@@ -20,11 +20,34 @@ export interface Command {
   nextPreview?: NextPreview | undefined;
   input?: Input | undefined;
   forceRebuild?: ForceRebuild | undefined;
+  setWatch?: SetWatch | undefined;
+  listDevices?: ListDevices | undefined;
+  setDevice?: SetDevice | undefined;
+  startRecording?: StartRecording | undefined;
+  stopRecording?: StopRecording | undefined;
+  shutdown?: Shutdown | undefined;
+  reload?: Reload | undefined;
+  poolStatus?: PoolStatus | undefined;
+}
+
+/**
+ * Shutdown ends the serve session gracefully: every stream is removed,
+ * companions are stopped, the pooled simulators axe booted are shut down,
+ * and the CLI exits with status 0 after sending ShutdownComplete. stream_id
+ * is ignored.
+ */
+export interface Shutdown {
 }
 
 /**
  * AddStream creates a new preview stream.
  * The CLI allocates a simulator from the device pool based on device_type + runtime.
+ * project/workspace/scheme/configuration optionally select a different project
+ * than the one serve was started with. The CLI switches the active project
+ * only when no streams of the previous project remain.
+ * An AddStream for a stream_id that is already running updates that stream:
+ * a new file or preview is switched in place, while a new device, runtime,
+ * or project restarts it.
  */
 export interface AddStream {
   /** Swift file path to preview */
@@ -33,6 +56,49 @@ export interface AddStream {
   deviceType: string;
   /** e.g. "com.apple.CoreSimulator.SimRuntime.iOS-18-2" */
   runtime: string;
+  /** path to .xcodeproj (empty = keep active project) */
+  project: string;
+  /** path to .xcworkspace (empty = keep active project) */
+  workspace: string;
+  /** scheme for project/workspace (empty = active scheme) */
+  scheme: string;
+  /** build configuration (empty = active configuration) */
+  configuration: string;
+  /** #Preview title, 0-based index, /regex/, or file:line (empty = first, or keep current on update) */
+  preview: string;
+  /**
+   * Requested Frame codec: "jpeg" (default), "png" (lossless, compressed at
+   * serve's --png-compression level), or "h264". Codecs the simulator
+   * cannot produce (e.g. "hevc", "webp") fall back to "jpeg"; the codec in
+   * use is reported in StreamStarted.codec.
+   */
+  codec: string;
+  /**
+   * Whether the stream reloads when watched source files change. Defaults to
+   * true; false pins the stream to what it currently shows. Toggle at runtime
+   * with SetWatch.
+   */
+  watch?: boolean | undefined;
+  /**
+   * Build only on request: file changes mark the stream "dirty" (a
+   * StreamStatus phase) instead of reloading it, and a Reload command
+   * applies them. Set when the stream starts; an update does not change it.
+   */
+  manualBuild: boolean;
+  /**
+   * Appearance of this stream's simulator: "light" or "dark". Empty uses
+   * serve's --appearance, or leaves the simulator as it is. A different
+   * value in an update restarts the stream.
+   */
+  appearance: string;
+  /**
+   * Locale ("ja_JP") and UI language ("ja") of this stream's simulator.
+   * Empty uses serve's --locale and --language. A locale replaces serve's
+   * --region and --language too. Apps read them at launch, so a different
+   * value in an update relaunches the app rather than hot-reloading.
+   */
+  locale: string;
+  language: string;
 }
 
 /** RemoveStream stops and removes a preview stream. */
@@ -48,10 +114,72 @@ export interface SwitchFile {
 export interface NextPreview {
 }
 
+/**
+ * SetWatch turns file watching on or off for the stream. While off, file
+ * changes are ignored by this stream; turning it back on does not replay
+ * changes made in the meantime (send ForceRebuild for that).
+ */
+export interface SetWatch {
+  enabled: boolean;
+}
+
 /** ForceRebuild triggers a full rebuild + relaunch for the current stream. */
 export interface ForceRebuild {
 }
 
+/**
+ * Reload applies the file changes a manual_build stream has seen since its
+ * last build, hot-reloading or rebuilding as a watched change would.
+ * A stream without such changes ignores it.
+ */
+export interface Reload {
+}
+
+/**
+ * ListDevices asks for the simulators in axe's device set. The CLI answers
+ * with a DeviceList event carrying the command's stream_id, which may be
+ * empty since the list is not stream-specific.
+ */
+export interface ListDevices {
+}
+
+/**
+ * PoolStatus asks for the state of the idb companions that relay video and
+ * input for the session's devices, to diagnose leaked companions or stuck
+ * starts. The CLI answers with a CompanionPoolStatus event carrying the
+ * command's stream_id, which may be empty.
+ */
+export interface PoolStatus {
+}
+
+/**
+ * SetDevice moves the stream to the simulator with the given UDID from
+ * axe's device set (see ListDevices). The stream restarts on that device
+ * with its current file and preview. The device must not be used by
+ * another stream; streams of serve --preview-all cannot be moved. A
+ * rejected SetDevice is answered with a ProtocolError giving the reason.
+ */
+export interface SetDevice {
+  udid: string;
+}
+
+/**
+ * StartRecording records the stream's simulator screen to an H.264 mp4 at
+ * path (absolute, overwritten if it exists) until StopRecording, the
+ * stream stopping, or the CLI exiting. Recording events report the
+ * progress. A stream records to one file at a time.
+ */
+export interface StartRecording {
+  path: string;
+}
+
+/**
+ * StopRecording ends the stream's recording. A Recording event with
+ * active = false follows once the file is finalized.
+ */
+export interface StopRecording {
+}
+
 /** Input forwards user interaction (touch/text) to the simulator. */
 export interface Input {
   touchDown?: TouchEvent | undefined;
@@ -84,6 +212,13 @@ export interface Event {
   streamStatus?: StreamStatus | undefined;
   protocolError?: ProtocolError | undefined;
   hello?: Hello | undefined;
+  logStream?: LogStream | undefined;
+  deviceList?: DeviceList | undefined;
+  buildFailed?: BuildFailed | undefined;
+  recording?: Recording | undefined;
+  buildComplete?: BuildComplete | undefined;
+  shutdownComplete?: ShutdownComplete | undefined;
+  companionPoolStatus?: CompanionPoolStatus | undefined;
 }
 
 /** Frame contains a base64-encoded JPEG preview image. */
@@ -92,38 +227,234 @@ export interface Frame {
   device: string;
   /** previewed file path */
   file: string;
-  /** base64-encoded JPEG */
+  /** base64-encoded JPEG, or a data: URL with --frame-encoding dataurl */
   data: string;
+  /**
+   * Per-stream sequence number of the frame received from the simulator,
+   * starting at 1 and reset when the stream's device changes. Frames skipped
+   * by the backpressure drop policy leave gaps in the sequence.
+   */
+  seq: number;
+  /**
+   * Capture time in Unix milliseconds. A double rather than int64 so that
+   * it stays a JSON number in the JSON Lines encoding.
+   */
+  timestamp: number;
+  /**
+   * Preview this frame renders when serve runs with --preview-all: the
+   * #Preview title, or "#<index>" for untitled previews. Empty otherwise.
+   */
+  label: string;
+  /**
+   * Path of a JPEG file holding the frame when serve runs with
+   * --frame-encoding file; data is empty in that case. Only the most recent
+   * few frame files are kept on disk.
+   */
+  path: string;
+  /**
+   * Codec of the image in data/path: "jpeg", "png", or "h264" for the next
+   * chunk of an H.264 Annex B byte stream. H.264 chunks depend on earlier ones,
+   * so none are dropped; feed them to the decoder in seq order. With
+   * --frame-encoding file an "h264" request falls back to "jpeg", since
+   * only the latest frame files are kept.
+   */
+  codec: string;
+  /**
+   * Region that changed since the previous frame of the stream, in frame
+   * pixels. Only set when serve runs with --frame-diff and the codec is
+   * "jpeg" or "png". An empty rectangle (width and height 0) means the frame is
+   * identical to the previous one; the first frame, and the first after a
+   * size change or reconnect, covers the whole frame. When absent, redraw
+   * the whole frame.
+   */
+  dirty: Rect | undefined;
+}
+
+/** Rect is an axis-aligned rectangle in frame pixels, origin at the top left. */
+export interface Rect {
+  x: number;
+  y: number;
+  width: number;
+  height: number;
 }
 
 /** StreamStarted is sent when an AddStream completes successfully. */
 export interface StreamStarted {
   /** number of #Preview blocks in the file */
   previewCount: number;
+  /** Frame codec negotiated for the stream, e.g. "jpeg" */
+  codec: string;
 }
 
 /** StreamStopped is sent when a stream ends (error or user action). */
 export interface StreamStopped {
-  /** e.g. "build_error", "runtime_error", "removed" */
+  /** e.g. "build_error", "runtime_error", "app_crashed", "user_removed" */
   reason: string;
   /** human-readable detail */
   message: string;
-  /** compiler output excerpt for build errors */
+  /** compiler output excerpt for build errors, crashed thread's backtrace for app_crashed */
   diagnostic: string;
 }
 
+/**
+ * BuildFailed is sent when building the app or compiling the preview thunk
+ * fails. Unlike a StreamStopped with reason "build_error", the stream stays
+ * alive: it retries once a watched file changes or on ForceRebuild, and a
+ * successful retry continues with the usual StreamStatus phases.
+ */
+export interface BuildFailed {
+  /** in order of appearance, duplicates removed */
+  diagnostics: BuildDiagnostic[];
+  /** raw xcodebuild / swiftc output */
+  log: string;
+}
+
+/**
+ * BuildComplete is sent once when the app build shared by all streams of
+ * the project succeeds, with an empty stream_id. Streams waiting on the
+ * build move on to compiling and injecting their thunks afterwards. It is
+ * not sent when a previous build is reused (streams then report the
+ * "reusing_build" phase).
+ */
+export interface BuildComplete {
+  /** project or workspace path */
+  project: string;
+  /** compiler warnings of the build */
+  warnings: BuildDiagnostic[];
+}
+
+/** BuildDiagnostic is one compiler message parsed from the build output. */
+export interface BuildDiagnostic {
+  /** absolute path; empty for messages without a location */
+  file: string;
+  /** 1-based; 0 when unknown */
+  line: number;
+  /** 1-based; 0 when unknown */
+  column: number;
+  /** "error", "warning", "note", or "remark" */
+  severity: string;
+  message: string;
+}
+
 /** StreamStatus reports progress during stream initialization. */
 export interface StreamStatus {
-  /** "booting", "building", "installing", "running", "degraded" */
+  /**
+   * "booting", "building", "installing", "running", "degraded", "updating",
+   * "compiling_thunk", or, when a saved change waits for the debounce
+   * window or an in-flight build, "pending" (first change) / "coalesced"
+   * (merged into the pending reload). A manual_build stream reports "dirty"
+   * when a file changes and waits for Reload.
+   */
   phase: string;
+  /** active simulator overrides, e.g. "dynamic_type=accessibility-large", "bold_text" */
+  overrides: string[];
+  /** active iPad multitasking layout (--layout), e.g. "split-half"; empty = none */
+  layout: string;
+  /** horizontal size class injected into the preview: "compact", "regular", or empty */
+  sizeClass: string;
 }
 
 /**
- * ProtocolError reports a protocol-level error (e.g. invalid command JSON).
- * stream_id on the parent Event may be empty since these errors are not stream-specific.
+ * ProtocolError reports a protocol-level error (e.g. invalid command JSON)
+ * or a rejected command (e.g. SetDevice to a device another stream uses).
+ * stream_id on the parent Event is the rejected command's, and may be empty
+ * for errors that are not stream-specific.
  */
 export interface ProtocolError {
   message: string;
+  /** offending input line, truncated */
+  line: string;
+  /** invalid input lines received so far */
+  errorCount: number;
+}
+
+/**
+ * LogStream carries one unified log entry of the previewed app. Sent only
+ * when serve runs with --logs, from launch until the stream stops.
+ */
+export interface LogStream {
+  message: string;
+  /** "default", "info", "debug", "error", or "fault" */
+  level: string;
+  /** os_log subsystem; empty for NSLog and untagged entries */
+  subsystem: string;
+  category: string;
+  /** Log time in Unix milliseconds, as Frame.timestamp. */
+  timestamp: number;
+  pid: number;
+}
+
+/**
+ * DeviceList answers ListDevices with every simulator in axe's device set,
+ * sorted by name. State is live, so devices booted for this session's
+ * streams are reported as such.
+ */
+export interface DeviceList {
+  devices: Device[];
+  /** set when the devices could not be listed */
+  error: string;
+}
+
+export interface Device {
+  udid: string;
+  /** e.g. "axe iPhone 16 Pro (1)" */
+  name: string;
+  /** simctl state, e.g. "Booted", "Booting", or "Shutdown" */
+  state: string;
+  /** e.g. "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro" */
+  deviceType: string;
+  /** e.g. "com.apple.CoreSimulator.SimRuntime.iOS-18-2" */
+  runtime: string;
+  /** stream running on the device in this session; empty if none */
+  streamId: string;
+  /** acquired by this session, with or without a stream */
+  inUse: boolean;
+}
+
+/**
+ * CompanionPoolStatus answers PoolStatus with the companion of every device,
+ * sorted by UDID. A companion is kept for a short while after its last
+ * stream ends, so one without streams is not necessarily leaked.
+ */
+export interface CompanionPoolStatus {
+  companions: CompanionStatus[];
+}
+
+export interface CompanionStatus {
+  udid: string;
+  /** gRPC address; empty while starting or once exited */
+  address: string;
+  /** streams using the companion */
+  refs: number;
+  /** a start is in progress */
+  starting: boolean;
+  /** since the running companion started; 0 if none runs */
+  uptimeSeconds: number;
+  /** exited companions replaced by a new start */
+  restarts: number;
+  /** last start or exit error; empty if none */
+  lastError: string;
+}
+
+/**
+ * Recording reports a stream's screen recording (StartRecording). It is
+ * sent with active = true once recording has started, and with
+ * active = false when it has ended, on StopRecording or otherwise. error
+ * is set when recording could not start or the file could not be
+ * finalized.
+ */
+export interface Recording {
+  path: string;
+  active: boolean;
+  error: string;
+}
+
+/**
+ * ShutdownComplete is the last event of a serve session ended by a
+ * Shutdown command, sent after every stream has been torn down. The CLI
+ * exits with status 0 right after it.
+ */
+export interface ShutdownComplete {
 }
 
 /**
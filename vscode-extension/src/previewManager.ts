@@ -106,7 +106,24 @@ export class PreviewManager {
 		}
 
 		this.streams.set(streamId, { file, deviceType, runtime });
-		this.sendCommand({ streamId, addStream: { file, deviceType, runtime } });
+		this.sendCommand({
+			streamId,
+			addStream: {
+				file,
+				deviceType,
+				runtime,
+				project: "",
+				workspace: "",
+				scheme: "",
+				configuration: "",
+				preview: "",
+				codec: "",
+				manualBuild: false,
+				appearance: "",
+				locale: "",
+				language: "",
+			},
+		});
 
 		const fileName = path.basename(file);
 		this.statusBar.showRunning(fileName);
@@ -149,7 +166,24 @@ export class PreviewManager {
 
 		// Add the new stream.
 		this.streams.set(streamId, { file, deviceType, runtime });
-		this.sendCommand({ streamId, addStream: { file, deviceType, runtime } });
+		this.sendCommand({
+			streamId,
+			addStream: {
+				file,
+				deviceType,
+				runtime,
+				project: "",
+				workspace: "",
+				scheme: "",
+				configuration: "",
+				preview: "",
+				codec: "",
+				manualBuild: false,
+				appearance: "",
+				locale: "",
+				language: "",
+			},
+		});
 
 		const fileName = path.basename(file);
 		this.statusBar.showRunning(fileName);
@@ -136,6 +136,16 @@ suite("Protocol", () => {
 					file: "/path/to/View.swift",
 					deviceType: "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro",
 					runtime: "com.apple.CoreSimulator.SimRuntime.iOS-18-2",
+					project: "",
+					workspace: "",
+					scheme: "",
+					configuration: "",
+					preview: "",
+					codec: "",
+					manualBuild: false,
+					appearance: "",
+					locale: "",
+					language: "",
 				},
 			};
 			const json = serializeCommand(cmd);
@@ -210,7 +220,21 @@ suite("Protocol", () => {
 		test("omits undefined payload fields", () => {
 			const cmd: Command = {
 				streamId: "s1",
-				addStream: { file: "/p", deviceType: "dt", runtime: "rt" },
+				addStream: {
+					file: "/p",
+					deviceType: "dt",
+					runtime: "rt",
+					project: "",
+					workspace: "",
+					scheme: "",
+					configuration: "",
+					preview: "",
+					codec: "",
+					manualBuild: false,
+					appearance: "",
+					locale: "",
+					language: "",
+				},
 			};
 			const json = serializeCommand(cmd);
 			assert.ok(!json.includes("removeStream"));
@@ -224,7 +248,21 @@ suite("Protocol", () => {
 			// This simulates the CLI receiving a serialized Command
 			const cmd: Command = {
 				streamId: "s1",
-				addStream: { file: "/p", deviceType: "dt", runtime: "rt" },
+				addStream: {
+					file: "/p",
+					deviceType: "dt",
+					runtime: "rt",
+					project: "",
+					workspace: "",
+					scheme: "",
+					configuration: "",
+					preview: "",
+					codec: "",
+					manualBuild: false,
+					appearance: "",
+					locale: "",
+					language: "",
+				},
 			};
 			const json = serializeCommand(cmd);
 			const parsed = JSON.parse(json);
@@ -237,7 +275,16 @@ suite("Protocol", () => {
 		test("isFrame returns true for Frame events", () => {
 			const event: Event = {
 				streamId: "a",
-				frame: { device: "iPhone", file: "V.swift", data: "abc" },
+				frame: {
+					device: "iPhone",
+					file: "V.swift",
+					data: "abc",
+					seq: 1,
+					timestamp: 0,
+					label: "",
+					path: "",
+					codec: "",
+				},
 			};
 			assert.strictEqual(isFrame(event), true);
 			assert.strictEqual(isStreamStarted(event), false);
@@ -250,7 +297,7 @@ suite("Protocol", () => {
 		test("isStreamStarted returns true for StreamStarted events", () => {
 			const event: Event = {
 				streamId: "a",
-				streamStarted: { previewCount: 2 },
+				streamStarted: { previewCount: 2, codec: "" },
 			};
 			assert.strictEqual(isFrame(event), false);
 			assert.strictEqual(isStreamStarted(event), true);
@@ -259,7 +306,7 @@ suite("Protocol", () => {
 		test("isStreamStopped returns true for StreamStopped events", () => {
 			const event: Event = {
 				streamId: "a",
-				streamStopped: { reason: "removed", message: "", diagnostic: "" },
+				streamStopped: { reason: "user_removed", message: "", diagnostic: "" },
 			};
 			assert.strictEqual(isStreamStopped(event), true);
 			assert.strictEqual(isFrame(event), false);
@@ -268,7 +315,7 @@ suite("Protocol", () => {
 		test("isStreamStatus returns true for StreamStatus events", () => {
 			const event: Event = {
 				streamId: "a",
-				streamStatus: { phase: "building" },
+				streamStatus: { phase: "building", overrides: [] },
 			};
 			assert.strictEqual(isStreamStatus(event), true);
 			assert.strictEqual(isFrame(event), false);
@@ -277,7 +324,7 @@ suite("Protocol", () => {
 		test("isProtocolError returns true for ProtocolError events", () => {
 			const event: Event = {
 				streamId: "",
-				protocolError: { message: "bad input" },
+				protocolError: { message: "bad input", line: "", errorCount: 0 },
 			};
 			assert.strictEqual(isProtocolError(event), true);
 			assert.strictEqual(isFrame(event), false);
@@ -317,6 +364,15 @@ suite("Protocol", () => {
 			assert.strictEqual(event.frame?.data, "AAAA");
 		});
 
+		test("Go-produced Frame seq and timestamp parse as numbers", () => {
+			const goJSON =
+				'{"streamId":"a","frame":{"device":"iPhone 16 Pro","file":"HogeView.swift","data":"AAAA","seq":7,"timestamp":1700000000123}}';
+			const event = parseEvent(goJSON);
+			assert.ok(event);
+			assert.strictEqual(event.frame?.seq, 7);
+			assert.strictEqual(event.frame?.timestamp, 1700000000123);
+		});
+
 		test("TS-produced AddStream JSON matches Go expected format", () => {
 			const cmd: Command = {
 				streamId: "stream-1",
@@ -324,6 +380,16 @@ suite("Protocol", () => {
 					file: "/path/to/HogeView.swift",
 					deviceType: "com.apple.CoreSimulator.SimDeviceType.iPhone-16-Pro",
 					runtime: "com.apple.CoreSimulator.SimRuntime.iOS-18-2",
+					project: "",
+					workspace: "",
+					scheme: "",
+					configuration: "",
+					preview: "",
+					codec: "",
+					manualBuild: false,
+					appearance: "",
+					locale: "",
+					language: "",
 				},
 			};
 			const json = serializeCommand(cmd);